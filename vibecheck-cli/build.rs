@@ -831,7 +831,24 @@ fn generate_readme_badges() {
     }
 }
 
+/// Generate the `vibecheck.v1` messages and `Analysis` service for
+/// `src/grpc.rs` from the proto file.  `protoc` comes from
+/// protoc-bin-vendored, so building needs none installed.
+#[cfg(feature = "grpc")]
+fn compile_protos() {
+    let protoc = protoc_bin_vendored::protoc_bin_path().expect("no vendored protoc for this platform");
+    std::env::set_var("PROTOC", protoc);
+    tonic_build::configure()
+        // build.rs reruns on every change, to keep the SVGs current.
+        .emit_rerun_if_changed(false)
+        .compile_protos(&["../proto/vibecheck/v1/analysis.proto"], &["../proto"])
+        .expect("cannot compile proto/vibecheck/v1/analysis.proto");
+}
+
 fn main() {
+    #[cfg(feature = "grpc")]
+    compile_protos();
+
     let source = match std::fs::read_to_string("src/output.rs") {
         Ok(s) => s,
         Err(e) => {
@@ -0,0 +1,630 @@
+//! Source extraction from build artifacts, for auditing what was shipped
+//! rather than what is in the repository.
+//!
+//! Supported inputs:
+//!
+//! - OCI images, either a `docker save` tarball or an OCI image-layout
+//!   tarball (`skopeo copy … oci-archive:`).  Layers are applied in order,
+//!   honouring whiteouts, so the scan sees the image's final filesystem.
+//! - Python sdists (`.tar.gz`) and any other plain tar archive.
+//! - Go module zips (as served by the module proxy) and Python wheels.
+//! - Batches of submissions: a zip or tarball whose entries are themselves
+//!   archives (`alice.zip`, `bob.tar.gz`) has each of those unpacked into a
+//!   directory of the same name, one level deep.
+//!
+//! Only regular files with a supported extension are extracted; symlinks,
+//! devices and paths escaping the archive root are skipped.  Extraction is
+//! capped (see [`Limits`]) so a zip bomb fails the scan instead of filling
+//! the disk: oversized entries are skipped, and an archive holding too many
+//! files or bytes in total is rejected.
+
+use std::fs::File;
+use std::io::{BufReader, Cursor, Read, Seek, SeekFrom};
+use std::path::{Component, Path, PathBuf};
+
+use anyhow::{bail, Context, Result};
+use flate2::read::{DeflateDecoder, GzDecoder};
+
+use vibecheck_core::language::{is_doc_file, SUPPORTED_EXTENSIONS};
+use vibecheck_core::language_pack;
+
+/// Archive extensions treated as artifacts rather than source files.
+const ARTIFACT_SUFFIXES: &[&str] = &[".tar", ".tar.gz", ".tgz", ".zip", ".whl"];
+
+/// Archives nested this many levels deep inside an artifact are unpacked
+/// too; deeper ones are skipped.
+const MAX_NESTING: usize = 1;
+
+/// Caps on what one artifact may extract.
+#[derive(Debug, Clone, Copy)]
+struct Limits {
+    /// Files extracted, nested archives included.
+    files: usize,
+    /// Uncompressed bytes extracted in total.
+    bytes: u64,
+    /// Larger entries are skipped, as no source file is this big.
+    file_bytes: u64,
+}
+
+const SOURCE_LIMITS: Limits = Limits { files: 100_000, bytes: 2 << 30, file_bytes: 64 << 20 };
+
+/// What an extraction has used of its [`Limits`].
+struct Budget {
+    limits: Limits,
+    files: usize,
+    bytes: u64,
+}
+
+impl Budget {
+    fn new(limits: Limits) -> Self {
+        Self { limits, files: 0, bytes: 0 }
+    }
+
+    /// Whether an entry of `size` bytes is small enough to extract.
+    fn admits(&self, size: u64) -> bool {
+        size <= self.limits.file_bytes
+    }
+
+    /// Count an extracted entry of `size` bytes, failing once a total is
+    /// exceeded.
+    fn charge(&mut self, size: u64) -> Result<()> {
+        self.files += 1;
+        self.bytes += size;
+        if self.files > self.limits.files {
+            bail!("archive holds more than {} files", self.limits.files);
+        }
+        if self.bytes > self.limits.bytes {
+            bail!("archive expands to more than {} MiB", self.limits.bytes >> 20);
+        }
+        Ok(())
+    }
+}
+
+/// Return `true` if `path` is a file vibecheck should unpack before
+/// scanning.
+pub fn is_artifact(path: &Path) -> bool {
+    path.is_file() && is_archive_name(path)
+}
+
+fn is_archive_name(path: &Path) -> bool {
+    let name = path.file_name().and_then(|n| n.to_str()).unwrap_or("");
+    ARTIFACT_SUFFIXES.iter().any(|s| name.ends_with(s))
+}
+
+/// An artifact unpacked into a temporary directory, removed on drop.
+pub struct Extracted {
+    artifact: PathBuf,
+    dir: tempfile::TempDir,
+}
+
+impl Extracted {
+    /// Directory holding the extracted source tree.
+    pub fn root(&self) -> &Path {
+        self.dir.path()
+    }
+
+    /// The path to report for an extracted file: `<artifact>!/<path inside>`.
+    pub fn display_path(&self, extracted: &Path) -> PathBuf {
+        let inner = extracted.strip_prefix(self.root()).unwrap_or(extracted);
+        PathBuf::from(format!("{}!/{}", self.artifact.display(), inner.display()))
+    }
+}
+
+/// Unpack the source files of the artifact at `path`.
+pub fn extract(path: &Path) -> Result<Extracted> {
+    let dir = tempfile::tempdir().context("cannot create extraction directory")?;
+    let name = path.file_name().and_then(|n| n.to_str()).unwrap_or("");
+    unpack(path, name, dir.path(), &mut Budget::new(SOURCE_LIMITS), 0)?;
+    Ok(Extracted { artifact: path.to_path_buf(), dir })
+}
+
+/// Unpack the archive at `path`, whose kind is told by `name`, into `dest`,
+/// then the archives it held while `depth` allows.
+fn unpack(path: &Path, name: &str, dest: &Path, budget: &mut Budget, depth: usize) -> Result<()> {
+    let nested = depth < MAX_NESTING;
+    let keep = |p: &Path| is_source(p) || (nested && is_archive_name(p));
+    if name.ends_with(".zip") || name.ends_with(".whl") {
+        let mut file = File::open(path).with_context(|| format!("cannot open {}", path.display()))?;
+        unpack_zip(&mut file, dest, keep, budget).with_context(|| format!("cannot read zip {name}"))?;
+    } else {
+        extract_tar(path, dest, keep, budget).with_context(|| format!("cannot read tar {name}"))?;
+    }
+    if !nested {
+        return Ok(());
+    }
+    for archive in nested_archives(dest)? {
+        // Unpack `alice.zip` into a directory named `alice.zip`, so its
+        // files are reported as `submissions.zip!/alice.zip/main.py`.
+        let staged = archive.with_extension("vibecheck-staged");
+        std::fs::rename(&archive, &staged)?;
+        std::fs::create_dir(&archive)?;
+        let inner = archive.file_name().and_then(|n| n.to_str()).unwrap_or("");
+        unpack(&staged, inner, &archive, budget, depth + 1)?;
+        std::fs::remove_file(&staged)?;
+    }
+    Ok(())
+}
+
+/// The archives extracted under `dir`.
+fn nested_archives(dir: &Path) -> Result<Vec<PathBuf>> {
+    let mut found = Vec::new();
+    let mut pending = vec![dir.to_path_buf()];
+    while let Some(dir) = pending.pop() {
+        for entry in std::fs::read_dir(&dir)? {
+            let path = entry?.path();
+            if path.is_dir() {
+                pending.push(path);
+            } else if is_archive_name(&path) {
+                found.push(path);
+            }
+        }
+    }
+    found.sort();
+    Ok(found)
+}
+
+fn is_source(path: &Path) -> bool {
+    path.extension()
+        .and_then(|e| e.to_str())
+        .map(|e| SUPPORTED_EXTENSIONS.contains(&e) || language_pack::is_pack_extension(e))
+        .unwrap_or(false)
+        || is_doc_file(path)
+}
+
+/// `path` relative to the archive root, or `None` if it would escape it.
+fn safe_relative(path: &Path) -> Option<PathBuf> {
+    let mut out = PathBuf::new();
+    for c in path.components() {
+        match c {
+            Component::Normal(p) => out.push(p),
+            Component::CurDir => {}
+            _ => return None,
+        }
+    }
+    (!out.as_os_str().is_empty()).then_some(out)
+}
+
+// ---------------------------------------------------------------------------
+// Tar archives and container images
+// ---------------------------------------------------------------------------
+
+/// Open a tar stream, transparently decompressing gzip.
+fn open_tar(path: &Path) -> Result<Box<dyn Read>> {
+    let mut file = File::open(path)?;
+    let mut magic = [0u8; 2];
+    let gzip = file.read(&mut magic)? == 2 && magic == [0x1f, 0x8b];
+    file.seek(SeekFrom::Start(0))?;
+    let reader = BufReader::new(file);
+    Ok(if gzip { Box::new(GzDecoder::new(reader)) } else { Box::new(reader) })
+}
+
+fn extract_tar(path: &Path, dest: &Path, keep: impl Fn(&Path) -> bool, budget: &mut Budget) -> Result<()> {
+    if !has_image_manifest(open_tar(path)?)? {
+        return unpack_tar(open_tar(path)?, dest, keep, budget);
+    }
+    // An image is a tar of layer tars plus a manifest, so stage the outer
+    // archive whole, then read its layers.  Staging is held to the same
+    // totals as extraction; only a single blob may use all of them.
+    let staging = tempfile::tempdir()?;
+    let limits = Limits { file_bytes: budget.limits.bytes, ..budget.limits };
+    unpack_tar(open_tar(path)?, staging.path(), |_| true, &mut Budget::new(limits))?;
+
+    match image_layers(staging.path())? {
+        Some(layers) => {
+            for layer in layers {
+                let layer = staging.path().join(&layer);
+                unpack_tar(open_tar(&layer)?, dest, is_source, budget)
+                    .with_context(|| format!("cannot read layer {}", layer.display()))?;
+            }
+        }
+        None => unpack_tar(open_tar(path)?, dest, keep, budget)?,
+    }
+    Ok(())
+}
+
+/// Whether the tar stream has a `manifest.json` or `index.json` at its root,
+/// as `docker save` and OCI image-layout tarballs do.  Only headers are read.
+fn has_image_manifest(reader: impl Read) -> Result<bool> {
+    let mut archive = tar::Archive::new(reader);
+    for entry in archive.entries()? {
+        let entry = entry?;
+        let Some(rel) = safe_relative(&entry.path()?) else { continue };
+        if rel == Path::new("manifest.json") || rel == Path::new("index.json") {
+            return Ok(true);
+        }
+    }
+    Ok(false)
+}
+
+/// Layer paths, in application order, if `root` is an unpacked image.
+fn image_layers(root: &Path) -> Result<Option<Vec<PathBuf>>> {
+    // `docker save`: manifest.json lists layer tarballs directly.
+    if let Ok(src) = std::fs::read_to_string(root.join("manifest.json")) {
+        let manifest: serde_json::Value = serde_json::from_str(&src).context("invalid manifest.json")?;
+        if let Some(layers) = manifest.pointer("/0/Layers").and_then(|l| l.as_array()) {
+            return Ok(Some(layers.iter().filter_map(|l| l.as_str()).map(PathBuf::from).collect()));
+        }
+    }
+    // OCI image layout: index.json -> manifest blob -> layer blobs.  A
+    // multi-platform index nests another index; the first platform is used.
+    let Ok(src) = std::fs::read_to_string(root.join("index.json")) else {
+        return Ok(None);
+    };
+    let mut doc: serde_json::Value = serde_json::from_str(&src).context("invalid index.json")?;
+    for _ in 0..4 {
+        if let Some(layers) = doc.get("layers").and_then(|l| l.as_array()) {
+            let paths = layers
+                .iter()
+                .filter_map(|l| l.get("digest").and_then(|d| d.as_str()))
+                .map(blob_path)
+                .collect::<Result<_>>()?;
+            return Ok(Some(paths));
+        }
+        let Some(digest) = doc.pointer("/manifests/0/digest").and_then(|d| d.as_str()) else {
+            return Ok(None);
+        };
+        let blob = std::fs::read_to_string(root.join(blob_path(digest)?))
+            .with_context(|| format!("missing manifest blob {digest}"))?;
+        doc = serde_json::from_str(&blob).with_context(|| format!("invalid manifest blob {digest}"))?;
+    }
+    bail!("image index nested too deeply")
+}
+
+/// `sha256:abc…` → `blobs/sha256/abc…`.
+fn blob_path(digest: &str) -> Result<PathBuf> {
+    let (algo, hex) = digest.split_once(':').with_context(|| format!("invalid digest {digest}"))?;
+    safe_relative(&Path::new("blobs").join(algo).join(hex)).with_context(|| format!("invalid digest {digest}"))
+}
+
+/// Extract the regular files accepted by `keep`, applying OCI whiteouts
+/// (`.wh.<name>` deletes `<name>`, `.wh..wh..opq` empties the directory).
+fn unpack_tar(reader: impl Read, dest: &Path, keep: impl Fn(&Path) -> bool, budget: &mut Budget) -> Result<()> {
+    let mut archive = tar::Archive::new(reader);
+    for entry in archive.entries()? {
+        let mut entry = entry?;
+        let Some(rel) = safe_relative(&entry.path()?) else { continue };
+        let name = rel.file_name().and_then(|n| n.to_str()).unwrap_or("");
+
+        if let Some(hidden) = name.strip_prefix(".wh.") {
+            let dir = dest.join(rel.parent().unwrap_or(Path::new("")));
+            if hidden == ".wh..opq" {
+                if dir.is_dir() {
+                    std::fs::remove_dir_all(&dir)?;
+                    std::fs::create_dir_all(&dir)?;
+                }
+            } else {
+                let target = dir.join(hidden);
+                if target.is_dir() {
+                    std::fs::remove_dir_all(&target)?;
+                } else if target.exists() {
+                    std::fs::remove_file(&target)?;
+                }
+            }
+            continue;
+        }
+
+        if !entry.header().entry_type().is_file() || !keep(&rel) {
+            continue;
+        }
+        let size = entry.header().size()?;
+        if !budget.admits(size) {
+            continue;
+        }
+        budget.charge(size)?;
+        let out = dest.join(&rel);
+        if let Some(parent) = out.parent() {
+            std::fs::create_dir_all(parent)?;
+        }
+        let mut file = File::create(&out)?;
+        std::io::copy(&mut entry, &mut file)?;
+    }
+    Ok(())
+}
+
+// ---------------------------------------------------------------------------
+// Zip archives
+// ---------------------------------------------------------------------------
+
+const EOCD_SIG: u32 = 0x0605_4b50;
+const CENTRAL_SIG: u32 = 0x0201_4b50;
+const LOCAL_SIG: u32 = 0x0403_4b50;
+
+fn u16_at(b: &[u8], at: usize) -> Result<u16> {
+    let s = b.get(at..at + 2).context("truncated zip")?;
+    Ok(u16::from_le_bytes([s[0], s[1]]))
+}
+
+fn u32_at(b: &[u8], at: usize) -> Result<u32> {
+    let s = b.get(at..at + 4).context("truncated zip")?;
+    Ok(u32::from_le_bytes([s[0], s[1], s[2], s[3]]))
+}
+
+/// Extract the source files of a zip archive.
+///
+/// Module zips and wheels only use stored and deflated entries, so those
+/// are the only methods supported; zip64 and encrypted archives are
+/// rejected or skipped.  Entries are inflated no further than their
+/// declared size, which is what the [`Budget`] is charged.
+fn unpack_zip(reader: &mut impl Read, dest: &Path, keep: impl Fn(&Path) -> bool, budget: &mut Budget) -> Result<()> {
+    let mut data = Vec::new();
+    reader.read_to_end(&mut data)?;
+
+    // The end-of-central-directory record sits in the last 22 bytes plus an
+    // optional comment of up to 64 KiB.
+    let min = data.len().saturating_sub(22 + u16::MAX as usize);
+    let eocd = (min..data.len().saturating_sub(21))
+        .rev()
+        .find(|&i| u32_at(&data, i).ok() == Some(EOCD_SIG))
+        .context("not a zip archive")?;
+    let count = u16_at(&data, eocd + 10)? as usize;
+    let mut at = u32_at(&data, eocd + 16)? as usize;
+    if at == u32::MAX as usize {
+        bail!("zip64 archives are not supported");
+    }
+
+    for _ in 0..count {
+        if u32_at(&data, at)? != CENTRAL_SIG {
+            bail!("corrupt central directory");
+        }
+        let flags = u16_at(&data, at + 8)?;
+        let method = u16_at(&data, at + 10)?;
+        let size = u32_at(&data, at + 20)? as usize;
+        let declared = u32_at(&data, at + 24)? as u64;
+        let name_len = u16_at(&data, at + 28)? as usize;
+        let extra_len = u16_at(&data, at + 30)? as usize;
+        let comment_len = u16_at(&data, at + 32)? as usize;
+        let local = u32_at(&data, at + 42)? as usize;
+        let name = String::from_utf8_lossy(data.get(at + 46..at + 46 + name_len).context("truncated zip")?).into_owned();
+        at += 46 + name_len + extra_len + comment_len;
+
+        let encrypted = flags & 1 != 0;
+        let Some(rel) = safe_relative(Path::new(&name)) else { continue };
+        if name.ends_with('/') || encrypted || !keep(&rel) || !budget.admits(declared) {
+            continue;
+        }
+        budget.charge(declared)?;
+
+        if u32_at(&data, local)? != LOCAL_SIG {
+            bail!("corrupt local header for {name}");
+        }
+        let start = local + 30 + u16_at(&data, local + 26)? as usize + u16_at(&data, local + 28)? as usize;
+        let raw = data.get(start..start + size).context("truncated zip")?;
+        let mut contents = Vec::new();
+        match method {
+            0 => contents.extend_from_slice(raw),
+            8 => {
+                DeflateDecoder::new(Cursor::new(raw)).take(declared + 1).read_to_end(&mut contents)?;
+            }
+            other => bail!("unsupported compression method {other} for {name}"),
+        }
+        if contents.len() as u64 > declared {
+            bail!("{name} is larger than its declared size");
+        }
+
+        let out = dest.join(&rel);
+        if let Some(parent) = out.parent() {
+            std::fs::create_dir_all(parent)?;
+        }
+        std::fs::write(&out, contents)?;
+    }
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::io::Write;
+
+    fn tar_bytes(files: &[(&str, &[u8])]) -> Vec<u8> {
+        let mut builder = tar::Builder::new(Vec::new());
+        for (path, data) in files {
+            let mut header = tar::Header::new_gnu();
+            header.set_size(data.len() as u64);
+            header.set_mode(0o644);
+            header.set_entry_type(tar::EntryType::Regular);
+            builder.append_data(&mut header, path, *data).unwrap();
+        }
+        builder.into_inner().unwrap()
+    }
+
+    fn gzip(data: &[u8]) -> Vec<u8> {
+        let mut enc = flate2::write::GzEncoder::new(Vec::new(), flate2::Compression::default());
+        enc.write_all(data).unwrap();
+        enc.finish().unwrap()
+    }
+
+    /// A minimal zip: `deflate` selects method 8 instead of stored.
+    fn zip_bytes(files: &[(&str, &[u8])], deflate: bool) -> Vec<u8> {
+        let mut out = Vec::new();
+        let mut central = Vec::new();
+        for (name, data) in files {
+            let body = if deflate {
+                let mut enc = flate2::write::DeflateEncoder::new(Vec::new(), flate2::Compression::default());
+                enc.write_all(data).unwrap();
+                enc.finish().unwrap()
+            } else {
+                data.to_vec()
+            };
+            let method: u16 = if deflate { 8 } else { 0 };
+            let offset = out.len() as u32;
+            out.extend(LOCAL_SIG.to_le_bytes());
+            out.extend([20, 0, 0, 0]);
+            out.extend(method.to_le_bytes());
+            out.extend([0; 8]); // time, date, crc
+            out.extend((body.len() as u32).to_le_bytes());
+            out.extend((data.len() as u32).to_le_bytes());
+            out.extend((name.len() as u16).to_le_bytes());
+            out.extend([0, 0]);
+            out.extend(name.as_bytes());
+            out.extend(&body);
+
+            central.extend(CENTRAL_SIG.to_le_bytes());
+            central.extend([20, 0, 20, 0, 0, 0]);
+            central.extend(method.to_le_bytes());
+            central.extend([0; 8]);
+            central.extend((body.len() as u32).to_le_bytes());
+            central.extend((data.len() as u32).to_le_bytes());
+            central.extend((name.len() as u16).to_le_bytes());
+            central.extend([0; 12]); // extra, comment, disk, attrs
+            central.extend(offset.to_le_bytes());
+            central.extend(name.as_bytes());
+        }
+        let cd_offset = out.len() as u32;
+        out.extend(&central);
+        out.extend(EOCD_SIG.to_le_bytes());
+        out.extend([0; 4]);
+        out.extend((files.len() as u16).to_le_bytes());
+        out.extend((files.len() as u16).to_le_bytes());
+        out.extend((central.len() as u32).to_le_bytes());
+        out.extend(cd_offset.to_le_bytes());
+        out.extend([0, 0]);
+        out
+    }
+
+    fn write(dir: &Path, name: &str, data: &[u8]) -> PathBuf {
+        let path = dir.join(name);
+        std::fs::write(&path, data).unwrap();
+        path
+    }
+
+    fn read(root: &Path, rel: &str) -> Option<String> {
+        std::fs::read_to_string(root.join(rel)).ok()
+    }
+
+    #[test]
+    fn recognizes_artifact_extensions() {
+        let dir = tempfile::tempdir().unwrap();
+        for name in ["a.tar", "a.tar.gz", "a.tgz", "a.zip", "a.whl"] {
+            assert!(is_artifact(&write(dir.path(), name, b"")), "{name}");
+        }
+        assert!(!is_artifact(&write(dir.path(), "a.rs", b"")));
+        assert!(!is_artifact(&dir.path().join("missing.tar")));
+    }
+
+    #[test]
+    fn safe_relative_rejects_escapes() {
+        assert_eq!(safe_relative(Path::new("./pkg/a.py")), Some(PathBuf::from("pkg/a.py")));
+        assert_eq!(safe_relative(Path::new("../etc/passwd")), None);
+        assert_eq!(safe_relative(Path::new("/etc/passwd")), None);
+    }
+
+    #[test]
+    fn sdist_extracts_source_files_only() {
+        let dir = tempfile::tempdir().unwrap();
+        let sdist = gzip(&tar_bytes(&[
+            ("pkg-1.0/pkg/__init__.py", b"x = 1\n"),
+            ("pkg-1.0/PKG-INFO", b"Name: pkg\n"),
+        ]));
+        let extracted = extract(&write(dir.path(), "pkg-1.0.tar.gz", &sdist)).unwrap();
+        assert_eq!(read(extracted.root(), "pkg-1.0/pkg/__init__.py").as_deref(), Some("x = 1\n"));
+        assert!(read(extracted.root(), "pkg-1.0/PKG-INFO").is_none());
+        let shown = extracted.display_path(&extracted.root().join("pkg-1.0/pkg/__init__.py"));
+        assert!(shown.to_string_lossy().ends_with("pkg-1.0.tar.gz!/pkg-1.0/pkg/__init__.py"));
+    }
+
+    #[test]
+    fn docker_save_applies_layers_in_order_with_whiteouts() {
+        let dir = tempfile::tempdir().unwrap();
+        let base = tar_bytes(&[("app/main.go", b"package main // v1\n"), ("app/old.py", b"x = 1\n")]);
+        let top = gzip(&tar_bytes(&[("app/main.go", b"package main // v2\n"), ("app/.wh.old.py", b"")]));
+        let manifest = br#"[{"Config": "c.json", "Layers": ["l1/layer.tar", "l2/layer.tar"]}]"#;
+        let image = tar_bytes(&[("l2/layer.tar", &top), ("manifest.json", manifest), ("l1/layer.tar", &base)]);
+
+        let extracted = extract(&write(dir.path(), "image.tar", &image)).unwrap();
+        assert_eq!(read(extracted.root(), "app/main.go").as_deref(), Some("package main // v2\n"));
+        assert!(read(extracted.root(), "app/old.py").is_none());
+        assert!(read(extracted.root(), "manifest.json").is_none());
+    }
+
+    #[test]
+    fn oci_layout_resolves_layers_through_index() {
+        let dir = tempfile::tempdir().unwrap();
+        let layer = gzip(&tar_bytes(&[("srv/index.js", b"const a = 1;\n")]));
+        let manifest = br#"{"layers": [{"digest": "sha256:aaa"}]}"#;
+        let index = br#"{"manifests": [{"digest": "sha256:mmm"}]}"#;
+        let image = tar_bytes(&[
+            ("oci-layout", b"{}"),
+            ("index.json", index),
+            ("blobs/sha256/mmm", manifest),
+            ("blobs/sha256/aaa", &layer),
+        ]);
+        let extracted = extract(&write(dir.path(), "image.tar", &image)).unwrap();
+        assert_eq!(read(extracted.root(), "srv/index.js").as_deref(), Some("const a = 1;\n"));
+    }
+
+    #[test]
+    fn go_module_zip_stored_and_deflated() {
+        let dir = tempfile::tempdir().unwrap();
+        for deflate in [false, true] {
+            let zip = zip_bytes(
+                &[("example.com/m@v1.0.0/m.go", b"package m\n"), ("example.com/m@v1.0.0/LICENSE", b"MIT\n")],
+                deflate,
+            );
+            let extracted = extract(&write(dir.path(), "v1.0.0.zip", &zip)).unwrap();
+            assert_eq!(read(extracted.root(), "example.com/m@v1.0.0/m.go").as_deref(), Some("package m\n"));
+            assert!(read(extracted.root(), "example.com/m@v1.0.0/LICENSE").is_none());
+        }
+    }
+
+    #[test]
+    fn submission_batches_unpack_nested_archives() {
+        let dir = tempfile::tempdir().unwrap();
+        let alice = zip_bytes(&[("main.py", b"print(1)\n"), ("deeper.zip", &zip_bytes(&[("x.py", b"")], false))], true);
+        let bob = gzip(&tar_bytes(&[("hw1/solve.go", b"package hw1\n")]));
+        let batch = zip_bytes(&[("alice.zip", &alice), ("late/bob.tar.gz", &bob), ("README.txt", b"grades\n")], false);
+
+        let extracted = extract(&write(dir.path(), "submissions.zip", &batch)).unwrap();
+        assert_eq!(read(extracted.root(), "alice.zip/main.py").as_deref(), Some("print(1)\n"));
+        assert_eq!(read(extracted.root(), "late/bob.tar.gz/hw1/solve.go").as_deref(), Some("package hw1\n"));
+        assert!(!extracted.root().join("alice.zip/deeper.zip").exists(), "only one level is unpacked");
+        let shown = extracted.display_path(&extracted.root().join("alice.zip/main.py"));
+        assert!(shown.to_string_lossy().ends_with("submissions.zip!/alice.zip/main.py"));
+    }
+
+    #[test]
+    fn limits_skip_large_entries_and_cap_totals() {
+        let dest = tempfile::tempdir().unwrap();
+        let small = Limits { files: 2, bytes: 1 << 20, file_bytes: 16 };
+        let zip = zip_bytes(&[("a.py", b"x = 1\n"), ("big.py", &[b'#'; 64])], true);
+        unpack_zip(&mut Cursor::new(&zip), dest.path(), is_source, &mut Budget::new(small)).unwrap();
+        assert!(read(dest.path(), "a.py").is_some());
+        assert!(read(dest.path(), "big.py").is_none(), "entries over the size cap are skipped");
+
+        let many = tar_bytes(&[("a.py", b""), ("b.py", b""), ("c.py", b"")]);
+        let err = unpack_tar(Cursor::new(many), dest.path(), is_source, &mut Budget::new(small)).unwrap_err();
+        assert!(err.to_string().contains("more than 2 files"), "{err}");
+
+        let heavy = Limits { files: 10, bytes: 10, file_bytes: 16 };
+        let tar = tar_bytes(&[("a.py", &[b'#'; 8]), ("b.py", &[b'#'; 8])]);
+        assert!(unpack_tar(Cursor::new(tar), dest.path(), is_source, &mut Budget::new(heavy)).is_err());
+    }
+
+    #[test]
+    fn oversized_plain_tarball_is_rejected_before_it_is_written() {
+        let dir = tempfile::tempdir().unwrap();
+        let dest = tempfile::tempdir().unwrap();
+        let heavy = Limits { files: 10, bytes: 10, file_bytes: 16 };
+        let tar = write(dir.path(), "src.tar", &tar_bytes(&[("a.py", &[b'#'; 8]), ("b.py", &[b'#'; 8])]));
+        let err = extract_tar(&tar, dest.path(), is_source, &mut Budget::new(heavy)).unwrap_err();
+        assert!(err.to_string().contains("expands to more than"), "{err}");
+        assert!(read(dest.path(), "a.py").is_some());
+        assert!(read(dest.path(), "b.py").is_none(), "the entry over the budget is not written");
+    }
+
+    #[test]
+    fn zip_entries_cannot_inflate_past_their_declared_size() {
+        let dest = tempfile::tempdir().unwrap();
+        let mut zip = zip_bytes(&[("bomb.py", &[b'#'; 4096])], true);
+        // Declare 100 bytes in the central directory.
+        let central = zip.len() - 22 - (46 + "bomb.py".len());
+        zip[central + 24..central + 28].copy_from_slice(&100u32.to_le_bytes());
+        let err = unpack_zip(&mut Cursor::new(&zip), dest.path(), is_source, &mut Budget::new(SOURCE_LIMITS)).unwrap_err();
+        assert!(err.to_string().contains("declared size"), "{err}");
+    }
+
+    #[test]
+    fn rejects_non_zip() {
+        let dir = tempfile::tempdir().unwrap();
+        assert!(extract(&write(dir.path(), "bad.zip", b"not a zip")).is_err());
+    }
+}
@@ -1,13 +1,30 @@
-use std::path::PathBuf;
+use std::path::{Path, PathBuf};
+use std::time::{Duration, Instant};
 
 use anyhow::{Context, Result};
 use walkdir::WalkDir;
 
+use vibecheck_core::calibration::Calibration;
+use vibecheck_core::fingerprint;
 use vibecheck_core::ignore_rules::{IgnoreConfig, IgnoreRules};
+use vibecheck_core::language::{is_doc_file, SUPPORTED_EXTENSIONS};
+use vibecheck_core::language_pack;
+use vibecheck_core::limits;
 use vibecheck_core::output::OutputFormat;
-use vibecheck_core::report::{ModelFamily, Report};
+use vibecheck_core::package_style;
+use vibecheck_core::report::{ModelFamily, Report, Severity};
+use vibecheck_core::rollup::{self, Grouping, Rollup};
+use vibecheck_core::template_reuse;
+use vibecheck_core::timeout;
+use vibecheck_core::FileOptions;
 
+use crate::artifact;
+use crate::commands::trend;
 use crate::output;
+use crate::policy;
+use crate::progress::Progress;
+use crate::stats::ScanStats;
+use crate::summary;
 
 /// Collect all supported source files under `path`, respecting `ignore`.
 ///
@@ -19,7 +36,6 @@ pub fn collect_files(path: &PathBuf, ignore: &dyn IgnoreRules) -> Result<Vec<Pat
         return Ok(vec![path.clone()]);
     }
 
-    let supported_exts = ["rs", "py", "js", "ts", "jsx", "tsx", "go"];
     let mut files = Vec::new();
     for entry in WalkDir::new(path)
         .into_iter()
@@ -31,8 +47,9 @@ pub fn collect_files(path: &PathBuf, ignore: &dyn IgnoreRules) -> Result<Vec<Pat
         let p = entry.path();
         if p.extension()
             .and_then(|e| e.to_str())
-            .map(|e| supported_exts.contains(&e))
+            .map(|e| SUPPORTED_EXTENSIONS.contains(&e) || language_pack::is_pack_extension(e))
             .unwrap_or(false)
+            || is_doc_file(p)
         {
             files.push(p.to_path_buf());
         }
@@ -41,12 +58,40 @@ pub fn collect_files(path: &PathBuf, ignore: &dyn IgnoreRules) -> Result<Vec<Pat
     Ok(files)
 }
 
+/// The supported, non-ignored files among `listed` — file names passed by
+/// a hook such as pre-commit, which lists every staged file whatever its
+/// type.  Missing files (deleted in the commit) are dropped too.
+pub fn listed_files(listed: &[PathBuf], ignore: &dyn IgnoreRules) -> Vec<PathBuf> {
+    let mut files: Vec<PathBuf> = listed
+        .iter()
+        .filter(|p| p.is_file() && !ignore.is_ignored(p))
+        .filter(|p| {
+            p.extension()
+                .and_then(|e| e.to_str())
+                .map(|e| SUPPORTED_EXTENSIONS.contains(&e) || language_pack::is_pack_extension(e))
+                .unwrap_or(false)
+                || is_doc_file(p)
+        })
+        .cloned()
+        .collect();
+    files.sort();
+    files.dedup();
+    files
+}
+
 pub fn parse_format(s: &str) -> Result<OutputFormat> {
     match s {
         "pretty" => Ok(OutputFormat::Pretty),
         "text" => Ok(OutputFormat::Text),
         "json" => Ok(OutputFormat::Json),
-        other => anyhow::bail!("unknown format: {other} (expected pretty, text, or json)"),
+        "html" => Ok(OutputFormat::Html),
+        "markdown" | "md" => Ok(OutputFormat::Markdown),
+        "junit" => Ok(OutputFormat::Junit),
+        "provenance" | "spdx" => Ok(OutputFormat::Provenance),
+        "annotations" => Ok(OutputFormat::Annotations),
+        other => anyhow::bail!(
+            "unknown format: {other} (expected pretty, text, json, html, markdown, junit, provenance, or annotations)"
+        ),
     }
 }
 
@@ -64,11 +109,154 @@ pub fn parse_families(names: &[String]) -> Result<Vec<ModelFamily>> {
         .collect()
 }
 
-pub fn format_report(report: &Report, fmt: OutputFormat) -> String {
-    match fmt {
-        OutputFormat::Json => output::format_json(report),
-        OutputFormat::Text => output::format_text(report),
-        OutputFormat::Pretty => output::format_pretty(report, &vibecheck_core::colors::DefaultTheme),
+/// Parse a byte size: a plain number of bytes, or one with a `K`/`M`/`G`
+/// suffix (binary multiples; `KB`, `KiB`, `MB`, `MiB`, … are accepted too).
+pub fn parse_size(s: &str) -> Result<u64> {
+    let t = s.trim();
+    let split = t.find(|c: char| !c.is_ascii_digit() && c != '.').unwrap_or(t.len());
+    let (number, unit) = t.split_at(split);
+    let number: f64 = number.parse().map_err(|_| anyhow::anyhow!("invalid size: {s}"))?;
+    let scale: u64 = match unit.trim().to_ascii_lowercase().as_str() {
+        "" | "b" => 1,
+        "k" | "kb" | "kib" => 1 << 10,
+        "m" | "mb" | "mib" => 1 << 20,
+        "g" | "gb" | "gib" => 1 << 30,
+        _ => anyhow::bail!("invalid size unit in {s} (use K, M or G)"),
+    };
+    Ok((number * scale as f64) as u64)
+}
+
+/// Exit status when a CI gate (`--assert-family`, `--fail-over`,
+/// `--fail-on`) trips.  Errors exit with 2; see `main`.
+pub const EXIT_GATE_FAILED: i32 = 1;
+
+/// Exit with [`EXIT_GATE_FAILED`] if any gate trips, listing the reasons on
+/// stderr.  Unless `quiet`, a run that had gates and passed them says so.
+fn enforce_gates(reports: &[Report], allowed: Option<&[ModelFamily]>, fail_over: Option<f64>, fail_on: &[String], quiet: bool) {
+    if allowed.is_none() && fail_over.is_none() && fail_on.is_empty() {
+        return;
+    }
+    let failures = gate_failures(reports, allowed, fail_over, fail_on);
+    if !failures.is_empty() {
+        eprintln!("{}--- VIBECHECK FAILED ---", if quiet { "" } else { "\n" });
+        for failure in &failures {
+            eprintln!("  {failure}");
+        }
+        std::process::exit(EXIT_GATE_FAILED);
+    } else if !quiet {
+        eprintln!("\nAll files passed the vibe check.");
+    }
+}
+
+/// Reject `--fail-on` rules that match no signal in the catalogue, so a
+/// typo fails the run instead of silently never tripping.
+pub fn check_rules(rules: &[String]) -> Result<()> {
+    let catalogue = vibecheck_core::heuristics::all_heuristics();
+    for rule in rules {
+        if rule.parse::<Severity>().is_err() && !catalogue.iter().any(|h| rule_matches(rule, h.id)) {
+            anyhow::bail!("unknown rule in --fail-on: {rule} (see `vibecheck heuristics`, or use info, warn or error)");
+        }
+    }
+    Ok(())
+}
+
+/// A rule is a signal ID or a dotted prefix of one: `rust.comments`
+/// matches `rust.comments.step_numbered` but not `rust.comments_extra.x`.
+pub fn rule_matches(rule: &str, id: &str) -> bool {
+    id.strip_prefix(rule).is_some_and(|rest| rest.is_empty() || rest.starts_with('.'))
+}
+
+/// `--fail-over` and `--fail-on`, or the policy's defaults for them when
+/// not given (see `[policy]` and `--profile`).
+pub fn gate_settings(config: &IgnoreConfig, fail_over: Option<f64>, fail_on: &[String]) -> (Option<f64>, Vec<String>) {
+    let policy = config.policy();
+    let fail_on = if fail_on.is_empty() { policy.fail_on.clone() } else { fail_on.to_vec() };
+    (fail_over.or(policy.fail_over), fail_on)
+}
+
+/// One line per reason a CI gate trips, in report order:
+///
+/// * a file not attributed to one of `allowed` (files without signals are
+///   exempt);
+/// * a file whose AI probability is over `fail_over`;
+/// * each `fail_on` rule that fired in a file, where a severity (`warn`,
+///   `error`) stands for every AI-leaning rule graded at or above it.
+pub fn gate_failures(
+    reports: &[Report],
+    allowed: Option<&[ModelFamily]>,
+    fail_over: Option<f64>,
+    fail_on: &[String],
+) -> Vec<String> {
+    let mut failures = Vec::new();
+    for report in reports {
+        let path = report
+            .metadata
+            .file_path
+            .as_ref()
+            .map(|p| p.display().to_string())
+            .unwrap_or_else(|| "<stdin>".into());
+        if let Some(allowed) = allowed {
+            if report.metadata.signal_count > 0 && !allowed.contains(&report.attribution.primary) {
+                failures.push(format!(
+                    "{} — detected as {} ({:.0}%), expected one of: {}",
+                    path,
+                    report.attribution.primary,
+                    report.attribution.confidence * 100.0,
+                    allowed.iter().map(|f| f.to_string()).collect::<Vec<_>>().join(", "),
+                ));
+            }
+        }
+        if let (Some(limit), Some(p)) = (fail_over, report.attribution.ai_probability) {
+            if p > limit {
+                failures.push(format!("{path} — AI probability {p:.2} is over {limit:.2}"));
+            }
+        }
+        for signal in &report.signals {
+            let fired = fail_on.iter().any(|rule| match rule.parse::<Severity>() {
+                Ok(level) => signal.is_ai_leaning() && signal.severity >= level,
+                Err(_) => rule_matches(rule, &signal.id),
+            });
+            if fired {
+                let lines = signal.lines_label().map(|l| format!(" ({l})")).unwrap_or_default();
+                let severity = if signal.severity > Severity::Info { format!(" [{}]", signal.severity) } else { String::new() };
+                failures.push(format!("{path} — rule {} fired: {}{lines}{severity}", signal.id, signal.description));
+            }
+        }
+    }
+    failures
+}
+
+/// Format one report.  HTML charts its probability against `calibration`,
+/// the one it was computed under.
+pub fn format_report(report: &Report, fmt: OutputFormat, remediation: bool, calibration: &Calibration) -> String {
+    match (fmt, remediation) {
+        (OutputFormat::Json, false) => output::format_json(report),
+        (OutputFormat::Json, true) => output::format_json_with_remediation(report),
+        (OutputFormat::Text, false) => output::format_text(report),
+        (OutputFormat::Text, true) => {
+            output::format_text(report) + &output::format_remediation_text(report)
+        }
+        (OutputFormat::Junit, _) => {
+            vibecheck_core::output::format_junit(std::slice::from_ref(report), output::DEFAULT_JUNIT_THRESHOLD)
+        }
+        (OutputFormat::Markdown, _) => vibecheck_core::output::format_markdown(std::slice::from_ref(report)),
+        (OutputFormat::Annotations, _) => vibecheck_core::output::format_annotations(std::slice::from_ref(report)),
+        (OutputFormat::Provenance, _) => {
+            vibecheck_core::provenance::format_spdx(std::slice::from_ref(report), &[None], trend::now())
+        }
+        (OutputFormat::Html, _) => vibecheck_core::html::format_html(
+            std::slice::from_ref(report),
+            &[None],
+            remediation,
+            calibration,
+        ),
+        (OutputFormat::Pretty, _) => {
+            let mut out = output::format_pretty(report, &vibecheck_core::colors::DefaultTheme);
+            if remediation {
+                out.push_str(&output::format_remediation_pretty(report));
+            }
+            out
+        }
     }
 }
 
@@ -76,6 +264,7 @@ pub fn format_report(report: &Report, fmt: OutputFormat) -> String {
 mod tests {
     use super::*;
     use vibecheck_core::ignore_rules::PatternIgnore;
+    use vibecheck_core::report::Signal;
 
     #[test]
     fn parse_format_pretty() {
@@ -92,11 +281,135 @@ mod tests {
         assert_eq!(parse_format("json").unwrap(), OutputFormat::Json);
     }
 
+    #[test]
+    fn parse_format_html() {
+        assert_eq!(parse_format("html").unwrap(), OutputFormat::Html);
+    }
+
+    #[test]
+    fn parse_format_markdown() {
+        assert_eq!(parse_format("markdown").unwrap(), OutputFormat::Markdown);
+        assert_eq!(parse_format("md").unwrap(), OutputFormat::Markdown);
+    }
+
+    #[test]
+    fn parse_format_junit() {
+        assert_eq!(parse_format("junit").unwrap(), OutputFormat::Junit);
+    }
+
+    #[test]
+    fn parse_format_provenance() {
+        assert_eq!(parse_format("provenance").unwrap(), OutputFormat::Provenance);
+        assert_eq!(parse_format("spdx").unwrap(), OutputFormat::Provenance);
+    }
+
+    #[test]
+    fn parse_format_annotations() {
+        assert_eq!(parse_format("annotations").unwrap(), OutputFormat::Annotations);
+    }
+
+    fn gated_report(probability: f64, signals: Vec<Signal>) -> Report {
+        let mut report = vibecheck_core::analyze("fn main() {}");
+        report.metadata.file_path = Some(PathBuf::from("src/lib.rs"));
+        report.metadata.signal_count = signals.len();
+        report.attribution.primary = ModelFamily::Claude;
+        report.attribution.confidence = 0.8;
+        report.attribution.ai_probability = Some(probability);
+        report.signals = signals;
+        report
+    }
+
+    #[test]
+    fn gate_failures_fail_over_is_strict() {
+        let reports = [gated_report(0.8, vec![])];
+        assert!(gate_failures(&reports, None, Some(0.8), &[]).is_empty());
+        assert_eq!(
+            gate_failures(&reports, None, Some(0.5), &[]),
+            vec!["src/lib.rs — AI probability 0.80 is over 0.50"]
+        );
+    }
+
+    #[test]
+    fn gate_failures_fail_on_matches_ids_and_prefixes() {
+        let mut signal = Signal::new("rust.comments.step_numbered", "comments", "Step comments", ModelFamily::Gpt, 1.5);
+        signal.lines = vec![3];
+        let reports = [gated_report(0.1, vec![signal])];
+        let expected = vec!["src/lib.rs — rule rust.comments.step_numbered fired: Step comments (line 3)"];
+        assert_eq!(gate_failures(&reports, None, None, &["rust.comments.step_numbered".into()]), expected);
+        assert_eq!(gate_failures(&reports, None, None, &["rust.comments".into()]), expected);
+        assert!(gate_failures(&reports, None, None, &["rust.comment".into(), "rust.errors".into()]).is_empty());
+    }
+
+    #[test]
+    fn gate_failures_keeps_assert_family_message() {
+        let reports = [gated_report(0.9, vec![Signal::new("x", "x", "x", ModelFamily::Claude, 1.0)])];
+        assert_eq!(
+            gate_failures(&reports, Some(&[ModelFamily::Human]), None, &[]),
+            vec!["src/lib.rs — detected as Claude (80%), expected one of: Human"]
+        );
+        assert!(gate_failures(&reports, Some(&[ModelFamily::Claude]), None, &[]).is_empty());
+    }
+
+    #[test]
+    fn check_rules_rejects_unknown_rules() {
+        assert!(check_rules(&["rust.comments".into(), "go.errors.errorf_wrap".into()]).is_ok());
+        assert!(check_rules(&["rust.coments".into()]).is_err());
+        assert!(check_rules(&["warn".into(), "error".into()]).is_ok());
+    }
+
+    #[test]
+    fn gate_failures_fail_on_severity_takes_ai_leaning_rules_at_or_above() {
+        let mut warned = Signal::new("rust.comments.step_numbered", "comments", "Step comments", ModelFamily::Gpt, 1.5);
+        warned.severity = Severity::Warn;
+        let mut human = Signal::new("rust.errors.many_unwraps", "errors", "Unwraps", ModelFamily::Human, 2.0);
+        human.severity = Severity::Error;
+        let reports = [gated_report(0.1, vec![warned, human])];
+        assert!(gate_failures(&reports, None, None, &["error".into()]).is_empty());
+        assert_eq!(
+            gate_failures(&reports, None, None, &["warn".into()]),
+            vec!["src/lib.rs — rule rust.comments.step_numbered fired: Step comments [warn]"]
+        );
+    }
+
+    #[test]
+    fn rollup_table_lists_groups_with_verdicts() {
+        let mut reports = Vec::new();
+        for (path, family, p) in [
+            ("src/a.rs", ModelFamily::Claude, 0.9),
+            ("src/b.rs", ModelFamily::Gpt, 0.5),
+            ("src/c.rs", ModelFamily::Claude, 0.7),
+            ("tests/t.rs", ModelFamily::Human, 0.1),
+        ] {
+            let mut report = gated_report(p, vec![]);
+            report.metadata.file_path = Some(PathBuf::from(path));
+            report.attribution.primary = family;
+            reports.push(report);
+        }
+        let table = rollup_table(&rollup::rollup(&reports, &[], Grouping::Directory), Grouping::Directory);
+        let rows: Vec<&str> = table.lines().skip(2).collect();
+        assert_eq!(rows.len(), 2);
+        assert!(rows[0].starts_with("src "), "{table}");
+        assert!(rows[0].contains("0.70    0.70   0.90  Claude 2, GPT 1"), "{table}");
+        assert!(rows[1].starts_with("tests"), "{table}");
+        assert_eq!(rollup_table(&[], Grouping::Package), "(no Go packages found)\n");
+    }
+
     #[test]
     fn parse_format_unknown_is_error() {
         assert!(parse_format("csv").is_err());
     }
 
+    #[test]
+    fn parse_size_accepts_units() {
+        assert_eq!(parse_size("2048").unwrap(), 2048);
+        assert_eq!(parse_size("512K").unwrap(), 512 * 1024);
+        assert_eq!(parse_size("1MiB").unwrap(), 1 << 20);
+        assert_eq!(parse_size("1.5 GB").unwrap(), 3 << 29);
+        assert_eq!(parse_size("0").unwrap(), 0);
+        assert!(parse_size("10 parsecs").is_err());
+        assert!(parse_size("MB").is_err());
+    }
+
     #[test]
     fn parse_families_known() {
         let input = vec!["claude".into(), "gpt".into(), "human".into()];
@@ -137,7 +450,7 @@ mod tests {
         for f in &files {
             let ext = f.extension().unwrap().to_str().unwrap();
             assert!(
-                ["rs", "py", "js", "go"].contains(&ext),
+                ["rs", "py", "js", "go", "java"].contains(&ext),
                 "unexpected extension: {ext}"
             );
         }
@@ -158,83 +471,285 @@ mod tests {
         }
     }
 
+    #[test]
+    fn listed_files_keeps_supported_existing_files() {
+        let fixture_dir = PathBuf::from(env!("CARGO_MANIFEST_DIR"))
+            .join("../vibecheck-core/tests/fixtures/lru_cache");
+        let listed = vec![
+            fixture_dir.join("gpt.py"),
+            fixture_dir.join("claude.rs"),
+            fixture_dir.join("claude.rs"),
+            fixture_dir.join("deleted.rs"),
+            PathBuf::from(env!("CARGO_MANIFEST_DIR")).join("Cargo.toml"),
+        ];
+        let files = listed_files(&listed, &PatternIgnore(vec![]));
+        assert_eq!(files, vec![fixture_dir.join("claude.rs"), fixture_dir.join("gpt.py")]);
+        assert!(listed_files(&listed, &PatternIgnore(vec!["claude".into(), "gpt".into()])).is_empty());
+    }
+
     #[test]
     fn format_report_text_contains_verdict() {
         let report = vibecheck_core::analyze("fn main() { println!(\"hello\"); }");
-        let output = format_report(&report, OutputFormat::Text);
+        let output = format_report(&report, OutputFormat::Text, false, &Calibration::default());
         assert!(output.contains("Verdict:"), "text output should have Verdict");
     }
 
     #[test]
     fn format_report_json_is_valid() {
         let report = vibecheck_core::analyze("fn main() {}");
-        let output = format_report(&report, OutputFormat::Json);
+        let output = format_report(&report, OutputFormat::Json, false, &Calibration::default());
         let _: serde_json::Value = serde_json::from_str(&output).expect("should be valid JSON");
     }
 
     #[test]
     fn format_report_pretty_contains_verdict() {
         let report = vibecheck_core::analyze("fn main() { println!(\"hello\"); }");
-        let output = format_report(&report, OutputFormat::Pretty);
+        let output = format_report(&report, OutputFormat::Pretty, false, &Calibration::default());
         assert!(output.contains("Verdict:"), "pretty output should have Verdict");
     }
+
+    #[test]
+    fn format_report_json_remediation_adds_field() {
+        let report = vibecheck_core::analyze("fn main() {}");
+        let output = format_report(&report, OutputFormat::Json, true, &Calibration::default());
+        let value: serde_json::Value = serde_json::from_str(&output).expect("should be valid JSON");
+        assert!(value["remediation"].is_array());
+    }
+}
+
+/// One row per group: file count, mean, median and max AI probability,
+/// and how many files each family was attributed.
+fn rollup_table(rollups: &[Rollup], grouping: Grouping) -> String {
+    let (heading, empty) = match grouping {
+        Grouping::Directory => ("DIRECTORY", "(no analyzed files)\n"),
+        Grouping::Package => ("PACKAGE", "(no Go packages found)\n"),
+    };
+    if rollups.is_empty() {
+        return empty.to_string();
+    }
+    let width = rollups.iter().map(|r| r.label().chars().count()).max().unwrap_or(0).max(heading.len());
+    let mut out = format!(
+        "{heading:<width$}  {:>5}  {:>5}  {:>6}  {:>5}  VERDICTS\n",
+        "FILES", "MEAN", "MEDIAN", "MAX"
+    );
+    out.push_str(&format!("{}\n", "─".repeat(width + 36)));
+    for r in rollups {
+        let stats = if r.scored == 0 {
+            format!("{:>5}  {:>6}  {:>5}", "—", "—", "—")
+        } else {
+            format!("{:>5.2}  {:>6.2}  {:>5.2}", r.mean, r.median, r.max)
+        };
+        let mut verdicts: Vec<(&ModelFamily, &usize)> = r.verdicts.iter().collect();
+        verdicts.sort_by(|a, b| b.1.cmp(a.1).then_with(|| a.0.cmp(b.0)));
+        let verdicts: Vec<String> = verdicts.iter().map(|(f, n)| format!("{f} {n}")).collect();
+        out.push_str(&format!("{:<width$}  {:>5}  {stats}  {}\n", r.label(), r.files, verdicts.join(", ")));
+    }
+    out
+}
+
+/// Analyze one file with the cache mode the flags select.
+fn analyze_one(
+    file: &Path,
+    symbols: bool,
+    segments: bool,
+    strip_comments: bool,
+    cache_dir: Option<&Path>,
+    no_cache: bool,
+) -> Result<Report> {
+    let options = FileOptions { cache_dir, no_cache: no_cache && cache_dir.is_none(), ..policy::file_options() };
+    let mut report = if symbols {
+        vibecheck_core::analyze_file_symbols_with(file, &options)?
+    } else {
+        vibecheck_core::analyze_file_with(file, &options)?
+    };
+    if segments {
+        report.segments = Some(vibecheck_core::segment_file_with(file, &options)?);
+    }
+    if strip_comments {
+        report.comment_free = Some(vibecheck_core::score_without_comments_with(file, &options)?);
+    }
+    Ok(report)
 }
 
+#[allow(clippy::too_many_arguments)]
 pub fn run(
     path: &PathBuf,
+    listed: Option<&[PathBuf]>,
+    quiet: bool,
     format: &str,
     no_cache: bool,
+    cache_dir: Option<&PathBuf>,
     symbols: bool,
+    segments: bool,
+    strip_comments: bool,
+    remediation: bool,
+    stats: bool,
+    group_by: Option<&str>,
     assert_family: Option<Vec<String>>,
     ignore_file: Option<&PathBuf>,
+    exclude: &[String],
+    include_generated: bool,
+    threshold: f64,
+    fail_over: Option<f64>,
+    fail_on: &[String],
+    timeout_per_file: Option<f64>,
+    max_file_size: &str,
+    max_memory_hint: Option<&str>,
 ) -> Result<()> {
     let fmt = parse_format(format)?;
     let allowed_families = assert_family
         .as_ref()
         .map(|f| parse_families(f))
         .transpose()?;
+    if let Some(limit) = fail_over {
+        anyhow::ensure!((0.0..=1.0).contains(&limit), "--fail-over must be between 0 and 1, got {limit}");
+    }
+    let grouping = match group_by {
+        None => None,
+        Some("dir") => Some(Grouping::Directory),
+        Some("package") => Some(Grouping::Package),
+        Some(other) => anyhow::bail!("unknown --group-by key: {other} (expected dir or package)"),
+    };
+    if grouping.is_some() && !matches!(fmt, OutputFormat::Pretty | OutputFormat::Text | OutputFormat::Json) {
+        anyhow::bail!("--group-by supports pretty, text and json output, not {format}");
+    }
+    let timeout_per_file = match timeout_per_file {
+        Some(secs) if !(secs > 0.0 && secs.is_finite()) => {
+            anyhow::bail!("--timeout-per-file must be a positive number of seconds, got {secs}")
+        }
+        secs => secs.map(Duration::from_secs_f64),
+    };
+    let max_file_size = limits::effective_max_file_size(
+        Some(parse_size(max_file_size).context("--max-file-size")?).filter(|&n| n > 0),
+        max_memory_hint.map(parse_size).transpose().context("--max-memory-hint")?,
+    );
+
+    let started = Instant::now();
+    let extracted = if artifact::is_artifact(path) {
+        Some(artifact::extract(path)?)
+    } else {
+        None
+    };
+    let scan_root = extracted.as_ref().map(|e| e.root().to_path_buf()).unwrap_or_else(|| path.clone());
 
-    let ignore: Box<dyn IgnoreRules> = match ignore_file {
-        Some(f) => Box::new(IgnoreConfig::from_file(f)?),
-        None => Box::new(IgnoreConfig::load(path)),
+    let config = match ignore_file {
+        Some(f) => policy::from_file(f)?,
+        None => policy::load(&scan_root),
     };
+    let (fail_over, fail_on) = gate_settings(&config, fail_over, fail_on);
+    check_rules(&fail_on)?;
+    let calibration = config.calibration();
+    let ignore: Box<dyn IgnoreRules> = Box::new(config.with_excludes(exclude));
 
-    let files = collect_files(path, ignore.as_ref()).context("failed to collect files")?;
+    let mut files = match listed {
+        Some(listed) => listed_files(listed, ignore.as_ref()),
+        None => collect_files(&scan_root, ignore.as_ref()).context("failed to collect files")?,
+    };
+    // A file named explicitly is analyzed even if generated; a hook's list
+    // is every staged file, so it is filtered like a directory.
+    let mut skipped_generated = 0;
+    if (listed.is_some() || scan_root.is_dir()) && !include_generated && !ignore.include_generated() {
+        let before = files.len();
+        files.retain(|f| !vibecheck_core::generated::is_generated_file(f));
+        skipped_generated = before - files.len();
+    }
+    let collected = Instant::now();
 
     if files.is_empty() {
+        // Nothing a hook passed is ours to check: that is a pass.
+        if listed.is_some() {
+            return Ok(());
+        }
+        if skipped_generated > 0 {
+            anyhow::bail!(
+                "no hand-written source files found in {} ({skipped_generated} generated; pass --include-generated)",
+                path.display()
+            );
+        }
         anyhow::bail!("no supported source files found in {}", path.display());
     }
 
-    let reports: Vec<Report> = if symbols {
-        let symbol_fn: fn(&std::path::Path) -> anyhow::Result<Report> = if no_cache {
-            vibecheck_core::analyze_file_symbols_no_cache
-        } else {
-            vibecheck_core::analyze_file_symbols
-        };
-        files
-            .iter()
-            .map(|f| symbol_fn(f).map_err(|e| std::io::Error::other(e.to_string())))
-            .collect::<std::io::Result<Vec<_>>>()
-            .context("failed to analyze files")?
-    } else {
-        let analyze_fn: fn(&std::path::Path) -> std::io::Result<Report> = if no_cache {
-            vibecheck_core::analyze_file_no_cache
-        } else {
-            vibecheck_core::analyze_file
-        };
+    let progress = if quiet { Progress::hidden() } else { Progress::stderr(files.len()) };
+    let cache_dir = cache_dir.map(|d| d.as_path());
+    let analyzed_files: Result<Vec<Report>> = files
+        .iter()
+        .enumerate()
+        .map(|(done, f)| {
+            progress.update(done, f);
+            if max_file_size.is_some_and(|limit| std::fs::metadata(f).is_ok_and(|m| m.len() > limit)) {
+                return Ok(Report::skipped(f.clone(), limits::SKIPPED_TOO_LARGE));
+            }
+            match timeout_per_file {
+                None => analyze_one(f, symbols, segments, strip_comments, cache_dir, no_cache),
+                Some(limit) => {
+                    let (file, dir) = (f.clone(), cache_dir.map(PathBuf::from));
+                    match timeout::run(limit, move || {
+                        analyze_one(&file, symbols, segments, strip_comments, dir.as_deref(), no_cache)
+                    }) {
+                        Some(report) => report,
+                        None => Ok(Report::skipped(f.clone(), timeout::SKIPPED_TIMEOUT)),
+                    }
+                }
+            }
+        })
+        .collect();
+    progress.finish();
+    let mut reports = analyzed_files.context("failed to analyze files")?;
+
+    if let Some(ref extracted) = extracted {
+        for report in &mut reports {
+            if let Some(ref p) = report.metadata.file_path {
+                report.metadata.file_path = Some(extracted.display_path(p));
+            }
+        }
+    }
+
+    let analyzed = Instant::now();
+
+    if quiet {
+        enforce_gates(&reports, allowed_families.as_deref(), fail_over, &fail_on, true);
+        return Ok(());
+    }
+
+    // Each report's source, for outputs that need more than the report.
+    let sources = || -> Vec<Option<String>> {
         files
             .iter()
-            .map(|f| analyze_fn(f))
-            .collect::<std::io::Result<Vec<_>>>()
-            .context("failed to analyze files")?
+            .zip(&reports)
+            .map(|(f, r)| r.metadata.skipped.is_none().then(|| std::fs::read_to_string(f).ok()).flatten())
+            .collect()
     };
 
-    if fmt == OutputFormat::Json && reports.len() > 1 {
-        let json = serde_json::to_string_pretty(&reports)?;
+    if let Some(grouping) = grouping {
+        let sources = if grouping == Grouping::Package { sources() } else { Vec::new() };
+        let rollups = rollup::rollup(&reports, &sources, grouping);
+        if fmt == OutputFormat::Json {
+            println!("{}", serde_json::to_string_pretty(&rollups)?);
+        } else {
+            print!("{}", rollup_table(&rollups, grouping));
+        }
+    } else if fmt == OutputFormat::Html {
+        // One document for the whole scan, with each file's source.
+        print!("{}", vibecheck_core::html::format_html(&reports, &sources(), remediation, &calibration));
+    } else if fmt == OutputFormat::Junit {
+        print!("{}", vibecheck_core::output::format_junit(&reports, threshold));
+    } else if fmt == OutputFormat::Markdown {
+        print!("{}", vibecheck_core::output::format_markdown(&reports));
+    } else if fmt == OutputFormat::Annotations {
+        print!("{}", vibecheck_core::output::format_annotations(&reports));
+    } else if fmt == OutputFormat::Provenance {
+        println!("{}", vibecheck_core::provenance::format_spdx(&reports, &sources(), trend::now()));
+    } else if fmt == OutputFormat::Json && reports.len() > 1 {
+        let json = if remediation {
+            let values: Vec<_> = reports.iter().map(vibecheck_core::output::json_with_remediation).collect();
+            serde_json::to_string_pretty(&values)?
+        } else {
+            serde_json::to_string_pretty(&reports)?
+        };
         println!("{json}");
     } else if symbols {
         for report in &reports {
-            println!("{}", format_report(report, fmt));
+            println!("{}", format_report(report, fmt, remediation, &calibration));
             if let Some(ref sym_reports) = report.symbol_reports {
                 if !sym_reports.is_empty() {
                     println!("  Symbol-level attribution:");
@@ -253,42 +768,72 @@ pub fn run(
         }
     } else {
         for report in &reports {
-            println!("{}", format_report(report, fmt));
+            println!("{}", format_report(report, fmt, remediation, &calibration));
         }
     }
 
-    if let Some(ref allowed) = allowed_families {
-        let mut failures = Vec::new();
-        for report in &reports {
-            if report.metadata.signal_count == 0 {
-                continue;
+    if let Some(warning) = summary::degraded_warning(&reports) {
+        eprint!("\n{warning}");
+    }
+
+    if let Some(note) = summary::generated_note(skipped_generated) {
+        eprint!("\n{note}");
+    }
+
+    if let Some(note) = summary::skipped_note(&reports) {
+        eprint!("\n{note}");
+    }
+
+    if fmt == OutputFormat::Pretty || fmt == OutputFormat::Text {
+        if files.len() > 1 {
+            // Cross-file detectors need every source again; the reports
+            // only keep what each file scored on its own.
+            let sources: Vec<(PathBuf, String)> = files
+                .iter()
+                .zip(&reports)
+                .filter(|(_, r)| r.metadata.skipped.is_none())
+                .filter_map(|(f, r)| {
+                    let display = r.metadata.file_path.clone().unwrap_or_else(|| f.clone());
+                    Some((display, std::fs::read_to_string(f).ok()?))
+                })
+                .collect();
+            if let Some(note) = summary::template_reuse_note(&template_reuse::detect(&sources)) {
+                eprint!("\n{note}");
             }
-            if !allowed.contains(&report.attribution.primary) {
-                failures.push(report);
+            if let Some(note) = summary::style_outlier_note(&fingerprint::outliers(&sources)) {
+                eprint!("\n{note}");
             }
-        }
-        if !failures.is_empty() {
-            eprintln!("\n--- VIBECHECK FAILED ---");
-            for report in &failures {
-                let path = report
-                    .metadata
-                    .file_path
-                    .as_ref()
-                    .map(|p| p.display().to_string())
-                    .unwrap_or_else(|| "<stdin>".into());
-                eprintln!(
-                    "  {} — detected as {} ({:.0}%), expected one of: {}",
-                    path,
-                    report.attribution.primary,
-                    report.attribution.confidence * 100.0,
-                    allowed.iter().map(|f| f.to_string()).collect::<Vec<_>>().join(", "),
-                );
+            if let Some(note) = summary::package_style_note(&package_style::divergent_files(&sources)) {
+                eprint!("\n{note}");
             }
-            std::process::exit(1);
+        }
+        if let Some(note) = summary::comment_reliance_note(&reports) {
+            eprint!("\n{note}");
+        }
+        if let Some(note) = summary::disagreement_note(&reports) {
+            eprint!("\n{note}");
+        }
+        if let Some(steps) = summary::next_steps(&reports) {
+            eprint!("\n{steps}");
+        }
+    }
+
+    if stats {
+        let scan = ScanStats::capture(
+            files.len(),
+            vec![
+                ("collect", collected - started),
+                ("analyze", analyzed - collected),
+                ("output", analyzed.elapsed()),
+            ],
+        );
+        if fmt == OutputFormat::Json {
+            eprintln!("{}", serde_json::to_string_pretty(&scan.to_json())?);
         } else {
-            eprintln!("\nAll files passed the vibe check.");
+            eprint!("\n{}", scan.render_text());
         }
     }
 
+    enforce_gates(&reports, allowed_families.as_deref(), fail_over, &fail_on, false);
     Ok(())
 }
@@ -0,0 +1,299 @@
+use std::collections::BTreeMap;
+use std::path::{Path, PathBuf};
+use std::time::{Duration, Instant};
+
+use anyhow::{bail, Context, Result};
+use walkdir::WalkDir;
+
+use vibecheck_core::language::SUPPORTED_EXTENSIONS;
+use vibecheck_core::language_pack;
+use vibecheck_core::pipeline::Profiler;
+
+use crate::alloc::AllocCount;
+use crate::policy;
+use crate::pprof;
+
+/// One detector stage on one file in one iteration.
+struct Sample {
+    stage: String,
+    file: usize,
+    elapsed: Duration,
+    alloc: AllocCount,
+}
+
+/// Records each stage [`vibecheck_core::profile_file`] runs.
+struct Recorder {
+    file: usize,
+    open: Option<(Instant, AllocCount)>,
+    samples: Vec<Sample>,
+}
+
+impl Profiler for Recorder {
+    fn begin(&mut self, _stage: &str) {
+        self.open = Some((Instant::now(), AllocCount::now()));
+    }
+
+    fn end(&mut self, stage: &str) {
+        if let Some((start, alloc)) = self.open.take() {
+            self.samples.push(Sample {
+                stage: stage.to_string(),
+                file: self.file,
+                elapsed: start.elapsed(),
+                alloc: AllocCount::now().since(alloc),
+            });
+        }
+    }
+}
+
+/// Latency and allocation totals for one detector across the corpus.
+struct DetectorStats {
+    detector: String,
+    runs: usize,
+    total: Duration,
+    mean: Duration,
+    p95: Duration,
+    max: Duration,
+    /// The file of the slowest run.
+    slowest: usize,
+    allocations: u64,
+    bytes: u64,
+}
+
+pub fn run(corpus: &Path, iterations: usize, format: &str, pprof_out: Option<&Path>) -> Result<()> {
+    let iterations = iterations.max(1);
+    let files = corpus_files(corpus)?;
+    if files.is_empty() {
+        bail!("no supported source files under {}", corpus.display());
+    }
+
+    let mut recorder = Recorder { file: 0, open: None, samples: Vec::new() };
+    let mut skipped = 0;
+    let started = Instant::now();
+    for _ in 0..iterations {
+        for (i, path) in files.iter().enumerate() {
+            recorder.file = i;
+            match vibecheck_core::profile_file_with(path, &mut recorder, &policy::file_options()) {
+                Ok(_) => {}
+                Err(e) if e.kind() == std::io::ErrorKind::InvalidData => skipped += 1,
+                Err(e) => return Err(e).with_context(|| format!("failed to analyze {}", path.display())),
+            }
+        }
+    }
+    let elapsed = started.elapsed();
+    let stats = summarize(&recorder.samples);
+    let skipped = skipped / iterations;
+
+    match format {
+        "json" => println!("{}", serde_json::to_string_pretty(&to_json(&stats, &files, iterations, elapsed))?),
+        _ => print_text(&stats, &files, iterations, skipped, elapsed),
+    }
+
+    if let Some(out) = pprof_out {
+        let mut profile = pprof::Profile::new(&[
+            ("wall", "nanoseconds"),
+            ("alloc_objects", "count"),
+            ("alloc_space", "bytes"),
+        ]);
+        for s in &recorder.samples {
+            let file = files[s.file].display().to_string();
+            profile.add(
+                &[&s.stage, &file],
+                &[s.elapsed.as_nanos() as i64, s.alloc.allocations as i64, s.alloc.bytes as i64],
+            );
+        }
+        profile.set_duration(elapsed);
+        profile.write(out)?;
+        eprintln!("Wrote {} (view with `go tool pprof -http=: {}`)", out.display(), out.display());
+    }
+    Ok(())
+}
+
+/// `corpus` itself if it is a file, else every file under it with a
+/// built-in or language-pack extension, sorted.
+fn corpus_files(corpus: &Path) -> Result<Vec<PathBuf>> {
+    if corpus.is_file() {
+        return Ok(vec![corpus.to_path_buf()]);
+    }
+    if !corpus.is_dir() {
+        bail!("corpus {} does not exist", corpus.display());
+    }
+    let packs = language_pack::installed();
+    let mut files = Vec::new();
+    for entry in WalkDir::new(corpus) {
+        let entry = entry.with_context(|| format!("failed to read corpus {}", corpus.display()))?;
+        let path = entry.path();
+        let supported = path
+            .extension()
+            .and_then(|e| e.to_str())
+            .is_some_and(|e| SUPPORTED_EXTENSIONS.contains(&e))
+            || packs.iter().any(|p| p.matches(path));
+        if entry.file_type().is_file() && supported {
+            files.push(path.to_path_buf());
+        }
+    }
+    files.sort();
+    Ok(files)
+}
+
+/// Per-detector statistics, slowest total first.
+fn summarize(samples: &[Sample]) -> Vec<DetectorStats> {
+    let mut by_stage: BTreeMap<&str, Vec<&Sample>> = BTreeMap::new();
+    for s in samples {
+        by_stage.entry(&s.stage).or_default().push(s);
+    }
+    let mut stats: Vec<DetectorStats> = by_stage
+        .into_iter()
+        .map(|(stage, runs)| {
+            let mut times: Vec<Duration> = runs.iter().map(|s| s.elapsed).collect();
+            times.sort();
+            let total: Duration = times.iter().sum();
+            let slowest = runs.iter().max_by_key(|s| s.elapsed).expect("at least one run");
+            DetectorStats {
+                detector: stage.to_string(),
+                runs: runs.len(),
+                total,
+                mean: total / runs.len() as u32,
+                p95: times[(times.len() * 95).div_ceil(100) - 1],
+                max: slowest.elapsed,
+                slowest: slowest.file,
+                allocations: runs.iter().map(|s| s.alloc.allocations).sum(),
+                bytes: runs.iter().map(|s| s.alloc.bytes).sum(),
+            }
+        })
+        .collect();
+    stats.sort_by(|a, b| b.total.cmp(&a.total).then_with(|| a.detector.cmp(&b.detector)));
+    stats
+}
+
+fn micros(d: Duration) -> f64 {
+    d.as_secs_f64() * 1e6
+}
+
+fn print_text(stats: &[DetectorStats], files: &[PathBuf], iterations: usize, skipped: usize, elapsed: Duration) {
+    println!(
+        "Benchmarked {} files × {} iteration{} in {:.2} s",
+        files.len(),
+        iterations,
+        if iterations == 1 { "" } else { "s" },
+        elapsed.as_secs_f64()
+    );
+    if skipped > 0 {
+        println!("({skipped} non-UTF-8 files skipped)");
+    }
+    let grand_total: Duration = stats.iter().map(|s| s.total).sum();
+
+    println!();
+    println!(
+        "{:<12}  {:>6}  {:>10}  {:>6}  {:>9}  {:>9}  {:>9}  {:>9}  {:>9}",
+        "DETECTOR", "RUNS", "TOTAL ms", "SHARE", "MEAN µs", "P95 µs", "MAX µs", "ALLOCS", "ALLOC KiB"
+    );
+    println!("{}", "─".repeat(98));
+    for s in stats {
+        let share = if grand_total.is_zero() { 0.0 } else { s.total.as_secs_f64() / grand_total.as_secs_f64() };
+        println!(
+            "{:<12}  {:>6}  {:>10.1}  {:>5.1}%  {:>9.1}  {:>9.1}  {:>9.1}  {:>9}  {:>9.1}",
+            s.detector,
+            s.runs,
+            s.total.as_secs_f64() * 1e3,
+            share * 100.0,
+            micros(s.mean),
+            micros(s.p95),
+            micros(s.max),
+            s.allocations,
+            s.bytes as f64 / 1024.0
+        );
+    }
+
+    println!();
+    println!("Slowest file per detector:");
+    for s in stats {
+        println!("  {:<12}  {:>9.1} µs  {}", s.detector, micros(s.max), files[s.slowest].display());
+    }
+    if let Some(b) = crate::stats::peak_rss_bytes() {
+        println!();
+        println!("Peak RSS: {:.1} MiB", b as f64 / (1024.0 * 1024.0));
+    }
+}
+
+fn to_json(stats: &[DetectorStats], files: &[PathBuf], iterations: usize, elapsed: Duration) -> serde_json::Value {
+    let detectors: Vec<serde_json::Value> = stats
+        .iter()
+        .map(|s| {
+            serde_json::json!({
+                "detector": s.detector,
+                "runs": s.runs,
+                "total_ns": s.total.as_nanos() as u64,
+                "mean_ns": s.mean.as_nanos() as u64,
+                "p95_ns": s.p95.as_nanos() as u64,
+                "max_ns": s.max.as_nanos() as u64,
+                "slowest_file": files[s.slowest],
+                "allocations": s.allocations,
+                "allocated_bytes": s.bytes,
+            })
+        })
+        .collect();
+    serde_json::json!({
+        "files": files.len(),
+        "iterations": iterations,
+        "elapsed_ms": elapsed.as_millis() as u64,
+        "peak_rss_bytes": crate::stats::peak_rss_bytes(),
+        "detectors": detectors,
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn sample(stage: &str, file: usize, micros: u64, bytes: u64) -> Sample {
+        Sample {
+            stage: stage.to_string(),
+            file,
+            elapsed: Duration::from_micros(micros),
+            alloc: AllocCount { allocations: 1, bytes },
+        }
+    }
+
+    #[test]
+    fn summarize_ranks_detectors_by_total_time() {
+        let mut samples: Vec<Sample> = (0..20).map(|i| sample("naming", i % 2, 10 + i as u64, 100)).collect();
+        samples.push(sample("parse", 1, 5000, 8));
+        let stats = summarize(&samples);
+
+        assert_eq!(stats[0].detector, "parse");
+        assert_eq!((stats[0].runs, stats[0].max, stats[0].slowest), (1, Duration::from_micros(5000), 1));
+        let naming = &stats[1];
+        assert_eq!(naming.runs, 20);
+        assert_eq!(naming.total, Duration::from_micros(390));
+        assert_eq!(naming.mean, Duration::from_micros(19) + Duration::from_nanos(500));
+        assert_eq!(naming.p95, Duration::from_micros(28));
+        assert_eq!((naming.max, naming.slowest), (Duration::from_micros(29), 1));
+        assert_eq!((naming.allocations, naming.bytes), (20, 2000));
+    }
+
+    #[test]
+    fn corpus_files_keeps_supported_sources() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::create_dir(dir.path().join("src")).unwrap();
+        for name in ["src/b.rs", "a.py", "notes.txt"] {
+            std::fs::write(dir.path().join(name), "x").unwrap();
+        }
+        let files = corpus_files(dir.path()).unwrap();
+        assert_eq!(files, [dir.path().join("a.py"), dir.path().join("src/b.rs")]);
+        assert_eq!(corpus_files(&files[0]).unwrap(), [files[0].clone()]);
+        assert!(corpus_files(&dir.path().join("missing")).is_err());
+    }
+
+    #[test]
+    fn recorder_attributes_stages_to_the_current_file() {
+        let dir = tempfile::tempdir().unwrap();
+        let path = dir.path().join("main.rs");
+        std::fs::write(&path, "fn main() {}\n").unwrap();
+        let mut recorder = Recorder { file: 3, open: None, samples: Vec::new() };
+        vibecheck_core::profile_file(&path, &mut recorder).unwrap();
+
+        assert!(recorder.samples.iter().all(|s| s.file == 3));
+        let stages: Vec<&str> = recorder.samples.iter().map(|s| s.stage.as_str()).collect();
+        assert!(stages.contains(&"naming") && stages.ends_with(&["classify"]), "{stages:?}");
+    }
+}
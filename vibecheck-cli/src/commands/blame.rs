@@ -0,0 +1,363 @@
+use std::collections::{BTreeMap, HashMap};
+use std::path::{Path, PathBuf};
+
+use anyhow::{Context, Result};
+use git2::{BlameHunk, Oid, Repository};
+use serde_json::{json, Value};
+
+use vibecheck_core::ignore_rules::IgnoreRules;
+use vibecheck_core::report::{ModelFamily, Report};
+
+use crate::commands::analyze::collect_files;
+use crate::commands::history::{format_date, relative_path};
+use crate::policy;
+
+/// Flagged lines of one file that one blame hunk covers: the first to the
+/// last of them, whatever lies between.
+#[derive(Debug, Clone, PartialEq)]
+struct Region {
+    /// Path relative to the repository work tree.
+    path: String,
+    start_line: usize,
+    end_line: usize,
+    /// AI-leaning signals pointing into the region, in order of first line.
+    signals: Vec<String>,
+}
+
+/// The commit that introduced a line, per `git blame`.
+#[derive(Debug, Clone, PartialEq)]
+struct Origin {
+    /// `None` for lines changed in the work tree but not committed.
+    commit: Option<String>,
+    author: String,
+    email: String,
+    time: i64,
+    summary: String,
+}
+
+impl Origin {
+    fn uncommitted() -> Self {
+        Origin { commit: None, author: String::new(), email: String::new(), time: 0, summary: String::new() }
+    }
+}
+
+/// Blame the flagged lines of every source file under `path` and print the
+/// regions they form, grouped by the commit that introduced them:
+/// uncommitted changes first, then newest commit first.
+pub fn run(path: &Path, format: &str, ignore_file: Option<&PathBuf>, exclude: &[String]) -> Result<()> {
+    let repo = Repository::discover(path).context("not inside a git repository (or no .git found)")?;
+    let config = match ignore_file {
+        Some(f) => policy::from_file(f)?,
+        None => policy::load(path),
+    };
+    let ignore = config.with_excludes(exclude);
+    let mut files = collect_files(&path.to_path_buf(), &ignore).context("failed to collect files")?;
+    if path.is_dir() && !ignore.include_generated() {
+        files.retain(|f| !vibecheck_core::generated::is_generated_file(f));
+    }
+
+    let mut commits: HashMap<Oid, Origin> = HashMap::new();
+    let mut found = Vec::new();
+    for file in &files {
+        let report = vibecheck_core::analyze_file_with(file, &policy::file_options()).with_context(|| format!("failed to analyze {}", file.display()))?;
+        let flagged = flagged_lines(&report);
+        if flagged.is_empty() {
+            continue;
+        }
+        let relative = relative_path(&repo, file)?;
+        let content = std::fs::read(file).with_context(|| format!("cannot read {}", file.display()))?;
+        // Blame the file as it is on disk, so lines changed since HEAD are
+        // reported as uncommitted rather than pinned on the wrong commit.
+        let committed = repo.blame_file(&relative, None).ok();
+        let blame = committed.as_ref().and_then(|b| b.blame_buffer(&content).ok());
+        let origin_of = |line: usize| match blame.as_ref().and_then(|b| b.get_line(line)) {
+            Some(hunk) => (hunk.final_start_line(), origin(&repo, &hunk, &mut commits)),
+            // Untracked files have no blame at all.
+            None => (0, Origin::uncommitted()),
+        };
+        let label = relative.to_string_lossy().replace('\\', "/");
+        found.extend(regions(&label, &flagged, origin_of));
+    }
+    let groups = by_commit(found);
+
+    let label = match relative_path(&repo, path)? {
+        p if p.as_os_str().is_empty() => ".".to_string(),
+        p => p.display().to_string(),
+    };
+    match format {
+        "json" => println!("{}", serde_json::to_string_pretty(&to_json(&label, &groups))?),
+        _ => print!("{}", render_text(&label, &groups)),
+    }
+    Ok(())
+}
+
+/// Lines that AI-leaning signals point at in a file attributed to AI, each
+/// with the ids of those signals.  Files attributed to humans, or without
+/// enough data for a verdict, have none.
+fn flagged_lines(report: &Report) -> BTreeMap<usize, Vec<String>> {
+    let mut lines: BTreeMap<usize, Vec<String>> = BTreeMap::new();
+    if !report.attribution.has_sufficient_data() || report.attribution.primary == ModelFamily::Human {
+        return lines;
+    }
+    for signal in report.signals.iter().filter(|s| s.family != ModelFamily::Human && s.weight > 0.0) {
+        for &line in &signal.lines {
+            let ids = lines.entry(line).or_default();
+            if !ids.contains(&signal.id) {
+                ids.push(signal.id.clone());
+            }
+        }
+    }
+    lines
+}
+
+/// The origin of a blamed hunk, looking each commit up once.
+fn origin(repo: &Repository, hunk: &BlameHunk<'_>, commits: &mut HashMap<Oid, Origin>) -> Origin {
+    let oid = hunk.final_commit_id();
+    if oid.is_zero() {
+        return Origin::uncommitted();
+    }
+    commits
+        .entry(oid)
+        .or_insert_with(|| {
+            let signature = hunk.final_signature();
+            Origin {
+                commit: Some(oid.to_string()),
+                author: signature.name().unwrap_or_default().to_string(),
+                email: signature.email().unwrap_or_default().to_string(),
+                time: signature.when().seconds(),
+                summary: repo
+                    .find_commit(oid)
+                    .ok()
+                    .and_then(|c| c.summary().map(String::from))
+                    .unwrap_or_default(),
+            }
+        })
+        .clone()
+}
+
+/// Merge the `flagged` lines of `path` into regions, one per blame hunk.
+/// `origin_of` maps a line to the first line of its hunk and the hunk's
+/// origin.
+fn regions(
+    path: &str,
+    flagged: &BTreeMap<usize, Vec<String>>,
+    mut origin_of: impl FnMut(usize) -> (usize, Origin),
+) -> Vec<(Origin, Region)> {
+    let mut out: Vec<(Origin, Region)> = Vec::new();
+    let mut current_hunk = None;
+    for (&line, ids) in flagged {
+        let (hunk, origin) = origin_of(line);
+        match out.last_mut() {
+            Some((_, region)) if current_hunk == Some(hunk) => {
+                region.end_line = line;
+                for id in ids {
+                    if !region.signals.contains(id) {
+                        region.signals.push(id.clone());
+                    }
+                }
+            }
+            _ => out.push((
+                origin,
+                Region { path: path.to_string(), start_line: line, end_line: line, signals: ids.clone() },
+            )),
+        }
+        current_hunk = Some(hunk);
+    }
+    out
+}
+
+/// Regions grouped by origin: uncommitted changes first, then the newest
+/// commit first.  Regions keep their file and line order within a group.
+fn by_commit(found: Vec<(Origin, Region)>) -> Vec<(Origin, Vec<Region>)> {
+    let mut groups: Vec<(Origin, Vec<Region>)> = Vec::new();
+    for (origin, region) in found {
+        match groups.iter_mut().find(|(o, _)| o.commit == origin.commit) {
+            Some((_, regions)) => regions.push(region),
+            None => groups.push((origin, vec![region])),
+        }
+    }
+    groups.sort_by(|(a, _), (b, _)| {
+        b.commit.is_none().cmp(&a.commit.is_none()).then(b.time.cmp(&a.time)).then(a.commit.cmp(&b.commit))
+    });
+    groups
+}
+
+fn short(commit: &str) -> &str {
+    &commit[..commit.len().min(8)]
+}
+
+fn lines(region: &Region) -> String {
+    if region.start_line == region.end_line {
+        region.start_line.to_string()
+    } else {
+        format!("{}-{}", region.start_line, region.end_line)
+    }
+}
+
+fn render_text(label: &str, groups: &[(Origin, Vec<Region>)]) -> String {
+    if groups.is_empty() {
+        return format!("No flagged regions in {label}.\n");
+    }
+    let mut out = format!("Flagged regions in {label}, by the commit that introduced them\n");
+    for (origin, regions) in groups {
+        out.push('\n');
+        match origin.commit {
+            Some(ref commit) => out.push_str(&format!(
+                "{}  {}  {} <{}>  {}\n",
+                short(commit),
+                format_date(origin.time),
+                origin.author,
+                origin.email,
+                origin.summary
+            )),
+            None => out.push_str("Not committed yet\n"),
+        }
+        for region in regions {
+            out.push_str(&format!("  {}:{}  {}\n", region.path, lines(region), region.signals.join(", ")));
+        }
+    }
+    let total: usize = groups.iter().map(|(_, r)| r.len()).sum();
+    let commits = groups.iter().filter(|(o, _)| o.commit.is_some()).count();
+    out.push_str(&format!(
+        "\n{} flagged region{} from {} commit{}\n",
+        total,
+        if total == 1 { "" } else { "s" },
+        commits,
+        if commits == 1 { "" } else { "s" },
+    ));
+    out
+}
+
+fn to_json(label: &str, groups: &[(Origin, Vec<Region>)]) -> Value {
+    let commits: Vec<Value> = groups
+        .iter()
+        .map(|(origin, regions)| {
+            let regions: Vec<Value> = regions
+                .iter()
+                .map(|r| {
+                    json!({
+                        "path": r.path,
+                        "start_line": r.start_line,
+                        "end_line": r.end_line,
+                        "signals": r.signals,
+                    })
+                })
+                .collect();
+            match origin.commit {
+                Some(ref commit) => json!({
+                    "commit": commit,
+                    "date": format_date(origin.time),
+                    "time": origin.time,
+                    "author": origin.author,
+                    "email": origin.email,
+                    "summary": origin.summary,
+                    "regions": regions,
+                }),
+                None => json!({ "commit": null, "regions": regions }),
+            }
+        })
+        .collect();
+    json!({ "path": label, "commits": commits })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use vibecheck_core::report::Signal;
+
+    fn committed(commit: &str, time: i64) -> Origin {
+        Origin {
+            commit: Some(commit.to_string()),
+            author: "Dev".into(),
+            email: "dev@example.com".into(),
+            time,
+            summary: format!("commit {commit}"),
+        }
+    }
+
+    fn report(family: ModelFamily, signals: Vec<Signal>) -> Report {
+        let mut report = vibecheck_core::analyze("fn main() {}");
+        report.metadata.signal_count = 5;
+        report.attribution.primary = family;
+        report.attribution.confidence = 0.8;
+        report.signals = signals;
+        report
+    }
+
+    #[test]
+    fn flagged_lines_come_from_ai_leaning_signals_in_ai_files() {
+        let mut narrated = Signal::new("rust.comments.narration", "comments", "Narrating", ModelFamily::Claude, 1.5);
+        narrated.lines = vec![4, 9];
+        let mut doc = Signal::new("rust.docs.verbose", "docs", "Verbose docs", ModelFamily::Gpt, 0.5);
+        doc.lines = vec![4];
+        let mut human = Signal::new("rust.errors.many_unwraps", "errors", "Unwraps", ModelFamily::Human, 1.5);
+        human.lines = vec![2];
+
+        let lines = flagged_lines(&report(ModelFamily::Claude, vec![narrated.clone(), doc, human]));
+        assert_eq!(lines.keys().copied().collect::<Vec<_>>(), [4, 9]);
+        assert_eq!(lines[&4], ["rust.comments.narration", "rust.docs.verbose"]);
+        assert!(flagged_lines(&report(ModelFamily::Human, vec![narrated])).is_empty());
+    }
+
+    #[test]
+    fn regions_span_the_flagged_lines_of_one_hunk() {
+        let flagged: BTreeMap<usize, Vec<String>> =
+            [(3, "a"), (5, "b"), (12, "a"), (20, "c")].into_iter().map(|(l, id)| (l, vec![id.to_string()])).collect();
+        // Lines 1–10 from one commit, 11–15 another, 16– uncommitted.
+        let found = regions("src/x.rs", &flagged, |line| match line {
+            1..=10 => (1, committed("aaa", 100)),
+            11..=15 => (11, committed("bbb", 200)),
+            _ => (16, Origin::uncommitted()),
+        });
+        let spans: Vec<(usize, usize, Vec<String>)> =
+            found.iter().map(|(_, r)| (r.start_line, r.end_line, r.signals.clone())).collect();
+        assert_eq!(spans, [(3, 5, vec!["a".into(), "b".into()]), (12, 12, vec!["a".into()]), (20, 20, vec!["c".into()])]);
+    }
+
+    #[test]
+    fn groups_put_uncommitted_then_newest_first() {
+        let region = |path: &str| Region { path: path.into(), start_line: 1, end_line: 2, signals: vec!["a".into()] };
+        let groups = by_commit(vec![
+            (committed("old", 100), region("a.rs")),
+            (committed("new", 300), region("a.rs")),
+            (Origin::uncommitted(), region("c.rs")),
+            (committed("old", 100), region("b.rs")),
+        ]);
+        let order: Vec<Option<&str>> = groups.iter().map(|(o, _)| o.commit.as_deref()).collect();
+        assert_eq!(order, [None, Some("new"), Some("old")]);
+        assert_eq!(groups[2].1.len(), 2);
+
+        let text = render_text("src", &groups);
+        assert!(text.starts_with("Flagged regions in src, by the commit"), "{text}");
+        assert!(text.contains("Not committed yet\n  c.rs:1-2  a\n"), "{text}");
+        assert!(text.contains("new  1970-01-01  Dev <dev@example.com>  commit new\n"), "{text}");
+        assert!(text.ends_with("4 flagged regions from 2 commits\n"), "{text}");
+
+        let json = to_json("src", &groups);
+        assert!(json["commits"][0]["commit"].is_null());
+        assert_eq!(json["commits"][2]["regions"][1]["path"], "b.rs");
+        assert_eq!(render_text(".", &[]), "No flagged regions in ..\n");
+    }
+
+    #[test]
+    fn blames_flagged_lines_on_the_commit_that_added_them() {
+        let dir = tempfile::tempdir().unwrap();
+        let repo = Repository::init(dir.path()).unwrap();
+        let sig = git2::Signature::new("Dev", "dev@example.com", &git2::Time::new(1_700_000_000, 0)).unwrap();
+        std::fs::write(dir.path().join("lib.rs"), "fn a() {}\n").unwrap();
+        let mut index = repo.index().unwrap();
+        index.add_all(["*"], git2::IndexAddOption::DEFAULT, None).unwrap();
+        index.write().unwrap();
+        let tree = repo.find_tree(index.write_tree().unwrap()).unwrap();
+        let oid = repo.commit(Some("HEAD"), &sig, &sig, "add a", &tree, &[]).unwrap();
+        std::fs::write(dir.path().join("lib.rs"), "fn a() {}\nfn b() {}\n").unwrap();
+
+        let blame = repo.blame_file(Path::new("lib.rs"), None).unwrap();
+        let blame = blame.blame_buffer(b"fn a() {}\nfn b() {}\n").unwrap();
+        let mut commits = HashMap::new();
+        let first = origin(&repo, &blame.get_line(1).unwrap(), &mut commits);
+        assert_eq!(first.commit, Some(oid.to_string()));
+        assert_eq!((first.author.as_str(), first.summary.as_str()), ("Dev", "add a"));
+        assert_eq!(origin(&repo, &blame.get_line(2).unwrap(), &mut commits), Origin::uncommitted());
+    }
+}
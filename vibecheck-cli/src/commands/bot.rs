@@ -0,0 +1,500 @@
+use std::path::{Path, PathBuf};
+use std::sync::mpsc::{self, SyncSender, TrySendError};
+use std::sync::{Arc, Mutex};
+use std::time::{Duration, Instant};
+
+use anyhow::{Context, Result};
+use ring::hmac;
+use serde_json::{json, Value};
+
+use vibecheck_core::ignore_rules::{IgnoreRules, NotifySettings};
+use vibecheck_core::report::{ModelFamily, Report, Severity};
+use vibecheck_core::{generated, limits, source_fs, Analyzer};
+
+use crate::github::{self, App};
+use crate::http::{self, Request, Response};
+use crate::metrics::Metrics;
+use crate::notify::{self, Scan};
+use crate::policy;
+use crate::reload::{self, ConfigSource, Reloadable};
+
+/// Environment variable holding the webhook secret configured on the app.
+pub const SECRET_ENV: &str = "GITHUB_WEBHOOK_SECRET";
+
+/// Name of the check run on pull requests.
+const CHECK_NAME: &str = "vibecheck";
+
+/// GitHub accepts at most 50 annotations per check run request.
+const ANNOTATIONS_PER_REQUEST: usize = 50;
+
+/// Check run summaries are capped at 65535 characters.
+const MAX_SUMMARY: usize = 65_000;
+
+/// Bound on any one file, so a pathological file can't hold up the check.
+const TIMEOUT_PER_FILE: Duration = Duration::from_secs(30);
+
+/// Reviews run at once; deliveries beyond these wait in the queue.
+const REVIEW_WORKERS: usize = 4;
+
+/// Deliveries that may wait for a worker.  Beyond this they are turned
+/// away with a 503, which GitHub shows as a failed delivery to redeliver.
+const QUEUE_CAPACITY: usize = 64;
+
+/// Pull request actions that change the code under review.
+const REVIEWED_ACTIONS: &[&str] = &["opened", "synchronize", "reopened", "ready_for_review"];
+
+/// Listen for GitHub `pull_request` webhooks on `listen` and answer each
+/// with a check run on the pull request's head commit.
+///
+/// Runs as the GitHub App `app_id`, authenticating with the private key at
+/// `private_key`; the webhook secret is read from [`SECRET_ENV`].  Each
+/// repository's own `.vibecheck` (at the head commit) configures its
+/// analysis, loaded untrusted, so one deployment can serve every
+/// repository the app is installed on.  With `cache_dir`, reports are cached there, so files a
+/// push leaves unchanged are not analyzed again.  Reviews are posted to
+/// the `[[notify]]` sinks of the bot's own config (`ignore_file`, or the
+/// one discovered from the current directory), never those of the
+/// repositories it reviews; the sinks are reloaded whenever that config
+/// changes.
+pub fn run(
+    listen: &str,
+    app_id: &str,
+    private_key: &Path,
+    api_url: &str,
+    fail_over: Option<f64>,
+    cache_dir: Option<&PathBuf>,
+    ignore_file: Option<&PathBuf>,
+) -> Result<()> {
+    if let Some(limit) = fail_over {
+        anyhow::ensure!((0.0..=1.0).contains(&limit), "--fail-over must be between 0 and 1, got {limit}");
+    }
+    let secret = std::env::var(SECRET_ENV).with_context(|| format!("{SECRET_ENV} is not set"))?;
+    anyhow::ensure!(!secret.is_empty(), "{SECRET_ENV} is empty");
+    let pem = std::fs::read_to_string(private_key)
+        .with_context(|| format!("cannot read {}", private_key.display()))?;
+    let source = ConfigSource::new(ignore_file)?;
+    let config = source.load()?;
+    let bot = Arc::new(Bot {
+        secret: secret.into_bytes(),
+        app: App::new(app_id, &pem)?,
+        api_url: api_url.to_string(),
+        fail_over,
+        cache_dir: cache_dir.cloned(),
+        metrics: Metrics::default().with_pull_requests(),
+        notify: Reloadable::new(config.notifications().to_vec()),
+    });
+    let reloaded = Arc::clone(&bot);
+    reload::watch(source, move |config| reloaded.notify.set(config.notifications().to_vec()))?;
+    let reviewer = Arc::clone(&bot);
+    let queue = worker_queue(REVIEW_WORKERS, QUEUE_CAPACITY, move |job: Job| {
+        if let Err(e) = reviewer.review(&job) {
+            eprintln!("{}#{}: {e:#}", job.repo, job.number);
+        }
+    });
+    http::serve(listen, Arc::new(move |request: &Request| bot.handle(request, &queue)))
+}
+
+/// A queue holding up to `capacity` jobs, served by `workers` threads that
+/// each run `work` on one job at a time.
+fn worker_queue<T: Send + 'static>(
+    workers: usize,
+    capacity: usize,
+    work: impl Fn(T) + Send + Sync + 'static,
+) -> SyncSender<T> {
+    let (queue, jobs) = mpsc::sync_channel(capacity);
+    let jobs = Arc::new(Mutex::new(jobs));
+    let work = Arc::new(work);
+    for _ in 0..workers {
+        let (jobs, work) = (Arc::clone(&jobs), Arc::clone(&work));
+        std::thread::spawn(move || loop {
+            // The lock is released before the job runs, so that the other
+            // workers can take the next one.
+            let job = jobs.lock().unwrap_or_else(|e| e.into_inner()).recv();
+            match job {
+                Ok(job) => work(job),
+                Err(_) => return,
+            }
+        });
+    }
+    queue
+}
+
+struct Bot {
+    secret: Vec<u8>,
+    app: App,
+    api_url: String,
+    fail_over: Option<f64>,
+    cache_dir: Option<PathBuf>,
+    metrics: Metrics,
+    notify: Reloadable<Vec<NotifySettings>>,
+}
+
+/// A pull request head to review.
+#[derive(Debug, PartialEq)]
+struct Job {
+    installation: u64,
+    /// `owner/name`.
+    repo: String,
+    number: u64,
+    head_sha: String,
+    /// The pull request's web page.
+    url: Option<String>,
+}
+
+impl Bot {
+    /// Verify and acknowledge a delivery.  Reviews are queued for the
+    /// workers: GitHub gives up on deliveries that take more than ten
+    /// seconds.
+    fn handle(&self, request: &Request, queue: &SyncSender<Job>) -> Response {
+        match (request.method.as_str(), request.path.as_str()) {
+            ("GET", "/healthz") => return Response::json(200, &json!({ "status": "ok" })),
+            ("GET", "/metrics") => return Response::text(200, self.metrics.render()),
+            ("POST", "/webhook") => {}
+            (_, "/healthz" | "/metrics" | "/webhook") => return Response::error(405, "method not allowed"),
+            (_, path) => return Response::error(404, format!("no such endpoint: {path}")),
+        }
+        if !verify_signature(&self.secret, request.header("x-hub-signature-256"), &request.body) {
+            return Response::error(401, "invalid webhook signature");
+        }
+        match request.header("x-github-event") {
+            Some("ping") => Response::json(200, &json!({ "status": "pong" })),
+            Some("pull_request") => {
+                let payload: Value = match serde_json::from_slice(&request.body) {
+                    Ok(v) => v,
+                    Err(e) => return Response::error(400, format!("invalid JSON: {e}")),
+                };
+                let Some(job) = pull_request_job(&payload) else {
+                    return Response::json(202, &json!({ "status": "ignored" }));
+                };
+                match queue.try_send(job) {
+                    Ok(()) => Response::json(202, &json!({ "status": "queued" })),
+                    Err(TrySendError::Full(job)) => {
+                        eprintln!("{}#{}: review queue is full", job.repo, job.number);
+                        Response::error(503, "review queue is full; redeliver later")
+                    }
+                    Err(TrySendError::Disconnected(_)) => Response::error(503, "no review workers are running"),
+                }
+            }
+            _ => Response::json(202, &json!({ "status": "ignored" })),
+        }
+    }
+
+    /// Open a check run on the head commit, analyze the changed files and
+    /// complete the run with the results.  If analysis fails the run is
+    /// completed as neutral with the error, so it never hangs in progress.
+    fn review(&self, job: &Job) -> Result<()> {
+        let client = github::Client::for_installation(&self.app, &self.api_url, job.installation)?;
+        let check = client.create_check_run(
+            &job.repo,
+            &json!({ "name": CHECK_NAME, "head_sha": job.head_sha, "status": "in_progress" }),
+        )?;
+        let (conclusion, title, summary, annotations) = match self.analyze(&client, job) {
+            Ok(reports) => {
+                self.metrics.record_pull_request(reports.iter().any(is_flagged));
+                notify::send(
+                    &self.notify.get(),
+                    &Scan {
+                        repo: job.repo.clone(),
+                        subject: format!("pull request #{}", job.number),
+                        commit: Some(job.head_sha.clone()),
+                        link: job.url.clone(),
+                        reports: &reports,
+                    },
+                );
+                let (conclusion, title) = verdict(&reports, self.fail_over);
+                let summary = truncate(vibecheck_core::output::format_markdown(&reports), MAX_SUMMARY);
+                (conclusion, title, summary, annotations(&reports))
+            }
+            Err(e) => ("neutral", "vibecheck could not analyze this pull request".to_string(), format!("```\n{e:#}\n```"), Vec::new()),
+        };
+
+        let mut batches = annotations.chunks(ANNOTATIONS_PER_REQUEST);
+        let output = |batch: &[Value]| json!({ "title": title, "summary": summary, "annotations": batch });
+        client.update_check_run(
+            &job.repo,
+            check,
+            &json!({ "status": "completed", "conclusion": conclusion, "output": output(batches.next().unwrap_or(&[])) }),
+        )?;
+        for batch in batches {
+            client.update_check_run(&job.repo, check, &json!({ "output": output(batch) }))?;
+        }
+        Ok(())
+    }
+
+    /// Reports for the supported source files the pull request adds or
+    /// modifies, as of its head commit, under the repository's `.vibecheck`.
+    fn analyze(&self, client: &github::Client, job: &Job) -> Result<Vec<Report>> {
+        // The config is materialized in a scratch directory, so relative paths
+        // in it (a weights file, a model) resolve there rather than against the
+        // server's working directory.  Anyone who can open a pull request
+        // writes it, so it is loaded untrusted: no endpoints, environment
+        // variables or paths outside the scratch directory.
+        let scratch = tempfile::tempdir()?;
+        if let Some(text) = client.file_contents(&job.repo, ".vibecheck", &job.head_sha)? {
+            std::fs::write(scratch.path().join(".vibecheck"), text)?;
+        }
+        let config = policy::load_untrusted(scratch.path()).context("invalid .vibecheck")?;
+        let mut analyzer = Analyzer::new()
+            .with_config(&config)
+            .with_timeout(TIMEOUT_PER_FILE)
+            .with_max_file_size(limits::DEFAULT_MAX_FILE_SIZE);
+        if let Some(dir) = &self.cache_dir {
+            analyzer = analyzer.with_cache_dir(dir);
+        }
+
+        let mut reports = Vec::new();
+        for file in client.pull_request_files(&job.repo, job.number)? {
+            let path = PathBuf::from(&file.path);
+            if file.status == "removed" || !source_fs::is_supported(&path) || config.is_ignored(&path) {
+                continue;
+            }
+            let Some(source) = client.file_contents(&job.repo, &file.path, &job.head_sha)? else {
+                continue;
+            };
+            if !config.include_generated() && generated::is_generated(&String::from_utf8_lossy(&source)) {
+                continue;
+            }
+            let started = Instant::now();
+            match analyzer.analyze_source(&file.path, &source) {
+                Ok(report) => {
+                    self.metrics.record(&report, started.elapsed());
+                    reports.push(report);
+                }
+                // Binary or non-UTF-8 content under a source extension.
+                Err(e) => {
+                    self.metrics.record_error();
+                    eprintln!("{}: skipping {}: {e:#}", job.repo, file.path);
+                }
+            }
+        }
+        Ok(reports)
+    }
+}
+
+/// The job for a `pull_request` delivery, or `None` for actions that don't
+/// change the code (labels, assignments, closing).
+fn pull_request_job(payload: &Value) -> Option<Job> {
+    let action = payload["action"].as_str()?;
+    if !REVIEWED_ACTIONS.contains(&action) {
+        return None;
+    }
+    Some(Job {
+        installation: payload["installation"]["id"].as_u64()?,
+        repo: payload["repository"]["full_name"].as_str()?.to_string(),
+        number: payload["pull_request"]["number"].as_u64()?,
+        head_sha: payload["pull_request"]["head"]["sha"].as_str()?.to_string(),
+        url: payload["pull_request"]["html_url"].as_str().map(String::from),
+    })
+}
+
+/// Check the `X-Hub-Signature-256` header, `sha256=<hex HMAC of the body>`,
+/// in constant time.
+fn verify_signature(secret: &[u8], header: Option<&str>, body: &[u8]) -> bool {
+    let Some(hex) = header.and_then(|h| h.strip_prefix("sha256=")) else {
+        return false;
+    };
+    let Some(tag) = decode_hex(hex) else {
+        return false;
+    };
+    hmac::verify(&hmac::Key::new(hmac::HMAC_SHA256, secret), body, &tag).is_ok()
+}
+
+fn decode_hex(hex: &str) -> Option<Vec<u8>> {
+    if hex.len() % 2 != 0 {
+        return None;
+    }
+    (0..hex.len())
+        .step_by(2)
+        .map(|i| u8::from_str_radix(hex.get(i..i + 2)?, 16).ok())
+        .collect()
+}
+
+/// Conclusion and title of the check run.  Flagged files make it neutral —
+/// informational, never blocking — unless a file's AI probability is over
+/// `fail_over`, which fails it.
+fn verdict(reports: &[Report], fail_over: Option<f64>) -> (&'static str, String) {
+    let flagged = reports.iter().filter(|r| is_flagged(r)).count();
+    let over = fail_over.map_or(0, |limit| {
+        reports.iter().filter(|r| r.attribution.ai_probability.is_some_and(|p| p > limit)).count()
+    });
+    let n = reports.len();
+    let files = if n == 1 { "file" } else { "files" };
+    match (over, flagged) {
+        _ if n == 0 => ("success", "No supported source files changed".to_string()),
+        (0, 0) => ("success", format!("No AI-generated code in {n} changed {files}")),
+        (0, flagged) => ("neutral", format!("{flagged} of {n} changed {files} attributed to AI")),
+        (over, _) => ("failure", format!("{over} of {n} changed {files} over the AI score limit")),
+    }
+}
+
+/// Whether a file is attributed to an AI family with enough data to say so.
+fn is_flagged(report: &Report) -> bool {
+    report.attribution.has_sufficient_data() && report.attribution.primary != ModelFamily::Human
+}
+
+/// One annotation per AI-leaning signal that points at lines, at its first
+/// line: at the signal's severity in files attributed to AI (failures for
+/// errors, warnings for warnings), notices elsewhere.
+fn annotations(reports: &[Report]) -> Vec<Value> {
+    let mut out = Vec::new();
+    for report in reports {
+        let Some(ref path) = report.metadata.file_path else { continue };
+        let flagged = report.attribution.has_sufficient_data() && report.attribution.primary != ModelFamily::Human;
+        for signal in &report.signals {
+            if signal.family == ModelFamily::Human || signal.weight <= 0.0 {
+                continue;
+            }
+            let (Some(&line), Some(label)) = (signal.lines.first(), signal.lines_label()) else {
+                continue;
+            };
+            out.push(json!({
+                "path": path.to_string_lossy(),
+                "start_line": line,
+                "end_line": line,
+                "annotation_level": match signal.severity {
+                    Severity::Error if flagged => "failure",
+                    Severity::Warn if flagged => "warning",
+                    _ => "notice",
+                },
+                "title": signal.id,
+                "message": format!("{} ({label}) — points toward {}", signal.description, signal.family),
+            }));
+        }
+    }
+    out
+}
+
+/// `text` cut to at most `max` bytes on a character boundary.
+fn truncate(mut text: String, max: usize) -> String {
+    if text.len() > max {
+        let mut end = max;
+        while !text.is_char_boundary(end) {
+            end -= 1;
+        }
+        text.truncate(end);
+        text.push_str("\n\n…truncated");
+    }
+    text
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use vibecheck_core::report::Signal;
+
+    #[test]
+    fn verifies_webhook_signatures() {
+        let body = br#"{"zen":"Keep it logically awesome."}"#;
+        let tag = hmac::sign(&hmac::Key::new(hmac::HMAC_SHA256, b"s3cret"), body);
+        let hex: String = tag.as_ref().iter().map(|b| format!("{b:02x}")).collect();
+        let header = format!("sha256={hex}");
+        assert!(verify_signature(b"s3cret", Some(&header), body));
+        assert!(!verify_signature(b"other", Some(&header), body));
+        assert!(!verify_signature(b"s3cret", Some(&header), b"tampered"));
+        assert!(!verify_signature(b"s3cret", Some(&hex), body));
+        assert!(!verify_signature(b"s3cret", None, body));
+    }
+
+    #[test]
+    fn pull_request_job_reads_reviewed_actions_only() {
+        let mut payload = json!({
+            "action": "synchronize",
+            "installation": { "id": 42 },
+            "repository": { "full_name": "acme/widgets" },
+            "pull_request": {
+                "number": 7,
+                "head": { "sha": "abc123" },
+                "html_url": "https://github.com/acme/widgets/pull/7",
+            },
+        });
+        let job = pull_request_job(&payload).unwrap();
+        assert_eq!(
+            job,
+            Job {
+                installation: 42,
+                repo: "acme/widgets".into(),
+                number: 7,
+                head_sha: "abc123".into(),
+                url: Some("https://github.com/acme/widgets/pull/7".into()),
+            }
+        );
+        payload["action"] = json!("labeled");
+        assert!(pull_request_job(&payload).is_none());
+    }
+
+    fn report(path: &str, primary: ModelFamily, p: f64, signals: Vec<Signal>) -> Report {
+        let mut report = vibecheck_core::analyze("fn main() {}\n");
+        report.metadata.file_path = Some(PathBuf::from(path));
+        report.attribution.primary = primary;
+        report.attribution.confidence = 0.8;
+        report.attribution.scores.insert(primary, 0.8);
+        report.attribution.ai_probability = Some(p);
+        report.signals = signals;
+        report.metadata.signal_count = report.signals.len();
+        report
+    }
+
+    #[test]
+    fn verdict_is_neutral_when_flagged_and_fails_over_the_limit() {
+        let human = report("a.rs", ModelFamily::Human, 0.1, vec![]);
+        let ai = report("b.rs", ModelFamily::Claude, 0.95, vec![]);
+        assert_eq!(verdict(&[], None).0, "success");
+        assert_eq!(verdict(std::slice::from_ref(&human), None).0, "success");
+        let both = [human, ai];
+        assert_eq!(verdict(&both, None), ("neutral", "1 of 2 changed files attributed to AI".to_string()));
+        assert_eq!(verdict(&both, Some(0.9)).0, "failure");
+        assert_eq!(verdict(&both, Some(0.99)).0, "neutral");
+    }
+
+    #[test]
+    fn annotations_point_at_ai_signal_lines() {
+        let mut narrated = Signal::new("rust.comments.narration", "comments", "Narrating comments", ModelFamily::Claude, 1.5);
+        narrated.lines = vec![4, 9];
+        narrated.severity = Severity::Warn;
+        let unlocated = Signal::new("rust.comments.high_density", "comments", "Dense", ModelFamily::Claude, 0.8);
+        let mut human = Signal::new("rust.errors.many_unwraps", "errors", "Unwraps", ModelFamily::Human, 1.5);
+        human.lines = vec![2];
+        let reports = [report("src/a.rs", ModelFamily::Claude, 0.9, vec![narrated, unlocated, human])];
+        let out = annotations(&reports);
+        assert_eq!(out.len(), 1);
+        assert_eq!(out[0]["path"], "src/a.rs");
+        assert_eq!(out[0]["start_line"], 4);
+        assert_eq!(out[0]["annotation_level"], "warning");
+        assert!(out[0]["message"].as_str().unwrap().contains("lines 4, 9"));
+
+        let mut severe = Signal::new("rust.comments.narration", "comments", "Narrating", ModelFamily::Claude, 2.0);
+        severe.lines = vec![1];
+        let mut weak = severe.clone();
+        (severe.severity, weak.severity) = (Severity::Error, Severity::Info);
+        let levels: Vec<Value> = annotations(&[report("src/a.rs", ModelFamily::Claude, 0.9, vec![severe.clone(), weak])])
+            .into_iter()
+            .map(|a| a["annotation_level"].clone())
+            .collect();
+        assert_eq!(levels, vec!["failure", "notice"]);
+        assert_eq!(annotations(&[report("src/b.rs", ModelFamily::Human, 0.9, vec![severe])])[0]["annotation_level"], "notice");
+    }
+
+    #[test]
+    fn worker_queue_turns_jobs_away_when_full() {
+        let (started, on_start) = mpsc::channel();
+        let (release, on_release) = mpsc::channel::<()>();
+        let (started, on_release) = (Mutex::new(started), Mutex::new(on_release));
+        let queue = worker_queue(1, 1, move |job: u32| {
+            started.lock().unwrap().send(job).unwrap();
+            on_release.lock().unwrap().recv().unwrap();
+        });
+        queue.try_send(1).unwrap();
+        assert_eq!(on_start.recv().unwrap(), 1);
+        queue.try_send(2).unwrap();
+        assert!(matches!(queue.try_send(3), Err(TrySendError::Full(3))));
+        release.send(()).unwrap();
+        assert_eq!(on_start.recv().unwrap(), 2);
+        release.send(()).unwrap();
+    }
+
+    #[test]
+    fn truncate_respects_char_boundaries() {
+        assert_eq!(truncate("abc".into(), 10), "abc");
+        assert!(truncate("é".repeat(10), 5).starts_with("éé\n"));
+    }
+}
@@ -0,0 +1,75 @@
+use std::io::Read;
+use std::path::{Path, PathBuf};
+
+use anyhow::{Context, Result};
+
+use vibecheck_core::language::{self, SUPPORTED_EXTENSIONS};
+use vibecheck_core::language_pack;
+use vibecheck_core::Analyzer;
+
+use crate::commands::analyze::{format_report, parse_format};
+use crate::policy;
+
+/// Analyze one snippet read from `input` (`-` for stdin) and print its
+/// verdict and evidence.
+///
+/// `lang` names the snippet's language, for stdin or a file whose name
+/// doesn't give it away; without it stdin is scored by the
+/// language-independent analyzers only.  The `.vibecheck` config of the
+/// current directory applies.
+pub fn run(input: &Path, lang: Option<&str>, format: &str) -> Result<()> {
+    let fmt = parse_format(format)?;
+    let stdin = input == Path::new("-");
+    let source = if stdin {
+        let mut source = Vec::new();
+        std::io::stdin().read_to_end(&mut source).context("failed to read stdin")?;
+        source
+    } else {
+        std::fs::read(input).with_context(|| format!("cannot read {}", input.display()))?
+    };
+    anyhow::ensure!(!source.iter().all(u8::is_ascii_whitespace), "no source to check");
+
+    let name = match lang {
+        Some(lang) => {
+            let ext = language_extension(lang)?;
+            let stem = if stdin { PathBuf::from("snippet") } else { input.to_path_buf() };
+            stem.with_extension(ext)
+        }
+        None if stdin => PathBuf::from("snippet"),
+        None => input.to_path_buf(),
+    };
+
+    let config = policy::load(&std::env::current_dir()?);
+    let mut report = Analyzer::new().with_config(&config).analyze_source(&name.to_string_lossy(), &source)?;
+    report.metadata.file_path = (!stdin).then(|| input.to_path_buf());
+    println!("{}", format_report(&report, fmt, false, &config.calibration()));
+    Ok(())
+}
+
+/// The extension to analyze a `--lang` snippet as: a built-in language
+/// name or extension, or an extension handled by an installed language pack.
+pub fn language_extension(lang: &str) -> Result<String> {
+    if let Some(ext) = language::extension_for(lang) {
+        return Ok(ext.to_string());
+    }
+    let ext = lang.to_ascii_lowercase();
+    anyhow::ensure!(
+        language_pack::is_pack_extension(&ext),
+        "unknown language: {lang} (expected rust, python, javascript, typescript, go, or one of: {})",
+        SUPPORTED_EXTENSIONS.join(", ")
+    );
+    Ok(ext)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn language_extension_resolves_names_and_rejects_unknown() {
+        assert_eq!(language_extension("go").unwrap(), "go");
+        assert_eq!(language_extension("Rust").unwrap(), "rs");
+        let err = language_extension("cobol").unwrap_err().to_string();
+        assert!(err.contains("unknown language: cobol"), "{err}");
+    }
+}
@@ -0,0 +1,206 @@
+use std::collections::HashMap;
+use std::path::Path;
+
+use anyhow::{Context, Result};
+use git2::{Repository, Sort};
+use serde_json::{json, Value};
+
+use vibecheck_core::commit_message::CommitReport;
+use vibecheck_core::report::ModelFamily;
+
+use crate::commands::history::format_date;
+use crate::policy;
+
+/// Rules listed in the summary section.
+const TOP_RULES: usize = 5;
+
+/// One scored commit message.
+struct ScoredCommit {
+    id: String,
+    time: i64,
+    author: String,
+    summary: String,
+    report: CommitReport,
+}
+
+impl ScoredCommit {
+    fn is_ai(&self) -> bool {
+        self.report.attribution.has_sufficient_data() && self.report.attribution.primary != ModelFamily::Human
+    }
+}
+
+/// Score the message of every commit after `since` up to HEAD with the
+/// commit-message detectors and print one row per commit, newest first,
+/// followed by a summary section.  Merge commits are skipped: their
+/// messages are written by git, not by the author.
+pub fn run(path: &Path, since: &str, format: &str) -> Result<()> {
+    let repo = Repository::discover(path).context("not inside a git repository (or no .git found)")?;
+    let base = repo
+        .revparse_single(since)
+        .and_then(|o| o.peel_to_commit())
+        .with_context(|| format!("unknown revision {since:?}"))?;
+
+    let mut revwalk = repo.revwalk()?;
+    revwalk.push_head().context("no HEAD commit found")?;
+    revwalk.hide(base.id())?;
+    revwalk.set_sorting(Sort::TOPOLOGICAL | Sort::TIME)?;
+
+    let mut commits = Vec::new();
+    for oid in revwalk {
+        let commit = repo.find_commit(oid?)?;
+        if commit.parent_count() > 1 {
+            continue;
+        }
+        let message = String::from_utf8_lossy(commit.message_bytes()).into_owned();
+        commits.push(ScoredCommit {
+            id: commit.id().to_string(),
+            time: commit.time().seconds(),
+            author: commit.author().name().unwrap_or_default().to_string(),
+            summary: commit.summary().unwrap_or_default().to_string(),
+            report: vibecheck_core::analyze_commit_message_with(&message, path, &policy::file_options()),
+        });
+    }
+
+    match format {
+        "json" => println!("{}", serde_json::to_string_pretty(&to_json(since, &commits))?),
+        _ => print!("{}", render_text(since, &commits)),
+    }
+    Ok(())
+}
+
+/// AI-family rules that fired on AI-attributed messages, with the number of
+/// messages each fired on, most frequent first.
+fn top_rules(commits: &[ScoredCommit]) -> Vec<(String, usize)> {
+    let mut counts: HashMap<&str, usize> = HashMap::new();
+    for c in commits.iter().filter(|c| c.is_ai()) {
+        for s in c.report.signals.iter().filter(|s| s.is_ai_leaning()) {
+            *counts.entry(s.id.as_str()).or_insert(0) += 1;
+        }
+    }
+    let mut rules: Vec<(String, usize)> = counts.into_iter().map(|(id, n)| (id.to_string(), n)).collect();
+    rules.sort_by(|a, b| b.1.cmp(&a.1).then_with(|| a.0.cmp(&b.0)));
+    rules.truncate(TOP_RULES);
+    rules
+}
+
+fn render_text(since: &str, commits: &[ScoredCommit]) -> String {
+    let mut out = format!("Commit messages since {since}\n\n");
+    if commits.is_empty() {
+        out.push_str(&format!("(no commits since {since})\n"));
+        return out;
+    }
+    out.push_str(&format!("{:<10}  {:<12}  {:<8}  {:>5}  SUBJECT\n", "COMMIT", "DATE", "FAMILY", "CONF"));
+    out.push_str(&format!("{}\n", "─".repeat(72)));
+    for c in commits {
+        let attribution = &c.report.attribution;
+        let (family, conf) = if attribution.has_sufficient_data() {
+            (attribution.primary.to_string(), format!("{:.0}%", attribution.confidence * 100.0))
+        } else {
+            ("—".to_string(), "—".to_string())
+        };
+        out.push_str(&format!("{:<10}  {:<12}  {:<8}  {:>5}  {}\n", &c.id[..8], format_date(c.time), family, conf, c.summary));
+        for s in &c.report.signals {
+            out.push_str(&format!("{:<36}  {} [{}]\n", "", s.description, s.id));
+        }
+    }
+
+    let ai = commits.iter().filter(|c| c.is_ai()).count();
+    out.push_str("\nSummary\n");
+    out.push_str(&format!(
+        "  {ai} of {} commit messages attributed to AI ({:.0}%)\n",
+        commits.len(),
+        ai as f64 / commits.len() as f64 * 100.0
+    ));
+    let rules = top_rules(commits);
+    if !rules.is_empty() {
+        let rules: Vec<String> = rules.iter().map(|(id, n)| format!("{id} ({n})")).collect();
+        out.push_str(&format!("  top rules: {}\n", rules.join(", ")));
+    }
+    out
+}
+
+fn to_json(since: &str, commits: &[ScoredCommit]) -> Value {
+    let ai = commits.iter().filter(|c| c.is_ai()).count();
+    let entries: Vec<Value> = commits
+        .iter()
+        .map(|c| {
+            json!({
+                "commit": c.id,
+                "date": format_date(c.time),
+                "timestamp": c.time,
+                "author": c.author,
+                "summary": c.summary,
+                "attribution": c.report.attribution,
+                "signals": c.report.signals,
+            })
+        })
+        .collect();
+    json!({
+        "since": since,
+        "commits": entries,
+        "summary": {
+            "commits": commits.len(),
+            "ai_commits": ai,
+            "ai_fraction": if commits.is_empty() { 0.0 } else { ai as f64 / commits.len() as f64 },
+            "top_rules": top_rules(commits).iter().map(|(id, n)| json!({ "id": id, "commits": n })).collect::<Vec<_>>(),
+        },
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn scored(summary: &str, message: &str) -> ScoredCommit {
+        ScoredCommit {
+            id: "0123456789abcdef".into(),
+            time: 1_700_000_000,
+            author: "Dev".into(),
+            summary: summary.into(),
+            report: vibecheck_core::commit_message::score(message, &vibecheck_core::heuristics::DefaultHeuristics),
+        }
+    }
+
+    const AI_MESSAGE: &str = "Add retry support\n\nThis commit:\n\n- Added a retry policy\n- Updated the builder\n\
+                              - Refactored dispatch\n- Added tests\n- Updated docs\n\n\
+                              Overall, these changes improve resilience.\n";
+
+    #[test]
+    fn summary_counts_ai_messages_and_their_rules() {
+        let commits = vec![scored("Add retry support", AI_MESSAGE), scored("typo", "typo")];
+        let json = to_json("v1.0.0", &commits);
+        assert_eq!(json["summary"]["commits"], 2);
+        assert_eq!(json["summary"]["ai_commits"], 1);
+        assert_eq!(json["summary"]["ai_fraction"], 0.5);
+        let rules: Vec<&str> = json["summary"]["top_rules"].as_array().unwrap().iter().map(|r| r["id"].as_str().unwrap()).collect();
+        assert!(rules.contains(&"commit.narration.this_commit"), "{rules:?}");
+        assert!(!rules.contains(&"commit.style.wip"));
+    }
+
+    #[test]
+    fn text_has_a_row_per_commit_and_a_summary_section() {
+        let text = render_text("v1.0.0", &[scored("Add retry support", AI_MESSAGE), scored("Tidy up", "Tidy up\n\nMoved the helper next to its only caller.\n")]);
+        assert!(text.contains("01234567    2023-11-14"), "{text}");
+        assert!(text.contains("[commit.narration.summary_closer]"), "{text}");
+        assert!(text.contains("Tidy up"));
+        assert!(text.contains("\nSummary\n  1 of 2 commit messages attributed to AI (50%)"), "{text}");
+        assert_eq!(render_text("v1.0.0", &[]), "Commit messages since v1.0.0\n\n(no commits since v1.0.0)\n");
+    }
+
+    #[test]
+    fn run_scores_commits_after_the_base() {
+        let dir = tempfile::tempdir().unwrap();
+        let repo = Repository::init(dir.path()).unwrap();
+        let sig = git2::Signature::new("Dev", "dev@example.com", &git2::Time::new(1_700_000_000, 0)).unwrap();
+        let commit = |message: &str| {
+            let tree = repo.find_tree(repo.index().unwrap().write_tree().unwrap()).unwrap();
+            let parent = repo.head().ok().map(|h| h.peel_to_commit().unwrap());
+            let parents: Vec<&git2::Commit> = parent.iter().collect();
+            repo.commit(Some("HEAD"), &sig, &sig, message, &tree, &parents).unwrap()
+        };
+        commit("base");
+        commit(AI_MESSAGE);
+        run(dir.path(), "HEAD~1", "json").unwrap();
+        assert!(run(dir.path(), "no-such-rev", "json").is_err());
+    }
+}
@@ -0,0 +1,241 @@
+use std::collections::BTreeMap;
+use std::path::{Path, PathBuf};
+
+use anyhow::{Context, Result};
+use colored::Colorize;
+use serde_json::json;
+
+use vibecheck_core::output::OutputFormat;
+use vibecheck_core::report::{ModelFamily, Report, Signal};
+use vibecheck_core::Analyzer;
+
+use crate::commands::analyze::parse_format;
+use crate::policy;
+
+/// AI probabilities closer than this are reported as a tie: calibration is
+/// not precise enough to rank them.
+const MIN_GAP: f64 = 0.05;
+
+/// One detector's evidence in both files.
+#[derive(Debug, PartialEq)]
+struct Delta {
+    /// Signal ID without its language prefix (`comments.minimal`), so
+    /// implementations in different languages line up.
+    detector: String,
+    /// Net weight toward AI in each file; 0.0 when it didn't fire.
+    a: f64,
+    b: f64,
+}
+
+/// Analyze `a` and `b` and print which is more likely AI-generated, with
+/// the detectors that separate them.
+pub fn run(a: &Path, b: &Path, ignore_file: Option<&PathBuf>, format: &str) -> Result<()> {
+    let fmt = parse_format(format)?;
+    anyhow::ensure!(
+        matches!(fmt, OutputFormat::Pretty | OutputFormat::Text | OutputFormat::Json),
+        "compare supports pretty, text and json output, not {format}"
+    );
+    let config = match ignore_file {
+        Some(f) => policy::from_file(f)?,
+        None => policy::load(&std::env::current_dir()?),
+    };
+    let analyzer = Analyzer::new().with_config(&config);
+    let report_a = analyzer.analyze_file(a).with_context(|| format!("cannot analyze {}", a.display()))?;
+    let report_b = analyzer.analyze_file(b).with_context(|| format!("cannot analyze {}", b.display()))?;
+    let deltas = deltas(&report_a, &report_b);
+    let more_ai = more_likely_ai(&report_a, &report_b);
+
+    if fmt == OutputFormat::Json {
+        let value = json!({
+            "a": report_a,
+            "b": report_b,
+            "more_likely_ai": more_ai.map(|first| if first { "a" } else { "b" }),
+            "deltas": deltas
+                .iter()
+                .map(|d| json!({ "detector": d.detector, "a": d.a, "b": d.b, "delta": d.a - d.b }))
+                .collect::<Vec<_>>(),
+        });
+        println!("{}", serde_json::to_string_pretty(&value)?);
+    } else {
+        let mut verdict = verdict_line(a, b, &report_a, &report_b, more_ai);
+        if fmt == OutputFormat::Pretty {
+            verdict = verdict.bold().to_string();
+        }
+        print!("{}", format_comparison(a, b, &report_a, &report_b, &verdict, &deltas));
+    }
+    Ok(())
+}
+
+/// `Some(true)` when `a` is clearly more likely AI-generated, `Some(false)`
+/// when `b` is, `None` for a tie or when either lacks data.
+fn more_likely_ai(a: &Report, b: &Report) -> Option<bool> {
+    let (pa, pb) = (a.attribution.ai_probability?, b.attribution.ai_probability?);
+    ((pa - pb).abs() >= MIN_GAP).then_some(pa > pb)
+}
+
+/// Net weight of a signal toward AI: human-family evidence counts against.
+fn ai_weight(signal: &Signal) -> f64 {
+    if signal.family == ModelFamily::Human {
+        -signal.weight.abs()
+    } else {
+        signal.weight
+    }
+}
+
+/// Detectors whose evidence differs between the two reports, largest
+/// difference first.
+fn deltas(a: &Report, b: &Report) -> Vec<Delta> {
+    let mut by_detector: BTreeMap<String, (f64, f64)> = BTreeMap::new();
+    let detector = |s: &Signal| match s.id.split_once('.') {
+        Some((_, rest)) => rest.to_string(),
+        None => s.description.clone(),
+    };
+    for s in &a.signals {
+        by_detector.entry(detector(s)).or_default().0 += ai_weight(s);
+    }
+    for s in &b.signals {
+        by_detector.entry(detector(s)).or_default().1 += ai_weight(s);
+    }
+    let mut deltas: Vec<Delta> = by_detector
+        .into_iter()
+        .filter(|(_, (a, b))| (a - b).abs() > 1e-9)
+        .map(|(detector, (a, b))| Delta { detector, a, b })
+        .collect();
+    deltas.sort_by(|x, y| (y.a - y.b).abs().total_cmp(&(x.a - x.b).abs()));
+    deltas
+}
+
+fn verdict_line(a: &Path, b: &Path, report_a: &Report, report_b: &Report, more_ai: Option<bool>) -> String {
+    let p = |r: &Report| r.attribution.ai_probability.unwrap_or(0.0);
+    match more_ai {
+        Some(true) => format!("{} is more likely AI-generated ({:.2} vs {:.2}).", a.display(), p(report_a), p(report_b)),
+        Some(false) => format!("{} is more likely AI-generated ({:.2} vs {:.2}).", b.display(), p(report_b), p(report_a)),
+        None if report_a.attribution.ai_probability.is_none() || report_b.attribution.ai_probability.is_none() => {
+            "Too little signal data to compare: a verdict needs evidence in both files.".to_string()
+        }
+        None => format!("Neither is clearly more likely AI-generated ({:.2} vs {:.2}).", p(report_a), p(report_b)),
+    }
+}
+
+fn format_comparison(
+    a: &Path,
+    b: &Path,
+    report_a: &Report,
+    report_b: &Report,
+    verdict: &str,
+    deltas: &[Delta],
+) -> String {
+    let summary = |label: &str, path: &Path, r: &Report| {
+        let verdict = if r.attribution.has_sufficient_data() {
+            format!("{} ({:.0}%)", r.attribution.primary, r.attribution.confidence * 100.0)
+        } else {
+            "Insufficient data".to_string()
+        };
+        let probability = r
+            .attribution
+            .ai_probability
+            .map(|p| format!(", AI probability {p:.2}"))
+            .unwrap_or_default();
+        format!("{label}: {} — {verdict}{probability}\n", path.display())
+    };
+    let mut out = summary("A", a, report_a);
+    out.push_str(&summary("B", b, report_b));
+    out.push_str(&format!("\n{verdict}\n"));
+
+    if deltas.is_empty() {
+        out.push_str("\nThe same detectors fired with the same weight in both.\n");
+        return out;
+    }
+    let width = deltas.iter().map(|d| d.detector.chars().count()).max().unwrap_or(0).max(8);
+    let cell = |w: f64| if w == 0.0 { "—".to_string() } else { format!("{w:+.1}") };
+    out.push_str(&format!("\n{:<width$}  {:>6}  {:>6}  {:>6}\n", "Detector", "A", "B", "A − B"));
+    for d in deltas {
+        out.push_str(&format!(
+            "{:<width$}  {:>6}  {:>6}  {:>+6.1}\n",
+            d.detector,
+            cell(d.a),
+            cell(d.b),
+            d.a - d.b
+        ));
+    }
+    out.push_str("\nWeights are net evidence toward AI; human-family signals count negative.\n");
+    out
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::collections::BTreeMap;
+    use vibecheck_core::report::{Attribution, ReportMetadata};
+
+    fn report(probability: Option<f64>, signals: Vec<Signal>) -> Report {
+        Report {
+            attribution: Attribution {
+                primary: ModelFamily::Claude,
+                confidence: 0.8,
+                scores: BTreeMap::new(),
+                era: None,
+                ai_probability: probability,
+                authorship: None,
+            },
+            metadata: ReportMetadata {
+                file_path: None,
+                lines_of_code: 10,
+                signal_count: signals.len(),
+                degraded: vec![],
+                skipped: None,
+            },
+            signals,
+            symbol_reports: None,
+            segments: None,
+            comment_free: None,
+            ensemble: None,
+        }
+    }
+
+    fn signal(id: &str, family: ModelFamily, weight: f64) -> Signal {
+        Signal::new(id, "test", "test signal", family, weight)
+    }
+
+    #[test]
+    fn deltas_line_up_detectors_across_languages() {
+        let a = report(Some(0.9), vec![
+            signal("go.comments.high_density", ModelFamily::Claude, 1.5),
+            signal("go.naming.short_names", ModelFamily::Human, 1.0),
+            signal("go.errors.errorf_wrap", ModelFamily::Gpt, 1.0),
+        ]);
+        let b = report(Some(0.2), vec![
+            signal("python.naming.short_names", ModelFamily::Human, 1.0),
+            signal("python.humanity.ticket_refs", ModelFamily::Human, -1.5),
+        ]);
+        let d = deltas(&a, &b);
+        assert_eq!(
+            d,
+            [
+                Delta { detector: "comments.high_density".into(), a: 1.5, b: 0.0 },
+                Delta { detector: "humanity.ticket_refs".into(), a: 0.0, b: -1.5 },
+                Delta { detector: "errors.errorf_wrap".into(), a: 1.0, b: 0.0 },
+            ]
+        );
+    }
+
+    #[test]
+    fn close_probabilities_are_a_tie() {
+        assert_eq!(more_likely_ai(&report(Some(0.9), vec![]), &report(Some(0.2), vec![])), Some(true));
+        assert_eq!(more_likely_ai(&report(Some(0.3), vec![]), &report(Some(0.7), vec![])), Some(false));
+        assert_eq!(more_likely_ai(&report(Some(0.52), vec![]), &report(Some(0.5), vec![])), None);
+        assert_eq!(more_likely_ai(&report(None, vec![]), &report(Some(0.5), vec![])), None);
+    }
+
+    #[test]
+    fn text_names_the_more_likely_file_and_tabulates_deltas() {
+        let a = report(Some(0.91), vec![signal("go.comments.high_density", ModelFamily::Claude, 1.5)]);
+        let b = report(Some(0.22), vec![]);
+        let (pa, pb) = (Path::new("a.go"), Path::new("b.go"));
+        let verdict = verdict_line(pa, pb, &a, &b, more_likely_ai(&a, &b));
+        let out = format_comparison(pa, pb, &a, &b, &verdict, &deltas(&a, &b));
+        assert!(out.contains("A: a.go — Claude (80%), AI probability 0.91\n"), "{out}");
+        assert!(out.contains("a.go is more likely AI-generated (0.91 vs 0.22)."), "{out}");
+        assert!(out.contains("comments.high_density    +1.5       —    +1.5\n"), "{out}");
+    }
+}
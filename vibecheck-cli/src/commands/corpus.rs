@@ -0,0 +1,268 @@
+use std::collections::{BTreeMap, BTreeSet, HashMap};
+use std::path::{Path, PathBuf};
+
+use anyhow::{bail, Context, Result};
+
+use vibecheck_core::corpus_manifest::{content_hash, Manifest, Provenance, MANIFEST_FILE};
+use vibecheck_core::language::detect_language;
+use vibecheck_core::report::{Authorship, ModelFamily};
+use vibecheck_core::scrub::{Kind, Scrubber};
+
+use super::analyze::parse_families;
+use crate::policy;
+use crate::providers::{self, Provider};
+
+/// `--languages` names: prompt wording and file extension.
+const LANGUAGES: &[(&str, &str, &str)] = &[
+    ("rust", "Rust", "rs"),
+    ("python", "Python", "py"),
+    ("js", "JavaScript", "js"),
+    ("go", "Go", "go"),
+];
+
+pub fn add(
+    corpus: &Path,
+    files: &[PathBuf],
+    label: &str,
+    assisted: bool,
+    model: Option<String>,
+    task: Option<String>,
+    license: Option<String>,
+) -> Result<()> {
+    let label = parse_label(label)?;
+    std::fs::create_dir_all(corpus)?;
+    let mut manifest = Manifest::load(corpus)?;
+    let authorship = assisted.then_some(Authorship::AiAssisted);
+    let provenance = Provenance { authorship, model, task, license };
+    let mut added = 0;
+    let mut result = Ok(());
+    for file in files {
+        match manifest.add(file, label, provenance.clone()) {
+            Ok(entry) => {
+                println!("added {} as {}", entry.path, entry.label);
+                added += 1;
+            }
+            // Save what was added before failing, so a bad file in a batch
+            // does not leave copied samples missing from the manifest.
+            Err(e) => {
+                result = Err(e);
+                break;
+            }
+        }
+    }
+    if added > 0 {
+        manifest.save()?;
+    }
+    result
+}
+
+pub fn list(corpus: &Path, label: Option<&str>, format: &str) -> Result<()> {
+    let manifest = Manifest::load(corpus)?;
+    let label = label.map(parse_label).transpose()?;
+    let samples: Vec<_> = manifest.samples.iter().filter(|s| label.is_none_or(|l| s.label == l)).collect();
+
+    if format == "json" {
+        println!("{}", serde_json::to_string_pretty(&samples)?);
+        return Ok(());
+    }
+    if samples.is_empty() {
+        println!("No samples in {} ({MANIFEST_FILE} is missing or empty).", corpus.display());
+        return Ok(());
+    }
+
+    let path_width = samples.iter().map(|s| s.path.len()).max().unwrap_or(0).max(4);
+    println!(
+        "{:<path_width$}  {:<7}  {:<22}  {:<14}  {:<6}  LICENSE",
+        "PATH", "LABEL", "MODEL", "TASK", "LANG"
+    );
+    println!("{}", "─".repeat(path_width + 68));
+    let mut counts: BTreeMap<&str, HashMap<ModelFamily, usize>> = BTreeMap::new();
+    for s in &samples {
+        println!(
+            "{:<path_width$}  {:<7}  {:<22}  {:<14}  {:<6}  {}",
+            s.path,
+            s.label.abbrev(),
+            s.model.as_deref().unwrap_or("-"),
+            s.task.as_deref().unwrap_or("-"),
+            s.language,
+            s.license.as_deref().unwrap_or("-"),
+        );
+        *counts.entry(&s.language).or_default().entry(s.label).or_default() += 1;
+    }
+
+    println!();
+    print!("{:<6}", "");
+    for f in ModelFamily::all() {
+        print!("  {:>7}", f.abbrev());
+    }
+    println!();
+    for (language, by_label) in &counts {
+        print!("{language:<6}");
+        for f in ModelFamily::all() {
+            print!("  {:>7}", by_label.get(f).copied().unwrap_or(0));
+        }
+        println!();
+    }
+    let assisted = samples.iter().filter(|s| s.authorship() == Authorship::AiAssisted).count();
+    if assisted > 0 {
+        println!("\n{} samples, {assisted} of them AI-assisted", samples.len());
+    } else {
+        println!("\n{} samples", samples.len());
+    }
+    Ok(())
+}
+
+pub fn relabel(corpus: &Path, path: &Path, label: &str) -> Result<()> {
+    let label = parse_label(label)?;
+    let mut manifest = Manifest::load(corpus)?;
+    // Accept the path as typed from the working directory too.
+    let path = path.strip_prefix(corpus).unwrap_or(path);
+    let old = manifest.relabel(path, label)?;
+    manifest.save()?;
+    println!("relabelled {}: {old} -> {label}", path.display());
+    Ok(())
+}
+
+pub fn dedupe(corpus: &Path, dry_run: bool) -> Result<()> {
+    let mut manifest = Manifest::load(corpus)?;
+    let result = manifest.dedupe();
+
+    let verb = if dry_run { "would remove" } else { "removed" };
+    for entry in &result.removed {
+        println!("{verb} {} (duplicate {})", entry.path, entry.label);
+    }
+    for group in &result.conflicts {
+        let members: Vec<_> = group.iter().map(|e| format!("{} ({})", e.path, e.label)).collect();
+        eprintln!("warning: identical content with different labels: {}", members.join(", "));
+    }
+    if result.removed.is_empty() {
+        println!("No duplicates.");
+    } else if !dry_run {
+        for entry in &result.removed {
+            std::fs::remove_file(manifest.root().join(&entry.path))?;
+        }
+        manifest.save()?;
+    }
+    Ok(())
+}
+
+pub fn scrub(corpus: &Path, domains: &[String], dry_run: bool) -> Result<()> {
+    let mut manifest = Manifest::load(corpus)?;
+    let mut scrubber = Scrubber::new(domains);
+    let mut scrubbed = 0;
+    let verb = if dry_run { "would scrub" } else { "scrubbed" };
+    for entry in &mut manifest.samples {
+        let path = corpus.join(&entry.path);
+        let source = std::fs::read_to_string(&path).with_context(|| format!("failed to read {}", path.display()))?;
+        let (text, replacements) = scrubber.scrub(&source, detect_language(&path));
+        if replacements.is_empty() {
+            continue;
+        }
+        let mut counts: BTreeMap<Kind, usize> = BTreeMap::new();
+        for r in &replacements {
+            *counts.entry(r.kind).or_default() += 1;
+        }
+        let summary: Vec<String> = counts.iter().map(|(kind, &n)| kind.count(n)).collect();
+        println!("{verb} {}: {}", entry.path, summary.join(", "));
+        if dry_run {
+            let mut seen = BTreeSet::new();
+            for r in replacements.iter().filter(|r| seen.insert(&r.original)) {
+                println!("  {} -> {}", r.original, r.placeholder);
+            }
+        } else {
+            std::fs::write(&path, &text).with_context(|| format!("failed to write {}", path.display()))?;
+            entry.hash = content_hash(text.as_bytes());
+        }
+        scrubbed += 1;
+    }
+    if scrubbed > 0 && !dry_run {
+        manifest.save()?;
+    }
+    println!("{scrubbed} of {} samples {}.", manifest.samples.len(), if dry_run { "need scrubbing" } else { "scrubbed" });
+    Ok(())
+}
+
+pub fn generate(
+    corpus: &Path,
+    task: &str,
+    models: &[String],
+    languages: &[String],
+    samples: usize,
+    license: Option<String>,
+    seed: Option<u32>,
+) -> Result<()> {
+    let families = parse_families(models)?;
+    let languages = languages
+        .iter()
+        .map(|name| {
+            LANGUAGES
+                .iter()
+                .find(|(id, ..)| id.eq_ignore_ascii_case(name))
+                .ok_or_else(|| anyhow::anyhow!("unknown language: {name} (expected rust, python, js, or go)"))
+        })
+        .collect::<Result<Vec<_>>>()?;
+
+    std::fs::create_dir_all(corpus)?;
+    let config = policy::load(corpus);
+    let mut manifest = Manifest::load(corpus)?;
+    let client = reqwest::blocking::Client::new();
+    let (mut added, mut failed) = (0, 0);
+
+    for family in families {
+        let name = family.to_string().to_lowercase();
+        let provider = match Provider::for_family(family, config.provider(&name)) {
+            Ok(p) => p,
+            Err(e) => {
+                eprintln!("skipping {name}: {e:#}");
+                failed += languages.len() * samples;
+                continue;
+            }
+        };
+        if seed.is_some() && !provider.supports_seed() {
+            eprintln!("warning: the {name} provider's API takes no seed; its samples will differ between runs");
+        }
+        for (_, language, ext) in &languages {
+            for n in 0..samples {
+                // One seed per sample, so a family's samples differ from
+                // each other but not between runs.
+                let seed = seed.map(|s| s.wrapping_add(n as u32));
+                let completion = match provider.complete(&client, &providers::prompt(task, language), seed) {
+                    Ok(c) => c,
+                    Err(e) => {
+                        eprintln!("{name}/{ext}: {e:#}");
+                        failed += 1;
+                        continue;
+                    }
+                };
+                let provenance = Provenance {
+                    authorship: None,
+                    model: Some(completion.model),
+                    task: Some(task.to_string()),
+                    license: license.clone(),
+                };
+                match manifest.add_source(&providers::extract_code(&completion.text), ext, family, provenance) {
+                    Ok(entry) => {
+                        println!("added {} ({})", entry.path, entry.model.as_deref().unwrap_or_default());
+                        added += 1;
+                        // Save as we go: a long run interrupted halfway keeps what it paid for.
+                        manifest.save()?;
+                    }
+                    Err(e) => {
+                        eprintln!("{name}/{ext}: {e:#}");
+                        failed += 1;
+                    }
+                }
+            }
+        }
+    }
+
+    println!("Generated {added} samples ({failed} failed).");
+    if added == 0 && failed > 0 {
+        bail!("no samples generated");
+    }
+    Ok(())
+}
+
+pub(crate) fn parse_label(name: &str) -> Result<ModelFamily> {
+    Ok(parse_families(&[name.to_string()])?[0])
+}
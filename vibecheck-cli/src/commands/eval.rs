@@ -0,0 +1,135 @@
+use std::path::Path;
+
+use anyhow::{bail, Context, Result};
+
+use vibecheck_core::eval::{self, Evaluation, Sample};
+use vibecheck_core::report::{Authorship, ModelFamily};
+use vibecheck_core::FileOptions;
+
+use crate::policy;
+
+pub fn run(corpus: &Path, format: &str) -> Result<()> {
+    let (samples, unlabelled) = load_samples(corpus)?;
+    let evaluation = eval::evaluate(&samples);
+
+    match format {
+        "json" => println!("{}", serde_json::to_string_pretty(&evaluation)?),
+        _ => print_text(&evaluation, unlabelled),
+    }
+    Ok(())
+}
+
+/// Analyze every labelled file under `corpus`, returning the samples and the
+/// number of unlabelled files skipped.  Paths are relative to `corpus`.
+pub fn load_samples(corpus: &Path) -> Result<(Vec<Sample>, usize)> {
+    if !corpus.is_dir() {
+        bail!("corpus {} is not a directory", corpus.display());
+    }
+    let (files, unlabelled) = eval::corpus_files(corpus)?;
+    if files.is_empty() {
+        bail!(
+            "no labelled files under {} (name files or directories after a family, e.g. claude.rs or gpt/)",
+            corpus.display()
+        );
+    }
+
+    let mut samples = Vec::with_capacity(files.len());
+    for (path, label, authorship) in files {
+        let report = vibecheck_core::analyze_file_with(&path, &FileOptions { no_cache: true, ..policy::file_options() })
+            .with_context(|| format!("failed to analyze {}", path.display()))?;
+        let path = path.strip_prefix(corpus).map(Path::to_path_buf).unwrap_or(path);
+        samples.push(Sample { path, label, authorship, report });
+    }
+    Ok((samples, unlabelled))
+}
+
+fn print_text(e: &Evaluation, unlabelled: usize) {
+    println!(
+        "Evaluated {} files: accuracy {:.1}%, macro F1 {:.3}",
+        e.files,
+        e.accuracy * 100.0,
+        e.macro_f1
+    );
+    if unlabelled > 0 {
+        println!("({unlabelled} unlabelled files skipped)");
+    }
+
+    println!();
+    println!("{:<8}  {:>9}  {:>6}  {:>5}  {:>7}", "FAMILY", "PRECISION", "RECALL", "F1", "SUPPORT");
+    println!("{}", "─".repeat(43));
+    for m in &e.families {
+        println!(
+            "{:<8}  {:>9.3}  {:>6.3}  {:>5.3}  {:>7}",
+            m.family.abbrev(),
+            m.precision,
+            m.recall,
+            m.f1,
+            m.support
+        );
+    }
+
+    println!();
+    println!("Confusion matrix (rows: actual, columns: predicted)");
+    print!("{:<8}", "");
+    for f in ModelFamily::all() {
+        print!("  {:>7}", f.abbrev());
+    }
+    println!();
+    for (family, row) in ModelFamily::all().iter().zip(&e.confusion) {
+        print!("{:<8}", family.abbrev());
+        for n in row {
+            print!("  {n:>7}");
+        }
+        println!();
+    }
+
+    let a = &e.authorship;
+    if a.files > 0 {
+        println!();
+        println!("Authorship: accuracy {:.1}% over {} files (rows: actual, columns: verdict)", a.accuracy * 100.0, a.files);
+        print!("{:<15}", "");
+        for authorship in Authorship::all() {
+            print!("  {:>14}", authorship.as_str());
+        }
+        println!();
+        for (authorship, row) in Authorship::all().iter().zip(&a.confusion) {
+            print!("{:<15}", authorship.as_str());
+            for n in row {
+                print!("  {n:>14}");
+            }
+            println!();
+        }
+    }
+
+    println!();
+    println!(
+        "{:<12}  {:>5}  {:>7}  {:>8}  {:>8}  {:>7}",
+        "DETECTOR", "FILES", "SIGNALS", "FOR", "AGAINST", "Δ ACC"
+    );
+    println!("{}", "─".repeat(59));
+    for d in &e.detectors {
+        println!(
+            "{:<12}  {:>5}  {:>7}  {:>8.1}  {:>8.1}  {:>+6.1}%",
+            d.detector,
+            d.files,
+            d.signals,
+            d.weight_for,
+            d.weight_against,
+            d.accuracy_delta * 100.0
+        );
+    }
+
+    if !e.misattributed.is_empty() {
+        println!();
+        println!("Misattributed:");
+        for m in &e.misattributed {
+            println!(
+                "  {}  expected {}, got {} ({:.0}%)",
+                m.path.display(),
+                m.label,
+                m.predicted,
+                m.confidence * 100.0
+            );
+        }
+    }
+}
@@ -0,0 +1,427 @@
+use std::path::{Path, PathBuf};
+use std::time::Duration;
+
+use anyhow::{Context, Result};
+use tokio::sync::mpsc;
+use tokio_stream::wrappers::ReceiverStream;
+use tonic::metadata::MetadataMap;
+use tonic::{Request, Response, Status};
+
+use vibecheck_core::ignore_rules::{IgnoreConfig, IgnoreRules};
+use vibecheck_core::limits::DEFAULT_MAX_FILE_SIZE;
+use vibecheck_core::report::{self, Authorship, ModelFamily, Severity};
+use vibecheck_core::source_fs::OsFs;
+use vibecheck_core::timeout::SKIPPED_TIMEOUT;
+use vibecheck_core::Analyzer;
+
+use crate::commands::check::language_extension;
+use crate::grpc::analysis_server::{Analysis, AnalysisServer};
+use crate::grpc as pb;
+use crate::policy;
+
+/// Time kept back from a call's deadline to send the reply in: analysis
+/// that would overrun it stops early and the file is reported as skipped,
+/// rather than the call failing with `DEADLINE_EXCEEDED`.
+const DEADLINE_MARGIN: Duration = Duration::from_millis(50);
+
+/// `AnalyzeRepo` results buffered ahead of a slow client.  A full buffer
+/// pauses the scan; a cancelled call stops it.
+const STREAM_BUFFER: usize = 16;
+
+/// The server's response stream for `AnalyzeRepo`.
+type FileResults = ReceiverStream<Result<pb::FileResult, Status>>;
+
+/// Serve the `vibecheck.v1.Analysis` gRPC service on `listen` until the
+/// process is killed.
+///
+/// `AnalyzeFile` scores under the `.vibecheck` config (from `ignore_file`,
+/// or discovered from the current directory), caching in `cache_dir` if
+/// set.  `AnalyzeRepo` scans directories under `root` only, each under its
+/// own `.vibecheck`.
+pub fn run(listen: &str, root: &Path, ignore_file: Option<&PathBuf>, cache_dir: Option<&PathBuf>) -> Result<()> {
+    let config = match ignore_file {
+        Some(f) => policy::from_file(f)?,
+        None => policy::load(&std::env::current_dir()?),
+    };
+    let root = root.canonicalize().with_context(|| format!("cannot serve {}", root.display()))?;
+    let service = Service { config, root, cache_dir: cache_dir.cloned() };
+    let addr = match listen.strip_prefix(':') {
+        Some(port) => format!("0.0.0.0:{port}"),
+        None => listen.to_string(),
+    };
+    let addr = addr.parse().with_context(|| format!("invalid address: {listen}"))?;
+
+    let runtime = tokio::runtime::Runtime::new()?;
+    eprintln!("Serving gRPC on {addr}");
+    runtime.block_on(tonic::transport::Server::builder().add_service(AnalysisServer::new(service)).serve(addr))?;
+    Ok(())
+}
+
+struct Service {
+    config: IgnoreConfig,
+    /// `AnalyzeRepo` roots must resolve to this directory or beneath it.
+    root: PathBuf,
+    cache_dir: Option<PathBuf>,
+}
+
+impl Service {
+    /// An analyzer for one call.  Calls differ in what they ask for and in
+    /// how long they may take, so each gets its own; the cache is shared.
+    fn analyzer(&self, config: &IgnoreConfig) -> Analyzer {
+        let analyzer = Analyzer::new().with_config(config);
+        match &self.cache_dir {
+            Some(dir) => analyzer.with_cache_dir(dir),
+            None => analyzer,
+        }
+    }
+
+    /// The directory `requested` names, relative to [`Service::root`].
+    fn resolve(&self, requested: &str) -> Result<PathBuf, Status> {
+        let path = self.root.join(requested);
+        let path = path.canonicalize().map_err(|e| Status::not_found(format!("{requested}: {e}")))?;
+        if !path.starts_with(&self.root) {
+            return Err(Status::permission_denied(format!("{requested} is outside the served root")));
+        }
+        if !path.is_dir() {
+            return Err(Status::invalid_argument(format!("{requested} is not a directory")));
+        }
+        Ok(path)
+    }
+}
+
+#[tonic::async_trait]
+impl Analysis for Service {
+    type AnalyzeRepoStream = FileResults;
+
+    async fn analyze_file(&self, request: Request<pb::AnalyzeFileRequest>) -> Result<Response<pb::Report>, Status> {
+        let deadline = deadline(request.metadata());
+        let request = request.into_inner();
+        if std::str::from_utf8(&request.source).is_err() {
+            return Err(Status::invalid_argument("source is not UTF-8"));
+        }
+        let name = match request.language.as_str() {
+            "" if request.path.is_empty() => return Err(Status::invalid_argument("need a language or a path")),
+            "" => PathBuf::from(&request.path),
+            lang => {
+                let ext = language_extension(lang).map_err(|e| Status::invalid_argument(e.to_string()))?;
+                Path::new(if request.path.is_empty() { "snippet" } else { request.path.as_str() }).with_extension(ext)
+            }
+        };
+        let path = (!request.path.is_empty()).then(|| PathBuf::from(&request.path));
+
+        let mut analyzer = self
+            .analyzer(&self.config)
+            .with_symbols(request.symbols)
+            .with_segments(request.segments)
+            .with_comment_free(request.comment_free);
+        if let Some(deadline) = deadline {
+            match deadline.checked_sub(DEADLINE_MARGIN).filter(|d| !d.is_zero()) {
+                Some(limit) => analyzer = analyzer.with_timeout(limit),
+                None => {
+                    let skipped = report::Report::skipped(path.unwrap_or(name), SKIPPED_TIMEOUT);
+                    return Ok(Response::new(to_proto(&skipped)));
+                }
+            }
+        }
+        let source = request.source;
+        let mut report = tokio::task::spawn_blocking(move || analyzer.analyze_source(&name.to_string_lossy(), &source))
+            .await
+            .map_err(|e| Status::internal(e.to_string()))?
+            .map_err(|e| Status::invalid_argument(format!("{e:#}")))?;
+        report.metadata.file_path = path;
+        Ok(Response::new(to_proto(&report)))
+    }
+
+    async fn analyze_repo(&self, request: Request<pb::AnalyzeRepoRequest>) -> Result<Response<FileResults>, Status> {
+        let request = request.into_inner();
+        let root = self.resolve(&request.root)?;
+        let config = policy::load(&root).with_excludes(&request.exclude);
+        let mut analyzer = self.analyzer(&config).with_max_file_size(match request.max_file_size {
+            0 => DEFAULT_MAX_FILE_SIZE,
+            bytes => bytes,
+        });
+        match request.timeout_per_file_seconds {
+            secs if secs == 0.0 => {}
+            secs if secs > 0.0 && secs.is_finite() => analyzer = analyzer.with_timeout(Duration::from_secs_f64(secs)),
+            secs => return Err(Status::invalid_argument(format!("timeout_per_file_seconds must be positive, got {secs}"))),
+        }
+        let ignore = RepoRules { config, include_generated: request.include_generated };
+
+        // The walk is lazy: each file is analyzed when the client has room
+        // for its result, and a dropped stream ends the walk.
+        let (tx, rx) = mpsc::channel(STREAM_BUFFER);
+        tokio::task::spawn_blocking(move || {
+            let results = match analyzer.analyze_tree(&OsFs, &root, &ignore) {
+                Ok(results) => results,
+                Err(e) => {
+                    let _ = tx.blocking_send(Err(Status::not_found(format!("{e:#}"))));
+                    return;
+                }
+            };
+            for file in results {
+                let path = file.path.strip_prefix(&root).unwrap_or(&file.path).to_path_buf();
+                let result = match file.report {
+                    Ok(mut report) => {
+                        report.metadata.file_path = Some(path.clone());
+                        pb::file_result::Result::Report(to_proto(&report))
+                    }
+                    Err(e) => pb::file_result::Result::Error(format!("{e:#}")),
+                };
+                let result = pb::FileResult { path: path.display().to_string(), result: Some(result) };
+                if tx.blocking_send(Ok(result)).is_err() {
+                    break;
+                }
+            }
+        });
+        Ok(Response::new(ReceiverStream::new(rx)))
+    }
+}
+
+/// A repository's config, with `include_generated` from the request.
+struct RepoRules {
+    config: IgnoreConfig,
+    include_generated: bool,
+}
+
+impl IgnoreRules for RepoRules {
+    fn is_ignored(&self, path: &Path) -> bool {
+        self.config.is_ignored(path)
+    }
+
+    fn is_ignored_dir(&self, path: &Path) -> bool {
+        self.config.is_ignored_dir(path)
+    }
+
+    fn include_generated(&self) -> bool {
+        self.include_generated || self.config.include_generated()
+    }
+}
+
+/// Time left before the call's deadline, from its `grpc-timeout` header:
+/// up to eight digits and a unit, `H`, `M`, `S`, `m`, `u` or `n`.
+fn deadline(metadata: &MetadataMap) -> Option<Duration> {
+    let value = metadata.get("grpc-timeout")?.to_str().ok()?;
+    let (digits, unit) = value.split_at(value.len().checked_sub(1)?);
+    if digits.is_empty() || digits.len() > 8 {
+        return None;
+    }
+    let n: u64 = digits.parse().ok()?;
+    Some(match unit {
+        "H" => Duration::from_secs(n * 3600),
+        "M" => Duration::from_secs(n * 60),
+        "S" => Duration::from_secs(n),
+        "m" => Duration::from_millis(n),
+        "u" => Duration::from_micros(n),
+        "n" => Duration::from_nanos(n),
+        _ => return None,
+    })
+}
+
+fn family(family: ModelFamily) -> pb::ModelFamily {
+    match family {
+        ModelFamily::Claude => pb::ModelFamily::Claude,
+        ModelFamily::Gpt => pb::ModelFamily::Gpt,
+        ModelFamily::Gemini => pb::ModelFamily::Gemini,
+        ModelFamily::Copilot => pb::ModelFamily::Copilot,
+        ModelFamily::Human => pb::ModelFamily::Human,
+    }
+}
+
+fn authorship(authorship: Authorship) -> pb::Authorship {
+    match authorship {
+        Authorship::HumanAuthored => pb::Authorship::HumanAuthored,
+        Authorship::AiAssisted => pb::Authorship::AiAssisted,
+        Authorship::AiGenerated => pb::Authorship::AiGenerated,
+    }
+}
+
+fn severity(severity: Severity) -> pb::Severity {
+    match severity {
+        Severity::Info => pb::Severity::Info,
+        Severity::Warn => pb::Severity::Warn,
+        Severity::Error => pb::Severity::Error,
+    }
+}
+
+fn attribution(attribution: &report::Attribution) -> pb::Attribution {
+    pb::Attribution {
+        primary: family(attribution.primary) as i32,
+        confidence: attribution.confidence,
+        // Keyed as in the JSON report.
+        scores: attribution.scores.iter().map(|(f, &s)| (f.abbrev().to_lowercase(), s)).collect(),
+        era: attribution.era.clone(),
+        ai_probability: attribution.ai_probability,
+        authorship: attribution.authorship.map(|a| authorship(a) as i32),
+    }
+}
+
+fn signals(signals: &[report::Signal]) -> Vec<pb::Signal> {
+    signals
+        .iter()
+        .map(|s| pb::Signal {
+            id: s.id.clone(),
+            source: s.source.clone(),
+            description: s.description.clone(),
+            family: family(s.family) as i32,
+            weight: s.weight,
+            lines: s.lines.iter().map(|&l| l as u32).collect(),
+            severity: severity(s.severity) as i32,
+        })
+        .collect()
+}
+
+/// `report` as the `vibecheck.v1.Report` message.
+fn to_proto(report: &report::Report) -> pb::Report {
+    let metadata = &report.metadata;
+    pb::Report {
+        attribution: Some(attribution(&report.attribution)),
+        signals: signals(&report.signals),
+        metadata: Some(pb::ReportMetadata {
+            file_path: metadata.file_path.as_ref().map(|p| p.display().to_string()).unwrap_or_default(),
+            lines_of_code: metadata.lines_of_code as u32,
+            signal_count: metadata.signal_count as u32,
+            degraded: metadata.degraded.iter().map(|c| c.to_string()).collect(),
+            skipped: metadata.skipped.clone(),
+        }),
+        symbol_reports: report
+            .symbol_reports
+            .iter()
+            .flatten()
+            .map(|s| pb::SymbolReport {
+                name: s.metadata.name.clone(),
+                kind: s.metadata.kind.clone(),
+                start_line: s.metadata.start_line as u32,
+                end_line: s.metadata.end_line as u32,
+                attribution: Some(attribution(&s.attribution)),
+                signals: signals(&s.signals),
+            })
+            .collect(),
+        segments: report
+            .segments
+            .iter()
+            .flatten()
+            .map(|s| pb::SegmentReport {
+                start_line: s.start_line as u32,
+                end_line: s.end_line as u32,
+                attribution: Some(attribution(&s.attribution)),
+                signals: signals(&s.signals),
+            })
+            .collect(),
+        comment_free: report.comment_free.as_ref().map(|c| pb::CommentFreeReport {
+            attribution: Some(attribution(&c.attribution)),
+            signal_count: c.signal_count as u32,
+        }),
+        ensemble: report.ensemble.as_ref().map(|e| pb::EnsembleReport {
+            verdicts: e
+                .verdicts
+                .iter()
+                .map(|v| pb::BackendVerdict {
+                    backend: v.backend.clone(),
+                    primary: family(v.primary) as i32,
+                    confidence: v.confidence,
+                })
+                .collect(),
+            disagreement: e.disagreement,
+        }),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::grpc::analysis_client::AnalysisClient;
+    use tokio_stream::StreamExt;
+
+    fn service(root: &Path) -> Service {
+        Service { config: IgnoreConfig::load(root), root: root.canonicalize().unwrap(), cache_dir: None }
+    }
+
+    /// Serve `service` on a free local port and connect a client to it.
+    async fn connect(service: Service) -> AnalysisClient<tonic::transport::Channel> {
+        let listener = tokio::net::TcpListener::bind("127.0.0.1:0").await.unwrap();
+        let addr = listener.local_addr().unwrap();
+        tokio::spawn(
+            tonic::transport::Server::builder()
+                .add_service(AnalysisServer::new(service))
+                .serve_with_incoming(tokio_stream::wrappers::TcpListenerStream::new(listener)),
+        );
+        AnalysisClient::connect(format!("http://{addr}")).await.unwrap()
+    }
+
+    #[test]
+    fn analyze_file_and_repo_over_the_wire() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::create_dir(dir.path().join("repo")).unwrap();
+        std::fs::write(dir.path().join("repo/a.py"), "def total(xs):\n    return sum(xs)\n").unwrap();
+        std::fs::write(dir.path().join("repo/b.go"), "package main\n\nfunc main() {}\n").unwrap();
+        let runtime = tokio::runtime::Runtime::new().unwrap();
+        runtime.block_on(async {
+            let mut client = connect(service(dir.path())).await;
+
+            let request = pb::AnalyzeFileRequest {
+                source: b"fn main() {\n    println!(\"hi\");\n}\n".to_vec(),
+                language: "rust".into(),
+                path: "src/main.rs".into(),
+                comment_free: true,
+                ..Default::default()
+            };
+            let report = client.analyze_file(request).await.unwrap().into_inner();
+            assert_eq!(report.metadata.unwrap().file_path, "src/main.rs");
+            let attribution = report.attribution.unwrap();
+            assert!(!attribution.scores.is_empty());
+            assert_eq!(attribution.authorship.is_some(), attribution.ai_probability.is_some());
+            assert!(report.comment_free.is_some());
+            assert!(report.signals.iter().all(|s| s.severity != pb::Severity::Unspecified as i32));
+
+            let request = pb::AnalyzeRepoRequest { root: "repo".into(), ..Default::default() };
+            let stream = client.analyze_repo(request).await.unwrap().into_inner();
+            let results: Vec<pb::FileResult> = stream.map(Result::unwrap).collect().await;
+            let paths: Vec<&str> = results.iter().map(|r| r.path.as_str()).collect();
+            assert_eq!(paths, ["a.py", "b.go"]);
+            assert!(results.iter().all(|r| matches!(r.result, Some(pb::file_result::Result::Report(_)))));
+
+            let outside = pb::AnalyzeRepoRequest { root: "..".into(), ..Default::default() };
+            let status = client.analyze_repo(outside).await.unwrap_err();
+            assert_eq!(status.code(), tonic::Code::PermissionDenied);
+        });
+    }
+
+    #[test]
+    fn deadlines_bound_the_analysis() {
+        let dir = tempfile::tempdir().unwrap();
+        let runtime = tokio::runtime::Runtime::new().unwrap();
+        let service = service(dir.path());
+        let call = |timeout: Option<&str>| {
+            let mut request = Request::new(pb::AnalyzeFileRequest {
+                source: b"x = 1\n".to_vec(),
+                path: "x.py".into(),
+                ..Default::default()
+            });
+            if let Some(timeout) = timeout {
+                request.metadata_mut().insert("grpc-timeout", timeout.parse().unwrap());
+            }
+            runtime.block_on(service.analyze_file(request)).unwrap().into_inner().metadata.unwrap()
+        };
+        assert_eq!(call(None).skipped, None);
+        assert_eq!(call(Some("10S")).skipped, None);
+        // No time left to analyze in: skipped, not failed.
+        assert_eq!(call(Some("20m")).skipped.as_deref(), Some(SKIPPED_TIMEOUT));
+        assert_eq!(call(Some("20m")).file_path, "x.py");
+    }
+
+    #[test]
+    fn parses_grpc_timeouts() {
+        let parse = |value: &str| {
+            let mut metadata = MetadataMap::new();
+            metadata.insert("grpc-timeout", value.parse().unwrap());
+            deadline(&metadata)
+        };
+        assert_eq!(parse("3S"), Some(Duration::from_secs(3)));
+        assert_eq!(parse("250m"), Some(Duration::from_millis(250)));
+        assert_eq!(parse("1H"), Some(Duration::from_secs(3600)));
+        assert_eq!(parse("123456789S"), None, "at most eight digits");
+        assert_eq!(parse("S"), None);
+        assert_eq!(parse("5x"), None);
+        assert_eq!(deadline(&MetadataMap::new()), None);
+    }
+}
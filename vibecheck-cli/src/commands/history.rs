@@ -13,16 +13,7 @@ pub fn run(path: &Path, limit: Option<usize>) -> Result<()> {
     let repo = Repository::discover(path)
         .context("not inside a git repository (or no .git found)")?;
 
-    let workdir = repo
-        .workdir()
-        .context("bare repositories are not supported")?;
-
-    let relative = path
-        .canonicalize()
-        .unwrap_or_else(|_| path.to_path_buf())
-        .strip_prefix(workdir.canonicalize().unwrap_or_else(|_| workdir.to_path_buf()))
-        .context("path is not inside the repository work tree")?
-        .to_path_buf();
+    let relative = relative_path(&repo, path)?;
 
     let is_dir = path.is_dir();
 
@@ -137,10 +128,10 @@ pub fn run(path: &Path, limit: Option<usize>) -> Result<()> {
 /// line-weighted dominant family + confidence. Returns `None` if no
 /// supported source files are found in the tree.
 fn aggregate_tree(repo: &Repository, tree: &git2::Tree) -> Option<(ModelFamily, f64)> {
-    use std::collections::HashMap;
+    use std::collections::BTreeMap;
 
     let mut total_lines = 0usize;
-    let mut family_scores: HashMap<ModelFamily, f64> = HashMap::new();
+    let mut family_scores: BTreeMap<ModelFamily, f64> = BTreeMap::new();
 
     tree.walk(git2::TreeWalkMode::PreOrder, |_root, entry| {
         if entry.kind() != Some(git2::ObjectType::Blob) {
@@ -180,12 +171,388 @@ fn aggregate_tree(repo: &Repository, tree: &git2::Tree) -> Option<(ModelFamily,
 
 /// Return `true` for file extensions vibecheck can analyse.
 fn is_source_file(name: &str) -> bool {
-    matches!(
-        std::path::Path::new(name)
-            .extension()
-            .and_then(|e| e.to_str()),
-        Some("rs" | "py" | "js" | "ts" | "go")
-    )
+    std::path::Path::new(name)
+        .extension()
+        .and_then(|e| e.to_str())
+        .map(|e| vibecheck_core::language::SUPPORTED_EXTENSIONS.contains(&e))
+        .unwrap_or(false)
+}
+
+// ---------------------------------------------------------------------------
+// Trend since a revision
+// ---------------------------------------------------------------------------
+
+/// Width of the sparklines; longer histories are averaged into this many
+/// buckets.
+const SPARKLINE_WIDTH: usize = 60;
+
+/// Sparkline levels from 0% to 100% AI, plain ASCII so the summary survives
+/// CI logs and terminals without Unicode fonts.
+pub(crate) const SPARK_LEVELS: &[u8] = b"_.-~=+*#";
+
+/// Rules listed per author by `--group-by author`.
+const TOP_RULES: usize = 3;
+
+/// Lines added by one commit and how many of them were attributed to AI.
+struct CommitPoint {
+    id: String,
+    time: i64,
+    summary: String,
+    author: String,
+    email: String,
+    added_lines: usize,
+    ai_lines: usize,
+    /// AI-family signals that fired on the AI-attributed files, by ID.
+    rules: Vec<String>,
+}
+
+impl CommitPoint {
+    fn fraction(&self) -> f64 {
+        self.ai_lines as f64 / self.added_lines.max(1) as f64
+    }
+}
+
+/// Replay every commit after `since` up to HEAD, score the lines each one
+/// added under `path`, and print how the AI-generated share of the added
+/// code has moved: a JSON time series (`format == "json"`) or ASCII
+/// sparklines.  With `group_by_author` the commits are instead rolled up
+/// per author; `anonymize` replaces author names with `author-N`.
+pub fn run_since(path: &Path, since: &str, format: &str, group_by_author: bool, anonymize: bool) -> Result<()> {
+    let repo = Repository::discover(path)
+        .context("not inside a git repository (or no .git found)")?;
+    let relative = relative_path(&repo, path)?;
+
+    let base = repo
+        .revparse_single(since)
+        .and_then(|o| o.peel_to_commit())
+        .with_context(|| format!("unknown revision {since:?}"))?;
+
+    let points = commit_points(&repo, &relative, base.id())?;
+
+    let label = if relative.as_os_str().is_empty() {
+        ".".to_string()
+    } else {
+        relative.display().to_string()
+    };
+    if group_by_author {
+        let authors = by_author(&points, anonymize);
+        match format {
+            "json" => println!("{}", serde_json::to_string_pretty(&authors_json(&label, since, &authors))?),
+            _ => print!("{}", authors_table(&label, since, &authors)),
+        }
+        return Ok(());
+    }
+    match format {
+        "json" => println!("{}", serde_json::to_string_pretty(&series_json(&label, since, &points))?),
+        _ => print!("{}", sparkline_summary(&label, since, &points)),
+    }
+    Ok(())
+}
+
+/// `path` relative to the work tree of `repo`; empty for the root.
+pub(crate) fn relative_path(repo: &Repository, path: &Path) -> Result<std::path::PathBuf> {
+    let workdir = repo
+        .workdir()
+        .context("bare repositories are not supported")?;
+    Ok(path
+        .canonicalize()
+        .unwrap_or_else(|_| path.to_path_buf())
+        .strip_prefix(workdir.canonicalize().unwrap_or_else(|_| workdir.to_path_buf()))
+        .context("path is not inside the repository work tree")?
+        .to_path_buf())
+}
+
+/// Score the commits reachable from HEAD but not from `base`, oldest
+/// first.  Merge commits are skipped — their lines were added by the
+/// commits being merged — as are commits that added no analysable source
+/// under `relative`.
+fn commit_points(repo: &Repository, relative: &Path, base: git2::Oid) -> Result<Vec<CommitPoint>> {
+    use std::collections::BTreeMap;
+
+    let mut revwalk = repo.revwalk()?;
+    revwalk.push_head().context("no HEAD commit found")?;
+    revwalk.hide(base)?;
+    revwalk.set_sorting(Sort::TOPOLOGICAL | Sort::TIME | Sort::REVERSE)?;
+
+    let pipeline = vibecheck_core::pipeline::Pipeline::with_defaults();
+    let mut points = Vec::new();
+
+    for oid_result in revwalk {
+        let commit = repo.find_commit(oid_result?)?;
+        if commit.parent_count() > 1 {
+            continue;
+        }
+        let parent_tree = match commit.parent(0) {
+            Ok(parent) => Some(parent.tree()?),
+            Err(_) => None,
+        };
+
+        let mut opts = git2::DiffOptions::new();
+        if !relative.as_os_str().is_empty() {
+            opts.pathspec(relative);
+        }
+        let diff = repo.diff_tree_to_tree(parent_tree.as_ref(), Some(&commit.tree()?), Some(&mut opts))?;
+
+        // Added lines, grouped by the file they were added to.
+        let mut added: BTreeMap<std::path::PathBuf, String> = BTreeMap::new();
+        diff.print(git2::DiffFormat::Patch, |delta, _hunk, line| {
+            if line.origin() != '+' {
+                return true;
+            }
+            let Some(file) = delta.new_file().path() else {
+                return true;
+            };
+            let name = file.file_name().and_then(|n| n.to_str()).unwrap_or("");
+            if let (true, Ok(text)) = (is_source_file(name), std::str::from_utf8(line.content())) {
+                added.entry(file.to_path_buf()).or_default().push_str(text);
+            }
+            true
+        })?;
+
+        let author = commit.author();
+        let mut point = CommitPoint {
+            id: commit.id().to_string(),
+            time: commit.time().seconds(),
+            summary: commit.summary().unwrap_or("").to_string(),
+            author: author.name().unwrap_or("").to_string(),
+            email: author.email().unwrap_or("").to_string(),
+            added_lines: 0,
+            ai_lines: 0,
+            rules: Vec::new(),
+        };
+        for (file, source) in added {
+            let lines = source.lines().filter(|l| !l.trim().is_empty()).count();
+            let report = pipeline.run(&source, Some(file));
+            point.added_lines += lines;
+            if report.attribution.has_sufficient_data() && report.attribution.primary != ModelFamily::Human {
+                point.ai_lines += lines;
+                point.rules.extend(
+                    report
+                        .signals
+                        .into_iter()
+                        .filter(|s| !s.id.is_empty() && s.family != ModelFamily::Human && s.weight > 0.0)
+                        .map(|s| s.id),
+                );
+            }
+        }
+        if point.added_lines > 0 {
+            points.push(point);
+        }
+    }
+    Ok(points)
+}
+
+/// The JSON time series: one entry per commit with its own AI share and
+/// the cumulative share of all lines added since `since`.
+fn series_json(label: &str, since: &str, points: &[CommitPoint]) -> serde_json::Value {
+    let (mut added, mut ai) = (0usize, 0usize);
+    let series: Vec<serde_json::Value> = points
+        .iter()
+        .map(|p| {
+            added += p.added_lines;
+            ai += p.ai_lines;
+            serde_json::json!({
+                "commit": p.id,
+                "date": format_date(p.time),
+                "timestamp": p.time,
+                "summary": p.summary,
+                "added_lines": p.added_lines,
+                "ai_lines": p.ai_lines,
+                "ai_fraction": p.fraction(),
+                "cumulative_ai_fraction": ai as f64 / added as f64,
+            })
+        })
+        .collect();
+    serde_json::json!({
+        "path": label,
+        "since": since,
+        "commits": points.len(),
+        "added_lines": added,
+        "ai_lines": ai,
+        "ai_fraction": if added == 0 { 0.0 } else { ai as f64 / added as f64 },
+        "series": series,
+    })
+}
+
+fn sparkline_summary(label: &str, since: &str, points: &[CommitPoint]) -> String {
+    let mut out = format!("AI-generated share of lines added to {label} since {since}\n\n");
+    let (Some(first), Some(last)) = (points.first(), points.last()) else {
+        out.push_str(&format!("(no commits since {since} added source to {label})\n"));
+        return out;
+    };
+
+    let per_commit: Vec<f64> = points.iter().map(CommitPoint::fraction).collect();
+    let (mut added, mut ai) = (0usize, 0usize);
+    let cumulative: Vec<f64> = points
+        .iter()
+        .map(|p| {
+            added += p.added_lines;
+            ai += p.ai_lines;
+            ai as f64 / added as f64
+        })
+        .collect();
+
+    out.push_str(&format!(
+        "  cumulative  {}  {:.0}% → {:.0}%\n",
+        sparkline(&cumulative),
+        cumulative[0] * 100.0,
+        cumulative[cumulative.len() - 1] * 100.0,
+    ));
+    out.push_str(&format!("  per commit  {}\n\n", sparkline(&per_commit)));
+    out.push_str(&format!(
+        "  {} commits ({} → {}), {ai} of {added} added lines attributed to AI\n",
+        points.len(),
+        format_date(first.time),
+        format_date(last.time),
+    ));
+    out.push_str(&format!(
+        "  scale: {} = 0% … {} = 100%\n",
+        SPARK_LEVELS[0] as char,
+        SPARK_LEVELS[SPARK_LEVELS.len() - 1] as char,
+    ));
+    out
+}
+
+// ---------------------------------------------------------------------------
+// Per-author rollup
+// ---------------------------------------------------------------------------
+
+/// One author's commits since the base revision.
+struct AuthorSummary {
+    author: String,
+    commits: usize,
+    added_lines: usize,
+    ai_lines: usize,
+    /// Most frequent AI-family signals with the number of files each fired
+    /// in, most frequent first.
+    top_rules: Vec<(String, usize)>,
+}
+
+impl AuthorSummary {
+    fn fraction(&self) -> f64 {
+        self.ai_lines as f64 / self.added_lines.max(1) as f64
+    }
+}
+
+/// Roll `points` up per author, keyed by email so a changed display name
+/// does not split anyone in two.  Ordered by AI share, highest first.
+///
+/// With `anonymize`, authors are named `author-1`, `author-2`, … in order
+/// of their first commit in the range, so a report can be shared without
+/// saying who used an assistant.  The numbering is stable for a given
+/// range but not across ranges.
+fn by_author(points: &[CommitPoint], anonymize: bool) -> Vec<AuthorSummary> {
+    use std::collections::HashMap;
+
+    // email → (display name, commits, added, ai, rule counts), in first-seen order.
+    let mut order: Vec<String> = Vec::new();
+    let mut totals: HashMap<&str, (&str, usize, usize, usize, HashMap<&str, usize>)> = HashMap::new();
+    for p in points {
+        let key = if p.email.is_empty() { p.author.as_str() } else { p.email.as_str() };
+        let entry = totals.entry(key).or_insert_with(|| {
+            order.push(key.to_string());
+            (p.author.as_str(), 0, 0, 0, HashMap::new())
+        });
+        entry.0 = p.author.as_str();
+        entry.1 += 1;
+        entry.2 += p.added_lines;
+        entry.3 += p.ai_lines;
+        for rule in &p.rules {
+            *entry.4.entry(rule.as_str()).or_insert(0) += 1;
+        }
+    }
+
+    let mut authors: Vec<AuthorSummary> = order
+        .iter()
+        .enumerate()
+        .map(|(i, key)| {
+            let (name, commits, added_lines, ai_lines, rules) = &totals[key.as_str()];
+            let mut top_rules: Vec<(String, usize)> = rules.iter().map(|(id, n)| (id.to_string(), *n)).collect();
+            top_rules.sort_by(|a, b| b.1.cmp(&a.1).then_with(|| a.0.cmp(&b.0)));
+            top_rules.truncate(TOP_RULES);
+            let author = match (anonymize, name.is_empty()) {
+                (true, _) => format!("author-{}", i + 1),
+                (false, true) => key.clone(),
+                (false, false) if key.contains('@') => format!("{name} <{key}>"),
+                (false, false) => name.to_string(),
+            };
+            AuthorSummary {
+                author,
+                commits: *commits,
+                added_lines: *added_lines,
+                ai_lines: *ai_lines,
+                top_rules,
+            }
+        })
+        .collect();
+    authors.sort_by(|a, b| {
+        b.fraction()
+            .partial_cmp(&a.fraction())
+            .unwrap()
+            .then_with(|| b.added_lines.cmp(&a.added_lines))
+    });
+    authors
+}
+
+fn authors_json(label: &str, since: &str, authors: &[AuthorSummary]) -> serde_json::Value {
+    let authors: Vec<serde_json::Value> = authors
+        .iter()
+        .map(|a| {
+            serde_json::json!({
+                "author": a.author,
+                "commits": a.commits,
+                "added_lines": a.added_lines,
+                "ai_lines": a.ai_lines,
+                "ai_fraction": a.fraction(),
+                "top_rules": a.top_rules.iter().map(|(id, files)| serde_json::json!({ "id": id, "files": files })).collect::<Vec<_>>(),
+            })
+        })
+        .collect();
+    serde_json::json!({
+        "path": label,
+        "since": since,
+        "authors": authors,
+    })
+}
+
+fn authors_table(label: &str, since: &str, authors: &[AuthorSummary]) -> String {
+    let mut out = format!("AI-generated share of lines added to {label} since {since}, by author\n\n");
+    if authors.is_empty() {
+        out.push_str(&format!("(no commits since {since} added source to {label})\n"));
+        return out;
+    }
+    let width = authors.iter().map(|a| a.author.chars().count()).max().unwrap_or(0).max(6);
+    out.push_str(&format!(
+        "{:<width$}  {:>7}  {:>7}  {:>6}  TOP RULES\n",
+        "AUTHOR", "COMMITS", "ADDED", "AI"
+    ));
+    out.push_str(&format!("{}\n", "─".repeat(width + 40)));
+    for a in authors {
+        let rules: Vec<String> = a.top_rules.iter().map(|(id, n)| format!("{id} ({n})")).collect();
+        out.push_str(&format!(
+            "{:<width$}  {:>7}  {:>7}  {:>5.0}%  {}\n",
+            a.author,
+            a.commits,
+            a.added_lines,
+            a.fraction() * 100.0,
+            if rules.is_empty() { "—".to_string() } else { rules.join(", ") },
+        ));
+    }
+    out
+}
+
+/// Render fractions in `0.0..=1.0` as an ASCII sparkline at most
+/// [`SPARKLINE_WIDTH`] characters wide.
+pub(crate) fn sparkline(values: &[f64]) -> String {
+    let buckets = values.len().min(SPARKLINE_WIDTH);
+    (0..buckets)
+        .map(|b| {
+            let bucket = &values[b * values.len() / buckets..(b + 1) * values.len() / buckets];
+            let mean = bucket.iter().sum::<f64>() / bucket.len() as f64;
+            let level = (mean.clamp(0.0, 1.0) * (SPARK_LEVELS.len() - 1) as f64).round() as usize;
+            SPARK_LEVELS[level] as char
+        })
+        .collect()
 }
 
 #[cfg(test)]
@@ -209,6 +576,95 @@ mod tests {
         assert!(!is_source_file("noextension"));
     }
 
+    #[test]
+    fn sparkline_maps_fractions_to_levels() {
+        assert_eq!(sparkline(&[0.0, 0.5, 1.0]), "_=#");
+        assert_eq!(sparkline(&[]), "");
+    }
+
+    #[test]
+    fn sparkline_averages_long_histories() {
+        let values: Vec<f64> = (0..SPARKLINE_WIDTH * 3).map(|i| if i % 3 == 0 { 1.0 } else { 0.0 }).collect();
+        let line = sparkline(&values);
+        assert_eq!(line.len(), SPARKLINE_WIDTH);
+        assert!(line.chars().all(|c| c == '-'), "{line}");
+    }
+
+    #[test]
+    fn commit_points_count_lines_added_since_base() {
+        let dir = tempfile::tempdir().unwrap();
+        let repo = Repository::init(dir.path()).unwrap();
+        let sig = git2::Signature::new("Dev", "dev@example.com", &git2::Time::new(1_700_000_000, 0)).unwrap();
+        let commit = |files: &[(&str, &str)], message: &str| {
+            for (name, content) in files {
+                std::fs::write(dir.path().join(name), content).unwrap();
+            }
+            let mut index = repo.index().unwrap();
+            index.add_all(["*"], git2::IndexAddOption::DEFAULT, None).unwrap();
+            index.write().unwrap();
+            let tree = repo.find_tree(index.write_tree().unwrap()).unwrap();
+            let parent = repo.head().ok().map(|h| h.peel_to_commit().unwrap());
+            let parents: Vec<&git2::Commit> = parent.iter().collect();
+            repo.commit(Some("HEAD"), &sig, &sig, message, &tree, &parents).unwrap()
+        };
+
+        let base = commit(&[("lib.rs", "fn a() {}\n")], "base");
+        commit(&[("lib.rs", "fn a() {}\n\nfn b() {}\nfn c() {}\n"), ("notes.md", "# notes\n")], "add b and c");
+        commit(&[("notes.md", "# notes\nmore\n")], "docs only");
+
+        let points = commit_points(&repo, Path::new(""), base).unwrap();
+        assert_eq!(points.len(), 1, "the docs-only commit adds no source");
+        assert_eq!(points[0].summary, "add b and c");
+        assert_eq!(points[0].added_lines, 2);
+
+        let json = series_json(".", "base", &points);
+        assert_eq!(json["added_lines"], 2);
+        assert_eq!(json["series"][0]["date"], "2023-11-14");
+    }
+
+    fn point(author: &str, email: &str, added_lines: usize, ai_lines: usize, rules: &[&str]) -> CommitPoint {
+        CommitPoint {
+            id: String::new(),
+            time: 0,
+            summary: String::new(),
+            author: author.into(),
+            email: email.into(),
+            added_lines,
+            ai_lines,
+            rules: rules.iter().map(|r| r.to_string()).collect(),
+        }
+    }
+
+    #[test]
+    fn by_author_rolls_up_by_email() {
+        let points = vec![
+            point("Ada", "ada@example.com", 10, 0, &[]),
+            point("Bob", "bob@example.com", 10, 8, &["rust.ai_signals.all_fns_documented", "rust.errors.zero_unwrap"]),
+            point("Ada L.", "ada@example.com", 10, 5, &["rust.errors.zero_unwrap"]),
+            point("Bob", "bob@example.com", 10, 10, &["rust.errors.zero_unwrap"]),
+        ];
+        let authors = by_author(&points, false);
+        assert_eq!(authors.len(), 2);
+        assert_eq!(authors[0].author, "Bob <bob@example.com>");
+        assert_eq!((authors[0].commits, authors[0].added_lines, authors[0].ai_lines), (2, 20, 18));
+        assert_eq!(
+            authors[0].top_rules,
+            vec![("rust.errors.zero_unwrap".to_string(), 2), ("rust.ai_signals.all_fns_documented".to_string(), 1)]
+        );
+        assert_eq!(authors[1].author, "Ada L. <ada@example.com>", "latest display name wins");
+        assert_eq!(authors[1].fraction(), 0.25);
+    }
+
+    #[test]
+    fn by_author_anonymizes_in_order_of_first_commit() {
+        let points = vec![point("Ada", "ada@example.com", 10, 0, &[]), point("Bob", "bob@example.com", 10, 10, &[])];
+        let authors = by_author(&points, true);
+        assert_eq!(authors[0].author, "author-2");
+        assert_eq!(authors[1].author, "author-1");
+        let table = authors_table(".", "v1", &authors);
+        assert!(!table.contains("Ada") && !table.contains("example.com"), "{table}");
+    }
+
     #[test]
     fn format_date_epoch() {
         assert_eq!(format_date(0), "1970-01-01");
@@ -228,7 +684,7 @@ mod tests {
 }
 
 /// Format a Unix timestamp as `YYYY-MM-DD`.
-fn format_date(unix_secs: i64) -> String {
+pub(crate) fn format_date(unix_secs: i64) -> String {
     // Hand-rolled to avoid a chrono dependency.
     let secs = unix_secs as u64;
     let days_since_epoch = secs / 86400;
@@ -1,5 +1,24 @@
 pub mod analyze;
+pub mod bench;
+pub mod blame;
+pub mod bot;
+pub mod check;
+pub mod commits;
+pub mod compare;
+pub mod corpus;
+pub mod eval;
+#[cfg(feature = "grpc")]
+pub mod grpc;
 pub mod heuristics;
 pub mod history;
+pub mod org;
+pub mod report;
+pub mod review;
+pub mod rules;
+pub mod scan;
+pub mod serve;
+pub mod top;
+pub mod trend;
 pub mod tui;
+pub mod tune;
 pub mod watch;
@@ -0,0 +1,643 @@
+//! `vibecheck org scan`: one report over many repositories.
+//!
+//! The repositories are listed in a small YAML or TOML file (see
+//! [`parse_config`]):
+//! local checkouts, or remote URLs that are shallow-cloned into a
+//! temporary directory.  They are scanned concurrently, each summarized as
+//! a [`Rollup`] with its most AI-like files, and the summaries make up one
+//! report.
+//!
+//! Each repository's summary is saved under the state directory as soon as
+//! it is scanned, so a run that dies halfway, or in which some clones
+//! failed, picks up where it left off when rerun; `--fresh` starts over.
+
+use std::collections::{BTreeMap, HashMap, HashSet, VecDeque};
+use std::path::{Path, PathBuf};
+use std::sync::Mutex;
+
+use anyhow::{Context, Result};
+use serde_json::{json, Value};
+
+use vibecheck_core::ignore_rules::IgnoreRules;
+use vibecheck_core::ranking::{self, Offender};
+use vibecheck_core::report::ModelFamily;
+use vibecheck_core::rollup::{self, Rollup};
+use vibecheck_core::Analyzer;
+
+use crate::commands::analyze::collect_files;
+use crate::commands::trend;
+use crate::policy;
+
+/// Directory, beside the repository list, where each repository's summary
+/// is saved once scanned.
+pub const STATE_DIR: &str = ".vibecheck-org";
+
+/// Repositories scanned at once unless `--jobs` or `jobs` says otherwise.
+const DEFAULT_JOBS: usize = 4;
+
+/// Most AI-like files kept per repository.
+const TOP_FILES: usize = 5;
+
+/// Where a repository's code comes from.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub enum Source {
+    /// A checkout on disk.
+    Path(PathBuf),
+    /// A remote, shallow-cloned for the scan.
+    Url(String),
+}
+
+impl Source {
+    /// `text` as a URL (`scheme://…` or `git@host:…`), or else as a path
+    /// relative to `base`.
+    fn parse(text: &str, base: &Path) -> Self {
+        let scp = text.starts_with("git@") && text.contains(':');
+        if text.contains("://") || scp {
+            Source::Url(text.to_string())
+        } else {
+            Source::Path(base.join(text))
+        }
+    }
+
+    fn label(&self) -> String {
+        match self {
+            Source::Path(path) => path.display().to_string(),
+            Source::Url(url) => url.clone(),
+        }
+    }
+}
+
+/// One entry of the repository list.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Repo {
+    /// Unique within the list; names the repository in the report and its
+    /// saved summary.
+    pub name: String,
+    pub source: Source,
+    /// Branch or tag to clone instead of the default branch; remotes only.
+    pub reference: Option<String>,
+    /// Gitignore-style globs skipped on top of the repository's own
+    /// `.vibecheck`, as with `--exclude`.
+    pub exclude: Vec<String>,
+}
+
+/// A parsed repository list.
+#[derive(Debug, Default)]
+pub struct OrgConfig {
+    pub jobs: Option<usize>,
+    pub repos: Vec<Repo>,
+}
+
+/// The repository list file, as written.
+#[derive(serde::Deserialize)]
+#[serde(deny_unknown_fields)]
+struct ListFile {
+    jobs: Option<usize>,
+    #[serde(default)]
+    repos: Vec<toml::Value>,
+}
+
+/// A `[[repos]]` table, or a mapping in a YAML list.
+#[derive(serde::Deserialize)]
+#[serde(deny_unknown_fields)]
+struct RepoTable {
+    name: Option<String>,
+    path: Option<String>,
+    url: Option<String>,
+    #[serde(rename = "ref")]
+    reference: Option<String>,
+    #[serde(default)]
+    exclude: Vec<String>,
+}
+
+/// Whether the list at `path` is YAML (`.yaml` or `.yml`) rather than TOML.
+fn is_yaml(path: &Path) -> bool {
+    matches!(path.extension().and_then(|e| e.to_str()), Some("yaml" | "yml"))
+}
+
+/// Parse a repository list, YAML if `yaml` is set and TOML otherwise.
+/// Relative paths are resolved against `base`, the directory of the list.
+///
+/// `repos` holds a path or URL per repository, or a table of `path` or
+/// `url` with optional `name`, `ref` and `exclude`; `jobs` sets how many
+/// are scanned at once.
+///
+/// ```yaml
+/// jobs: 8
+/// repos:
+///   - ../billing
+///   - url: https://github.com/acme/api.git
+///     ref: release
+///     exclude: ["vendor/**", "*.pb.go"]
+/// ```
+pub fn parse_config(text: &str, yaml: bool, base: &Path) -> Result<OrgConfig> {
+    let list: ListFile = if yaml { serde_yaml::from_str(text)? } else { toml::from_str(text)? };
+    anyhow::ensure!(list.jobs != Some(0), "jobs must be a positive number");
+    let mut names = HashSet::new();
+    let mut repos = Vec::with_capacity(list.repos.len());
+    for (i, entry) in list.repos.into_iter().enumerate() {
+        let repo = parse_repo(entry, base).with_context(|| format!("repos[{i}]"))?;
+        anyhow::ensure!(
+            names.insert(repo.name.clone()),
+            "repos[{i}]: a repository named `{}` is already listed; give one a `name`",
+            repo.name
+        );
+        repos.push(repo);
+    }
+    Ok(OrgConfig { jobs: list.jobs, repos })
+}
+
+/// One `repos` entry: a path or URL string, or a [`RepoTable`].
+fn parse_repo(entry: toml::Value, base: &Path) -> Result<Repo> {
+    if let toml::Value::String(source) = &entry {
+        anyhow::ensure!(!source.is_empty(), "empty repository");
+        return Ok(Repo {
+            name: default_name(source, base),
+            source: Source::parse(source, base),
+            reference: None,
+            exclude: Vec::new(),
+        });
+    }
+    let table: RepoTable = entry.try_into()?;
+    let source = match (table.path, table.url) {
+        (Some(path), None) => Source::Path(base.join(path)),
+        (None, Some(url)) => Source::Url(url),
+        _ => anyhow::bail!("give a repository exactly one of `path` or `url`"),
+    };
+    anyhow::ensure!(
+        table.reference.is_none() || matches!(source, Source::Url(_)),
+        "`ref` needs a `url`; check out the branch in a local path yourself"
+    );
+    let name = match table.name {
+        Some(name) => {
+            anyhow::ensure!(is_name(&name), "name `{name}` may only use letters, digits, `.`, `_` and `-`");
+            name
+        }
+        None => default_name(&source.label(), base),
+    };
+    Ok(Repo { name, source, reference: table.reference, exclude: table.exclude })
+}
+
+fn is_name(name: &str) -> bool {
+    !name.is_empty() && !name.starts_with('.') && name.chars().all(|c| c.is_ascii_alphanumeric() || "._-".contains(c))
+}
+
+/// A repository's name from its path or URL: the last path element, less
+/// any `.git`, with characters [`is_name`] rejects replaced by `-`.
+fn default_name(source: &str, base: &Path) -> String {
+    let trimmed = source.trim_end_matches(['/', '\\']);
+    let last = trimmed.rsplit(['/', '\\', ':']).next().unwrap_or(trimmed);
+    let last = match last {
+        "" | "." | ".." => {
+            let dir = base.join(trimmed).canonicalize().ok();
+            dir.and_then(|d| d.file_name().map(|n| n.to_string_lossy().into_owned())).unwrap_or_else(|| "repo".into())
+        }
+        last => last.strip_suffix(".git").unwrap_or(last).to_string(),
+    };
+    let name: String = last.chars().map(|c| if c.is_ascii_alphanumeric() || "._-".contains(c) { c } else { '-' }).collect();
+    match name.trim_start_matches('.') {
+        "" => "repo".to_string(),
+        name => name.to_string(),
+    }
+}
+
+/// What a scan of one repository found.
+#[derive(Debug, Clone)]
+struct RepoResult {
+    name: String,
+    /// [`Source::label`] of what was scanned.
+    source: String,
+    reference: Option<String>,
+    time: i64,
+    /// The commit checked out, when the repository is a git checkout.
+    commit: Option<String>,
+    rollup: Rollup,
+    /// The most AI-like files, paths relative to the repository.
+    top: Vec<Offender>,
+    /// Loaded from an earlier run instead of scanned.
+    resumed: bool,
+}
+
+impl RepoResult {
+    /// Whether this summary is of `repo` as it is listed now.
+    fn is_of(&self, repo: &Repo) -> bool {
+        self.name == repo.name && self.source == repo.source.label() && self.reference == repo.reference
+    }
+
+    fn to_json(&self) -> Value {
+        json!({
+            "name": self.name,
+            "source": self.source,
+            "ref": self.reference,
+            "time": self.time,
+            "commit": self.commit,
+            "rollup": self.rollup,
+            "top": self.top,
+        })
+    }
+
+    fn from_json(value: &Value) -> Option<Self> {
+        Some(RepoResult {
+            name: value.get("name")?.as_str()?.to_string(),
+            source: value.get("source")?.as_str()?.to_string(),
+            reference: value.get("ref").and_then(Value::as_str).map(String::from),
+            time: value.get("time")?.as_i64()?,
+            commit: value.get("commit").and_then(Value::as_str).map(String::from),
+            rollup: serde_json::from_value(value.get("rollup")?.clone()).ok()?,
+            top: serde_json::from_value(value.get("top")?.clone()).ok()?,
+            resumed: false,
+        })
+    }
+}
+
+fn state_file(state: &Path, name: &str) -> PathBuf {
+    state.join(format!("{name}.json"))
+}
+
+fn save_result(state: &Path, result: &RepoResult) -> Result<()> {
+    let path = state_file(state, &result.name);
+    // Write aside and rename, so a run killed mid-write leaves no half
+    // summary to resume from.
+    let partial = path.with_extension("json.partial");
+    std::fs::write(&partial, serde_json::to_string_pretty(&result.to_json())?)
+        .with_context(|| format!("cannot write {}", partial.display()))?;
+    std::fs::rename(&partial, &path).with_context(|| format!("cannot write {}", path.display()))
+}
+
+/// The summary saved for `name`, if any.
+fn load_result(state: &Path, name: &str) -> Result<Option<RepoResult>> {
+    let path = state_file(state, name);
+    let text = match std::fs::read_to_string(&path) {
+        Ok(text) => text,
+        Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(None),
+        Err(e) => return Err(e).with_context(|| format!("cannot read {}", path.display())),
+    };
+    let result = serde_json::from_str(&text).ok().as_ref().and_then(RepoResult::from_json);
+    Ok(Some(result.with_context(|| format!("{}: not a repository summary; delete it or pass --fresh", path.display()))?))
+}
+
+/// Scan every repository listed in `config_path`, `jobs` at a time, and
+/// print one report.  Summaries already saved under `state` (default:
+/// [`STATE_DIR`] beside the list) are reused unless `fresh` is set.  Fails
+/// after the report if any repository could not be scanned.
+pub fn scan(config_path: &Path, jobs: Option<usize>, state: Option<&PathBuf>, fresh: bool, format: &str) -> Result<()> {
+    let text = std::fs::read_to_string(config_path).with_context(|| format!("cannot read {}", config_path.display()))?;
+    let base = config_path.parent().unwrap_or(Path::new(""));
+    let config = parse_config(&text, is_yaml(config_path), base).with_context(|| format!("failed to parse {}", config_path.display()))?;
+    anyhow::ensure!(!config.repos.is_empty(), "{} lists no repositories", config_path.display());
+    let state = state.cloned().unwrap_or_else(|| base.join(STATE_DIR));
+    std::fs::create_dir_all(&state).with_context(|| format!("cannot create {}", state.display()))?;
+    let jobs = jobs.or(config.jobs).unwrap_or(DEFAULT_JOBS).max(1);
+
+    let mut results = Vec::new();
+    let mut pending = VecDeque::new();
+    for repo in &config.repos {
+        if fresh {
+            match std::fs::remove_file(state_file(&state, &repo.name)) {
+                Err(e) if e.kind() != std::io::ErrorKind::NotFound => {
+                    return Err(e).with_context(|| format!("cannot remove the saved summary of {}", repo.name));
+                }
+                _ => {}
+            }
+        }
+        match load_result(&state, &repo.name)? {
+            Some(result) if result.is_of(repo) => results.push(RepoResult { resumed: true, ..result }),
+            _ => pending.push_back(repo),
+        }
+    }
+    if !results.is_empty() {
+        eprintln!(
+            "Resuming: {} of {} repositories already scanned (--fresh to rescan them).",
+            results.len(),
+            config.repos.len()
+        );
+    }
+
+    let total = pending.len();
+    let queue = Mutex::new(pending);
+    let done = Mutex::new((results, Vec::<(String, String)>::new(), 0));
+    std::thread::scope(|scope| {
+        for _ in 0..jobs.min(total) {
+            scope.spawn(|| loop {
+                let Some(repo) = queue.lock().unwrap().pop_front() else {
+                    break;
+                };
+                let outcome = scan_repo(repo).and_then(|result| save_result(&state, &result).map(|_| result));
+                let mut done = done.lock().unwrap();
+                let (results, failed, finished) = &mut *done;
+                *finished += 1;
+                match outcome {
+                    Ok(result) => {
+                        eprintln!(
+                            "[{finished}/{total}] {}: {} files, mean AI probability {:.2}",
+                            result.name, result.rollup.files, result.rollup.mean
+                        );
+                        results.push(result);
+                    }
+                    Err(e) => {
+                        eprintln!("[{finished}/{total}] {}: failed: {e:#}", repo.name);
+                        failed.push((repo.name.clone(), format!("{e:#}")));
+                    }
+                }
+            });
+        }
+    });
+    let (mut results, mut failed, _) = done.into_inner().unwrap();
+    let order: HashMap<&str, usize> = config.repos.iter().enumerate().map(|(i, r)| (r.name.as_str(), i)).collect();
+    failed.sort_by_key(|(name, _)| order[name.as_str()]);
+    results.sort_by(|a, b| b.rollup.mean.total_cmp(&a.rollup.mean).then_with(|| order[a.name.as_str()].cmp(&order[b.name.as_str()])));
+
+    match format {
+        "json" => println!("{}", serde_json::to_string_pretty(&to_json(&results, &failed))?),
+        _ => print!("{}", render_text(&results, &failed, &state)),
+    }
+    anyhow::ensure!(
+        failed.is_empty(),
+        "{} of {} repositories failed; rerun the same command to retry them",
+        failed.len(),
+        config.repos.len()
+    );
+    Ok(())
+}
+
+/// Check out (if remote) and analyze one repository.
+fn scan_repo(repo: &Repo) -> Result<RepoResult> {
+    let checkout;
+    let root = match &repo.source {
+        Source::Path(path) => {
+            anyhow::ensure!(path.is_dir(), "{} is not a directory", path.display());
+            path.clone()
+        }
+        Source::Url(url) => {
+            checkout = shallow_clone(url, repo.reference.as_deref())?;
+            checkout.path().to_path_buf()
+        }
+    };
+
+    // A clone's `.vibecheck` is written by whoever controls the remote.
+    let config = match &repo.source {
+        Source::Path(_) => policy::load(&root),
+        Source::Url(_) => policy::load_untrusted(&root).context("invalid .vibecheck")?,
+    };
+    let analyzer = Analyzer::new().with_config(&config);
+    let ignore = config.with_excludes(&repo.exclude);
+    let mut files = collect_files(&root, &ignore).context("failed to collect files")?;
+    if !ignore.include_generated() {
+        files.retain(|f| !vibecheck_core::generated::is_generated_file(f));
+    }
+    let mut reports = Vec::with_capacity(files.len());
+    for file in &files {
+        let mut report = analyzer.analyze_file(file).with_context(|| format!("failed to analyze {}", file.display()))?;
+        if let Ok(relative) = file.strip_prefix(&root) {
+            report.metadata.file_path = Some(relative.to_path_buf());
+        }
+        reports.push(report);
+    }
+
+    Ok(RepoResult {
+        name: repo.name.clone(),
+        source: repo.source.label(),
+        reference: repo.reference.clone(),
+        time: trend::now(),
+        commit: git2::Repository::discover(&root)
+            .ok()
+            .and_then(|r| r.head().ok()?.peel_to_commit().ok())
+            .map(|c| c.id().to_string()),
+        rollup: rollup::total(&reports, &repo.name),
+        top: ranking::top(&reports, TOP_FILES, false),
+        resumed: false,
+    })
+}
+
+/// A depth-1 clone of `url` (at `reference`, if given) in a temporary
+/// directory that is removed when dropped.
+fn shallow_clone(url: &str, reference: Option<&str>) -> Result<tempfile::TempDir> {
+    let dir = tempfile::Builder::new().prefix("vibecheck-org-").tempdir().context("cannot create a checkout directory")?;
+    let mut git = std::process::Command::new("git");
+    // A scheduled job has nobody to answer a credentials prompt.
+    git.env("GIT_TERMINAL_PROMPT", "0").args(["clone", "--quiet", "--depth", "1"]);
+    if let Some(reference) = reference {
+        git.args(["--branch", reference]);
+    }
+    let output = git.arg("--").arg(url).arg(dir.path()).output().context("cannot run git")?;
+    anyhow::ensure!(
+        output.status.success(),
+        "git clone {url} failed: {}",
+        String::from_utf8_lossy(&output.stderr).trim()
+    );
+    Ok(dir)
+}
+
+/// Totals over every scanned repository: files, scored files, the mean AI
+/// probability over the scored files, the highest, and verdicts per family.
+fn totals(results: &[RepoResult]) -> (usize, usize, f64, f64, BTreeMap<ModelFamily, usize>) {
+    let files = results.iter().map(|r| r.rollup.files).sum();
+    let scored: usize = results.iter().map(|r| r.rollup.scored).sum();
+    let sum: f64 = results.iter().map(|r| r.rollup.mean * r.rollup.scored as f64).sum();
+    let mean = if scored == 0 { 0.0 } else { sum / scored as f64 };
+    let max = results.iter().map(|r| r.rollup.max).fold(0.0, f64::max);
+    let mut verdicts = BTreeMap::new();
+    for (family, n) in results.iter().flat_map(|r| &r.rollup.verdicts) {
+        *verdicts.entry(*family).or_insert(0) += n;
+    }
+    (files, scored, mean, max, verdicts)
+}
+
+fn render_text(results: &[RepoResult], failed: &[(String, String)], state: &Path) -> String {
+    let (files, scored, mean, max, _) = totals(results);
+    let repos = results.len() + failed.len();
+    let mut out = format!(
+        "Scanned {} of {repos} repositor{}: {files} files, mean AI probability {mean:.2}\n\n",
+        results.len(),
+        if repos == 1 { "y" } else { "ies" },
+    );
+    if !results.is_empty() {
+        let label = |r: &RepoResult| if r.resumed { format!("{}*", r.name) } else { r.name.clone() };
+        let width = results.iter().map(|r| label(r).chars().count()).max().unwrap_or(0).max(4);
+        out.push_str(&format!(
+            "{:<width$}  {:>6}  {:>6}  {:>4}  {:>6}  {:>4}  {:<8}  TOP FILE\n",
+            "REPO", "FILES", "SCORED", "MEAN", "MEDIAN", "MAX", "MOSTLY"
+        ));
+        out.push_str(&format!("{}\n", "─".repeat(width + 56)));
+        for r in results {
+            let mostly = r.rollup.dominant().map_or("—".to_string(), |f| f.to_string());
+            let top = r.top.first().map_or("—".to_string(), |o| format!("{} ({:.2})", o.location(), o.ai_probability));
+            out.push_str(&format!(
+                "{:<width$}  {:>6}  {:>6}  {:>4.2}  {:>6.2}  {:>4.2}  {mostly:<8}  {top}\n",
+                label(r),
+                r.rollup.files,
+                r.rollup.scored,
+                r.rollup.mean,
+                r.rollup.median,
+                r.rollup.max,
+            ));
+        }
+        out.push_str(&format!("{}\n", "─".repeat(width + 56)));
+        out.push_str(&format!("{:<width$}  {files:>6}  {scored:>6}  {mean:>4.2}  {:>6}  {max:>4.2}\n", "all", "—"));
+        if results.iter().any(|r| r.resumed) {
+            out.push_str(&format!("\n* from an earlier run, saved in {} (--fresh to rescan)\n", state.display()));
+        }
+    }
+    if !failed.is_empty() {
+        out.push_str("\nFailed:\n");
+        for (name, error) in failed {
+            out.push_str(&format!("  {name}: {error}\n"));
+        }
+    }
+    out
+}
+
+fn to_json(results: &[RepoResult], failed: &[(String, String)]) -> Value {
+    let (files, scored, mean, max, verdicts) = totals(results);
+    json!({
+        "repositories": results
+            .iter()
+            .map(|r| {
+                let mut entry = r.to_json();
+                entry["resumed"] = json!(r.resumed);
+                entry
+            })
+            .collect::<Vec<_>>(),
+        "failed": failed.iter().map(|(name, error)| json!({ "name": name, "error": error })).collect::<Vec<_>>(),
+        "total": {
+            "repositories": results.len(),
+            "files": files,
+            "scored": scored,
+            "mean": mean,
+            "max": max,
+            "verdicts": verdicts,
+        },
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const LIST: &str = r#"
+# Weekly scan
+jobs = 8
+repos = [
+    "../billing",  # a sibling checkout
+    "https://github.com/acme/api.git",
+    { name = "web-app", url = "git@github.com:acme/web.git", ref = "release", exclude = ["vendor/**", "*.pb.go"] },
+    { path = "tools", exclude = ["gen/**"] },
+]
+"#;
+
+    #[test]
+    fn parses_string_and_table_entries() {
+        let config = parse_config(LIST, false, Path::new("/org")).unwrap();
+        assert_eq!(config.jobs, Some(8));
+        let names: Vec<&str> = config.repos.iter().map(|r| r.name.as_str()).collect();
+        assert_eq!(names, ["billing", "api", "web-app", "tools"]);
+        assert_eq!(config.repos[0].source, Source::Path(PathBuf::from("/org/../billing")));
+        assert_eq!(config.repos[1].source, Source::Url("https://github.com/acme/api.git".into()));
+        let web = &config.repos[2];
+        assert_eq!(web.source, Source::Url("git@github.com:acme/web.git".into()));
+        assert_eq!(web.reference.as_deref(), Some("release"));
+        assert_eq!(web.exclude, ["vendor/**", "*.pb.go"]);
+        assert_eq!(config.repos[3].source, Source::Path(PathBuf::from("/org/tools")));
+        assert_eq!(config.repos[3].exclude, ["gen/**"]);
+
+        let tables = parse_config("[[repos]]\npath = \"a\"\n\n[[repos]]\nurl = \"https://x/b.git\"\nref = \"main\"\n", false, Path::new(""))
+            .unwrap();
+        assert_eq!(tables.repos.iter().map(|r| r.name.as_str()).collect::<Vec<_>>(), ["a", "b"]);
+    }
+
+    #[test]
+    fn yaml_lists_parse_like_toml() {
+        let yaml = r#"
+# Weekly scan
+jobs: 8
+repos:
+  - ../billing  # a sibling checkout
+  - https://github.com/acme/api.git
+  - name: web-app
+    url: git@github.com:acme/web.git
+    ref: release
+    exclude: ["vendor/**", "*.pb.go"]
+  - path: tools
+    exclude: ["gen/**"]
+"#;
+        let config = parse_config(yaml, true, Path::new("/org")).unwrap();
+        let toml = parse_config(LIST, false, Path::new("/org")).unwrap();
+        assert_eq!(config.jobs, toml.jobs);
+        assert_eq!(config.repos, toml.repos);
+        assert!(is_yaml(Path::new("repos.yaml")) && is_yaml(Path::new("repos.yml")) && !is_yaml(Path::new("repos.toml")));
+
+        let error = format!("{:#}", parse_config("repos:\n  - path: a\n    branch: main\n", true, Path::new("")).unwrap_err());
+        assert!(error.contains("unknown field `branch`"), "{error}");
+    }
+
+    #[test]
+    fn rejects_what_it_cannot_scan() {
+        let error = |text: &str| format!("{:#}", parse_config(text, false, Path::new("/org")).unwrap_err());
+        assert!(error("repos = [\"a\", \"x/a\"]\n").contains("repos[1]: a repository named `a` is already listed"));
+        assert!(error("[[repos]]\npath = \"a\"\nurl = \"https://x/a\"\n").contains("exactly one of `path` or `url`"));
+        assert!(error("[[repos]]\npath = \"a\"\nref = \"main\"\n").contains("`ref` needs a `url`"));
+        assert!(error("[[repos]]\npath = \"a\"\nbranch = \"main\"\n").contains("unknown field `branch`"));
+        assert!(error("[[repos]]\npath = \"a\"\nname = \"../b\"\n").contains("name `../b` may only use"));
+        assert!(error("jobs = 0\n").contains("jobs must be a positive number"));
+        assert!(error("repositories = []\n").contains("unknown field `repositories`"));
+    }
+
+    #[test]
+    fn names_come_from_the_last_path_element() {
+        assert_eq!(default_name("https://github.com/acme/api.git", Path::new("")), "api");
+        assert_eq!(default_name("git@github.com:acme/web", Path::new("")), "web");
+        assert_eq!(default_name("../My Repo/", Path::new("")), "My-Repo");
+        assert!(is_name("api.v2_x") && !is_name("../a") && !is_name(".hidden"));
+    }
+
+    fn result(name: &str, mean: f64, resumed: bool) -> RepoResult {
+        let mut report = vibecheck_core::analyze("fn main() {}\n");
+        report.metadata.file_path = Some(PathBuf::from("src/main.rs"));
+        report.attribution.primary = ModelFamily::Claude;
+        report.attribution.ai_probability = Some(mean);
+        RepoResult {
+            name: name.into(),
+            source: format!("/org/{name}"),
+            reference: None,
+            time: 1_700_000_000,
+            commit: Some("abc123".into()),
+            rollup: rollup::total(std::slice::from_ref(&report), name),
+            top: ranking::rank(std::slice::from_ref(&report), false),
+            resumed,
+        }
+    }
+
+    #[test]
+    fn summaries_are_saved_and_resumed() {
+        let dir = tempfile::tempdir().unwrap();
+        assert!(load_result(dir.path(), "api").unwrap().is_none());
+        let saved = result("api", 0.8, false);
+        save_result(dir.path(), &saved).unwrap();
+        let loaded = load_result(dir.path(), "api").unwrap().unwrap();
+        assert_eq!(loaded.to_json(), saved.to_json());
+
+        let repo = Repo { name: "api".into(), source: Source::Path("/org/api".into()), reference: None, exclude: vec![] };
+        assert!(loaded.is_of(&repo));
+        assert!(!loaded.is_of(&Repo { reference: Some("main".into()), ..repo }));
+
+        std::fs::write(state_file(dir.path(), "api"), "{}").unwrap();
+        assert!(load_result(dir.path(), "api").is_err());
+    }
+
+    #[test]
+    fn report_has_a_row_per_repository_and_totals() {
+        let results = [result("api", 0.9, false), result("billing", 0.3, true)];
+        let failed = [("web".to_string(), "git clone failed".to_string())];
+        let text = render_text(&results, &failed, Path::new(".vibecheck-org"));
+        assert!(text.starts_with("Scanned 2 of 3 repositories: 2 files, mean AI probability 0.60\n"), "{text}");
+        assert!(text.contains("\napi       "), "{text}");
+        assert!(text.contains("\nbilling*  "), "{text}");
+        assert!(text.contains("src/main.rs (0.90)"), "{text}");
+        assert!(text.contains("\nFailed:\n  web: git clone failed\n"), "{text}");
+
+        let json = to_json(&results, &failed);
+        assert_eq!(json["total"]["files"], 2);
+        assert_eq!(json["repositories"][1]["resumed"], true);
+        assert_eq!(json["failed"][0]["name"], "web");
+    }
+}
@@ -0,0 +1,162 @@
+use std::fmt::Write;
+use std::path::Path;
+
+use anyhow::{Context, Result};
+use serde_json::{json, Value};
+
+use vibecheck_core::report::Report;
+use vibecheck_core::report_diff::{self, FileDelta, FileStatus, Finding, ReportDiff};
+
+/// Compare two saved scans — `--format json` output of `analyze` or `scan`
+/// — and print the findings that appeared and disappeared and the files
+/// whose AI probability moved.
+pub fn diff(old: &Path, new: &Path, format: &str) -> Result<()> {
+    let diff = report_diff::diff(&load(old)?, &load(new)?);
+    match format {
+        "json" => println!("{}", serde_json::to_string_pretty(&to_json(&diff))?),
+        _ => print!("{}", render_text(&diff)),
+    }
+    Ok(())
+}
+
+/// The reports in a JSON file holding one report or an array of them.
+fn load(path: &Path) -> Result<Vec<Report>> {
+    let text = std::fs::read_to_string(path).with_context(|| format!("cannot read {}", path.display()))?;
+    parse(&text).with_context(|| format!("{} is not a vibecheck JSON report", path.display()))
+}
+
+fn parse(text: &str) -> Result<Vec<Report>> {
+    let value: Value = serde_json::from_str(text)?;
+    Ok(match value {
+        Value::Array(_) => serde_json::from_value(value)?,
+        _ => vec![serde_json::from_value(value)?],
+    })
+}
+
+fn render_text(diff: &ReportDiff) -> String {
+    let changed = diff.files.iter().filter(|f| f.status == FileStatus::Changed).count();
+    let added = diff.files.iter().filter(|f| f.status == FileStatus::Added).count();
+    let removed = diff.files.len() - changed - added;
+    let mut out = format!(
+        "{} new finding{}, {} resolved; {changed} file{} changed, {added} added, {removed} removed, {} unchanged\n",
+        diff.new_findings.len(),
+        plural(diff.new_findings.len()),
+        diff.resolved_findings.len(),
+        plural(changed),
+        diff.unchanged,
+    );
+    for (title, marker, findings) in [("New findings", '+', &diff.new_findings), ("Resolved findings", '-', &diff.resolved_findings)] {
+        if findings.is_empty() {
+            continue;
+        }
+        let _ = writeln!(out, "\n{title}:");
+        for f in findings {
+            let _ = writeln!(out, "  {marker} {}", finding_line(f));
+        }
+    }
+    if !diff.files.is_empty() {
+        out.push_str("\nAI probability:\n");
+        let width = diff.files.iter().map(|f| f.path.display().to_string().chars().count()).max().unwrap_or(0);
+        for f in &diff.files {
+            let _ = writeln!(out, "  {:<width$}  {}", f.path.display().to_string(), score_change(f));
+        }
+    }
+    out
+}
+
+fn finding_line(f: &Finding) -> String {
+    let mut line = format!("{}  {}", f.path.display(), f.rule);
+    let lines: Vec<String> = f.lines.iter().map(usize::to_string).collect();
+    match lines.len() {
+        0 => {}
+        1 => line.push_str(&format!(" (line {})", lines[0])),
+        _ => line.push_str(&format!(" (lines {})", lines.join(", "))),
+    }
+    line.push_str(&format!(" — {}", f.description));
+    line
+}
+
+fn score_change(f: &FileDelta) -> String {
+    let score = |p: Option<f64>| p.map_or_else(|| "—".to_string(), |p| format!("{p:.2}"));
+    match (f.status, f.delta()) {
+        (FileStatus::Added, _) => format!("added at {}", score(f.new)),
+        (FileStatus::Removed, _) => format!("removed (was {})", score(f.old)),
+        (_, Some(delta)) => format!("{} → {} ({delta:+.2})", score(f.old), score(f.new)),
+        (_, None) => format!("{} → {}", score(f.old), score(f.new)),
+    }
+}
+
+fn plural(n: usize) -> &'static str {
+    if n == 1 {
+        ""
+    } else {
+        "s"
+    }
+}
+
+fn to_json(diff: &ReportDiff) -> Value {
+    let mut value = serde_json::to_value(diff).expect("diff should be serializable");
+    value["files"] = json!(diff
+        .files
+        .iter()
+        .map(|f| {
+            let mut entry = serde_json::to_value(f).expect("file delta should be serializable");
+            entry["delta"] = json!(f.delta());
+            entry
+        })
+        .collect::<Vec<_>>());
+    value
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::path::PathBuf;
+    use vibecheck_core::report::{ModelFamily, Signal};
+
+    fn scan(files: &[(&str, f64, &[&str])]) -> String {
+        let reports: Vec<Report> = files
+            .iter()
+            .map(|(path, p, rules)| {
+                let mut report = vibecheck_core::analyze("fn main() {}\n");
+                report.metadata.file_path = Some(PathBuf::from(path));
+                report.attribution.ai_probability = Some(*p);
+                report.signals =
+                    rules.iter().map(|id| Signal::new(id, "test", "desc", ModelFamily::Claude, 1.0).with_lines(vec![4])).collect();
+                report
+            })
+            .collect();
+        serde_json::to_string(&reports).unwrap()
+    }
+
+    #[test]
+    fn parses_single_reports_and_arrays() {
+        let one = serde_json::to_string(&vibecheck_core::analyze("fn main() {}\n")).unwrap();
+        assert_eq!(parse(&one).unwrap().len(), 1);
+        assert_eq!(parse(&scan(&[("a.rs", 0.5, &[]), ("b.rs", 0.5, &[])])).unwrap().len(), 2);
+        assert!(parse("{\"not\": \"a report\"}").is_err());
+    }
+
+    #[test]
+    fn text_lists_findings_and_score_changes() {
+        let old = parse(&scan(&[("a.rs", 0.40, &["rust.errors.zero_unwrap"]), ("gone.rs", 0.9, &[])])).unwrap();
+        let new = parse(&scan(&[("a.rs", 0.75, &["rust.naming.very_descriptive_vars"]), ("b.rs", 0.2, &[])])).unwrap();
+        let text = render_text(&report_diff::diff(&old, &new));
+        assert!(text.starts_with("1 new finding, 1 resolved; 1 file changed, 1 added, 1 removed, 0 unchanged\n"), "{text}");
+        assert!(text.contains("\n  + a.rs  rust.naming.very_descriptive_vars (line 4) — desc\n"), "{text}");
+        assert!(text.contains("\n  - a.rs  rust.errors.zero_unwrap (line 4) — desc\n"), "{text}");
+        assert!(text.contains("a.rs     0.40 → 0.75 (+0.35)\n"), "{text}");
+        assert!(text.contains("b.rs     added at 0.20\n"), "{text}");
+        assert!(text.contains("gone.rs  removed (was 0.90)\n"), "{text}");
+    }
+
+    #[test]
+    fn json_carries_deltas() {
+        let old = parse(&scan(&[("a.rs", 0.5, &[])])).unwrap();
+        let new = parse(&scan(&[("a.rs", 0.25, &[])])).unwrap();
+        let json = to_json(&report_diff::diff(&old, &new));
+        assert_eq!(json["files"][0]["status"], "changed");
+        assert_eq!(json["files"][0]["delta"], -0.25);
+        assert_eq!(json["unchanged"], 0);
+    }
+}
@@ -0,0 +1,802 @@
+use std::collections::{BTreeMap, BTreeSet, HashMap};
+use std::io::{self, Write};
+use std::path::{Path, PathBuf};
+
+use anyhow::{Context, Result};
+use crossterm::{
+    event::{self, Event, KeyCode, KeyModifiers},
+    execute,
+    terminal::{disable_raw_mode, enable_raw_mode, EnterAlternateScreen, LeaveAlternateScreen},
+};
+use ratatui::{
+    backend::CrosstermBackend,
+    layout::{Constraint, Direction, Layout},
+    style::{Color, Modifier, Style},
+    text::{Line, Span},
+    widgets::{Block, Borders, Paragraph},
+    Frame, Terminal,
+};
+use serde_json::{json, Value};
+
+use vibecheck_core::corpus_manifest::{content_hash, Manifest, Provenance};
+use vibecheck_core::feedback::{Feedback, Review, FEEDBACK_FILE};
+use vibecheck_core::ignore_rules::IgnoreRules;
+use vibecheck_core::ranking;
+use vibecheck_core::report::{ModelFamily, Report};
+
+use crate::commands::analyze::collect_files;
+use crate::commands::corpus::parse_label;
+use crate::commands::trend;
+use crate::commands::tui::family_color;
+use crate::policy;
+
+/// Review decisions, one JSON object per line, at the project root beside
+/// the `.vibecheck` config file.  Later lines override earlier ones for the
+/// same file.
+pub const REVIEW_FILE: &str = ".vibecheck-reviews";
+
+/// Default AI probability at which a file is up for review.
+pub const DEFAULT_THRESHOLD: f64 = 0.5;
+
+// ---------------------------------------------------------------------------
+// Decisions
+// ---------------------------------------------------------------------------
+
+/// What the reviewer made of a flagged file.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Verdict {
+    /// The file is AI-written, as flagged.
+    Confirmed,
+    /// The file was written by a person.
+    FalsePositive,
+}
+
+impl Verdict {
+    pub fn as_str(self) -> &'static str {
+        match self {
+            Verdict::Confirmed => "confirmed",
+            Verdict::FalsePositive => "false-positive",
+        }
+    }
+
+    fn parse(s: &str) -> Option<Self> {
+        match s {
+            "confirmed" => Some(Verdict::Confirmed),
+            "false-positive" => Some(Verdict::FalsePositive),
+            _ => None,
+        }
+    }
+}
+
+/// One reviewed file.
+#[derive(Debug, Clone, PartialEq)]
+pub struct Decision {
+    /// Unix time of the decision.
+    pub time: i64,
+    /// Relative to the project root, `/`-separated.
+    pub path: String,
+    /// [`content_hash`] of the file as reviewed; the decision does not carry
+    /// over to later edits.
+    pub hash: String,
+    pub verdict: Verdict,
+    /// The family the file was attributed to, or the one the reviewer
+    /// named with `vibecheck mark`.
+    pub family: ModelFamily,
+    pub ai_probability: f64,
+    /// The AI-leaning rules that weighed most in the verdict.
+    pub rules: Vec<String>,
+}
+
+impl Decision {
+    /// The corpus label the decision implies: the attributed family when
+    /// confirmed, human for a false positive.
+    pub fn label(&self) -> ModelFamily {
+        match self.verdict {
+            Verdict::Confirmed => self.family,
+            Verdict::FalsePositive => ModelFamily::Human,
+        }
+    }
+
+    pub fn to_json(&self) -> Value {
+        json!({
+            "time": self.time,
+            "path": self.path,
+            "hash": self.hash,
+            "verdict": self.verdict.as_str(),
+            "family": self.family,
+            "ai_probability": self.ai_probability,
+            "rules": self.rules,
+            "label": self.label(),
+        })
+    }
+
+    fn from_json(value: &Value) -> Option<Self> {
+        Some(Decision {
+            time: value.get("time")?.as_i64()?,
+            path: value.get("path")?.as_str()?.to_string(),
+            hash: value.get("hash")?.as_str()?.to_string(),
+            verdict: Verdict::parse(value.get("verdict")?.as_str()?)?,
+            family: serde_json::from_value(value.get("family")?.clone()).ok()?,
+            ai_probability: value.get("ai_probability")?.as_f64()?,
+            rules: value
+                .get("rules")
+                .and_then(Value::as_array)
+                .map(|rules| rules.iter().filter_map(Value::as_str).map(String::from).collect())
+                .unwrap_or_default(),
+        })
+    }
+}
+
+/// Append `decision` to the review file under `root`, returning its path.
+pub fn append(root: &Path, decision: &Decision) -> Result<PathBuf> {
+    let path = root.join(REVIEW_FILE);
+    let mut file = std::fs::OpenOptions::new()
+        .create(true)
+        .append(true)
+        .open(&path)
+        .with_context(|| format!("cannot open {}", path.display()))?;
+    writeln!(file, "{}", decision.to_json()).with_context(|| format!("cannot write {}", path.display()))?;
+    Ok(path)
+}
+
+/// Every decision recorded under `root`, oldest first; none if nothing was
+/// reviewed.
+pub fn load(root: &Path) -> Result<Vec<Decision>> {
+    let path = root.join(REVIEW_FILE);
+    let text = match std::fs::read_to_string(&path) {
+        Ok(text) => text,
+        Err(e) if e.kind() == io::ErrorKind::NotFound => return Ok(Vec::new()),
+        Err(e) => return Err(e).with_context(|| format!("cannot read {}", path.display())),
+    };
+    let mut decisions = Vec::new();
+    for (i, line) in text.lines().enumerate().filter(|(_, l)| !l.trim().is_empty()) {
+        let decision = serde_json::from_str(line)
+            .ok()
+            .as_ref()
+            .and_then(Decision::from_json)
+            .with_context(|| format!("{}:{}: not a review decision", path.display(), i + 1))?;
+        decisions.push(decision);
+    }
+    Ok(decisions)
+}
+
+/// The latest of `decisions` for each file, by path.
+pub fn latest(decisions: Vec<Decision>) -> BTreeMap<String, Decision> {
+    let mut by_path = BTreeMap::new();
+    for decision in decisions {
+        by_path.insert(decision.path.clone(), decision);
+    }
+    by_path
+}
+
+/// Regenerate the weight adjustments under `root` from `decisions` (the
+/// latest per file).  Nothing is written while no file was ever a false
+/// positive.
+pub fn refresh_feedback(root: &Path, decisions: &BTreeMap<String, Decision>) -> Result<Feedback> {
+    let feedback = Feedback::from_reviews(
+        decisions.values().map(|d| Review { rules: &d.rules, false_positive: d.verdict == Verdict::FalsePositive }),
+    );
+    if !feedback.is_empty() || root.join(FEEDBACK_FILE).exists() {
+        feedback.save(root)?;
+    }
+    Ok(feedback)
+}
+
+/// `path` relative to `root`, `/`-separated.
+fn relative(root: &Path, path: &Path) -> String {
+    let rel = path.strip_prefix(root).unwrap_or(path);
+    rel.components()
+        .filter(|c| !matches!(c, std::path::Component::CurDir))
+        .map(|c| c.as_os_str().to_string_lossy())
+        .collect::<Vec<_>>()
+        .join("/")
+}
+
+// ---------------------------------------------------------------------------
+// Review session
+// ---------------------------------------------------------------------------
+
+/// A flagged file up for review.
+struct Item {
+    path: PathBuf,
+    /// Key into the decisions; see [`Decision::path`].
+    key: String,
+    hash: String,
+    source: String,
+    report: Report,
+    ai_probability: f64,
+    rules: Vec<String>,
+}
+
+impl Item {
+    /// 1-based lines that AI-leaning signals point at.
+    fn evidence_lines(&self) -> BTreeSet<usize> {
+        self.report.signals.iter().filter(|s| s.is_ai_leaning()).flat_map(|s| s.lines.iter().copied()).collect()
+    }
+}
+
+struct App {
+    root: PathBuf,
+    items: Vec<Item>,
+    current: usize,
+    /// Vertical scroll offset of the source pane.
+    scroll: u16,
+    /// Inner height of the source pane (updated each frame, used for
+    /// centering evidence).
+    pane_h: u16,
+    decisions: BTreeMap<String, Decision>,
+    /// Decisions recorded in this session.
+    recorded: usize,
+    /// Error from the last attempt to save a decision.
+    error: Option<String>,
+}
+
+impl App {
+    fn new(root: PathBuf, items: Vec<Item>, decisions: BTreeMap<String, Decision>) -> Self {
+        App { root, items, current: 0, scroll: 0, pane_h: 0, decisions, recorded: 0, error: None }
+    }
+
+    /// The decision standing for `item`, unless it was about other content.
+    fn decision(&self, item: &Item) -> Option<&Decision> {
+        self.decisions.get(&item.key).filter(|d| d.hash == item.hash)
+    }
+
+    fn reviewed(&self) -> usize {
+        self.items.iter().filter(|i| self.decision(i).is_some()).count()
+    }
+
+    fn next(&mut self) {
+        if self.current + 1 < self.items.len() {
+            self.current += 1;
+            self.scroll = 0;
+        }
+    }
+
+    fn previous(&mut self) {
+        if self.current > 0 {
+            self.current -= 1;
+            self.scroll = 0;
+        }
+    }
+
+    fn scroll_down(&mut self, amount: u16) {
+        let lines = self.items[self.current].source.lines().count() as u16;
+        self.scroll = (self.scroll + amount).min(lines.saturating_sub(1));
+    }
+
+    fn scroll_up(&mut self, amount: u16) {
+        self.scroll = self.scroll.saturating_sub(amount);
+    }
+
+    /// Scroll to the first evidence line below the top of the pane, wrapping
+    /// around to the first one.
+    fn next_evidence(&mut self) {
+        let evidence = self.items[self.current].evidence_lines();
+        let top = self.scroll as usize + self.pane_h as usize / 3 + 1;
+        let Some(&line) = evidence.range(top + 1..).next().or_else(|| evidence.iter().next()) else {
+            return;
+        };
+        self.scroll = line.saturating_sub(1 + self.pane_h as usize / 3) as u16;
+    }
+
+    /// Record `verdict` for the current file and move on to the next one.
+    fn decide(&mut self, verdict: Verdict, time: i64) -> Decision {
+        let item = &self.items[self.current];
+        let decision = Decision {
+            time,
+            path: item.key.clone(),
+            hash: item.hash.clone(),
+            verdict,
+            family: item.report.attribution.primary,
+            ai_probability: item.ai_probability,
+            rules: item.rules.clone(),
+        };
+        self.decisions.insert(decision.path.clone(), decision.clone());
+        self.recorded += 1;
+        self.next();
+        decision
+    }
+}
+
+/// The files in `reports` at or above `threshold` AI probability, highest
+/// first.  Files that cannot be read are left out.
+fn flagged(root: &Path, reports: Vec<Report>, threshold: f64) -> Vec<Item> {
+    let ranked = ranking::rank(&reports, false);
+    let mut by_path: HashMap<PathBuf, Report> =
+        reports.into_iter().filter_map(|r| Some((r.metadata.file_path.clone()?, r))).collect();
+    ranked
+        .into_iter()
+        .filter(|o| o.ai_probability >= threshold)
+        .filter_map(|o| {
+            let bytes = std::fs::read(&o.path).ok()?;
+            let report = by_path.remove(&o.path)?;
+            Some(Item {
+                key: relative(root, &o.path),
+                hash: content_hash(&bytes),
+                source: String::from_utf8_lossy(&bytes).into_owned(),
+                report,
+                ai_probability: o.ai_probability,
+                rules: o.rules,
+                path: o.path,
+            })
+        })
+        .collect()
+}
+
+// ---------------------------------------------------------------------------
+// Rendering
+// ---------------------------------------------------------------------------
+
+fn verdict_span(verdict: Verdict) -> Span<'static> {
+    match verdict {
+        Verdict::Confirmed => Span::styled("confirmed AI", Style::default().fg(Color::Green).add_modifier(Modifier::BOLD)),
+        Verdict::FalsePositive => {
+            Span::styled("false positive", Style::default().fg(Color::Yellow).add_modifier(Modifier::BOLD))
+        }
+    }
+}
+
+fn render(frame: &mut Frame, app: &mut App) {
+    let outer = Layout::default()
+        .direction(Direction::Vertical)
+        .constraints([Constraint::Min(0), Constraint::Length(1)])
+        .split(frame.area());
+    let main = Layout::default()
+        .direction(Direction::Horizontal)
+        .constraints([Constraint::Percentage(60), Constraint::Percentage(40)])
+        .split(outer[0]);
+
+    render_source(frame, app, main[0]);
+    render_evidence(frame, app, main[1]);
+    render_statusbar(frame, app, outer[1]);
+}
+
+/// The file with its evidence lines highlighted.
+fn render_source(frame: &mut Frame, app: &mut App, area: ratatui::layout::Rect) {
+    let inner = area.inner(ratatui::layout::Margin { horizontal: 1, vertical: 1 });
+    app.pane_h = inner.height;
+    let item = &app.items[app.current];
+    let title = format!(" {}/{}  {} ", app.current + 1, app.items.len(), item.path.display());
+    let block = Block::default().borders(Borders::ALL).title(title);
+
+    let evidence = item.evidence_lines();
+    let width = item.source.lines().count().to_string().len();
+    let lines: Vec<Line> = item
+        .source
+        .lines()
+        .enumerate()
+        .map(|(i, text)| {
+            let text = text.replace('\t', "    ");
+            if evidence.contains(&(i + 1)) {
+                Line::from(vec![
+                    Span::styled(format!("{:>width$} ▌", i + 1), Style::default().fg(Color::Yellow)),
+                    Span::styled(text, Style::default().bg(Color::Rgb(70, 55, 0))),
+                ])
+            } else {
+                Line::from(vec![
+                    Span::styled(format!("{:>width$} │", i + 1), Style::default().fg(Color::DarkGray)),
+                    Span::raw(text),
+                ])
+            }
+        })
+        .collect();
+    frame.render_widget(block, area);
+    frame.render_widget(Paragraph::new(lines).scroll((app.scroll, 0)), inner);
+}
+
+/// The verdict, the reviewer's decision and the signals behind the verdict.
+fn render_evidence(frame: &mut Frame, app: &App, area: ratatui::layout::Rect) {
+    let item = &app.items[app.current];
+    let attribution = &item.report.attribution;
+    let mut lines = vec![
+        Line::from(Span::styled(
+            format!("{} ({:.0}% AI probability)", attribution.primary, item.ai_probability * 100.0),
+            Style::default().fg(family_color(attribution.primary)).add_modifier(Modifier::BOLD),
+        )),
+    ];
+    if let Some(authorship) = attribution.authorship {
+        lines.push(Line::raw(format!("Authorship: {authorship}")));
+    }
+    lines.push(match app.decision(item) {
+        Some(d) => Line::from(vec![Span::raw("Reviewed: "), verdict_span(d.verdict)]),
+        None => Line::styled("Not reviewed", Style::default().fg(Color::DarkGray)),
+    });
+    lines.push(Line::raw(""));
+
+    let mut signals: Vec<_> = item.report.signals.iter().collect();
+    signals.sort_by(|a, b| b.is_ai_leaning().cmp(&a.is_ai_leaning()).then_with(|| b.weight.total_cmp(&a.weight)));
+    lines.push(Line::styled(format!("Evidence ({}):", signals.len()), Style::default().add_modifier(Modifier::BOLD)));
+    for s in signals {
+        let style = if s.is_ai_leaning() { Style::default() } else { Style::default().fg(Color::DarkGray) };
+        let mut text = format!("{:+.1} {} — {}", s.weight, s.family, s.description);
+        if let Some(at) = s.lines_label() {
+            text.push_str(&format!(" ({at})"));
+        }
+        lines.push(Line::styled(text, style));
+    }
+
+    frame.render_widget(
+        Paragraph::new(lines)
+            .wrap(ratatui::widgets::Wrap { trim: false })
+            .block(Block::default().borders(Borders::ALL).title(" Evidence ")),
+        area,
+    );
+}
+
+fn render_statusbar(frame: &mut Frame, app: &App, area: ratatui::layout::Rect) {
+    let key = |k: &'static str| Span::styled(k, Style::default().fg(Color::Cyan));
+    let mut spans = vec![
+        key(" c "),
+        Span::raw("confirm  "),
+        key(" f "),
+        Span::raw("false positive  "),
+        key(" →/← "),
+        Span::raw("next/prev  "),
+        key(" ↑↓ "),
+        Span::raw("scroll  "),
+        key(" e "),
+        Span::raw("next evidence  "),
+        key(" q "),
+        Span::raw("quit  "),
+    ];
+    match app.error {
+        Some(ref e) => spans.push(Span::styled(format!(" {e}"), Style::default().fg(Color::Red))),
+        None => spans.push(Span::raw(format!(" {}/{} reviewed", app.reviewed(), app.items.len()))),
+    }
+    frame.render_widget(Paragraph::new(Line::from(spans)).style(Style::default().bg(Color::DarkGray)), area);
+}
+
+// ---------------------------------------------------------------------------
+// Entry points
+// ---------------------------------------------------------------------------
+
+/// Walk the files under `path` at or above `threshold` AI probability,
+/// highest first, recording the reviewer's verdicts in [`REVIEW_FILE`].
+/// Files already reviewed at their current content are skipped unless
+/// `all` is set.
+pub fn run(path: &Path, threshold: f64, all: bool, ignore_file: Option<&PathBuf>, exclude: &[String]) -> Result<()> {
+    let config = match ignore_file {
+        Some(f) => policy::from_file(f)?,
+        None => policy::load(path),
+    };
+    let root = config.root().to_path_buf();
+    let ignore = config.with_excludes(exclude);
+    let mut files = collect_files(&path.to_path_buf(), &ignore).context("failed to collect files")?;
+    if path.is_dir() && !ignore.include_generated() {
+        files.retain(|f| !vibecheck_core::generated::is_generated_file(f));
+    }
+
+    eprintln!("Analyzing {}…", path.display());
+    let mut reports = Vec::with_capacity(files.len());
+    for file in &files {
+        reports.push(vibecheck_core::analyze_file_with(file, &policy::file_options()).with_context(|| format!("failed to analyze {}", file.display()))?);
+    }
+
+    let mut app = App::new(root.clone(), flagged(&root, reports, threshold), latest(load(&root)?));
+    if !all {
+        let pending: Vec<Item> = std::mem::take(&mut app.items).into_iter().filter(|i| app.decision(i).is_none()).collect();
+        app.items = pending;
+    }
+    if app.items.is_empty() {
+        println!(
+            "Nothing to review: no files at or above {threshold:.2} AI probability{}.",
+            if all { "" } else { " are unreviewed (--all revisits reviewed files)" }
+        );
+        return Ok(());
+    }
+
+    enable_raw_mode()?;
+    let mut stdout = io::stdout();
+    execute!(stdout, EnterAlternateScreen)?;
+    let mut terminal = Terminal::new(CrosstermBackend::new(stdout))?;
+
+    let result = event_loop(&mut terminal, &mut app);
+
+    // Always restore terminal before returning.
+    disable_raw_mode()?;
+    execute!(terminal.backend_mut(), LeaveAlternateScreen)?;
+    terminal.show_cursor()?;
+
+    result?;
+    println!(
+        "Recorded {} decision{} in {} ({}/{} flagged files reviewed).",
+        app.recorded,
+        if app.recorded == 1 { "" } else { "s" },
+        root.join(REVIEW_FILE).display(),
+        app.reviewed(),
+        app.items.len(),
+    );
+    let feedback = Feedback::load(&root)?;
+    if app.recorded > 0 && !feedback.is_empty() {
+        println!("{} rules down-weighted for this repository in {}.", feedback.rules.len(), root.join(FEEDBACK_FILE).display());
+    }
+    Ok(())
+}
+
+/// Record `label` as the reviewer's verdict on `file`, as `review` would:
+/// `human` marks a false positive, a family confirms the file as that
+/// family's.  The repository's weight adjustments are regenerated.
+pub fn mark(file: &Path, label: &str, ignore_file: Option<&PathBuf>) -> Result<()> {
+    let label = parse_label(label)?;
+    let root = project_root(file, ignore_file)?;
+    let bytes = std::fs::read(file).with_context(|| format!("cannot read {}", file.display()))?;
+    let report = vibecheck_core::analyze_file_with(file, &policy::file_options()).with_context(|| format!("failed to analyze {}", file.display()))?;
+    let (ai_probability, rules) = ranking::rank(std::slice::from_ref(&report), false)
+        .into_iter()
+        .next()
+        .map(|o| (o.ai_probability, o.rules))
+        .unwrap_or_default();
+    let decision = Decision {
+        time: trend::now(),
+        path: relative(&root, file),
+        hash: content_hash(&bytes),
+        verdict: if label == ModelFamily::Human { Verdict::FalsePositive } else { Verdict::Confirmed },
+        family: if label == ModelFamily::Human { report.attribution.primary } else { label },
+        ai_probability,
+        rules,
+    };
+    append(&root, &decision)?;
+    let feedback = refresh_feedback(&root, &latest(load(&root)?))?;
+
+    println!("Marked {} as {} ({}).", decision.path, label, decision.verdict.as_str());
+    let adjusted: Vec<_> = decision.rules.iter().filter_map(|r| Some((r, feedback.rules.get(r)?))).collect();
+    if !adjusted.is_empty() {
+        println!("Weights for this repository ({}):", root.join(FEEDBACK_FILE).display());
+        let width = adjusted.iter().map(|(r, _)| r.len()).max().unwrap_or(0);
+        for (rule, a) in adjusted {
+            println!(
+                "  {rule:<width$}  ×{:.2}  ({} false positive{}, {} confirmed)",
+                a.multiplier,
+                a.false_positives,
+                if a.false_positives == 1 { "" } else { "s" },
+                a.confirmed,
+            );
+        }
+    }
+    Ok(())
+}
+
+fn event_loop<B: ratatui::backend::Backend>(terminal: &mut Terminal<B>, app: &mut App) -> Result<()> {
+    loop {
+        terminal.draw(|f| render(f, app))?;
+
+        let Event::Key(key) = event::read()? else { continue };
+        let verdict = match key.code {
+            KeyCode::Char('q') | KeyCode::Esc => break,
+            KeyCode::Char('c') if key.modifiers.contains(KeyModifiers::CONTROL) => break,
+            KeyCode::Char('c') | KeyCode::Char('y') => Some(Verdict::Confirmed),
+            KeyCode::Char('f') | KeyCode::Char('x') => Some(Verdict::FalsePositive),
+            KeyCode::Right | KeyCode::Char('l') | KeyCode::Char('n') | KeyCode::Tab => {
+                app.next();
+                None
+            }
+            KeyCode::Left | KeyCode::Char('h') | KeyCode::Char('p') | KeyCode::BackTab => {
+                app.previous();
+                None
+            }
+            KeyCode::Down | KeyCode::Char('j') => {
+                app.scroll_down(1);
+                None
+            }
+            KeyCode::Up | KeyCode::Char('k') => {
+                app.scroll_up(1);
+                None
+            }
+            KeyCode::PageDown | KeyCode::Char('d') => {
+                app.scroll_down(app.pane_h.max(1));
+                None
+            }
+            KeyCode::PageUp | KeyCode::Char('u') => {
+                app.scroll_up(app.pane_h.max(1));
+                None
+            }
+            KeyCode::Char('e') => {
+                app.next_evidence();
+                None
+            }
+            _ => None,
+        };
+        if let Some(verdict) = verdict {
+            let decision = app.decide(verdict, trend::now());
+            app.error = append(&app.root, &decision)
+                .and_then(|_| refresh_feedback(&app.root, &app.decisions))
+                .err()
+                .map(|e| format!("{e:#}"));
+        }
+    }
+    Ok(())
+}
+
+/// Write the latest decision for every reviewed file under `path`'s project
+/// as a JSON array to `out` (`-` for stdout).
+pub fn export(path: &Path, out: &Path, ignore_file: Option<&PathBuf>) -> Result<()> {
+    let root = project_root(path, ignore_file)?;
+    let decisions: Vec<Value> = latest(load(&root)?).values().map(Decision::to_json).collect();
+    let text = serde_json::to_string_pretty(&decisions)?;
+    if out == Path::new("-") {
+        println!("{text}");
+    } else {
+        std::fs::write(out, format!("{text}\n")).with_context(|| format!("cannot write {}", out.display()))?;
+        eprintln!("Exported {} decisions to {}", decisions.len(), out.display());
+    }
+    Ok(())
+}
+
+/// Add every reviewed file to the labelled corpus at `corpus` under the
+/// label its decision implies (see [`Decision::label`]), so that `eval`
+/// and `tune` learn from the reviews.  Files edited since their review, and
+/// files the corpus already has, are skipped.
+pub fn export_corpus(path: &Path, corpus: &Path, ignore_file: Option<&PathBuf>) -> Result<()> {
+    let root = project_root(path, ignore_file)?;
+    let decisions = latest(load(&root)?);
+    std::fs::create_dir_all(corpus)?;
+    let mut manifest = Manifest::load(corpus)?;
+    let mut added = 0;
+    for decision in decisions.values() {
+        let file = root.join(&decision.path);
+        match std::fs::read(&file) {
+            Ok(bytes) if content_hash(&bytes) == decision.hash => {}
+            Ok(_) => {
+                eprintln!("skipped {}: changed since it was reviewed", decision.path);
+                continue;
+            }
+            Err(e) => {
+                eprintln!("skipped {}: {e}", decision.path);
+                continue;
+            }
+        }
+        match manifest.add(&file, decision.label(), Provenance::default()) {
+            Ok(entry) => {
+                println!("added {} as {} ({})", entry.path, entry.label, decision.verdict.as_str());
+                added += 1;
+            }
+            Err(e) => eprintln!("skipped {}: {e:#}", decision.path),
+        }
+    }
+    if added > 0 {
+        manifest.save()?;
+    }
+    println!("Added {added} of {} reviewed files to {}.", decisions.len(), corpus.display());
+    Ok(())
+}
+
+fn project_root(path: &Path, ignore_file: Option<&PathBuf>) -> Result<PathBuf> {
+    let config = match ignore_file {
+        Some(f) => policy::from_file(f)?,
+        None => policy::load(path),
+    };
+    Ok(config.root().to_path_buf())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use vibecheck_core::report::{Attribution, ReportMetadata, Signal};
+
+    fn decision(path: &str, verdict: Verdict, time: i64) -> Decision {
+        Decision {
+            time,
+            path: path.into(),
+            hash: "abc".into(),
+            verdict,
+            family: ModelFamily::Gpt,
+            ai_probability: 0.9,
+            rules: vec!["gpt_step_comments".into()],
+        }
+    }
+
+    fn item(path: &str, source: &str, signals: Vec<Signal>) -> Item {
+        let mut scores = BTreeMap::new();
+        scores.insert(ModelFamily::Claude, 0.8);
+        scores.insert(ModelFamily::Human, 0.2);
+        Item {
+            path: PathBuf::from(path),
+            key: path.into(),
+            hash: content_hash(source.as_bytes()),
+            source: source.into(),
+            report: Report {
+                attribution: Attribution {
+                    primary: ModelFamily::Claude,
+                    confidence: 0.8,
+                    scores,
+                    era: None,
+                    ai_probability: Some(0.85),
+                    authorship: None,
+                },
+                signals,
+                metadata: ReportMetadata {
+                    file_path: Some(PathBuf::from(path)),
+                    lines_of_code: source.lines().count(),
+                    signal_count: 0,
+                    degraded: Vec::new(),
+                    skipped: None,
+                },
+                symbol_reports: None,
+                segments: None,
+                comment_free: None,
+                ensemble: None,
+            },
+            ai_probability: 0.85,
+            rules: vec![],
+        }
+    }
+
+    #[test]
+    fn decisions_round_trip_and_the_latest_wins() {
+        let dir = tempfile::tempdir().unwrap();
+        append(dir.path(), &decision("src/a.rs", Verdict::Confirmed, 1)).unwrap();
+        append(dir.path(), &decision("src/b.rs", Verdict::Confirmed, 2)).unwrap();
+        append(dir.path(), &decision("src/a.rs", Verdict::FalsePositive, 3)).unwrap();
+
+        let loaded = load(dir.path()).unwrap();
+        assert_eq!(loaded.len(), 3);
+        assert_eq!(loaded[1], decision("src/b.rs", Verdict::Confirmed, 2));
+        let latest = latest(loaded);
+        assert_eq!(latest["src/a.rs"].verdict, Verdict::FalsePositive);
+        assert_eq!(latest["src/a.rs"].label(), ModelFamily::Human);
+        assert_eq!(latest["src/b.rs"].label(), ModelFamily::Gpt);
+
+        std::fs::write(dir.path().join(REVIEW_FILE), "{\"path\": \"a.rs\"}\n").unwrap();
+        let err = load(dir.path()).unwrap_err().to_string();
+        assert!(err.ends_with(":1: not a review decision"), "{err}");
+    }
+
+    #[test]
+    fn decisions_apply_to_the_content_reviewed() {
+        let items = vec![item("a.rs", "fn a() {}\n", vec![]), item("b.rs", "fn b() {}\n", vec![])];
+        let mut stale = decision("b.rs", Verdict::Confirmed, 1);
+        stale.hash = "edited since".into();
+        let mut app = App::new(PathBuf::from("."), items, latest(vec![stale]));
+        assert_eq!(app.reviewed(), 0);
+
+        let recorded = app.decide(Verdict::FalsePositive, 7);
+        assert_eq!((recorded.path.as_str(), recorded.family), ("a.rs", ModelFamily::Claude));
+        assert_eq!(app.current, 1, "deciding moves on");
+        app.decide(Verdict::Confirmed, 8);
+        assert_eq!(app.current, 1, "the last file stays put");
+        assert_eq!(app.reviewed(), 2);
+    }
+
+    #[test]
+    fn next_evidence_scrolls_to_flagged_lines() {
+        let source = (1..=100).map(|i| format!("line {i}\n")).collect::<String>();
+        let signal = Signal::new("claude_x", "x", "x", ModelFamily::Claude, 1.0).with_lines(vec![40, 80]);
+        let human = Signal::new("human_x", "x", "x", ModelFamily::Human, 1.0).with_lines(vec![60]);
+        let mut app = App::new(PathBuf::from("."), vec![item("a.rs", &source, vec![signal, human])], BTreeMap::new());
+        app.pane_h = 30;
+        assert_eq!(app.items[0].evidence_lines(), BTreeSet::from([40, 80]));
+
+        app.next_evidence();
+        assert_eq!(app.scroll, 29, "line 40 a third of the way down");
+        app.next_evidence();
+        assert_eq!(app.scroll, 69);
+        app.next_evidence();
+        assert_eq!(app.scroll, 29, "wraps around");
+    }
+
+    #[test]
+    fn relative_paths_are_slash_separated() {
+        assert_eq!(relative(Path::new("/repo"), Path::new("/repo/src/a.rs")), "src/a.rs");
+        assert_eq!(relative(Path::new("/repo"), Path::new("other/b.rs")), "other/b.rs");
+        assert_eq!(relative(Path::new(""), Path::new("./src/a.rs")), "src/a.rs");
+    }
+
+    #[test]
+    fn false_positives_down_weight_their_rules() {
+        let dir = tempfile::tempdir().unwrap();
+        let confirmed = decision("src/a.rs", Verdict::Confirmed, 1);
+        let decisions = latest(vec![confirmed.clone()]);
+        assert!(refresh_feedback(dir.path(), &decisions).unwrap().is_empty());
+        assert!(!dir.path().join(FEEDBACK_FILE).exists(), "nothing to write");
+
+        let decisions = latest(vec![confirmed, decision("src/b.rs", Verdict::FalsePositive, 2)]);
+        let feedback = refresh_feedback(dir.path(), &decisions).unwrap();
+        assert_eq!(feedback.rules["gpt_step_comments"].multiplier, 0.75);
+        assert_eq!(Feedback::load(dir.path()).unwrap(), feedback);
+
+        // Overturning the false positive restores the weight.
+        let decisions = latest(vec![decision("src/b.rs", Verdict::Confirmed, 3)]);
+        refresh_feedback(dir.path(), &decisions).unwrap();
+        assert!(Feedback::load(dir.path()).unwrap().is_empty());
+    }
+}
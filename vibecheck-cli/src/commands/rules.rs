@@ -0,0 +1,276 @@
+use std::path::{Path, PathBuf};
+
+use anyhow::{Context, Result};
+use serde_json::{json, Value};
+
+use vibecheck_core::heuristics::{all_heuristics, HeuristicLanguage, HeuristicSpec};
+use vibecheck_core::ignore_rules::IgnoreConfig;
+
+use crate::commands::analyze::rule_matches;
+use crate::policy;
+
+/// List every signal with its weight under the current config, or, with
+/// `enable`/`disable`, turn signals on and off in the `.vibecheck` file.
+/// Rules are signal IDs or dotted prefixes of them.
+pub fn run(
+    language: Option<&str>,
+    format: &str,
+    enable: &[String],
+    disable: &[String],
+    ignore_file: Option<&PathBuf>,
+) -> Result<()> {
+    let editing = !enable.is_empty() || !disable.is_empty();
+    if let Some(f) = ignore_file.filter(|f| editing && !f.exists()) {
+        write_config(f, "")?;
+    }
+    let config = match ignore_file {
+        Some(f) => policy::from_file(f)?,
+        None => policy::load(&std::env::current_dir()?),
+    };
+    if editing {
+        let path = ignore_file.cloned().unwrap_or_else(|| config.root().join(".vibecheck"));
+        return toggle(&path, &config, enable, disable);
+    }
+
+    let overrides = config.heuristics_map();
+    let rules: Vec<Rule> = all_heuristics()
+        .iter()
+        .filter(|h| language.is_none_or(|l| source_language(h.language) == l || h.language.to_string() == l))
+        .map(|spec| Rule { spec, weight: overrides.get(spec.id).copied().unwrap_or(spec.default_weight) })
+        .collect();
+    match format {
+        "json" => {
+            let rules: Vec<Value> = rules.iter().map(Rule::to_json).collect();
+            println!("{}", serde_json::to_string_pretty(&rules)?);
+        }
+        _ => print!("{}", rules_table(&rules)),
+    }
+    Ok(())
+}
+
+struct Rule {
+    spec: &'static HeuristicSpec,
+    /// The weight under the config; 0 disables the signal.
+    weight: f64,
+}
+
+impl Rule {
+    fn enabled(&self) -> bool {
+        self.weight != 0.0
+    }
+
+    fn to_json(&self) -> Value {
+        json!({
+            "id": self.spec.id,
+            "language": source_language(self.spec.language),
+            "analyzer": self.spec.analyzer,
+            "family": self.spec.family,
+            "description": self.spec.description,
+            "default_weight": self.spec.default_weight,
+            "weight": self.weight,
+            "enabled": self.enabled(),
+        })
+    }
+}
+
+/// The source languages a signal applies to.  Syntax-tree signals run on
+/// the same files as the text signals of their language; commit-message
+/// signals run on no files at all.
+fn source_language(language: HeuristicLanguage) -> &'static str {
+    match language {
+        HeuristicLanguage::Rust | HeuristicLanguage::RustCst => "rust",
+        HeuristicLanguage::Python | HeuristicLanguage::PythonCst => "python",
+        HeuristicLanguage::Js | HeuristicLanguage::JsCst => "javascript",
+        HeuristicLanguage::Go | HeuristicLanguage::GoCst => "go",
+        HeuristicLanguage::PackCst => "packs",
+        HeuristicLanguage::Commit => "commits",
+        HeuristicLanguage::Docs => "docs",
+        HeuristicLanguage::All => "all",
+    }
+}
+
+fn rules_table(rules: &[Rule]) -> String {
+    let width = rules.iter().map(|r| r.spec.id.len()).max().unwrap_or(0).max(2);
+    let mut out = format!(
+        "{:<width$}  {:<10}  {:<7}  {:>7}  {:>7}  DESCRIPTION\n",
+        "ID", "LANGUAGE", "FAMILY", "DEFAULT", "WEIGHT"
+    );
+    out.push_str(&format!("{}\n", "─".repeat(width + 52)));
+    for r in rules {
+        let weight = if r.enabled() { format!("{:.2}", r.weight) } else { "off".to_string() };
+        out.push_str(&format!(
+            "{:<width$}  {:<10}  {:<7}  {:>7.2}  {:>7}  {}\n",
+            r.spec.id,
+            source_language(r.spec.language),
+            format!("{:?}", r.spec.family),
+            r.spec.default_weight,
+            weight,
+            r.spec.description,
+        ));
+    }
+    let off = rules.iter().filter(|r| !r.enabled()).count();
+    let n = rules.len();
+    out.push_str(&format!("\n{n} rule{}, {off} disabled by the config\n", if n == 1 { "" } else { "s" }));
+    out
+}
+
+/// Disable signals by setting their weight to 0 under `[heuristics]` in
+/// the config at `path`, and enable them by removing that override.  A
+/// signal the `[weights] file` turns off gets its default weight back
+/// explicitly.
+fn toggle(path: &Path, config: &IgnoreConfig, enable: &[String], disable: &[String]) -> Result<()> {
+    let ids = |rules: &[String], flag: &str| -> Result<Vec<&'static HeuristicSpec>> {
+        let mut matched = Vec::new();
+        for rule in rules {
+            let before = matched.len();
+            matched.extend(all_heuristics().iter().filter(|h| rule_matches(rule, h.id)));
+            anyhow::ensure!(matched.len() > before, "unknown rule in --{flag}: {rule} (see `vibecheck rules`)");
+        }
+        Ok(matched)
+    };
+    let (enable, disable) = (ids(enable, "enable")?, ids(disable, "disable")?);
+    if let Some(both) = enable.iter().find(|h| disable.iter().any(|d| d.id == h.id)) {
+        anyhow::bail!("{} is both enabled and disabled", both.id);
+    }
+
+    let overrides = config.heuristics_map();
+    let weight = |h: &HeuristicSpec| overrides.get(h.id).copied().unwrap_or(h.default_weight);
+    let enable: Vec<&HeuristicSpec> = enable.into_iter().filter(|h| weight(h) == 0.0).collect();
+    let disable: Vec<&HeuristicSpec> = disable.into_iter().filter(|h| weight(h) != 0.0).collect();
+
+    let original = match std::fs::read_to_string(path) {
+        Ok(text) => text,
+        Err(e) if e.kind() == std::io::ErrorKind::NotFound => String::new(),
+        Err(e) => return Err(e).with_context(|| format!("cannot read {}", path.display())),
+    };
+    let mut edits: Vec<(&str, Option<f64>)> = enable.iter().map(|h| (h.id, None)).collect();
+    edits.extend(disable.iter().map(|h| (h.id, Some(0.0))));
+    write_config(path, &set_weights(&original, &edits))?;
+
+    // Enabled signals still off come from the weights file.
+    let reloaded = policy::from_file(path)?.heuristics_map();
+    let pinned: Vec<(&str, Option<f64>)> = enable
+        .iter()
+        .filter(|h| reloaded.get(h.id) == Some(&0.0) && h.default_weight != 0.0)
+        .map(|h| (h.id, Some(h.default_weight)))
+        .collect();
+    if !pinned.is_empty() {
+        let text = std::fs::read_to_string(path)?;
+        write_config(path, &set_weights(&text, &pinned))?;
+    }
+
+    for h in &enable {
+        eprintln!("enabled  {}", h.id);
+    }
+    for h in &disable {
+        eprintln!("disabled {}", h.id);
+    }
+    if enable.is_empty() && disable.is_empty() {
+        eprintln!("Nothing to change.");
+    } else {
+        eprintln!("\nUpdated {}.", path.display());
+    }
+    Ok(())
+}
+
+fn write_config(path: &Path, text: &str) -> Result<()> {
+    std::fs::write(path, text).with_context(|| format!("cannot write {}", path.display()))
+}
+
+/// `text` with the `[heuristics]` entry of each ID in `weights` set to the
+/// weight, or removed for `None`.  Other lines, comments included, are kept;
+/// new entries go at the end of the table, which is added if missing.
+fn set_weights(text: &str, weights: &[(&str, Option<f64>)]) -> String {
+    let mut lines: Vec<String> = text.lines().map(String::from).collect();
+    let header = match lines.iter().position(|l| l.trim() == "[heuristics]") {
+        Some(i) => i,
+        None => {
+            if lines.last().is_some_and(|l| !l.trim().is_empty()) {
+                lines.push(String::new());
+            }
+            lines.push("[heuristics]".to_string());
+            lines.len() - 1
+        }
+    };
+    let section_end = |lines: &[String]| {
+        lines[header + 1..]
+            .iter()
+            .position(|l| l.trim_start().starts_with('['))
+            .map_or(lines.len(), |i| header + 1 + i)
+    };
+
+    let mut end = section_end(&lines);
+    let mut i = header + 1;
+    while i < end {
+        if entry_key(&lines[i]).is_some_and(|key| weights.iter().any(|(id, _)| *id == key)) {
+            lines.remove(i);
+            end -= 1;
+        } else {
+            i += 1;
+        }
+    }
+
+    let mut at = section_end(&lines);
+    while at > header + 1 && lines[at - 1].trim().is_empty() {
+        at -= 1;
+    }
+    for (id, weight) in weights {
+        if let Some(w) = weight {
+            lines.insert(at, format!("\"{id}\" = {w:?}"));
+            at += 1;
+        }
+    }
+    let mut out = lines.join("\n");
+    out.push('\n');
+    out
+}
+
+/// The key of a `"key" = value` line.
+fn entry_key(line: &str) -> Option<&str> {
+    let rest = line.trim_start().strip_prefix('"')?;
+    let (key, after) = rest.split_once('"')?;
+    after.trim_start().starts_with('=').then_some(key)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn set_weights_edits_the_heuristics_table_in_place() {
+        let text = "[ignore]\npatterns = [\"vendor/\"]\n\n[heuristics]\n# tuned\n\"rust.a\" = 0.0\n\"rust.b\" = 2.0\n\n[cache]\ndir = \"x\"\n";
+        let edited = set_weights(text, &[("rust.a", None), ("go.c", Some(0.0))]);
+        assert_eq!(
+            edited,
+            "[ignore]\npatterns = [\"vendor/\"]\n\n[heuristics]\n# tuned\n\"rust.b\" = 2.0\n\"go.c\" = 0.0\n\n[cache]\ndir = \"x\"\n"
+        );
+        assert_eq!(set_weights("", &[("go.c", Some(0.0))]), "[heuristics]\n\"go.c\" = 0.0\n");
+        assert_eq!(set_weights("[ignore]\n", &[("go.c", Some(1.5))]), "[ignore]\n\n[heuristics]\n\"go.c\" = 1.5\n");
+    }
+
+    #[test]
+    fn toggle_disables_and_reenables_rules() {
+        let dir = tempfile::tempdir().unwrap();
+        let path = dir.path().join(".vibecheck");
+        std::fs::write(&path, "[ignore]\npatterns = []\n").unwrap();
+        let id = all_heuristics()[0].id;
+
+        toggle(&path, &IgnoreConfig::from_file(&path).unwrap(), &[], &[id.to_string()]).unwrap();
+        assert_eq!(IgnoreConfig::from_file(&path).unwrap().heuristics_map().get(id), Some(&0.0));
+
+        toggle(&path, &IgnoreConfig::from_file(&path).unwrap(), &[id.to_string()], &[]).unwrap();
+        assert!(IgnoreConfig::from_file(&path).unwrap().heuristics_map().get(id).is_none());
+
+        let config = IgnoreConfig::from_file(&path).unwrap();
+        assert!(toggle(&path, &config, &["rust.no_such_rule".into()], &[]).is_err());
+        assert!(toggle(&path, &config, &[id.to_string()], &[id.to_string()]).is_err());
+    }
+
+    #[test]
+    fn table_marks_disabled_rules() {
+        let spec = &all_heuristics()[0];
+        let table = rules_table(&[Rule { spec, weight: 0.0 }]);
+        assert!(table.lines().nth(2).unwrap().contains("  off  "), "{table}");
+        assert!(table.ends_with("1 rule, 1 disabled by the config\n"), "{table}");
+    }
+}
@@ -0,0 +1,237 @@
+use std::io::Read;
+use std::path::{Path, PathBuf};
+
+use anyhow::{Context, Result};
+
+use vibecheck_core::ignore_rules::IgnoreRules;
+use vibecheck_core::output::OutputFormat;
+use vibecheck_core::sampling::{self, SampleSize};
+use vibecheck_core::Analyzer;
+
+use crate::artifact;
+use crate::commands::analyze::{check_rules, collect_files, format_report, gate_failures, gate_settings, parse_format, EXIT_GATE_FAILED};
+use crate::commands::trend::{self, Snapshot};
+use crate::notify;
+use crate::policy;
+use crate::progress::Progress;
+use crate::summary;
+
+/// Score the lines a unified diff adds, reading it from `patch` or, for
+/// `-`, from stdin.  Paths in the diff are relative to the current
+/// directory, whose `.vibecheck` config applies.
+pub fn run(
+    patch: &Path,
+    format: &str,
+    ignore_file: Option<&PathBuf>,
+    exclude: &[String],
+    threshold: f64,
+    fail_over: Option<f64>,
+    fail_on: &[String],
+) -> Result<()> {
+    let fmt = parse_format(format)?;
+    if let Some(limit) = fail_over {
+        anyhow::ensure!((0.0..=1.0).contains(&limit), "--fail-over must be between 0 and 1, got {limit}");
+    }
+
+    let diff = read_patch(patch)?;
+    let config = match ignore_file {
+        Some(f) => policy::from_file(f)?,
+        None => policy::load(&std::env::current_dir()?),
+    };
+    let (fail_over, fail_on) = gate_settings(&config, fail_over, fail_on);
+    check_rules(&fail_on)?;
+    let analyzer = Analyzer::new().with_config(&config);
+    let calibration = config.calibration();
+    let reports = analyzer
+        .analyze_patch(&diff, &config.with_excludes(exclude))
+        .context("invalid patch")?;
+
+    if reports.is_empty() {
+        eprintln!("No added lines in supported source files.");
+        return Ok(());
+    }
+
+    match fmt {
+        // The sources behind the report are not all in the patch.
+        OutputFormat::Html => print!("{}", vibecheck_core::html::format_html(&reports, &vec![None; reports.len()], false, &calibration)),
+        OutputFormat::Junit => print!("{}", vibecheck_core::output::format_junit(&reports, threshold)),
+        OutputFormat::Markdown => print!("{}", vibecheck_core::output::format_markdown(&reports)),
+        OutputFormat::Annotations => print!("{}", vibecheck_core::output::format_annotations(&reports)),
+        OutputFormat::Provenance => {
+            println!("{}", vibecheck_core::provenance::format_spdx(&reports, &vec![None; reports.len()], trend::now()))
+        }
+        OutputFormat::Json if reports.len() > 1 => println!("{}", serde_json::to_string_pretty(&reports)?),
+        _ => {
+            for report in &reports {
+                println!("{}", format_report(report, fmt, false, &calibration));
+            }
+        }
+    }
+
+    if let Some(warning) = summary::degraded_warning(&reports) {
+        eprint!("\n{warning}");
+    }
+
+    if fail_over.is_some() || !fail_on.is_empty() {
+        let failures = gate_failures(&reports, None, fail_over, &fail_on);
+        if !failures.is_empty() {
+            eprintln!("\n--- VIBECHECK FAILED ---");
+            for failure in &failures {
+                eprintln!("  {failure}");
+            }
+            std::process::exit(EXIT_GATE_FAILED);
+        }
+    }
+
+    Ok(())
+}
+
+/// Scan the tree under `path` and print one repository-level snapshot:
+/// how many files and lines are attributed to AI, and the mean AI
+/// probability.  With `save` the snapshot is appended to the project's
+/// history file for `vibecheck trend`, and with `notify` its summary is
+/// posted to the config's `[[notify]]` sinks.  With `sample` only that
+/// many files, drawn with `seed`, are analyzed, and the snapshot estimates
+/// the whole tree's AI share from them.
+#[allow(clippy::too_many_arguments)]
+pub fn run_tree(
+    path: &Path,
+    format: &str,
+    save: bool,
+    notify: bool,
+    sample: Option<SampleSize>,
+    seed: u64,
+    ignore_file: Option<&PathBuf>,
+    exclude: &[String],
+    fail_over: Option<f64>,
+    fail_on: &[String],
+) -> Result<()> {
+    let fmt = parse_format(format)?;
+    if !matches!(fmt, OutputFormat::Pretty | OutputFormat::Text | OutputFormat::Json) {
+        anyhow::bail!("scanning a directory supports pretty, text and json output, not {format}");
+    }
+    if let Some(limit) = fail_over {
+        anyhow::ensure!((0.0..=1.0).contains(&limit), "--fail-over must be between 0 and 1, got {limit}");
+    }
+
+    let config = match ignore_file {
+        Some(f) => policy::from_file(f)?,
+        None => policy::load(path),
+    };
+    let (fail_over, fail_on) = gate_settings(&config, fail_over, fail_on);
+    check_rules(&fail_on)?;
+    let root = config.root().to_path_buf();
+    let ignore = config.with_excludes(exclude);
+    // An archive is scanned as the tree inside it, under the config of the
+    // directory it sits in.
+    let extracted = if artifact::is_artifact(path) { Some(artifact::extract(path)?) } else { None };
+    let scan_root = extracted.as_ref().map_or_else(|| path.to_path_buf(), |e| e.root().to_path_buf());
+    let mut files = collect_files(&scan_root, &ignore).context("failed to collect files")?;
+    if scan_root.is_dir() && !ignore.include_generated() {
+        files.retain(|f| !vibecheck_core::generated::is_generated_file(f));
+    }
+    if files.is_empty() {
+        anyhow::bail!("no supported source files found in {}", path.display());
+    }
+    let population = files.len();
+    if let Some(size) = sample {
+        let sample_root = if extracted.is_some() { &scan_root } else { &root };
+        files = sampling::select(&files, sample_root, size, seed);
+    }
+
+    let progress = Progress::stderr(files.len());
+    let reports: std::io::Result<Vec<_>> = files
+        .iter()
+        .enumerate()
+        .map(|(done, f)| {
+            progress.update(done, f);
+            vibecheck_core::analyze_file_with(f, &policy::file_options())
+        })
+        .collect();
+    progress.finish();
+    let mut reports = reports.context("failed to analyze files")?;
+    if let Some(ref extracted) = extracted {
+        for report in &mut reports {
+            if let Some(ref p) = report.metadata.file_path {
+                report.metadata.file_path = Some(extracted.display_path(p));
+            }
+        }
+    }
+
+    let mut snapshot = Snapshot::new(scope(&root, path), head_commit(path), trend::now(), &reports);
+    if sample.is_some() {
+        snapshot = snapshot.sampled(population, seed, &reports);
+    }
+    match fmt {
+        OutputFormat::Json => println!("{}", serde_json::to_string_pretty(&snapshot.to_json())?),
+        _ => print!("{}", snapshot.render_text()),
+    }
+    if save {
+        let saved = trend::append(&root, &snapshot)?;
+        eprintln!("\nSaved to {}; see `vibecheck trend`.", saved.display());
+    }
+    if notify {
+        let repo = root.file_name().map_or_else(|| root.display().to_string(), |n| n.to_string_lossy().into_owned());
+        notify::send(
+            ignore.notifications(),
+            &notify::Scan { repo, subject: snapshot.scope.clone(), commit: snapshot.commit.clone(), link: None, reports: &reports },
+        );
+    }
+
+    if let Some(warning) = summary::degraded_warning(&reports) {
+        eprint!("\n{warning}");
+    }
+
+    if fail_over.is_some() || !fail_on.is_empty() {
+        let failures = gate_failures(&reports, None, fail_over, &fail_on);
+        if !failures.is_empty() {
+            eprintln!("\n--- VIBECHECK FAILED ---");
+            for failure in &failures {
+                eprintln!("  {failure}");
+            }
+            std::process::exit(EXIT_GATE_FAILED);
+        }
+    }
+
+    Ok(())
+}
+
+/// `path` relative to the project `root`, `.` for the root itself.
+fn scope(root: &Path, path: &Path) -> String {
+    let canonical = |p: &Path| p.canonicalize().unwrap_or_else(|_| p.to_path_buf());
+    match canonical(path).strip_prefix(canonical(root)) {
+        Ok(rel) if rel.as_os_str().is_empty() => ".".to_string(),
+        Ok(rel) => rel.display().to_string(),
+        Err(_) => path.display().to_string(),
+    }
+}
+
+/// The commit checked out in the repository containing `path`, if any.
+fn head_commit(path: &Path) -> Option<String> {
+    let repo = git2::Repository::discover(path).ok()?;
+    let commit = repo.head().ok()?.peel_to_commit().ok()?;
+    Some(commit.id().to_string())
+}
+
+fn read_patch(patch: &Path) -> Result<String> {
+    if patch == Path::new("-") {
+        let mut diff = String::new();
+        std::io::stdin().read_to_string(&mut diff).context("failed to read patch from stdin")?;
+        Ok(diff)
+    } else {
+        std::fs::read_to_string(patch).with_context(|| format!("cannot read {}", patch.display()))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn scope_is_relative_to_the_root() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::create_dir(dir.path().join("src")).unwrap();
+        assert_eq!(scope(dir.path(), dir.path()), ".");
+        assert_eq!(scope(dir.path(), &dir.path().join("src")), "src");
+    }
+}
@@ -0,0 +1,211 @@
+use std::path::{Path, PathBuf};
+use std::sync::Arc;
+use std::time::Instant;
+
+use anyhow::Result;
+use serde_json::{json, Value};
+
+use vibecheck_core::heuristics::all_heuristics;
+use vibecheck_core::ignore_rules::IgnoreConfig;
+use vibecheck_core::Analyzer;
+
+use crate::commands::check::language_extension;
+use crate::http::{self, Request, Response};
+use crate::metrics::Metrics;
+use crate::reload::{self, ConfigSource, Reloadable};
+
+/// Serve the JSON API on `listen` until the process is killed.
+///
+/// The `.vibecheck` config (from `ignore_file`, or discovered from the
+/// current directory) is reloaded whenever it changes: detector weights,
+/// thresholds and ignore rules apply to the requests that follow, while
+/// those in flight finish under the config they started with.  With
+/// `cache_dir`, reports are cached there.
+pub fn run(listen: &str, ignore_file: Option<&PathBuf>, cache_dir: Option<&PathBuf>) -> Result<()> {
+    let source = ConfigSource::new(ignore_file)?;
+    let server = Arc::new(Server::new(&source.load()?, cache_dir.cloned()));
+    let reloaded = Arc::clone(&server);
+    reload::watch(source, move |config| reloaded.reload(&config))?;
+    http::serve(listen, Arc::new(move |request: &Request| server.handle(request)))
+}
+
+struct Server {
+    state: Reloadable<State>,
+    cache_dir: Option<PathBuf>,
+    metrics: Metrics,
+}
+
+/// Everything derived from the config, replaced as a whole on reload.
+struct State {
+    analyzer: Analyzer,
+    /// `GET /rules` body.
+    rules: String,
+}
+
+impl State {
+    fn new(config: &IgnoreConfig, cache_dir: Option<&Path>) -> Self {
+        let overrides = config.heuristics_map();
+        let rules: Vec<Value> = all_heuristics()
+            .iter()
+            .map(|h| {
+                json!({
+                    "id": h.id,
+                    "language": h.language.to_string(),
+                    "analyzer": h.analyzer,
+                    "family": h.family,
+                    "description": h.description,
+                    "default_weight": h.default_weight,
+                    "weight": overrides.get(h.id).copied().unwrap_or(h.default_weight),
+                })
+            })
+            .collect();
+        let mut analyzer = Analyzer::new().with_config(config);
+        if let Some(dir) = cache_dir {
+            analyzer = analyzer.with_cache_dir(dir);
+        }
+        Self { analyzer, rules: Value::Array(rules).to_string() }
+    }
+}
+
+impl Server {
+    fn new(config: &IgnoreConfig, cache_dir: Option<PathBuf>) -> Self {
+        Self {
+            state: Reloadable::new(State::new(config, cache_dir.as_deref())),
+            cache_dir,
+            metrics: Metrics::default(),
+        }
+    }
+
+    /// Serve later requests under `config`.  The cache is kept: cache keys
+    /// cover the config's settings, so reports from before are not reused
+    /// where they would now differ.
+    fn reload(&self, config: &IgnoreConfig) {
+        self.state.set(State::new(config, self.cache_dir.as_deref()));
+    }
+
+    fn handle(&self, request: &Request) -> Response {
+        let (method, path) = (request.method.as_str(), request.path.as_str());
+        match (method, path) {
+            ("GET", "/healthz") => Response::json(200, &json!({ "status": "ok" })),
+            ("GET", "/rules") => Response::raw_json(200, self.state.get().rules.clone()),
+            ("GET", "/metrics") => Response::text(200, self.metrics.render()),
+            ("POST", "/analyze") => self.analyze(&request.body),
+            (_, "/healthz" | "/rules" | "/metrics" | "/analyze") => Response::error(405, format!("{method} not allowed on {path}")),
+            _ => Response::error(404, format!("no such endpoint: {path}")),
+        }
+    }
+
+    /// `POST /analyze` with `{"source": "...", "language": "go"}` and an
+    /// optional `"path"`, whose extension gives the language when
+    /// `language` is absent.  Answers with the report as `analyze --format
+    /// json` prints it.
+    fn analyze(&self, body: &[u8]) -> Response {
+        let request: Value = match serde_json::from_slice(body) {
+            Ok(v) => v,
+            Err(e) => return Response::error(400, format!("invalid JSON: {e}")),
+        };
+        let Some(source) = request.get("source").and_then(Value::as_str) else {
+            return Response::error(400, "missing string field: source");
+        };
+        let path = request.get("path").and_then(Value::as_str);
+        let name = match request.get("language").and_then(Value::as_str) {
+            Some(lang) => match language_extension(lang) {
+                Ok(ext) => Path::new(path.unwrap_or("snippet")).with_extension(ext),
+                Err(e) => return Response::error(400, e),
+            },
+            None => PathBuf::from(path.unwrap_or("snippet")),
+        };
+        let started = Instant::now();
+        let state = self.state.get();
+        match state.analyzer.analyze_source(&name.to_string_lossy(), source.as_bytes()) {
+            Ok(mut report) => {
+                self.metrics.record(&report, started.elapsed());
+                report.metadata.file_path = path.map(PathBuf::from);
+                match serde_json::to_value(&report) {
+                    Ok(value) => Response::json(200, &value),
+                    Err(e) => Response::error(500, e),
+                }
+            }
+            Err(e) => {
+                self.metrics.record_error();
+                Response::error(500, format!("{e:#}"))
+            }
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn server() -> Server {
+        Server::new(&IgnoreConfig::load(tempfile::tempdir().unwrap().path()), None)
+    }
+
+    fn request(method: &str, path: &str, body: &[u8]) -> Request {
+        Request {
+            method: method.to_string(),
+            path: path.to_string(),
+            headers: Default::default(),
+            body: body.to_vec(),
+        }
+    }
+
+    #[test]
+    fn healthz_and_rules() {
+        let server = server();
+        let health = server.handle(&request("GET", "/healthz", b""));
+        assert_eq!((health.status, health.body.as_str()), (200, r#"{"status":"ok"}"#));
+
+        let rules = server.handle(&request("GET", "/rules", b""));
+        let rules: Value = serde_json::from_str(&rules.body).unwrap();
+        let first = &rules.as_array().unwrap()[0];
+        assert!(first["id"].is_string());
+        assert_eq!(first["weight"], first["default_weight"]);
+    }
+
+    #[test]
+    fn analyze_returns_report_json() {
+        let body = json!({ "source": "fn main() {\n    println!(\"hi\");\n}\n", "language": "rust", "path": "src/main.rs" });
+        let server = server();
+        let response = server.handle(&request("POST", "/analyze", body.to_string().as_bytes()));
+        assert_eq!(response.status, 200, "{}", response.body);
+        let report: Value = serde_json::from_str(&response.body).unwrap();
+        assert_eq!(report["metadata"]["file_path"], "src/main.rs");
+        assert!(report["attribution"]["scores"].is_object());
+
+        let metrics = server.handle(&request("GET", "/metrics", b""));
+        assert_eq!(metrics.content_type, "text/plain; version=0.0.4; charset=utf-8");
+        assert!(metrics.body.contains("\nvibecheck_files_analyzed_total 1\n"), "{}", metrics.body);
+    }
+
+    #[test]
+    fn analyze_rejects_bad_requests() {
+        let server = server();
+        let status = |method, path, body: &[u8]| server.handle(&request(method, path, body)).status;
+        assert_eq!(status("POST", "/analyze", b"{"), 400);
+        assert_eq!(status("POST", "/analyze", br#"{"language":"go"}"#), 400);
+        assert_eq!(status("POST", "/analyze", br#"{"source":"x","language":"cobol"}"#), 400);
+        assert_eq!(status("GET", "/analyze", b""), 405);
+        assert_eq!(status("POST", "/metrics", b""), 405);
+        assert_eq!(status("GET", "/nope", b""), 404);
+    }
+
+    #[test]
+    fn reload_swaps_weights_for_later_requests() {
+        let dir = tempfile::tempdir().unwrap();
+        let file = dir.path().join(".vibecheck");
+        std::fs::write(&file, "").unwrap();
+        let server = Server::new(&IgnoreConfig::from_file(&file).unwrap(), None);
+        let in_flight = server.state.get();
+
+        std::fs::write(&file, "[heuristics]\n\"rust.errors.zero_unwrap\" = 3.0\n").unwrap();
+        server.reload(&IgnoreConfig::from_file(&file).unwrap());
+        let weight = |body: &str| {
+            let rules: Value = serde_json::from_str(body).unwrap();
+            rules.as_array().unwrap().iter().find(|r| r["id"] == "rust.errors.zero_unwrap").unwrap()["weight"].clone()
+        };
+        assert_eq!(weight(&server.handle(&request("GET", "/rules", b"")).body), 3.0);
+        assert_eq!(weight(&in_flight.rules), 0.8, "in-flight requests keep the old config");
+    }
+}
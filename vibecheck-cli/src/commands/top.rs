@@ -0,0 +1,131 @@
+use std::path::{Path, PathBuf};
+
+use anyhow::{Context, Result};
+use serde_json::{json, Value};
+
+use vibecheck_core::ignore_rules::IgnoreRules;
+use vibecheck_core::ranking::{self, Offender};
+
+use crate::commands::analyze::collect_files;
+use crate::policy;
+
+/// Analyze every source file under `path` and print the `n` files and
+/// functions (files only with `files_only`) most likely to be AI-generated,
+/// highest AI probability first, with the rules behind each verdict.
+pub fn run(
+    path: &Path,
+    n: usize,
+    files_only: bool,
+    format: &str,
+    ignore_file: Option<&PathBuf>,
+    exclude: &[String],
+) -> Result<()> {
+    let config = match ignore_file {
+        Some(f) => policy::from_file(f)?,
+        None => policy::load(path),
+    };
+    let ignore = config.with_excludes(exclude);
+    let mut files = collect_files(&path.to_path_buf(), &ignore).context("failed to collect files")?;
+    if path.is_dir() && !ignore.include_generated() {
+        files.retain(|f| !vibecheck_core::generated::is_generated_file(f));
+    }
+
+    let mut reports = Vec::with_capacity(files.len());
+    for file in &files {
+        let report = if files_only {
+            vibecheck_core::analyze_file_with(file, &policy::file_options()).map_err(anyhow::Error::from)
+        } else {
+            vibecheck_core::analyze_file_symbols_with(file, &policy::file_options())
+        };
+        reports.push(report.with_context(|| format!("failed to analyze {}", file.display()))?);
+    }
+    let ranked = ranking::rank(&reports, !files_only);
+    let total = ranked.len();
+    let top: Vec<Offender> = ranked.into_iter().take(n).collect();
+
+    match format {
+        "json" => println!("{}", serde_json::to_string_pretty(&to_json(&top, total))?),
+        _ => print!("{}", render_text(&top, total, files_only)),
+    }
+    Ok(())
+}
+
+fn render_text(top: &[Offender], total: usize, files_only: bool) -> String {
+    let what = if files_only { "files" } else { "files and functions" };
+    if top.is_empty() {
+        return format!("No {what} with enough signal data to rank.\n");
+    }
+    let mut out = format!("Top {} of {total} {what} by AI probability\n\n", top.len());
+    let width = top.iter().map(|o| o.location().chars().count()).max().unwrap_or(0).max(8);
+    out.push_str(&format!("{:>3}  {:>4}  {:<8}  {:<width$}  RULES\n", "#", "AI", "FAMILY", "LOCATION"));
+    out.push_str(&format!("{}\n", "─".repeat(30 + width)));
+    for (i, o) in top.iter().enumerate() {
+        let rules = if o.rules.is_empty() { "—".to_string() } else { o.rules.join(", ") };
+        out.push_str(&format!(
+            "{:>3}  {:>4}  {:<8}  {:<width$}  {rules}\n",
+            i + 1,
+            format!("{:.2}", o.ai_probability),
+            o.family.to_string(),
+            o.location(),
+        ));
+    }
+    out
+}
+
+fn to_json(top: &[Offender], total: usize) -> Value {
+    json!({
+        "ranked": total,
+        "offenders": top
+            .iter()
+            .enumerate()
+            .map(|(i, o)| {
+                let mut entry = serde_json::to_value(o).expect("offender should be serializable");
+                entry["rank"] = json!(i + 1);
+                entry
+            })
+            .collect::<Vec<_>>(),
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use vibecheck_core::report::ModelFamily;
+
+    fn offender(path: &str, symbol: Option<&str>, ai_probability: f64) -> Offender {
+        Offender {
+            path: PathBuf::from(path),
+            symbol: symbol.map(String::from),
+            lines: symbol.map(|_| (3, 9)),
+            family: ModelFamily::Claude,
+            ai_probability,
+            rules: vec!["rust.errors.zero_unwrap".into()],
+        }
+    }
+
+    #[test]
+    fn text_lists_offenders_in_rank_order() {
+        let top = [offender("src/lib.rs", Some("parse"), 0.97), offender("src/main.rs", None, 0.81)];
+        let text = render_text(&top, 40, false);
+        assert!(text.starts_with("Top 2 of 40 files and functions by AI probability\n"), "{text}");
+        let rows: Vec<&str> = text.lines().skip(4).collect();
+        assert!(rows[0].starts_with("  1  0.97  Claude    src/lib.rs:3-9 parse()"), "{text}");
+        assert!(rows[1].contains("src/main.rs") && rows[1].ends_with("rust.errors.zero_unwrap"), "{text}");
+        assert_eq!(render_text(&[], 0, true), "No files with enough signal data to rank.\n");
+    }
+
+    #[test]
+    fn json_carries_ranks() {
+        let json = to_json(&[offender("a.rs", None, 0.9), offender("b.rs", Some("f"), 0.8)], 2);
+        assert_eq!(json["ranked"], 2);
+        assert_eq!(json["offenders"][1]["rank"], 2);
+        assert_eq!(json["offenders"][1]["symbol"], "f");
+        assert!(json["offenders"][0].get("symbol").is_none());
+    }
+
+    #[test]
+    fn run_ranks_the_fixture_corpus() {
+        let fixtures = PathBuf::from(env!("CARGO_MANIFEST_DIR")).join("../vibecheck-core/tests/fixtures/lru_cache");
+        run(&fixtures, 5, true, "json", None, &[]).unwrap();
+    }
+}
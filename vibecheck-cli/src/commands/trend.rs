@@ -0,0 +1,374 @@
+use std::io::Write;
+use std::path::{Path, PathBuf};
+use std::time::{SystemTime, UNIX_EPOCH};
+
+use anyhow::{Context, Result};
+use serde_json::{json, Value};
+
+use vibecheck_core::report::{ModelFamily, Report};
+use vibecheck_core::sampling::{self, Estimate};
+
+use crate::commands::history::{format_date, sparkline, SPARK_LEVELS};
+use crate::policy;
+
+/// Saved scans, one JSON object per line, at the project root beside the
+/// `.vibecheck` config file.
+pub const HISTORY_FILE: &str = ".vibecheck-history";
+
+/// Repository-level result of one `vibecheck scan`, as saved by `--save`.
+#[derive(Debug, Clone, PartialEq)]
+pub struct Snapshot {
+    /// Unix time of the scan.
+    pub time: i64,
+    /// HEAD when the scan ran; uncommitted changes were scanned too.
+    pub commit: Option<String>,
+    /// The scanned path relative to the project root, `.` for all of it.
+    pub scope: String,
+    pub files: usize,
+    /// Files attributed to an AI family with enough data for a verdict.
+    pub ai_files: usize,
+    pub lines: usize,
+    /// Lines of code in the AI-attributed files.
+    pub ai_lines: usize,
+    /// Mean calibrated AI probability over the files that have one.
+    pub mean_ai_probability: Option<f64>,
+    /// Set when only a sample of the files was scanned (`scan --sample`).
+    pub sample: Option<Sample>,
+}
+
+/// How a sampled scan drew its files, and what they say about the rest.
+#[derive(Debug, Clone, PartialEq)]
+pub struct Sample {
+    /// Files the sample was drawn from.
+    pub population: usize,
+    pub seed: u64,
+    /// AI share of the population's lines, with its 95% confidence interval.
+    pub estimate: Estimate,
+}
+
+impl Snapshot {
+    /// Summarize `reports`; skipped files are not counted.
+    pub fn new(scope: String, commit: Option<String>, time: i64, reports: &[Report]) -> Self {
+        let mut snapshot = Snapshot {
+            time,
+            commit,
+            scope,
+            files: 0,
+            ai_files: 0,
+            lines: 0,
+            ai_lines: 0,
+            mean_ai_probability: None,
+            sample: None,
+        };
+        let mut probabilities = Vec::new();
+        for report in scanned(reports) {
+            snapshot.files += 1;
+            snapshot.lines += report.metadata.lines_of_code;
+            if is_ai(report) {
+                snapshot.ai_files += 1;
+                snapshot.ai_lines += report.metadata.lines_of_code;
+            }
+            probabilities.extend(report.attribution.ai_probability);
+        }
+        if !probabilities.is_empty() {
+            snapshot.mean_ai_probability = Some(probabilities.iter().sum::<f64>() / probabilities.len() as f64);
+        }
+        snapshot
+    }
+
+    /// Mark the snapshot as a sample of `population` files drawn with
+    /// `seed`, estimating the population's AI share from `reports`.
+    pub fn sampled(mut self, population: usize, seed: u64, reports: &[Report]) -> Self {
+        let per_file: Vec<(usize, usize)> = scanned(reports)
+            .map(|r| (r.metadata.lines_of_code, if is_ai(r) { r.metadata.lines_of_code } else { 0 }))
+            .collect();
+        // A sample without a line of code says nothing about the rest.
+        let estimate = sampling::estimate_fraction(&per_file, population)
+            .unwrap_or(Estimate { fraction: 0.0, low: 0.0, high: 1.0 });
+        self.sample = Some(Sample { population, seed, estimate });
+        self
+    }
+
+    /// Share of the scanned lines in AI-attributed files; for a sample, the
+    /// estimated share of the whole tree's.
+    pub fn ai_fraction(&self) -> f64 {
+        self.ai_lines as f64 / self.lines.max(1) as f64
+    }
+
+    pub fn to_json(&self) -> Value {
+        let mut value = json!({
+            "time": self.time,
+            "commit": self.commit,
+            "scope": self.scope,
+            "files": self.files,
+            "ai_files": self.ai_files,
+            "lines": self.lines,
+            "ai_lines": self.ai_lines,
+            "ai_fraction": self.ai_fraction(),
+            "mean_ai_probability": self.mean_ai_probability,
+        });
+        if let Some(ref sample) = self.sample {
+            value["sample"] = json!({
+                "population": sample.population,
+                "seed": sample.seed,
+                "ai_fraction_low": sample.estimate.low,
+                "ai_fraction_high": sample.estimate.high,
+            });
+        }
+        value
+    }
+
+    fn from_json(value: &Value) -> Option<Self> {
+        let count = |key: &str| value.get(key)?.as_u64().map(|n| n as usize);
+        Some(Snapshot {
+            time: value.get("time")?.as_i64()?,
+            commit: value.get("commit").and_then(Value::as_str).map(String::from),
+            scope: value.get("scope")?.as_str()?.to_string(),
+            files: count("files")?,
+            ai_files: count("ai_files")?,
+            lines: count("lines")?,
+            ai_lines: count("ai_lines")?,
+            mean_ai_probability: value.get("mean_ai_probability").and_then(Value::as_f64),
+            sample: match value.get("sample") {
+                Some(sample) => Some(Sample {
+                    population: sample.get("population")?.as_u64()? as usize,
+                    seed: sample.get("seed")?.as_u64()?,
+                    estimate: Estimate {
+                        fraction: value.get("ai_fraction")?.as_f64()?,
+                        low: sample.get("ai_fraction_low")?.as_f64()?,
+                        high: sample.get("ai_fraction_high")?.as_f64()?,
+                    },
+                }),
+                None => None,
+            },
+        })
+    }
+
+    pub fn render_text(&self) -> String {
+        let mut out = format!("Scan of {}", self.scope);
+        if let Some(ref commit) = self.commit {
+            out.push_str(&format!(" at {}", short(commit)));
+        }
+        out.push_str(&format!(
+            "\n  {} files, {} attributed to AI\n  {} of {} lines in AI-attributed files ({:.0}%)\n",
+            self.files,
+            self.ai_files,
+            self.ai_lines,
+            self.lines,
+            self.ai_fraction() * 100.0,
+        ));
+        if let Some(p) = self.mean_ai_probability {
+            out.push_str(&format!("  mean AI probability {p:.2}\n"));
+        }
+        if let Some(ref sample) = self.sample {
+            out.push_str(&format!(
+                "  sampled {} of {} files (seed {}): estimated AI share {:.1}% (95% CI {:.1}–{:.1}%)\n",
+                self.files,
+                sample.population,
+                sample.seed,
+                sample.estimate.fraction * 100.0,
+                sample.estimate.low * 100.0,
+                sample.estimate.high * 100.0,
+            ));
+        }
+        out
+    }
+}
+
+/// The reports of files that were analyzed rather than skipped.
+fn scanned(reports: &[Report]) -> impl Iterator<Item = &Report> {
+    reports.iter().filter(|r| r.metadata.skipped.is_none())
+}
+
+/// Attributed to an AI family with enough data for a verdict.
+fn is_ai(report: &Report) -> bool {
+    report.attribution.has_sufficient_data() && report.attribution.primary != ModelFamily::Human
+}
+
+fn short(commit: &str) -> &str {
+    &commit[..commit.len().min(8)]
+}
+
+/// Seconds since the Unix epoch.
+pub fn now() -> i64 {
+    SystemTime::now().duration_since(UNIX_EPOCH).map(|d| d.as_secs() as i64).unwrap_or(0)
+}
+
+/// Append `snapshot` to the history file under `root`, returning its path.
+pub fn append(root: &Path, snapshot: &Snapshot) -> Result<PathBuf> {
+    let path = root.join(HISTORY_FILE);
+    let mut file = std::fs::OpenOptions::new()
+        .create(true)
+        .append(true)
+        .open(&path)
+        .with_context(|| format!("cannot open {}", path.display()))?;
+    writeln!(file, "{}", snapshot.to_json()).with_context(|| format!("cannot write {}", path.display()))?;
+    Ok(path)
+}
+
+/// The scans saved under `root`, oldest first; none if nothing was saved.
+pub fn load(root: &Path) -> Result<Vec<Snapshot>> {
+    let path = root.join(HISTORY_FILE);
+    let text = match std::fs::read_to_string(&path) {
+        Ok(text) => text,
+        Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Vec::new()),
+        Err(e) => return Err(e).with_context(|| format!("cannot read {}", path.display())),
+    };
+    let mut snapshots = Vec::new();
+    for (i, line) in text.lines().enumerate().filter(|(_, l)| !l.trim().is_empty()) {
+        let snapshot = serde_json::from_str(line)
+            .ok()
+            .as_ref()
+            .and_then(Snapshot::from_json)
+            .with_context(|| format!("{}:{}: not a saved scan", path.display(), i + 1))?;
+        snapshots.push(snapshot);
+    }
+    snapshots.sort_by_key(|s| s.time);
+    Ok(snapshots)
+}
+
+/// Print how the scans saved with `vibecheck scan --save` evolved: a table
+/// (`format == "text"`) or the scans as JSON.  `scope` keeps only the scans
+/// of one path, so a history mixing `src` and `.` scans can still be read.
+pub fn run(path: &Path, format: &str, scope: Option<&str>) -> Result<()> {
+    let config = policy::load(path);
+    let mut snapshots = load(config.root())?;
+    if let Some(scope) = scope {
+        snapshots.retain(|s| s.scope == scope);
+    }
+    match format {
+        "json" => {
+            let scans: Vec<Value> = snapshots.iter().map(Snapshot::to_json).collect();
+            println!("{}", serde_json::to_string_pretty(&json!({ "scans": scans }))?);
+        }
+        _ => print!("{}", trend_table(&snapshots)),
+    }
+    Ok(())
+}
+
+fn trend_table(snapshots: &[Snapshot]) -> String {
+    let (Some(first), Some(last)) = (snapshots.first(), snapshots.last()) else {
+        return format!("(no saved scans; run `vibecheck scan --save .` to record one in {HISTORY_FILE})\n");
+    };
+    let fractions: Vec<f64> = snapshots.iter().map(Snapshot::ai_fraction).collect();
+    let mut out = format!(
+        "AI-generated share of scanned lines, {} saved scan{} ({} → {})\n\n",
+        snapshots.len(),
+        if snapshots.len() == 1 { "" } else { "s" },
+        format_date(first.time),
+        format_date(last.time),
+    );
+    out.push_str(&format!(
+        "  {}  {:.0}% → {:.0}%\n\n",
+        sparkline(&fractions),
+        first.ai_fraction() * 100.0,
+        last.ai_fraction() * 100.0,
+    ));
+
+    let width = snapshots.iter().map(|s| s.scope.chars().count()).max().unwrap_or(0).max(5);
+    out.push_str(&format!(
+        "{:<10}  {:<8}  {:<width$}  {:>6}  {:>8}  {:>8}  {:>4}\n",
+        "DATE", "COMMIT", "SCOPE", "FILES", "AI FILES", "AI LINES", "MEAN"
+    ));
+    out.push_str(&format!("{}\n", "─".repeat(width + 58)));
+    for s in snapshots {
+        out.push_str(&format!(
+            "{:<10}  {:<8}  {:<width$}  {:>6}  {:>8}  {:>7.0}%  {:>4}\n",
+            format_date(s.time),
+            s.commit.as_deref().map(short).unwrap_or("—"),
+            s.scope,
+            s.files,
+            s.ai_files,
+            s.ai_fraction() * 100.0,
+            s.mean_ai_probability.map(|p| format!("{p:.2}")).unwrap_or_else(|| "—".into()),
+        ));
+    }
+    out.push_str(&format!(
+        "\n  scale: {} = 0% … {} = 100%\n",
+        SPARK_LEVELS[0] as char,
+        SPARK_LEVELS[SPARK_LEVELS.len() - 1] as char,
+    ));
+    out
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn report(family: ModelFamily, lines: usize, probability: f64) -> Report {
+        let mut report = vibecheck_core::analyze("fn main() {}");
+        report.metadata.lines_of_code = lines;
+        report.metadata.signal_count = 5;
+        report.attribution.primary = family;
+        report.attribution.confidence = 0.8;
+        report.attribution.ai_probability = Some(probability);
+        report
+    }
+
+    fn snapshot(time: i64, ai_lines: usize) -> Snapshot {
+        Snapshot {
+            time,
+            commit: Some("0123456789abcdef".into()),
+            scope: ".".into(),
+            files: 10,
+            ai_files: 2,
+            lines: 100,
+            ai_lines,
+            mean_ai_probability: Some(0.25),
+            sample: None,
+        }
+    }
+
+    #[test]
+    fn snapshot_counts_ai_attributed_lines() {
+        let reports = vec![
+            report(ModelFamily::Claude, 30, 0.9),
+            report(ModelFamily::Human, 70, 0.1),
+            Report::skipped(PathBuf::from("big.rs"), "too_large"),
+        ];
+        let s = Snapshot::new("src".into(), None, 0, &reports);
+        assert_eq!((s.files, s.ai_files, s.lines, s.ai_lines), (2, 1, 100, 30));
+        assert!((s.ai_fraction() - 0.3).abs() < 1e-9);
+        assert!((s.mean_ai_probability.unwrap() - 0.5).abs() < 1e-9);
+    }
+
+    #[test]
+    fn history_round_trips_oldest_first() {
+        let dir = tempfile::tempdir().unwrap();
+        assert!(load(dir.path()).unwrap().is_empty());
+        append(dir.path(), &snapshot(200, 40)).unwrap();
+        append(dir.path(), &snapshot(100, 10)).unwrap();
+        let loaded = load(dir.path()).unwrap();
+        assert_eq!(loaded, vec![snapshot(100, 10), snapshot(200, 40)]);
+
+        std::fs::write(dir.path().join(HISTORY_FILE), "{\"time\": 1}\n").unwrap();
+        let err = load(dir.path()).unwrap_err().to_string();
+        assert!(err.ends_with(":1: not a saved scan"), "{err}");
+    }
+
+    #[test]
+    fn sampled_snapshots_carry_their_estimate() {
+        let reports: Vec<Report> = (0..20)
+            .map(|i| if i % 4 == 0 { report(ModelFamily::Gpt, 50, 0.9) } else { report(ModelFamily::Human, 50, 0.1) })
+            .collect();
+        let s = Snapshot::new(".".into(), None, 0, &reports).sampled(400, 7, &reports);
+        let sample = s.sample.as_ref().unwrap();
+        assert_eq!((sample.population, sample.seed), (400, 7));
+        assert!((sample.estimate.fraction - s.ai_fraction()).abs() < 1e-9);
+        assert!(sample.estimate.low < 0.25 && 0.25 < sample.estimate.high, "{:?}", sample.estimate);
+        assert!(s.render_text().contains("sampled 20 of 400 files (seed 7): estimated AI share 25.0% (95% CI"), "{}", s.render_text());
+
+        let dir = tempfile::tempdir().unwrap();
+        append(dir.path(), &s).unwrap();
+        assert_eq!(load(dir.path()).unwrap(), vec![s]);
+    }
+
+    #[test]
+    fn trend_table_shows_growth() {
+        let table = trend_table(&[snapshot(0, 10), snapshot(86_400, 40)]);
+        assert!(table.contains("2 saved scans (1970-01-01 → 1970-01-02)"), "{table}");
+        assert!(table.contains("10% → 40%"), "{table}");
+        assert!(table.contains("1970-01-02  01234567  ."), "{table}");
+        assert!(trend_table(&[]).starts_with("(no saved scans"));
+    }
+}
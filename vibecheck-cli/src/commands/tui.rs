@@ -18,9 +18,11 @@ use ratatui::{
     Frame, Terminal,
 };
 
-use vibecheck_core::ignore_rules::{IgnoreConfig, IgnoreRules};
+use vibecheck_core::ignore_rules::IgnoreRules;
 use vibecheck_core::report::{ModelFamily, Report, SymbolReport};
 
+use crate::policy;
+
 // ---------------------------------------------------------------------------
 // Data model
 // ---------------------------------------------------------------------------
@@ -131,7 +133,7 @@ impl App {
         let detail = all
             .first()
             .filter(|e| !e.is_dir)
-            .and_then(|e| vibecheck_core::analyze_file_symbols(&e.path).ok());
+            .and_then(|e| vibecheck_core::analyze_file_symbols_with(&e.path, &policy::file_options()).ok());
         App {
             all,
             collapsed: HashSet::new(),
@@ -211,7 +213,7 @@ impl App {
         self.detail = visible
             .get(self.selected)
             .filter(|e| !e.is_dir)
-            .and_then(|e| vibecheck_core::analyze_file_symbols(&e.path).ok());
+            .and_then(|e| vibecheck_core::analyze_file_symbols_with(&e.path, &policy::file_options()).ok());
         self.detail_scroll = 0;
         self.detail_scroll_x = 0;
         // Close the history panel when navigating to a different file.
@@ -447,7 +449,7 @@ fn name_to_family(name: &str) -> ModelFamily {
 // Rendering
 // ---------------------------------------------------------------------------
 
-fn family_color(family: ModelFamily) -> Color {
+pub(crate) fn family_color(family: ModelFamily) -> Color {
     match family {
         ModelFamily::Claude  => Color::Rgb(210, 168, 255), // #d2a8ff
         ModelFamily::Gpt     => Color::Rgb(126, 231, 135), // #7ee787
@@ -964,13 +966,13 @@ fn git_history_for_file(path: &Path) -> anyhow::Result<Vec<HistoryEntry>> {
 
 pub fn run(path: &Path, ignore_file: Option<&PathBuf>) -> Result<()> {
     let ignore: Box<dyn IgnoreRules> = match ignore_file {
-        Some(f) => Box::new(IgnoreConfig::from_file(f)?),
-        None => Box::new(IgnoreConfig::load(path)),
+        Some(f) => Box::new(policy::from_file(f)?),
+        None => Box::new(policy::load(path)),
     };
 
     // Analyze all files up front (cache-backed, so fast on repeat runs).
     eprintln!("Analyzing {}…", path.display());
-    let reports = vibecheck_core::analyze_directory_with(path, true, ignore.as_ref())?;
+    let reports = vibecheck_core::analyze_directory_with_options(path, ignore.as_ref(), &policy::file_options())?;
     if reports.is_empty() {
         anyhow::bail!("no supported source files found in {}", path.display());
     }
@@ -1086,7 +1088,7 @@ fn event_loop<B: ratatui::backend::Backend>(
 #[cfg(test)]
 mod tests {
     use super::*;
-    use std::collections::HashMap;
+    use std::collections::BTreeMap;
     use vibecheck_core::report::{
         Attribution, ModelFamily, Report, ReportMetadata, SymbolMetadata, SymbolReport,
     };
@@ -1096,13 +1098,16 @@ mod tests {
     // -------------------------------------------------------------------------
 
     fn make_report(family: ModelFamily, confidence: f64, loc: usize) -> Report {
-        let mut scores = HashMap::new();
+        let mut scores = BTreeMap::new();
         scores.insert(family, confidence);
         Report {
-            attribution: Attribution { primary: family, confidence, scores },
+            attribution: Attribution { primary: family, confidence, scores, era: None, ai_probability: None, authorship: None },
             signals: vec![],
-            metadata: ReportMetadata { file_path: None, lines_of_code: loc, signal_count: 0 },
+            metadata: ReportMetadata { file_path: None, lines_of_code: loc, signal_count: 0, degraded: vec![], skipped: None },
             symbol_reports: None,
+            segments: None,
+            comment_free: None,
+            ensemble: None,
         }
     }
 
@@ -1117,7 +1122,10 @@ mod tests {
             attribution: Attribution {
                 primary: family,
                 confidence,
-                scores: HashMap::new(),
+                scores: BTreeMap::new(),
+                era: None,
+                ai_probability: None,
+                authorship: None,
             },
             signals: vec![],
         }
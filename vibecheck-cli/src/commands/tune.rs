@@ -0,0 +1,182 @@
+use std::path::Path;
+
+use anyhow::{bail, Context, Result};
+
+use vibecheck_core::embedding;
+use vibecheck_core::language::detect_language;
+use vibecheck_core::tuning;
+
+/// Largest weight changes printed after tuning.
+const SHOWN_CHANGES: usize = 10;
+
+pub fn run(corpus: &Path, folds: usize, l2: f64, output: &Path) -> Result<()> {
+    let samples = load(corpus, folds)?;
+    let tuning = tuning::tune(&samples, folds, l2);
+    println!(
+        "Tuned {} signals on {} files ({}-fold cross-validation)",
+        tuning.weights.len(),
+        tuning.files,
+        tuning.folds
+    );
+    println!("  accuracy before tuning: {:.1}%", tuning.baseline_accuracy * 100.0);
+    println!("  accuracy after tuning:  {:.1}%", tuning.tuned_accuracy * 100.0);
+
+    let changes: Vec<_> = tuning.changes().into_iter().filter(|(_, old, new)| (new - old).abs() >= 0.005).collect();
+    if !changes.is_empty() {
+        println!();
+        println!("{:<42}  {:>6}  {:>6}", "SIGNAL", "BEFORE", "AFTER");
+        println!("{}", "─".repeat(58));
+        for (id, old, new) in changes.iter().take(SHOWN_CHANGES) {
+            println!("{id:<42}  {old:>6.2}  {new:>6.2}");
+        }
+        if changes.len() > SHOWN_CHANGES {
+            println!("… and {} more", changes.len() - SHOWN_CHANGES);
+        }
+    }
+
+    std::fs::write(output, tuning.to_toml()).with_context(|| format!("failed to write {}", output.display()))?;
+    println!();
+    println!("Wrote {}. Load it from .vibecheck with:", output.display());
+    println!("  [weights]");
+    println!("  file = \"{}\"", output.display());
+    if tuning.tuned_accuracy < tuning.baseline_accuracy {
+        eprintln!(
+            "warning: tuned weights generalize worse than the current ones on this corpus; \
+             try a larger corpus or a stronger --l2"
+        );
+    }
+    Ok(())
+}
+
+pub fn run_logistic(corpus: &Path, folds: usize, l2: f64, output: &Path) -> Result<()> {
+    let samples = load(corpus, folds)?;
+    let training = tuning::train_logistic(&samples, folds, l2);
+    println!(
+        "Trained a logistic model on {} features from {} files ({}-fold cross-validation)",
+        training.model.coefficients.len(),
+        training.files,
+        training.folds
+    );
+    println!("  accuracy of the weighted sum: {:.1}%", training.baseline_accuracy * 100.0);
+    println!("  accuracy of the model:        {:.1}%", training.accuracy * 100.0);
+
+    std::fs::write(output, training.to_toml()?).with_context(|| format!("failed to write {}", output.display()))?;
+    println!();
+    println!("Wrote {}. Load it from .vibecheck with:", output.display());
+    println!("  [classifier]");
+    println!("  backend = \"logistic\"");
+    println!("  model = \"{}\"", output.display());
+    if training.accuracy < training.baseline_accuracy {
+        eprintln!(
+            "warning: the model generalizes worse than the weighted sum on this corpus; \
+             try a larger corpus or a stronger --l2"
+        );
+    }
+    Ok(())
+}
+
+pub fn run_onnx(corpus: &Path, folds: usize, model: &Path, output: &Path) -> Result<()> {
+    let embedder = embedding::load_embedder(model)?;
+    let samples = load(corpus, folds)?;
+    let mut embeddings = Vec::with_capacity(samples.len());
+    for sample in &samples {
+        let path = corpus.join(&sample.path);
+        let source = std::fs::read_to_string(&path).with_context(|| format!("failed to read {}", path.display()))?;
+        let code = embedding::code_only(&source, detect_language(&path));
+        embeddings.push(embedder.embed(&code).with_context(|| format!("failed to embed {}", path.display()))?);
+    }
+
+    let training = tuning::train_prototypes(&samples, &embeddings, folds, embedding::DEFAULT_BLEND);
+    println!(
+        "Fitted {} family prototypes on {} files ({}-fold cross-validation)",
+        training.prototypes.families.len(),
+        training.files,
+        training.folds
+    );
+    println!("  accuracy of the weighted sum:  {:.1}%", training.baseline_accuracy * 100.0);
+    println!("  accuracy blended with {}: {:.1}%", model.display(), training.accuracy * 100.0);
+
+    std::fs::write(output, training.prototypes.to_toml()?)
+        .with_context(|| format!("failed to write {}", output.display()))?;
+    println!();
+    println!("Wrote {}. Load the model from .vibecheck with:", output.display());
+    println!("  [classifier]");
+    println!("  backend = \"onnx\"");
+    println!("  model = \"{}\"", model.display());
+    if training.accuracy < training.baseline_accuracy {
+        eprintln!("warning: blending in the embeddings generalizes worse than the weighted sum on this corpus");
+    }
+    Ok(())
+}
+
+pub fn run_stylometry(corpus: &Path, folds: usize, output: &Path) -> Result<()> {
+    let samples = load(corpus, folds)?;
+    let mut sources = Vec::with_capacity(samples.len());
+    for sample in &samples {
+        let path = corpus.join(&sample.path);
+        sources.push(std::fs::read_to_string(&path).with_context(|| format!("failed to read {}", path.display()))?);
+    }
+
+    let training = tuning::train_stylometry(&samples, &sources, folds);
+    let ngrams: usize = training.model.families.values().map(|counts| counts.len()).sum();
+    println!(
+        "Counted {ngrams} comment n-grams over {} families from {} files ({}-fold cross-validation)",
+        training.model.families.len(),
+        training.files,
+        training.folds
+    );
+    println!("  accuracy of the weighted sum:      {:.1}%", training.baseline_accuracy * 100.0);
+    println!("  accuracy with the n-gram signal:   {:.1}%", training.accuracy * 100.0);
+    println!("  held-out files the model fired on: {}", training.fired);
+
+    std::fs::write(output, training.model.to_toml()?).with_context(|| format!("failed to write {}", output.display()))?;
+    println!();
+    println!("Wrote {}. Load it from .vibecheck with:", output.display());
+    println!("  [stylometry]");
+    println!("  model = \"{}\"", output.display());
+    if training.accuracy < training.baseline_accuracy {
+        eprintln!("warning: the n-gram signal generalizes worse than the weighted sum alone on this corpus");
+    }
+    Ok(())
+}
+
+pub fn run_calibration(corpus: &Path, folds: usize, output: &Path) -> Result<()> {
+    let samples = load(corpus, folds)?;
+    let training = tuning::train_calibration(&samples, folds);
+    println!(
+        "Fitted calibrations for {} of {} languages on {} files ({}-fold cross-validation)",
+        training.calibration.languages.len(),
+        training.languages.len(),
+        training.files,
+        training.folds
+    );
+    println!("  {:<12} {:>6} {:>10} {:>14}", "language", "files", "one model", "per language");
+    for (language, brier) in &training.languages {
+        let own = if training.calibration.languages.contains_key(language) { "" } else { " (fallback)" };
+        println!("  {language:<12} {:>6} {:>10.4} {:>14.4}{own}", brier.files, brier.baseline, brier.calibrated);
+    }
+    println!("  {:<12} {:>6} {:>10.4} {:>14.4}", "all", training.files, training.baseline_brier, training.brier);
+    println!("  (Brier score on held-out files; lower is better)");
+
+    std::fs::write(output, training.to_toml()?).with_context(|| format!("failed to write {}", output.display()))?;
+    println!();
+    println!("Wrote {}. Load it from .vibecheck with:", output.display());
+    println!("  [calibration]");
+    println!("  file = \"{}\"", output.display());
+    if training.brier > training.baseline_brier {
+        eprintln!("warning: per-language calibration generalizes worse than a single calibration on this corpus");
+    }
+    Ok(())
+}
+
+/// The labelled samples under `corpus`, checked to be enough for `folds`.
+fn load(corpus: &Path, folds: usize) -> Result<Vec<vibecheck_core::eval::Sample>> {
+    if folds < 2 {
+        bail!("--folds must be at least 2");
+    }
+    let (samples, _) = super::eval::load_samples(corpus)?;
+    if samples.len() < folds {
+        bail!("{} labelled files is too few for {folds}-fold cross-validation", samples.len());
+    }
+    Ok(samples)
+}
@@ -1,4 +1,4 @@
-use std::collections::{HashMap, HashSet};
+use std::collections::{BTreeMap, HashMap, HashSet};
 use std::path::{Path, PathBuf};
 use std::sync::mpsc;
 use std::time::{Duration, Instant};
@@ -6,22 +6,30 @@ use std::time::{Duration, Instant};
 use anyhow::Result;
 use notify::{Config, RecommendedWatcher, RecursiveMode, Watcher};
 
-use vibecheck_core::ignore_rules::{IgnoreConfig, IgnoreRules};
+use vibecheck_core::calibration::Calibration;
+use vibecheck_core::ignore_rules::IgnoreRules;
+use vibecheck_core::language::{is_doc_file, SUPPORTED_EXTENSIONS};
+use vibecheck_core::language_pack;
 use vibecheck_core::output::OutputFormat;
+use vibecheck_core::report::{ModelFamily, Report};
+use vibecheck_core::FileOptions;
 
 use crate::commands::analyze::format_report;
+use crate::policy;
 
 const DEBOUNCE: Duration = Duration::from_millis(300);
 /// Minimum gap between two analyses of the same file. Prevents re-analysis
 /// from late-arriving OS events (kernel batching, atomic-rename sequences).
 const COOLDOWN: Duration = Duration::from_secs(2);
-const SUPPORTED_EXTS: &[&str] = &["rs", "py", "js", "ts", "jsx", "tsx", "go"];
 
-pub fn run(path: &Path, no_cache: bool, ignore_file: Option<&PathBuf>) -> Result<()> {
-    let ignore: Box<dyn IgnoreRules> = match ignore_file {
-        Some(f) => Box::new(IgnoreConfig::from_file(f)?),
-        None => Box::new(IgnoreConfig::load(path)),
+pub fn run(path: &Path, no_cache: bool, ignore_file: Option<&PathBuf>, exclude: &[String]) -> Result<()> {
+    let path = strip_recursive_suffix(path);
+    let config = match ignore_file {
+        Some(f) => policy::from_file(f)?,
+        None => policy::load(path),
     };
+    let calibration = config.calibration();
+    let ignore: Box<dyn IgnoreRules> = Box::new(config.with_excludes(exclude));
 
     let (tx, rx) = mpsc::channel();
     let mut watcher = RecommendedWatcher::new(tx, Config::default())?;
@@ -35,6 +43,7 @@ pub fn run(path: &Path, no_cache: bool, ignore_file: Option<&PathBuf>) -> Result
     let mut deadline: Option<Instant> = None;
     // Per-file cooldown: skip re-analysis if the file was analyzed < COOLDOWN ago.
     let mut last_analyzed: HashMap<PathBuf, Instant> = HashMap::new();
+    let mut session = Session::default();
 
     loop {
         // Block for up to DEBOUNCE, collecting events.
@@ -60,7 +69,9 @@ pub fn run(path: &Path, no_cache: bool, ignore_file: Option<&PathBuf>) -> Result
         let ready = deadline.map(|d| Instant::now() >= d).unwrap_or(false);
         if ready && !pending.is_empty() {
             let now = Instant::now();
-            let paths: Vec<PathBuf> = pending.drain().collect();
+            let mut paths: Vec<PathBuf> = pending.drain().collect();
+            paths.sort();
+            let mut analyzed_any = false;
             for p in &paths {
                 if last_analyzed
                     .get(p)
@@ -70,7 +81,13 @@ pub fn run(path: &Path, no_cache: bool, ignore_file: Option<&PathBuf>) -> Result
                     continue;
                 }
                 last_analyzed.insert(p.clone(), now);
-                analyze_and_print(p, no_cache);
+                if let Some(report) = analyze_and_print(p, no_cache, &calibration) {
+                    session.record(p, &report);
+                    analyzed_any = true;
+                }
+            }
+            if analyzed_any {
+                println!("{}\n", session.render());
             }
             // Drain events that accumulated during analysis. Keep any for
             // *different* files (user saved a second file while the first was
@@ -94,32 +111,100 @@ pub fn run(path: &Path, no_cache: bool, ignore_file: Option<&PathBuf>) -> Result
     Ok(())
 }
 
+/// Accept Go-style `dir/...` patterns: watching is always recursive, so the
+/// trailing `...` component is dropped.
+fn strip_recursive_suffix(path: &Path) -> &Path {
+    if path.file_name().map(|n| n == "...").unwrap_or(false) {
+        match path.parent() {
+            Some(p) if !p.as_os_str().is_empty() => p,
+            _ => Path::new("."),
+        }
+    } else {
+        path
+    }
+}
+
 fn is_supported(path: &Path) -> bool {
     path.extension()
         .and_then(|e| e.to_str())
-        .map(|e| SUPPORTED_EXTS.contains(&e))
+        .map(|e| SUPPORTED_EXTENSIONS.contains(&e) || language_pack::is_pack_extension(e))
         .unwrap_or(false)
+        || is_doc_file(path)
 }
 
-fn analyze_and_print(path: &Path, no_cache: bool) {
+fn analyze_and_print(path: &Path, no_cache: bool, calibration: &Calibration) -> Option<Report> {
     let now = chrono_now();
-    let analyze: fn(&Path) -> std::io::Result<vibecheck_core::report::Report> = if no_cache {
-        vibecheck_core::analyze_file_no_cache
-    } else {
-        vibecheck_core::analyze_file
-    };
-
-    match analyze(path) {
+    let options = FileOptions { no_cache, ..policy::file_options() };
+    match vibecheck_core::analyze_file_with(path, &options) {
         Ok(report) => {
             println!("[{now}] {}", path.display());
-            print!("{}", format_report(&report, OutputFormat::Pretty));
+            print!("{}", format_report(&report, OutputFormat::Pretty, false, calibration));
+            Some(report)
         }
         Err(e) => {
             eprintln!("[{now}] {} — error: {e}", path.display());
+            None
         }
     }
 }
 
+// ---------------------------------------------------------------------------
+// Session summary
+// ---------------------------------------------------------------------------
+
+/// Latest attribution of every file analyzed since the watch started,
+/// re-rendered as a one-line tally after each batch of saves.
+#[derive(Default)]
+struct Session {
+    latest: BTreeMap<PathBuf, (ModelFamily, f64)>,
+}
+
+impl Session {
+    /// Record `report` as the current attribution for `path`.  Files without
+    /// enough signal to attribute are dropped from the tally.
+    fn record(&mut self, path: &Path, report: &Report) {
+        if report.attribution.has_sufficient_data() {
+            self.latest.insert(
+                path.to_path_buf(),
+                (report.attribution.primary, report.attribution.confidence),
+            );
+        } else {
+            self.latest.remove(path);
+        }
+    }
+
+    fn render(&self) -> String {
+        let mut counts: Vec<(ModelFamily, usize)> = ModelFamily::all()
+            .iter()
+            .map(|&f| (f, self.latest.values().filter(|(p, _)| *p == f).count()))
+            .filter(|&(_, n)| n > 0)
+            .collect();
+        counts.sort_by(|a, b| b.1.cmp(&a.1));
+
+        let n = self.latest.len();
+        let mut out = format!("Session: {n} file{} —", if n == 1 { "" } else { "s" });
+        if counts.is_empty() {
+            out.push_str(" none attributed yet");
+        }
+        for (family, count) in &counts {
+            out.push_str(&format!(" {family} {count}"));
+        }
+        let top_ai = self
+            .latest
+            .iter()
+            .filter(|(_, (f, _))| *f != ModelFamily::Human)
+            .max_by(|a, b| a.1 .1.partial_cmp(&b.1 .1).unwrap().then_with(|| b.0.cmp(a.0)));
+        if let Some((path, (family, confidence))) = top_ai {
+            out.push_str(&format!(
+                " | most AI-like: {} ({family} {:.0}%)",
+                path.display(),
+                confidence * 100.0
+            ));
+        }
+        out
+    }
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -128,21 +213,65 @@ mod tests {
     fn is_supported_known_extensions() {
         assert!(is_supported(Path::new("main.rs")));
         assert!(is_supported(Path::new("script.py")));
+        assert!(is_supported(Path::new("analysis.ipynb")));
         assert!(is_supported(Path::new("app.js")));
         assert!(is_supported(Path::new("types.ts")));
         assert!(is_supported(Path::new("component.jsx")));
         assert!(is_supported(Path::new("component.tsx")));
         assert!(is_supported(Path::new("server.go")));
+        assert!(is_supported(Path::new("README.md")));
     }
 
     #[test]
     fn is_supported_rejects_unsupported() {
-        assert!(!is_supported(Path::new("README.md")));
+        assert!(!is_supported(Path::new("notes.txt")));
         assert!(!is_supported(Path::new("Cargo.toml")));
         assert!(!is_supported(Path::new("image.png")));
         assert!(!is_supported(Path::new("noext")));
     }
 
+    #[test]
+    fn strip_recursive_suffix_handles_go_style_patterns() {
+        assert_eq!(strip_recursive_suffix(Path::new("./...")), Path::new("."));
+        assert_eq!(strip_recursive_suffix(Path::new("...")), Path::new("."));
+        assert_eq!(strip_recursive_suffix(Path::new("src/...")), Path::new("src"));
+        assert_eq!(strip_recursive_suffix(Path::new("src")), Path::new("src"));
+    }
+
+    fn report(family: ModelFamily, confidence: f64) -> Report {
+        let mut report = vibecheck_core::analyze("");
+        report.attribution.primary = family;
+        report.attribution.confidence = confidence;
+        report.attribution.scores = BTreeMap::from([(family, confidence)]);
+        report
+    }
+
+    #[test]
+    fn session_tracks_latest_attribution_per_file() {
+        let mut session = Session::default();
+        session.record(Path::new("a.rs"), &report(ModelFamily::Claude, 0.9));
+        session.record(Path::new("b.rs"), &report(ModelFamily::Human, 0.8));
+        session.record(Path::new("c.rs"), &report(ModelFamily::Gpt, 0.6));
+        assert_eq!(
+            session.render(),
+            "Session: 3 files — Claude 1 GPT 1 Human 1 | most AI-like: a.rs (Claude 90%)"
+        );
+
+        session.record(Path::new("a.rs"), &report(ModelFamily::Human, 0.7));
+        assert_eq!(
+            session.render(),
+            "Session: 3 files — Human 2 GPT 1 | most AI-like: c.rs (GPT 60%)"
+        );
+    }
+
+    #[test]
+    fn session_drops_files_without_attribution() {
+        let mut session = Session::default();
+        session.record(Path::new("a.rs"), &report(ModelFamily::Claude, 0.9));
+        session.record(Path::new("a.rs"), &report(ModelFamily::Human, 0.0));
+        assert_eq!(session.render(), "Session: 0 files — none attributed yet");
+    }
+
     #[test]
     fn chrono_now_is_valid_time_format() {
         let now = chrono_now();
@@ -152,10 +281,9 @@ mod tests {
     }
 
     #[test]
-    fn supported_exts_includes_all_expected() {
-        assert_eq!(SUPPORTED_EXTS.len(), 7);
-        assert!(SUPPORTED_EXTS.contains(&"rs"));
-        assert!(SUPPORTED_EXTS.contains(&"tsx"));
+    fn is_supported_typescript_module_variants() {
+        assert!(is_supported(Path::new("index.mts")));
+        assert!(is_supported(Path::new("config.cjs")));
     }
 }
 
@@ -0,0 +1,9 @@
+//! Transport for `vibecheck grpc`: the `vibecheck.v1` messages and the
+//! `Analysis` service of `proto/vibecheck/v1/analysis.proto`, generated by
+//! `tonic-build` in `build.rs`.
+
+// The generated code includes enum name lookups and a client that only the
+// tests use.
+#![allow(dead_code)]
+
+tonic::include_proto!("vibecheck.v1");
@@ -0,0 +1,222 @@
+use std::collections::HashMap;
+use std::io::{BufRead, BufReader, Write};
+use std::net::{TcpListener, TcpStream};
+use std::sync::Arc;
+use std::time::Duration;
+
+use anyhow::{Context, Result};
+use serde_json::{json, Value};
+
+/// Largest request body accepted; bigger bodies are rejected with 413.
+pub const MAX_BODY: usize = 8 * 1024 * 1024;
+
+/// How long a client may take to send its request before the connection
+/// is dropped, so stalled clients don't pin a thread forever.
+const READ_TIMEOUT: Duration = Duration::from_secs(30);
+
+/// One request, read whole.
+pub struct Request {
+    pub method: String,
+    /// Path without the query string.
+    pub path: String,
+    /// Header names are lowercased.
+    pub headers: HashMap<String, String>,
+    pub body: Vec<u8>,
+}
+
+impl Request {
+    pub fn header(&self, name: &str) -> Option<&str> {
+        self.headers.get(name).map(String::as_str)
+    }
+}
+
+/// A response, JSON unless built with [`Response::text`].
+pub struct Response {
+    pub status: u16,
+    pub content_type: &'static str,
+    pub body: String,
+}
+
+impl Response {
+    pub fn json(status: u16, body: &Value) -> Self {
+        Self::raw_json(status, body.to_string())
+    }
+
+    /// A body that is already serialized JSON.
+    pub fn raw_json(status: u16, body: String) -> Self {
+        Self { status, content_type: "application/json", body }
+    }
+
+    /// A plain-text body, e.g. Prometheus metrics.
+    pub fn text(status: u16, body: String) -> Self {
+        Self { status, content_type: "text/plain; version=0.0.4; charset=utf-8", body }
+    }
+
+    pub fn error(status: u16, message: impl std::fmt::Display) -> Self {
+        Self::json(status, &json!({ "error": message.to_string() }))
+    }
+}
+
+pub type Handler = dyn Fn(&Request) -> Response + Send + Sync;
+
+/// Serve `handler` on `listen` until the process is killed.
+///
+/// A deliberately small HTTP/1.1 server for the JSON endpoints of `serve`
+/// and `bot`: one request per connection, each connection on its own
+/// thread, bodies only with a `Content-Length`.
+pub fn serve(listen: &str, handler: Arc<Handler>) -> Result<()> {
+    let addr = listen_addr(listen);
+    let listener = TcpListener::bind(&addr).with_context(|| format!("cannot listen on {addr}"))?;
+    eprintln!("Listening on http://{}", listener.local_addr()?);
+
+    for stream in listener.incoming() {
+        let stream = match stream {
+            Ok(s) => s,
+            Err(e) => {
+                eprintln!("accept failed: {e}");
+                continue;
+            }
+        };
+        let handler = Arc::clone(&handler);
+        std::thread::spawn(move || {
+            if let Err(e) = serve_connection(stream, handler.as_ref()) {
+                eprintln!("connection error: {e:#}");
+            }
+        });
+    }
+    Ok(())
+}
+
+/// `:8080` listens on every interface, as in Go; anything else is used as is.
+fn listen_addr(listen: &str) -> String {
+    match listen.strip_prefix(':') {
+        Some(port) => format!("0.0.0.0:{port}"),
+        None => listen.to_string(),
+    }
+}
+
+fn serve_connection(stream: TcpStream, handler: &Handler) -> Result<()> {
+    stream.set_read_timeout(Some(READ_TIMEOUT))?;
+    let mut reader = BufReader::new(&stream);
+    let response = match read_request(&mut reader)? {
+        Ok(request) => handler(&request),
+        Err(response) => response,
+    };
+    write_response(&stream, &response)?;
+    Ok(())
+}
+
+/// Read the request line, headers and body.  The inner `Err` is a response
+/// for a request that can't be served (malformed, too large); the outer one
+/// is an I/O failure.
+fn read_request(reader: &mut impl BufRead) -> Result<std::result::Result<Request, Response>> {
+    let mut line = String::new();
+    reader.read_line(&mut line).context("failed to read request line")?;
+    let mut parts = line.split_whitespace();
+    let (Some(method), Some(target)) = (parts.next(), parts.next()) else {
+        return Ok(Err(Response::error(400, "malformed request line")));
+    };
+    let method = method.to_string();
+    let path = target.split('?').next().unwrap_or(target).to_string();
+
+    let mut headers = HashMap::new();
+    loop {
+        line.clear();
+        if reader.read_line(&mut line).context("failed to read headers")? == 0 {
+            break;
+        }
+        let header = line.trim_end();
+        if header.is_empty() {
+            break;
+        }
+        if let Some((name, value)) = header.split_once(':') {
+            headers.insert(name.trim().to_ascii_lowercase(), value.trim().to_string());
+        }
+    }
+
+    if headers.contains_key("transfer-encoding") {
+        return Ok(Err(Response::error(411, "chunked bodies are not supported; send Content-Length")));
+    }
+    let length = match headers.get("content-length").map(|v| v.parse::<usize>()) {
+        None => 0,
+        Some(Ok(n)) if n <= MAX_BODY => n,
+        Some(Ok(n)) => return Ok(Err(Response::error(413, format!("body of {n} bytes is over the {MAX_BODY} byte limit")))),
+        Some(Err(_)) => return Ok(Err(Response::error(400, "invalid Content-Length"))),
+    };
+    let mut body = vec![0; length];
+    reader.read_exact(&mut body).context("failed to read body")?;
+    Ok(Ok(Request { method, path, headers, body }))
+}
+
+fn write_response(mut stream: &TcpStream, response: &Response) -> std::io::Result<()> {
+    let reason = match response.status {
+        200 => "OK",
+        202 => "Accepted",
+        400 => "Bad Request",
+        401 => "Unauthorized",
+        404 => "Not Found",
+        405 => "Method Not Allowed",
+        411 => "Length Required",
+        413 => "Payload Too Large",
+        _ => "Internal Server Error",
+    };
+    write!(
+        stream,
+        "HTTP/1.1 {} {reason}\r\nContent-Type: {}\r\nContent-Length: {}\r\nConnection: close\r\n\r\n{}",
+        response.status,
+        response.content_type,
+        response.body.len(),
+        response.body
+    )?;
+    stream.flush()
+}
+
+#[cfg(test)]
+mod tests {
+    use std::io::Read;
+
+    use super::*;
+
+    #[test]
+    fn listen_addr_expands_bare_port() {
+        assert_eq!(listen_addr(":8080"), "0.0.0.0:8080");
+        assert_eq!(listen_addr("127.0.0.1:9000"), "127.0.0.1:9000");
+    }
+
+    #[test]
+    fn reads_request_line_headers_and_body() {
+        let raw = "POST /analyze?x=1 HTTP/1.1\r\nX-GitHub-Event: ping\r\nContent-Length: 2\r\n\r\n{}";
+        let Ok(Ok(request)) = read_request(&mut raw.as_bytes()) else { panic!("expected a request") };
+        assert_eq!((request.method.as_str(), request.path.as_str()), ("POST", "/analyze"));
+        assert_eq!(request.header("x-github-event"), Some("ping"));
+        assert_eq!(request.body, b"{}");
+    }
+
+    #[test]
+    fn oversized_and_chunked_bodies_are_refused() {
+        let big = format!("POST /analyze HTTP/1.1\r\nContent-Length: {}\r\n\r\n", MAX_BODY + 1);
+        let Ok(Err(response)) = read_request(&mut big.as_bytes()) else { panic!("expected a refusal") };
+        assert_eq!(response.status, 413);
+        let chunked = "POST /analyze HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n";
+        let Ok(Err(response)) = read_request(&mut chunked.as_bytes()) else { panic!("expected a refusal") };
+        assert_eq!(response.status, 411);
+    }
+
+    #[test]
+    fn serves_a_request_over_tcp() {
+        let listener = TcpListener::bind("127.0.0.1:0").unwrap();
+        let addr = listener.local_addr().unwrap();
+        let handle = std::thread::spawn(move || {
+            let (stream, _) = listener.accept().unwrap();
+            let echo = |r: &Request| Response::json(200, &json!({ "len": r.body.len() }));
+            serve_connection(stream, &echo).unwrap();
+        });
+        let mut client = TcpStream::connect(addr).unwrap();
+        write!(client, "POST /x HTTP/1.1\r\nHost: x\r\nContent-Length: 3\r\n\r\nabc").unwrap();
+        let mut response = String::new();
+        client.read_to_string(&mut response).unwrap();
+        handle.join().unwrap();
+        assert!(response.starts_with("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\n"), "{response}");
+        assert!(response.ends_with(r#"{"len":3}"#), "{response}");
+    }
+}
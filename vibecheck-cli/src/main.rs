@@ -1,12 +1,31 @@
 #![deny(dead_code)]
 
-use std::path::PathBuf;
+use std::path::{Path, PathBuf};
+use std::process::ExitCode;
 
 use anyhow::Result;
 use clap::{Args, Parser, Subcommand};
 
+mod alloc;
+mod artifact;
 mod commands;
+mod github;
+#[cfg(feature = "grpc")]
+mod grpc;
+mod http;
+mod metrics;
+mod notify;
 mod output;
+mod policy;
+mod pprof;
+mod progress;
+mod providers;
+mod reload;
+mod stats;
+mod summary;
+
+#[global_allocator]
+static ALLOCATOR: alloc::Counting = alloc::Counting;
 
 // ---------------------------------------------------------------------------
 // CLI definition
@@ -27,7 +46,10 @@ mod output;
                   vibecheck                           Open TUI in current directory\n  \
                   vibecheck src/main.rs               Analyze a single file\n  \
                   vibecheck src/ --format json         Analyze a directory as JSON\n  \
+                  vibecheck image.tar                 Analyze the sources in an image or archive\n  \
                   vibecheck src/ --assert-family human  CI gate: fail if AI-generated\n  \
+                  vibecheck src/ --fail-over 0.8      CI gate: fail on likely AI-generated files\n  \
+                  vibecheck src/ --profile strict     Grade and gate with the strict policy\n  \
                   vibecheck analyze --symbols src/lib.rs  Symbol-level attribution\n  \
                   vibecheck heuristics --format toml   Dump signal weights as TOML",
 )]
@@ -35,10 +57,18 @@ struct Cli {
     #[command(subcommand)]
     command: Option<Command>,
 
-    /// File or directory to analyze (shorthand for `vibecheck analyze <path>`).
+    /// Policy profile: severity thresholds, rule set and gate defaults for
+    /// strict (coursework, hiring), balanced (the defaults) or lenient (OSS
+    /// triage) review. Overrides `[policy] profile` in `.vibecheck`.
+    #[arg(long, global = true, value_name = "NAME", value_parser = vibecheck_core::policy::PROFILES.to_vec())]
+    profile: Option<String>,
+
+    /// File, directory or artifact to analyze (shorthand for `vibecheck analyze <path>`).
     path: Option<PathBuf>,
 
-    /// Output format: pretty (colored), text (plain), or json (machine-readable).
+    /// Output format: pretty (colored), text (plain), json (machine-readable), html
+    /// (self-contained report), markdown (PR comment), junit (CI test report),
+    /// provenance (SPDX document), or annotations (`file:line:col: rule: message`).
     #[arg(long, default_value = "pretty", requires = "path")]
     format: String,
 
@@ -51,13 +81,78 @@ struct Cli {
     #[arg(long, requires = "path")]
     no_cache: bool,
 
+    /// Use this directory for the cache instead of the configured one.
+    /// Restore it between CI runs so only changed files are re-analyzed.
+    #[arg(long, requires = "path", conflicts_with = "no_cache")]
+    cache_dir: Option<PathBuf>,
+
     /// Perform symbol-level analysis and show per-function attribution.
     #[arg(long, requires = "path")]
     symbols: bool,
 
+    /// Split files into regions of differing style and attribute each, to
+    /// spot AI-written functions inside human code (and vice versa).
+    #[arg(long, requires = "path")]
+    segments: bool,
+
+    /// Score each file again with its comments removed and report both
+    /// verdicts, to show how much of each rests on comments.
+    #[arg(long, requires = "path")]
+    strip_comments: bool,
+
+    /// Include a cleanup suggestion for each category of AI-attributed findings.
+    #[arg(long, requires = "path")]
+    remediation: bool,
+
+    /// Print per-phase timing, cache hit rate, CPU time and peak memory to stderr.
+    #[arg(long, requires = "path")]
+    stats: bool,
+
+    /// Print one row per directory (`dir`) or Go package (`package`) —
+    /// mean, median and max AI probability, file count and verdicts —
+    /// instead of one report per file.
+    #[arg(long, value_name = "KEY", value_parser = ["dir", "package"], requires = "path")]
+    group_by: Option<String>,
+
     /// Path to a `.vibecheck` config file (default: auto-discovered from project root).
     #[arg(long, requires = "path")]
     ignore_file: Option<PathBuf>,
+
+    /// Exclude paths matching this gitignore-style glob (repeatable; `!glob` re-includes).
+    #[arg(long, value_name = "GLOB", requires = "path")]
+    exclude: Vec<String>,
+
+    /// Also analyze files with a `Code generated ... DO NOT EDIT.` header.
+    #[arg(long, requires = "path")]
+    include_generated: bool,
+
+    /// AI probability at or above which `--format junit` reports a file as a failure.
+    #[arg(long, default_value_t = output::DEFAULT_JUNIT_THRESHOLD, requires = "path")]
+    threshold: f64,
+
+    /// Exit 1 if any file's AI probability is over this score (0–1).
+    #[arg(long, value_name = "SCORE", requires = "path")]
+    fail_over: Option<f64>,
+
+    /// Exit 1 if any of these signals fire. Comma-separated signal IDs,
+    /// dotted prefixes or severities, e.g. `--fail-on rust.comments,error`
+    #[arg(long, value_name = "RULE", value_delimiter = ',', requires = "path")]
+    fail_on: Vec<String>,
+
+    /// Give up on any single file after this many seconds and report it as
+    /// `skipped: timeout`, so one pathological file can't stall the scan.
+    #[arg(long, value_name = "SECS", requires = "path")]
+    timeout_per_file: Option<f64>,
+
+    /// Skip files larger than this without reading them (e.g. `512K`, `2M`;
+    /// `0` for no limit) and report them as `skipped: too_large`.
+    #[arg(long, value_name = "SIZE", default_value = "1M", requires = "path")]
+    max_file_size: String,
+
+    /// Memory budget for analysis (e.g. `512M`); lowers the file size limit
+    /// to what fits, estimating the parser's overhead per source byte.
+    #[arg(long, value_name = "SIZE", requires = "path")]
+    max_memory_hint: Option<String>,
 }
 
 #[derive(Subcommand)]
@@ -67,12 +162,48 @@ enum Command {
         long_about = "Analyze source files for AI-generated code patterns and attribute each \
                       file to a model family. Supports Rust, Python, JavaScript, and Go.\n\n\
                       By default, results are cached by file content hash (SHA-256). Use \
-                      --no-cache to force re-analysis. Use --symbols for per-function attribution.",
+                      --no-cache to force re-analysis, or --cache-dir to keep the cache in a \
+                      directory CI can save and restore between runs. Use --symbols for \
+                      per-function attribution, and --remediation for a cleanup suggestion per \
+                      finding category.\n\n\
+                      Files whose leading comments carry the standard `Code generated ... DO NOT \
+                      EDIT.` header (protoc, stringer, mockgen) are skipped when scanning a \
+                      directory; pass --include-generated to analyze them.\n\n\
+                      For CI gating, --assert-family, --fail-over and --fail-on make the run exit \
+                      1 when tripped. Any error, including an analysis that could not run, exits \
+                      2, and a clean run exits 0.\n\n\
+                      For git hooks, --files takes the staged files in place of a path and \
+                      quietly skips the ones vibecheck does not analyze; --quiet prints nothing \
+                      but gate failures. See `.pre-commit-hooks.yaml` for the pre-commit hook.\n\n\
+                      --timeout-per-file bounds the time spent on any one file; files that hit it \
+                      are reported as `skipped: timeout` rather than dropped. Likewise files over \
+                      --max-file-size (default 1M; lowered by --max-memory-hint) are reported as \
+                      `skipped: too_large` without being read.\n\n\
+                      The path may also be a build artifact: a container image saved with \
+                      `docker save` or as an OCI archive, a tarball such as a Python sdist, or a \
+                      zip such as a Go module zip or wheel. Its source files are extracted to a \
+                      temporary directory and reported as `<artifact>!/<path>`.",
         after_help = "EXAMPLES:\n  \
                       vibecheck analyze src/main.rs\n  \
                       vibecheck analyze src/ --format json\n  \
                       vibecheck analyze src/ --assert-family human --no-cache\n  \
-                      vibecheck analyze --symbols src/lib.rs",
+                      vibecheck analyze src/ --fail-over 0.9 --fail-on rust.comments\n  \
+                      vibecheck analyze src/ --format json --cache-dir .vibecheck-cache\n  \
+                      vibecheck analyze --symbols src/lib.rs\n  \
+                      vibecheck analyze src/ --strip-comments\n  \
+                      vibecheck analyze src/ --remediation\n  \
+                      vibecheck analyze . --group-by package\n  \
+                      vibecheck analyze src/ --format html > report.html\n  \
+                      vibecheck analyze src/ --format markdown > comment.md\n  \
+                      vibecheck analyze src/ --format junit --threshold 0.8 > vibecheck.xml\n  \
+                      vibecheck analyze src/ --format annotations > vibecheck.err\n  \
+                      vibecheck analyze src/ --format provenance > provenance.spdx.json\n  \
+                      vibecheck analyze . --exclude 'gen/' --exclude '*.pb.go'\n  \
+                      vibecheck analyze app-image.tar --format json\n  \
+                      vibecheck analyze src/ --no-cache --stats\n  \
+                      vibecheck analyze . --timeout-per-file 10\n  \
+                      vibecheck analyze . --max-file-size 256K --max-memory-hint 512M\n  \
+                      vibecheck analyze --quiet --fail-over 0.9 --files src/a.rs src/b.py",
     )]
     Analyze(AnalyzeArgs),
 
@@ -97,22 +228,301 @@ enum Command {
     #[command(
         long_about = "Monitor a file or directory for changes using OS file-system events \
                       (inotify/kqueue/FSEvents). On each save, re-analyze the changed file \
-                      and print the updated attribution to stdout. Uses a 300ms debounce \
-                      and 2s per-file cooldown.",
+                      and print the updated attribution to stdout, followed by a one-line \
+                      session summary of every file analyzed so far. Uses a 300ms debounce \
+                      and 2s per-file cooldown. Go-style `dir/...` paths are accepted.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck watch src/\n  \
+                      vibecheck watch ./...",
     )]
     Watch(WatchArgs),
 
+    /// Check a single snippet, e.g. piped from an editor selection.
+    #[command(
+        long_about = "Read one snippet from stdin (`-`) or a file and print its verdict and \
+                      the evidence behind it. A snippet has no file name to detect its \
+                      language from, so pass --lang to enable that language's analyzers; \
+                      without it only the language-independent ones run. The `.vibecheck` \
+                      config of the current directory applies.",
+        after_help = "EXAMPLES:\n  \
+                      pbpaste | vibecheck check - --lang=go\n  \
+                      vibecheck check - --lang python --format json < snippet.txt\n  \
+                      vibecheck check notes/draft.txt --lang rust",
+    )]
+    Check(CheckArgs),
+
+    /// Compare two implementations: which is more likely AI-generated, and why.
+    #[command(
+        long_about = "Analyze two files and report which is more likely AI-generated, by \
+                      calibrated AI probability, followed by a detector-by-detector delta: the \
+                      net weight toward AI each detector contributed in each file. Detectors \
+                      are matched without their language prefix, so implementations in \
+                      different languages can be compared. Useful for reviewing take-home \
+                      submissions against a reference, or when curating a corpus. The \
+                      `.vibecheck` config of the current directory applies.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck compare a.go b.go\n  \
+                      vibecheck compare submission.py reference.py --format json",
+    )]
+    Compare(CompareArgs),
+
+    /// Summarize a tree in one snapshot, or score the lines a diff adds.
+    #[command(
+        long_about = "Scan a directory and print one repository-level snapshot: files and \
+                      lines attributed to AI and the mean AI probability. With --save the \
+                      snapshot, the scanned path and the HEAD commit are appended to \
+                      `.vibecheck-history` at the project root, so `vibecheck trend` can show \
+                      how the AI share moved between scans. With --notify the snapshot's \
+                      summary is also posted to the `[[notify]]` sinks in `.vibecheck` whose \
+                      thresholds it crosses.\n\n\
+                      With --sample, only a reproducible random subset of the files is \
+                      analyzed (a count, or a percentage with `%`), and the snapshot adds an \
+                      estimate of the whole tree's AI share with a 95% confidence interval. \
+                      Files are drawn by hashing their paths with --seed, so a weekly run with \
+                      the same seed mostly rescans the same files.\n\n\
+                      With --patch, instead parse a unified diff (`git diff`, `diff -u`) and \
+                      score the lines it adds to each supported source file, together per file. \
+                      Removed and context lines are ignored, and line numbers in findings refer \
+                      to the new file, so review tooling that has the diff in hand needs no \
+                      checkout. Pass `-` to read the diff from stdin. Paths are matched against \
+                      the `.vibecheck` config of the current directory.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck scan . --save\n  \
+                      vibecheck scan . --save --notify\n  \
+                      vibecheck scan src/ --format json\n  \
+                      vibecheck scan . --sample 2000 --seed 7 --save\n  \
+                      vibecheck scan --patch changes.diff\n  \
+                      git diff main | vibecheck scan --patch - --format json\n  \
+                      vibecheck scan --patch pr.diff --format markdown --fail-over 0.9",
+    )]
+    Scan(ScanArgs),
+
+    /// Serve a JSON API for analysis over HTTP.
+    #[command(
+        long_about = "Run an HTTP server so tools written in other languages can call \
+                      vibecheck without spawning a process per request. Endpoints:\n\n  \
+                      POST /analyze  {\"source\": \"...\", \"language\": \"go\"} (optional \
+                      \"path\") -> the report, as `analyze --format json` prints it\n  \
+                      GET  /rules    every signal with its default and configured weight\n  \
+                      GET  /metrics  Prometheus counters: files analyzed and flagged, latency, \
+                      verdicts, findings per rule, cache lookups\n  \
+                      GET  /healthz  {\"status\": \"ok\"}\n\n\
+                      The `.vibecheck` config is reloaded when it or `.vibecheckignore` changes; \
+                      requests in flight finish under the config they started with, and a config \
+                      that fails to parse is reported and ignored. Requests must carry a \
+                      Content-Length; bodies over 8 MiB are refused.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck serve --listen :8080\n  \
+                      vibecheck serve --listen :8080 --cache-dir /var/cache/vibecheck\n  \
+                      vibecheck serve --listen 127.0.0.1:9000 --ignore-file ci/.vibecheck",
+    )]
+    Serve(ServeArgs),
+
+    /// Serve the `vibecheck.v1.Analysis` gRPC service.
+    #[cfg(feature = "grpc")]
+    #[command(
+        long_about = "Serve the `vibecheck.v1.Analysis` service of \
+                      `proto/vibecheck/v1/analysis.proto` over gRPC. Built only with \
+                      `--features grpc`.\n\n  \
+                      AnalyzeFile  score one source, as `POST /analyze` of `vibecheck serve` does\n  \
+                      AnalyzeRepo  stream a report per file of a directory under --root, in \
+                      path order, as it is analyzed\n\n\
+                      A call's deadline bounds its analysis: AnalyzeFile answers with a report \
+                      skipped for `timeout` rather than failing when the deadline would pass. \
+                      Cancelling AnalyzeRepo, or letting its deadline pass, stops the scan. \
+                      AnalyzeRepo roots are resolved against --root and may not leave it; each \
+                      is scanned under its own `.vibecheck`.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck grpc --listen :50051\n  \
+                      vibecheck grpc --listen 127.0.0.1:50051 --root /srv/checkouts --cache-dir /var/cache/vibecheck",
+    )]
+    Grpc(GrpcArgs),
+
+    /// Review pull requests as a GitHub App, via webhooks.
+    #[command(
+        long_about = "Listen for GitHub `pull_request` webhooks (POST /webhook) and answer each \
+                      opened or updated pull request with a `vibecheck` check run on its head \
+                      commit: a summary table and an annotation per AI-leaning finding. Changed \
+                      files are analyzed as of the head commit under that repository's own \
+                      `.vibecheck`, so one deployment serves every repository the app is \
+                      installed on.\n\n\
+                      Deliveries are verified against the webhook secret in \
+                      GITHUB_WEBHOOK_SECRET. The app needs read access to contents and pull \
+                      requests and write access to checks. Flagged files make the check \
+                      neutral; with --fail-over, files scoring over the limit fail it. \
+                      GET /metrics exports Prometheus counters, including pull requests \
+                      reviewed and flagged.\n\n\
+                      Reviews that cross a threshold are posted to the `[[notify]]` sinks of \
+                      the bot's own `.vibecheck` (--ignore-file, or the one in the current \
+                      directory), reloaded when it changes; sinks in the reviewed repositories \
+                      are ignored.",
+        after_help = "EXAMPLES:\n  \
+                      GITHUB_WEBHOOK_SECRET=... vibecheck bot --app-id 123456 --private-key app.pem\n  \
+                      vibecheck bot --listen :8080 --app-id 123456 --private-key app.pem --fail-over 0.9\n  \
+                      vibecheck bot --app-id 7 --private-key app.pem --api-url https://ghe.example.com/api/v3",
+    )]
+    Bot(BotArgs),
+
     /// Walk git history and show per-commit attribution over time.
     #[command(
         long_about = "Replay git history for a file and show how attribution changed over \
                       commits. Reads blobs directly from the git object store (no working-tree \
-                      checkout). Prints a table: COMMIT | DATE | FAMILY | CONFIDENCE | CHANGE.",
+                      checkout). Prints a table: COMMIT | DATE | FAMILY | CONFIDENCE | CHANGE. \
+                      With --since, instead scores the lines each commit after the given \
+                      revision added and reports how the AI-generated share of them trended, as \
+                      ASCII sparklines or a JSON time series; --group-by author rolls that up \
+                      per commit author.",
         after_help = "EXAMPLES:\n  \
                       vibecheck history src/pipeline.rs\n  \
-                      vibecheck history src/lib.rs --limit 5",
+                      vibecheck history src/lib.rs --limit 5\n  \
+                      vibecheck history --since v1.0.0\n  \
+                      vibecheck history src/ --since v1.0.0 --format json\n  \
+                      vibecheck history --since v1.0.0 --group-by author --anonymize",
     )]
     History(HistoryArgs),
 
+    /// Trace flagged lines to the commits that introduced them.
+    #[command(
+        long_about = "Analyze the files under a path, take the lines that AI-leaning signals \
+                      point at in files attributed to AI, and run `git blame` on them. Flagged \
+                      lines that one blame hunk covers form a region; regions are printed \
+                      grouped by the commit that introduced them, newest first, with its date, \
+                      author and summary. Files are blamed as they are on disk, so lines changed \
+                      since HEAD are listed as not committed yet.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck blame\n  \
+                      vibecheck blame src/handlers/\n  \
+                      vibecheck blame src/lib.rs --format json",
+    )]
+    Blame(BlameArgs),
+
+    /// Score commit messages for AI tells, separately from the code.
+    #[command(
+        long_about = "Score the message of every commit after a revision with the commit-message \
+                      detectors (the `commit.*` rules): a \"This commit:\" preamble, bulleted \
+                      narration of every change, a closing summary paragraph, emoji prefixes, \
+                      and on the human side terse or work-in-progress subjects. Prints one row \
+                      per commit, newest first, with the rules that fired, then a summary \
+                      section with the share of messages attributed to AI and the most frequent \
+                      rules. Merge commits are skipped. Weights come from the [heuristics] table \
+                      of the nearest .vibecheck, like every other rule.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck commits --since v1.0.0\n  \
+                      vibecheck commits --since main~50 --format json",
+    )]
+    Commits(CommitsArgs),
+
+    /// List the files and functions most likely to be AI-generated.
+    #[command(
+        long_about = "Analyze the files under a path, with per-function attribution, and list \
+                      the N files and functions with the highest calibrated AI probability, \
+                      highest first, each with the AI-leaning rules that weighed most in its \
+                      verdict. A prioritized worklist for review rather than every file in path \
+                      order. Entries without enough signal data for a verdict are not ranked.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck top\n  \
+                      vibecheck top src/ -n 50\n  \
+                      vibecheck top --files-only --format json",
+    )]
+    Top(TopArgs),
+
+    /// Review flagged files one by one and record confirmed or false-positive verdicts.
+    #[command(
+        long_about = "Open a terminal review of the files at or above --threshold AI probability, \
+                      highest first: the source with the lines behind the verdict highlighted, \
+                      beside the evidence. Mark each file confirmed (c) or a false positive (f); \
+                      decisions are appended to `.vibecheck-reviews` at the project root and \
+                      files already reviewed at their current content are skipped. The rules \
+                      behind false positives are down-weighted for later scans of the project \
+                      via `.vibecheck-feedback`. --export \
+                      writes the decisions as JSON; --export-corpus adds the reviewed files to a \
+                      labelled corpus (confirmed files under their attributed family, false \
+                      positives as human) for `eval` and `tune`.",
+        after_help = "KEYBINDINGS:\n  \
+                      c/y       Confirm: the file is AI-written\n  \
+                      f/x       False positive: the file is human-written\n  \
+                      →/l/n     Next file\n  \
+                      ←/h/p     Previous file\n  \
+                      j/↓ k/↑   Scroll source\n  \
+                      d/u       Scroll a page\n  \
+                      e         Jump to the next evidence line\n  \
+                      q/Esc     Quit\n\n\
+                      EXAMPLES:\n  \
+                      vibecheck review\n  \
+                      vibecheck review src/ --threshold 0.8\n  \
+                      vibecheck review --export reviews.json\n  \
+                      vibecheck review --export-corpus corpus/",
+    )]
+    Review(ReviewArgs),
+
+    /// Record a reviewer verdict on one file: `human` for a false positive, or the family that wrote it.
+    #[command(
+        long_about = "Record a verdict on FILE as `review` does, without the terminal UI. `human` \
+                      marks a false positive; a model family confirms the file as that family's. \
+                      The decision is appended to `.vibecheck-reviews` at the project root and \
+                      `.vibecheck-feedback` is regenerated: each AI-leaning rule named in a false \
+                      positive has its weight scaled down for every later scan of the project, \
+                      and confirmed verdicts win weight back. Delete `.vibecheck-feedback` to \
+                      score with the configured weights alone.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck mark src/handlers.rs human\n  \
+                      vibecheck mark src/generated.py claude",
+    )]
+    Mark(MarkArgs),
+
+    /// Scan many repositories at once into one report with a row per repository.
+    #[command(
+        long_about = "Scan every repository listed in a YAML or TOML file, local paths or remote URLs \
+                      (shallow-cloned into a temporary directory), several at a time, and print \
+                      one report: per repository the files scanned, the mean, median and highest \
+                      AI probability, the most common verdict and the most AI-like file, then \
+                      totals. Each repository's summary is saved under `.vibecheck-org` beside \
+                      the list as soon as it is scanned; rerunning after a failure scans only \
+                      what is missing, and --fresh starts over. Exits 1 after the report if any \
+                      repository failed.",
+        after_help = "REPOSITORY LIST (YAML if named .yaml or .yml, else TOML with the same keys):\n  \
+                      jobs: 8                              # optional, like --jobs\n  \
+                      repos:\n    \
+                      - ../billing                       # relative to the list\n    \
+                      - url: git@github.com:acme/web.git\n      \
+                      name: web                        # default: last path element\n      \
+                      ref: release                     # branch or tag to clone\n      \
+                      exclude: [\"vendor/**\"]\n\n\
+                      EXAMPLES:\n  \
+                      vibecheck org scan --config repos.yaml\n  \
+                      vibecheck org scan --config repos.yaml --jobs 8 --format json > org.json\n  \
+                      vibecheck org scan --config repos.toml --fresh",
+    )]
+    Org(OrgArgs),
+
+    /// Work with saved JSON reports.
+    #[command(
+        long_about = "Work with reports saved by `analyze --format json` or `scan --format json`. \
+                      `diff` compares an old scan with a new one: findings (AI-leaning rules, \
+                      matched by file and rule id so moved lines don't count) that appeared or \
+                      were resolved, then the files whose AI probability changed, largest change \
+                      first, and the files added or removed.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck report diff yesterday.json today.json\n  \
+                      vibecheck report diff base.json head.json --format json",
+    )]
+    Report(ReportArgs),
+
+    /// Show how saved scans evolved: the AI share per scan and commit.
+    #[command(
+        long_about = "Read the snapshots `vibecheck scan --save` appended to \
+                      `.vibecheck-history` at the project root and print them oldest first: \
+                      date, commit, scanned path, file counts, the share of lines in \
+                      AI-attributed files and the mean AI probability, under a sparkline of \
+                      that share. Unlike `history --since`, nothing is re-scored, so the \
+                      numbers are what each scan reported with the detectors of its day.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck trend\n  \
+                      vibecheck trend --scope src\n  \
+                      vibecheck trend --format json",
+    )]
+    Trend(TrendArgs),
+
     /// List all detection signals with their default weights.
     #[command(
         long_about = "Display the full catalogue of detection heuristics. Each signal has a \
@@ -123,14 +533,116 @@ enum Command {
                       vibecheck heuristics --format toml",
     )]
     Heuristics(HeuristicsArgs),
+
+    /// List every rule and whether the config enables it; turn rules on or off.
+    #[command(
+        long_about = "List every detection rule (signal) with its ID, the languages it applies \
+                      to, the family it points at, its default weight, its weight under the \
+                      current `.vibecheck` config and its description. A weight of 0 disables \
+                      a rule and shows as `off`.\n\n\
+                      --disable sets the weight of each matching rule to 0 under [heuristics] \
+                      in `.vibecheck`, creating the file or table if needed; --enable removes \
+                      that override again. Both take signal IDs or dotted prefixes, so \
+                      `--disable go.errors` turns off every Go error-handling rule.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck rules\n  \
+                      vibecheck rules --language go --format json\n  \
+                      vibecheck rules --disable rust.comments.teaching_voice,go.errors\n  \
+                      vibecheck rules --enable go.errors",
+    )]
+    Rules(RulesArgs),
+
+    /// Measure detection accuracy against a labelled corpus.
+    #[command(
+        long_about = "Run the full detector pipeline over a labelled corpus and report accuracy, \
+                      per-family precision, recall and F1, a confusion matrix, and each \
+                      detector's contribution. A file is labelled by its stem (claude.rs, \
+                      human.go) or, failing that, its parent directory (gpt/cache.py); \
+                      unlabelled files are skipped. Each detector's accuracy delta is measured \
+                      by re-scoring the corpus without its signals.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck eval --corpus vibecheck-core/tests/fixtures\n  \
+                      vibecheck eval --corpus ./corpus --format json",
+    )]
+    Eval(EvalArgs),
+
+    /// Time each detector over a corpus and report latency and allocations.
+    #[command(
+        long_about = "Run the full detector pipeline over every supported file in a corpus and \
+                      report, per detector stage, total and share of time, mean, p95 and max \
+                      latency, the slowest file, and heap allocations. Stages are the text \
+                      analyzers, tree-sitter parsing (parse), each CST analyzer, structural \
+                      metrics (shape), language packs, plugins, and scoring (classify). The \
+                      corpus need not be labelled; the nearest .vibecheck of each file applies, \
+                      so opt-in detectors are timed when configured. With --pprof, also write \
+                      a pprof profile with one sample per detector and file.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck bench --corpus vibecheck-core/tests/fixtures\n  \
+                      vibecheck bench --corpus src --iterations 5 --format json\n  \
+                      vibecheck bench --corpus . --pprof bench.pb.gz && go tool pprof -top bench.pb.gz",
+    )]
+    Bench(BenchArgs),
+
+    /// Fit signal weights or a logistic model to a labelled corpus.
+    #[command(
+        long_about = "Fit signal weights to a labelled corpus and write them as a [heuristics] \
+                      table. Each signal keeps the family it points at; only its weight is \
+                      learned, by softmax regression over the per-family weight sums, with an L2 \
+                      penalty (--l2) pulling it toward the current weight. k-fold \
+                      cross-validation reports accuracy before and after tuning. Load the \
+                      result with `[weights] file = \"...\"` in .vibecheck. With --classifier \
+                      logistic, train a logistic-regression model over the signals instead, \
+                      for `[classifier] backend = \"logistic\"`; with --classifier onnx, embed \
+                      each sample's comment-free code with the model in --model and write the \
+                      per-family prototypes beside it (needs the `onnx` build feature); with \
+                      --classifier calibration, fit per-language probability calibrations for \
+                      `[calibration] file = \"...\"`.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck tune --corpus vibecheck-core/tests/fixtures\n  \
+                      vibecheck tune --corpus ./corpus --folds 10 --l2 0.1 --output weights.toml\n  \
+                      vibecheck tune --corpus ./corpus --classifier logistic\n  \
+                      vibecheck tune --corpus ./corpus --classifier onnx --model models/unixcoder\n  \
+                      vibecheck tune --corpus ./corpus --classifier calibration",
+    )]
+    Tune(TuneArgs),
+
+    /// Add, list, relabel and deduplicate labelled corpus samples.
+    #[command(
+        long_about = "Manage a labelled corpus through its corpus.toml manifest, which records \
+                      each sample's label, source model, task, language, license and content \
+                      hash. `add` copies files into <task>/<label>.<ext> (or registers files \
+                      already inside the corpus in place) and refuses content the corpus \
+                      already has; `dedupe` drops repeated samples, ignoring line endings and \
+                      trailing whitespace. `vibecheck eval` reads labels from the manifest.",
+        after_help = "EXAMPLES:\n  \
+                      vibecheck corpus add cache.go --label gpt --model gpt-4o --task lru_cache --license MIT\n  \
+                      vibecheck corpus list --label human\n  \
+                      vibecheck corpus relabel lru_cache/gpt_2.go copilot\n  \
+                      vibecheck corpus dedupe --dry-run --corpus tests/fixtures\n  \
+                      vibecheck corpus generate --task \"lru cache\" --models claude,gpt,gemini",
+    )]
+    Corpus(CorpusArgs),
 }
 
 #[derive(Args)]
 struct AnalyzeArgs {
-    /// File or directory to analyze.
-    path: PathBuf,
+    /// File, directory, or artifact (image tarball, sdist, module zip) to analyze.
+    #[arg(required_unless_present = "files")]
+    path: Option<PathBuf>,
 
-    /// Output format: pretty (colored), text (plain), or json (machine-readable).
+    /// Analyze exactly these files, as passed by a hook such as pre-commit.
+    /// Unsupported, ignored, generated and missing files are skipped, and a
+    /// list with nothing left to analyze passes.
+    #[arg(long, value_name = "FILE", num_args = 1.., conflicts_with = "path")]
+    files: Vec<PathBuf>,
+
+    /// Print nothing but gate failures; the exit status tells the rest.
+    #[arg(short, long)]
+    quiet: bool,
+
+    /// Output format: pretty (colored), text (plain), json (machine-readable), html
+    /// (self-contained report), markdown (PR comment), junit (CI test report),
+    /// provenance (SPDX document), or annotations (`file:line:col: rule: message`).
     #[arg(long, default_value = "pretty")]
     format: String,
 
@@ -143,13 +655,78 @@ struct AnalyzeArgs {
     #[arg(long)]
     no_cache: bool,
 
+    /// Use this directory for the cache instead of the configured one.
+    /// Restore it between CI runs so only changed files are re-analyzed.
+    #[arg(long, conflicts_with = "no_cache")]
+    cache_dir: Option<PathBuf>,
+
     /// Perform symbol-level analysis (per-function/method attribution).
     #[arg(long)]
     symbols: bool,
 
+    /// Split files into regions of differing style and attribute each, to
+    /// spot AI-written functions inside human code (and vice versa).
+    #[arg(long)]
+    segments: bool,
+
+    /// Score each file again with its comments removed and report both
+    /// verdicts, to show how much of each rests on comments.
+    #[arg(long)]
+    strip_comments: bool,
+
+    /// Include a cleanup suggestion for each category of AI-attributed findings.
+    #[arg(long)]
+    remediation: bool,
+
+    /// Print per-phase timing, cache hit rate, CPU time and peak memory to stderr.
+    #[arg(long)]
+    stats: bool,
+
+    /// Print one row per directory (`dir`) or Go package (`package`) —
+    /// mean, median and max AI probability, file count and verdicts —
+    /// instead of one report per file.
+    #[arg(long, value_name = "KEY", value_parser = ["dir", "package"])]
+    group_by: Option<String>,
+
     /// Path to a `.vibecheck` config file (default: auto-discovered from project root).
     #[arg(long)]
     ignore_file: Option<PathBuf>,
+
+    /// Exclude paths matching this gitignore-style glob (repeatable; `!glob` re-includes).
+    #[arg(long, value_name = "GLOB")]
+    exclude: Vec<String>,
+
+    /// Also analyze files with a `Code generated ... DO NOT EDIT.` header.
+    #[arg(long)]
+    include_generated: bool,
+
+    /// AI probability at or above which `--format junit` reports a file as a failure.
+    #[arg(long, default_value_t = output::DEFAULT_JUNIT_THRESHOLD)]
+    threshold: f64,
+
+    /// Exit 1 if any file's AI probability is over this score (0–1).
+    #[arg(long, value_name = "SCORE")]
+    fail_over: Option<f64>,
+
+    /// Exit 1 if any of these signals fire. Comma-separated signal IDs,
+    /// dotted prefixes or severities, e.g. `--fail-on rust.comments,error`
+    #[arg(long, value_name = "RULE", value_delimiter = ',')]
+    fail_on: Vec<String>,
+
+    /// Give up on any single file after this many seconds and report it as
+    /// `skipped: timeout`, so one pathological file can't stall the scan.
+    #[arg(long, value_name = "SECS")]
+    timeout_per_file: Option<f64>,
+
+    /// Skip files larger than this without reading them (e.g. `512K`, `2M`;
+    /// `0` for no limit) and report them as `skipped: too_large`.
+    #[arg(long, value_name = "SIZE", default_value = "1M")]
+    max_file_size: String,
+
+    /// Memory budget for analysis (e.g. `512M`); lowers the file size limit
+    /// to what fits, estimating the parser's overhead per source byte.
+    #[arg(long, value_name = "SIZE")]
+    max_memory_hint: Option<String>,
 }
 
 #[derive(Args)]
@@ -174,16 +751,374 @@ struct WatchArgs {
     /// Path to a `.vibecheck` config file (default: auto-discovered from project root).
     #[arg(long)]
     ignore_file: Option<PathBuf>,
+
+    /// Ignore changes to paths matching this gitignore-style glob (repeatable).
+    #[arg(long, value_name = "GLOB")]
+    exclude: Vec<String>,
+}
+
+#[derive(Args)]
+struct CheckArgs {
+    /// Snippet to check, or `-` to read it from stdin.
+    input: PathBuf,
+
+    /// Language of the snippet: rust, python, javascript, typescript, go, or
+    /// a file extension (`tsx`, `py`, ...).
+    #[arg(long, value_name = "LANG")]
+    lang: Option<String>,
+
+    /// Output format: pretty (colored), text (plain), or json (machine-readable).
+    #[arg(long, default_value = "pretty")]
+    format: String,
+}
+
+#[derive(Args)]
+struct CompareArgs {
+    /// First file.
+    a: PathBuf,
+
+    /// Second file.
+    b: PathBuf,
+
+    /// Path to a `.vibecheck` config file (default: the current directory's).
+    #[arg(long)]
+    ignore_file: Option<PathBuf>,
+
+    /// Output format: pretty (colored), text (plain), or json (machine-readable).
+    #[arg(long, default_value = "pretty")]
+    format: String,
+}
+
+#[derive(Args)]
+struct ScanArgs {
+    /// Directory, file or archive to scan into one repository-level snapshot.
+    #[arg(conflicts_with = "patch", required_unless_present = "patch")]
+    path: Option<PathBuf>,
+
+    /// Unified diff to score, or `-` to read it from stdin.
+    #[arg(long, value_name = "FILE")]
+    patch: Option<PathBuf>,
+
+    /// Append the snapshot to `.vibecheck-history` at the project root, for
+    /// `vibecheck trend`.
+    #[arg(long, requires = "path")]
+    save: bool,
+
+    /// Post the snapshot's summary to the `[[notify]]` sinks in `.vibecheck`
+    /// whose thresholds it crosses.
+    #[arg(long, requires = "path")]
+    notify: bool,
+
+    /// Analyze only a reproducible random sample of the files, a count (`2000`)
+    /// or a percentage (`5%`), and estimate the tree's AI share from it.
+    #[arg(long, value_name = "N|P%", requires = "path")]
+    sample: Option<vibecheck_core::sampling::SampleSize>,
+
+    /// Seed for --sample; the same seed draws the same files.
+    #[arg(long, default_value_t = 0, requires = "sample")]
+    seed: u64,
+
+    /// Output format: pretty (colored), text (plain), json (machine-readable), html
+    /// (self-contained report), markdown (PR comment), junit (CI test report),
+    /// provenance (SPDX document), or annotations (`file:line:col: rule: message`).
+    #[arg(long, default_value = "pretty")]
+    format: String,
+
+    /// Path to a `.vibecheck` config file (default: auto-discovered from the current directory).
+    #[arg(long)]
+    ignore_file: Option<PathBuf>,
+
+    /// Skip changed paths matching this gitignore-style glob (repeatable; `!glob` re-includes).
+    #[arg(long, value_name = "GLOB")]
+    exclude: Vec<String>,
+
+    /// AI probability at or above which `--format junit` reports a file as a failure.
+    #[arg(long, default_value_t = output::DEFAULT_JUNIT_THRESHOLD)]
+    threshold: f64,
+
+    /// Exit 1 if any file's added lines score an AI probability over this (0–1).
+    #[arg(long, value_name = "SCORE")]
+    fail_over: Option<f64>,
+
+    /// Exit 1 if any of these signals fire in the added lines. Comma-separated
+    /// signal IDs, dotted prefixes or severities.
+    #[arg(long, value_name = "RULE", value_delimiter = ',')]
+    fail_on: Vec<String>,
+}
+
+#[derive(Args)]
+struct ServeArgs {
+    /// Address to listen on: `host:port`, or `:port` for every interface.
+    #[arg(long, value_name = "ADDR", default_value = "127.0.0.1:8080")]
+    listen: String,
+
+    /// Path to a `.vibecheck` config file (default: auto-discovered from the current directory).
+    #[arg(long)]
+    ignore_file: Option<PathBuf>,
+
+    /// Cache reports in this directory, so resubmitted sources are answered
+    /// without re-analysis.
+    #[arg(long, value_name = "DIR")]
+    cache_dir: Option<PathBuf>,
+}
+
+#[cfg(feature = "grpc")]
+#[derive(Args)]
+struct GrpcArgs {
+    /// Address to listen on: `host:port`, or `:port` for every interface.
+    #[arg(long, value_name = "ADDR", default_value = "127.0.0.1:50051")]
+    listen: String,
+
+    /// Directory that AnalyzeRepo roots are resolved against and confined to.
+    #[arg(long, value_name = "DIR", default_value = ".")]
+    root: PathBuf,
+
+    /// Path to a `.vibecheck` config file for AnalyzeFile (default: auto-discovered from the current directory).
+    #[arg(long)]
+    ignore_file: Option<PathBuf>,
+
+    /// Cache reports in this directory, so resubmitted sources are answered
+    /// without re-analysis.
+    #[arg(long, value_name = "DIR")]
+    cache_dir: Option<PathBuf>,
+}
+
+#[derive(Args)]
+struct BotArgs {
+    /// Address to listen on: `host:port`, or `:port` for every interface.
+    #[arg(long, value_name = "ADDR", default_value = "127.0.0.1:8080")]
+    listen: String,
+
+    /// The GitHub App's ID.
+    #[arg(long, value_name = "ID")]
+    app_id: String,
+
+    /// The GitHub App's private key (PEM), as downloaded from its settings.
+    #[arg(long, value_name = "FILE")]
+    private_key: PathBuf,
+
+    /// GitHub API base URL; set for GitHub Enterprise Server.
+    #[arg(long, value_name = "URL", default_value = github::DEFAULT_API_URL)]
+    api_url: String,
+
+    /// Fail the check if any changed file's AI probability is over this (0–1).
+    #[arg(long, value_name = "SCORE")]
+    fail_over: Option<f64>,
+
+    /// Cache reports in this directory, so files a push leaves unchanged
+    /// are not re-analyzed.
+    #[arg(long, value_name = "DIR")]
+    cache_dir: Option<PathBuf>,
+
+    /// The bot's own `.vibecheck`, for its `[[notify]]` sinks (default:
+    /// auto-discovered from the current directory).
+    #[arg(long)]
+    ignore_file: Option<PathBuf>,
 }
 
 #[derive(Args)]
 struct HistoryArgs {
-    /// File whose git history to replay.
+    /// File or directory whose git history to replay.
+    #[arg(default_value = ".")]
     path: PathBuf,
 
-    /// Maximum number of commits to show (default: 20).
+    /// Maximum number of commits to show (default: 20). Ignored with --since.
     #[arg(long, short = 'n', default_value = "20")]
     limit: usize,
+
+    /// Score the lines added by every commit after this revision (tag,
+    /// branch or hash) and report the AI-generated share over time.
+    #[arg(long, value_name = "REV")]
+    since: Option<String>,
+
+    /// Output format for --since: `text` (default, sparklines) or `json`.
+    #[arg(long, default_value = "text", requires = "since")]
+    format: String,
+
+    /// Roll the --since results up per commit author: AI share of their
+    /// added lines and the rules that fired most on them.
+    #[arg(long, value_name = "KEY", value_parser = ["author"], requires = "since")]
+    group_by: Option<String>,
+
+    /// With --group-by author, name authors `author-1`, `author-2`, …
+    /// instead of by name and email.
+    #[arg(long, requires = "group_by")]
+    anonymize: bool,
+}
+
+#[derive(Args)]
+struct BlameArgs {
+    /// File or directory whose flagged lines to blame.
+    #[arg(default_value = ".")]
+    path: PathBuf,
+
+    /// Output format: `text` (default) or `json`.
+    #[arg(long, default_value = "text", value_parser = ["text", "json"])]
+    format: String,
+
+    /// Path to a `.vibecheck` config file (default: auto-discovered from the path).
+    #[arg(long)]
+    ignore_file: Option<PathBuf>,
+
+    /// Skip paths matching this gitignore-style glob (repeatable; `!glob` re-includes).
+    #[arg(long, value_name = "GLOB")]
+    exclude: Vec<String>,
+}
+
+#[derive(Args)]
+struct CommitsArgs {
+    /// Any path inside the repository (default: current directory).
+    #[arg(default_value = ".")]
+    path: PathBuf,
+
+    /// Score the commits after this revision (tag, branch or hash) up to HEAD.
+    #[arg(long, value_name = "REV")]
+    since: String,
+
+    /// Output format: `text` (default) or `json`.
+    #[arg(long, default_value = "text", value_parser = ["text", "json"])]
+    format: String,
+}
+
+#[derive(Args)]
+struct TopArgs {
+    /// File or directory to rank.
+    #[arg(default_value = ".")]
+    path: PathBuf,
+
+    /// Number of entries to list.
+    #[arg(short = 'n', long = "count", default_value_t = 20)]
+    count: usize,
+
+    /// Rank whole files only, without per-function entries.
+    #[arg(long)]
+    files_only: bool,
+
+    /// Output format: `text` (default) or `json`.
+    #[arg(long, default_value = "text", value_parser = ["text", "json"])]
+    format: String,
+
+    /// Path to a `.vibecheck` config file (default: auto-discovered from the path).
+    #[arg(long)]
+    ignore_file: Option<PathBuf>,
+
+    /// Skip paths matching this gitignore-style glob (repeatable; `!glob` re-includes).
+    #[arg(long, value_name = "GLOB")]
+    exclude: Vec<String>,
+}
+
+#[derive(Args)]
+struct ReviewArgs {
+    /// File or directory whose flagged files to review.
+    #[arg(default_value = ".")]
+    path: PathBuf,
+
+    /// Review files at or above this AI probability.
+    #[arg(long, default_value_t = commands::review::DEFAULT_THRESHOLD)]
+    threshold: f64,
+
+    /// Also revisit files already reviewed at their current content.
+    #[arg(long)]
+    all: bool,
+
+    /// Write the recorded decisions as JSON to FILE (`-` for stdout) instead of reviewing.
+    #[arg(long, value_name = "FILE")]
+    export: Option<PathBuf>,
+
+    /// Add the reviewed files to the labelled corpus at DIR instead of reviewing.
+    #[arg(long, value_name = "DIR", conflicts_with = "export")]
+    export_corpus: Option<PathBuf>,
+
+    /// Path to a `.vibecheck` config file (default: auto-discovered from the path).
+    #[arg(long)]
+    ignore_file: Option<PathBuf>,
+
+    /// Skip paths matching this gitignore-style glob (repeatable; `!glob` re-includes).
+    #[arg(long, value_name = "GLOB")]
+    exclude: Vec<String>,
+}
+
+#[derive(Args)]
+struct MarkArgs {
+    /// File to record the verdict on.
+    file: PathBuf,
+
+    /// `human`, or the model family that wrote the file (claude, gpt, gemini, copilot).
+    label: String,
+
+    /// Path to a `.vibecheck` config file (default: auto-discovered from the file).
+    #[arg(long)]
+    ignore_file: Option<PathBuf>,
+}
+
+#[derive(Args)]
+struct OrgArgs {
+    #[command(subcommand)]
+    action: OrgAction,
+}
+
+#[derive(Subcommand)]
+enum OrgAction {
+    /// Scan the repositories of a list and report on them together.
+    Scan {
+        /// The repository list: YAML if named `.yaml` or `.yml`, else TOML.
+        #[arg(long, value_name = "FILE")]
+        config: PathBuf,
+
+        /// Repositories to scan at once (default: the list's `jobs`, else 4).
+        #[arg(long, short = 'j')]
+        jobs: Option<usize>,
+
+        /// Where each repository's summary is saved for resuming (default: `.vibecheck-org` beside the list).
+        #[arg(long, value_name = "DIR")]
+        state: Option<PathBuf>,
+
+        /// Rescan every repository instead of resuming from saved summaries.
+        #[arg(long)]
+        fresh: bool,
+
+        /// Output format: `text` (default) or `json`.
+        #[arg(long, default_value = "text", value_parser = ["text", "json"])]
+        format: String,
+    },
+}
+
+#[derive(Args)]
+struct ReportArgs {
+    #[command(subcommand)]
+    action: ReportAction,
+}
+
+#[derive(Subcommand)]
+enum ReportAction {
+    /// Show new and resolved findings and per-file score changes between two scans.
+    Diff {
+        /// The earlier scan's JSON report.
+        old: PathBuf,
+
+        /// The later scan's JSON report.
+        new: PathBuf,
+
+        /// Output format: `text` (default) or `json`.
+        #[arg(long, default_value = "text", value_parser = ["text", "json"])]
+        format: String,
+    },
+}
+
+#[derive(Args)]
+struct TrendArgs {
+    /// Any path in the project whose saved scans to show.
+    #[arg(default_value = ".")]
+    path: PathBuf,
+
+    /// Only the scans of this path, as recorded (relative to the project
+    /// root, `.` for all of it).
+    #[arg(long, value_name = "PATH")]
+    scope: Option<String>,
+
+    /// Output format: `text` (default) or `json`.
+    #[arg(long, default_value = "text", value_parser = ["text", "json"])]
+    format: String,
 }
 
 #[derive(Args)]
@@ -193,6 +1128,228 @@ struct HeuristicsArgs {
     format: String,
 }
 
+#[derive(Args)]
+struct RulesArgs {
+    /// Only list rules for this language: rust, python, javascript, go, packs,
+    /// or a rule language such as go_cst.
+    #[arg(long, value_name = "LANG")]
+    language: Option<String>,
+
+    /// Output format: `table` (default) or `json`.
+    #[arg(long, default_value = "table", value_parser = ["table", "json"])]
+    format: String,
+
+    /// Enable these rules in `.vibecheck`. Comma-separated signal IDs or dotted prefixes.
+    #[arg(long, value_name = "RULE", value_delimiter = ',')]
+    enable: Vec<String>,
+
+    /// Disable these rules in `.vibecheck`. Comma-separated signal IDs or dotted prefixes.
+    #[arg(long, value_name = "RULE", value_delimiter = ',')]
+    disable: Vec<String>,
+
+    /// Path to a `.vibecheck` config file to read and edit (default: auto-discovered
+    /// from the current directory).
+    #[arg(long)]
+    ignore_file: Option<PathBuf>,
+}
+
+#[derive(Args)]
+struct EvalArgs {
+    /// Directory of labelled source files.
+    #[arg(long)]
+    corpus: PathBuf,
+
+    /// Output format: `text` (default) or `json`.
+    #[arg(long, default_value = "text")]
+    format: String,
+}
+
+#[derive(Args)]
+struct BenchArgs {
+    /// Directory (or single file) of source files to analyze.
+    #[arg(long)]
+    corpus: PathBuf,
+
+    /// Times to analyze every file; more iterations steady the percentiles.
+    #[arg(long, default_value_t = 3)]
+    iterations: usize,
+
+    /// Output format: `text` (default) or `json`.
+    #[arg(long, default_value = "text")]
+    format: String,
+
+    /// Also write a gzipped pprof profile (wall time, allocation count and
+    /// bytes per detector and file) to this path.
+    #[arg(long, value_name = "FILE")]
+    pprof: Option<PathBuf>,
+}
+
+#[derive(Args)]
+struct TuneArgs {
+    /// Directory of labelled source files.
+    #[arg(long)]
+    corpus: PathBuf,
+
+    /// Cross-validation folds.
+    #[arg(long, default_value_t = vibecheck_core::tuning::DEFAULT_FOLDS)]
+    folds: usize,
+
+    /// L2 regularization: the pull toward the current weights, or toward zero
+    /// for a logistic model; larger is more conservative.
+    #[arg(long, default_value_t = vibecheck_core::tuning::DEFAULT_L2)]
+    l2: f64,
+
+    /// What to fit: heuristic (signal weights), logistic (a model for
+    /// `[classifier] backend = "logistic"`), onnx (the family prototypes of
+    /// the embedding model in --model), stylometry (the comment n-gram
+    /// model for `[stylometry]`) or calibration (per-language probability
+    /// calibrations for `[calibration]`).
+    #[arg(
+        long,
+        default_value = "heuristic",
+        value_parser = ["heuristic", "logistic", "onnx", "stylometry", "calibration"]
+    )]
+    classifier: String,
+
+    /// Embedding model directory (model.onnx and tokenizer.json) for
+    /// --classifier onnx.
+    #[arg(long, value_name = "DIR", required_if_eq("classifier", "onnx"))]
+    model: Option<PathBuf>,
+
+    /// Where to write the result [default: vibecheck-weights.toml,
+    /// vibecheck-logistic.toml, vibecheck-stylometry.toml,
+    /// vibecheck-calibration.toml, or <DIR>/prototypes.toml for onnx].
+    #[arg(long)]
+    output: Option<PathBuf>,
+}
+
+#[derive(Args)]
+struct CorpusArgs {
+    /// Corpus root; its manifest is `<DIR>/corpus.toml`.
+    #[arg(long, global = true, value_name = "DIR", default_value = ".")]
+    corpus: PathBuf,
+
+    #[command(subcommand)]
+    action: CorpusAction,
+}
+
+#[derive(Subcommand)]
+enum CorpusAction {
+    /// Add source files to the corpus under one label.
+    Add {
+        /// Files to add.
+        #[arg(required = true)]
+        files: Vec<PathBuf>,
+
+        /// The family that wrote the files: claude, gpt, gemini, copilot or human.
+        #[arg(long)]
+        label: String,
+
+        /// The model only assisted: a person edited or finished its output.
+        #[arg(long)]
+        assisted: bool,
+
+        /// The exact model, e.g. `claude-3-5-sonnet` or `gpt-4o-2024-08-06`.
+        #[arg(long)]
+        model: Option<String>,
+
+        /// What the files implement; also the directory they are copied into.
+        #[arg(long)]
+        task: Option<String>,
+
+        /// SPDX license identifier of the files.
+        #[arg(long)]
+        license: Option<String>,
+    },
+
+    /// List samples with their provenance and per-label counts.
+    List {
+        /// Only samples with this label.
+        #[arg(long)]
+        label: Option<String>,
+
+        /// Output format: `text` (default) or `json`.
+        #[arg(long, default_value = "text")]
+        format: String,
+    },
+
+    /// Change the label of a sample.
+    Relabel {
+        /// Sample path, relative to the corpus root.
+        path: PathBuf,
+
+        /// The new label.
+        label: String,
+    },
+
+    /// Generate samples for a task by calling each family's LLM provider.
+    #[command(
+        long_about = "Ask each family's provider API to implement a task in each language and \
+                      store the replies as labelled samples, recording the model version that \
+                      served them. Defaults: claude uses the Anthropic API (ANTHROPIC_API_KEY), \
+                      gpt OpenAI (OPENAI_API_KEY), gemini Google (GEMINI_API_KEY); override or \
+                      add providers with [providers.<family>] tables in .vibecheck. Failed \
+                      requests and duplicate replies are reported and skipped."
+    )]
+    Generate {
+        /// What to implement, e.g. "lru cache" or "token bucket rate limiter".
+        #[arg(long)]
+        task: String,
+
+        /// Families to generate for, comma-separated.
+        #[arg(long, value_delimiter = ',', default_value = "claude,gpt,gemini")]
+        models: Vec<String>,
+
+        /// Languages to generate in, comma-separated: rust, python, js, go.
+        #[arg(long, value_delimiter = ',', default_value = "rust,python,js,go")]
+        languages: Vec<String>,
+
+        /// Samples per family and language.
+        #[arg(long, default_value = "1")]
+        samples: usize,
+
+        /// SPDX license identifier to record for the samples.
+        #[arg(long)]
+        license: Option<String>,
+
+        /// Sampling seed sent to providers that accept one (OpenAI and Gemini
+        /// APIs), so a rerun asks for the same replies. Sample n uses seed + n.
+        #[arg(long)]
+        seed: Option<u32>,
+    },
+
+    /// Remove samples whose content repeats another sample's.
+    Dedupe {
+        /// Report duplicates without changing anything.
+        #[arg(long)]
+        dry_run: bool,
+    },
+
+    /// Replace emails, usernames, ticket IDs and internal hostnames in samples.
+    #[command(
+        long_about = "Rewrite every sample to remove personal and internal details before the corpus \
+                      is shared: email addresses become dev1@example.com, @mentions, TODO(name) and \
+                      home directories get dev1, dev2, ... usernames, ticket IDs in comments become \
+                      PROJ-1, PROJ-2, ..., and hosts under internal domains (.internal, .corp, .lan, \
+                      .intranet, or any --domain) become host1.example.com. Placeholders keep the \
+                      shape of what they replace, so the humanity detectors still fire, and one \
+                      person or ticket gets the same placeholder in every sample. Manifest hashes \
+                      are updated.\n\n\
+                      Examples:\n  \
+                      vibecheck corpus scrub --dry-run\n  \
+                      vibecheck corpus scrub --domain acme.net,acme.io"
+    )]
+    Scrub {
+        /// More internal domains, comma-separated; hosts under them are replaced.
+        #[arg(long, value_delimiter = ',')]
+        domain: Vec<String>,
+
+        /// List what would be replaced without changing anything.
+        #[arg(long)]
+        dry_run: bool,
+    },
+}
+
 // ---------------------------------------------------------------------------
 // Dispatch
 // ---------------------------------------------------------------------------
@@ -225,38 +1382,223 @@ mod tests {
         assert!(names.contains(&"watch".to_string()));
         assert!(names.contains(&"history".to_string()));
         assert!(names.contains(&"heuristics".to_string()));
+        assert!(names.contains(&"eval".to_string()));
+        assert!(names.contains(&"corpus".to_string()));
+        assert!(names.contains(&"tune".to_string()));
+        assert!(names.contains(&"review".to_string()));
+        assert!(names.contains(&"mark".to_string()));
+        assert!(names.contains(&"org".to_string()));
     }
 }
 
-fn main() -> Result<()> {
-    let cli = Cli::parse();
+/// Exit status for any error, including an analysis that could not run.
+/// Clap uses the same code for usage errors; a tripped CI gate exits with
+/// [`commands::analyze::EXIT_GATE_FAILED`].
+const EXIT_ERROR: u8 = 2;
+
+fn main() -> ExitCode {
+    match run(Cli::parse()) {
+        Ok(()) => ExitCode::SUCCESS,
+        Err(e) => {
+            eprintln!("Error: {e:?}");
+            ExitCode::from(EXIT_ERROR)
+        }
+    }
+}
 
+fn run(cli: Cli) -> Result<()> {
+    // Every config load, including per-directory ones, runs under the profile.
+    policy::init(cli.profile.clone());
     match cli.command {
         Some(Command::Analyze(a)) => commands::analyze::run(
-            &a.path,
+            a.path.as_ref().unwrap_or(&PathBuf::from(".")),
+            (!a.files.is_empty()).then_some(a.files.as_slice()),
+            a.quiet,
             &a.format,
             a.no_cache,
+            a.cache_dir.as_ref(),
             a.symbols,
+            a.segments,
+            a.strip_comments,
+            a.remediation,
+            a.stats,
+            a.group_by.as_deref(),
             a.assert_family,
             a.ignore_file.as_ref(),
+            &a.exclude,
+            a.include_generated,
+            a.threshold,
+            a.fail_over,
+            &a.fail_on,
+            a.timeout_per_file,
+            &a.max_file_size,
+            a.max_memory_hint.as_deref(),
         ),
 
         Some(Command::Tui(a)) => commands::tui::run(&a.path, a.ignore_file.as_ref()),
 
-        Some(Command::Watch(a)) => commands::watch::run(&a.path, a.no_cache, a.ignore_file.as_ref()),
+        Some(Command::Watch(a)) => commands::watch::run(&a.path, a.no_cache, a.ignore_file.as_ref(), &a.exclude),
+
+        Some(Command::Check(a)) => commands::check::run(&a.input, a.lang.as_deref(), &a.format),
+
+        Some(Command::Compare(a)) => commands::compare::run(&a.a, &a.b, a.ignore_file.as_ref(), &a.format),
+
+        Some(Command::Scan(a)) => match (a.patch, a.path) {
+            (Some(patch), _) => commands::scan::run(
+                &patch,
+                &a.format,
+                a.ignore_file.as_ref(),
+                &a.exclude,
+                a.threshold,
+                a.fail_over,
+                &a.fail_on,
+            ),
+            (None, Some(path)) => commands::scan::run_tree(
+                &path,
+                &a.format,
+                a.save,
+                a.notify,
+                a.sample,
+                a.seed,
+                a.ignore_file.as_ref(),
+                &a.exclude,
+                a.fail_over,
+                &a.fail_on,
+            ),
+            (None, None) => unreachable!("clap requires a path or --patch"),
+        },
+
+        Some(Command::Trend(a)) => commands::trend::run(&a.path, &a.format, a.scope.as_deref()),
+
+        Some(Command::Serve(a)) => commands::serve::run(&a.listen, a.ignore_file.as_ref(), a.cache_dir.as_ref()),
+
+        #[cfg(feature = "grpc")]
+        Some(Command::Grpc(a)) => commands::grpc::run(&a.listen, &a.root, a.ignore_file.as_ref(), a.cache_dir.as_ref()),
+
+        Some(Command::Bot(a)) => commands::bot::run(
+            &a.listen,
+            &a.app_id,
+            &a.private_key,
+            &a.api_url,
+            a.fail_over,
+            a.cache_dir.as_ref(),
+            a.ignore_file.as_ref(),
+        ),
+
+        Some(Command::History(a)) => match &a.since {
+            Some(since) => commands::history::run_since(
+                &a.path,
+                since,
+                &a.format,
+                a.group_by.is_some(),
+                a.anonymize,
+            ),
+            None => commands::history::run(&a.path, Some(a.limit)),
+        },
+
+        Some(Command::Blame(a)) => commands::blame::run(&a.path, &a.format, a.ignore_file.as_ref(), &a.exclude),
 
-        Some(Command::History(a)) => commands::history::run(&a.path, Some(a.limit)),
+        Some(Command::Commits(a)) => commands::commits::run(&a.path, &a.since, &a.format),
+
+        Some(Command::Top(a)) => {
+            commands::top::run(&a.path, a.count, a.files_only, &a.format, a.ignore_file.as_ref(), &a.exclude)
+        }
+
+        Some(Command::Review(a)) => match (a.export, a.export_corpus) {
+            (Some(out), _) => commands::review::export(&a.path, &out, a.ignore_file.as_ref()),
+            (_, Some(corpus)) => commands::review::export_corpus(&a.path, &corpus, a.ignore_file.as_ref()),
+            _ => commands::review::run(&a.path, a.threshold, a.all, a.ignore_file.as_ref(), &a.exclude),
+        },
+
+        Some(Command::Mark(a)) => commands::review::mark(&a.file, &a.label, a.ignore_file.as_ref()),
+
+        Some(Command::Org(a)) => match a.action {
+            OrgAction::Scan { config, jobs, state, fresh, format } => {
+                commands::org::scan(&config, jobs, state.as_ref(), fresh, &format)
+            }
+        },
+
+        Some(Command::Report(a)) => match a.action {
+            ReportAction::Diff { old, new, format } => commands::report::diff(&old, &new, &format),
+        },
 
         Some(Command::Heuristics(a)) => commands::heuristics::run(&a.format),
 
+        Some(Command::Rules(a)) => {
+            commands::rules::run(a.language.as_deref(), &a.format, &a.enable, &a.disable, a.ignore_file.as_ref())
+        }
+
+        Some(Command::Eval(a)) => commands::eval::run(&a.corpus, &a.format),
+
+        Some(Command::Bench(a)) => commands::bench::run(&a.corpus, a.iterations, &a.format, a.pprof.as_deref()),
+
+        Some(Command::Tune(a)) => match a.classifier.as_str() {
+            "onnx" => {
+                let model = a.model.expect("required by clap");
+                let output = a.output.unwrap_or_else(|| model.join(vibecheck_core::embedding::PROTOTYPES_FILE));
+                commands::tune::run_onnx(&a.corpus, a.folds, &model, &output)
+            }
+            "logistic" => commands::tune::run_logistic(
+                &a.corpus,
+                a.folds,
+                a.l2,
+                a.output.as_deref().unwrap_or(Path::new("vibecheck-logistic.toml")),
+            ),
+            "stylometry" => commands::tune::run_stylometry(
+                &a.corpus,
+                a.folds,
+                a.output.as_deref().unwrap_or(Path::new("vibecheck-stylometry.toml")),
+            ),
+            "calibration" => commands::tune::run_calibration(
+                &a.corpus,
+                a.folds,
+                a.output.as_deref().unwrap_or(Path::new("vibecheck-calibration.toml")),
+            ),
+            _ => commands::tune::run(
+                &a.corpus,
+                a.folds,
+                a.l2,
+                a.output.as_deref().unwrap_or(Path::new("vibecheck-weights.toml")),
+            ),
+        },
+
+        Some(Command::Corpus(a)) => match a.action {
+            CorpusAction::Add { files, label, assisted, model, task, license } => {
+                commands::corpus::add(&a.corpus, &files, &label, assisted, model, task, license)
+            }
+            CorpusAction::List { label, format } => commands::corpus::list(&a.corpus, label.as_deref(), &format),
+            CorpusAction::Relabel { path, label } => commands::corpus::relabel(&a.corpus, &path, &label),
+            CorpusAction::Dedupe { dry_run } => commands::corpus::dedupe(&a.corpus, dry_run),
+            CorpusAction::Scrub { domain, dry_run } => commands::corpus::scrub(&a.corpus, &domain, dry_run),
+            CorpusAction::Generate { task, models, languages, samples, license, seed } => {
+                commands::corpus::generate(&a.corpus, &task, &models, &languages, samples, license, seed)
+            }
+        },
+
         None => match cli.path {
             Some(path) => commands::analyze::run(
                 &path,
+                None,
+                false,
                 &cli.format,
                 cli.no_cache,
+                cli.cache_dir.as_ref(),
                 cli.symbols,
+                cli.segments,
+                cli.strip_comments,
+                cli.remediation,
+                cli.stats,
+                cli.group_by.as_deref(),
                 cli.assert_family,
                 cli.ignore_file.as_ref(),
+                &cli.exclude,
+                cli.include_generated,
+                cli.threshold,
+                cli.fail_over,
+                &cli.fail_on,
+                cli.timeout_per_file,
+                &cli.max_file_size,
+                cli.max_memory_hint.as_deref(),
             ),
             None => {
                 let cwd = std::env::current_dir()?;
@@ -0,0 +1,190 @@
+use std::collections::BTreeMap;
+use std::fmt::Write;
+use std::sync::Mutex;
+use std::time::Duration;
+
+use vibecheck_core::cache;
+use vibecheck_core::report::{ModelFamily, Report};
+
+/// Upper bounds, in seconds, of the analysis latency histogram buckets.
+const LATENCY_BUCKETS: [f64; 12] = [0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0, 30.0];
+
+/// Counters for `GET /metrics` on `serve` and `bot`, in the Prometheus
+/// text exposition format.  The AI-flag rate is
+/// `rate(vibecheck_files_flagged_total) / rate(vibecheck_files_analyzed_total)`.
+#[derive(Default)]
+pub struct Metrics {
+    inner: Mutex<Counters>,
+    pull_requests: bool,
+}
+
+#[derive(Default)]
+struct Counters {
+    analyzed: u64,
+    flagged: u64,
+    skipped: u64,
+    errors: u64,
+    /// Per-bucket counts (not cumulative), plus the overflow bucket.
+    latency: [u64; LATENCY_BUCKETS.len() + 1],
+    latency_sum: f64,
+    verdicts: BTreeMap<ModelFamily, u64>,
+    findings: BTreeMap<String, u64>,
+    pulls_reviewed: u64,
+    pulls_flagged: u64,
+}
+
+impl Metrics {
+    /// Also export pull request counters, for `bot`.
+    pub fn with_pull_requests(mut self) -> Self {
+        self.pull_requests = true;
+        self
+    }
+
+    /// Count one analyzed file and how long it took.
+    pub fn record(&self, report: &Report, elapsed: Duration) {
+        let mut c = self.inner.lock().unwrap();
+        if report.metadata.skipped.is_some() {
+            c.skipped += 1;
+            return;
+        }
+        c.analyzed += 1;
+        if report.attribution.has_sufficient_data() && report.attribution.primary != ModelFamily::Human {
+            c.flagged += 1;
+        }
+        let secs = elapsed.as_secs_f64();
+        let bucket = LATENCY_BUCKETS.iter().position(|&le| secs <= le).unwrap_or(LATENCY_BUCKETS.len());
+        c.latency[bucket] += 1;
+        c.latency_sum += secs;
+        *c.verdicts.entry(report.attribution.primary).or_insert(0) += 1;
+        for signal in report.signals.iter().filter(|s| !s.id.is_empty()) {
+            *c.findings.entry(signal.id.clone()).or_insert(0) += 1;
+        }
+    }
+
+    /// Count a file that could not be analyzed at all.
+    pub fn record_error(&self) {
+        self.inner.lock().unwrap().errors += 1;
+    }
+
+    /// Count one reviewed pull request, `flagged` when any of its files was
+    /// attributed to AI.
+    pub fn record_pull_request(&self, flagged: bool) {
+        let mut c = self.inner.lock().unwrap();
+        c.pulls_reviewed += 1;
+        c.pulls_flagged += u64::from(flagged);
+    }
+
+    pub fn render(&self) -> String {
+        let c = self.inner.lock().unwrap();
+        let mut out = String::new();
+        counter(&mut out, "vibecheck_files_analyzed_total", "Files analyzed.", &[("", c.analyzed)]);
+        counter(&mut out, "vibecheck_files_flagged_total", "Analyzed files attributed to an AI family.", &[("", c.flagged)]);
+        counter(&mut out, "vibecheck_files_skipped_total", "Files skipped for size or time limits.", &[("", c.skipped)]);
+        counter(&mut out, "vibecheck_analysis_errors_total", "Files that could not be analyzed.", &[("", c.errors)]);
+
+        let name = "vibecheck_analysis_duration_seconds";
+        let _ = writeln!(out, "# HELP {name} Time to analyze one file.\n# TYPE {name} histogram");
+        let mut cumulative = 0;
+        for (i, le) in LATENCY_BUCKETS.iter().enumerate() {
+            cumulative += c.latency[i];
+            let _ = writeln!(out, "{name}_bucket{{le=\"{le}\"}} {cumulative}");
+        }
+        cumulative += c.latency[LATENCY_BUCKETS.len()];
+        let _ = writeln!(out, "{name}_bucket{{le=\"+Inf\"}} {cumulative}");
+        let _ = writeln!(out, "{name}_sum {}\n{name}_count {cumulative}", c.latency_sum);
+
+        let verdicts: Vec<(String, u64)> = ModelFamily::all()
+            .iter()
+            .map(|f| (label("family", &f.to_string().to_lowercase()), c.verdicts.get(f).copied().unwrap_or(0)))
+            .collect();
+        counter_labelled(&mut out, "vibecheck_verdicts_total", "Analyzed files by attributed family.", &verdicts);
+        let findings: Vec<(String, u64)> = c.findings.iter().map(|(id, n)| (label("rule", id), *n)).collect();
+        counter_labelled(&mut out, "vibecheck_findings_total", "Signals fired, by rule.", &findings);
+
+        let lookups = cache::stats();
+        counter_labelled(
+            &mut out,
+            "vibecheck_cache_lookups_total",
+            "Report cache lookups, by result.",
+            &[(label("result", "hit"), lookups.hits), (label("result", "miss"), lookups.misses)],
+        );
+
+        if self.pull_requests {
+            counter(&mut out, "vibecheck_pull_requests_reviewed_total", "Pull requests reviewed.", &[("", c.pulls_reviewed)]);
+            counter(
+                &mut out,
+                "vibecheck_pull_requests_flagged_total",
+                "Reviewed pull requests with a file attributed to AI.",
+                &[("", c.pulls_flagged)],
+            );
+        }
+        out
+    }
+}
+
+fn counter(out: &mut String, name: &str, help: &str, samples: &[(&str, u64)]) {
+    let samples: Vec<(String, u64)> = samples.iter().map(|(l, n)| (l.to_string(), *n)).collect();
+    counter_labelled(out, name, help, &samples);
+}
+
+fn counter_labelled(out: &mut String, name: &str, help: &str, samples: &[(String, u64)]) {
+    let _ = writeln!(out, "# HELP {name} {help}\n# TYPE {name} counter");
+    for (labels, n) in samples {
+        let _ = writeln!(out, "{name}{labels} {n}");
+    }
+}
+
+/// `{key="value"}`, with the value escaped as the format requires.
+fn label(key: &str, value: &str) -> String {
+    let escaped = value.replace('\\', r"\\").replace('"', "\\\"").replace('\n', r"\n");
+    format!("{{{key}=\"{escaped}\"}}")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use vibecheck_core::report::Signal;
+
+    fn report(primary: ModelFamily, signals: Vec<Signal>) -> Report {
+        let mut report = vibecheck_core::analyze("fn main() {}\n");
+        report.attribution.primary = primary;
+        report.attribution.confidence = 0.8;
+        report.signals = signals;
+        report
+    }
+
+    #[test]
+    fn renders_counters_histogram_and_labels() {
+        let metrics = Metrics::default().with_pull_requests();
+        let rule = Signal::new("rust.comments.narration", "comments", "x", ModelFamily::Claude, 1.0);
+        metrics.record(&report(ModelFamily::Claude, vec![rule.clone()]), Duration::from_millis(30));
+        metrics.record(&report(ModelFamily::Human, vec![rule]), Duration::from_secs(60));
+        metrics.record(&Report::skipped("big.rs".into(), "too_large"), Duration::ZERO);
+        metrics.record_pull_request(true);
+
+        let text = metrics.render();
+        for line in [
+            "vibecheck_files_analyzed_total 2",
+            "vibecheck_files_flagged_total 1",
+            "vibecheck_files_skipped_total 1",
+            "vibecheck_analysis_duration_seconds_bucket{le=\"0.025\"} 0",
+            "vibecheck_analysis_duration_seconds_bucket{le=\"0.05\"} 1",
+            "vibecheck_analysis_duration_seconds_bucket{le=\"30\"} 1",
+            "vibecheck_analysis_duration_seconds_bucket{le=\"+Inf\"} 2",
+            "vibecheck_analysis_duration_seconds_count 2",
+            "vibecheck_verdicts_total{family=\"claude\"} 1",
+            "vibecheck_verdicts_total{family=\"gemini\"} 0",
+            "vibecheck_findings_total{rule=\"rust.comments.narration\"} 2",
+            "vibecheck_pull_requests_flagged_total 1",
+            "# TYPE vibecheck_cache_lookups_total counter",
+        ] {
+            assert!(text.lines().any(|l| l == line), "missing {line:?} in:\n{text}");
+        }
+        assert!(!Metrics::default().render().contains("pull_requests"));
+    }
+
+    #[test]
+    fn label_values_are_escaped() {
+        assert_eq!(label("rule", "a\"b\\c\n"), r#"{rule="a\"b\\c\n"}"#);
+    }
+}
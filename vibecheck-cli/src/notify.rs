@@ -0,0 +1,239 @@
+//! Scan summaries posted to Slack or a generic webhook.
+//!
+//! `[[notify]]` tables in `.vibecheck` configure the sinks.  A sink fires
+//! when the scan crosses one of its thresholds.  It then gets the
+//! repository, the AI-attributed file count, the most AI-like files and a
+//! link to the full report.  Posting is best effort: a failed notification
+//! is reported on stderr and never fails the scan.
+
+use std::time::Duration;
+
+use anyhow::{bail, Context, Result};
+use serde_json::{json, Value};
+
+use vibecheck_core::ignore_rules::NotifySettings;
+use vibecheck_core::report::{ModelFamily, Report};
+
+const REQUEST_TIMEOUT: Duration = Duration::from_secs(10);
+
+const DEFAULT_TOP: usize = 5;
+
+/// What was scanned, for the summary.
+pub struct Scan<'a> {
+    /// `owner/name` for pull requests, the project directory otherwise.
+    pub repo: String,
+    /// What the scan covered: a path, or a pull request.
+    pub subject: String,
+    pub commit: Option<String>,
+    /// Where to read more when the sink has no `report_url`.
+    pub link: Option<String>,
+    pub reports: &'a [Report],
+}
+
+impl Scan<'_> {
+    fn flagged(&self) -> Vec<&Report> {
+        self.reports
+            .iter()
+            .filter(|r| r.attribution.has_sufficient_data() && r.attribution.primary != ModelFamily::Human)
+            .collect()
+    }
+
+    /// Flagged files, highest AI probability first.
+    fn top(&self, n: usize) -> Vec<&Report> {
+        let mut flagged = self.flagged();
+        flagged.sort_by(|a, b| probability(b).total_cmp(&probability(a)));
+        flagged.truncate(n);
+        flagged
+    }
+
+    fn link(&self, sink: &NotifySettings) -> Option<String> {
+        match &sink.report_url {
+            Some(template) => Some(
+                template
+                    .replace("{repo}", &self.repo)
+                    .replace("{commit}", self.commit.as_deref().unwrap_or("")),
+            ),
+            None => self.link.clone(),
+        }
+    }
+}
+
+fn probability(report: &Report) -> f64 {
+    report.attribution.ai_probability.unwrap_or(report.attribution.confidence)
+}
+
+/// Post `scan` to every sink whose thresholds it crosses.
+pub fn send(sinks: &[NotifySettings], scan: &Scan) {
+    if sinks.is_empty() {
+        return;
+    }
+    let http = match reqwest::blocking::Client::builder()
+        .user_agent(concat!("vibecheck/", env!("CARGO_PKG_VERSION")))
+        .timeout(REQUEST_TIMEOUT)
+        .build()
+    {
+        Ok(http) => http,
+        Err(e) => {
+            eprintln!("vibecheck: warning: cannot send notifications: {e}");
+            return;
+        }
+    };
+    for sink in sinks {
+        if let Err(e) = post(&http, sink, scan) {
+            eprintln!("vibecheck: warning: {} notification failed: {e:#}", sink.kind.as_deref().unwrap_or("webhook"));
+        }
+    }
+}
+
+fn post(http: &reqwest::blocking::Client, sink: &NotifySettings, scan: &Scan) -> Result<()> {
+    let Some(reason) = triggered(sink, scan) else {
+        return Ok(());
+    };
+    let body = match sink.kind.as_deref().unwrap_or("webhook") {
+        "slack" => slack_payload(sink, scan, &reason),
+        "webhook" => webhook_payload(sink, scan, &reason),
+        other => bail!("unknown notify kind: {other} (expected slack or webhook)"),
+    };
+    let url = match &sink.url_env {
+        Some(var) => std::env::var(var).with_context(|| format!("{var} is not set"))?,
+        None => sink.url.clone().context("no url or url_env")?,
+    };
+    http.post(&url).json(&body).send()?.error_for_status()?;
+    Ok(())
+}
+
+/// Why `sink` fires for `scan`, or `None` if it stays quiet.
+fn triggered(sink: &NotifySettings, scan: &Scan) -> Option<String> {
+    let flagged = scan.flagged().len();
+    let min_files = match (sink.min_ai_files, sink.ai_probability_over) {
+        (None, None) => Some(1),
+        (min, _) => min,
+    };
+    if let Some(min) = min_files {
+        if flagged >= min {
+            let n = scan.reports.len();
+            let files = if n == 1 { "file" } else { "files" };
+            return Some(format!("{flagged} of {n} {files} attributed to AI"));
+        }
+    }
+    let limit = sink.ai_probability_over?;
+    let over = scan
+        .reports
+        .iter()
+        .filter(|r| r.attribution.ai_probability.is_some_and(|p| p > limit))
+        .count();
+    (over > 0).then(|| format!("{over} of {} files over AI probability {limit}", scan.reports.len()))
+}
+
+fn file_name(report: &Report) -> String {
+    report.metadata.file_path.as_deref().map(|p| p.display().to_string()).unwrap_or_else(|| "<stdin>".into())
+}
+
+/// A Slack incoming-webhook message in `mrkdwn`.
+fn slack_payload(sink: &NotifySettings, scan: &Scan, reason: &str) -> Value {
+    let mut text = format!("*vibecheck*: {reason} in *{}* ({})", scan.repo, scan.subject);
+    for report in scan.top(sink.top.unwrap_or(DEFAULT_TOP)) {
+        text.push_str(&format!(
+            "\n• `{}` — {} ({:.0}%)",
+            file_name(report),
+            report.attribution.primary,
+            probability(report) * 100.0
+        ));
+    }
+    if let Some(link) = scan.link(sink) {
+        text.push_str(&format!("\n<{link}|Full report>"));
+    }
+    json!({ "text": text })
+}
+
+/// The summary as JSON, for anything that isn't Slack.
+fn webhook_payload(sink: &NotifySettings, scan: &Scan, reason: &str) -> Value {
+    let top: Vec<Value> = scan
+        .top(sink.top.unwrap_or(DEFAULT_TOP))
+        .into_iter()
+        .map(|r| {
+            json!({
+                "path": file_name(r),
+                "family": r.attribution.primary.to_string().to_lowercase(),
+                "ai_probability": r.attribution.ai_probability,
+                "confidence": r.attribution.confidence,
+            })
+        })
+        .collect();
+    json!({
+        "repo": scan.repo,
+        "subject": scan.subject,
+        "commit": scan.commit,
+        "reason": reason,
+        "files": scan.reports.len(),
+        "ai_files": scan.flagged().len(),
+        "top_files": top,
+        "report_url": scan.link(sink),
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::path::PathBuf;
+
+    fn report(path: &str, family: ModelFamily, p: f64) -> Report {
+        let mut report = vibecheck_core::analyze("fn main() {}\n");
+        report.metadata.file_path = Some(PathBuf::from(path));
+        report.metadata.signal_count = 5;
+        report.attribution.primary = family;
+        report.attribution.confidence = 0.8;
+        report.attribution.ai_probability = Some(p);
+        report
+    }
+
+    fn scan(reports: &[Report]) -> Scan<'_> {
+        Scan {
+            repo: "acme/widgets".into(),
+            subject: "pull request #7".into(),
+            commit: Some("abc123".into()),
+            link: Some("https://github.com/acme/widgets/pull/7".into()),
+            reports,
+        }
+    }
+
+    #[test]
+    fn thresholds_decide_when_a_sink_fires() {
+        let reports = [report("a.rs", ModelFamily::Claude, 0.8), report("b.rs", ModelFamily::Human, 0.2)];
+        let default = NotifySettings::default();
+        assert_eq!(triggered(&default, &scan(&reports)).as_deref(), Some("1 of 2 files attributed to AI"));
+        let three = NotifySettings { min_ai_files: Some(3), ..Default::default() };
+        assert_eq!(triggered(&three, &scan(&reports)), None);
+        let over = NotifySettings { ai_probability_over: Some(0.9), ..Default::default() };
+        assert_eq!(triggered(&over, &scan(&reports)), None);
+        let over = NotifySettings { ai_probability_over: Some(0.5), ..Default::default() };
+        assert_eq!(triggered(&over, &scan(&reports)).as_deref(), Some("1 of 2 files over AI probability 0.5"));
+    }
+
+    #[test]
+    fn payloads_list_top_files_and_link() {
+        let reports = [
+            report("low.rs", ModelFamily::Gpt, 0.6),
+            report("high.rs", ModelFamily::Claude, 0.95),
+            report("human.rs", ModelFamily::Human, 0.1),
+        ];
+        let sink = NotifySettings {
+            top: Some(1),
+            report_url: Some("https://ci.example.com/{repo}/{commit}.html".into()),
+            ..Default::default()
+        };
+        let slack = slack_payload(&sink, &scan(&reports), "2 of 3 files attributed to AI");
+        assert_eq!(
+            slack["text"],
+            "*vibecheck*: 2 of 3 files attributed to AI in *acme/widgets* (pull request #7)\n\
+             • `high.rs` — Claude (95%)\n\
+             <https://ci.example.com/acme/widgets/abc123.html|Full report>"
+        );
+
+        let hook = webhook_payload(&NotifySettings::default(), &scan(&reports), "x");
+        assert_eq!(hook["ai_files"], 2);
+        assert_eq!(hook["top_files"][0]["path"], "high.rs");
+        assert_eq!(hook["top_files"][1]["family"], "gpt");
+        assert_eq!(hook["report_url"], "https://github.com/acme/widgets/pull/7");
+    }
+}
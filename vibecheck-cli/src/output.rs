@@ -1,6 +1,6 @@
 use colored::Colorize;
 use vibecheck_core::colors::ColorTheme;
-use vibecheck_core::report::Report;
+use vibecheck_core::report::{Report, Severity};
 
 /// Format a report with terminal colors, using the supplied [`ColorTheme`].
 ///
@@ -25,6 +25,12 @@ pub fn format_pretty(report: &Report, theme: &dyn ColorTheme) -> String {
             "Verdict:".bold(),
             verdict_str.color(verdict_color).bold()
         ));
+    } else if let Some(ref reason) = report.metadata.skipped {
+        out.push_str(&format!(
+            "{} {}\n",
+            "Verdict:".bold(),
+            format!("skipped: {reason}").yellow()
+        ));
     } else {
         out.push_str(&format!(
             "{} {}\n",
@@ -32,6 +38,33 @@ pub fn format_pretty(report: &Report, theme: &dyn ColorTheme) -> String {
             "Insufficient data".dimmed()
         ));
     }
+    if let Some(p) = report.attribution.ai_probability {
+        out.push_str(&format!("{} {:.2} likely AI-generated\n", "AI probability:".bold(), p));
+    }
+    if let Some(authorship) = report.attribution.authorship {
+        out.push_str(&format!("{} {}\n", "Authorship:".bold(), authorship));
+    }
+    if let Some(stripped) = vibecheck_core::output::comment_free_summary(report) {
+        out.push_str(&format!("{} {}\n", "Without comments:".bold(), stripped));
+    }
+    if let Some(verdicts) = vibecheck_core::output::ensemble_verdicts(report) {
+        out.push_str(&format!("{} {}", "Backends:".bold(), verdicts));
+        if report.needs_review() {
+            out.push_str(&format!(" {}", "— backends disagree; review manually".yellow().bold()));
+        }
+        out.push('\n');
+    }
+    if let Some(ref era) = report.attribution.era {
+        out.push_str(&format!("{} {}\n", "Era:".bold(), era));
+    }
+    if !report.metadata.degraded.is_empty() {
+        let caps: Vec<String> = report.metadata.degraded.iter().map(|c| c.to_string()).collect();
+        out.push_str(&format!(
+            "{} {}\n",
+            "Degraded:".yellow().bold(),
+            format!("{} unavailable", caps.join(", ")).yellow()
+        ));
+    }
     out.push_str(&format!(
         "{} {} | {} {}\n",
         "Lines:".dimmed(),
@@ -40,6 +73,22 @@ pub fn format_pretty(report: &Report, theme: &dyn ColorTheme) -> String {
         report.metadata.signal_count,
     ));
 
+    if let Some(ref segments) = report.segments {
+        out.push_str(&format!("\n{}\n", "Segments:".bold()));
+        if segments.len() < 2 {
+            out.push_str(&format!("  {}\n", "uniform style".dimmed()));
+        } else {
+            for seg in segments {
+                let verdict = format!("{} ({:.0}%)", seg.attribution.primary, seg.attribution.confidence * 100.0);
+                out.push_str(&format!(
+                    "  {}  {}\n",
+                    format!("L{}–{}", seg.start_line, seg.end_line).dimmed(),
+                    verdict.color(theme.terminal_color(seg.attribution.primary))
+                ));
+            }
+        }
+    }
+
     out.push_str(&format!("\n{}\n", "Scores:".bold()));
     let mut sorted_scores: Vec<_> = report.attribution.scores.iter().collect();
     sorted_scores.sort_by(|a, b| b.1.partial_cmp(a.1).unwrap().then_with(|| a.0.to_string().cmp(&b.0.to_string())));
@@ -67,19 +116,49 @@ pub fn format_pretty(report: &Report, theme: &dyn ColorTheme) -> String {
                 weight_str.red()
             };
             out.push_str(&format!(
-                "  {} {} {} — {}\n",
+                "  {} {} {} — {}",
                 format!("[{}]", signal.source).dimmed(),
                 colored_weight,
                 signal.family.to_string().bold(),
                 signal.description,
             ));
+            if let Some(lines) = signal.lines_label() {
+                out.push_str(&format!(" {}", format!("({lines})").dimmed()));
+            }
+            match signal.severity {
+                Severity::Error => out.push_str(&format!(" {}", "error".red().bold())),
+                Severity::Warn => out.push_str(&format!(" {}", "warn".yellow())),
+                Severity::Info => {}
+            }
+            out.push('\n');
         }
     }
 
     out
 }
 
-pub use vibecheck_core::output::{format_json, format_text};
+/// Format the remediation suggestions for a report with terminal colors.
+///
+/// Returns an empty string when no AI-attributed finding has a suggestion.
+pub fn format_remediation_pretty(report: &Report) -> String {
+    let fixes = vibecheck_core::remediation::for_report(report);
+    if fixes.is_empty() {
+        return String::new();
+    }
+    let mut out = format!("\n{}\n", "Remediation:".bold());
+    for fix in &fixes {
+        out.push_str(&format!(
+            "  {} {}\n",
+            format!("[{}]", fix.category).dimmed(),
+            fix.suggestion,
+        ));
+    }
+    out
+}
+
+pub use vibecheck_core::output::{
+    format_json, format_json_with_remediation, format_remediation_text, format_text, DEFAULT_JUNIT_THRESHOLD,
+};
 
 #[cfg(test)]
 mod tests {
@@ -121,6 +200,24 @@ mod tests {
         }
     }
 
+    #[test]
+    fn format_remediation_pretty_lists_categories() {
+        use vibecheck_core::report::{ModelFamily, Signal};
+        let mut report = vibecheck_core::analyze("fn main() {}");
+        report.signals = vec![Signal::new(
+            "rust.comments.step_numbered",
+            "comments",
+            "3+ step-numbered comments",
+            ModelFamily::Gpt,
+            1.5,
+        )];
+        let output = format_remediation_pretty(&report);
+        assert!(output.contains("Remediation:"));
+        assert!(output.contains("[comments]"));
+        report.signals.clear();
+        assert!(format_remediation_pretty(&report).is_empty());
+    }
+
     #[test]
     fn format_pretty_insufficient_data() {
         let report = vibecheck_core::analyze("");
@@ -0,0 +1,60 @@
+//! The policy profile for this run, and loading `.vibecheck` under it.
+//!
+//! `--profile` (or `VIBECHECK_PROFILE`) overrides `[policy] profile` in
+//! every config a command loads, including the per-directory ones behind
+//! each analyzed file.  It is recorded once, before any command starts, and
+//! handed to the library explicitly from then on.
+//!
+//! The loaders here also print the settings the library ignored while
+//! loading, which it only records.
+
+use std::path::Path;
+use std::sync::OnceLock;
+
+use anyhow::Result;
+use vibecheck_core::ignore_rules::IgnoreConfig;
+use vibecheck_core::FileOptions;
+
+/// Environment variable naming a profile when `--profile` is not given.
+pub const PROFILE_ENV: &str = "VIBECHECK_PROFILE";
+
+static PROFILE: OnceLock<Option<String>> = OnceLock::new();
+
+/// Record the run's profile: `flag` if given, else [`PROFILE_ENV`].
+pub fn init(flag: Option<String>) {
+    let profile = flag.or_else(|| std::env::var(PROFILE_ENV).ok().filter(|p| !p.is_empty()));
+    let _ = PROFILE.set(profile);
+}
+
+/// The profile recorded by [`init`], if any.
+pub fn profile() -> Option<&'static str> {
+    PROFILE.get().and_then(Option::as_deref)
+}
+
+/// [`IgnoreConfig::load`] under the run's profile.
+pub fn load(start: &Path) -> IgnoreConfig {
+    warn(IgnoreConfig::load_with_profile(start, profile()))
+}
+
+/// [`IgnoreConfig::from_file`] under the run's profile.
+pub fn from_file(path: &Path) -> Result<IgnoreConfig> {
+    IgnoreConfig::from_file_with_profile(path, profile()).map(warn)
+}
+
+/// [`IgnoreConfig::load_untrusted`] under the run's profile.
+pub fn load_untrusted(root: &Path) -> Result<IgnoreConfig> {
+    IgnoreConfig::load_untrusted_with_profile(root, profile()).map(warn)
+}
+
+/// Print the warnings collected while loading `config`.
+fn warn(config: IgnoreConfig) -> IgnoreConfig {
+    for warning in config.warnings() {
+        eprintln!("vibecheck: warning: {warning}");
+    }
+    config
+}
+
+/// Options for the library's per-file functions under the run's profile.
+pub fn file_options() -> FileOptions<'static> {
+    FileOptions { profile: profile(), ..FileOptions::default() }
+}
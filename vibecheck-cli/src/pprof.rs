@@ -0,0 +1,233 @@
+//! Minimal writer for the pprof profile format (`profile.proto` from
+//! github.com/google/pprof), enough for `go tool pprof` and speedscope to
+//! read the per-detector samples `vibecheck bench --pprof` records.
+
+use std::collections::HashMap;
+use std::io::Write;
+use std::path::Path;
+
+use anyhow::{Context, Result};
+
+/// A profile under construction.  Every frame is its own function and
+/// location; stacks are given leaf first, as in pprof.
+pub struct Profile {
+    strings: Vec<String>,
+    string_ids: HashMap<String, i64>,
+    sample_types: Vec<(i64, i64)>,
+    /// Function names, by id − 1.
+    functions: Vec<i64>,
+    function_ids: HashMap<String, u64>,
+    samples: Vec<(Vec<u64>, Vec<i64>)>,
+    duration_nanos: i64,
+}
+
+impl Profile {
+    /// An empty profile whose samples carry one value per `(type, unit)`,
+    /// e.g. `("wall", "nanoseconds")`.
+    pub fn new(sample_types: &[(&str, &str)]) -> Self {
+        let mut profile = Self {
+            strings: Vec::new(),
+            string_ids: HashMap::new(),
+            sample_types: Vec::new(),
+            functions: Vec::new(),
+            function_ids: HashMap::new(),
+            samples: Vec::new(),
+            duration_nanos: 0,
+        };
+        // The string table must start with "".
+        profile.string("");
+        profile.sample_types = sample_types.iter().map(|(t, u)| (profile.string(t), profile.string(u))).collect();
+        profile
+    }
+
+    fn string(&mut self, s: &str) -> i64 {
+        if let Some(&id) = self.string_ids.get(s) {
+            return id;
+        }
+        let id = self.strings.len() as i64;
+        self.strings.push(s.to_string());
+        self.string_ids.insert(s.to_string(), id);
+        id
+    }
+
+    fn function(&mut self, name: &str) -> u64 {
+        if let Some(&id) = self.function_ids.get(name) {
+            return id;
+        }
+        let name_id = self.string(name);
+        self.functions.push(name_id);
+        let id = self.functions.len() as u64;
+        self.function_ids.insert(name.to_string(), id);
+        id
+    }
+
+    /// Record one sample of `stack` (leaf first) with one value per sample type.
+    pub fn add(&mut self, stack: &[&str], values: &[i64]) {
+        debug_assert_eq!(values.len(), self.sample_types.len());
+        let locations = stack.iter().map(|frame| self.function(frame)).collect();
+        self.samples.push((locations, values.to_vec()));
+    }
+
+    /// Wall-clock time the profile covers.
+    pub fn set_duration(&mut self, duration: std::time::Duration) {
+        self.duration_nanos = i64::try_from(duration.as_nanos()).unwrap_or(i64::MAX);
+    }
+
+    /// The serialized, uncompressed `Profile` message.
+    pub fn encode(&self) -> Vec<u8> {
+        let mut out = Vec::new();
+        for &(ty, unit) in &self.sample_types {
+            message(&mut out, 1, &value_type(ty, unit));
+        }
+        for (locations, values) in &self.samples {
+            let mut sample = Vec::new();
+            packed(&mut sample, 1, locations.iter().copied());
+            packed(&mut sample, 2, values.iter().map(|&v| v as u64));
+            message(&mut out, 2, &sample);
+        }
+        for id in 1..=self.functions.len() as u64 {
+            let mut line = Vec::new();
+            varint_field(&mut line, 1, id);
+            let mut location = Vec::new();
+            varint_field(&mut location, 1, id);
+            message(&mut location, 4, &line);
+            message(&mut out, 4, &location);
+        }
+        for (id, &name) in (1u64..).zip(&self.functions) {
+            let mut function = Vec::new();
+            varint_field(&mut function, 1, id);
+            varint_field(&mut function, 2, name as u64);
+            varint_field(&mut function, 3, name as u64);
+            message(&mut out, 5, &function);
+        }
+        for s in &self.strings {
+            message(&mut out, 6, s.as_bytes());
+        }
+        varint_field(&mut out, 10, self.duration_nanos as u64);
+        if let Some(&(ty, unit)) = self.sample_types.first() {
+            message(&mut out, 11, &value_type(ty, unit));
+            varint_field(&mut out, 12, 1);
+        }
+        out
+    }
+
+    /// Write the gzip-compressed profile to `path`, as pprof tools expect.
+    pub fn write(&self, path: &Path) -> Result<()> {
+        let file = std::fs::File::create(path).with_context(|| format!("failed to create {}", path.display()))?;
+        let mut gz = flate2::write::GzEncoder::new(file, flate2::Compression::default());
+        gz.write_all(&self.encode())?;
+        gz.finish().with_context(|| format!("failed to write {}", path.display()))?;
+        Ok(())
+    }
+}
+
+fn value_type(ty: i64, unit: i64) -> Vec<u8> {
+    let mut out = Vec::new();
+    varint_field(&mut out, 1, ty as u64);
+    varint_field(&mut out, 2, unit as u64);
+    out
+}
+
+fn varint(out: &mut Vec<u8>, mut v: u64) {
+    while v >= 0x80 {
+        out.push(v as u8 | 0x80);
+        v >>= 7;
+    }
+    out.push(v as u8);
+}
+
+fn varint_field(out: &mut Vec<u8>, field: u64, v: u64) {
+    if v != 0 {
+        varint(out, field << 3);
+        varint(out, v);
+    }
+}
+
+/// A length-delimited field: an embedded message, string or packed array.
+fn message(out: &mut Vec<u8>, field: u64, bytes: &[u8]) {
+    varint(out, field << 3 | 2);
+    varint(out, bytes.len() as u64);
+    out.extend_from_slice(bytes);
+}
+
+fn packed(out: &mut Vec<u8>, field: u64, values: impl Iterator<Item = u64>) {
+    let mut bytes = Vec::new();
+    for v in values {
+        varint(&mut bytes, v);
+    }
+    if !bytes.is_empty() {
+        message(out, field, &bytes);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::io::Read;
+
+    /// `(field, varint value or length-delimited bytes)` of one message level.
+    fn fields(mut bytes: &[u8]) -> Vec<(u64, Result<u64, Vec<u8>>)> {
+        fn read_varint(bytes: &mut &[u8]) -> u64 {
+            let mut v = 0;
+            for shift in (0..).step_by(7) {
+                let b = bytes[0];
+                *bytes = &bytes[1..];
+                v |= u64::from(b & 0x7f) << shift;
+                if b & 0x80 == 0 {
+                    break;
+                }
+            }
+            v
+        }
+        let mut out = Vec::new();
+        while !bytes.is_empty() {
+            let key = read_varint(&mut bytes);
+            let value = match key & 7 {
+                0 => Ok(read_varint(&mut bytes)),
+                2 => {
+                    let len = read_varint(&mut bytes) as usize;
+                    let (body, rest) = bytes.split_at(len);
+                    bytes = rest;
+                    Err(body.to_vec())
+                }
+                wire => panic!("unexpected wire type {wire}"),
+            };
+            out.push((key >> 3, value));
+        }
+        out
+    }
+
+    #[test]
+    fn encodes_samples_functions_and_string_table() {
+        let mut profile = Profile::new(&[("wall", "nanoseconds"), ("alloc_space", "bytes")]);
+        profile.add(&["filler", "a.rs"], &[1500, 64]);
+        profile.add(&["filler", "b.rs"], &[300, 0]);
+        let top = fields(&profile.encode());
+
+        let strings: Vec<String> = top
+            .iter()
+            .filter(|(f, _)| *f == 6)
+            .map(|(_, v)| String::from_utf8(v.clone().unwrap_err()).unwrap())
+            .collect();
+        assert_eq!(strings, ["", "wall", "nanoseconds", "alloc_space", "bytes", "filler", "a.rs", "b.rs"]);
+        assert_eq!(top.iter().filter(|(f, _)| *f == 5).count(), 3, "one function per distinct frame");
+
+        let samples: Vec<_> = top.iter().filter(|(f, _)| *f == 2).map(|(_, v)| fields(v.as_ref().unwrap_err())).collect();
+        assert_eq!(samples.len(), 2);
+        assert_eq!(samples[0][0], (1, Err(vec![1, 2])), "location ids, leaf first");
+        assert_eq!(samples[0][1], (2, Err(vec![0xdc, 0x0b, 64])), "values as varints");
+    }
+
+    #[test]
+    fn write_gzips_the_encoded_profile() {
+        let mut profile = Profile::new(&[("wall", "nanoseconds")]);
+        profile.add(&["parse"], &[42]);
+        let dir = tempfile::tempdir().unwrap();
+        let path = dir.path().join("bench.pb.gz");
+        profile.write(&path).unwrap();
+
+        let mut decoded = Vec::new();
+        flate2::read::GzDecoder::new(std::fs::File::open(&path).unwrap()).read_to_end(&mut decoded).unwrap();
+        assert_eq!(decoded, profile.encode());
+    }
+}
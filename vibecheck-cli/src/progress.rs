@@ -0,0 +1,88 @@
+use std::io::{IsTerminal, Write};
+use std::path::Path;
+
+/// Width of the `[####    ]` bar, brackets excluded.
+const BAR_WIDTH: usize = 24;
+
+/// A one-line progress bar on stderr for long scans.
+///
+/// Drawn only when stderr is a terminal, so CI logs and redirected output
+/// stay clean; otherwise every method is a no-op.
+pub struct Progress {
+    total: usize,
+    enabled: bool,
+}
+
+impl Progress {
+    /// A bar for `total` files.  Single-file runs finish too fast to need one.
+    pub fn stderr(total: usize) -> Self {
+        Self { total, enabled: total > 1 && std::io::stderr().is_terminal() }
+    }
+
+    /// A bar that is never drawn, for `--quiet` runs.
+    pub fn hidden() -> Self {
+        Self { total: 0, enabled: false }
+    }
+
+    /// Redraw with `done` files finished and `current` being analyzed.
+    pub fn update(&self, done: usize, current: &Path) {
+        if !self.enabled {
+            return;
+        }
+        let columns = crossterm::terminal::size().map(|(w, _)| w as usize).unwrap_or(80);
+        let line = render(done, self.total, current, columns);
+        let mut err = std::io::stderr().lock();
+        let _ = write!(err, "\r\x1b[2K{line}");
+        let _ = err.flush();
+    }
+
+    /// Erase the bar so the report starts on a clean line.
+    pub fn finish(&self) {
+        if self.enabled {
+            let _ = write!(std::io::stderr(), "\r\x1b[2K");
+        }
+    }
+}
+
+/// `[#########               ] 12/48 src/handler.go`, with the path
+/// shortened from the left so the line fits in `columns`.
+fn render(done: usize, total: usize, current: &Path, columns: usize) -> String {
+    let filled = (done * BAR_WIDTH).checked_div(total).unwrap_or(0).min(BAR_WIDTH);
+    let head = format!(
+        "[{}{}] {done}/{total} ",
+        "#".repeat(filled),
+        " ".repeat(BAR_WIDTH - filled)
+    );
+    let room = columns.saturating_sub(head.chars().count() + 1);
+    let path = current.display().to_string();
+    let count = path.chars().count();
+    let path = if count <= room {
+        path
+    } else if room > 1 {
+        let tail: String = path.chars().skip(count - (room - 1)).collect();
+        format!("…{tail}")
+    } else {
+        String::new()
+    };
+    format!("{head}{path}")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn render_fills_bar_in_proportion() {
+        let line = render(12, 48, Path::new("src/a.rs"), 120);
+        assert_eq!(line, format!("[{}{}] 12/48 src/a.rs", "#".repeat(6), " ".repeat(18)));
+        assert!(render(0, 0, Path::new("a.rs"), 80).starts_with(&format!("[{}]", " ".repeat(BAR_WIDTH))));
+    }
+
+    #[test]
+    fn render_shortens_long_paths_from_the_left() {
+        let line = render(1, 2, Path::new("very/deeply/nested/module/handler.go"), 50);
+        assert_eq!(line.chars().count(), 49);
+        assert!(line.ends_with("handler.go"));
+        assert!(line.contains('…'));
+    }
+}
@@ -0,0 +1,266 @@
+//! LLM provider clients for `vibecheck corpus generate`.
+//!
+//! Each model family maps to one chat API — Anthropic Messages, OpenAI Chat
+//! Completions or Gemini `generateContent` — with a default model and API-key
+//! variable that a `[providers.<family>]` table in `.vibecheck` can override.
+//! Copilot has no public completion API; configure an OpenAI-compatible
+//! endpoint for it to generate Copilot samples.
+
+use std::time::Duration;
+
+use anyhow::{bail, Context, Result};
+use serde_json::{json, Value};
+
+use vibecheck_core::ignore_rules::ProviderSettings;
+use vibecheck_core::report::ModelFamily;
+
+/// Completions can take a while for a few hundred lines of code.
+const REQUEST_TIMEOUT: Duration = Duration::from_secs(180);
+
+const MAX_TOKENS: u32 = 4096;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Api {
+    Anthropic,
+    OpenAi,
+    Gemini,
+}
+
+impl Api {
+    fn parse(name: &str) -> Result<Self> {
+        match name.to_lowercase().as_str() {
+            "anthropic" => Ok(Api::Anthropic),
+            "openai" => Ok(Api::OpenAi),
+            "gemini" => Ok(Api::Gemini),
+            other => bail!("unknown provider api: {other} (expected anthropic, openai, or gemini)"),
+        }
+    }
+
+    fn default_base_url(self) -> &'static str {
+        match self {
+            Api::Anthropic => "https://api.anthropic.com/v1",
+            Api::OpenAi => "https://api.openai.com/v1",
+            Api::Gemini => "https://generativelanguage.googleapis.com/v1beta",
+        }
+    }
+}
+
+/// A resolved provider: where to send a prompt for one family.
+#[derive(Debug, Clone, PartialEq)]
+pub struct Provider {
+    pub api: Api,
+    pub model: String,
+    pub api_key_env: String,
+    pub base_url: String,
+}
+
+/// A completion and the model version that actually served it.
+pub struct Completion {
+    pub text: String,
+    pub model: String,
+}
+
+impl Provider {
+    /// The provider for `family`: its built-in defaults with `settings`
+    /// applied on top.
+    pub fn for_family(family: ModelFamily, settings: Option<&ProviderSettings>) -> Result<Self> {
+        let defaults = match family {
+            ModelFamily::Claude => Some((Api::Anthropic, "claude-sonnet-4-5", "ANTHROPIC_API_KEY")),
+            ModelFamily::Gpt => Some((Api::OpenAi, "gpt-4o", "OPENAI_API_KEY")),
+            ModelFamily::Gemini => Some((Api::Gemini, "gemini-2.5-flash", "GEMINI_API_KEY")),
+            ModelFamily::Copilot => None,
+            ModelFamily::Human => bail!("human samples cannot be generated"),
+        };
+        let settings = settings.cloned().unwrap_or_default();
+        let api = match (&settings.api, defaults) {
+            (Some(name), _) => Api::parse(name)?,
+            (None, Some((api, ..))) => api,
+            (None, None) => bail!(
+                "no provider for {family}: add a [providers.{}] table with an `api` to .vibecheck",
+                family.to_string().to_lowercase()
+            ),
+        };
+        // A family's default model and key only make sense with its own API.
+        let defaults = defaults.filter(|(default_api, ..)| *default_api == api);
+        let model = settings
+            .model
+            .or_else(|| defaults.map(|(_, model, _)| model.to_string()))
+            .with_context(|| format!("no model configured for {family}"))?;
+        let api_key_env = settings
+            .api_key_env
+            .or_else(|| defaults.map(|(.., key)| key.to_string()))
+            .with_context(|| format!("no api_key_env configured for {family}"))?;
+        let base_url = settings.base_url.unwrap_or_else(|| api.default_base_url().to_string());
+        Ok(Provider { api, model, api_key_env, base_url: base_url.trim_end_matches('/').to_string() })
+    }
+
+    /// Whether the API accepts a sampling seed.  The Anthropic Messages API
+    /// does not, so its replies vary between runs whatever the seed.
+    pub fn supports_seed(&self) -> bool {
+        self.api != Api::Anthropic
+    }
+
+    /// Send `prompt` and return the reply, sampled with `seed` where the
+    /// API supports one.  Providers treat seeds as best effort: the same
+    /// seed usually, but not always, reproduces a reply.
+    pub fn complete(&self, client: &reqwest::blocking::Client, prompt: &str, seed: Option<u32>) -> Result<Completion> {
+        let key = std::env::var(&self.api_key_env)
+            .with_context(|| format!("{} is not set", self.api_key_env))?;
+        let (url, body) = self.request(prompt, seed);
+        let request = match self.api {
+            Api::Anthropic => client
+                .post(url)
+                .header("x-api-key", key)
+                .header("anthropic-version", "2023-06-01"),
+            Api::OpenAi => client.post(url).bearer_auth(key),
+            Api::Gemini => client.post(url).header("x-goog-api-key", key),
+        };
+        let response = request
+            .timeout(REQUEST_TIMEOUT)
+            .json(&body)
+            .send()
+            .with_context(|| format!("request to {} failed", self.base_url))?;
+        let status = response.status();
+        let reply: Value = response.json().context("provider returned invalid JSON")?;
+        if !status.is_success() {
+            let message = reply.pointer("/error/message").and_then(Value::as_str).unwrap_or("no error message");
+            bail!("{} returned {status}: {message}", self.model);
+        }
+        self.parse(&reply)
+    }
+
+    /// Endpoint and JSON body for `prompt`.
+    fn request(&self, prompt: &str, seed: Option<u32>) -> (String, Value) {
+        let (url, mut body) = match self.api {
+            Api::Anthropic => (
+                format!("{}/messages", self.base_url),
+                json!({
+                    "model": self.model,
+                    "max_tokens": MAX_TOKENS,
+                    "messages": [{ "role": "user", "content": prompt }],
+                }),
+            ),
+            Api::OpenAi => (
+                format!("{}/chat/completions", self.base_url),
+                json!({
+                    "model": self.model,
+                    "messages": [{ "role": "user", "content": prompt }],
+                }),
+            ),
+            Api::Gemini => (
+                format!("{}/models/{}:generateContent", self.base_url, self.model),
+                json!({
+                    "contents": [{ "role": "user", "parts": [{ "text": prompt }] }],
+                    "generationConfig": { "maxOutputTokens": MAX_TOKENS },
+                }),
+            ),
+        };
+        match (self.api, seed) {
+            (Api::OpenAi, Some(seed)) => body["seed"] = seed.into(),
+            // Gemini's seed is an int32.
+            (Api::Gemini, Some(seed)) => body["generationConfig"]["seed"] = (seed & i32::MAX as u32).into(),
+            _ => {}
+        }
+        (url, body)
+    }
+
+    fn parse(&self, reply: &Value) -> Result<Completion> {
+        let (text, model) = match self.api {
+            Api::Anthropic => (reply.pointer("/content/0/text"), reply.get("model")),
+            Api::OpenAi => (reply.pointer("/choices/0/message/content"), reply.get("model")),
+            Api::Gemini => (reply.pointer("/candidates/0/content/parts/0/text"), reply.get("modelVersion")),
+        };
+        let text = text.and_then(Value::as_str).context("provider reply has no text")?;
+        let model = model.and_then(Value::as_str).unwrap_or(&self.model);
+        Ok(Completion { text: text.to_string(), model: model.to_string() })
+    }
+}
+
+/// The prompt asking for one self-contained implementation of `task`.
+/// Deliberately plain: any style instruction would leak into the sample.
+pub fn prompt(task: &str, language: &str) -> String {
+    format!(
+        "Write a complete, self-contained implementation of the following in {language}: {task}.\n\
+         Reply with the source file only, in a single fenced code block."
+    )
+}
+
+/// The contents of the first fenced code block in `reply`, or the whole
+/// reply when it has none.
+pub fn extract_code(reply: &str) -> String {
+    let mut lines = reply.lines().skip_while(|l| !l.trim_start().starts_with("```"));
+    if lines.next().is_none() {
+        return format!("{}\n", reply.trim());
+    }
+    let mut code = String::new();
+    for line in lines.take_while(|l| !l.trim_start().starts_with("```")) {
+        code.push_str(line);
+        code.push('\n');
+    }
+    code
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn families_resolve_to_their_default_apis() {
+        let claude = Provider::for_family(ModelFamily::Claude, None).unwrap();
+        assert_eq!((claude.api, claude.api_key_env.as_str()), (Api::Anthropic, "ANTHROPIC_API_KEY"));
+        assert!(Provider::for_family(ModelFamily::Copilot, None).is_err());
+        assert!(Provider::for_family(ModelFamily::Human, None).is_err());
+    }
+
+    #[test]
+    fn settings_override_defaults() {
+        let settings = ProviderSettings {
+            api: Some("openai".into()),
+            model: Some("openai/gpt-4.1".into()),
+            api_key_env: Some("GITHUB_TOKEN".into()),
+            base_url: Some("https://models.github.ai/inference/".into()),
+        };
+        let copilot = Provider::for_family(ModelFamily::Copilot, Some(&settings)).unwrap();
+        let (url, body) = copilot.request("hi", None);
+        assert_eq!(url, "https://models.github.ai/inference/chat/completions");
+        assert_eq!(body["model"], "openai/gpt-4.1");
+
+        // Switching Claude to another API drops the Anthropic model default.
+        let switched = ProviderSettings { api: Some("openai".into()), ..Default::default() };
+        assert!(Provider::for_family(ModelFamily::Claude, Some(&switched)).is_err());
+    }
+
+    #[test]
+    fn replies_are_parsed_per_api() {
+        let gemini = Provider::for_family(ModelFamily::Gemini, None).unwrap();
+        let (url, _) = gemini.request("hi", None);
+        assert!(url.ends_with("/models/gemini-2.5-flash:generateContent"), "{url}");
+        let reply = json!({
+            "candidates": [{ "content": { "parts": [{ "text": "```go\npackage lru\n```" }] } }],
+            "modelVersion": "gemini-2.5-flash-001",
+        });
+        let completion = gemini.parse(&reply).unwrap();
+        assert_eq!(completion.model, "gemini-2.5-flash-001");
+        assert_eq!(extract_code(&completion.text), "package lru\n");
+    }
+
+    #[test]
+    fn seeds_are_sent_where_the_api_takes_one() {
+        let gpt = Provider::for_family(ModelFamily::Gpt, None).unwrap();
+        assert_eq!(gpt.request("hi", Some(7)).1["seed"], 7);
+        assert!(gpt.request("hi", None).1.get("seed").is_none());
+
+        let gemini = Provider::for_family(ModelFamily::Gemini, None).unwrap();
+        assert_eq!(gemini.request("hi", Some(u32::MAX)).1["generationConfig"]["seed"], i32::MAX);
+
+        let claude = Provider::for_family(ModelFamily::Claude, None).unwrap();
+        assert!(!claude.supports_seed());
+        assert!(claude.request("hi", Some(7)).1.get("seed").is_none());
+    }
+
+    #[test]
+    fn extract_code_falls_back_to_whole_reply() {
+        assert_eq!(extract_code("def f(): pass\n\n"), "def f(): pass\n");
+        assert_eq!(extract_code("Here you go:\n```rust\nfn f() {}\n\nfn g() {}\n```\nEnjoy!"), "fn f() {}\n\nfn g() {}\n");
+    }
+}
@@ -0,0 +1,155 @@
+//! Hot reloading of the `.vibecheck` config for the long-running `serve`
+//! and `bot` commands.
+//!
+//! The config's directory is watched for changes to `.vibecheck` (or the
+//! `--ignore-file`) and `.vibecheckignore`.  On a change the config is
+//! parsed again and handed to the command, which swaps its state behind a
+//! [`Reloadable`]: requests already in flight keep the state they started
+//! with, later ones see the new one.  A config that fails to parse is
+//! reported and the previous one stays in effect.
+
+use std::path::{Path, PathBuf};
+use std::sync::{mpsc, Arc, RwLock};
+use std::time::Duration;
+
+use anyhow::Result;
+use notify::{Config, RecommendedWatcher, RecursiveMode, Watcher};
+
+use vibecheck_core::ignore_rules::{IgnoreConfig, IGNORE_FILE_NAME};
+
+use crate::policy;
+
+/// Editors write a file in several steps; wait this long after the last
+/// event before reloading.
+const DEBOUNCE: Duration = Duration::from_millis(300);
+
+/// A value swapped wholesale on reload.  [`get`](Reloadable::get) hands out
+/// the current value, which stays valid however many reloads follow.
+pub struct Reloadable<T> {
+    current: RwLock<Arc<T>>,
+}
+
+impl<T> Reloadable<T> {
+    pub fn new(value: T) -> Self {
+        Self { current: RwLock::new(Arc::new(value)) }
+    }
+
+    pub fn get(&self) -> Arc<T> {
+        Arc::clone(&self.current.read().unwrap())
+    }
+
+    pub fn set(&self, value: T) {
+        *self.current.write().unwrap() = Arc::new(value);
+    }
+}
+
+/// Where a command's config comes from: an explicit `--ignore-file`, or
+/// the `.vibecheck` discovered from a directory.
+#[derive(Debug, Clone)]
+pub struct ConfigSource {
+    explicit: Option<PathBuf>,
+    root: PathBuf,
+}
+
+impl ConfigSource {
+    /// `ignore_file`, or discovery from the current directory.
+    pub fn new(ignore_file: Option<&PathBuf>) -> Result<Self> {
+        Ok(match ignore_file {
+            Some(f) => Self { explicit: Some(f.clone()), root: f.parent().unwrap_or(Path::new(".")).to_path_buf() },
+            None => Self { explicit: None, root: policy::load(&std::env::current_dir()?).root().to_path_buf() },
+        })
+    }
+
+    /// Read the config.  An explicit file must parse; a discovered
+    /// `.vibecheck` must parse if it exists, and defaults apply otherwise.
+    pub fn load(&self) -> Result<IgnoreConfig> {
+        let discovered = self.root.join(".vibecheck");
+        match &self.explicit {
+            Some(f) => policy::from_file(f),
+            None if discovered.is_file() => policy::from_file(&discovered),
+            None => Ok(policy::load(&self.root)),
+        }
+    }
+
+    /// Whether a change to `path` can change the loaded config.
+    fn affects(&self, path: &Path) -> bool {
+        let config = self.explicit.as_deref().and_then(Path::file_name).unwrap_or(".vibecheck".as_ref());
+        path.file_name().is_some_and(|name| name == config || name == IGNORE_FILE_NAME)
+    }
+}
+
+/// Watch `source` in a background thread and call `on_reload` with each
+/// config that parses after a change.
+pub fn watch(source: ConfigSource, on_reload: impl Fn(IgnoreConfig) + Send + 'static) -> Result<()> {
+    let (tx, rx) = mpsc::channel();
+    let mut watcher = RecommendedWatcher::new(tx, Config::default())?;
+    // The directory rather than the file: editors and config management
+    // replace files by renaming over them, which ends a watch on the file.
+    let dir = if source.root.as_os_str().is_empty() { Path::new(".") } else { source.root.as_path() };
+    watcher.watch(dir, RecursiveMode::NonRecursive)?;
+    std::thread::spawn(move || {
+        let _watcher = watcher;
+        loop {
+            match rx.recv() {
+                Ok(Ok(event)) if event.paths.iter().any(|p| source.affects(p)) => {}
+                Ok(Ok(_)) => continue,
+                Ok(Err(e)) => {
+                    eprintln!("vibecheck: config watch error: {e}");
+                    continue;
+                }
+                Err(_) => return,
+            }
+            while rx.recv_timeout(DEBOUNCE).is_ok() {}
+            match source.load() {
+                Ok(config) => {
+                    on_reload(config);
+                    eprintln!("vibecheck: reloaded config from {}", source.root.display());
+                }
+                Err(e) => eprintln!("vibecheck: keeping the previous config: {e:#}"),
+            }
+        }
+    });
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn readers_keep_the_value_they_started_with() {
+        let value = Reloadable::new(1);
+        let in_flight = value.get();
+        value.set(2);
+        assert_eq!((*in_flight, *value.get()), (1, 2));
+    }
+
+    #[test]
+    fn source_tracks_the_config_and_ignore_files() {
+        let dir = tempfile::tempdir().unwrap();
+        let file = dir.path().join("ci.toml");
+        std::fs::write(&file, "[heuristics]\n").unwrap();
+        let source = ConfigSource::new(Some(&file)).unwrap();
+        assert!(source.affects(&file));
+        assert!(source.affects(&dir.path().join(IGNORE_FILE_NAME)));
+        assert!(!source.affects(&dir.path().join(".vibecheck")));
+        assert!(source.load().is_ok());
+
+        std::fs::write(&file, "[heuristics\n").unwrap();
+        assert!(source.load().is_err(), "a broken config is not loaded");
+    }
+
+    #[test]
+    fn watch_reloads_on_change() {
+        let dir = tempfile::tempdir().unwrap();
+        let file = dir.path().join(".vibecheck");
+        std::fs::write(&file, "").unwrap();
+        let (tx, rx) = mpsc::channel();
+        watch(ConfigSource::new(Some(&file)).unwrap(), move |config| {
+            let _ = tx.send(config.heuristics_map().len());
+        })
+        .unwrap();
+        std::fs::write(&file, "[heuristics]\n\"rust.errors.zero_unwrap\" = 0.0\n").unwrap();
+        assert_eq!(rx.recv_timeout(Duration::from_secs(10)).unwrap(), 1);
+    }
+}
@@ -0,0 +1,181 @@
+use std::time::Duration;
+
+use vibecheck_core::cache::{self, CacheStats};
+use vibecheck_core::capability::{self, CapabilityStatus};
+
+/// Resource usage for one scan, printed by `--stats` so operators can size
+/// CI runners for large repositories.
+pub struct ScanStats {
+    files: usize,
+    phases: Vec<(&'static str, Duration)>,
+    cache: CacheStats,
+    cpu_time: Option<Duration>,
+    peak_rss_bytes: Option<u64>,
+    capabilities: Vec<CapabilityStatus>,
+}
+
+impl ScanStats {
+    /// Sample process-wide counters at the end of a scan. `phases` are the
+    /// wall-clock timings the caller measured, in order.
+    pub fn capture(files: usize, phases: Vec<(&'static str, Duration)>) -> Self {
+        Self {
+            files,
+            phases,
+            cache: cache::stats(),
+            cpu_time: cpu_time(),
+            peak_rss_bytes: peak_rss_bytes(),
+            capabilities: capability::probe(),
+        }
+    }
+
+    pub fn render_text(&self) -> String {
+        let mut out = format!("Scan stats ({} file{}):\n", self.files, if self.files == 1 { "" } else { "s" });
+        for (name, d) in &self.phases {
+            out.push_str(&format!("  {:<16} {} ms\n", name, d.as_millis()));
+        }
+        let lookups = self.cache.hits + self.cache.misses;
+        match self.cache.hit_rate() {
+            Some(rate) => out.push_str(&format!(
+                "  {:<16} {:.0}% ({} of {} lookups)\n",
+                "cache hit rate",
+                rate * 100.0,
+                self.cache.hits,
+                lookups
+            )),
+            None => out.push_str(&format!("  {:<16} n/a (cache not used)\n", "cache hit rate")),
+        }
+        match self.cpu_time {
+            Some(d) => out.push_str(&format!("  {:<16} {:.2} s\n", "CPU time", d.as_secs_f64())),
+            None => out.push_str(&format!("  {:<16} n/a\n", "CPU time")),
+        }
+        match self.peak_rss_bytes {
+            Some(b) => out.push_str(&format!("  {:<16} {:.1} MiB\n", "peak RSS", b as f64 / (1024.0 * 1024.0))),
+            None => out.push_str(&format!("  {:<16} n/a\n", "peak RSS")),
+        }
+        out.push_str("Capabilities:\n");
+        for c in &self.capabilities {
+            out.push_str(&format!(
+                "  {:<16} {:<9} {}\n",
+                c.capability.to_string(),
+                if c.available { "available" } else { "disabled" },
+                c.detail
+            ));
+        }
+        out
+    }
+
+    pub fn to_json(&self) -> serde_json::Value {
+        let phases: serde_json::Map<String, serde_json::Value> = self
+            .phases
+            .iter()
+            .map(|(name, d)| (name.to_string(), (d.as_millis() as u64).into()))
+            .collect();
+        serde_json::json!({
+            "files": self.files,
+            "phases_ms": phases,
+            "cache": {
+                "hits": self.cache.hits,
+                "misses": self.cache.misses,
+                "hit_rate": self.cache.hit_rate(),
+            },
+            "cpu_time_ms": self.cpu_time.map(|d| d.as_millis() as u64),
+            "peak_rss_bytes": self.peak_rss_bytes,
+            "capabilities": self.capabilities,
+        })
+    }
+}
+
+/// User + system CPU time of this process. Linux only (`/proc`).
+fn cpu_time() -> Option<Duration> {
+    parse_cpu_time(&std::fs::read_to_string("/proc/self/stat").ok()?)
+}
+
+/// Peak resident set size of this process. Linux only (`/proc`).
+pub(crate) fn peak_rss_bytes() -> Option<u64> {
+    parse_peak_rss(&std::fs::read_to_string("/proc/self/status").ok()?)
+}
+
+/// `utime` + `stime` from `/proc/self/stat`, in clock ticks of 1/100 s
+/// (the value of `USER_HZ` on every mainstream Linux architecture).
+fn parse_cpu_time(stat: &str) -> Option<Duration> {
+    // The command name (field 2) may contain spaces, so split after its ')'.
+    let rest = &stat[stat.rfind(')')? + 1..];
+    let fields: Vec<&str> = rest.split_whitespace().collect();
+    // `rest` starts at field 3 (state); utime and stime are fields 14 and 15.
+    let utime: u64 = fields.get(11)?.parse().ok()?;
+    let stime: u64 = fields.get(12)?.parse().ok()?;
+    Some(Duration::from_millis((utime + stime) * 10))
+}
+
+/// `VmHWM` (peak RSS) from `/proc/self/status`.
+fn parse_peak_rss(status: &str) -> Option<u64> {
+    let line = status.lines().find(|l| l.starts_with("VmHWM:"))?;
+    let kb: u64 = line.split_whitespace().nth(1)?.parse().ok()?;
+    Some(kb * 1024)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use vibecheck_core::capability::Capability;
+
+    fn sample() -> ScanStats {
+        ScanStats {
+            files: 12,
+            phases: vec![
+                ("collect", Duration::from_millis(3)),
+                ("analyze", Duration::from_millis(412)),
+            ],
+            cache: CacheStats { hits: 9, misses: 3 },
+            cpu_time: Some(Duration::from_millis(410)),
+            peak_rss_bytes: Some(48 * 1024 * 1024),
+            capabilities: vec![CapabilityStatus {
+                capability: Capability::Cache,
+                available: false,
+                affects_verdicts: false,
+                detail: "read-only filesystem".into(),
+            }],
+        }
+    }
+
+    #[test]
+    fn render_text_lists_phases_and_resources() {
+        let out = sample().render_text();
+        assert!(out.starts_with("Scan stats (12 files):"));
+        assert!(out.contains("analyze          412 ms"));
+        assert!(out.contains("75% (9 of 12 lookups)"));
+        assert!(out.contains("0.41 s"));
+        assert!(out.contains("48.0 MiB"));
+        assert!(out.contains("cache            disabled  read-only filesystem"));
+    }
+
+    #[test]
+    fn to_json_has_machine_readable_fields() {
+        let v = sample().to_json();
+        assert_eq!(v["files"], 12);
+        assert_eq!(v["phases_ms"]["collect"], 3);
+        assert_eq!(v["cache"]["hit_rate"], 0.75);
+        assert_eq!(v["peak_rss_bytes"], 48 * 1024 * 1024);
+        assert_eq!(v["capabilities"][0]["capability"], "cache");
+        assert_eq!(v["capabilities"][0]["available"], false);
+    }
+
+    #[test]
+    fn render_text_without_cache_lookups() {
+        let mut s = sample();
+        s.cache = CacheStats::default();
+        s.cpu_time = None;
+        let out = s.render_text();
+        assert!(out.contains("n/a (cache not used)"));
+        assert!(out.contains("CPU time         n/a"));
+    }
+
+    #[test]
+    fn parse_proc_files() {
+        let stat = "4242 (vibe check) R 1 4242 4242 0 -1 4194304 500 0 0 0 37 5 0 0 20 0 1 0 100 0 0";
+        assert_eq!(parse_cpu_time(stat), Some(Duration::from_millis(420)));
+        let status = "Name:\tvibecheck\nVmPeak:\t  20000 kB\nVmHWM:\t   1024 kB\n";
+        assert_eq!(parse_peak_rss(status), Some(1024 * 1024));
+        assert_eq!(parse_peak_rss("Name:\tx\n"), None);
+    }
+}
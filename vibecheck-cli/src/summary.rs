@@ -0,0 +1,542 @@
+use std::collections::{BTreeMap, HashMap};
+
+use vibecheck_core::capability::Capability;
+use vibecheck_core::fingerprint::StyleOutlier;
+use vibecheck_core::output::COMMENT_RELIANT;
+use vibecheck_core::package_style::DivergentFile;
+use vibecheck_core::report::{ModelFamily, Report};
+use vibecheck_core::template_reuse::ReusedComment;
+
+/// Number of files listed in the "review these first" step.
+const TOP_FILES: usize = 3;
+
+/// Build a short, actionable checklist from a finished scan.
+///
+/// Returns `None` when nothing was attributed to an AI family — there is
+/// nothing to act on, so the caller prints no summary at all.
+pub fn next_steps(reports: &[Report]) -> Option<String> {
+    let mut flagged: Vec<&Report> = reports
+        .iter()
+        .filter(|r| r.attribution.has_sufficient_data() && r.attribution.primary != ModelFamily::Human)
+        .collect();
+    if flagged.is_empty() {
+        return None;
+    }
+    flagged.sort_by(|a, b| {
+        b.attribution
+            .confidence
+            .total_cmp(&a.attribution.confidence)
+            .then_with(|| display_path(a).cmp(&display_path(b)))
+    });
+
+    let mut out = String::new();
+    out.push_str(&format!(
+        "Next steps ({} of {} files attributed to AI):\n",
+        flagged.len(),
+        reports.len()
+    ));
+
+    out.push_str("  1. Review the most likely AI-generated files first:\n");
+    for r in flagged.iter().take(TOP_FILES) {
+        out.push_str(&format!(
+            "       {} — {} ({:.0}%)\n",
+            display_path(r),
+            r.attribution.primary,
+            r.attribution.confidence * 100.0
+        ));
+    }
+
+    out.push_str("  2. Once reviewed, suppress a file by adding it to `.vibecheck`:\n");
+    out.push_str("       [ignore]\n");
+    out.push_str(&format!("       patterns = [\"{}\"]\n", display_path(flagged[0])));
+
+    if let Some((id, files)) = dominant_signal(&flagged) {
+        out.push_str(&format!(
+            "  3. Silence the dominant signal `{id}` (fired in {files} flagged file{}) in `.vibecheck`:\n",
+            if files == 1 { "" } else { "s" }
+        ));
+        out.push_str("       [heuristics]\n");
+        out.push_str(&format!("       \"{id}\" = 0.0\n"));
+    }
+
+    Some(out)
+}
+
+/// List the files on which an ensemble's backends split between human and
+/// AI.  Their averaged verdict hides the split, so each wants a human look.
+///
+/// Returns `None` when no report needs review.
+pub fn disagreement_note(reports: &[Report]) -> Option<String> {
+    let split: Vec<&Report> = reports.iter().filter(|r| r.needs_review()).collect();
+    if split.is_empty() {
+        return None;
+    }
+    let mut out = format!(
+        "note: the classifier backends disagree on {} file{}; review {} manually:\n",
+        split.len(),
+        if split.len() == 1 { "" } else { "s" },
+        if split.len() == 1 { "it" } else { "them" }
+    );
+    for r in split {
+        let verdicts = vibecheck_core::output::ensemble_verdicts(r).unwrap_or_default();
+        out.push_str(&format!("    {} — {verdicts}\n", display_path(r)));
+    }
+    Some(out)
+}
+
+/// Warn when some verdicts were computed without a backend that normally
+/// contributes signals, e.g. a tree-sitter grammar that failed to load.
+///
+/// Returns `None` when every report was analyzed with full capabilities.
+pub fn degraded_warning(reports: &[Report]) -> Option<String> {
+    let mut counts: Vec<(Capability, usize)> = Vec::new();
+    for cap in reports.iter().flat_map(|r| &r.metadata.degraded) {
+        match counts.iter_mut().find(|(c, _)| c == cap) {
+            Some((_, n)) => *n += 1,
+            None => counts.push((*cap, 1)),
+        }
+    }
+    if counts.is_empty() {
+        return None;
+    }
+    let mut out = String::new();
+    for (cap, n) in counts {
+        out.push_str(&format!(
+            "warning: {cap} unavailable for {n} of {} file{}; those verdicts use the remaining signals\n",
+            reports.len(),
+            if reports.len() == 1 { "" } else { "s" }
+        ));
+    }
+    Some(out)
+}
+
+/// Note how many files were left out for carrying a generated-code header.
+///
+/// Returns `None` when none were skipped.
+pub fn generated_note(skipped: usize) -> Option<String> {
+    (skipped > 0).then(|| {
+        format!(
+            "note: skipped {skipped} generated file{} (`Code generated ... DO NOT EDIT.`); pass --include-generated to analyze {}\n",
+            if skipped == 1 { "" } else { "s" },
+            if skipped == 1 { "it" } else { "them" }
+        )
+    })
+}
+
+/// Note how many files were reported as skipped instead of analyzed, per
+/// reason, with the flag that controls each.
+///
+/// Returns `None` when none were skipped.
+pub fn skipped_note(reports: &[Report]) -> Option<String> {
+    let mut counts: BTreeMap<&str, usize> = BTreeMap::new();
+    for reason in reports.iter().filter_map(|r| r.metadata.skipped.as_deref()) {
+        *counts.entry(reason).or_default() += 1;
+    }
+    if counts.is_empty() {
+        return None;
+    }
+    let mut out = String::new();
+    for (reason, n) in counts {
+        let plural = if n == 1 { "" } else { "s" };
+        let them = if n == 1 { "it" } else { "them" };
+        let line = match reason {
+            vibecheck_core::timeout::SKIPPED_TIMEOUT => {
+                format!("note: {n} file{plural} skipped: timeout; raise --timeout-per-file to analyze {them}\n")
+            }
+            vibecheck_core::limits::SKIPPED_TOO_LARGE => format!(
+                "note: {n} file{plural} skipped: too large; raise --max-file-size (0 for no limit) to analyze {them}\n"
+            ),
+            vibecheck_core::notebook::SKIPPED_UNSUPPORTED_KERNEL => {
+                format!("note: {n} notebook{plural} skipped: not a Python kernel\n")
+            }
+            other => format!("note: {n} file{plural} skipped: {other}\n"),
+        };
+        out.push_str(&line);
+    }
+    Some(out)
+}
+
+/// Reused comments listed before the note is cut off.
+const TOP_REUSED: usize = 3;
+
+/// Note narrative comments repeated near-verbatim across packages, a sign
+/// that one assistant session wrote the files (see
+/// [`vibecheck_core::template_reuse`]).
+///
+/// Returns `None` when no comment is reused.
+pub fn template_reuse_note(reused: &[ReusedComment]) -> Option<String> {
+    if reused.is_empty() {
+        return None;
+    }
+    let mut out = format!(
+        "note: {} comment{} repeated near-verbatim across unrelated packages:\n",
+        reused.len(),
+        if reused.len() == 1 { "" } else { "s" }
+    );
+    for comment in reused.iter().take(TOP_REUSED) {
+        let text: String = comment.text.chars().take(72).collect();
+        let ellipsis = if text.len() < comment.text.len() { "…" } else { "" };
+        out.push_str(&format!("  \"{text}{ellipsis}\"\n"));
+        for at in &comment.locations {
+            out.push_str(&format!("      {}:{}\n", at.path.display(), at.line));
+        }
+    }
+    if reused.len() > TOP_REUSED {
+        out.push_str(&format!("  … and {} more\n", reused.len() - TOP_REUSED));
+    }
+    Some(out)
+}
+
+/// Outliers listed before the note is cut off.
+const TOP_OUTLIERS: usize = 5;
+
+/// Note files whose style stands out from the rest of the repository (see
+/// [`vibecheck_core::fingerprint`]).
+///
+/// Returns `None` when no file does.
+pub fn style_outlier_note(outliers: &[StyleOutlier]) -> Option<String> {
+    if outliers.is_empty() {
+        return None;
+    }
+    let mut out = format!(
+        "note: {} file{} stand{} out from this repository's own style:\n",
+        outliers.len(),
+        if outliers.len() == 1 { "" } else { "s" },
+        if outliers.len() == 1 { "s" } else { "" }
+    );
+    for outlier in outliers.iter().take(TOP_OUTLIERS) {
+        let deviations: Vec<String> = outlier
+            .deviations
+            .iter()
+            .map(|d| format!("{} {} (z {:.1})", d.feature, if d.z > 0.0 { "higher" } else { "lower" }, d.z.abs()))
+            .collect();
+        out.push_str(&format!("  {} — {}\n", outlier.path.display(), deviations.join(", ")));
+    }
+    if outliers.len() > TOP_OUTLIERS {
+        out.push_str(&format!("  … and {} more\n", outliers.len() - TOP_OUTLIERS));
+    }
+    Some(out)
+}
+
+/// Files breaking their package's conventions listed before the note is
+/// cut off.
+const TOP_DIVERGENT: usize = 5;
+
+/// Note Go files that break conventions the rest of their package keeps
+/// (see [`vibecheck_core::package_style`]).
+///
+/// Returns `None` when no file does.
+pub fn package_style_note(divergent: &[DivergentFile]) -> Option<String> {
+    if divergent.is_empty() {
+        return None;
+    }
+    let mut out = format!(
+        "note: {} Go file{} break{} conventions the rest of {} package keeps:\n",
+        divergent.len(),
+        if divergent.len() == 1 { "" } else { "s" },
+        if divergent.len() == 1 { "s" } else { "" },
+        if divergent.len() == 1 { "its" } else { "their" }
+    );
+    for file in divergent.iter().take(TOP_DIVERGENT) {
+        out.push_str(&format!("  {} (package {})\n", file.path.display(), file.package));
+        for d in &file.divergences {
+            out.push_str(&format!("      {}: {}, package: {}\n", d.aspect, d.file, d.package));
+        }
+    }
+    if divergent.len() > TOP_DIVERGENT {
+        out.push_str(&format!("  … and {} more\n", divergent.len() - TOP_DIVERGENT));
+    }
+    Some(out)
+}
+
+/// Comment-reliant files listed before the note is cut off.
+const TOP_COMMENT_RELIANT: usize = 5;
+
+/// Summarize how much of a scan's verdicts rest on comments, from reports
+/// analyzed with `--strip-comments`: the mean AI probability with and
+/// without comments, and the files whose probability drops by at least
+/// [`COMMENT_RELIANT`] once their comments are gone.
+///
+/// Returns `None` when no report has a comment-free verdict.
+pub fn comment_reliance_note(reports: &[Report]) -> Option<String> {
+    let pairs: Vec<(&Report, f64, f64)> = reports
+        .iter()
+        .filter_map(|r| Some((r, r.attribution.ai_probability?, r.comment_free.as_ref()?.attribution.ai_probability?)))
+        .collect();
+    if pairs.is_empty() {
+        return None;
+    }
+    let mean = |f: fn(&(&Report, f64, f64)) -> f64| pairs.iter().map(f).sum::<f64>() / pairs.len() as f64;
+    let mut out = format!(
+        "note: mean AI probability {:.2} with comments, {:.2} without ({} file{}).\n",
+        mean(|p| p.1),
+        mean(|p| p.2),
+        pairs.len(),
+        if pairs.len() == 1 { "" } else { "s" }
+    );
+
+    let mut reliant: Vec<_> = pairs.iter().filter(|(_, full, stripped)| full - stripped >= COMMENT_RELIANT).collect();
+    if reliant.is_empty() {
+        return Some(out);
+    }
+    reliant.sort_by(|a, b| (b.1 - b.2).total_cmp(&(a.1 - a.2)).then_with(|| display_path(a.0).cmp(&display_path(b.0))));
+    out.push_str(&format!(
+        "  {} verdict{} rest{} on comments, which deleting them would defeat:\n",
+        reliant.len(),
+        if reliant.len() == 1 { "" } else { "s" },
+        if reliant.len() == 1 { "s" } else { "" }
+    ));
+    for (r, full, stripped) in reliant.iter().take(TOP_COMMENT_RELIANT) {
+        out.push_str(&format!("    {} — {full:.2} → {stripped:.2}\n", display_path(r)));
+    }
+    if reliant.len() > TOP_COMMENT_RELIANT {
+        out.push_str(&format!("    … and {} more\n", reliant.len() - TOP_COMMENT_RELIANT));
+    }
+    Some(out)
+}
+
+/// The AI-family signal contributing the most total weight across `reports`,
+/// with the number of reports it fired in. Ties break on signal ID so the
+/// suggestion is stable between runs.
+fn dominant_signal(reports: &[&Report]) -> Option<(String, usize)> {
+    let mut totals: HashMap<&str, (f64, usize)> = HashMap::new();
+    for r in reports {
+        for s in &r.signals {
+            if s.id.is_empty() || s.family == ModelFamily::Human || s.weight <= 0.0 {
+                continue;
+            }
+            let entry = totals.entry(s.id.as_str()).or_insert((0.0, 0));
+            entry.0 += s.weight;
+            entry.1 += 1;
+        }
+    }
+    totals
+        .into_iter()
+        .max_by(|a, b| a.1 .0.total_cmp(&b.1 .0).then_with(|| b.0.cmp(a.0)))
+        .map(|(id, (_, files))| (id.to_string(), files))
+}
+
+fn display_path(report: &Report) -> String {
+    report
+        .metadata
+        .file_path
+        .as_ref()
+        .map(|p| p.display().to_string())
+        .unwrap_or_else(|| "<stdin>".into())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::path::PathBuf;
+    use vibecheck_core::report::{Attribution, ReportMetadata, Signal};
+
+    fn report(path: &str, family: ModelFamily, confidence: f64, signals: Vec<Signal>) -> Report {
+        Report {
+            attribution: Attribution {
+                primary: family,
+                confidence,
+                scores: BTreeMap::from([(family, confidence)]),
+                era: None,
+                ai_probability: None,
+                authorship: None,
+            },
+            metadata: ReportMetadata {
+                file_path: Some(PathBuf::from(path)),
+                lines_of_code: 10,
+                signal_count: signals.len(),
+                degraded: vec![],
+                skipped: None,
+            },
+            signals,
+            symbol_reports: None,
+            segments: None,
+            comment_free: None,
+            ensemble: None,
+        }
+    }
+
+    fn signal(id: &str, family: ModelFamily, weight: f64) -> Signal {
+        Signal::new(id, "test", "test signal", family, weight)
+    }
+
+    #[test]
+    fn disagreement_note_lists_split_verdicts() {
+        use vibecheck_core::report::{BackendVerdict, EnsembleReport};
+        let verdict = |backend: &str, primary, confidence| BackendVerdict { backend: backend.into(), primary, confidence };
+        let mut split = report("src/a.rs", ModelFamily::Claude, 0.6, vec![]);
+        split.ensemble = Some(EnsembleReport {
+            verdicts: vec![verdict("logistic", ModelFamily::Claude, 0.8), verdict("heuristic", ModelFamily::Human, 0.55)],
+            disagreement: true,
+        });
+        let mut agreed = report("src/b.rs", ModelFamily::Claude, 0.9, vec![]);
+        agreed.ensemble = Some(EnsembleReport { verdicts: vec![verdict("logistic", ModelFamily::Claude, 0.9)], disagreement: false });
+
+        assert!(disagreement_note(std::slice::from_ref(&agreed)).is_none());
+        assert_eq!(
+            disagreement_note(&[split, agreed]).unwrap(),
+            "note: the classifier backends disagree on 1 file; review it manually:\n    \
+             src/a.rs — logistic: Claude (80%), heuristic: Human (55%)\n"
+        );
+    }
+
+    #[test]
+    fn no_summary_when_all_human() {
+        let reports = vec![report("a.rs", ModelFamily::Human, 0.9, vec![signal("x", ModelFamily::Human, 1.0)])];
+        assert!(next_steps(&reports).is_none());
+    }
+
+    #[test]
+    fn lists_top_three_by_confidence() {
+        let s = vec![signal("x", ModelFamily::Claude, 1.0)];
+        let reports = vec![
+            report("low.rs", ModelFamily::Claude, 0.5, s.clone()),
+            report("high.rs", ModelFamily::Gpt, 0.95, s.clone()),
+            report("mid.rs", ModelFamily::Claude, 0.7, s.clone()),
+            report("mid2.rs", ModelFamily::Claude, 0.6, s),
+        ];
+        let out = next_steps(&reports).unwrap();
+        let high = out.find("high.rs").unwrap();
+        let mid = out.find("mid.rs").unwrap();
+        let mid2 = out.find("mid2.rs").unwrap();
+        assert!(high < mid && mid < mid2);
+        assert!(!out.contains("low.rs"), "only the top three files are listed");
+        assert!(out.contains("patterns = [\"high.rs\"]"));
+    }
+
+    #[test]
+    fn suggests_disabling_dominant_signal() {
+        let reports = vec![
+            report("a.rs", ModelFamily::Claude, 0.8, vec![
+                signal("rust.errors.zero_unwrap", ModelFamily::Claude, 1.5),
+                signal("rust.naming.long_names", ModelFamily::Claude, 1.0),
+            ]),
+            report("b.rs", ModelFamily::Claude, 0.8, vec![
+                signal("rust.errors.zero_unwrap", ModelFamily::Claude, 1.5),
+                signal("rust.errors.many_unwraps", ModelFamily::Human, 5.0),
+            ]),
+        ];
+        let out = next_steps(&reports).unwrap();
+        assert!(out.contains("`rust.errors.zero_unwrap` (fired in 2 flagged files)"));
+        assert!(out.contains("\"rust.errors.zero_unwrap\" = 0.0"));
+    }
+
+    #[test]
+    fn skips_signal_step_without_ids() {
+        let reports = vec![report("a.rs", ModelFamily::Claude, 0.8, vec![signal("", ModelFamily::Claude, 1.0)])];
+        let out = next_steps(&reports).unwrap();
+        assert!(!out.contains("[heuristics]"));
+    }
+
+    #[test]
+    fn warns_about_degraded_verdicts() {
+        let mut degraded = report("a.rs", ModelFamily::Claude, 0.8, vec![]);
+        degraded.metadata.degraded = vec![Capability::CstParsing];
+        let full = report("b.rs", ModelFamily::Human, 0.8, vec![]);
+        assert!(degraded_warning(&[full.clone()]).is_none());
+        let out = degraded_warning(&[degraded, full]).unwrap();
+        assert_eq!(out, "warning: cst_parsing unavailable for 1 of 2 files; those verdicts use the remaining signals\n");
+    }
+
+    #[test]
+    fn notes_skipped_generated_files() {
+        assert!(generated_note(0).is_none());
+        assert!(generated_note(1).unwrap().contains("skipped 1 generated file ("));
+        assert!(generated_note(3).unwrap().contains("skipped 3 generated files"));
+    }
+
+    #[test]
+    fn notes_skipped_files_by_reason() {
+        use vibecheck_core::{limits::SKIPPED_TOO_LARGE, timeout::SKIPPED_TIMEOUT};
+        let full = report("a.rs", ModelFamily::Human, 0.8, vec![]);
+        assert!(skipped_note(&[full.clone()]).is_none());
+        let slow = Report::skipped(PathBuf::from("b.rs"), SKIPPED_TIMEOUT);
+        let big = |p: &str| Report::skipped(PathBuf::from(p), SKIPPED_TOO_LARGE);
+        assert_eq!(
+            skipped_note(&[full, slow, big("c.js"), big("d.js")]).unwrap(),
+            "note: 1 file skipped: timeout; raise --timeout-per-file to analyze it\n\
+             note: 2 files skipped: too large; raise --max-file-size (0 for no limit) to analyze them\n"
+        );
+    }
+
+    #[test]
+    fn notes_reused_comments() {
+        use vibecheck_core::template_reuse::CommentLocation;
+        assert!(template_reuse_note(&[]).is_none());
+        let at = |p: &str, line| CommentLocation { path: PathBuf::from(p), line };
+        let reused = ReusedComment {
+            text: "Validate the request before processing it.".into(),
+            locations: vec![at("orders/create.go", 3), at("users/create.go", 7)],
+        };
+        assert_eq!(
+            template_reuse_note(&[reused]).unwrap(),
+            "note: 1 comment repeated near-verbatim across unrelated packages:\n  \
+             \"Validate the request before processing it.\"\n      orders/create.go:3\n      users/create.go:7\n"
+        );
+    }
+
+    #[test]
+    fn notes_style_outliers() {
+        use vibecheck_core::fingerprint::Deviation;
+        assert!(style_outlier_note(&[]).is_none());
+        let outlier = StyleOutlier {
+            path: PathBuf::from("pkg/new.go"),
+            distance: 4.0,
+            deviations: vec![
+                Deviation { feature: "comment density", z: 6.24 },
+                Deviation { feature: "blank lines", z: -3.1 },
+            ],
+        };
+        assert_eq!(
+            style_outlier_note(&[outlier]).unwrap(),
+            "note: 1 file stands out from this repository's own style:\n  \
+             pkg/new.go — comment density higher (z 6.2), blank lines lower (z 3.1)\n"
+        );
+    }
+
+    #[test]
+    fn notes_package_style_divergence() {
+        use vibecheck_core::package_style::{Aspect, Divergence};
+        assert!(package_style_note(&[]).is_none());
+        let file = DivergentFile {
+            path: PathBuf::from("internal/cache/evict.go"),
+            package: "cache".into(),
+            divergences: vec![Divergence {
+                aspect: Aspect::Receiver("Cache".into()),
+                file: "`cache`".into(),
+                package: "`c`".into(),
+            }],
+        };
+        assert_eq!(
+            package_style_note(&[file]).unwrap(),
+            "note: 1 Go file breaks conventions the rest of its package keeps:\n  \
+             internal/cache/evict.go (package cache)\n      receiver name of Cache: `cache`, package: `c`\n"
+        );
+    }
+
+    #[test]
+    fn comment_reliance_note_lists_files_that_rest_on_comments() {
+        let scored = |path: &str, full: f64, stripped: f64| {
+            let mut r = report(path, ModelFamily::Claude, 0.8, vec![]);
+            r.attribution.ai_probability = Some(full);
+            let mut attribution = r.attribution.clone();
+            attribution.ai_probability = Some(stripped);
+            r.comment_free = Some(vibecheck_core::report::CommentFreeReport { attribution, signal_count: 0 });
+            r
+        };
+        assert!(comment_reliance_note(&[report("a.rs", ModelFamily::Claude, 0.8, vec![])]).is_none());
+
+        let reports = [scored("a.rs", 0.9, 0.3), scored("b.rs", 0.8, 0.7), scored("c.rs", 0.7, 0.2)];
+        assert_eq!(
+            comment_reliance_note(&reports).unwrap(),
+            "note: mean AI probability 0.80 with comments, 0.40 without (3 files).\n  \
+             2 verdicts rest on comments, which deleting them would defeat:\n    \
+             a.rs — 0.90 → 0.30\n    \
+             c.rs — 0.70 → 0.20\n"
+        );
+        assert_eq!(
+            comment_reliance_note(&reports[1..2]).unwrap(),
+            "note: mean AI probability 0.80 with comments, 0.70 without (1 file).\n"
+        );
+    }
+}
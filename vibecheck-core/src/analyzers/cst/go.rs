@@ -1,4 +1,4 @@
-use std::collections::HashMap;
+use std::collections::{HashMap, HashSet};
 
 use tree_sitter::{Node, Tree};
 
@@ -90,6 +90,15 @@ impl CstAnalyzer for GoCstAnalyzer {
         let named_returns = count_named_returns(root, src_bytes);
         metrics.insert("named_return_count".into(), named_returns as f64);
 
+        let redundant_guards = count_redundant_guards(root, src_bytes);
+        metrics.insert("redundant_guard_count".into(), redundant_guards as f64);
+
+        let unused = count_unused_declarations(root, src_bytes);
+        metrics.insert("unused_declaration_count".into(), unused as f64);
+
+        let accessors = largest_unused_accessor_set(root, src_bytes);
+        metrics.insert("unused_accessor_count".into(), accessors as f64);
+
         metrics
     }
 
@@ -375,6 +384,496 @@ fn count_named_returns(root: Node<'_>, src_bytes: &[u8]) -> usize {
     count
 }
 
+/// Count guards whose outcome is already settled by the code around them:
+///
+/// - `x != nil && len(x) > 0`, or a `x != nil` check nested inside a
+///   `len(x) > 0` branch (a non-zero length implies non-nil);
+/// - a nil check on a variable last assigned a composite literal, `&T{}`,
+///   `make` or `new`;
+/// - clamping a size to at least 1 (`if n < 1 { n = 1 }`) that is then only
+///   used as a `make` capacity, where zero is fine.
+///
+/// "Provably" is per syntax only: a pointer to an array has a constant
+/// `len` even when nil, so the length rules can misfire on those.
+fn count_redundant_guards(root: Node<'_>, src_bytes: &[u8]) -> usize {
+    let mut count = 0;
+    let mut stack = vec![root];
+    while let Some(node) = stack.pop() {
+        match node.kind() {
+            "binary_expression" if is_nil_and_len_check(node, src_bytes) => count += 1,
+            "if_statement" => count += nil_checks_under_len_check(node, src_bytes),
+            "block" => count += redundant_guards_in_block(node, src_bytes),
+            _ => {}
+        }
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            stack.push(child);
+        }
+    }
+    count
+}
+
+/// The statements of a block, whether or not the grammar wraps them in a
+/// `statement_list`.
+fn block_statements(block: Node<'_>) -> Vec<Node<'_>> {
+    let mut cursor = block.walk();
+    let children: Vec<Node<'_>> = block.named_children(&mut cursor).collect();
+    match children.as_slice() {
+        [list] if list.kind() == "statement_list" => {
+            let mut cursor = list.walk();
+            list.named_children(&mut cursor)
+                .filter(|n| n.kind() != "comment")
+                .collect()
+        }
+        _ => children.into_iter().filter(|n| n.kind() != "comment").collect(),
+    }
+}
+
+fn strip_parens(mut node: Node<'_>) -> Node<'_> {
+    while node.kind() == "parenthesized_expression" {
+        match node.named_child(0) {
+            Some(inner) => node = inner,
+            None => break,
+        }
+    }
+    node
+}
+
+fn text<'s>(node: Node<'_>, src_bytes: &'s [u8]) -> &'s str {
+    node.utf8_text(src_bytes).unwrap_or("")
+}
+
+/// `(left, operator, right)` of a binary expression.
+fn binary_parts<'t>(node: Node<'t>, src_bytes: &[u8]) -> Option<(Node<'t>, String, Node<'t>)> {
+    let node = strip_parens(node);
+    if node.kind() != "binary_expression" {
+        return None;
+    }
+    let left = strip_parens(node.child_by_field_name("left")?);
+    let op = text(node.child_by_field_name("operator")?, src_bytes).to_string();
+    let right = strip_parens(node.child_by_field_name("right")?);
+    Some((left, op, right))
+}
+
+/// The variable compared against nil by `x != nil` (or `nil != x`).
+fn not_nil_operand<'s>(cond: Node<'_>, src_bytes: &'s [u8]) -> Option<&'s str> {
+    let (left, op, right) = binary_parts(cond, src_bytes)?;
+    if op != "!=" {
+        return None;
+    }
+    match (left.kind(), right.kind()) {
+        ("identifier", "nil") => Some(text(left, src_bytes)),
+        ("nil", "identifier") => Some(text(right, src_bytes)),
+        _ => None,
+    }
+}
+
+/// The variable compared against nil by `x != nil` or `x == nil`.
+fn nil_compared<'s>(cond: Node<'_>, src_bytes: &'s [u8]) -> Option<&'s str> {
+    let (left, op, right) = binary_parts(cond, src_bytes)?;
+    if op != "!=" && op != "==" {
+        return None;
+    }
+    match (left.kind(), right.kind()) {
+        ("identifier", "nil") => Some(text(left, src_bytes)),
+        ("nil", "identifier") => Some(text(right, src_bytes)),
+        _ => None,
+    }
+}
+
+/// The variable of `len(x) > 0`, `len(x) != 0` or `len(x) >= 1`.
+fn non_empty_operand<'s>(cond: Node<'_>, src_bytes: &'s [u8]) -> Option<&'s str> {
+    let (left, op, right) = binary_parts(cond, src_bytes)?;
+    let bound = text(right, src_bytes);
+    let non_empty = matches!((op.as_str(), bound), (">", "0") | ("!=", "0") | (">=", "1"));
+    if !non_empty || left.kind() != "call_expression" {
+        return None;
+    }
+    if text(left.child_by_field_name("function")?, src_bytes) != "len" {
+        return None;
+    }
+    let args = left.child_by_field_name("arguments")?;
+    let arg = strip_parens(args.named_child(0)?);
+    (args.named_child_count() == 1 && arg.kind() == "identifier").then(|| text(arg, src_bytes))
+}
+
+fn is_nil_and_len_check(node: Node<'_>, src_bytes: &[u8]) -> bool {
+    let Some((left, op, right)) = binary_parts(node, src_bytes) else {
+        return false;
+    };
+    op == "&&"
+        && not_nil_operand(left, src_bytes).is_some()
+        && not_nil_operand(left, src_bytes) == non_empty_operand(right, src_bytes)
+}
+
+/// `if len(x) > 0 { ... if x != nil { ... } ... }`, counting each such
+/// nil check that comes before any assignment to `x`.
+fn nil_checks_under_len_check(node: Node<'_>, src_bytes: &[u8]) -> usize {
+    let Some(var) = node
+        .child_by_field_name("condition")
+        .and_then(|c| non_empty_operand(c, src_bytes))
+    else {
+        return 0;
+    };
+    let Some(body) = node.child_by_field_name("consequence") else {
+        return 0;
+    };
+    let mut count = 0;
+    for stmt in block_statements(body) {
+        if is_nil_check_of(stmt, var, src_bytes) {
+            count += 1;
+        }
+        if assigns_to(stmt, var, src_bytes) {
+            break;
+        }
+    }
+    count
+}
+
+fn is_nil_check_of(stmt: Node<'_>, var: &str, src_bytes: &[u8]) -> bool {
+    stmt.kind() == "if_statement"
+        && stmt.child_by_field_name("initializer").is_none()
+        && stmt
+            .child_by_field_name("condition")
+            .and_then(|c| nil_compared(c, src_bytes))
+            == Some(var)
+}
+
+/// Whether `stmt` assigns to `var` or takes its address anywhere inside it,
+/// after which nothing is known about its value.
+fn assigns_to(stmt: Node<'_>, var: &str, src_bytes: &[u8]) -> bool {
+    let mut stack = vec![stmt];
+    while let Some(node) = stack.pop() {
+        let target = match node.kind() {
+            "assignment_statement" | "short_var_declaration" | "range_clause" => {
+                node.child_by_field_name("left")
+            }
+            "unary_expression" | "inc_statement" | "dec_statement" => {
+                node.child_by_field_name("operand").or_else(|| node.named_child(0))
+            }
+            _ => None,
+        };
+        if let Some(target) = target {
+            let is_address = node.kind() != "unary_expression"
+                || node
+                    .child_by_field_name("operator")
+                    .is_some_and(|op| text(op, src_bytes) == "&");
+            let mut cursor = target.walk();
+            let names_var = target.kind() == "identifier" && text(target, src_bytes) == var
+                || target
+                    .named_children(&mut cursor)
+                    .any(|n| n.kind() == "identifier" && text(n, src_bytes) == var);
+            if is_address && names_var {
+                return true;
+            }
+        }
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            stack.push(child);
+        }
+    }
+    false
+}
+
+/// The variable bound by `x := T{}`, `x = &T{}`, `x := make(...)` or
+/// `x := new(T)` — values that can never be nil.
+fn non_nil_binding<'s>(stmt: Node<'_>, src_bytes: &'s [u8]) -> Option<&'s str> {
+    if stmt.kind() != "short_var_declaration" && stmt.kind() != "assignment_statement" {
+        return None;
+    }
+    if stmt.kind() == "assignment_statement"
+        && text(stmt.child_by_field_name("operator")?, src_bytes) != "="
+    {
+        return None;
+    }
+    let left = stmt.child_by_field_name("left")?;
+    let right = stmt.child_by_field_name("right")?;
+    if left.named_child_count() != 1 || right.named_child_count() != 1 {
+        return None;
+    }
+    let var = left.named_child(0)?;
+    let value = strip_parens(right.named_child(0)?);
+    let non_nil = match value.kind() {
+        "composite_literal" | "func_literal" => true,
+        "unary_expression" => {
+            value
+                .child_by_field_name("operator")
+                .is_some_and(|op| text(op, src_bytes) == "&")
+                && value
+                    .child_by_field_name("operand")
+                    .is_some_and(|o| strip_parens(o).kind() == "composite_literal")
+        }
+        "call_expression" => value
+            .child_by_field_name("function")
+            .is_some_and(|f| matches!(text(f, src_bytes), "make" | "new")),
+        _ => false,
+    };
+    (non_nil && var.kind() == "identifier").then(|| text(var, src_bytes))
+}
+
+/// The variable of `if n < 1 { n = 1 }` (or `n <= 0`, `n == 0`).
+fn clamped_to_one<'s>(stmt: Node<'_>, src_bytes: &'s [u8]) -> Option<&'s str> {
+    if stmt.kind() != "if_statement"
+        || stmt.child_by_field_name("initializer").is_some()
+        || stmt.child_by_field_name("alternative").is_some()
+    {
+        return None;
+    }
+    let (left, op, right) = binary_parts(stmt.child_by_field_name("condition")?, src_bytes)?;
+    let below_one = matches!(
+        (op.as_str(), text(right, src_bytes)),
+        ("<", "1") | ("<=", "0") | ("==", "0")
+    );
+    if !below_one || left.kind() != "identifier" {
+        return None;
+    }
+    let var = text(left, src_bytes);
+    let [assign] = block_statements(stmt.child_by_field_name("consequence")?)[..] else {
+        return None;
+    };
+    let (target, value) = (
+        assign.child_by_field_name("left")?,
+        assign.child_by_field_name("right")?,
+    );
+    let is_clamp = assign.kind() == "assignment_statement"
+        && text(target, src_bytes) == var
+        && text(value, src_bytes) == "1";
+    is_clamp.then_some(var)
+}
+
+/// How often `var` appears in `stmt`.
+fn mentions(stmt: Node<'_>, var: &str, src_bytes: &[u8]) -> usize {
+    let mut count = 0;
+    let mut stack = vec![stmt];
+    while let Some(node) = stack.pop() {
+        if node.kind() == "identifier" && text(node, src_bytes) == var {
+            count += 1;
+        }
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            stack.push(child);
+        }
+    }
+    count
+}
+
+/// How often `var` is passed to `make` as a slice capacity or map size
+/// hint — the arguments where 0 and 1 behave the same.
+fn capacity_uses(stmt: Node<'_>, var: &str, src_bytes: &[u8]) -> usize {
+    let mut count = 0;
+    let mut stack = vec![stmt];
+    while let Some(node) = stack.pop() {
+        let is_make = node.kind() == "call_expression"
+            && node
+                .child_by_field_name("function")
+                .is_some_and(|f| text(f, src_bytes) == "make");
+        if let Some(args) = node.child_by_field_name("arguments").filter(|_| is_make) {
+            let mut cursor = args.walk();
+            let args: Vec<Node<'_>> = args.named_children(&mut cursor).collect();
+            let capacity = match args.first().map(|t| t.kind()) {
+                Some("slice_type") => args.get(2),
+                Some("map_type") => args.get(1),
+                _ => None,
+            };
+            if capacity.is_some_and(|c| c.kind() == "identifier" && text(*c, src_bytes) == var) {
+                count += 1;
+            }
+        }
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            stack.push(child);
+        }
+    }
+    count
+}
+
+/// Nil checks after a non-nil binding, and size clamps before `make`, among
+/// the direct statements of one block.
+fn redundant_guards_in_block(block: Node<'_>, src_bytes: &[u8]) -> usize {
+    let stmts = block_statements(block);
+    let mut count = 0;
+    for (i, &stmt) in stmts.iter().enumerate() {
+        if let Some(var) = non_nil_binding(stmt, src_bytes) {
+            for &later in &stmts[i + 1..] {
+                if is_nil_check_of(later, var, src_bytes) {
+                    count += 1;
+                    break;
+                }
+                if assigns_to(later, var, src_bytes) {
+                    break;
+                }
+            }
+        }
+        if let Some(var) = clamped_to_one(stmt, src_bytes) {
+            // Redundant only if the clamped value is never used for
+            // anything but a capacity afterwards.
+            let rest = &stmts[i + 1..];
+            let capacity_only = rest
+                .iter()
+                .all(|&s| mentions(s, var, src_bytes) == capacity_uses(s, var, src_bytes));
+            if capacity_only && rest.iter().any(|&s| capacity_uses(s, var, src_bytes) > 0) {
+                count += 1;
+            }
+        }
+    }
+    count
+}
+
+/// Count unexported top-level consts, vars, functions and methods that
+/// nothing else in the file refers to — the leftover `iota` blocks and
+/// `peek_value`-style helpers a generator pads a type with.
+///
+/// This is per file, not per package: the pipeline never sees the rest of
+/// the package, so a helper used only from a sibling file counts too.
+/// Exported names, `main`, `init` and `_` are never counted.
+fn count_unused_declarations(root: Node<'_>, src_bytes: &[u8]) -> usize {
+    let mut declared: Vec<Node<'_>> = Vec::new();
+    let mut cursor = root.walk();
+    for decl in root.named_children(&mut cursor) {
+        match decl.kind() {
+            "function_declaration" | "method_declaration" => {
+                declared.extend(decl.child_by_field_name("name"));
+            }
+            "const_declaration" | "var_declaration" => {
+                let mut stack = vec![decl];
+                while let Some(node) = stack.pop() {
+                    if node.kind() == "const_spec" || node.kind() == "var_spec" {
+                        let mut names = node.walk();
+                        declared.extend(node.children_by_field_name("name", &mut names));
+                        continue;
+                    }
+                    let mut inner = node.walk();
+                    for child in node.named_children(&mut inner) {
+                        stack.push(child);
+                    }
+                }
+            }
+            _ => {}
+        }
+    }
+    declared.retain(|&n| {
+        let name = text(n, src_bytes);
+        !matches!(name, "" | "_" | "main" | "init")
+            && !name.chars().next().is_some_and(|c| c.is_uppercase())
+    });
+
+    let declaring: HashSet<usize> = declared.iter().map(|n| n.id()).collect();
+    let mut referenced: HashSet<&str> = HashSet::new();
+    let mut stack = vec![root];
+    while let Some(node) = stack.pop() {
+        let is_name = matches!(node.kind(), "identifier" | "field_identifier");
+        if is_name && !declaring.contains(&node.id()) {
+            referenced.insert(text(node, src_bytes));
+        }
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            stack.push(child);
+        }
+    }
+    declared
+        .iter()
+        .filter(|&&n| !referenced.contains(text(n, src_bytes)))
+        .count()
+}
+
+/// Whether `name` belongs to the convenience suite generators export
+/// alongside a container type: size and emptiness, membership, bulk
+/// views, resets and `GetOldest`-style peeks.
+fn is_convenience_accessor(name: &str) -> bool {
+    const NAMES: &[&str] = &[
+        "Len", "Size", "Count", "Cap", "Capacity", "IsEmpty", "IsFull", "Empty", "Full", "Contains", "Has",
+        "Exists", "Keys", "Values", "Items", "Entries", "All", "Clear", "Reset", "Purge", "Peek",
+    ];
+    const ENDS: &[&str] = &["Oldest", "Newest", "First", "Last", "Front", "Back", "Min", "Max", "All"];
+    if NAMES.contains(&name) {
+        return true;
+    }
+    ["Get", "Peek"].iter().any(|prefix| name.strip_prefix(prefix).is_some_and(|rest| ENDS.contains(&rest)))
+}
+
+/// The most exported convenience accessors (see [`is_convenience_accessor`])
+/// any one receiver type defines that nothing in the file uses — the `Len`,
+/// `Contains`, `Clear`, `GetOldest` suite a generator rounds a type off
+/// with whether or not anything asked for it.
+///
+/// Only `package main` counts: nothing outside it can call its methods,
+/// whereas a library container's exported suite is its API, and its callers
+/// live in other packages this never sees.
+///
+/// Without type information, a call counts as using the method when its
+/// operand is a plain name (`c.Len()`, `cache.Clear`), not a field or call
+/// result: `c.items.Len()` is some other type's `Len`.  Methods an
+/// interface in the file declares are used, and so is the `Len` of a type
+/// that also has `Less` and `Swap` (`sort.Interface`).  Like
+/// [`count_unused_declarations`] this sees one file only.
+fn largest_unused_accessor_set(root: Node<'_>, src_bytes: &[u8]) -> usize {
+    let mut cursor = root.walk();
+    let package = root
+        .named_children(&mut cursor)
+        .find(|n| n.kind() == "package_clause")
+        .and_then(|clause| clause.named_child(0))
+        .map(|name| text(name, src_bytes));
+    if package != Some("main") {
+        return 0;
+    }
+
+    // Receiver type → exported method names.
+    let mut methods: HashMap<&str, Vec<&str>> = HashMap::new();
+    let mut cursor = root.walk();
+    for decl in root.named_children(&mut cursor).filter(|n| n.kind() == "method_declaration") {
+        let Some(name) = decl.child_by_field_name("name").map(|n| text(n, src_bytes)) else {
+            continue;
+        };
+        let Some(ty) = decl.child_by_field_name("receiver").and_then(|r| receiver_type(r, src_bytes)) else {
+            continue;
+        };
+        if name.chars().next().is_some_and(|c| c.is_uppercase()) {
+            methods.entry(ty).or_default().push(name);
+        }
+    }
+
+    let mut used: HashSet<&str> = HashSet::new();
+    let mut stack = vec![root];
+    while let Some(node) = stack.pop() {
+        match node.kind() {
+            "selector_expression" => {
+                let plain = node.child_by_field_name("operand").is_some_and(|o| o.kind() == "identifier");
+                if let (true, Some(field)) = (plain, node.child_by_field_name("field")) {
+                    used.insert(text(field, src_bytes));
+                }
+            }
+            "method_elem" | "method_spec" => {
+                used.extend(node.child_by_field_name("name").map(|n| text(n, src_bytes)));
+            }
+            _ => {}
+        }
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            stack.push(child);
+        }
+    }
+
+    methods
+        .values()
+        .map(|names| {
+            let sortable = names.contains(&"Less") && names.contains(&"Swap");
+            names
+                .iter()
+                .filter(|&&n| is_convenience_accessor(n) && !used.contains(n) && !(sortable && n == "Len"))
+                .count()
+        })
+        .max()
+        .unwrap_or(0)
+}
+
+/// The receiver's type name, without pointer and type parameters.
+fn receiver_type<'s>(list: Node<'_>, src_bytes: &'s [u8]) -> Option<&'s str> {
+    let mut cursor = list.walk();
+    let param = list.named_children(&mut cursor).find(|n| n.kind() == "parameter_declaration")?;
+    let ty = text(param.child_by_field_name("type")?, src_bytes).trim_start_matches('*');
+    Some(ty.split('[').next().unwrap_or(ty).trim())
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -483,4 +982,187 @@ func Run() {
         let m = parse_and_metrics(source);
         assert!(m["err_nil_check_count"] >= 3.0);
     }
+
+    #[test]
+    fn redundant_guard_metrics() {
+        let source = r#"package main
+
+func Tail(xs []int) int {
+    if xs != nil && len(xs) > 0 {
+        return xs[len(xs)-1]
+    }
+    return 0
+}
+
+func Build(n int) map[string]int {
+    cfg := &Config{}
+    if cfg != nil {
+        cfg.Apply()
+    }
+    if n < 1 {
+        n = 1
+    }
+    return make(map[string]int, n)
+}
+"#;
+        let m = parse_and_metrics(source);
+        assert_eq!(m["redundant_guard_count"], 3.0);
+    }
+
+    #[test]
+    fn necessary_guards_are_not_redundant() {
+        let source = r#"package main
+
+func Load(path string, n int) []byte {
+    cfg := &Config{}
+    cfg = lookup(path)
+    if cfg != nil {
+        cfg.Apply()
+    }
+    if n < 1 {
+        n = 1
+    }
+    buf := make([]byte, n)
+    return buf[:len(buf)/n]
+}
+"#;
+        let m = parse_and_metrics(source);
+        assert_eq!(m["redundant_guard_count"], 0.0);
+    }
+
+    #[test]
+    fn unused_declaration_metrics() {
+        let source = r#"package cache
+
+const (
+    defaultCap = iota + 1
+    smallCap
+)
+
+var registry = map[string]int{}
+
+type Cache struct{ items map[string]int }
+
+func New() *Cache { return &Cache{items: registry} }
+
+func (c *Cache) peek(k string) int { return c.items[k] }
+
+func (c *Cache) lookup(k string) int { return c.items[k] }
+
+func (c *Cache) Get(k string) int { return c.lookup(k) }
+
+func Exported() {}
+
+func main() {}
+"#;
+        let m = parse_and_metrics(source);
+        // defaultCap, smallCap and peek; registry and lookup are used.
+        assert_eq!(m["unused_declaration_count"], 3.0);
+    }
+
+    #[test]
+    fn unused_accessor_metrics() {
+        let source = r#"package main
+
+type Cache struct{ items *list.List }
+
+func (c *Cache) Get(k string) int { return 0 }
+func (c *Cache) Len() int { return c.items.Len() }
+func (c *Cache) Contains(k string) bool { return false }
+func (c *Cache) Clear() { c.items.Init() }
+func (c *Cache) GetOldest() int { return 0 }
+func (c *Cache) Keys() []string { return nil }
+
+type byAge []int
+
+func (b byAge) Len() int           { return len(b) }
+func (b byAge) Less(i, j int) bool { return b[i] < b[j] }
+func (b byAge) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byAge) Values() []int      { return b }
+
+type Sizer interface{ Values() []int }
+
+func Drain(c *Cache) {
+    if c.Contains("x") {
+        c.Clear()
+    }
+}
+"#;
+        let m = parse_and_metrics(source);
+        // Len (the field's Len is another type's), GetOldest and Keys;
+        // byAge's Len is sort.Interface and Values is declared by Sizer.
+        assert_eq!(m["unused_accessor_count"], 3.0);
+    }
+
+    #[test]
+    fn library_containers_export_accessors_for_other_packages() {
+        // After hashicorp/golang-lru: every accessor is API for importers.
+        let source = r#"package lru
+
+// Cache is a thread-safe fixed size LRU cache.
+type Cache[K comparable, V any] struct {
+    lru  *simplelru.LRU[K, V]
+    lock sync.RWMutex
+}
+
+// Contains checks if a key is in the cache, without updating the
+// recent-ness or deleting it for being stale.
+func (c *Cache[K, V]) Contains(key K) bool {
+    c.lock.RLock()
+    defer c.lock.RUnlock()
+    return c.lru.Contains(key)
+}
+
+// Peek returns the key value (or undefined if not found) without updating
+// the "recently used"-ness of the key.
+func (c *Cache[K, V]) Peek(key K) (value V, ok bool) {
+    c.lock.RLock()
+    defer c.lock.RUnlock()
+    return c.lru.Peek(key)
+}
+
+// Keys returns a slice of the keys in the cache, from oldest to newest.
+func (c *Cache[K, V]) Keys() []K {
+    c.lock.RLock()
+    defer c.lock.RUnlock()
+    return c.lru.Keys()
+}
+
+// Len returns the number of items in the cache.
+func (c *Cache[K, V]) Len() int {
+    c.lock.RLock()
+    defer c.lock.RUnlock()
+    return c.lru.Len()
+}
+
+// Purge is used to completely clear the cache.
+func (c *Cache[K, V]) Purge() {
+    c.lock.Lock()
+    c.lru.Purge()
+    c.lock.Unlock()
+}
+
+// GetOldest returns the oldest entry.
+func (c *Cache[K, V]) GetOldest() (key K, value V, ok bool) {
+    c.lock.RLock()
+    defer c.lock.RUnlock()
+    return c.lru.GetOldest()
+}
+"#;
+        let m = parse_and_metrics(source);
+        assert_eq!(m["unused_accessor_count"], 0.0);
+
+        let main = source.replacen("package lru", "package main", 1);
+        assert_eq!(parse_and_metrics(&main)["unused_accessor_count"], 6.0);
+    }
+
+    #[test]
+    fn accessor_names() {
+        for name in ["Len", "Contains", "GetOldest", "PeekFront", "IsEmpty"] {
+            assert!(is_convenience_accessor(name), "{name}");
+        }
+        for name in ["Get", "Put", "Remove", "GetUser", "Lenient"] {
+            assert!(!is_convenience_accessor(name), "{name}");
+        }
+    }
 }
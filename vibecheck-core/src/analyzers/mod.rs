@@ -3,10 +3,16 @@ pub mod text;
 
 use std::collections::HashMap;
 
+use crate::capability::Capability;
 use crate::language::Language;
 use crate::report::{Signal, SymbolMetadata};
+use crate::source_file::SourceFile;
 
 /// Trait for text-pattern source code analyzers.
+///
+/// One instance serves every thread of a shared [`crate::Analyzer`]:
+/// implementations keep per-call state on the stack and anything they
+/// memoize behind a lock.
 pub trait Analyzer: Send + Sync {
     /// A short name identifying this analyzer.
     fn name(&self) -> &str;
@@ -47,9 +53,25 @@ pub trait Analyzer: Send + Sync {
             Some(Language::Go)                => self.analyze_go(source),
         }
     }
+
+    /// Analyze a file the pipeline has already parsed.  Defaults to
+    /// [`analyze_with_language`] on its source; analyzers that need the
+    /// tree or the comment map override this rather than parsing again.
+    fn analyze_file(&self, file: &SourceFile) -> Vec<Signal> {
+        self.analyze_with_language(&file.source, file.language)
+    }
+
+    /// [`analyze_file`], for analyzers backed by something that can fail
+    /// per file (an LLM endpoint): the capability that failed, which the
+    /// pipeline lists in [`ReportMetadata::degraded`](crate::report::ReportMetadata::degraded).
+    /// Defaults to [`analyze_file`], which cannot fail.
+    fn try_analyze_file(&self, file: &SourceFile) -> Result<Vec<Signal>, Capability> {
+        Ok(self.analyze_file(file))
+    }
 }
 
-/// Trait for tree-sitter CST analyzers.
+/// Trait for tree-sitter CST analyzers.  Shared across threads like
+/// [`Analyzer`].
 pub trait CstAnalyzer: Send + Sync {
     /// A short name identifying this analyzer.
     fn name(&self) -> &str;
@@ -95,13 +117,40 @@ pub trait CstAnalyzer: Send + Sync {
 
 /// Returns the default set of text analyzers.
 pub fn default_analyzers() -> Vec<Box<dyn Analyzer>> {
+    analyzers_with(
+        text::filler_phrases::FillerPhraseAnalyzer::default(),
+        text::hedging_phrases::HedgingPhraseAnalyzer::default(),
+        text::doc_verbosity::DocVerbosityAnalyzer::default(),
+        text::decoration::DecorationAnalyzer::default(),
+    )
+}
+
+/// The default text analyzers, with `filler`, `hedging`, `verbosity` and
+/// `decoration` in place of the default conversational-filler, hedging,
+/// doc-verbosity and Unicode-decoration detectors (see the `[filler]`,
+/// `[hedging]`, `[verbosity]` and `[decoration]` sections of `.vibecheck`).
+pub fn analyzers_with(
+    filler: text::filler_phrases::FillerPhraseAnalyzer,
+    hedging: text::hedging_phrases::HedgingPhraseAnalyzer,
+    verbosity: text::doc_verbosity::DocVerbosityAnalyzer,
+    decoration: text::decoration::DecorationAnalyzer,
+) -> Vec<Box<dyn Analyzer>> {
     vec![
         Box::new(text::comment_style::CommentStyleAnalyzer),
+        Box::new(text::step_comments::StepCommentAnalyzer),
+        Box::new(filler),
+        Box::new(hedging),
+        Box::new(text::echo_comments::EchoCommentAnalyzer),
+        Box::new(text::bullet_comments::BulletCommentAnalyzer),
+        Box::new(decoration),
+        Box::new(verbosity),
         Box::new(text::ai_signals::AiSignalsAnalyzer),
         Box::new(text::error_handling::ErrorHandlingAnalyzer),
         Box::new(text::naming::NamingAnalyzer),
+        Box::new(text::identifier_style::IdentifierStyleAnalyzer),
         Box::new(text::code_structure::CodeStructureAnalyzer),
         Box::new(text::idiom_usage::IdiomUsageAnalyzer),
+        Box::new(text::humanity::HumanityAnalyzer),
     ]
 }
 
@@ -60,6 +60,18 @@ mod tests {
         );
     }
 
+    #[test]
+    fn triple_backtick_skips_rust_doctests() {
+        let mut lines: Vec<&str> = vec!["/// ```", "/// assert!(ok());", "/// ```", "//! ```text", "//! ```"];
+        for _ in 0..10 { lines.push("let x = 1;"); }
+        let has_backtick = |lines: &[&str]| {
+            run(&lines.join("\n")).iter().any(|s| s.id == signal_ids::RUST_AI_SIGNALS_TRIPLE_BACKTICK)
+        };
+        assert!(!has_backtick(&lines), "fenced doctests are idiomatic");
+        lines.push("// ```rust");
+        assert!(has_backtick(&lines), "markdown fence in a plain comment");
+    }
+
     #[test]
     fn all_functions_documented_is_claude() {
         let source = "\
@@ -638,6 +650,25 @@ impl Analyzer for AiSignalsAnalyzer {
             ));
         }
 
+        // GPT: markdown triple-backtick in code comments.  Doc comments are
+        // excluded — fenced doctests in `///` and `//!` are idiomatic Rust.
+        let backtick_count = lines
+            .iter()
+            .filter(|l| {
+                let t = l.trim();
+                t.starts_with("//") && !t.starts_with("///") && !t.starts_with("//!") && t.contains("```")
+            })
+            .count();
+        if backtick_count >= 1 {
+            signals.push(Signal::new(
+                signal_ids::RUST_AI_SIGNALS_TRIPLE_BACKTICK,
+                self.name(),
+                format!("{backtick_count} triple-backtick(s) in comments — markdown artifact"),
+                ModelFamily::Gpt,
+                1.5,
+            ));
+        }
+
         signals
     }
 }
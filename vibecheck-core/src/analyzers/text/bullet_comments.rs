@@ -0,0 +1,159 @@
+use crate::analyzers::Analyzer;
+use crate::heuristics::signal_ids;
+use crate::report::{ModelFamily, Signal};
+
+/// Detects dash-bullet comment lists narrating logic inside function bodies:
+///
+/// ```text
+/// node, found := c.items[key]
+/// // - return zero value on miss
+/// // - move to front on hit
+/// // - return stored value
+/// if !found {
+/// ```
+///
+/// Gemini writes these where a human would write one sentence or nothing.
+/// Bullet lists in doc comments — above a declaration, or at the top level
+/// of a file — are left alone: people write those too.
+pub struct BulletCommentAnalyzer;
+
+/// Consecutive bullet comments that form a list.
+const MIN_RUN: usize = 2;
+
+/// Bullet lines in statement position, across all lists, before the signal
+/// fires.
+const MIN_BULLETS: usize = 3;
+
+/// Leading keywords of function and type definitions.  A list above one is
+/// a doc comment.  `const`/`let`/`var` are absent: inside a body they are
+/// statements.
+const DEFINITION_KEYWORDS: &[&str] = &[
+    "async", "class", "def", "enum", "export", "fn", "func", "function", "impl", "interface",
+    "pub", "struct", "trait", "type",
+];
+
+impl BulletCommentAnalyzer {
+    fn analyze_impl(source: &str, marker: &str, signal_id: &str) -> Vec<Signal> {
+        let lines: Vec<&str> = source.lines().collect();
+        let is_bullet = |l: &str| {
+            l.trim_start()
+                .strip_prefix(marker)
+                .is_some_and(|body| ["- ", "* ", "• "].iter().any(|b| body.trim_start().starts_with(b)))
+        };
+
+        let mut bullets = Vec::new();
+        let mut runs = 0;
+        let mut i = 0;
+        while i < lines.len() {
+            if !is_bullet(lines[i]) {
+                i += 1;
+                continue;
+            }
+            let start = i;
+            while i < lines.len() && is_bullet(lines[i]) {
+                i += 1;
+            }
+            // The list must be indented (inside a body) and followed,
+            // possibly after more comment lines, by a statement rather
+            // than a declaration.
+            let indented = lines[start].starts_with([' ', '\t']);
+            let next = lines[i..]
+                .iter()
+                .map(|l| l.trim())
+                .find(|l| !l.is_empty() && !l.starts_with(marker));
+            let before_statement = next.is_some_and(|l| !opens_definition(l));
+            if i - start >= MIN_RUN && indented && before_statement {
+                runs += 1;
+                bullets.extend(start + 1..=i);
+            }
+        }
+        if bullets.len() < MIN_BULLETS {
+            return vec![];
+        }
+        vec![Signal::new(
+            signal_id,
+            "bullets",
+            format!(
+                "{runs} dash-bullet comment list{} narrating function bodies ({} lines)",
+                if runs == 1 { "" } else { "s" },
+                bullets.len()
+            ),
+            ModelFamily::Gemini,
+            1.5,
+        )
+        .with_lines(bullets)]
+    }
+}
+
+/// Whether `line` starts a definition, or a decorator or attribute on one.
+fn opens_definition(line: &str) -> bool {
+    let first = line.split(|c: char| !c.is_alphanumeric()).next().unwrap_or("");
+    DEFINITION_KEYWORDS.contains(&first) || line.starts_with('@') || line.starts_with("#[")
+}
+
+impl Analyzer for BulletCommentAnalyzer {
+    fn name(&self) -> &str {
+        "bullets"
+    }
+
+    fn analyze_python(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(source, "#", signal_ids::PYTHON_COMMENTS_BULLET_NARRATION)
+    }
+
+    fn analyze_javascript(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(source, "//", signal_ids::JS_COMMENTS_BULLET_NARRATION)
+    }
+
+    fn analyze_go(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(source, "//", signal_ids::GO_COMMENTS_BULLET_NARRATION)
+    }
+
+    fn analyze(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(source, "//", signal_ids::RUST_COMMENTS_BULLET_NARRATION)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn bullets_in_function_bodies_fire_with_lines() {
+        let source = "\
+func (c *Cache) Get(key string) int {
+\tnode, found := c.items[key]
+\t// - return zero value on miss
+\t// - move to front on hit
+\tif !found {
+\t\treturn 0
+\t}
+\t// - return stored value
+\t// - keep the lock held
+\treturn node.value
+}
+";
+        let signals = BulletCommentAnalyzer.analyze_go(source);
+        assert_eq!(signals.len(), 1);
+        assert_eq!(signals[0].id, signal_ids::GO_COMMENTS_BULLET_NARRATION);
+        assert_eq!(signals[0].lines, vec![3, 4, 8, 9]);
+    }
+
+    #[test]
+    fn doc_comment_bullets_do_not_fire() {
+        let source = "\
+// Package lru:
+// - O(1) get and put
+// - fixed capacity
+class Cache:
+    # LRU cache
+    # - O(1) get and put operations
+    # - doubly linked list for ordering
+
+    def __init__(self, cap):
+        # - a single bullet
+        self.cap = cap
+";
+        assert!(BulletCommentAnalyzer.analyze_go(source).is_empty());
+        assert!(BulletCommentAnalyzer.analyze_python(source).is_empty());
+    }
+}
@@ -133,7 +133,6 @@ impl CommentStyleAnalyzer {
         comment_lines_lower: &[String],
         total_lines: usize,
         comment_count: usize,
-        step_id: &str,
         heres_id: &str,
         bullet_id: &str,
         minimal_id: &str,
@@ -143,24 +142,6 @@ impl CommentStyleAnalyzer {
         let mut signals = Vec::new();
         let density = if total_lines > 0 { comment_count as f64 / total_lines as f64 } else { 0.0 };
 
-        // GPT: step-numbered comments ("step 1", "step 2", etc.)
-        let step_count = comment_lines_lower
-            .iter()
-            .filter(|l| {
-                // Match "step N" or "Step N:" patterns
-                l.contains("step 1") || l.contains("step 2") || l.contains("step 3")
-                    || l.contains("step 4") || l.contains("step 5")
-                    || l.contains("1.") || l.contains("2.") || l.contains("3.")
-            })
-            .count();
-        if step_count >= 3 {
-            signals.push(Signal::new(
-                step_id, name,
-                format!("{step_count} step-numbered comments"),
-                ModelFamily::Gpt, 1.5,
-            ));
-        }
-
         // GPT: "here's" / "let's" phrases
         let heres_count = comment_lines_lower
             .iter()
@@ -262,7 +243,6 @@ impl CommentStyleAnalyzer {
         teaching_id: &str,
         explanatory_id: &str,
         terse_id: &str,
-        step_id: &str,
         heres_id: &str,
         bullet_id: &str,
         minimal_id: &str,
@@ -355,7 +335,7 @@ impl CommentStyleAnalyzer {
         let comment_lower: Vec<String> = comment_lines.iter().map(|l| l.to_lowercase()).collect();
         signals.extend(Self::detect_extra_signals(
             name, &comment_lower, total_lines, comment_count,
-            step_id, heres_id, bullet_id, minimal_id, external_id, verbose_id,
+            heres_id, bullet_id, minimal_id, external_id, verbose_id,
         ));
 
         signals
@@ -465,7 +445,6 @@ impl CommentStyleAnalyzer {
         let comment_lower: Vec<String> = comment_lines.iter().map(|l| l.to_lowercase()).collect();
         signals.extend(Self::detect_extra_signals(
             "comments", &comment_lower, total_lines, comment_count,
-            signal_ids::PYTHON_COMMENTS_STEP_NUMBERED,
             signal_ids::PYTHON_COMMENTS_HERES_LETS,
             signal_ids::PYTHON_COMMENTS_BULLET_STYLE,
             signal_ids::PYTHON_COMMENTS_MINIMAL,
@@ -484,7 +463,6 @@ impl CommentStyleAnalyzer {
             signal_ids::JS_COMMENTS_TEACHING_VOICE,
             signal_ids::JS_COMMENTS_SOME_EXPLANATORY,
             signal_ids::JS_COMMENTS_TERSE_MARKERS,
-            signal_ids::JS_COMMENTS_STEP_NUMBERED,
             signal_ids::JS_COMMENTS_HERES_LETS,
             signal_ids::JS_COMMENTS_BULLET_STYLE,
             signal_ids::JS_COMMENTS_MINIMAL,
@@ -518,7 +496,6 @@ impl CommentStyleAnalyzer {
             signal_ids::GO_COMMENTS_TEACHING_VOICE,
             signal_ids::GO_COMMENTS_SOME_EXPLANATORY,
             signal_ids::GO_COMMENTS_TERSE_MARKERS,
-            signal_ids::GO_COMMENTS_STEP_NUMBERED,
             signal_ids::GO_COMMENTS_HERES_LETS,
             signal_ids::GO_COMMENTS_BULLET_STYLE,
             signal_ids::GO_COMMENTS_MINIMAL,
@@ -638,7 +615,6 @@ impl Analyzer for CommentStyleAnalyzer {
         let comment_lower: Vec<String> = comment_lines.iter().map(|l| l.to_lowercase()).collect();
         signals.extend(Self::detect_extra_signals(
             self.name(), &comment_lower, total_lines, comment_count,
-            signal_ids::RUST_COMMENTS_STEP_NUMBERED,
             signal_ids::RUST_COMMENTS_HERES_LETS,
             signal_ids::RUST_COMMENTS_BULLET_STYLE,
             signal_ids::RUST_COMMENTS_MINIMAL,
@@ -0,0 +1,132 @@
+use crate::analyzers::Analyzer;
+use crate::heuristics::signal_ids;
+use crate::report::{ModelFamily, Signal};
+
+/// Detects decorative Unicode — emoji (`✅ Done`, `🚀`), dingbats, arrows,
+/// and box-drawing separators (`// ── Helpers ──`) — in comments and string
+/// literals.  None of these can appear elsewhere in source, so the whole
+/// file is scanned.
+///
+/// Letters and digits of every script, typographic punctuation (`’`, `—`,
+/// `…`) and mathematical operators are never counted, so comments written in
+/// Japanese or German do not fire.  Symbols a codebase uses on purpose can
+/// be exempted with `[decoration] allow = [...]` in `.vibecheck`.
+#[derive(Default)]
+pub struct DecorationAnalyzer {
+    /// Characters exempt from detection.
+    allow: Vec<char>,
+}
+
+/// Decorated lines in a file before the signal fires.
+const MIN_DECORATED_LINES: usize = 2;
+
+/// Code-point ranges counted as decoration.
+const DECORATIVE_RANGES: &[(u32, u32)] = &[
+    (0x2190, 0x21FF),   // Arrows
+    (0x2300, 0x23FF),   // Miscellaneous Technical (⌘, ⏱, ⏳)
+    (0x2500, 0x257F),   // Box Drawing
+    (0x2580, 0x259F),   // Block Elements
+    (0x25A0, 0x25FF),   // Geometric Shapes
+    (0x2600, 0x26FF),   // Miscellaneous Symbols (⚠, ⚡)
+    (0x2700, 0x27BF),   // Dingbats (✅, ✨, ❌)
+    (0x2B00, 0x2BFF),   // Miscellaneous Symbols and Arrows (⭐)
+    (0x1F000, 0x1FAFF), // Emoji and pictographs
+];
+
+impl DecorationAnalyzer {
+    /// A detector that ignores every character of every string in `allow`.
+    pub fn new(allow: &[impl AsRef<str>]) -> Self {
+        let mut allow: Vec<char> = allow.iter().flat_map(|s| s.as_ref().chars()).collect();
+        allow.sort_unstable();
+        allow.dedup();
+        Self { allow }
+    }
+
+    fn is_decoration(&self, c: char) -> bool {
+        let cp = c as u32;
+        DECORATIVE_RANGES.iter().any(|&(lo, hi)| (lo..=hi).contains(&cp)) && !self.allow.contains(&c)
+    }
+
+    fn analyze_impl(&self, source: &str, signal_id: &str) -> Vec<Signal> {
+        // 1-based line numbers of lines carrying decoration.
+        let hits: Vec<usize> = source
+            .lines()
+            .enumerate()
+            .filter(|(_, l)| l.chars().any(|c| self.is_decoration(c)))
+            .map(|(i, _)| i + 1)
+            .collect();
+        if hits.len() < MIN_DECORATED_LINES {
+            return vec![];
+        }
+        vec![Signal::new(
+            signal_id,
+            "decoration",
+            format!("{} lines decorated with emoji, arrows or box-drawing characters", hits.len()),
+            ModelFamily::Gpt,
+            1.5,
+        )
+        .with_lines(hits)]
+    }
+}
+
+impl Analyzer for DecorationAnalyzer {
+    fn name(&self) -> &str {
+        "decoration"
+    }
+
+    fn analyze_python(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, signal_ids::PYTHON_COMMENTS_UNICODE_DECORATION)
+    }
+
+    fn analyze_javascript(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, signal_ids::JS_COMMENTS_UNICODE_DECORATION)
+    }
+
+    fn analyze_go(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, signal_ids::GO_COMMENTS_UNICODE_DECORATION)
+    }
+
+    fn analyze(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, signal_ids::RUST_COMMENTS_UNICODE_DECORATION)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn emoji_and_box_drawing_fire_with_lines() {
+        let source = "\
+// ── Cache ─────────────
+const cache = new Map();
+console.log(\"✅ cache ready\");
+// 🚀 fast path
+";
+        let signals = DecorationAnalyzer::default().analyze_javascript(source);
+        assert_eq!(signals.len(), 1);
+        assert_eq!(signals[0].id, signal_ids::JS_COMMENTS_UNICODE_DECORATION);
+        assert_eq!(signals[0].lines, vec![1, 3, 4]);
+    }
+
+    #[test]
+    fn international_text_does_not_fire() {
+        let source = "\
+# キャッシュから古い項目を削除する。
+# Größe überschreitet die Kapazität — älteste Einträge löschen…
+# «Caché» lleno: ¿desalojar?
+";
+        assert!(DecorationAnalyzer::default().analyze_python(source).is_empty());
+    }
+
+    #[test]
+    fn allowed_symbols_are_ignored() {
+        let source = "\
+// key → node
+// ✓ evicted
+// 🚀 fast path
+";
+        assert_eq!(DecorationAnalyzer::default().analyze_go(source).len(), 1);
+        assert!(DecorationAnalyzer::new(&["→", "✓"]).analyze_go(source).is_empty());
+    }
+}
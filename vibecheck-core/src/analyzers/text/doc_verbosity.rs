@@ -0,0 +1,268 @@
+use crate::analyzers::Analyzer;
+use crate::frontend::Span;
+use crate::heuristics::signal_ids;
+use crate::language::Language;
+use crate::report::{ModelFamily, Signal};
+use crate::source_file::SourceFile;
+
+/// Detects over-documented code: doc comments that run as long as the
+/// functions they describe.  Claude documents even trivial getters with
+/// multi-sentence prose; human code keeps doc comments to a line or two.
+///
+/// Each function's doc lines (the comment block above it, plus a Python
+/// docstring) are divided by its code lines, and the mean ratio is taken
+/// separately for exported API and internal helpers, since public functions
+/// are legitimately documented more.  The file is flagged when either mean
+/// exceeds its threshold.
+pub struct DocVerbosityAnalyzer {
+    /// Mean doc-to-code ratio above which exported functions are flagged.
+    exported: f64,
+    /// Mean doc-to-code ratio above which internal functions are flagged.
+    internal: f64,
+}
+
+/// Default threshold for exported functions.  The human fixtures average
+/// 0.00–0.14; Claude's average 0.36–1.14.
+pub const DEFAULT_EXPORTED_RATIO: f64 = 0.35;
+
+/// Default threshold for internal helpers.  The human fixtures average
+/// 0.00–0.15.
+pub const DEFAULT_INTERNAL_RATIO: f64 = 0.25;
+
+/// Functions in a file before the ratio means anything.
+const MIN_FUNCTIONS: usize = 3;
+
+impl Default for DocVerbosityAnalyzer {
+    fn default() -> Self {
+        Self::new(DEFAULT_EXPORTED_RATIO, DEFAULT_INTERNAL_RATIO)
+    }
+}
+
+/// One function's documentation footprint.
+#[derive(Debug, PartialEq)]
+struct Declaration {
+    /// 1-based line where the documentation (or the function, if
+    /// undocumented) starts.
+    line: usize,
+    doc_lines: usize,
+    code_lines: usize,
+    exported: bool,
+}
+
+impl Declaration {
+    fn ratio(&self) -> f64 {
+        self.doc_lines as f64 / self.code_lines.max(1) as f64
+    }
+}
+
+impl DocVerbosityAnalyzer {
+    pub fn new(exported: f64, internal: f64) -> Self {
+        Self { exported, internal }
+    }
+
+    fn analyze_impl(&self, source: &str, lang: Language, signal_id: &str) -> Vec<Signal> {
+        let file = SourceFile::for_language(source, lang);
+        let lines: Vec<&str> = source.lines().collect();
+        self.verbosity_signal(&declarations(&lines, &file.tokens.functions, lang), signal_id)
+    }
+
+    fn verbosity_signal(&self, decls: &[Declaration], signal_id: &str) -> Vec<Signal> {
+        if decls.len() < MIN_FUNCTIONS {
+            return vec![];
+        }
+        let mut over = Vec::new();
+        let mut lines = Vec::new();
+        for (exported, threshold, kind) in [(true, self.exported, "exported"), (false, self.internal, "internal")] {
+            let kind_decls: Vec<&Declaration> = decls.iter().filter(|d| d.exported == exported).collect();
+            if kind_decls.is_empty() {
+                continue;
+            }
+            let mean = kind_decls.iter().map(|d| d.ratio()).sum::<f64>() / kind_decls.len() as f64;
+            if mean > threshold {
+                over.push(format!("{mean:.2} for {kind} functions"));
+                lines.extend(kind_decls.iter().filter(|d| d.ratio() > threshold).map(|d| d.line));
+            }
+        }
+        if over.is_empty() {
+            return vec![];
+        }
+        lines.sort_unstable();
+        vec![Signal::new(
+            signal_id,
+            "verbosity",
+            format!("Doc comments nearly as long as the code — doc-to-code ratio {}", over.join(", ")),
+            ModelFamily::Claude,
+            1.5,
+        )
+        .with_lines(lines)]
+    }
+}
+
+/// The documentation footprint of each function in `functions`.  Closures
+/// and other functions that do not start their line are not declarations.
+fn declarations(lines: &[&str], functions: &[Span], lang: Language) -> Vec<Declaration> {
+    let (comment_prefixes, attribute_prefixes): (&[&str], &[&str]) = match lang {
+        Language::Python => (&["#"], &["@"]),
+        Language::Rust => (&["//", "/*", "*"], &["#["]),
+        Language::JavaScript => (&["//", "/*", "*"], &["@"]),
+        Language::Go => (&["//", "/*", "*"], &[]),
+    };
+    let mut out = Vec::new();
+    for f in functions {
+        let Some(header) = lines.get(f.start_line - 1).map(|l| l.trim()) else { continue };
+        if !f.text.starts_with(header.split_whitespace().next().unwrap_or("")) {
+            continue;
+        }
+
+        // The comment block directly above, looking through attributes
+        // and decorators.
+        let mut doc_lines = 0;
+        let mut line = f.start_line;
+        let mut i = f.start_line - 1;
+        while i > 0 {
+            let above = lines[i - 1].trim();
+            if comment_prefixes.iter().any(|p| above.starts_with(p)) {
+                doc_lines += 1;
+                line = i;
+            } else if !attribute_prefixes.iter().any(|p| above.starts_with(p)) || above.is_empty() {
+                break;
+            }
+            i -= 1;
+        }
+
+        let mut code_lines = f.line_count();
+        if lang == Language::Python {
+            let docstring = docstring_lines(&lines[f.start_line - 1..f.end_line.min(lines.len())]);
+            doc_lines += docstring;
+            code_lines -= docstring;
+        }
+        out.push(Declaration { line, doc_lines, code_lines, exported: is_exported(header, lang) });
+    }
+    out
+}
+
+/// Lines taken by the docstring opening the Python function whose lines are
+/// `function`, or 0.
+fn docstring_lines(function: &[&str]) -> usize {
+    // The body starts after the line closing the (possibly multi-line)
+    // signature.
+    let Some(start) = function.iter().position(|l| l.trim_end().ends_with(':')).map(|s| s + 1) else { return 0 };
+    let Some(first) = function.get(start).map(|l| l.trim()) else { return 0 };
+    let Some(quote) = ["\"\"\"", "'''"].into_iter().find(|q| first.starts_with(q)) else { return 0 };
+    if first.len() > 3 && first[3..].contains(quote) {
+        return 1;
+    }
+    function[start + 1..]
+        .iter()
+        .position(|l| l.contains(quote))
+        .map_or(0, |end| end + 2)
+}
+
+/// Whether the function declared on `header` is part of the file's API.
+fn is_exported(header: &str, lang: Language) -> bool {
+    match lang {
+        Language::Rust => header.starts_with("pub"),
+        Language::Go => {
+            // `func Name(` or `func (r *T) Name(`.
+            let rest = header.trim_start_matches("func").trim_start();
+            let rest = match rest.strip_prefix('(') {
+                Some(recv) => recv.split_once(')').map_or("", |(_, r)| r.trim_start()),
+                None => rest,
+            };
+            rest.starts_with(|c: char| c.is_uppercase())
+        }
+        Language::Python => {
+            let name = header
+                .trim_start_matches("async ")
+                .trim_start_matches("def ")
+                .split(|c: char| !c.is_alphanumeric() && c != '_')
+                .next()
+                .unwrap_or("");
+            !name.starts_with('_') || (name.starts_with("__") && name.ends_with("__"))
+        }
+        Language::JavaScript => {
+            let name = header
+                .split_whitespace()
+                .find(|w| !matches!(*w, "export" | "default" | "async" | "static" | "get" | "set" | "function" | "const" | "let" | "var"))
+                .unwrap_or("");
+            !name.starts_with(['_', '#'])
+        }
+    }
+}
+
+impl Analyzer for DocVerbosityAnalyzer {
+    fn name(&self) -> &str {
+        "verbosity"
+    }
+
+    fn analyze_python(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, Language::Python, signal_ids::PYTHON_COMMENTS_DOC_VERBOSITY)
+    }
+
+    fn analyze_javascript(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, Language::JavaScript, signal_ids::JS_COMMENTS_DOC_VERBOSITY)
+    }
+
+    fn analyze_go(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, Language::Go, signal_ids::GO_COMMENTS_DOC_VERBOSITY)
+    }
+
+    fn analyze(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, Language::Rust, signal_ids::RUST_COMMENTS_DOC_VERBOSITY)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn span(lines: &[&str], start_line: usize, end_line: usize) -> Span {
+        Span { start_line, end_line, text: lines[start_line - 1..end_line].join("\n") }
+    }
+
+    #[test]
+    fn doc_lines_include_comment_block_and_docstring() {
+        let source = [
+            "# Internal.",
+            "@cached",
+            "def _size(self):",
+            "    \"\"\"Return the size.",
+            "",
+            "    Counts every entry.",
+            "    \"\"\"",
+            "    return len(self.items)",
+        ];
+        let decls = declarations(&source, &[span(&source, 3, 8)], Language::Python);
+        assert_eq!(
+            decls,
+            vec![Declaration { line: 1, doc_lines: 5, code_lines: 2, exported: false }]
+        );
+    }
+
+    #[test]
+    fn exported_follows_language_convention() {
+        assert!(is_exported("pub fn len(&self) -> usize {", Language::Rust));
+        assert!(!is_exported("fn evict(&mut self) {", Language::Rust));
+        assert!(is_exported("func (c *Cache) Get(k string) int {", Language::Go));
+        assert!(!is_exported("func (c *Cache) evict() {", Language::Go));
+        assert!(is_exported("def __len__(self):", Language::Python));
+        assert!(!is_exported("def _evict(self):", Language::Python));
+        assert!(!is_exported("#evict() {", Language::JavaScript));
+        assert!(is_exported("export async function load(path) {", Language::JavaScript));
+    }
+
+    #[test]
+    fn thresholds_apply_per_kind() {
+        let decl = |line, doc_lines, code_lines, exported| Declaration { line, doc_lines, code_lines, exported };
+        let decls = [decl(1, 3, 3, true), decl(8, 4, 4, true), decl(20, 0, 5, false)];
+        let analyzer = DocVerbosityAnalyzer::default();
+        let signals = analyzer.verbosity_signal(&decls, signal_ids::GO_COMMENTS_DOC_VERBOSITY);
+        assert_eq!(signals.len(), 1);
+        assert_eq!(signals[0].family, ModelFamily::Claude);
+        assert_eq!(signals[0].lines, vec![1, 8]);
+
+        let lenient = DocVerbosityAnalyzer::new(1.5, DEFAULT_INTERNAL_RATIO);
+        assert!(lenient.verbosity_signal(&decls, signal_ids::GO_COMMENTS_DOC_VERBOSITY).is_empty());
+        assert!(analyzer.verbosity_signal(&decls[..2], signal_ids::GO_COMMENTS_DOC_VERBOSITY).is_empty());
+    }
+}
@@ -0,0 +1,204 @@
+use crate::analyzers::Analyzer;
+use crate::heuristics::signal_ids;
+use crate::report::{ModelFamily, Signal};
+
+/// Detects "echo comments" — a comment that restates the statement directly
+/// below it, e.g. `// Return a pointer to the newly created cache` above
+/// `return &LruCache{...}`.  The comment's content words are compared with
+/// the identifiers and keywords of the next line; a high overlap means the
+/// comment says nothing the code does not.
+pub struct EchoCommentAnalyzer;
+
+/// Echo comments in a file before the signal fires.
+const MIN_ECHOES: usize = 2;
+
+/// Share of a comment's content words that must appear in the next line.
+const MIN_OVERLAP: f64 = 0.5;
+
+/// Content words a comment needs before it is compared at all, and the
+/// fewest shared words that count as an echo.
+const MIN_SHARED_WORDS: usize = 2;
+
+/// Words that carry no meaning on their own and are ignored in comments.
+const STOPWORDS: &[&str] = &[
+    "a", "all", "an", "and", "any", "are", "as", "at", "be", "by", "each", "for", "from", "if",
+    "in", "into", "is", "it", "its", "of", "on", "or", "our", "so", "some", "that", "the", "then",
+    "this", "to", "we", "with",
+];
+
+/// Operators that a restating comment spells out in words.
+const OPERATOR_WORDS: &[(&str, &[&str])] = &[
+    ("&", &["pointer", "reference", "address"]),
+    ("!", &["not"]),
+    ("==", &["equal", "equals"]),
+    ("+=", &["increment", "add"]),
+    ("-=", &["decrement", "subtract"]),
+];
+
+/// Leading keywords of declarations.  Comments above declarations are doc
+/// comments — Go even requires them to start with the declared name — so
+/// they are not compared.
+const DECLARATION_KEYWORDS: &[&str] = &[
+    "async", "class", "const", "def", "enum", "export", "fn", "from", "func", "function", "impl",
+    "import", "interface", "mod", "package", "pub", "static", "struct", "trait", "type", "use",
+    "var",
+];
+
+impl EchoCommentAnalyzer {
+    fn analyze_impl(source: &str, marker: &str, signal_id: &str) -> Vec<Signal> {
+        let lines: Vec<&str> = source.lines().collect();
+        // 1-based line numbers of comments that restate the next line.
+        let echoes: Vec<usize> = lines
+            .iter()
+            .zip(lines.iter().skip(1))
+            .enumerate()
+            .filter_map(|(i, (line, next))| {
+                let body = line.trim_start().strip_prefix(marker)?;
+                // `///` and `//!` are doc comments; `#!` is a shebang.
+                if body.starts_with(['/', '!']) {
+                    return None;
+                }
+                is_echo(body, next.trim(), marker).then_some(i + 1)
+            })
+            .collect();
+        if echoes.len() < MIN_ECHOES {
+            return vec![];
+        }
+        vec![Signal::new(
+            signal_id,
+            "echo",
+            format!("{} comments restate the line of code below them", echoes.len()),
+            ModelFamily::Gpt,
+            1.0,
+        )
+        .with_lines(echoes)]
+    }
+}
+
+/// Whether the comment `body` (marker stripped) restates the statement `next`.
+fn is_echo(body: &str, next: &str, marker: &str) -> bool {
+    if next.is_empty() || next.starts_with(marker) || is_declaration(next) || is_commented_code(body) {
+        return false;
+    }
+    let comment: Vec<String> = words(body)
+        .into_iter()
+        .filter(|w| !STOPWORDS.contains(&w.as_str()))
+        .collect();
+    if comment.len() < MIN_SHARED_WORDS {
+        return false;
+    }
+    let mut code = words(next);
+    for (op, spelled) in OPERATOR_WORDS {
+        if next.contains(op) {
+            code.extend(spelled.iter().map(|w| w.to_string()));
+        }
+    }
+    let shared = comment.iter().filter(|w| code.iter().any(|c| same_word(w, c))).count();
+    shared >= MIN_SHARED_WORDS && shared as f64 / comment.len() as f64 >= MIN_OVERLAP
+}
+
+fn is_declaration(line: &str) -> bool {
+    let first = line.split(|c: char| !c.is_alphanumeric()).next().unwrap_or("");
+    DECLARATION_KEYWORDS.contains(&first)
+}
+
+/// Commented-out code (`// old := e.v`) trivially overlaps the live line
+/// that replaced it; it is a human habit, not an echo.
+fn is_commented_code(body: &str) -> bool {
+    let body = body.trim_end();
+    body.contains('=') || body.ends_with([';', '{', '}'])
+}
+
+/// Lowercased words of `text`, with `camelCase`, `PascalCase` and
+/// `snake_case` identifiers split into their parts.
+pub(crate) fn words(text: &str) -> Vec<String> {
+    let mut out = Vec::new();
+    for token in text.split(|c: char| !c.is_ascii_alphanumeric()).filter(|t| !t.is_empty()) {
+        let chars: Vec<char> = token.chars().collect();
+        let mut start = 0;
+        for i in 1..chars.len() {
+            let (prev, cur) = (chars[i - 1], chars[i]);
+            let next_lower = chars.get(i + 1).is_some_and(|c| c.is_ascii_lowercase());
+            // "lruCache" → lru|Cache; "LRUCache" → LRU|Cache.
+            if cur.is_ascii_uppercase() && (!prev.is_ascii_uppercase() || next_lower) {
+                out.push(chars[start..i].iter().collect::<String>().to_lowercase());
+                start = i;
+            }
+        }
+        out.push(chars[start..].iter().collect::<String>().to_lowercase());
+    }
+    out
+}
+
+/// Equal, or one is a short inflection of the other (`create`/`created`,
+/// `new`/`newly`).
+fn same_word(a: &str, b: &str) -> bool {
+    let (short, long) = if a.len() <= b.len() { (a, b) } else { (b, a) };
+    short == long || (short.len() >= 3 && long.starts_with(short) && long.len() - short.len() <= 3)
+}
+
+impl Analyzer for EchoCommentAnalyzer {
+    fn name(&self) -> &str {
+        "echo"
+    }
+
+    fn analyze_python(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(source, "#", signal_ids::PYTHON_COMMENTS_ECHO_COMMENT)
+    }
+
+    fn analyze_javascript(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(source, "//", signal_ids::JS_COMMENTS_ECHO_COMMENT)
+    }
+
+    fn analyze_go(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(source, "//", signal_ids::GO_COMMENTS_ECHO_COMMENT)
+    }
+
+    fn analyze(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(source, "//", signal_ids::RUST_COMMENTS_ECHO_COMMENT)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn splits_identifiers_into_words() {
+        assert_eq!(words("return &LruCache{"), vec!["return", "lru", "cache"]);
+        assert_eq!(words("self.map.contains_key(key)"), vec!["self", "map", "contains", "key", "key"]);
+        assert_eq!(words("LRUCache"), vec!["lru", "cache"]);
+    }
+
+    #[test]
+    fn restating_comments_fire_with_lines() {
+        let source = "\
+func New() *LruCache {
+\t// Return a pointer to the newly created cache.
+\treturn &LruCache{}
+}
+
+func (c *LruCache) Len() int {
+\t// Return the length of the internal map.
+\treturn len(c.items)
+}
+";
+        let signals = EchoCommentAnalyzer.analyze_go(source);
+        assert_eq!(signals.len(), 1);
+        assert_eq!(signals[0].id, signal_ids::GO_COMMENTS_ECHO_COMMENT);
+        assert_eq!(signals[0].lines, vec![2, 7]);
+    }
+
+    #[test]
+    fn explanatory_doc_and_commented_out_code_do_not_fire() {
+        let source = "\
+# Evict before insert so capacity is never exceeded.
+self.cache[key] = value
+# Get returns the cached value.
+def get(self, key):
+# old = n.v
+n.v = v
+";
+        assert!(EchoCommentAnalyzer.analyze_python(source).is_empty());
+    }
+}
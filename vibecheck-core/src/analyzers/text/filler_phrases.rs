@@ -0,0 +1,160 @@
+use crate::analyzers::Analyzer;
+use crate::heuristics::signal_ids;
+use crate::report::{ModelFamily, Signal};
+
+/// Detects first-person-plural narration in comments — `// Let's ...`,
+/// `// Here's how we ...`, `// Now we ...` — the conversational voice LLMs
+/// carry over from chat answers into code, and which human-written code
+/// almost never uses.
+///
+/// The phrase list is built from per-locale tables ([`BUILTIN_PHRASES`]) plus
+/// any extra phrases from the `[filler]` section of `.vibecheck`.
+pub struct FillerPhraseAnalyzer {
+    /// Lowercased phrases, matched at word boundaries.
+    phrases: Vec<String>,
+}
+
+/// Matching comment lines in a file before the signal fires.
+const MIN_FILLER_LINES: usize = 2;
+
+/// Built-in filler phrases by locale.  Enable more locales with
+/// `[filler] locales = [...]` in `.vibecheck`.
+pub const BUILTIN_PHRASES: &[(&str, &[&str])] = &[
+    ("en", &[
+        "let's", "let us", "here's how we", "here's where we", "here is how we",
+        "now we", "we'll", "we will now", "we're going to", "first, we",
+        "next, we", "then we", "finally, we",
+    ]),
+    ("de", &["lass uns", "lasst uns", "jetzt können wir", "nun können wir", "hier ist, wie wir", "als nächstes"]),
+    ("es", &["vamos a", "veamos", "ahora vamos", "así es como", "aquí es donde"]),
+    ("fr", &["allons-y", "voyons", "nous allons", "maintenant, nous", "voici comment nous"]),
+];
+
+/// Locales enabled when `.vibecheck` does not choose any.
+pub const DEFAULT_LOCALES: &[&str] = &["en"];
+
+impl Default for FillerPhraseAnalyzer {
+    fn default() -> Self {
+        Self::new(DEFAULT_LOCALES, &[] as &[&str])
+    }
+}
+
+impl FillerPhraseAnalyzer {
+    /// Phrases from the built-in tables for `locales`, plus `extra`.
+    /// Unknown locales contribute nothing.
+    pub fn new(locales: &[impl AsRef<str>], extra: &[impl AsRef<str>]) -> Self {
+        let builtin = BUILTIN_PHRASES
+            .iter()
+            .filter(|(locale, _)| locales.iter().any(|l| l.as_ref().eq_ignore_ascii_case(locale)))
+            .flat_map(|(_, phrases)| phrases.iter().copied());
+        let mut phrases: Vec<String> = builtin
+            .chain(extra.iter().map(|p| p.as_ref()))
+            .map(normalize)
+            .filter(|p| !p.is_empty())
+            .collect();
+        phrases.sort();
+        phrases.dedup();
+        Self { phrases }
+    }
+
+    fn analyze_impl(&self, source: &str, marker: &str, signal_id: &str) -> Vec<Signal> {
+        // 1-based line numbers of comments that use a filler phrase.
+        let hits: Vec<usize> = source
+            .lines()
+            .enumerate()
+            .filter_map(|(i, l)| l.trim_start().strip_prefix(marker).map(|body| (i, body)))
+            .filter(|(_, body)| {
+                let body = normalize(body);
+                self.phrases.iter().any(|p| contains_phrase(&body, p))
+            })
+            .map(|(i, _)| i + 1)
+            .collect();
+        if hits.len() < MIN_FILLER_LINES {
+            return vec![];
+        }
+        vec![Signal::new(
+            signal_id,
+            "filler",
+            format!("{} comments narrate in the first person plural (\"let's\", \"now we\")", hits.len()),
+            ModelFamily::Gpt,
+            1.5,
+        )
+        .with_lines(hits)]
+    }
+}
+
+/// Lowercase and fold typographic apostrophes so `Let’s` matches `let's`.
+pub(crate) fn normalize(s: &str) -> String {
+    s.trim().to_lowercase().replace('\u{2019}', "'")
+}
+
+/// Whether `phrase` occurs in `text` as whole words — `"now we"` matches
+/// `"and now we evict"` but not `"snow weather"`.
+pub(crate) fn contains_phrase(text: &str, phrase: &str) -> bool {
+    let is_word = |c: Option<char>| c.is_some_and(char::is_alphanumeric);
+    text.match_indices(phrase).any(|(start, _)| {
+        let end = start + phrase.len();
+        !is_word(text[..start].chars().next_back()) && !is_word(text[end..].chars().next())
+    })
+}
+
+impl Analyzer for FillerPhraseAnalyzer {
+    fn name(&self) -> &str {
+        "filler"
+    }
+
+    fn analyze_python(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, "#", signal_ids::PYTHON_COMMENTS_CONVERSATIONAL_FILLER)
+    }
+
+    fn analyze_javascript(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, "//", signal_ids::JS_COMMENTS_CONVERSATIONAL_FILLER)
+    }
+
+    fn analyze_go(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, "//", signal_ids::GO_COMMENTS_CONVERSATIONAL_FILLER)
+    }
+
+    fn analyze(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, "//", signal_ids::RUST_COMMENTS_CONVERSATIONAL_FILLER)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn matches_whole_words_only() {
+        assert!(contains_phrase("and now we evict", "now we"));
+        assert!(contains_phrase("let's go", "let's"));
+        assert!(!contains_phrase("snow weather", "now we"));
+        assert!(!contains_phrase("outlet's plug", "let's"));
+    }
+
+    #[test]
+    fn narration_is_gpt_with_lines() {
+        let source = "// Let’s build the map.\nlet m = 1;\n// Here's how we evict.\n// Evict the oldest.\n";
+        let signals = FillerPhraseAnalyzer::default().analyze(source);
+        assert_eq!(signals.len(), 1);
+        assert_eq!(signals[0].id, signal_ids::RUST_COMMENTS_CONVERSATIONAL_FILLER);
+        assert_eq!(signals[0].family, ModelFamily::Gpt);
+        assert_eq!(signals[0].lines, vec![1, 3]);
+    }
+
+    #[test]
+    fn single_phrase_or_string_literal_does_not_fire() {
+        let source = "// Let's see.\nlet msg = \"now we wait\";\n";
+        assert!(FillerPhraseAnalyzer::default().analyze(source).is_empty());
+    }
+
+    #[test]
+    fn locales_and_extra_phrases_are_configurable() {
+        let source = "# Lass uns die Liste sortieren.\n# As you can see, it works.\n";
+        assert!(FillerPhraseAnalyzer::default().analyze_python(source).is_empty());
+        let analyzer = FillerPhraseAnalyzer::new(&["en", "DE"], &["As you can see"]);
+        let signals = analyzer.analyze_python(source);
+        assert_eq!(signals[0].id, signal_ids::PYTHON_COMMENTS_CONVERSATIONAL_FILLER);
+        assert_eq!(signals[0].lines, vec![1, 2]);
+    }
+}
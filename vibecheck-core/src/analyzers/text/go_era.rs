@@ -0,0 +1,213 @@
+//! Detects Go idioms the file's own module has outgrown: code written for an
+//! older toolchain than the `go` directive in the nearest `go.mod` declares.
+//! Models reproduce the Go of their training data, so `interface{}`,
+//! `io/ioutil` and loop-variable copies keep appearing in modules that
+//! require Go 1.22.
+//!
+//! Unlike the other text analyzers this needs the file's location, so the
+//! pipeline calls [`anachronisms`] directly for Go files inside a module.
+
+use std::path::Path;
+
+use crate::heuristics::signal_ids;
+use crate::language::{detect_language, Language};
+use crate::report::{ModelFamily, Signal};
+
+/// A Go language version, `go 1.21` → `GoVersion { major: 1, minor: 21 }`.
+/// Patch releases are irrelevant to which idioms are available.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord)]
+pub struct GoVersion {
+    pub major: u32,
+    pub minor: u32,
+}
+
+impl GoVersion {
+    const fn new(major: u32, minor: u32) -> Self {
+        Self { major, minor }
+    }
+}
+
+impl std::fmt::Display for GoVersion {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        write!(f, "{}.{}", self.major, self.minor)
+    }
+}
+
+/// `any` replaced `interface{}` in Go 1.18.
+const ANY: GoVersion = GoVersion::new(1, 18);
+/// `io` and `os` took over every `io/ioutil` function in Go 1.16.
+const IOUTIL_REPLACED: GoVersion = GoVersion::new(1, 16);
+/// Loop variables became per-iteration in Go 1.22, making `v := v` a no-op.
+const PER_ITERATION_LOOP_VARS: GoVersion = GoVersion::new(1, 22);
+
+/// The version in a `go.mod`'s `go` directive, if it has one.
+pub fn parse_go_mod(contents: &str) -> Option<GoVersion> {
+    let version = contents
+        .lines()
+        .find_map(|l| l.trim().strip_prefix("go ").map(str::trim))?;
+    let version = version.split("//").next()?.trim();
+    let mut parts = version.split('.');
+    let major = parts.next()?.parse().ok()?;
+    let minor = parts.next().and_then(|m| {
+        // Pre-release versions such as `1.21rc1`.
+        let digits: String = m.chars().take_while(char::is_ascii_digit).collect();
+        digits.parse().ok()
+    })?;
+    Some(GoVersion::new(major, minor))
+}
+
+/// The Go version declared by the module containing `path`: the `go`
+/// directive of the nearest `go.mod` above it.
+pub fn module_go_version(path: &Path) -> Option<GoVersion> {
+    let go_mod = path.ancestors().skip(1).map(|dir| dir.join("go.mod")).find(|p| p.is_file())?;
+    parse_go_mod(&std::fs::read_to_string(go_mod).ok()?)
+}
+
+/// A cache setting recording the module Go version a Go file at `path` is
+/// analyzed under, so that bumping `go.mod` re-analyzes its files.
+pub fn cache_setting(path: &Path) -> Option<String> {
+    if detect_language(path) != Some(Language::Go) {
+        return None;
+    }
+    module_go_version(path).map(|v| format!("go.version={v}"))
+}
+
+/// Signals for idioms in Go `source` that predate `version`.
+pub fn anachronisms(source: &str, version: GoVersion) -> Vec<Signal> {
+    let mut empty_interfaces = Vec::new();
+    let mut ioutil = Vec::new();
+    let mut loop_var_copies = Vec::new();
+    let mut previous = "";
+
+    for (i, line) in source.lines().enumerate() {
+        let code = line.split("//").next().unwrap_or("").trim();
+        if code.is_empty() {
+            continue;
+        }
+        if code.contains("interface{}") {
+            empty_interfaces.push(i + 1);
+        }
+        if code.contains("\"io/ioutil\"") || code.contains("ioutil.") {
+            ioutil.push(i + 1);
+        }
+        if previous.starts_with("for ") && is_self_copy(code) {
+            loop_var_copies.push(i + 1);
+        }
+        previous = code;
+    }
+
+    let mut signals = Vec::new();
+    if version >= ANY && !empty_interfaces.is_empty() {
+        signals.push(
+            Signal::new(
+                signal_ids::GO_ERA_INTERFACE_ANY,
+                "era",
+                format!("`interface{{}}` instead of `any` in a Go {version} module"),
+                ModelFamily::Gpt,
+                1.0,
+            )
+            .with_lines(empty_interfaces),
+        );
+    }
+    if version >= IOUTIL_REPLACED && !ioutil.is_empty() {
+        signals.push(
+            Signal::new(
+                signal_ids::GO_ERA_IOUTIL,
+                "era",
+                format!("Deprecated `io/ioutil` in a Go {version} module"),
+                ModelFamily::Gpt,
+                1.5,
+            )
+            .with_lines(ioutil),
+        );
+    }
+    if version >= PER_ITERATION_LOOP_VARS && !loop_var_copies.is_empty() {
+        signals.push(
+            Signal::new(
+                signal_ids::GO_ERA_LOOP_VAR_COPY,
+                "era",
+                format!("Pre-1.22 loop variable copy (`v := v`) in a Go {version} module"),
+                ModelFamily::Gpt,
+                1.5,
+            )
+            .with_lines(loop_var_copies),
+        );
+    }
+    signals
+}
+
+/// `v := v`, the first statement of a loop body that captures `v`.
+fn is_self_copy(code: &str) -> bool {
+    let Some((left, right)) = code.split_once(":=") else {
+        return false;
+    };
+    let (left, right) = (left.trim(), right.trim());
+    !left.is_empty()
+        && left == right
+        && left.chars().all(|c| c.is_alphanumeric() || c == '_')
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const OLD_STYLE: &str = r#"package store
+
+import "io/ioutil"
+
+func Load(path string) (map[string]interface{}, error) {
+    data, err := ioutil.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    for _, tc := range cases {
+        tc := tc
+        run(tc, data)
+    }
+    return nil, nil
+}
+"#;
+
+    #[test]
+    fn parses_go_directive() {
+        let go_mod = "module example.com/m\n\ngo 1.22.3 // toolchain pinned below\n\ntoolchain go1.22.4\n";
+        assert_eq!(parse_go_mod(go_mod), Some(GoVersion::new(1, 22)));
+        assert_eq!(parse_go_mod("module m\ngo 1.21rc1\n"), Some(GoVersion::new(1, 21)));
+        assert_eq!(parse_go_mod("module m\n"), None);
+    }
+
+    #[test]
+    fn modern_module_flags_old_idioms_with_lines() {
+        let signals = anachronisms(OLD_STYLE, GoVersion::new(1, 22));
+        let ids: Vec<&str> = signals.iter().map(|s| s.id.as_str()).collect();
+        assert_eq!(
+            ids,
+            [signal_ids::GO_ERA_INTERFACE_ANY, signal_ids::GO_ERA_IOUTIL, signal_ids::GO_ERA_LOOP_VAR_COPY]
+        );
+        assert_eq!(signals[0].lines, vec![5]);
+        assert_eq!(signals[1].lines, vec![3, 6]);
+        assert_eq!(signals[2].lines, vec![11]);
+    }
+
+    #[test]
+    fn old_module_is_not_anachronistic() {
+        assert!(anachronisms(OLD_STYLE, GoVersion::new(1, 15)).is_empty());
+        let ids: Vec<String> = anachronisms(OLD_STYLE, GoVersion::new(1, 20))
+            .into_iter()
+            .map(|s| s.id)
+            .collect();
+        assert_eq!(ids, [signal_ids::GO_ERA_INTERFACE_ANY, signal_ids::GO_ERA_IOUTIL]);
+    }
+
+    #[test]
+    fn finds_nearest_go_mod() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join("go.mod"), "module m\n\ngo 1.21\n").unwrap();
+        let nested = dir.path().join("tools");
+        std::fs::create_dir(&nested).unwrap();
+        std::fs::write(nested.join("go.mod"), "module m/tools\n\ngo 1.23\n").unwrap();
+
+        assert_eq!(module_go_version(&dir.path().join("main.go")), Some(GoVersion::new(1, 21)));
+        assert_eq!(module_go_version(&nested.join("gen.go")), Some(GoVersion::new(1, 23)));
+    }
+}
@@ -0,0 +1,157 @@
+use crate::analyzers::text::filler_phrases::{contains_phrase, normalize};
+use crate::analyzers::Analyzer;
+use crate::heuristics::signal_ids;
+use crate::report::{ModelFamily, Signal};
+
+/// Detects hedging and assurance phrases in comments and docs — "Note that",
+/// "This ensures", "Make sure", "otherwise the cache would be unable to".
+/// Models pad explanations with them; people state the fact and move on.
+///
+/// The lexicon is [`BUILTIN_PHRASES`] plus any extra phrases from the
+/// `[hedging]` section of `.vibecheck`, which can also change how many
+/// matching lines a file needs before the signal fires.
+pub struct HedgingPhraseAnalyzer {
+    /// Lowercased phrases, matched at word boundaries.
+    phrases: Vec<String>,
+    min_lines: usize,
+}
+
+/// Built-in hedging and assurance phrases, curated from the Claude and GPT
+/// fixtures; none occurs in the human ones.
+pub const BUILTIN_PHRASES: &[&str] = &[
+    "note that", "this ensures", "ensures that", "this guarantees", "guarantees that",
+    "make sure", "be sure to", "keep in mind", "it's important", "it is important",
+    "in order to", "would be unable to", "so that we", "this way",
+];
+
+/// Matching lines a file needs before the signal fires, unless `.vibecheck`
+/// sets `[hedging] min_lines`.
+pub const DEFAULT_MIN_LINES: usize = 2;
+
+impl Default for HedgingPhraseAnalyzer {
+    fn default() -> Self {
+        Self::new(&[] as &[&str], DEFAULT_MIN_LINES)
+    }
+}
+
+impl HedgingPhraseAnalyzer {
+    /// The built-in phrases plus `extra`, firing at `min_lines` matching
+    /// lines (at least one).
+    pub fn new(extra: &[impl AsRef<str>], min_lines: usize) -> Self {
+        let mut phrases: Vec<String> = BUILTIN_PHRASES
+            .iter()
+            .copied()
+            .chain(extra.iter().map(|p| p.as_ref()))
+            .map(normalize)
+            .filter(|p| !p.is_empty())
+            .collect();
+        phrases.sort();
+        phrases.dedup();
+        Self { phrases, min_lines: min_lines.max(1) }
+    }
+
+    fn analyze_impl(&self, source: &str, marker: &str, docstrings: bool, signal_id: &str) -> Vec<Signal> {
+        let hits: Vec<usize> = prose_lines(source, marker, docstrings)
+            .into_iter()
+            .filter(|(_, text)| {
+                let text = normalize(text);
+                self.phrases.iter().any(|p| contains_phrase(&text, p))
+            })
+            .map(|(line, _)| line)
+            .collect();
+        if hits.len() < self.min_lines {
+            return vec![];
+        }
+        vec![Signal::new(
+            signal_id,
+            "hedging",
+            format!("{} comment lines hedge or reassure (\"note that\", \"this ensures\")", hits.len()),
+            ModelFamily::Claude,
+            1.5,
+        )
+        .with_lines(hits)]
+    }
+}
+
+/// 1-based line numbers and text of the prose lines in `source`: `marker`
+/// comments (doc comments included), the continuation lines of `/* ... */`
+/// blocks, and — when `docstrings` is set — lines inside `"""`/`'''` strings.
+pub(crate) fn prose_lines<'a>(source: &'a str, marker: &str, docstrings: bool) -> Vec<(usize, &'a str)> {
+    let mut out = Vec::new();
+    let mut in_docstring = false;
+    for (i, line) in source.lines().enumerate() {
+        let t = line.trim_start();
+        let quotes = t.matches("\"\"\"").count() + t.matches("'''").count();
+        if docstrings && (in_docstring || quotes > 0) {
+            out.push((i + 1, t));
+            in_docstring ^= quotes % 2 == 1;
+        } else if let Some(body) = t.strip_prefix(marker) {
+            out.push((i + 1, body));
+        } else if marker == "//" && (t.starts_with("/*") || t.starts_with('*')) {
+            out.push((i + 1, t.trim_start_matches(['/', '*'])));
+        }
+    }
+    out
+}
+
+impl Analyzer for HedgingPhraseAnalyzer {
+    fn name(&self) -> &str {
+        "hedging"
+    }
+
+    fn analyze_python(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, "#", true, signal_ids::PYTHON_COMMENTS_HEDGING_PHRASES)
+    }
+
+    fn analyze_javascript(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, "//", false, signal_ids::JS_COMMENTS_HEDGING_PHRASES)
+    }
+
+    fn analyze_go(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, "//", false, signal_ids::GO_COMMENTS_HEDGING_PHRASES)
+    }
+
+    fn analyze(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, "//", false, signal_ids::RUST_COMMENTS_HEDGING_PHRASES)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn hedging_in_comments_and_docstrings_fires_with_lines() {
+        let source = "\
+def put(self, key, value):
+    \"\"\"Insert a value.
+
+    Note that the oldest entry is evicted first.
+    \"\"\"
+    # Make sure the capacity is respected.
+    self.evict()
+    msg = \"note that this is a string\"
+";
+        let signals = HedgingPhraseAnalyzer::default().analyze_python(source);
+        assert_eq!(signals.len(), 1);
+        assert_eq!(signals[0].id, signal_ids::PYTHON_COMMENTS_HEDGING_PHRASES);
+        assert_eq!(signals[0].family, ModelFamily::Claude);
+        assert_eq!(signals[0].lines, vec![4, 6]);
+    }
+
+    #[test]
+    fn block_comments_count_and_a_single_hit_does_not_fire() {
+        let source = "/**\n * This ensures eviction.\n */\nfunction f() {}\n";
+        assert!(HedgingPhraseAnalyzer::default().analyze_javascript(source).is_empty());
+        let signals = HedgingPhraseAnalyzer::new(&[] as &[&str], 1).analyze_javascript(source);
+        assert_eq!(signals[0].lines, vec![2]);
+    }
+
+    #[test]
+    fn extra_phrases_are_configurable() {
+        let source = "// Rest assured, it works.\n// Rest assured, it is fast.\n";
+        assert!(HedgingPhraseAnalyzer::default().analyze_go(source).is_empty());
+        let signals = HedgingPhraseAnalyzer::new(&["Rest assured"], DEFAULT_MIN_LINES).analyze_go(source);
+        assert_eq!(signals[0].lines, vec![1, 2]);
+    }
+}
@@ -0,0 +1,337 @@
+use crate::analyzers::Analyzer;
+use crate::heuristics::signal_ids;
+use crate::language::{get_ts_language, Language};
+use crate::report::{ModelFamily, Signal};
+
+/// Detects the traces people leave in code and models do not: ticket
+/// references (`GO-342`, `#1034`), `@name` mentions, lint suppressions
+/// (`//nolint`, `# noqa`), `FIXME`/`XXX`/`HACK` markers and commented-out
+/// code — a comment that parses as a statement of the file's language.
+///
+/// One of each is already telling, so these signals fire on a single
+/// occurrence.  Their weights are negative: instead of raising the Human
+/// score they count against every AI family (see `Pipeline::aggregate`), so
+/// a file's attribution reflects evidence in both directions.
+pub struct HumanityAnalyzer;
+
+/// The signal IDs for one language.
+struct Ids {
+    ticket_refs: &'static str,
+    mentions: &'static str,
+    /// `None` for Rust, whose suppressions are `#[allow]` attributes that
+    /// models write as readily as people.
+    lint_directives: Option<&'static str>,
+    fixme_markers: &'static str,
+    commented_out_code: &'static str,
+}
+
+/// Lint-suppression directives, matched case-sensitively in comment bodies.
+const LINT_DIRECTIVES: &[&str] = &[
+    "nolint", "noqa", "type: ignore", "pylint: disable", "eslint-disable", "@ts-ignore",
+    "@ts-expect-error", "istanbul ignore",
+];
+
+/// Doc-comment tags that look like `@mentions` but are not.
+pub(crate) const DOC_TAGS: &[&str] = &[
+    "param", "return", "returns", "throws", "type", "typedef", "see", "example", "deprecated",
+    "since", "async", "override", "private", "public", "template", "callback", "property",
+    "link", "author", "version", "todo",
+];
+
+/// Upper-case prefixes of standard names, not ticket keys (`UTF-8`, `SHA-256`).
+pub(crate) const NOT_TICKET_KEYS: &[&str] = &["AES", "ECMA", "ISO", "MD", "RFC", "RSA", "SHA", "UTF"];
+
+/// Markers that mean "this is knowingly wrong", written in upper case.
+const FIXME_MARKERS: &[&str] = &["FIXME", "XXX", "HACK"];
+
+impl HumanityAnalyzer {
+    fn analyze_impl(source: &str, marker: &str, language: Language, ids: Ids) -> Vec<Signal> {
+        // (1-based line, comment body)
+        let comments: Vec<(usize, &str)> = source
+            .lines()
+            .enumerate()
+            .filter_map(|(i, l)| comment_body(l, marker).map(|body| (i + 1, body)))
+            .collect();
+        let lines_where = |pred: &mut dyn FnMut(&str) -> bool| -> Vec<usize> {
+            comments.iter().filter(|(_, body)| pred(body)).map(|&(line, _)| line).collect()
+        };
+        let mut code = CodeRecognizer::new(language);
+
+        let detectors: [(Option<&str>, Vec<usize>, &str, f64); 5] = [
+            (Some(ids.ticket_refs), lines_where(&mut has_ticket_ref), "ticket/issue reference", -1.5),
+            (Some(ids.mentions), lines_where(&mut has_mention), "@mention of a colleague", -1.5),
+            (ids.lint_directives, lines_where(&mut has_lint_directive), "lint suppression directive", -1.0),
+            (Some(ids.fixme_markers), lines_where(&mut has_fixme), "FIXME/XXX/HACK marker", -1.0),
+            (Some(ids.commented_out_code), lines_where(&mut |body| code.is_code(body)), "line of commented-out code", -1.0),
+        ];
+        detectors
+            .into_iter()
+            .filter_map(|(id, lines, what, weight)| Some((id?, lines, what, weight)))
+            .filter(|(_, lines, _, _)| !lines.is_empty())
+            .map(|(id, lines, what, weight)| {
+                let n = lines.len();
+                Signal::new(id, "humanity", format!("{n} {what}{}", if n == 1 { "" } else { "s" }), ModelFamily::Human, weight)
+                    .with_lines(lines)
+            })
+            .collect()
+    }
+}
+
+/// The body of a comment on `line` — a whole-line comment or a trailing one
+/// (`code //nolint`).  Doc comments (`///`, `//!`) are skipped, as is a
+/// marker inside a `"..."` or `` `...` `` string.  Single quotes are not
+/// tracked: they open Rust lifetimes and appear in comment prose.
+fn comment_body<'a>(line: &'a str, marker: &str) -> Option<&'a str> {
+    let mut in_string = None;
+    let mut escaped = false;
+    for (i, c) in line.char_indices() {
+        match in_string {
+            Some(_) if escaped => escaped = false,
+            Some(_) if c == '\\' => escaped = true,
+            Some(q) if c == q => in_string = None,
+            Some(_) => {}
+            None if matches!(c, '"' | '`') => in_string = Some(c),
+            None if line[i..].starts_with(marker) => {
+                let body = &line[i + marker.len()..];
+                return (!body.starts_with(['/', '!'])).then_some(body);
+            }
+            None => {}
+        }
+    }
+    None
+}
+
+/// `GO-342`, `JIRA-17`, `#1034` — upper-case project key and number, or a
+/// `#` issue number of two or more digits.
+fn has_ticket_ref(body: &str) -> bool {
+    let bytes = body.as_bytes();
+    (0..bytes.len()).any(|i| {
+        let prev_is_word = i > 0 && bytes[i - 1].is_ascii_alphanumeric();
+        if prev_is_word {
+            return false;
+        }
+        let key = bytes[i..].iter().take_while(|b| b.is_ascii_uppercase()).count();
+        let digits_after = |at: usize| bytes[at..].iter().take_while(|b| b.is_ascii_digit()).count();
+        let is_standard = NOT_TICKET_KEYS.iter().any(|k| k.as_bytes() == &bytes[i..i + key]);
+        (key >= 2 && !is_standard && bytes.get(i + key) == Some(&b'-') && digits_after(i + key + 1) >= 1)
+            || (bytes[i] == b'#' && digits_after(i + 1) >= 2)
+    })
+}
+
+/// `@agarwal` — but not an e-mail address or a doc tag like `@param`.
+fn has_mention(body: &str) -> bool {
+    body.match_indices('@').any(|(i, _)| {
+        let prev_is_word = body[..i].chars().next_back().is_some_and(|c| c.is_alphanumeric());
+        let name: String = body[i + 1..].chars().take_while(|c| c.is_alphanumeric() || *c == '-' || *c == '_').collect();
+        !prev_is_word
+            && name.starts_with(|c: char| c.is_ascii_lowercase())
+            && !DOC_TAGS.contains(&name.as_str())
+    })
+}
+
+fn has_lint_directive(body: &str) -> bool {
+    LINT_DIRECTIVES.iter().any(|d| body.contains(d))
+}
+
+fn has_fixme(body: &str) -> bool {
+    FIXME_MARKERS.iter().any(|m| {
+        body.match_indices(m).any(|(i, _)| {
+            let before = body[..i].chars().next_back();
+            let after = body[i + m.len()..].chars().next();
+            !before.is_some_and(char::is_alphanumeric) && !after.is_some_and(char::is_alphanumeric)
+        })
+    })
+}
+
+/// Recognizes commented-out code by parsing comment bodies as statements.
+struct CodeRecognizer {
+    language: Language,
+    parser: tree_sitter::Parser,
+}
+
+impl CodeRecognizer {
+    fn new(language: Language) -> Self {
+        let mut parser = tree_sitter::Parser::new();
+        parser.set_language(&get_ts_language(language)).expect("bundled grammar");
+        Self { language, parser }
+    }
+
+    /// Whether `body` is a line of code rather than prose: it has code
+    /// punctuation and parses without errors where a statement can go.  A
+    /// line opening a block (`if x {`, `def f():`) is closed before
+    /// parsing, and one closing a block (`}`, `} else {`) is opened.
+    fn is_code(&mut self, body: &str) -> bool {
+        let t = body.trim();
+        if !t.contains(['=', '(', ')', '[', ']', '{', '}', ';', '.']) || has_lint_directive(t) {
+            return false;
+        }
+        // `(optional)`, `[1]`: an aside, even when it parses.
+        if t.starts_with(['(', '[']) {
+            return false;
+        }
+        let rest = t.trim_start_matches(|c: char| matches!(c, '}' | ')' | ']') || c.is_whitespace());
+        if rest.is_empty() {
+            return true;
+        }
+        let mut line = String::new();
+        if rest.starts_with("else") || rest.starts_with("elif") || rest.starts_with("except") {
+            line.push_str(match self.language {
+                Language::Python if rest.starts_with("except") => "try:\n    pass\n",
+                Language::Python => "if x:\n    pass\n",
+                Language::JavaScript => "if (x) {} ",
+                Language::Rust | Language::Go => "if x {} ",
+            });
+        }
+        line.push_str(rest);
+        if self.language == Language::Python {
+            if rest.ends_with(':') {
+                line.push_str("\n    pass");
+            }
+        } else {
+            let unclosed = rest.matches('{').count().saturating_sub(rest.matches('}').count());
+            line.push_str(&"}".repeat(unclosed));
+        }
+
+        let contexts: &[(&str, &str)] = match self.language {
+            Language::Rust => &[("fn f() {\n", "\n}")],
+            // A function body for statements, the top level for declarations.
+            Language::Go => &[("package p\nfunc f() {\n", "\n}"), ("package p\n", "")],
+            Language::Python | Language::JavaScript => &[("", "")],
+        };
+        contexts.iter().any(|(before, after)| {
+            let Some(tree) = self.parser.parse(format!("{before}{line}{after}"), None) else {
+                return false;
+            };
+            // `Note: see foo()` parses as a label in Go and JavaScript.
+            !tree.root_node().has_error() && !has_kind(tree.root_node(), "labeled_statement")
+        })
+    }
+}
+
+fn has_kind(node: tree_sitter::Node, kind: &str) -> bool {
+    node.kind() == kind || (0..node.child_count()).filter_map(|i| node.child(i)).any(|c| has_kind(c, kind))
+}
+
+impl Analyzer for HumanityAnalyzer {
+    fn name(&self) -> &str {
+        "humanity"
+    }
+
+    fn analyze_python(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(source, "#", Language::Python, Ids {
+            ticket_refs: signal_ids::PYTHON_HUMANITY_TICKET_REFS,
+            mentions: signal_ids::PYTHON_HUMANITY_MENTIONS,
+            lint_directives: Some(signal_ids::PYTHON_HUMANITY_LINT_DIRECTIVES),
+            fixme_markers: signal_ids::PYTHON_HUMANITY_FIXME_MARKERS,
+            commented_out_code: signal_ids::PYTHON_HUMANITY_COMMENTED_OUT_CODE,
+        })
+    }
+
+    fn analyze_javascript(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(source, "//", Language::JavaScript, Ids {
+            ticket_refs: signal_ids::JS_HUMANITY_TICKET_REFS,
+            mentions: signal_ids::JS_HUMANITY_MENTIONS,
+            lint_directives: Some(signal_ids::JS_HUMANITY_LINT_DIRECTIVES),
+            fixme_markers: signal_ids::JS_HUMANITY_FIXME_MARKERS,
+            commented_out_code: signal_ids::JS_HUMANITY_COMMENTED_OUT_CODE,
+        })
+    }
+
+    fn analyze_go(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(source, "//", Language::Go, Ids {
+            ticket_refs: signal_ids::GO_HUMANITY_TICKET_REFS,
+            mentions: signal_ids::GO_HUMANITY_MENTIONS,
+            lint_directives: Some(signal_ids::GO_HUMANITY_LINT_DIRECTIVES),
+            fixme_markers: signal_ids::GO_HUMANITY_FIXME_MARKERS,
+            commented_out_code: signal_ids::GO_HUMANITY_COMMENTED_OUT_CODE,
+        })
+    }
+
+    fn analyze(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(source, "//", Language::Rust, Ids {
+            ticket_refs: signal_ids::RUST_HUMANITY_TICKET_REFS,
+            mentions: signal_ids::RUST_HUMANITY_MENTIONS,
+            lint_directives: None,
+            fixme_markers: signal_ids::RUST_HUMANITY_FIXME_MARKERS,
+            commented_out_code: signal_ids::RUST_HUMANITY_COMMENTED_OUT_CODE,
+        })
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn recognizes_markers() {
+        assert!(has_ticket_ref(" see GO-342"));
+        assert!(has_ticket_ref(" sharded map (see #1034)"));
+        assert!(!has_ticket_ref(" UTF-8 input"));
+        assert!(!has_ticket_ref(" step #1"));
+        assert!(has_mention(" @agarwal asked us"));
+        assert!(!has_mention(" @param key the key"));
+        assert!(!has_mention(" mail ops@example.com"));
+        assert!(has_fixme(" FIXME: evict doesn't shrink"));
+        assert!(!has_fixme(" fixme later, XXXL sizes"));
+    }
+
+    #[test]
+    fn commented_out_code_must_parse() {
+        let mut go = CodeRecognizer::new(Language::Go);
+        for code in [" old := e.v", " cache.clear()", " for _, e := range c.items {", " } else {", " }", " import \"go.uber.org/zap\""] {
+            assert!(go.is_code(code), "{code:?}");
+        }
+        for prose in [
+            " Evict the oldest entry (the tail)",
+            " x = y means they match",
+            " Output: foo()",
+            " (optional)",
+            " see https://go.dev/ref/spec",
+            " nolint:errcheck // cache.clear()",
+        ] {
+            assert!(!go.is_code(prose), "{prose:?}");
+        }
+
+        let mut python = CodeRecognizer::new(Language::Python);
+        assert!(python.is_code(" self.cache = {}"));
+        assert!(python.is_code(" if key in self.cache:"));
+        assert!(python.is_code(" except KeyError:"));
+        assert!(!python.is_code(" Note: this is slow."));
+        assert!(!python.is_code(" the cache is keyed by path.name"));
+
+        let mut rust = CodeRecognizer::new(Language::Rust);
+        assert!(rust.is_code(" let old = std::mem::replace(&mut e.v, v);"));
+        assert!(!rust.is_code(" Safety: the pointer is valid for reads."));
+
+        let mut js = CodeRecognizer::new(Language::JavaScript);
+        assert!(js.is_code(" console.log(entry);"));
+        assert!(!js.is_code(" Example: lru.get(key)"));
+    }
+
+    #[test]
+    fn each_marker_is_one_negative_signal_with_lines() {
+        let source = "\
+// LRU cache for the cfg service layer - see GO-342
+// @agarwal asked us to keep allocs low
+func New(cap int) *Cache { //nolint:revive
+\t// old := e.v
+\treturn nil
+}
+// FIXME: evict doesn't shrink the map - GO-351
+";
+        let signals = HumanityAnalyzer.analyze_go(source);
+        let by_id = |id: &str| signals.iter().find(|s| s.id == id).unwrap_or_else(|| panic!("{id} missing"));
+        assert_eq!(by_id(signal_ids::GO_HUMANITY_TICKET_REFS).lines, vec![1, 7]);
+        assert_eq!(by_id(signal_ids::GO_HUMANITY_MENTIONS).lines, vec![2]);
+        assert_eq!(by_id(signal_ids::GO_HUMANITY_LINT_DIRECTIVES).lines, vec![3]);
+        assert_eq!(by_id(signal_ids::GO_HUMANITY_COMMENTED_OUT_CODE).lines, vec![4]);
+        assert_eq!(by_id(signal_ids::GO_HUMANITY_FIXME_MARKERS).lines, vec![7]);
+        assert!(signals.iter().all(|s| s.weight < 0.0 && s.family == ModelFamily::Human));
+    }
+
+    #[test]
+    fn markers_in_strings_and_doc_comments_are_ignored() {
+        let source = "/// See GO-342.\nlet url = \"http://x//nolint\";\n";
+        assert!(HumanityAnalyzer.analyze(source).is_empty());
+    }
+}
@@ -0,0 +1,274 @@
+use std::collections::BTreeMap;
+
+use crate::analyzers::text::echo_comments::words;
+use crate::analyzers::Analyzer;
+use crate::heuristics::signal_ids;
+use crate::report::{ModelFamily, Signal};
+
+/// Detects over-descriptive Go identifiers — `NewLeastRecentlyUsedCache`,
+/// `entryLookupTable`, `evictLeastRecentlyUsedEntry` — where idiomatic Go
+/// uses `New`, `idx` and `evict`.
+///
+/// Rather than a hard length cutoff, the file's declared identifiers are
+/// scored against the distribution of identifier length and word count in
+/// the Go fixture corpus: the signal fires when a one-sample z-test puts the
+/// file's means far above the corpus means.
+pub struct IdentifierStyleAnalyzer;
+
+/// Identifier length (chars) and word count over the unique declared names
+/// of each `tests/fixtures/**/*.go` file, pooled.  Kept in sync with the
+/// fixtures by `baseline_matches_fixture_corpus`.
+const CORPUS_LEN_MEAN: f64 = 6.272;
+const CORPUS_LEN_SD: f64 = 4.750;
+const CORPUS_WORDS_MEAN: f64 = 1.412;
+const CORPUS_WORDS_SD: f64 = 0.759;
+
+/// Combined z-score of the file's means above which the file is flagged.
+const MIN_FILE_Z: f64 = 3.0;
+
+/// Word-count z-score above which a single identifier is pointed at.
+const MIN_IDENT_Z: f64 = 2.0;
+
+/// Distinct identifiers a file needs before its means are tested.
+const MIN_IDENTIFIERS: usize = 8;
+
+impl IdentifierStyleAnalyzer {
+    fn analyze_go_impl(source: &str) -> Vec<Signal> {
+        let idents = go_identifiers(source);
+        if idents.len() < MIN_IDENTIFIERS {
+            return vec![];
+        }
+        let n = idents.len() as f64;
+        let mean_len = idents.keys().map(|id| id.len() as f64).sum::<f64>() / n;
+        let mean_words = idents.keys().map(|id| word_count(id) as f64).sum::<f64>() / n;
+        let z_len = (mean_len - CORPUS_LEN_MEAN) / (CORPUS_LEN_SD / n.sqrt());
+        let z_words = (mean_words - CORPUS_WORDS_MEAN) / (CORPUS_WORDS_SD / n.sqrt());
+        let z = (z_len + z_words) / 2.0;
+        if z < MIN_FILE_Z {
+            return vec![];
+        }
+
+        let mut lines: Vec<usize> = idents
+            .iter()
+            .filter(|(id, _)| (word_count(id) as f64 - CORPUS_WORDS_MEAN) / CORPUS_WORDS_SD > MIN_IDENT_Z)
+            .map(|(_, &line)| line)
+            .collect();
+        lines.sort_unstable();
+        lines.dedup();
+        vec![Signal::new(
+            signal_ids::GO_NAMING_OVER_DESCRIPTIVE,
+            "identifiers",
+            format!(
+                "Identifiers far longer than idiomatic Go (avg {mean_len:.1} chars, {mean_words:.1} words; z = {z:.1})"
+            ),
+            ModelFamily::Claude,
+            1.5,
+        )
+        .with_lines(lines)]
+    }
+}
+
+fn word_count(ident: &str) -> usize {
+    words(ident).len()
+}
+
+/// Distinct identifiers declared in Go `source` — types, struct fields,
+/// functions, receivers, parameters, `var`s and `:=` bindings — each with the
+/// 1-based line of its first declaration.
+fn go_identifiers(source: &str) -> BTreeMap<String, usize> {
+    let mut out: BTreeMap<String, usize> = BTreeMap::new();
+    let mut add = |name: &str, line: usize| {
+        if is_ident(name) && name != "_" {
+            out.entry(name.to_string()).or_insert(line);
+        }
+    };
+    let mut in_struct = false;
+    for (i, raw) in source.lines().enumerate() {
+        let line = i + 1;
+        let t = raw.trim();
+        if t.starts_with("//") {
+            continue;
+        }
+        if in_struct {
+            if t.starts_with('}') {
+                in_struct = false;
+            } else if let [field, _, ..] = t.split_whitespace().collect::<Vec<_>>()[..] {
+                add(field, line);
+            }
+            continue;
+        }
+        if let Some(rest) = t.strip_prefix("type ") {
+            let mut parts = rest.split_whitespace();
+            if let Some(name) = parts.next() {
+                add(name, line);
+            }
+            in_struct = parts.next() == Some("struct") && t.ends_with('{');
+            continue;
+        }
+        if let Some(rest) = t.strip_prefix("func ") {
+            let rest = match rest.strip_prefix('(') {
+                // Receiver: `(c *Cache)`.
+                Some(recv) => {
+                    let Some((recv, after)) = recv.split_once(')') else { continue };
+                    if let Some(name) = recv.split_whitespace().next() {
+                        add(name, line);
+                    }
+                    after.trim_start()
+                }
+                None => rest,
+            };
+            if let Some((name, after)) = rest.split_once('(') {
+                add(name.trim(), line);
+                let params = after.split_once(')').map_or("", |(p, _)| p);
+                for param in params.split(',') {
+                    if let Some(name) = param.split_whitespace().next() {
+                        add(name, line);
+                    }
+                }
+            }
+        }
+        if let Some(rest) = t.strip_prefix("var ") {
+            if let Some(name) = rest.split_whitespace().next() {
+                add(name, line);
+            }
+        }
+        let binding = t.strip_prefix("if ").or_else(|| t.strip_prefix("for ")).unwrap_or(t);
+        if let Some((lhs, _)) = binding.split_once(":=") {
+            if lhs.chars().all(|c| c.is_alphanumeric() || c == '_' || c == ',' || c.is_whitespace()) {
+                for name in lhs.split(',') {
+                    add(name.trim(), line);
+                }
+            }
+        }
+    }
+    out
+}
+
+fn is_ident(s: &str) -> bool {
+    s.starts_with(|c: char| c.is_alphabetic() || c == '_') && s.chars().all(|c| c.is_alphanumeric() || c == '_')
+}
+
+impl Analyzer for IdentifierStyleAnalyzer {
+    fn name(&self) -> &str {
+        "identifiers"
+    }
+
+    fn analyze_python(&self, _source: &str) -> Vec<Signal> {
+        vec![]
+    }
+
+    fn analyze_javascript(&self, _source: &str) -> Vec<Signal> {
+        vec![]
+    }
+
+    fn analyze_go(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_go_impl(source)
+    }
+
+    fn analyze(&self, _source: &str) -> Vec<Signal> {
+        vec![]
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn extracts_declared_identifiers() {
+        let source = "\
+type Cache struct {
+\tentryLookupTable map[string]int
+\tsync.Mutex
+}
+
+func (c *Cache) Get(k string, fallbackValue int) int {
+\tif v, ok := c.entryLookupTable[k]; ok {
+\t\treturn v
+\t}
+\tvar _ = 1
+\treturn fallbackValue
+}
+";
+        let idents = go_identifiers(source);
+        let names: Vec<&str> = idents.keys().map(String::as_str).collect();
+        assert_eq!(names, vec!["Cache", "Get", "c", "entryLookupTable", "fallbackValue", "k", "ok", "v"]);
+        assert_eq!(idents["entryLookupTable"], 2);
+        assert_eq!(idents["ok"], 7);
+    }
+
+    #[test]
+    fn baseline_matches_fixture_corpus() {
+        let dir = std::path::Path::new(env!("CARGO_MANIFEST_DIR")).join("tests/fixtures/lru_cache");
+        let mut lens = Vec::new();
+        let mut counts = Vec::new();
+        for entry in std::fs::read_dir(dir).unwrap().flatten() {
+            let path = entry.path();
+            if path.extension().is_some_and(|e| e == "go") {
+                for id in go_identifiers(&std::fs::read_to_string(&path).unwrap()).keys() {
+                    lens.push(id.len() as f64);
+                    counts.push(word_count(id) as f64);
+                }
+            }
+        }
+        let stats = |xs: &[f64]| {
+            let mean = xs.iter().sum::<f64>() / xs.len() as f64;
+            let var = xs.iter().map(|x| (x - mean).powi(2)).sum::<f64>() / xs.len() as f64;
+            (mean, var.sqrt())
+        };
+        let (len_mean, len_sd) = stats(&lens);
+        let (words_mean, words_sd) = stats(&counts);
+        for (name, actual, constant) in [
+            ("CORPUS_LEN_MEAN", len_mean, CORPUS_LEN_MEAN),
+            ("CORPUS_LEN_SD", len_sd, CORPUS_LEN_SD),
+            ("CORPUS_WORDS_MEAN", words_mean, CORPUS_WORDS_MEAN),
+            ("CORPUS_WORDS_SD", words_sd, CORPUS_WORDS_SD),
+        ] {
+            assert!((actual - constant).abs() < 0.001, "{name} is {constant}; the fixtures give {actual:.3}");
+        }
+    }
+
+    #[test]
+    fn over_descriptive_file_fires_idiomatic_does_not() {
+        let verbose = "\
+type LeastRecentlyUsedCache struct {
+\tmaximumCapacity  int
+\tentryLookupTable map[string]int
+\taccessOrderList  []string
+}
+
+func NewLeastRecentlyUsedCache(maximumCapacity int) *LeastRecentlyUsedCache {
+\treturn nil
+}
+
+func (cache *LeastRecentlyUsedCache) evictLeastRecentlyUsedEntry(evictedEntryKey string) {
+\tcurrentEntryCount := len(cache.entryLookupTable)
+\tremainingCapacityAfterEviction := cache.maximumCapacity - currentEntryCount
+\t_ = remainingCapacityAfterEviction
+}
+";
+        let signals = IdentifierStyleAnalyzer.analyze_go(verbose);
+        assert_eq!(signals.len(), 1);
+        assert_eq!(signals[0].id, signal_ids::GO_NAMING_OVER_DESCRIPTIVE);
+        assert!(signals[0].lines.contains(&7), "{:?}", signals[0].lines);
+
+        let idiomatic = "\
+type Cache struct {
+\tcap int
+\tidx map[string]int
+\tll  []string
+}
+
+func New(cap int) *Cache {
+\treturn nil
+}
+
+func (c *Cache) evict(k string) {
+\tn := len(c.idx)
+\tfree := c.cap - n
+\t_ = free
+}
+";
+        assert!(IdentifierStyleAnalyzer.analyze_go(idiomatic).is_empty());
+    }
+}
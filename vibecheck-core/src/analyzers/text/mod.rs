@@ -1,6 +1,18 @@
 pub mod ai_signals;
+pub mod bullet_comments;
 pub mod code_structure;
 pub mod comment_style;
+pub mod decoration;
+pub mod doc_verbosity;
+pub mod echo_comments;
 pub mod error_handling;
+pub mod filler_phrases;
+pub mod go_era;
+pub mod hedging_phrases;
+pub mod humanity;
+pub mod identifier_style;
 pub mod idiom_usage;
 pub mod naming;
+pub mod perplexity;
+pub mod step_comments;
+pub mod stylometry;
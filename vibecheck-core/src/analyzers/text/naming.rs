@@ -1,5 +1,6 @@
 use crate::analyzers::Analyzer;
 use crate::heuristics::signal_ids;
+use crate::language::Language;
 use crate::report::{ModelFamily, Signal};
 
 pub struct NamingAnalyzer;
@@ -147,6 +148,41 @@ var transformationOutput string\n";
             "expected Claude signal for very descriptive Go names"
         );
     }
+
+    #[test]
+    fn conventions_are_classified() {
+        assert_eq!(convention("evictOldest"), Some(Convention::LowerCamel));
+        assert_eq!(convention("GetOldest"), Some(Convention::UpperCamel));
+        assert_eq!(convention("evict_oldest"), Some(Convention::Snake));
+        assert_eq!(convention("Get_keys"), Some(Convention::Snake));
+        assert_eq!(convention("MAX_ENTRIES"), Some(Convention::ScreamingSnake));
+        assert_eq!(convention("_private_helper"), Some(Convention::Snake));
+        assert_eq!(convention("cache"), None);
+        assert_eq!(convention("Len"), None);
+        assert_eq!(convention("LRU"), None);
+    }
+
+    #[test]
+    fn go_exported_and_snake_mix_is_copilot() {
+        let names: Vec<String> = ["GetOldest", "NewCache", "resultKeys", "evict_oldest", "peek_value", "get_keys", "c"]
+            .iter()
+            .map(|n| n.to_string())
+            .collect();
+        let signal = mixed_conventions(&names, Language::Go, signal_ids::GO_NAMING_MIXED_CONVENTIONS, "naming").unwrap();
+        assert_eq!(signal.family, ModelFamily::Copilot);
+        assert!(signal.description.contains("3 camelCase vs 3 snake_case"), "{}", signal.description);
+    }
+
+    #[test]
+    fn python_classes_beside_snake_functions_are_consistent() {
+        let names: Vec<String> = ["LeastRecentlyUsedCache", "OrderedDict", "insert_entry", "retrieve_entry", "MAX_SIZE"]
+            .iter()
+            .map(|n| n.to_string())
+            .collect();
+        assert!(mixed_conventions(&names, Language::Python, signal_ids::PYTHON_NAMING_MIXED_CONVENTIONS, "naming").is_none());
+        // In Go the same spellings are a mix of MixedCaps and underscores.
+        assert!(mixed_conventions(&names, Language::Go, signal_ids::GO_NAMING_MIXED_CONVENTIONS, "naming").is_some());
+    }
 }
 
 impl NamingAnalyzer {
@@ -192,6 +228,7 @@ impl NamingAnalyzer {
         mixed_conventions_id: &str,
         domain_abbreviations_id: &str,
         names: &[String],
+        lang: Language,
     ) -> Vec<Signal> {
         let mut signals = Vec::new();
         if names.is_empty() {
@@ -253,28 +290,7 @@ impl NamingAnalyzer {
             ));
         }
 
-        // Mixed naming conventions (both camelCase and snake_case)
-        let camel_count = names
-            .iter()
-            .filter(|n| n.len() > 2 && n.chars().any(|c| c.is_uppercase()) && !n.contains('_'))
-            .count();
-        let snake_count = names
-            .iter()
-            .filter(|n| {
-                n.contains('_')
-                    && n.chars()
-                        .all(|c| c.is_lowercase() || c == '_' || c.is_numeric())
-            })
-            .count();
-        if camel_count >= 2 && snake_count >= 2 {
-            signals.push(Signal::new(
-                mixed_conventions_id,
-                source_name,
-                format!("{camel_count} camelCase + {snake_count} snake_case identifiers"),
-                ModelFamily::Copilot,
-                1.5,
-            ));
-        }
+        signals.extend(mixed_conventions(names, lang, mixed_conventions_id, source_name));
 
         // Domain abbreviations
         const ABBREVIATIONS: &[&str] = &[
@@ -322,6 +338,7 @@ impl NamingAnalyzer {
             signal_ids::PYTHON_NAMING_MIXED_CONVENTIONS,
             signal_ids::PYTHON_NAMING_DOMAIN_ABBREVIATIONS,
             &names,
+            Language::Python,
         )
     }
 
@@ -360,6 +377,7 @@ impl NamingAnalyzer {
             signal_ids::JS_NAMING_MIXED_CONVENTIONS,
             signal_ids::JS_NAMING_DOMAIN_ABBREVIATIONS,
             &names,
+            Language::JavaScript,
         )
     }
 
@@ -411,10 +429,101 @@ impl NamingAnalyzer {
             signal_ids::GO_NAMING_MIXED_CONVENTIONS,
             signal_ids::GO_NAMING_DOMAIN_ABBREVIATIONS,
             &names,
+            Language::Go,
         )
     }
 }
 
+/// Share of a file's multi-word identifiers that must follow its dominant
+/// convention before the file counts as consistent.
+const MIN_CONVENTION_CONSISTENCY: f64 = 0.9;
+
+/// Case convention of a multi-word identifier.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+enum Convention {
+    /// `evictOldest`
+    LowerCamel,
+    /// `GetOldest`
+    UpperCamel,
+    /// `evict_oldest`, and hybrids such as `Get_keys`
+    Snake,
+    /// `MAX_ENTRIES`
+    ScreamingSnake,
+}
+
+/// The convention `name` is written in, or `None` for single-word names
+/// (`cache`, `Len`, `LRU`), which fit every convention.  Leading and trailing
+/// underscores (`_private`, `__dunder__`) are ignored.
+fn convention(name: &str) -> Option<Convention> {
+    let name = name.trim_matches('_');
+    if name.contains('_') {
+        return Some(if name.chars().any(|c| c.is_lowercase()) {
+            Convention::Snake
+        } else {
+            Convention::ScreamingSnake
+        });
+    }
+    let mut chars = name.chars();
+    let first = chars.next()?;
+    let rest: Vec<char> = chars.collect();
+    if !rest.iter().any(|c| c.is_lowercase()) {
+        return None;
+    }
+    let interior_upper = rest.iter().any(|c| c.is_uppercase());
+    match (first.is_uppercase(), interior_upper) {
+        (false, true) => Some(Convention::LowerCamel),
+        (true, true) => Some(Convention::UpperCamel),
+        _ => None,
+    }
+}
+
+/// Whether `conv` counts toward the camelCase side (`Some(true)`), the
+/// snake_case side (`Some(false)`) or neither.  Go writes every identifier
+/// in MixedCaps, exported (`GetOldest`) or not (`getOldest`), so there
+/// PascalCase and SCREAMING_SNAKE are part of the mix; elsewhere they are
+/// how types and constants are legitimately spelled next to either style.
+fn camel_side(conv: Convention, lang: Language) -> Option<bool> {
+    match (conv, lang) {
+        (Convention::LowerCamel, _) => Some(true),
+        (Convention::Snake, _) => Some(false),
+        (Convention::UpperCamel, Language::Go) => Some(true),
+        (Convention::ScreamingSnake, Language::Go) => Some(false),
+        _ => None,
+    }
+}
+
+/// Flag files whose identifiers do not settle on one convention — e.g.
+/// `GetOldest` next to `evict_oldest` and `peek_value` in a Go file.  Fires
+/// when the minority style has at least two names and the dominant style
+/// covers less than [`MIN_CONVENTION_CONSISTENCY`] of the file.
+fn mixed_conventions(names: &[String], lang: Language, signal_id: &str, source_name: &str) -> Option<Signal> {
+    let mut unique: Vec<&str> = names.iter().map(String::as_str).collect();
+    unique.sort_unstable();
+    unique.dedup();
+    let (mut camel, mut snake) = (0usize, 0usize);
+    for side in unique.iter().filter_map(|n| convention(n)).filter_map(|c| camel_side(c, lang)) {
+        if side {
+            camel += 1;
+        } else {
+            snake += 1;
+        }
+    }
+    let consistency = camel.max(snake) as f64 / (camel + snake).max(1) as f64;
+    if camel.min(snake) < 2 || consistency >= MIN_CONVENTION_CONSISTENCY {
+        return None;
+    }
+    Some(Signal::new(
+        signal_id,
+        source_name,
+        format!(
+            "Inconsistent naming: {camel} camelCase vs {snake} snake_case identifiers ({:.0}% consistent)",
+            consistency * 100.0
+        ),
+        ModelFamily::Copilot,
+        1.5,
+    ))
+}
+
 impl Analyzer for NamingAnalyzer {
     fn name(&self) -> &str {
         "naming"
@@ -557,33 +666,18 @@ impl Analyzer for NamingAnalyzer {
             ));
         }
 
-        // Mixed naming conventions (both camelCase and snake_case in same file)
+        // Naming-convention consistency
         let all_names: Vec<String> = let_names
             .iter()
             .chain(fn_names.iter())
             .map(|n| n.to_string())
             .collect();
-        let camel_count = all_names
-            .iter()
-            .filter(|n| n.len() > 2 && n.chars().any(|c| c.is_uppercase()) && !n.contains('_'))
-            .count();
-        let snake_count = all_names
-            .iter()
-            .filter(|n| {
-                n.contains('_')
-                    && n.chars()
-                        .all(|c| c.is_lowercase() || c == '_' || c.is_numeric())
-            })
-            .count();
-        if camel_count >= 2 && snake_count >= 2 {
-            signals.push(Signal::new(
-                signal_ids::RUST_NAMING_MIXED_CONVENTIONS,
-                self.name(),
-                format!("{camel_count} camelCase + {snake_count} snake_case identifiers"),
-                ModelFamily::Copilot,
-                1.5,
-            ));
-        }
+        signals.extend(mixed_conventions(
+            &all_names,
+            Language::Rust,
+            signal_ids::RUST_NAMING_MIXED_CONVENTIONS,
+            self.name(),
+        ));
 
         // Domain abbreviations
         const ABBREVIATIONS: &[&str] = &[
@@ -0,0 +1,351 @@
+use std::path::PathBuf;
+use std::sync::{Arc, Mutex, OnceLock};
+use std::time::{Duration, Instant};
+
+use sha2::{Digest, Sha256};
+
+use crate::analyzers::Analyzer;
+use crate::capability::Capability;
+use crate::heuristics::signal_ids;
+use crate::language::Language;
+use crate::report::{ModelFamily, Signal};
+use crate::source_file::SourceFile;
+
+/// Scores code by its perplexity under a language model.  Generated code is
+/// the kind of text a model finds most likely, so a low perplexity is
+/// evidence of AI authorship and a high one of a person.
+///
+/// Opt-in: the token log-probabilities come from an LLM endpoint configured
+/// in the `[perplexity]` section of `.vibecheck`, so the detector is absent
+/// unless `enabled = true`.  Requests are spaced out to the configured rate,
+/// and each result is cached on disk by a hash of the model and the code, so
+/// a file is scored once per model however often it is analyzed.  When the
+/// endpoint fails the detector emits nothing for the file, whose report
+/// lists [`Capability::Perplexity`] as degraded.
+pub struct PerplexityAnalyzer {
+    source: Arc<dyn LogprobSource>,
+    /// Perplexity at or below which the `low` signal fires.
+    low: f64,
+    /// Perplexity at or above which the `high` signal fires.
+    high: f64,
+    /// Minimum time between requests.
+    interval: Duration,
+    /// Where perplexities are cached; `None` disables the cache.
+    cache_dir: Option<PathBuf>,
+}
+
+/// A language model that returns the log-probability of every token of a
+/// text given the tokens before it.
+pub trait LogprobSource: Send + Sync {
+    /// Identifies the model in cache keys, e.g. `"davinci-002@https://api.openai.com/v1"`.
+    fn model(&self) -> &str;
+
+    /// Natural-log probabilities of the tokens of `text`.  The first token
+    /// has no context and may be omitted.
+    fn token_logprobs(&self, text: &str) -> anyhow::Result<Vec<f64>>;
+}
+
+/// Thresholds and request rate unless `.vibecheck` sets them.
+pub const DEFAULT_LOW: f64 = 2.5;
+pub const DEFAULT_HIGH: f64 = 6.0;
+pub const DEFAULT_REQUESTS_PER_MINUTE: u32 = 60;
+
+/// Only the head of long files is scored, to bound the cost of a request.
+const MAX_CHARS: usize = 16_000;
+
+/// Fewer scored tokens than this say too little to fire either signal.
+const MIN_TOKENS: usize = 20;
+
+impl PerplexityAnalyzer {
+    pub fn new(source: Arc<dyn LogprobSource>, low: f64, high: f64, requests_per_minute: u32) -> Self {
+        Self {
+            source,
+            low,
+            high,
+            interval: Duration::from_secs(60) / requests_per_minute.max(1),
+            cache_dir: None,
+        }
+    }
+
+    /// Cache perplexities under `dir`.
+    pub fn with_cache_dir(mut self, dir: PathBuf) -> Self {
+        self.cache_dir = Some(dir);
+        self
+    }
+
+    /// The perplexity of `text`, from the cache when it has been scored by
+    /// the same model before.  `None` when the text is too short to say;
+    /// an error when the request failed.
+    pub fn perplexity(&self, text: &str) -> anyhow::Result<Option<f64>> {
+        let key: String = Sha256::digest(format!("{}\0{text}", self.source.model()))
+            .iter()
+            .map(|b| format!("{b:02x}"))
+            .collect();
+        let cached = self.cache_dir.as_ref().map(|dir| dir.join(&key));
+        if let Some(value) = cached.as_ref().and_then(|p| std::fs::read_to_string(p).ok()) {
+            return Ok(value.trim().parse().ok());
+        }
+
+        throttle(self.interval);
+        let logprobs = self.source.token_logprobs(text)?;
+        if logprobs.len() < MIN_TOKENS {
+            return Ok(None);
+        }
+        let perplexity = (-logprobs.iter().sum::<f64>() / logprobs.len() as f64).exp();
+        if let Some(path) = cached {
+            let _ = std::fs::create_dir_all(path.parent().unwrap());
+            let _ = std::fs::write(path, perplexity.to_string());
+        }
+        Ok(Some(perplexity))
+    }
+
+    /// The signals for `source` in `lang`, or the request's error.
+    fn signals(&self, source: &str, lang: Option<Language>) -> anyhow::Result<Vec<Signal>> {
+        let (low_id, high_id) = match lang {
+            None | Some(Language::Rust) => (signal_ids::RUST_PERPLEXITY_LOW, signal_ids::RUST_PERPLEXITY_HIGH),
+            Some(Language::Python) => (signal_ids::PYTHON_PERPLEXITY_LOW, signal_ids::PYTHON_PERPLEXITY_HIGH),
+            Some(Language::JavaScript) => (signal_ids::JS_PERPLEXITY_LOW, signal_ids::JS_PERPLEXITY_HIGH),
+            Some(Language::Go) => (signal_ids::GO_PERPLEXITY_LOW, signal_ids::GO_PERPLEXITY_HIGH),
+        };
+        let Some(perplexity) = self.perplexity(head(source))? else {
+            return Ok(vec![]);
+        };
+        Ok(if perplexity <= self.low {
+            vec![Signal::new(
+                low_id,
+                "perplexity",
+                format!("Low perplexity under {} ({perplexity:.2})", self.source.model()),
+                ModelFamily::Gpt,
+                2.0,
+            )]
+        } else if perplexity >= self.high {
+            vec![Signal::new(
+                high_id,
+                "perplexity",
+                format!("High perplexity under {} ({perplexity:.2})", self.source.model()),
+                ModelFamily::Human,
+                -1.5,
+            )]
+        } else {
+            vec![]
+        })
+    }
+}
+
+impl Analyzer for PerplexityAnalyzer {
+    fn name(&self) -> &str {
+        "perplexity"
+    }
+
+    fn analyze_python(&self, source: &str) -> Vec<Signal> {
+        self.signals(source, Some(Language::Python)).unwrap_or_default()
+    }
+
+    fn analyze_javascript(&self, source: &str) -> Vec<Signal> {
+        self.signals(source, Some(Language::JavaScript)).unwrap_or_default()
+    }
+
+    fn analyze_go(&self, source: &str) -> Vec<Signal> {
+        self.signals(source, Some(Language::Go)).unwrap_or_default()
+    }
+
+    fn analyze(&self, source: &str) -> Vec<Signal> {
+        self.signals(source, Some(Language::Rust)).unwrap_or_default()
+    }
+
+    fn try_analyze_file(&self, file: &SourceFile) -> Result<Vec<Signal>, Capability> {
+        self.signals(&file.source, file.language).map_err(|_| Capability::Perplexity)
+    }
+}
+
+/// The whole lines of `source` that fit in [`MAX_CHARS`].
+fn head(source: &str) -> &str {
+    if source.len() <= MAX_CHARS {
+        return source;
+    }
+    let limit = (0..=MAX_CHARS).rev().find(|&i| source.is_char_boundary(i)).unwrap_or(0);
+    let end = source[..limit].rfind('\n').map_or(limit, |i| i + 1);
+    &source[..end]
+}
+
+/// Block until `interval` has passed since the previous request in this
+/// process.  Pipelines are built per file, so the clock is global.
+fn throttle(interval: Duration) {
+    static NEXT: OnceLock<Mutex<Instant>> = OnceLock::new();
+    let mut next = NEXT.get_or_init(|| Mutex::new(Instant::now())).lock().unwrap();
+    let now = Instant::now();
+    if *next > now {
+        std::thread::sleep(*next - now);
+    }
+    *next = Instant::now() + interval;
+}
+
+// ---------------------------------------------------------------------------
+// OpenAI-compatible completions endpoint
+// ---------------------------------------------------------------------------
+
+/// Token log-probabilities from an OpenAI-compatible `/completions`
+/// endpoint that echoes the prompt (`echo: true, logprobs: 0,
+/// max_tokens: 0`): OpenAI's base models, vLLM, llama.cpp and most
+/// self-hosted servers.  Chat endpoints do not score the prompt.
+#[cfg(feature = "perplexity")]
+pub struct CompletionsLogprobs {
+    id: String,
+    model: String,
+    url: String,
+    api_key: Option<String>,
+    client: reqwest::blocking::Client,
+}
+
+#[cfg(feature = "perplexity")]
+impl CompletionsLogprobs {
+    /// `api_key` may be `None` for local servers.
+    pub fn new(base_url: &str, model: &str, api_key: Option<String>) -> Self {
+        let base_url = base_url.trim_end_matches('/');
+        Self {
+            id: format!("{model}@{base_url}"),
+            model: model.to_string(),
+            url: format!("{base_url}/completions"),
+            api_key,
+            client: reqwest::blocking::Client::new(),
+        }
+    }
+}
+
+#[cfg(feature = "perplexity")]
+impl LogprobSource for CompletionsLogprobs {
+    fn model(&self) -> &str {
+        &self.id
+    }
+
+    fn token_logprobs(&self, text: &str) -> anyhow::Result<Vec<f64>> {
+        let body = serde_json::json!({
+            "model": self.model,
+            "prompt": text,
+            "max_tokens": 0,
+            "echo": true,
+            "logprobs": 0,
+        });
+        let mut request = self.client.post(&self.url).timeout(Duration::from_secs(60)).json(&body);
+        if let Some(key) = &self.api_key {
+            request = request.bearer_auth(key);
+        }
+        let response = request.send()?;
+        let status = response.status();
+        let reply: serde_json::Value = response.json()?;
+        if !status.is_success() {
+            let message = reply.pointer("/error/message").and_then(|m| m.as_str()).unwrap_or("no error message");
+            anyhow::bail!("{} returned {status}: {message}", self.url);
+        }
+        let logprobs = reply
+            .pointer("/choices/0/logprobs/token_logprobs")
+            .and_then(|l| l.as_array())
+            .ok_or_else(|| anyhow::anyhow!("{} returned no token_logprobs", self.url))?;
+        // The first token has no context and comes back as null.
+        Ok(logprobs.iter().filter_map(|l| l.as_f64()).collect())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::sync::atomic::{AtomicUsize, Ordering};
+
+    /// Every token gets the same log-probability; counts requests.
+    struct Uniform {
+        logprob: f64,
+        calls: AtomicUsize,
+    }
+
+    impl LogprobSource for Uniform {
+        fn model(&self) -> &str {
+            "uniform"
+        }
+
+        fn token_logprobs(&self, text: &str) -> anyhow::Result<Vec<f64>> {
+            self.calls.fetch_add(1, Ordering::Relaxed);
+            Ok(text.split_whitespace().map(|_| self.logprob).collect())
+        }
+    }
+
+    fn analyzer(logprob: f64) -> (Arc<Uniform>, PerplexityAnalyzer) {
+        let source = Arc::new(Uniform { logprob, calls: AtomicUsize::new(0) });
+        let analyzer = PerplexityAnalyzer::new(source.clone(), DEFAULT_LOW, DEFAULT_HIGH, 6000);
+        (source, analyzer)
+    }
+
+    const CODE: &str = "fn main() { let total = items.iter().map(|item| item.price * item.quantity).sum::<u64>(); \
+                        println!(\"{}\", total); if total > 100 { apply_discount(total); } else { checkout(total); } }";
+
+    #[test]
+    fn low_and_high_perplexity_fire() {
+        let (_, predictable) = analyzer(-0.5);
+        let signals = predictable.analyze(CODE);
+        assert_eq!(signals[0].id, signal_ids::RUST_PERPLEXITY_LOW);
+        assert_eq!(signals[0].family, ModelFamily::Gpt);
+
+        let (_, surprising) = analyzer(-2.5);
+        let signals = surprising.analyze_go(CODE);
+        assert_eq!(signals[0].id, signal_ids::GO_PERPLEXITY_HIGH);
+        assert!(signals[0].weight < 0.0);
+
+        let (_, middling) = analyzer(-1.5);
+        assert!(middling.analyze(CODE).is_empty());
+        assert!(predictable.analyze("fn main() {}").is_empty(), "too few tokens");
+    }
+
+    #[test]
+    fn perplexities_are_cached_by_content() {
+        let dir = tempfile::tempdir().unwrap();
+        let (source, analyzer) = analyzer(-0.5);
+        let analyzer = analyzer.with_cache_dir(dir.path().to_path_buf());
+        let first = analyzer.perplexity(CODE).unwrap().unwrap();
+        assert_eq!(analyzer.perplexity(CODE).unwrap(), Some(first));
+        assert_eq!(source.calls.load(Ordering::Relaxed), 1);
+        analyzer.perplexity(&format!("{CODE}\n")).unwrap().unwrap();
+        assert_eq!(source.calls.load(Ordering::Relaxed), 2);
+    }
+
+    /// An endpoint that is down.
+    struct Unreachable;
+
+    impl LogprobSource for Unreachable {
+        fn model(&self) -> &str {
+            "unreachable"
+        }
+
+        fn token_logprobs(&self, _text: &str) -> anyhow::Result<Vec<f64>> {
+            anyhow::bail!("connection refused")
+        }
+    }
+
+    #[test]
+    fn failed_requests_degrade_the_report() {
+        let down = PerplexityAnalyzer::new(Arc::new(Unreachable), DEFAULT_LOW, DEFAULT_HIGH, 6000);
+        assert!(format!("{:#}", down.perplexity(CODE).unwrap_err()).contains("connection refused"));
+        assert!(down.analyze(CODE).is_empty());
+
+        let pipeline = crate::pipeline::Pipeline::with_heuristics(
+            vec![Box::new(down)],
+            crate::analyzers::default_cst_analyzers(),
+            Box::new(crate::heuristics::DefaultHeuristics),
+        );
+        let report = pipeline.run(CODE, Some(PathBuf::from("main.rs")));
+        assert_eq!(report.metadata.degraded, vec![Capability::Perplexity]);
+
+        let (_, working) = analyzer(-0.5);
+        let pipeline = crate::pipeline::Pipeline::with_heuristics(
+            vec![Box::new(working)],
+            crate::analyzers::default_cst_analyzers(),
+            Box::new(crate::heuristics::DefaultHeuristics),
+        );
+        assert!(pipeline.run(CODE, Some(PathBuf::from("main.rs"))).metadata.degraded.is_empty());
+    }
+
+    #[test]
+    fn long_sources_are_cut_at_a_line() {
+        let source = "é\n".repeat(MAX_CHARS);
+        let cut = head(&source);
+        assert!(cut.len() <= MAX_CHARS && cut.ends_with('\n'));
+    }
+}
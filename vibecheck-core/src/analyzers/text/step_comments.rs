@@ -0,0 +1,248 @@
+use crate::analyzers::Analyzer;
+use crate::heuristics::signal_ids;
+use crate::language::Language;
+use crate::report::{ModelFamily, Signal};
+use crate::source_file::SourceFile;
+
+/// Detects tutorial-style enumerated step comments (`// Step 1:`,
+/// `# Step 2a:`, `// 3. ...`) — GPT's habit of narrating an implementation
+/// as a numbered recipe.
+pub struct StepCommentAnalyzer;
+
+/// Step comments in one function before the function counts as narrated.
+const DENSE_STEPS_PER_FN: usize = 3;
+
+/// Step comments in a file before the file-level signal fires.
+const MIN_STEPS: usize = 3;
+
+impl StepCommentAnalyzer {
+    fn analyze_impl(
+        source: &str,
+        lang: Language,
+        marker: &str,
+        step_id: &str,
+        dense_id: &str,
+    ) -> Vec<Signal> {
+        let lines: Vec<&str> = source.lines().collect();
+        let is_comment = |l: &str| l.trim_start().starts_with(marker);
+
+        // 1-based line numbers of step comments.
+        let steps: Vec<usize> = lines
+            .iter()
+            .enumerate()
+            .filter_map(|(i, l)| l.trim_start().strip_prefix(marker).map(|body| (i, body)))
+            .filter(|(_, body)| is_step(body.trim_start_matches(['/', '!'])))
+            .map(|(i, _)| i + 1)
+            .collect();
+        if steps.len() < MIN_STEPS {
+            return vec![];
+        }
+
+        let mut signals = vec![Signal::new(
+            step_id,
+            "steps",
+            format!("{} step-numbered comments", steps.len()),
+            ModelFamily::Gpt,
+            1.5,
+        )
+        .with_lines(steps.clone())];
+
+        // Per-function density: a step belongs to the function it sits in,
+        // or to the function its comment block directly precedes.
+        let file = SourceFile::for_language(source, lang);
+        let mut dense_lines = Vec::new();
+        let mut dense_fns = 0;
+        let mut max_per_fn = 0;
+        for f in &file.tokens.functions {
+            let mut header_start = f.start_line;
+            while header_start > 1 && is_comment(lines[header_start - 2]) {
+                header_start -= 1;
+            }
+            let in_fn: Vec<usize> = steps
+                .iter()
+                .copied()
+                .filter(|&l| l >= header_start && l <= f.end_line)
+                .collect();
+            max_per_fn = max_per_fn.max(in_fn.len());
+            if in_fn.len() >= DENSE_STEPS_PER_FN {
+                dense_fns += 1;
+                dense_lines.extend(in_fn);
+            }
+        }
+        if dense_fns > 0 {
+            signals.push(
+                Signal::new(
+                    dense_id,
+                    "steps",
+                    format!(
+                        "{dense_fns} function{} narrated step by step (up to {max_per_fn} steps)",
+                        if dense_fns == 1 { "" } else { "s" }
+                    ),
+                    ModelFamily::Gpt,
+                    1.0,
+                )
+                .with_lines(dense_lines),
+            );
+        }
+
+        signals
+    }
+}
+
+/// Return `true` if a comment body (marker stripped) is an enumerated step:
+/// `Step 3:`, `step 2a -`, `Step 4.1`, `1. `, `3b) `.
+fn is_step(body: &str) -> bool {
+    let body = body.trim_start().to_lowercase();
+    if let Some(rest) = body.strip_prefix("step") {
+        // "Step 3", "Step 3a:", "step 4.1"; but not "steps" or "stepwise".
+        return match enumerator(rest.trim_start()) {
+            Some(after) => after.is_empty() || !after.starts_with(|c: char| c.is_alphanumeric()),
+            None => false,
+        };
+    }
+    // A bare enumeration needs a short number, an explicit terminator and a
+    // following word, so "1.5x faster" and "2024: rewrite" are not steps.
+    let digits = body.len() - body.trim_start_matches(|c: char| c.is_ascii_digit()).len();
+    if digits > 2 {
+        return false;
+    }
+    match enumerator(&body) {
+        Some(after) => {
+            let mut chars = after.chars();
+            matches!(chars.next(), Some('.' | ')'))
+                && chars.next().map(char::is_whitespace).unwrap_or(false)
+        }
+        None => false,
+    }
+}
+
+/// Strip a step number — digits plus an optional sub-step letter (`3a`) or
+/// dotted number (`3.1`) — and return the remainder.
+fn enumerator(s: &str) -> Option<&str> {
+    let digits = s.len() - s.trim_start_matches(|c: char| c.is_ascii_digit()).len();
+    if digits == 0 {
+        return None;
+    }
+    let rest = &s[digits..];
+    let mut chars = rest.chars();
+    match (chars.next(), chars.next()) {
+        // "3a", "3b:" — but not a word like "3rd".
+        (Some(c), next) if c.is_ascii_lowercase() && !next.map(|n| n.is_alphanumeric()).unwrap_or(false) => {
+            Some(&rest[1..])
+        }
+        // "4.1"
+        (Some('.'), Some(n)) if n.is_ascii_digit() => {
+            let sub = &rest[1..];
+            Some(sub.trim_start_matches(|c: char| c.is_ascii_digit()))
+        }
+        _ => Some(rest),
+    }
+}
+
+impl Analyzer for StepCommentAnalyzer {
+    fn name(&self) -> &str {
+        "steps"
+    }
+
+    fn analyze_python(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(
+            source,
+            Language::Python,
+            "#",
+            signal_ids::PYTHON_COMMENTS_STEP_NUMBERED,
+            signal_ids::PYTHON_COMMENTS_STEP_DENSE_FUNCTION,
+        )
+    }
+
+    fn analyze_javascript(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(
+            source,
+            Language::JavaScript,
+            "//",
+            signal_ids::JS_COMMENTS_STEP_NUMBERED,
+            signal_ids::JS_COMMENTS_STEP_DENSE_FUNCTION,
+        )
+    }
+
+    fn analyze_go(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(
+            source,
+            Language::Go,
+            "//",
+            signal_ids::GO_COMMENTS_STEP_NUMBERED,
+            signal_ids::GO_COMMENTS_STEP_DENSE_FUNCTION,
+        )
+    }
+
+    fn analyze(&self, source: &str) -> Vec<Signal> {
+        Self::analyze_impl(
+            source,
+            Language::Rust,
+            "//",
+            signal_ids::RUST_COMMENTS_STEP_NUMBERED,
+            signal_ids::RUST_COMMENTS_STEP_DENSE_FUNCTION,
+        )
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn recognizes_step_forms() {
+        for body in [" Step 1: Initialize", " step 2a: move", " Step 3b - evict", " Step 4.1 insert", " 1. Parse", " 3a) Retry", "Step 5"] {
+            assert!(is_step(body), "{body:?} should be a step");
+        }
+        for body in [" steps are listed below", " stepwise refinement", " 1.5x faster", " 2024: rewrite", " 3rd attempt", " 100. items", " Step up the rate"] {
+            assert!(!is_step(body), "{body:?} should not be a step");
+        }
+    }
+
+    #[test]
+    fn fewer_than_three_steps_no_signal() {
+        let source = "// Step 1: a\nlet a = 1;\n// Step 2: b\nlet b = 2;\n";
+        assert!(StepCommentAnalyzer.analyze(source).is_empty());
+    }
+
+    #[test]
+    fn file_signal_points_at_each_step() {
+        let source = "// Step 1: a\nlet a = 1;\n// Step 2a: b\n// Step 2b: c\nlet b = 2;\n// unrelated\n";
+        let signals = StepCommentAnalyzer.analyze(source);
+        let step = signals.iter().find(|s| s.id == signal_ids::RUST_COMMENTS_STEP_NUMBERED).unwrap();
+        assert_eq!(step.family, ModelFamily::Gpt);
+        assert_eq!(step.lines, vec![1, 3, 4]);
+    }
+
+    #[test]
+    fn python_uses_hash_comments() {
+        let source = "# Step 1: a\na = 1\n# Step 2: b\nb = 2\n# Step 3: c\n// Step 4\n";
+        let signals = StepCommentAnalyzer.analyze_python(source);
+        assert_eq!(signals[0].id, signal_ids::PYTHON_COMMENTS_STEP_NUMBERED);
+        assert_eq!(signals[0].lines, vec![1, 3, 5]);
+    }
+
+    #[test]
+    fn dense_function_includes_header_steps() {
+        let source = "\
+// Step 3: Insert a pair.
+fn put(&mut self) {
+    // Step 3a: Update.
+    self.a();
+    // Step 3b: Evict.
+    self.b();
+}
+
+fn get(&self) {
+    // Step 4: Read.
+}
+";
+        let signals = StepCommentAnalyzer.analyze(source);
+        let dense = signals
+            .iter()
+            .find(|s| s.id == signal_ids::RUST_COMMENTS_STEP_DENSE_FUNCTION)
+            .expect("put() is narrated by three steps");
+        assert_eq!(dense.lines, vec![1, 3, 5]);
+        assert!(dense.description.starts_with("1 function"));
+    }
+}
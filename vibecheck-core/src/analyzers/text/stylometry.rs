@@ -0,0 +1,257 @@
+use std::collections::{BTreeMap, HashSet};
+use std::path::Path;
+use std::sync::Arc;
+
+use anyhow::Context;
+use serde::{Deserialize, Serialize};
+
+use crate::analyzers::text::hedging_phrases::prose_lines;
+use crate::analyzers::Analyzer;
+use crate::language::Language;
+use crate::report::{ModelFamily, Signal};
+
+/// Scores the wording of comments with character and word n-gram
+/// frequencies learned from a labelled corpus.  The filler and hedging
+/// detectors only catch the phrasings someone wrote down; an n-gram model
+/// also recognizes paraphrases of them — narration nobody enumerated.
+///
+/// Opt-in: the model is trained by `vibecheck tune --classifier stylometry`
+/// and loaded from the `[stylometry]` section of `.vibecheck`.  Files with
+/// too little comment prose emit nothing.  Otherwise the family whose
+/// n-gram distribution explains the comments best fires its signal, if it
+/// beats the runner-up by the configured margin.
+pub struct StylometryAnalyzer {
+    model: Arc<NgramModel>,
+    /// Mean log-likelihood lead, in nats per n-gram, the best family needs.
+    margin: f64,
+}
+
+/// Lead over the runner-up unless `.vibecheck` sets `[stylometry] margin`.
+pub const DEFAULT_MARGIN: f64 = 0.05;
+
+/// Known n-grams a file's comments need before the model is consulted;
+/// roughly three or four comment lines.
+const MIN_NGRAMS: usize = 150;
+
+/// Length of the character n-grams.
+const CHAR_ORDER: usize = 3;
+
+/// Most frequent n-grams kept in a trained model.
+const MAX_NGRAMS: usize = 5000;
+
+/// Additive smoothing for n-grams a family never used.
+const SMOOTHING: f64 = 0.5;
+
+impl StylometryAnalyzer {
+    pub fn new(model: Arc<NgramModel>, margin: f64) -> Self {
+        Self { model, margin }
+    }
+
+    fn analyze_impl(&self, source: &str, lang: Language, prefix: &str) -> Vec<Signal> {
+        let scores = self.model.score(&comment_prose(source, Some(lang)));
+        let (best, runner_up) = match scores.as_slice() {
+            [best, runner_up, ..] => (best, runner_up),
+            _ => return vec![],
+        };
+        let lead = best.1 - runner_up.1;
+        if lead < self.margin {
+            return vec![];
+        }
+        let family = best.0;
+        vec![Signal::new(
+            &format!("{prefix}.stylometry.{}", family.to_string().to_lowercase()),
+            "stylometry",
+            format!("Comment wording is closest to the {family} corpus samples ({lead:.2} nats/n-gram ahead of {})", runner_up.0),
+            family,
+            1.5,
+        )]
+    }
+}
+
+impl Analyzer for StylometryAnalyzer {
+    fn name(&self) -> &str {
+        "stylometry"
+    }
+
+    fn analyze_python(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, Language::Python, "python")
+    }
+
+    fn analyze_javascript(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, Language::JavaScript, "js")
+    }
+
+    fn analyze_go(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, Language::Go, "go")
+    }
+
+    fn analyze(&self, source: &str) -> Vec<Signal> {
+        self.analyze_impl(source, Language::Rust, "rust")
+    }
+}
+
+/// The comment and docstring text of `source`, one line per comment line.
+/// Rust comment syntax is assumed when the language is unknown.
+pub fn comment_prose(source: &str, lang: Option<Language>) -> String {
+    let (marker, docstrings) = match lang {
+        Some(Language::Python) => ("#", true),
+        _ => ("//", false),
+    };
+    let mut prose = String::new();
+    for (_, text) in prose_lines(source, marker, docstrings) {
+        prose.push_str(text.trim_start_matches(['/', '!', '#', '*']));
+        prose.push('\n');
+    }
+    prose
+}
+
+/// Character trigrams (`c:`) and word bigrams (`w:`) of `text`, lowercased
+/// with runs of whitespace collapsed.  Each line is scored on its own so
+/// n-grams never span two comments.
+fn ngrams(text: &str) -> Vec<String> {
+    let mut out = Vec::new();
+    for line in text.lines() {
+        let words: Vec<String> = line
+            .split_whitespace()
+            .map(|w| w.to_lowercase())
+            .filter(|w| w.chars().any(char::is_alphanumeric))
+            .collect();
+        if words.is_empty() {
+            continue;
+        }
+        let chars: Vec<char> = format!(" {} ", words.join(" ")).chars().collect();
+        out.extend(chars.windows(CHAR_ORDER).map(|w| format!("c:{}", w.iter().collect::<String>())));
+        out.extend(words.windows(2).map(|w| format!("w:{} {}", w[0], w[1])));
+    }
+    out
+}
+
+/// Per-family n-gram counts of comment prose: a multinomial naive Bayes
+/// model, serialized as TOML by `vibecheck tune --classifier stylometry`.
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct NgramModel {
+    /// Family → n-gram → occurrences in that family's training comments.
+    #[serde(default)]
+    pub families: BTreeMap<ModelFamily, BTreeMap<String, u32>>,
+}
+
+impl NgramModel {
+    /// Count the n-grams of each `(family, prose)` sample, keeping the
+    /// [`MAX_NGRAMS`] most frequent that occur at least twice overall.
+    pub fn train<'a>(samples: impl IntoIterator<Item = (ModelFamily, &'a str)>) -> Self {
+        let mut families: BTreeMap<ModelFamily, BTreeMap<String, u32>> = BTreeMap::new();
+        let mut totals: BTreeMap<String, u32> = BTreeMap::new();
+        for (family, prose) in samples {
+            let counts = families.entry(family).or_default();
+            for gram in ngrams(prose) {
+                *counts.entry(gram.clone()).or_default() += 1;
+                *totals.entry(gram).or_default() += 1;
+            }
+        }
+        let mut ranked: Vec<(String, u32)> = totals.into_iter().filter(|(_, n)| *n >= 2).collect();
+        ranked.sort_by(|a, b| b.1.cmp(&a.1).then_with(|| a.0.cmp(&b.0)));
+        let kept: HashSet<String> = ranked.into_iter().take(MAX_NGRAMS).map(|(gram, _)| gram).collect();
+        for counts in families.values_mut() {
+            counts.retain(|gram, _| kept.contains(gram));
+        }
+        families.retain(|_, counts| !counts.is_empty());
+        Self { families }
+    }
+
+    pub fn from_file(path: &Path) -> anyhow::Result<Self> {
+        let text = std::fs::read_to_string(path).with_context(|| format!("failed to read {}", path.display()))?;
+        toml::from_str(&text).with_context(|| format!("failed to parse {}", path.display()))
+    }
+
+    pub fn to_toml(&self) -> anyhow::Result<String> {
+        Ok(toml::to_string(self)?)
+    }
+
+    /// Mean log-likelihood per n-gram of `prose` under each family, best
+    /// first.  Empty when fewer than [`MIN_NGRAMS`] of its n-grams are in
+    /// the model's vocabulary.
+    pub fn score(&self, prose: &str) -> Vec<(ModelFamily, f64)> {
+        let vocabulary: HashSet<&str> = self.families.values().flat_map(|c| c.keys().map(String::as_str)).collect();
+        let grams: Vec<String> = ngrams(prose).into_iter().filter(|g| vocabulary.contains(g.as_str())).collect();
+        if grams.len() < MIN_NGRAMS {
+            return vec![];
+        }
+        let v = vocabulary.len() as f64;
+        let mut scores: Vec<(ModelFamily, f64)> = self
+            .families
+            .iter()
+            .map(|(&family, counts)| {
+                let total = counts.values().map(|&n| f64::from(n)).sum::<f64>() + SMOOTHING * v;
+                let ll: f64 = grams
+                    .iter()
+                    .map(|g| ((f64::from(counts.get(g).copied().unwrap_or(0)) + SMOOTHING) / total).ln())
+                    .sum();
+                (family, ll / grams.len() as f64)
+            })
+            .collect();
+        scores.sort_by(|a, b| b.1.total_cmp(&a.1));
+        scores
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::heuristics::all_heuristics;
+
+    const NARRATION: &str = "// Here we iterate over each item in the list to ensure it is valid.\n";
+    const TERSE: &str = "// hack: skip dupes, upstream sends them twice\n";
+
+    fn model() -> NgramModel {
+        NgramModel::train([
+            (ModelFamily::Claude, NARRATION.repeat(20).as_str()),
+            (ModelFamily::Human, TERSE.repeat(20).as_str()),
+        ])
+    }
+
+    #[test]
+    fn ngrams_are_per_line_char_trigrams_and_word_bigrams() {
+        let grams = ngrams("Note  that\nx\n");
+        assert_eq!(&grams[..3], ["c: no", "c:not", "c:ote"]);
+        assert!(grams.contains(&"w:note that".to_string()));
+        assert!(grams.contains(&"c: x ".to_string()));
+        assert!(!grams.iter().any(|g| g.contains("t x") || g.contains('\n')));
+    }
+
+    #[test]
+    fn paraphrased_narration_scores_closest_to_its_family() {
+        let paraphrase = "// Here we loop over each entry in the map to ensure it is valid.\n".repeat(4);
+        let scores = model().score(&comment_prose(&paraphrase, Some(Language::Rust)));
+        assert_eq!(scores[0].0, ModelFamily::Claude, "{scores:?}");
+
+        let analyzer = StylometryAnalyzer::new(Arc::new(model()), DEFAULT_MARGIN);
+        let signals = analyzer.analyze(&format!("{paraphrase}fn main() {{}}\n"));
+        assert_eq!(signals.len(), 1);
+        assert_eq!(signals[0].id, "rust.stylometry.claude");
+        assert_eq!(signals[0].family, ModelFamily::Claude);
+    }
+
+    #[test]
+    fn short_comments_and_empty_models_stay_silent() {
+        assert!(model().score(&comment_prose(NARRATION, None)).is_empty());
+        let analyzer = StylometryAnalyzer::new(Arc::new(NgramModel::default()), DEFAULT_MARGIN);
+        assert!(analyzer.analyze_go(&NARRATION.repeat(10)).is_empty());
+    }
+
+    #[test]
+    fn model_round_trips_through_toml() {
+        let model = model();
+        assert_eq!(toml::from_str::<NgramModel>(&model.to_toml().unwrap()).unwrap(), model);
+    }
+
+    #[test]
+    fn every_family_has_a_signal_per_language() {
+        for prefix in ["rust", "python", "js", "go"] {
+            for family in ModelFamily::all() {
+                let id = format!("{prefix}.stylometry.{}", family.to_string().to_lowercase());
+                let spec = all_heuristics().iter().find(|h| h.id == id).unwrap_or_else(|| panic!("{id} missing"));
+                assert_eq!(spec.family, *family, "{id}");
+            }
+        }
+    }
+}
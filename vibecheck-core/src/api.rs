@@ -0,0 +1,680 @@
+use std::collections::HashMap;
+use std::path::{Path, PathBuf};
+use std::sync::Arc;
+use std::time::Duration;
+
+use anyhow::Context;
+
+use crate::cache::Cache;
+use crate::ignore_rules::{IgnoreConfig, IgnoreRules};
+use crate::pipeline::Pipeline;
+use crate::patch::{self, FilePatch};
+use crate::report::{Report, Signal, SymbolReport};
+use crate::source_fs::{self, OsFs, SourceFs, Walk};
+use crate::test_files::TestFiles;
+use crate::{analyzers, language_pack, limits, pipeline_from_config, plugin, timeout};
+
+/// A configured, reusable analyzer — the stable API for embedding vibecheck
+/// (review bots, editor integrations).
+///
+/// Build once with [`Analyzer::new`] and the `with_*` options, then call
+/// [`analyze_file`](Self::analyze_file) or
+/// [`analyze_source`](Self::analyze_source) as often as needed.  Unlike the
+/// free functions in the crate root, no `.vibecheck` discovery happens per
+/// call.
+///
+/// `Analyzer` is `Send + Sync`, and one value is meant to serve every thread
+/// of an embedding server for its whole life: analysis takes `&self`, the
+/// detectors behind it keep all per-call state on the stack (the
+/// [`Analyzer`](crate::analyzers::Analyzer) and
+/// [`CstAnalyzer`](crate::analyzers::CstAnalyzer) traits require
+/// `Send + Sync`), and the cache is shared and internally synchronized.
+/// Building a new analyzer per request works but throws the in-memory tier
+/// of the cache away each time.
+pub struct Analyzer {
+    /// Shared with timeout workers, which may outlive a single call.
+    pipeline: Arc<Pipeline>,
+    /// Weight overrides and analyzer settings mixed into cache keys.
+    overrides: HashMap<String, f64>,
+    settings: Vec<String>,
+    /// Test files, which are keyed by the test weights too.
+    tests: TestFiles,
+    /// Shared with every other analyzer using the same directory; see
+    /// [`Cache::shared`].
+    cache: Option<Arc<Cache>>,
+    symbols: bool,
+    segments: bool,
+    comment_free: bool,
+    timeout: Option<Duration>,
+    max_file_size: Option<u64>,
+    progress: Option<Box<ProgressFn>>,
+}
+
+/// Progress callback for tree scans: `(done, total, current)` — files
+/// finished so far, the number of files in the scan when known, and the
+/// file about to be analyzed.  See [`Analyzer::with_progress`].
+pub type ProgressFn = dyn Fn(usize, Option<usize>, &Path) + Send + Sync;
+
+/// One file's outcome from [`Analyzer::analyze_tree`].
+#[derive(Debug)]
+pub struct FileResult {
+    /// The file analyzed, or the directory that could not be listed.
+    pub path: PathBuf,
+    pub report: anyhow::Result<Report>,
+}
+
+impl Default for Analyzer {
+    fn default() -> Self {
+        Self::new()
+    }
+}
+
+impl Analyzer {
+    /// An analyzer with the built-in heuristics and weights, no cache and no
+    /// symbol-level attribution.
+    pub fn new() -> Self {
+        Self {
+            pipeline: Arc::new(
+                Pipeline::with_defaults()
+                    .with_language_packs(language_pack::installed())
+                    .with_plugins(plugin::installed()),
+            ),
+            overrides: HashMap::new(),
+            settings: Vec::new(),
+            tests: TestFiles::none(),
+            cache: None,
+            symbols: false,
+            segments: false,
+            comment_free: false,
+            timeout: None,
+            max_file_size: None,
+            progress: None,
+        }
+    }
+
+    /// Apply a loaded `.vibecheck` config: heuristic weights, analyzer
+    /// settings, the classifier backend and the calibration.
+    ///
+    /// Use [`IgnoreConfig::load`] to discover the config for a directory,
+    /// [`IgnoreConfig::from_file`] for an explicit path, or
+    /// [`IgnoreConfig::load_untrusted`] for a repository someone else
+    /// controls.  The config's
+    /// `[cache] dir` is not used; call [`with_cache_dir`](Self::with_cache_dir)
+    /// to enable caching.
+    pub fn with_config(mut self, config: &IgnoreConfig) -> Self {
+        self.pipeline = Arc::new(pipeline_from_config(config));
+        self.overrides = config.heuristics_map();
+        self.settings = config.analysis_settings();
+        self.tests = config.test_files().clone();
+        self
+    }
+
+    /// Read and write the content-addressed cache in `dir`.
+    ///
+    /// Entries are keyed by content and configuration, so the directory can
+    /// be shared with the CLI (`--cache-dir`).  The cache is opened here,
+    /// once, and shared with every other analyzer in the process that uses
+    /// `dir`; if it cannot be opened, analysis runs uncached.
+    pub fn with_cache_dir(mut self, dir: impl Into<PathBuf>) -> Self {
+        self.cache = Cache::shared(&dir.into()).ok();
+        self
+    }
+
+    /// Read and write `cache`, e.g. one with an
+    /// [`InMemoryBackend`](crate::cache::InMemoryBackend) for a server that
+    /// should not touch the disk.
+    pub fn with_cache(mut self, cache: Arc<Cache>) -> Self {
+        self.cache = Some(cache);
+        self
+    }
+
+    /// Populate [`Report::symbol_reports`] with per-function attribution.
+    pub fn with_symbols(mut self, enabled: bool) -> Self {
+        self.symbols = enabled;
+        self
+    }
+
+    /// Populate [`Report::segments`] with a verdict per region of differing
+    /// style, for files that mix human and AI-written code.
+    pub fn with_segments(mut self, enabled: bool) -> Self {
+        self.segments = enabled;
+        self
+    }
+
+    /// Populate [`Report::comment_free`] with the verdict on the code alone,
+    /// to show how much of the full verdict rests on comments.
+    pub fn with_comment_free(mut self, enabled: bool) -> Self {
+        self.comment_free = enabled;
+        self
+    }
+
+    /// Give up on any single file after `limit`, returning
+    /// [`Report::skipped`] with [`SKIPPED_TIMEOUT`](timeout::SKIPPED_TIMEOUT)
+    /// for it instead, so one pathological file cannot stall a scan.
+    ///
+    /// Each file is then analyzed on a worker thread; see [`timeout::run`]
+    /// for what happens to a worker that overruns.
+    pub fn with_timeout(mut self, limit: Duration) -> Self {
+        self.timeout = Some(limit);
+        self
+    }
+
+    /// Skip files larger than `bytes` without reading them, returning
+    /// [`Report::skipped`] with [`SKIPPED_TOO_LARGE`](limits::SKIPPED_TOO_LARGE)
+    /// — bundled and minified assets can exhaust memory during parsing.
+    /// See [`limits::effective_max_file_size`] to derive a limit from a
+    /// memory budget.
+    pub fn with_max_file_size(mut self, bytes: u64) -> Self {
+        self.max_file_size = Some(bytes);
+        self
+    }
+
+    /// Call `progress` before each file of an [`analyze_fs`](Self::analyze_fs)
+    /// or [`analyze_tree`](Self::analyze_tree) scan, so long scans can show
+    /// where they are.
+    ///
+    /// `total` is `Some` for `analyze_fs`, which lists the tree first, and
+    /// `None` for the lazy `analyze_tree`.  The callback runs on the scanning
+    /// thread; keep it cheap.
+    pub fn with_progress(mut self, progress: impl Fn(usize, Option<usize>, &Path) + Send + Sync + 'static) -> Self {
+        self.progress = Some(Box::new(progress));
+        self
+    }
+
+    /// Read and analyze the file at `path`.
+    pub fn analyze_file(&self, path: &Path) -> anyhow::Result<Report> {
+        self.analyze_file_in(&OsFs, path)
+    }
+
+    /// Read and analyze the file at `path` in `fs`.
+    pub fn analyze_file_in(&self, fs: &dyn SourceFs, path: &Path) -> anyhow::Result<Report> {
+        if let Some(limit) = self.max_file_size {
+            let size = fs.size(path).with_context(|| format!("cannot read {}", path.display()))?;
+            if size > limit {
+                return Ok(Report::skipped(path.to_path_buf(), limits::SKIPPED_TOO_LARGE));
+            }
+        }
+        let bytes = fs.read(path).with_context(|| format!("cannot read {}", path.display()))?;
+        self.analyze_bytes(&bytes, path)
+    }
+
+    /// Analyze every supported source file under `root` in `fs` — an
+    /// in-memory [`MemFs`](crate::source_fs::MemFs), [`OsFs`], or any other
+    /// [`SourceFs`] — in path order, stopping at the first failure.
+    ///
+    /// `ignore` filters paths and decides whether generated files are
+    /// included; pass [`AllowAll`](crate::ignore_rules::AllowAll) to analyze
+    /// everything, or an [`IgnoreConfig`] for the project's rules.  For large
+    /// trees prefer [`analyze_tree`](Self::analyze_tree).
+    pub fn analyze_fs(
+        &self,
+        fs: &dyn SourceFs,
+        root: &Path,
+        ignore: &dyn IgnoreRules,
+    ) -> anyhow::Result<Vec<Report>> {
+        let files = source_fs::source_files(fs, root, ignore)
+            .with_context(|| format!("cannot list {}", root.display()))?;
+        let total = files.len();
+        files
+            .iter()
+            .enumerate()
+            .map(|(done, path)| {
+                self.report_progress(done, Some(total), path);
+                self.analyze_file_in(fs, path)
+            })
+            .collect()
+    }
+
+    /// Stream results for every supported source file under `root`, in the
+    /// same order as [`analyze_fs`](Self::analyze_fs), without holding the
+    /// whole tree's reports in memory.
+    ///
+    /// The iterator is lazy: each file is listed and analyzed only when the
+    /// next result is requested, so a slow consumer never has more than one
+    /// report in flight, and dropping the iterator (or breaking out of the
+    /// loop) stops the scan.  A file or directory that cannot be read yields
+    /// a [`FileResult`] carrying the error and the scan continues; only an
+    /// unreadable `root` fails up front.
+    pub fn analyze_tree<'a>(
+        &'a self,
+        fs: &'a dyn SourceFs,
+        root: &Path,
+        ignore: &'a dyn IgnoreRules,
+    ) -> anyhow::Result<impl Iterator<Item = FileResult> + 'a> {
+        let walk = Walk::new(fs, root, ignore).with_context(|| format!("cannot list {}", root.display()))?;
+        let mut done = 0;
+        Ok(walk.map(move |item| match item {
+            Ok(path) => {
+                self.report_progress(done, None, &path);
+                done += 1;
+                FileResult { report: self.analyze_file_in(fs, &path), path }
+            }
+            Err((path, e)) => FileResult {
+                report: Err(anyhow::Error::new(e).context(format!("cannot list {}", path.display()))),
+                path,
+            },
+        }))
+    }
+
+    /// Analyze `source` as if it were a file called `name`.
+    ///
+    /// The language is taken from `name`'s extension (e.g. `"src/lib.rs"`),
+    /// and `name` is recorded as the report's file path.  Nothing is read
+    /// from disk.
+    pub fn analyze_source(&self, name: &str, source: &[u8]) -> anyhow::Result<Report> {
+        if self.max_file_size.is_some_and(|limit| source.len() as u64 > limit) {
+            return Ok(Report::skipped(PathBuf::from(name), limits::SKIPPED_TOO_LARGE));
+        }
+        self.analyze_bytes(source, Path::new(name))
+    }
+
+    /// Analyze the lines a unified diff adds, one report per changed source
+    /// file, without a checkout.
+    ///
+    /// Each file's added lines are scored together as one source; line
+    /// numbers in signals, symbols and segments are mapped back to the new
+    /// file.  `ignore` filters the patch's paths as in
+    /// [`analyze_fs`](Self::analyze_fs).  Fails only if `diff` is malformed.
+    pub fn analyze_patch(&self, diff: &str, ignore: &dyn IgnoreRules) -> anyhow::Result<Vec<Report>> {
+        let files: Vec<FilePatch> = patch::parse(diff)?
+            .into_iter()
+            .filter(|f| source_fs::is_supported(&f.path) && !ignore.is_ignored(&f.path))
+            .collect();
+        let total = files.len();
+        files
+            .iter()
+            .enumerate()
+            .map(|(done, file)| {
+                self.report_progress(done, Some(total), &file.path);
+                let name = file.path.to_string_lossy();
+                let mut report = self.analyze_source(&name, file.source().as_bytes())?;
+                remap_lines(&mut report, file);
+                Ok(report)
+            })
+            .collect()
+    }
+
+    fn report_progress(&self, done: usize, total: Option<usize>, current: &Path) {
+        if let Some(ref progress) = self.progress {
+            progress(done, total, current);
+        }
+    }
+
+    fn analyze_bytes(&self, bytes: &[u8], path: &Path) -> anyhow::Result<Report> {
+        let mut settings = self.settings.clone();
+        settings.extend(analyzers::text::go_era::cache_setting(path));
+        settings.extend(plugin::cache_settings());
+        settings.extend(self.tests.cache_setting(path));
+        let hash = Cache::hash_content_with_settings(bytes, &self.overrides, &settings);
+        if let Some(c) = self.cache.as_deref() {
+            if let Some(mut cached) = c.get(&hash) {
+                let symbols = if self.symbols { c.get_symbols(&hash) } else { None };
+                let complete = (!self.symbols || symbols.is_some())
+                    && (!self.segments || cached.segments.is_some())
+                    && (!self.comment_free || cached.comment_free.is_some());
+                if complete {
+                    cached.metadata.file_path = Some(path.to_path_buf());
+                    cached.symbol_reports = symbols;
+                    if !self.segments {
+                        cached.segments = None;
+                    }
+                    if !self.comment_free {
+                        cached.comment_free = None;
+                    }
+                    return Ok(cached);
+                }
+            }
+        }
+
+        let source = String::from_utf8(bytes.to_vec())
+            .with_context(|| format!("{} is not valid UTF-8", path.display()))?;
+        let (mut report, symbol_reports) = match self.timeout {
+            None => run_pipeline(&self.pipeline, &source, path, self.symbols, self.segments, self.comment_free)?,
+            Some(limit) => {
+                let (pipeline, file) = (Arc::clone(&self.pipeline), path.to_path_buf());
+                let (symbols, segments, comment_free) = (self.symbols, self.segments, self.comment_free);
+                match timeout::run(limit, move || {
+                    run_pipeline(&pipeline, &source, &file, symbols, segments, comment_free)
+                }) {
+                    Some(result) => result?,
+                    None => return Ok(Report::skipped(path.to_path_buf(), timeout::SKIPPED_TIMEOUT)),
+                }
+            }
+        };
+
+        if let Some(c) = self.cache.as_deref() {
+            let _ = c.put(&hash, &report);
+            if let Some(ref syms) = symbol_reports {
+                let _ = c.put_symbols(&hash, syms);
+            }
+        }
+
+        report.symbol_reports = symbol_reports;
+        Ok(report)
+    }
+}
+
+/// Rewrite line numbers in `report`, computed over `file`'s added lines
+/// alone, to lines of the new file.
+fn remap_lines(report: &mut Report, file: &FilePatch) {
+    let remap = |signals: &mut [Signal]| {
+        for line in signals.iter_mut().flat_map(|s| s.lines.iter_mut()) {
+            *line = file.new_line(*line);
+        }
+    };
+    remap(&mut report.signals);
+    for symbol in report.symbol_reports.iter_mut().flatten() {
+        symbol.metadata.start_line = file.new_line(symbol.metadata.start_line);
+        symbol.metadata.end_line = file.new_line(symbol.metadata.end_line);
+        remap(&mut symbol.signals);
+    }
+    for segment in report.segments.iter_mut().flatten() {
+        segment.start_line = file.new_line(segment.start_line);
+        segment.end_line = file.new_line(segment.end_line);
+        remap(&mut segment.signals);
+    }
+}
+
+/// The file report (with its segments when `segments` is set and its
+/// comment-free verdict when `comment_free` is) and, when `symbols` is set,
+/// the per-symbol reports.
+fn run_pipeline(
+    pipeline: &Pipeline,
+    source: &str,
+    path: &Path,
+    symbols: bool,
+    segments: bool,
+    comment_free: bool,
+) -> anyhow::Result<(Report, Option<Vec<SymbolReport>>)> {
+    let mut report = pipeline.run(source, Some(path.to_path_buf()));
+    if segments {
+        report.segments = Some(pipeline.run_segments(source, Some(path)));
+    }
+    if comment_free {
+        report.comment_free = Some(pipeline.run_comment_free(source, Some(path)));
+    }
+    let symbol_reports = if symbols {
+        Some(pipeline.run_symbols(source.as_bytes(), path)?)
+    } else {
+        None
+    };
+    Ok((report, symbol_reports))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const SOURCE: &str = "/// Adds two numbers.\nfn add(a: i32, b: i32) -> i32 {\n    a + b\n}\n";
+
+    #[test]
+    fn analyzer_is_send_and_sync() {
+        fn assert_send_sync<T: Send + Sync>() {}
+        assert_send_sync::<Analyzer>();
+    }
+
+    #[test]
+    fn analyze_source_records_name_as_file_path() {
+        let report = Analyzer::new().analyze_source("src/add.rs", SOURCE.as_bytes()).unwrap();
+        assert_eq!(report.metadata.file_path, Some(PathBuf::from("src/add.rs")));
+        assert!(report.metadata.lines_of_code > 0);
+        assert!(report.symbol_reports.is_none());
+    }
+
+    #[test]
+    fn analyze_source_rejects_non_utf8() {
+        let err = Analyzer::new().analyze_source("bad.rs", &[0xff, 0xfe]).unwrap_err();
+        assert!(err.to_string().contains("bad.rs"));
+    }
+
+    #[test]
+    fn analyze_file_matches_analyze_source() {
+        let dir = tempfile::tempdir().unwrap();
+        let path = dir.path().join("add.rs");
+        std::fs::write(&path, SOURCE).unwrap();
+        let analyzer = Analyzer::new();
+        let from_file = analyzer.analyze_file(&path).unwrap();
+        let from_source = analyzer.analyze_source("add.rs", SOURCE.as_bytes()).unwrap();
+        assert_eq!(from_file.metadata.file_path, Some(path));
+        assert_eq!(from_file.attribution.primary, from_source.attribution.primary);
+        assert_eq!(from_file.signals.len(), from_source.signals.len());
+    }
+
+    #[test]
+    fn analyze_file_reports_missing_path() {
+        let err = Analyzer::new().analyze_file(Path::new("/nonexistent/add.rs")).unwrap_err();
+        assert!(err.to_string().contains("/nonexistent/add.rs"));
+    }
+
+    #[test]
+    fn analyze_patch_scores_added_lines_at_new_file_numbers() {
+        use crate::ignore_rules::AllowAll;
+        let added: String = SOURCE.lines().map(|l| format!("+{l}\n")).collect();
+        let diff = format!(
+            "--- a/README.md\n+++ b/README.md\n@@ -1 +1 @@\n-old\n+new\n\
+             --- a/src/a.rs\n+++ b/src/a.rs\n@@ -8,2 +8,6 @@\n fn x() {{}}\n \n{added}"
+        );
+        let reports = Analyzer::new().with_segments(true).analyze_patch(&diff, &AllowAll).unwrap();
+        assert_eq!(reports.len(), 1);
+        let report = &reports[0];
+        assert_eq!(report.metadata.file_path.as_deref(), Some(Path::new("src/a.rs")));
+        let segments = report.segments.as_ref().unwrap();
+        assert_eq!((segments[0].start_line, segments[0].end_line), (10, 13));
+        let lines = report.signals.iter().flat_map(|s| &s.lines);
+        assert!(lines.into_iter().all(|l| (10..=13).contains(l)));
+
+        assert!(Analyzer::new().analyze_patch("not a diff", &AllowAll).is_err());
+    }
+
+    #[test]
+    fn analyze_fs_reads_in_memory_files() {
+        use crate::ignore_rules::AllowAll;
+        use crate::source_fs::MemFs;
+
+        let fs = MemFs::new()
+            .with_file("src/add.rs", SOURCE)
+            .with_file("src/notes.txt", "not source")
+            .with_file("lib/util.py", "def f():\n    return 1\n");
+        let reports = Analyzer::new().analyze_fs(&fs, Path::new(""), &AllowAll).unwrap();
+        let paths: Vec<_> = reports.iter().filter_map(|r| r.metadata.file_path.clone()).collect();
+        assert_eq!(paths, vec![PathBuf::from("lib/util.py"), PathBuf::from("src/add.rs")]);
+
+        let single = Analyzer::new().analyze_file_in(&fs, Path::new("src/add.rs")).unwrap();
+        assert_eq!(single.signals.len(), reports[1].signals.len());
+        assert!(Analyzer::new().analyze_fs(&fs, Path::new("missing"), &AllowAll).is_err());
+    }
+
+    #[test]
+    fn analyze_tree_streams_lazily_and_reports_errors_per_file() {
+        use crate::ignore_rules::AllowAll;
+        use crate::source_fs::MemFs;
+        use std::sync::atomic::{AtomicUsize, Ordering};
+
+        /// Counts reads; `broken.rs` cannot be read.
+        struct Counting(MemFs, AtomicUsize);
+
+        impl SourceFs for Counting {
+            fn read(&self, path: &Path) -> std::io::Result<Vec<u8>> {
+                self.1.fetch_add(1, Ordering::SeqCst);
+                if path.ends_with("broken.rs") {
+                    return Err(std::io::Error::other("disk on fire"));
+                }
+                self.0.read(path)
+            }
+
+            fn read_dir(&self, dir: &Path) -> std::io::Result<Vec<crate::source_fs::DirEntry>> {
+                self.0.read_dir(dir)
+            }
+        }
+
+        let files: MemFs = (0..20).map(|i| (format!("src/f{i:02}.rs"), SOURCE)).collect();
+        let fs = Counting(files.with_file("a/broken.rs", SOURCE), AtomicUsize::new(0));
+        let analyzer = Analyzer::new();
+
+        let mut results = analyzer.analyze_tree(&fs, Path::new(""), &AllowAll).unwrap();
+        let first = results.next().unwrap();
+        assert_eq!(first.path, PathBuf::from("a/broken.rs"));
+        assert!(format!("{:#}", first.report.unwrap_err()).contains("disk on fire"));
+        let second = results.next().unwrap();
+        assert_eq!(second.path, PathBuf::from("src/f00.rs"));
+        assert!(second.report.is_ok());
+        drop(results);
+        assert_eq!(fs.1.load(Ordering::SeqCst), 2, "nothing past the second file was read");
+
+        let all: Vec<_> = analyzer.analyze_tree(&fs, Path::new(""), &AllowAll).unwrap().collect();
+        assert_eq!(all.len(), 21);
+        assert!(analyzer.analyze_tree(&fs, Path::new("missing"), &AllowAll).is_err());
+    }
+
+    #[test]
+    fn with_progress_reports_each_file_before_analysis() {
+        use crate::ignore_rules::AllowAll;
+        use crate::source_fs::MemFs;
+        use std::sync::{Arc, Mutex};
+
+        let seen = Arc::new(Mutex::new(Vec::new()));
+        let sink = Arc::clone(&seen);
+        let analyzer = Analyzer::new().with_progress(move |done, total, current| {
+            sink.lock().unwrap().push((done, total, current.to_path_buf()));
+        });
+        let fs = MemFs::new().with_file("a.rs", SOURCE).with_file("b.py", "x = 1\n");
+
+        analyzer.analyze_fs(&fs, Path::new(""), &AllowAll).unwrap();
+        assert_eq!(
+            *seen.lock().unwrap(),
+            vec![(0, Some(2), PathBuf::from("a.rs")), (1, Some(2), PathBuf::from("b.py"))]
+        );
+
+        seen.lock().unwrap().clear();
+        let _ = analyzer.analyze_tree(&fs, Path::new(""), &AllowAll).unwrap().count();
+        assert_eq!(
+            *seen.lock().unwrap(),
+            vec![(0, None, PathBuf::from("a.rs")), (1, None, PathBuf::from("b.py"))]
+        );
+    }
+
+    #[test]
+    fn with_timeout_reports_overrunning_files_as_skipped() {
+        let big: String = (0..20_000).map(|i| format!("let x{i} = {i}; // step {i}\n")).collect();
+        let analyzer = Analyzer::new().with_timeout(Duration::from_nanos(1));
+        let report = analyzer.analyze_source("big.rs", big.as_bytes()).unwrap();
+        assert_eq!(report.metadata.skipped.as_deref(), Some(timeout::SKIPPED_TIMEOUT));
+        assert_eq!(report.metadata.file_path, Some(PathBuf::from("big.rs")));
+        assert!(!report.attribution.has_sufficient_data());
+
+        let relaxed = Analyzer::new().with_timeout(Duration::from_secs(60));
+        let report = relaxed.analyze_source("add.rs", SOURCE.as_bytes()).unwrap();
+        assert!(report.metadata.skipped.is_none());
+        assert!(report.metadata.lines_of_code > 0);
+    }
+
+    #[test]
+    fn with_max_file_size_skips_large_files_unread() {
+        use crate::ignore_rules::AllowAll;
+        use crate::source_fs::MemFs;
+
+        /// Panics if a file's content is read.
+        struct SizeOnly(MemFs);
+
+        impl SourceFs for SizeOnly {
+            fn read(&self, path: &Path) -> std::io::Result<Vec<u8>> {
+                panic!("{} should not be read", path.display())
+            }
+
+            fn read_dir(&self, dir: &Path) -> std::io::Result<Vec<crate::source_fs::DirEntry>> {
+                self.0.read_dir(dir)
+            }
+
+            fn size(&self, path: &Path) -> std::io::Result<u64> {
+                self.0.size(path)
+            }
+        }
+
+        let analyzer = Analyzer::new().with_max_file_size(16);
+        let fs = SizeOnly(MemFs::new().with_file("bundle.min.js", "x".repeat(1000)));
+        let reports = analyzer.analyze_fs(&fs, Path::new(""), &AllowAll).unwrap();
+        assert_eq!(reports[0].metadata.skipped.as_deref(), Some(limits::SKIPPED_TOO_LARGE));
+
+        let report = analyzer.analyze_source("add.rs", SOURCE.as_bytes()).unwrap();
+        assert_eq!(report.metadata.skipped.as_deref(), Some(limits::SKIPPED_TOO_LARGE));
+        let report = analyzer.analyze_source("tiny.rs", b"fn f() {}").unwrap();
+        assert!(report.metadata.skipped.is_none());
+    }
+
+    #[test]
+    fn with_segments_populates_segments_and_survives_the_cache() {
+        let cache_dir = tempfile::tempdir().unwrap();
+        let plain = Analyzer::new().with_cache_dir(cache_dir.path());
+        assert!(plain.analyze_source("a.rs", SOURCE.as_bytes()).unwrap().segments.is_none());
+
+        let segmented = Analyzer::new().with_cache_dir(cache_dir.path()).with_segments(true);
+        let report = segmented.analyze_source("a.rs", SOURCE.as_bytes()).unwrap();
+        let segments = report.segments.expect("segments requested");
+        assert_eq!(segments.len(), 1);
+        assert_eq!((segments[0].start_line, segments[0].end_line), (1, 4));
+        assert!(plain.analyze_source("a.rs", SOURCE.as_bytes()).unwrap().segments.is_none());
+    }
+
+    #[test]
+    fn with_comment_free_populates_stripped_verdict_and_survives_the_cache() {
+        let cache_dir = tempfile::tempdir().unwrap();
+        let plain = Analyzer::new().with_cache_dir(cache_dir.path());
+        assert!(plain.analyze_source("a.rs", SOURCE.as_bytes()).unwrap().comment_free.is_none());
+
+        let stripped = Analyzer::new().with_cache_dir(cache_dir.path()).with_comment_free(true);
+        assert!(stripped.analyze_source("a.rs", SOURCE.as_bytes()).unwrap().comment_free.is_some());
+        assert!(stripped.analyze_source("b.rs", SOURCE.as_bytes()).unwrap().comment_free.is_some());
+        assert!(plain.analyze_source("a.rs", SOURCE.as_bytes()).unwrap().comment_free.is_none());
+    }
+
+    #[test]
+    fn with_cache_dir_serves_second_call_from_cache() {
+        let cache_dir = tempfile::tempdir().unwrap();
+        let analyzer = Analyzer::new().with_cache_dir(cache_dir.path());
+        let first = analyzer.analyze_source("a.rs", SOURCE.as_bytes()).unwrap();
+        assert!(cache_dir.path().join("cache.redb").exists());
+
+        let cache = Cache::shared(cache_dir.path()).unwrap();
+        assert!(cache.get(&Cache::hash_content(SOURCE.as_bytes())).is_some());
+        drop(cache);
+
+        let second = analyzer.analyze_source("b.rs", SOURCE.as_bytes()).unwrap();
+        assert_eq!(second.metadata.file_path, Some(PathBuf::from("b.rs")));
+        assert_eq!(first.attribution.primary, second.attribution.primary);
+    }
+
+    #[test]
+    fn one_analyzer_serves_many_threads_through_one_cache() {
+        let cache_dir = tempfile::tempdir().unwrap();
+        let analyzer = Analyzer::new().with_cache_dir(cache_dir.path());
+        let sources: Vec<String> = (0..8).map(|i| format!("fn f{i}(x: i32) -> i32 {{\n    x + {i}\n}}\n")).collect();
+        std::thread::scope(|scope| {
+            for (i, source) in sources.iter().enumerate() {
+                let analyzer = &analyzer;
+                scope.spawn(move || analyzer.analyze_source(&format!("f{i}.rs"), source.as_bytes()).unwrap());
+            }
+        });
+
+        // Every thread wrote through the one open database.
+        let cache = Cache::shared(cache_dir.path()).unwrap();
+        for source in &sources {
+            assert!(cache.get(&Cache::hash_content(source.as_bytes())).is_some());
+        }
+    }
+
+    #[test]
+    fn with_config_applies_weight_overrides() {
+        let dir = tempfile::tempdir().unwrap();
+        let cfg = dir.path().join(".vibecheck");
+        std::fs::write(&cfg, "[heuristics]\n\"rust.ai_signals.all_fns_documented\" = 0.0\n").unwrap();
+        let config = IgnoreConfig::from_file(&cfg).unwrap();
+        let report = Analyzer::new()
+            .with_config(&config)
+            .analyze_source("add.rs", SOURCE.as_bytes())
+            .unwrap();
+        assert!(report
+            .signals
+            .iter()
+            .all(|s| s.id != "rust.ai_signals.all_fns_documented" || s.weight == 0.0));
+    }
+}
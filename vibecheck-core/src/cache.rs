@@ -1,21 +1,30 @@
 use std::collections::HashMap;
 use std::path::{Path, PathBuf};
-use std::sync::{Mutex, OnceLock};
+use std::sync::atomic::{AtomicU64, Ordering};
+use std::sync::{Arc, Mutex, OnceLock, Weak};
 
+#[cfg(not(target_arch = "wasm32"))]
 use redb::{Database, TableDefinition};
 use sha2::{Digest, Sha256};
 
+use crate::lru::LruCache;
 use crate::merkle::DirNode;
 use crate::report::{Report, SymbolReport};
 
-/// SHA-256 of the embedded heuristics.toml, computed once.
-/// Mixed into every content hash so cache entries auto-invalidate
-/// when signal definitions change.
+/// SHA-256 of the crate version, the embedded heuristics.toml and
+/// test_heuristics.toml, the default severity thresholds and the probability
+/// calibration, computed once.  Mixed into every content hash so
+/// cache entries auto-invalidate when signal definitions, detector code or
+/// the calibration change.
 fn heuristics_epoch() -> &'static [u8; 32] {
     static EPOCH: OnceLock<[u8; 32]> = OnceLock::new();
     EPOCH.get_or_init(|| {
         let mut h = Sha256::new();
+        h.update(env!("CARGO_PKG_VERSION").as_bytes());
         h.update(include_str!("../heuristics.toml").as_bytes());
+        h.update(include_str!("../profiles/balanced.toml").as_bytes());
+        h.update(include_str!("../test_heuristics.toml").as_bytes());
+        h.update(format!("{:?}", crate::calibration::FIXTURE_CALIBRATION).as_bytes());
         let result = h.finalize();
         let mut hash = [0u8; 32];
         hash.copy_from_slice(&result);
@@ -61,13 +70,17 @@ pub trait CacheBackend: Send + Sync {
 // RedbBackend
 // ---------------------------------------------------------------------------
 
+#[cfg(not(target_arch = "wasm32"))]
 const KV_TABLE: TableDefinition<&[u8], &[u8]> = TableDefinition::new("kv_v2");
 
-/// Persistent cache backend backed by a redb embedded database.
+/// Persistent cache backend backed by a redb embedded database.  Not
+/// available on `wasm32`, which has no filesystem.
+#[cfg(not(target_arch = "wasm32"))]
 pub struct RedbBackend {
     db: Database,
 }
 
+#[cfg(not(target_arch = "wasm32"))]
 impl RedbBackend {
     pub fn open(dir: &Path) -> Result<Self, Box<dyn std::error::Error + Send + Sync>> {
         std::fs::create_dir_all(dir)?;
@@ -77,6 +90,7 @@ impl RedbBackend {
     }
 }
 
+#[cfg(not(target_arch = "wasm32"))]
 impl CacheBackend for RedbBackend {
     fn get(&self, key: &[u8]) -> Result<Option<Vec<u8>>, CacheError> {
         let read_txn = self.db.begin_read().map_err(|e| CacheError::Backend(e.into()))?;
@@ -123,46 +137,42 @@ impl CacheBackend for RedbBackend {
 // InMemoryBackend
 // ---------------------------------------------------------------------------
 
-/// In-memory cache backend using a `HashMap`.
-/// When `max_entries` is reached, new insertions for unknown keys are dropped.
+/// In-memory cache backend with least-recently-used eviction.
+/// When `max_entries` is reached, inserting a new key evicts the entry
+/// that was read or written longest ago.
 pub struct InMemoryBackend {
-    store: Mutex<HashMap<Vec<u8>, Vec<u8>>>,
-    max_entries: usize,
+    store: Mutex<LruCache<Vec<u8>, Vec<u8>>>,
 }
 
 impl InMemoryBackend {
     pub fn new(max_entries: usize) -> Self {
         Self {
-            store: Mutex::new(HashMap::new()),
-            max_entries,
+            store: Mutex::new(LruCache::new(max_entries)),
         }
     }
 }
 
 impl CacheBackend for InMemoryBackend {
     fn get(&self, key: &[u8]) -> Result<Option<Vec<u8>>, CacheError> {
-        let store = self.store.lock().unwrap();
-        Ok(store.get(key).cloned())
+        let mut store = self.store.lock().unwrap();
+        Ok(store.get(&key.to_vec()).cloned())
     }
 
     fn put(&self, key: &[u8], value: &[u8]) -> Result<(), CacheError> {
         let mut store = self.store.lock().unwrap();
-        if store.len() >= self.max_entries && !store.contains_key(key) {
-            return Ok(());
-        }
-        store.insert(key.to_vec(), value.to_vec());
+        store.put(key.to_vec(), value.to_vec());
         Ok(())
     }
 
     fn delete(&self, key: &[u8]) -> Result<(), CacheError> {
         let mut store = self.store.lock().unwrap();
-        store.remove(key);
+        store.remove(&key.to_vec());
         Ok(())
     }
 
     fn contains(&self, key: &[u8]) -> Result<bool, CacheError> {
         let store = self.store.lock().unwrap();
-        Ok(store.contains_key(key))
+        Ok(store.contains(&key.to_vec()))
     }
 }
 
@@ -172,17 +182,20 @@ impl CacheBackend for InMemoryBackend {
 
 /// Two-tier cache: fast in-memory hot tier with persistent cold tier.
 /// Reads check hot first, promoting cold hits. Writes go to both tiers.
+#[cfg(not(target_arch = "wasm32"))]
 pub struct TieredBackend {
     hot: InMemoryBackend,
     cold: RedbBackend,
 }
 
+#[cfg(not(target_arch = "wasm32"))]
 impl TieredBackend {
     pub fn new(hot: InMemoryBackend, cold: RedbBackend) -> Self {
         Self { hot, cold }
     }
 }
 
+#[cfg(not(target_arch = "wasm32"))]
 impl CacheBackend for TieredBackend {
     fn get(&self, key: &[u8]) -> Result<Option<Vec<u8>>, CacheError> {
         if let Some(val) = self.hot.get(key)? {
@@ -213,6 +226,37 @@ impl CacheBackend for TieredBackend {
     }
 }
 
+// ---------------------------------------------------------------------------
+// Lookup statistics
+// ---------------------------------------------------------------------------
+
+static REPORT_HITS: AtomicU64 = AtomicU64::new(0);
+static REPORT_MISSES: AtomicU64 = AtomicU64::new(0);
+
+/// Report lookups made through every [`Cache`] in this process.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub struct CacheStats {
+    pub hits: u64,
+    pub misses: u64,
+}
+
+impl CacheStats {
+    /// Fraction of lookups served from the cache, or `None` if there were
+    /// no lookups (e.g. `--no-cache`).
+    pub fn hit_rate(&self) -> Option<f64> {
+        let total = self.hits + self.misses;
+        (total > 0).then(|| self.hits as f64 / total as f64)
+    }
+}
+
+/// Snapshot of the process-wide report lookup counters.
+pub fn stats() -> CacheStats {
+    CacheStats {
+        hits: REPORT_HITS.load(Ordering::Relaxed),
+        misses: REPORT_MISSES.load(Ordering::Relaxed),
+    }
+}
+
 // ---------------------------------------------------------------------------
 // Cache — public API (unchanged signatures)
 // ---------------------------------------------------------------------------
@@ -225,6 +269,7 @@ pub struct Cache {
 
 impl Cache {
     /// Open (or create) the cache database at `dir/cache.redb`.
+    #[cfg(not(target_arch = "wasm32"))]
     pub fn open(dir: &Path) -> Result<Self, Box<dyn std::error::Error + Send + Sync>> {
         let cold = RedbBackend::open(dir)?;
         let hot = InMemoryBackend::new(1024);
@@ -233,6 +278,35 @@ impl Cache {
         })
     }
 
+    /// There is no persistent cache on `wasm32`, so opening one always
+    /// fails and analysis runs uncached.  Use [`with_backend`](Self::with_backend)
+    /// with an [`InMemoryBackend`] for a cache that lives as long as the page.
+    #[cfg(target_arch = "wasm32")]
+    pub fn open(dir: &Path) -> Result<Self, Box<dyn std::error::Error + Send + Sync>> {
+        Err(format!("no persistent cache on wasm32: {}", dir.display()).into())
+    }
+
+    /// The cache at `dir/cache.redb`, opened once per process and shared by
+    /// every caller until the last handle is dropped.
+    ///
+    /// The database file can only be open once per process, so concurrent
+    /// [`open`](Self::open) calls on one directory fail; analyzers sharing a
+    /// cache directory go through here instead, and share its in-memory hot
+    /// tier too.
+    pub fn shared(dir: &Path) -> Result<Arc<Self>, Box<dyn std::error::Error + Send + Sync>> {
+        static OPEN: OnceLock<Mutex<HashMap<PathBuf, Weak<Cache>>>> = OnceLock::new();
+        std::fs::create_dir_all(dir)?;
+        let key = std::fs::canonicalize(dir)?;
+        let mut open = OPEN.get_or_init(Default::default).lock().unwrap();
+        if let Some(cache) = open.get(&key).and_then(Weak::upgrade) {
+            return Ok(cache);
+        }
+        let cache = Arc::new(Self::open(dir)?);
+        open.retain(|_, c| c.strong_count() > 0);
+        open.insert(key, Arc::downgrade(&cache));
+        Ok(cache)
+    }
+
     /// Construct a cache with a custom backend.
     pub fn with_backend(backend: Box<dyn CacheBackend>) -> Self {
         Self { backend }
@@ -272,6 +346,47 @@ impl Cache {
         hash
     }
 
+    /// Like [`hash_content`](Self::hash_content), but also mixes in
+    /// `[heuristics]` weight overrides so that changing a weight in
+    /// `.vibecheck` invalidates reports scored under the old weights.
+    /// With no overrides the result is identical to `hash_content`.
+    pub fn hash_content_with_overrides(content: &[u8], overrides: &HashMap<String, f64>) -> [u8; 32] {
+        Self::hash_content_with_settings(content, overrides, &[])
+    }
+
+    /// Like [`hash_content_with_overrides`](Self::hash_content_with_overrides),
+    /// but also mixes in non-weight `settings` that change analyzer output
+    /// (e.g. configured filler phrases).  With no overrides and no settings
+    /// the result is identical to `hash_content`.
+    pub fn hash_content_with_settings(
+        content: &[u8],
+        overrides: &HashMap<String, f64>,
+        settings: &[String],
+    ) -> [u8; 32] {
+        if overrides.is_empty() && settings.is_empty() {
+            return Self::hash_content(content);
+        }
+        let mut sorted: Vec<_> = overrides.iter().collect();
+        sorted.sort_by(|a, b| a.0.cmp(b.0));
+
+        let mut hasher = Sha256::new();
+        hasher.update(heuristics_epoch());
+        for (id, weight) in sorted {
+            hasher.update(id.as_bytes());
+            hasher.update([0u8]);
+            hasher.update(weight.to_bits().to_le_bytes());
+        }
+        for setting in settings {
+            hasher.update([1u8]);
+            hasher.update(setting.as_bytes());
+        }
+        hasher.update(content);
+        let result = hasher.finalize();
+        let mut hash = [0u8; 32];
+        hash.copy_from_slice(&result);
+        hash
+    }
+
     fn ns_key(ns: u8, key: &[u8]) -> Vec<u8> {
         let mut k = Vec::with_capacity(1 + key.len());
         k.push(ns);
@@ -282,16 +397,30 @@ impl Cache {
     /// Look up a cached `Report` by file-content hash.
     pub fn get(&self, hash: &[u8; 32]) -> Option<Report> {
         let key = Self::ns_key(NS_REPORT, hash);
-        let bytes = self.backend.get(&key).ok()??;
-        serde_json::from_slice(&bytes).ok()
+        let report: Option<Report> = self
+            .backend
+            .get(&key)
+            .ok()
+            .flatten()
+            .and_then(|bytes| serde_json::from_slice(&bytes).ok());
+        let counter = if report.is_some() { &REPORT_HITS } else { &REPORT_MISSES };
+        counter.fetch_add(1, Ordering::Relaxed);
+        report
     }
 
     /// Store a `Report` under the given file-content hash.
+    ///
+    /// Degraded reports are not stored, so that the file is scored in full
+    /// once the missing backend (an endpoint that was down) is back.  Nor is
+    /// anything a timed-out worker produces (see [`crate::timeout::run`]).
     pub fn put(
         &self,
         hash: &[u8; 32],
         report: &Report,
     ) -> Result<(), Box<dyn std::error::Error + Send + Sync>> {
+        if !report.metadata.degraded.is_empty() || crate::timeout::cancelled() {
+            return Ok(());
+        }
         let key = Self::ns_key(NS_REPORT, hash);
         let json = serde_json::to_vec(report)?;
         self.backend.put(&key, &json)?;
@@ -305,12 +434,16 @@ impl Cache {
         serde_json::from_slice(&bytes).ok()
     }
 
-    /// Store `SymbolReport`s under the given file-content hash.
+    /// Store `SymbolReport`s under the given file-content hash, unless a
+    /// timed-out worker produced them.
     pub fn put_symbols(
         &self,
         hash: &[u8; 32],
         symbols: &[SymbolReport],
     ) -> Result<(), Box<dyn std::error::Error + Send + Sync>> {
+        if crate::timeout::cancelled() {
+            return Ok(());
+        }
         let key = Self::ns_key(NS_SYMBOL, hash);
         let json = serde_json::to_vec(symbols)?;
         self.backend.put(&key, &json)?;
@@ -342,12 +475,51 @@ impl Cache {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use std::collections::BTreeMap;
     use crate::merkle::DirNode;
 
+    #[test]
+    fn stats_count_report_hits_and_misses() {
+        use crate::report::{Attribution, ModelFamily, Report, ReportMetadata};
+
+        let cache = Cache::with_backend(Box::new(InMemoryBackend::new(8)));
+        let report = Report {
+            attribution: Attribution {
+                primary: ModelFamily::Human,
+                confidence: 0.5,
+                scores: BTreeMap::new(),
+                era: None,
+                ai_probability: None,
+                authorship: None,
+            },
+            signals: vec![],
+            metadata: ReportMetadata { file_path: None, lines_of_code: 1, signal_count: 0, degraded: vec![], skipped: None },
+            symbol_reports: None,
+            segments: None,
+            comment_free: None,
+            ensemble: None,
+        };
+
+        // Counters are process-wide and tests run in parallel, so compare
+        // lower bounds rather than exact deltas.
+        let before = stats();
+        assert!(cache.get(&[1u8; 32]).is_none());
+        cache.put(&[1u8; 32], &report).unwrap();
+        assert!(cache.get(&[1u8; 32]).is_some());
+        let after = stats();
+        assert!(after.hits > before.hits);
+        assert!(after.misses > before.misses);
+    }
+
+    #[test]
+    fn hit_rate_is_none_without_lookups() {
+        assert_eq!(CacheStats::default().hit_rate(), None);
+        assert_eq!(CacheStats { hits: 3, misses: 1 }.hit_rate(), Some(0.75));
+    }
+
     #[test]
     fn file_cache_round_trip() {
         use crate::report::{Attribution, ModelFamily, Report, ReportMetadata};
-        use std::collections::HashMap;
 
         let dir = tempfile::tempdir().unwrap();
         let cache = Cache::open(dir.path()).unwrap();
@@ -357,21 +529,34 @@ mod tests {
             attribution: Attribution {
                 primary: ModelFamily::Claude,
                 confidence: 0.9,
-                scores: HashMap::from([(ModelFamily::Claude, 0.9), (ModelFamily::Human, 0.1)]),
+                scores: BTreeMap::from([(ModelFamily::Claude, 0.9), (ModelFamily::Human, 0.1)]),
+                era: None,
+                ai_probability: None,
+                authorship: None,
             },
             signals: vec![],
             metadata: ReportMetadata {
                 file_path: None,
                 lines_of_code: 10,
                 signal_count: 0,
+                degraded: vec![],
+                skipped: None,
             },
             symbol_reports: None,
+            segments: None,
+            comment_free: None,
+            ensemble: None,
         };
 
         cache.put(&hash, &report).unwrap();
         let retrieved = cache.get(&hash).unwrap();
         assert_eq!(retrieved.metadata.lines_of_code, 10);
         assert_eq!(retrieved.attribution.primary, ModelFamily::Claude);
+
+        let mut degraded = report.clone();
+        degraded.metadata.degraded = vec![crate::capability::Capability::Perplexity];
+        cache.put(&[8u8; 32], &degraded).unwrap();
+        assert!(cache.get(&[8u8; 32]).is_none());
     }
 
     #[test]
@@ -399,6 +584,18 @@ mod tests {
         assert_eq!(retrieved.children, vec!["a.rs", "b.rs"]);
     }
 
+    #[test]
+    fn shared_cache_is_opened_once_per_directory() {
+        let dir = tempfile::tempdir().unwrap();
+        let a = Cache::shared(dir.path()).unwrap();
+        let b = Cache::shared(&dir.path().join(".")).unwrap();
+        assert!(Arc::ptr_eq(&a, &b));
+        assert!(Cache::open(dir.path()).is_err(), "the database is held open");
+
+        drop((a, b));
+        assert!(Cache::open(dir.path()).is_ok(), "the last handle closes it");
+    }
+
     #[test]
     fn dir_cache_miss_returns_none() {
         let dir = tempfile::tempdir().unwrap();
@@ -409,7 +606,6 @@ mod tests {
     #[test]
     fn symbol_cache_round_trip() {
         use crate::report::{Attribution, ModelFamily, Signal, SymbolMetadata, SymbolReport};
-        use std::collections::HashMap;
 
         let dir = tempfile::tempdir().unwrap();
         let cache = Cache::open(dir.path()).unwrap();
@@ -425,7 +621,10 @@ mod tests {
             attribution: Attribution {
                 primary: ModelFamily::Claude,
                 confidence: 0.85,
-                scores: HashMap::from([(ModelFamily::Claude, 0.85)]),
+                scores: BTreeMap::from([(ModelFamily::Claude, 0.85)]),
+                era: None,
+                ai_probability: None,
+                authorship: None,
             },
             signals: vec![Signal::new("", "test", "test signal", ModelFamily::Claude, 1.0)],
         }];
@@ -459,15 +658,16 @@ mod tests {
     }
 
     #[test]
-    fn in_memory_backend_respects_max_entries() {
+    fn in_memory_backend_evicts_least_recently_used() {
         let backend = InMemoryBackend::new(2);
         backend.put(b"a", b"1").unwrap();
         backend.put(b"b", b"2").unwrap();
+        backend.get(b"a").unwrap();
         backend.put(b"c", b"3").unwrap();
 
         assert!(backend.get(b"a").unwrap().is_some());
-        assert!(backend.get(b"b").unwrap().is_some());
-        assert!(backend.get(b"c").unwrap().is_none());
+        assert!(backend.get(b"b").unwrap().is_none());
+        assert!(backend.get(b"c").unwrap().is_some());
     }
 
     #[test]
@@ -534,15 +734,23 @@ mod tests {
             attribution: Attribution {
                 primary: ModelFamily::Human,
                 confidence: 0.5,
-                scores: HashMap::from([(ModelFamily::Human, 0.5)]),
+                scores: BTreeMap::from([(ModelFamily::Human, 0.5)]),
+                era: None,
+                ai_probability: None,
+                authorship: None,
             },
             signals: vec![],
             metadata: ReportMetadata {
                 file_path: None,
                 lines_of_code: 1,
                 signal_count: 0,
+                degraded: vec![],
+                skipped: None,
             },
             symbol_reports: None,
+            segments: None,
+            comment_free: None,
+            ensemble: None,
         };
 
         cache.put(&hash, &report).unwrap();
@@ -550,6 +758,37 @@ mod tests {
         assert!(cache.get_symbols(&hash).is_none());
     }
 
+    #[test]
+    fn hash_without_overrides_matches_plain_hash() {
+        let content = b"fn main() {}";
+        assert_eq!(
+            Cache::hash_content_with_overrides(content, &HashMap::new()),
+            Cache::hash_content(content)
+        );
+    }
+
+    #[test]
+    fn hash_changes_when_weight_overrides_change() {
+        let content = b"fn main() {}";
+        let a = HashMap::from([("rust.errors.zero_unwrap".to_string(), 3.0)]);
+        let b = HashMap::from([("rust.errors.zero_unwrap".to_string(), 0.0)]);
+        let ha = Cache::hash_content_with_overrides(content, &a);
+        assert_ne!(ha, Cache::hash_content(content));
+        assert_ne!(ha, Cache::hash_content_with_overrides(content, &b));
+        assert_eq!(ha, Cache::hash_content_with_overrides(content, &a.clone()));
+    }
+
+    #[test]
+    fn hash_changes_when_settings_change() {
+        let content = b"fn main() {}";
+        let none = HashMap::new();
+        let de = vec!["filler.locales=en,de".to_string()];
+        let h = Cache::hash_content_with_settings(content, &none, &de);
+        assert_ne!(h, Cache::hash_content(content));
+        assert_ne!(h, Cache::hash_content_with_settings(content, &none, &["filler.locales=en".to_string()]));
+        assert_eq!(Cache::hash_content_with_settings(content, &none, &[]), Cache::hash_content(content));
+    }
+
     #[test]
     fn resolve_path_config_override_takes_priority() {
         let custom = Path::new("/tmp/my-custom-cache");
@@ -0,0 +1,389 @@
+//! Calibrated "likely AI-generated" probability.
+//!
+//! The family scores are a normalized weighted sum: a 0.6 for Claude says
+//! Claude beat the other families, not that the file is 60% likely to be
+//! AI-written.  This module maps the AI share of the scores — everything
+//! but Human — to a probability with Platt scaling (a logistic fit), fitted
+//! against the labelled fixture corpus so that, on that corpus, a reported
+//! 0.9 is right about nine times in ten.
+//!
+//! Detector coverage differs by language, so the same AI share can mean
+//! different things in Go and in Python.  A [`Calibration`] holds one model
+//! per language, fitted on each language's samples by `vibecheck tune
+//! --classifier calibration` and loaded through `[calibration] file` in
+//! `.vibecheck`, so that a 0.7 — and the thresholds and policy gates
+//! compared against it — means the same across a polyglot repository.
+//! Languages without their own model use the fallback, which by default is
+//! [`FIXTURE_CALIBRATION`].
+//!
+//! The probability is then cut into a three-way [`Authorship`] verdict —
+//! human-authored, AI-assisted, AI-generated — by [`Bands`], which
+//! `[calibration]` can move.  A fitted calibration also keeps the
+//! [`Distribution`] of its samples' probabilities, which the HTML report
+//! charts each file against.
+
+use std::collections::BTreeMap;
+use std::path::Path;
+
+use anyhow::Context;
+use serde::{Deserialize, Serialize};
+
+use crate::heuristics::HeuristicLanguage;
+use crate::language::detect_language;
+use crate::language_pack;
+use crate::report::{Attribution, Authorship, ModelFamily};
+
+/// A Platt-scaling model: `p = 1 / (1 + exp(-(a·x + b)))`.
+#[derive(Debug, Clone, Copy, PartialEq, Serialize, Deserialize)]
+pub struct Platt {
+    pub a: f64,
+    pub b: f64,
+}
+
+/// Fitted against `tests/fixtures/**` (16 model-written files, 4 human).
+/// Kept honest by `calibration_separates_fixture_corpus` in
+/// `tests/fixture_detection.rs`; refit with [`Platt::fit`] when the
+/// corpus or the weights change.
+pub const FIXTURE_CALIBRATION: Platt = Platt { a: 5.505, b: -2.454 };
+
+/// Newton iterations in [`Platt::fit`]; it converges in well under ten.
+const FIT_ITERATIONS: usize = 50;
+
+/// A language gets its own model in [`Calibration::fit`] only with at
+/// least this many AI-written and this many human-written samples; two
+/// parameters fitted on fewer would track the samples, not the language.
+pub const MIN_SAMPLES_PER_CLASS: usize = 5;
+
+impl Platt {
+    /// The calibrated probability for the uncalibrated score `x`.
+    pub fn probability(&self, x: f64) -> f64 {
+        1.0 / (1.0 + (-(self.a * x + self.b)).exp())
+    }
+
+    /// Fit to `(score, is_ai)` samples by maximum likelihood, with Platt's
+    /// smoothed targets — `(n₊ + 1) / (n₊ + 2)` for positives and
+    /// `1 / (n₋ + 2)` for negatives — so that a perfectly separable corpus
+    /// still yields a finite slope instead of a step function.
+    pub fn fit(samples: &[(f64, bool)]) -> Self {
+        let positives = samples.iter().filter(|(_, ai)| *ai).count() as f64;
+        let negatives = samples.len() as f64 - positives;
+        let hi = (positives + 1.0) / (positives + 2.0);
+        let lo = 1.0 / (negatives + 2.0);
+
+        let mut model = Platt { a: 0.0, b: 0.0 };
+        for _ in 0..FIT_ITERATIONS {
+            // Gradient and Hessian of the cross-entropy in (a, b).
+            let (mut ga, mut gb, mut haa, mut hab, mut hbb) = (0.0, 0.0, 0.0, 0.0, 0.0);
+            for &(x, ai) in samples {
+                let p = model.probability(x);
+                let d = p - if ai { hi } else { lo };
+                let w = p * (1.0 - p);
+                ga += d * x;
+                gb += d;
+                haa += w * x * x;
+                hab += w * x;
+                hbb += w;
+            }
+            let det = haa * hbb - hab * hab;
+            if det.abs() < 1e-12 {
+                break;
+            }
+            model.a -= (hbb * ga - hab * gb) / det;
+            model.b -= (haa * gb - hab * ga) / det;
+        }
+        model
+    }
+}
+
+/// Calibrated AI probability at which a file counts as AI-assisted.
+pub const AI_ASSISTED_AT: f64 = 0.3;
+
+/// Calibrated AI probability at which a file counts as AI-generated.
+pub const AI_GENERATED_AT: f64 = 0.7;
+
+/// Where the calibrated AI probability crosses from one [`Authorship`]
+/// verdict into the next.
+#[derive(Debug, Clone, Copy, PartialEq)]
+pub struct Bands {
+    pub assisted_at: f64,
+    pub generated_at: f64,
+}
+
+impl Default for Bands {
+    fn default() -> Self {
+        Self { assisted_at: AI_ASSISTED_AT, generated_at: AI_GENERATED_AT }
+    }
+}
+
+impl Bands {
+    /// Bands with the given cutoffs, which must be probabilities with
+    /// `assisted_at <= generated_at`.  Equal cutoffs leave no AI-assisted
+    /// band: a binary verdict.
+    pub fn new(assisted_at: f64, generated_at: f64) -> anyhow::Result<Self> {
+        if !(0.0..=1.0).contains(&assisted_at) || !(0.0..=1.0).contains(&generated_at) {
+            anyhow::bail!("cutoffs must be between 0 and 1, got {assisted_at} and {generated_at}");
+        }
+        if assisted_at > generated_at {
+            anyhow::bail!("assisted_at ({assisted_at}) is above generated_at ({generated_at})");
+        }
+        Ok(Self { assisted_at, generated_at })
+    }
+
+    /// The verdict for calibrated AI probability `p`.
+    pub fn authorship(&self, p: f64) -> Authorship {
+        if p >= self.generated_at {
+            Authorship::AiGenerated
+        } else if p >= self.assisted_at {
+            Authorship::AiAssisted
+        } else {
+            Authorship::HumanAuthored
+        }
+    }
+}
+
+/// Bins of a [`Distribution`]: probabilities 0–0.05, 0.05–0.1, … 0.95–1.
+pub const DISTRIBUTION_BINS: usize = 20;
+
+/// The calibrated probabilities of the samples a [`Calibration`] was fitted
+/// on, binned per class, so that a file's probability can be read against
+/// the human and the AI-written files it was calibrated with.
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct Distribution {
+    /// Human-written samples per bin.
+    pub human: Vec<u32>,
+    /// AI-written samples per bin.
+    pub ai: Vec<u32>,
+}
+
+impl Distribution {
+    /// Bin `(probability, is_ai)` samples.
+    pub fn of(samples: impl IntoIterator<Item = (f64, bool)>) -> Self {
+        let mut distribution = Self { human: vec![0; DISTRIBUTION_BINS], ai: vec![0; DISTRIBUTION_BINS] };
+        for (p, ai) in samples {
+            let counts = if ai { &mut distribution.ai } else { &mut distribution.human };
+            counts[bin(p)] += 1;
+        }
+        distribution
+    }
+
+    /// The shares of human-written and of AI-written samples in `p`'s bin
+    /// or above: how often the corpus saw a probability this high.
+    pub fn at_or_above(&self, p: f64) -> (f64, f64) {
+        let share = |counts: &[u32]| {
+            let total: u32 = counts.iter().sum();
+            let above: u32 = counts.iter().skip(bin(p)).sum();
+            if total == 0 {
+                0.0
+            } else {
+                f64::from(above) / f64::from(total)
+            }
+        };
+        (share(&self.human), share(&self.ai))
+    }
+}
+
+/// The [`Distribution`] bin of probability `p`.
+pub fn bin(p: f64) -> usize {
+    ((p.clamp(0.0, 1.0) * DISTRIBUTION_BINS as f64) as usize).min(DISTRIBUTION_BINS - 1)
+}
+
+/// Platt models per language, with a fallback for the rest.
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct Calibration {
+    /// The model for languages without their own.
+    pub fallback: Platt,
+    /// Language — a signal-ID prefix (`rust`, `python`, `js`, `go`) or a
+    /// language pack name — → its model.
+    #[serde(default, rename = "language")]
+    pub languages: BTreeMap<String, Platt>,
+    /// Cutoffs for the authorship verdict; set from `.vibecheck`, not
+    /// fitted, so not part of a calibration file.
+    #[serde(skip)]
+    pub bands: Bands,
+    /// Where the samples it was fitted on fell; `None` for
+    /// [`FIXTURE_CALIBRATION`], whose samples are not shipped.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub distribution: Option<Distribution>,
+}
+
+impl Default for Calibration {
+    /// [`FIXTURE_CALIBRATION`] for every language.  The fixture corpus has
+    /// one human sample per language, too few to fit languages apart.
+    fn default() -> Self {
+        Self { fallback: FIXTURE_CALIBRATION, languages: BTreeMap::new(), bands: Bands::default(), distribution: None }
+    }
+}
+
+impl Calibration {
+    /// Load a calibration written by [`to_toml`](Self::to_toml).
+    pub fn from_file(path: &Path) -> anyhow::Result<Self> {
+        let s = std::fs::read_to_string(path).with_context(|| format!("failed to read {}", path.display()))?;
+        toml::from_str(&s).with_context(|| format!("failed to parse {}", path.display()))
+    }
+
+    pub fn to_toml(&self) -> anyhow::Result<String> {
+        Ok(toml::to_string(self)?)
+    }
+
+    /// The model for `language` (see [`language_key`]).
+    pub fn model(&self, language: Option<&str>) -> &Platt {
+        language.and_then(|l| self.languages.get(l)).unwrap_or(&self.fallback)
+    }
+
+    /// Set `attribution.ai_probability` with the model for `language`, and
+    /// `attribution.authorship` from it.  Left unset when there was no
+    /// signal data to calibrate.
+    pub fn calibrate(&self, attribution: &mut Attribution, language: Option<&str>) {
+        let model = self.model(language);
+        attribution.ai_probability = attribution.has_sufficient_data().then(|| model.probability(ai_share(attribution)));
+        attribution.authorship = attribution.ai_probability.map(|p| self.bands.authorship(p));
+    }
+
+    /// Fit to `(language, score, is_ai)` samples: the fallback on all of
+    /// them, and a model of its own for each language with
+    /// [`MIN_SAMPLES_PER_CLASS`] samples of both classes.  The samples'
+    /// calibrated probabilities are kept as its [`Distribution`].
+    pub fn fit(samples: &[(Option<&str>, f64, bool)]) -> Self {
+        let all: Vec<(f64, bool)> = samples.iter().map(|&(_, x, ai)| (x, ai)).collect();
+        let mut by_language: BTreeMap<&str, Vec<(f64, bool)>> = BTreeMap::new();
+        for &(language, x, ai) in samples {
+            if let Some(language) = language {
+                by_language.entry(language).or_default().push((x, ai));
+            }
+        }
+        let languages = by_language
+            .into_iter()
+            .filter(|(_, rows)| {
+                let ai = rows.iter().filter(|(_, ai)| *ai).count();
+                ai >= MIN_SAMPLES_PER_CLASS && rows.len() - ai >= MIN_SAMPLES_PER_CLASS
+            })
+            .map(|(language, rows)| (language.to_string(), Platt::fit(&rows)))
+            .collect();
+        let calibration = Self { fallback: Platt::fit(&all), languages, bands: Bands::default(), distribution: None };
+        let probabilities: Vec<(f64, bool)> =
+            samples.iter().map(|&(language, x, ai)| (calibration.model(language).probability(x), ai)).collect();
+        Self { distribution: Some(Distribution::of(probabilities)), ..calibration }
+    }
+}
+
+/// The calibration language of the file at `path`: its signal-ID prefix,
+/// or the name of the language pack handling it.
+pub fn language_key(path: &Path) -> Option<String> {
+    detect_language(path)
+        .map(|l| HeuristicLanguage::from(l).to_string())
+        .or_else(|| language_pack::pack_for(path).map(|p| p.name().to_string()))
+}
+
+/// The uncalibrated AI score of an attribution: the share of the score
+/// distribution held by the AI families.
+pub fn ai_share(attribution: &Attribution) -> f64 {
+    1.0 - attribution.scores.get(&ModelFamily::Human).copied().unwrap_or(0.0)
+}
+
+/// Set `attribution.ai_probability` from [`FIXTURE_CALIBRATION`], and
+/// `attribution.authorship` by the default bands.  Left unset when there
+/// was no signal data to calibrate.
+pub fn calibrate(attribution: &mut Attribution) {
+    Calibration::default().calibrate(attribution, None);
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::collections::BTreeMap;
+
+    #[test]
+    fn fit_recovers_a_monotone_separating_curve() {
+        let samples = [(0.1, false), (0.2, false), (0.3, false), (0.8, true), (0.9, true), (1.0, true)];
+        let model = Platt::fit(&samples);
+        assert!(model.a > 0.0, "{model:?}");
+        assert!(model.probability(0.2) < 0.3);
+        assert!(model.probability(0.9) > 0.7);
+        // Smoothed targets keep it short of certainty.
+        assert!(model.probability(1.0) < 0.95);
+    }
+
+    #[test]
+    fn calibrate_leaves_empty_attribution_unset() {
+        let mut attribution = Attribution {
+            primary: ModelFamily::Human,
+            confidence: 0.0,
+            scores: BTreeMap::new(),
+            era: None,
+            ai_probability: None,
+            authorship: None,
+        };
+        calibrate(&mut attribution);
+        assert_eq!((attribution.ai_probability, attribution.authorship), (None, None));
+
+        attribution.confidence = 0.8;
+        attribution.scores = BTreeMap::from([(ModelFamily::Human, 0.8), (ModelFamily::Gpt, 0.2)]);
+        calibrate(&mut attribution);
+        assert!(attribution.ai_probability.unwrap() < 0.5);
+        assert_eq!(attribution.authorship, Some(Bands::default().authorship(attribution.ai_probability.unwrap())));
+    }
+
+    #[test]
+    fn bands_cut_probability_into_verdicts() {
+        let bands = Bands::default();
+        assert_eq!(bands.authorship(0.1), Authorship::HumanAuthored);
+        assert_eq!(bands.authorship(AI_ASSISTED_AT), Authorship::AiAssisted);
+        assert_eq!(bands.authorship(0.95), Authorship::AiGenerated);
+
+        let binary = Bands::new(0.5, 0.5).unwrap();
+        assert_eq!(binary.authorship(0.49), Authorship::HumanAuthored);
+        assert_eq!(binary.authorship(0.5), Authorship::AiGenerated);
+
+        assert!(Bands::new(0.8, 0.6).is_err());
+        assert!(Bands::new(0.3, 1.5).is_err());
+    }
+
+    /// Go detectors see more, so Go scores run higher than Python ones
+    /// for the same kind of file.
+    fn polyglot() -> Vec<(Option<&'static str>, f64, bool)> {
+        let mut samples = Vec::new();
+        for i in 0..6 {
+            let jitter = i as f64 * 0.02;
+            samples.push((Some("go"), 0.75 + jitter, true));
+            samples.push((Some("go"), 0.45 + jitter, false));
+            samples.push((Some("python"), 0.40 + jitter, true));
+            samples.push((Some("python"), 0.10 + jitter, false));
+        }
+        samples.push((Some("java"), 0.9, true));
+        samples
+    }
+
+    #[test]
+    fn per_language_fit_aligns_scores() {
+        let calibration = Calibration::fit(&polyglot());
+        assert_eq!(calibration.languages.keys().collect::<Vec<_>>(), vec!["go", "python"]);
+        let p = |language, x| calibration.model(Some(language)).probability(x);
+        // A 0.5 share is a human Go file but an AI-written Python one.
+        assert!(p("go", 0.5) < 0.5 && p("python", 0.5) > 0.5, "{calibration:?}");
+        assert_eq!(calibration.model(Some("java")), &calibration.fallback, "too few java samples");
+        assert_eq!(calibration.model(None), &calibration.fallback);
+    }
+
+    #[test]
+    fn fit_records_where_each_class_fell() {
+        let calibration = Calibration::fit(&polyglot());
+        let distribution = calibration.distribution.as_ref().unwrap();
+        assert_eq!(distribution.human.iter().sum::<u32>(), 12);
+        assert_eq!(distribution.ai.iter().sum::<u32>(), 13);
+        // Calibrated per language, the classes land at opposite ends.
+        let (human, ai) = distribution.at_or_above(0.5);
+        assert!(human < 0.2 && ai > 0.8, "{distribution:?}");
+        assert_eq!(distribution.at_or_above(0.0), (1.0, 1.0));
+        assert_eq!(bin(1.0), DISTRIBUTION_BINS - 1);
+        assert!(Calibration::default().distribution.is_none());
+    }
+
+    #[test]
+    fn calibration_round_trips_through_toml() {
+        let calibration = Calibration::fit(&polyglot());
+        let toml = calibration.to_toml().unwrap();
+        assert!(toml.contains("[language.go]"), "{toml}");
+        assert_eq!(toml::from_str::<Calibration>(&toml).unwrap(), calibration);
+        assert_eq!(language_key(Path::new("a/b.tsx")).as_deref(), Some("js"));
+    }
+}
@@ -0,0 +1,228 @@
+//! Optional analysis backends and how their absence degrades results.
+//!
+//! Some capabilities depend on the environment — a grammar that fails to
+//! load, an unwritable cache directory, a build without `language-packs`.
+//! Rather than silently producing different verdicts on different machines,
+//! vibecheck degrades predictably:
+//!
+//! - Signals from an unavailable backend are simply absent; attribution is
+//!   normalized over the signals that remain, so their relative weights are
+//!   unchanged.
+//! - Every report whose verdict lost a backend lists it in
+//!   [`ReportMetadata::degraded`](crate::report::ReportMetadata::degraded).
+//! - [`probe`] reports the full matrix so tools can print which
+//!   capabilities are disabled before a scan.
+
+use serde::{Deserialize, Serialize};
+
+use crate::cache::Cache;
+use crate::frontend::{LanguageFrontend, BUILTIN_FRONTENDS};
+
+/// An optional backend that contributes to analysis.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum Capability {
+    /// tree-sitter parsing for the CST analyzers.
+    CstParsing,
+    /// The content-addressed report cache.
+    Cache,
+    /// Runtime-loaded tree-sitter grammars.
+    LanguagePacks,
+    /// WebAssembly detector plugins.
+    Plugins,
+    /// The `[perplexity]` LLM endpoint.
+    Perplexity,
+    /// A `[classifier]` backend other than the built-in heuristic.
+    Classifier,
+}
+
+impl Capability {
+    /// Whether losing this capability changes verdicts, not just speed.
+    pub fn affects_verdicts(self) -> bool {
+        match self {
+            Capability::CstParsing => true,
+            Capability::Cache => false,
+            Capability::LanguagePacks => true,
+            Capability::Plugins => true,
+            Capability::Perplexity => true,
+            Capability::Classifier => true,
+        }
+    }
+}
+
+impl std::fmt::Display for Capability {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        match self {
+            Capability::CstParsing => write!(f, "cst_parsing"),
+            Capability::Cache => write!(f, "cache"),
+            Capability::LanguagePacks => write!(f, "language_packs"),
+            Capability::Plugins => write!(f, "plugins"),
+            Capability::Perplexity => write!(f, "perplexity"),
+            Capability::Classifier => write!(f, "classifier"),
+        }
+    }
+}
+
+/// Availability of one capability in the current environment.
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct CapabilityStatus {
+    pub capability: Capability,
+    pub available: bool,
+    pub affects_verdicts: bool,
+    /// Why the capability is unavailable, or what is loaded when it is.
+    pub detail: String,
+}
+
+impl CapabilityStatus {
+    fn new(capability: Capability, available: bool, detail: impl Into<String>) -> Self {
+        Self {
+            capability,
+            available,
+            affects_verdicts: capability.affects_verdicts(),
+            detail: detail.into(),
+        }
+    }
+}
+
+/// Check every optional capability in the current environment.
+pub fn probe() -> Vec<CapabilityStatus> {
+    vec![
+        probe_cst(),
+        probe_cache(),
+        probe_language_packs(),
+        probe_plugins(),
+        probe_perplexity(),
+        probe_classifier(),
+    ]
+}
+
+/// The probed capabilities that are unavailable and would change verdicts.
+pub fn disabled_affecting_verdicts(statuses: &[CapabilityStatus]) -> Vec<&CapabilityStatus> {
+    statuses
+        .iter()
+        .filter(|s| !s.available && s.affects_verdicts)
+        .collect()
+}
+
+fn probe_cst() -> CapabilityStatus {
+    let failed: Vec<&str> = BUILTIN_FRONTENDS
+        .iter()
+        .filter(|f| f.tokenize("").is_none())
+        .map(|f| f.name())
+        .collect();
+    if failed.is_empty() {
+        CapabilityStatus::new(Capability::CstParsing, true, "all built-in grammars load")
+    } else {
+        CapabilityStatus::new(
+            Capability::CstParsing,
+            false,
+            format!("grammar failed to load: {}", failed.join(", ")),
+        )
+    }
+}
+
+fn probe_cache() -> CapabilityStatus {
+    let path = Cache::resolve_path(None);
+    match Cache::shared(&path) {
+        Ok(_) => CapabilityStatus::new(Capability::Cache, true, path.display().to_string()),
+        Err(e) => CapabilityStatus::new(Capability::Cache, false, format!("{}: {e}", path.display())),
+    }
+}
+
+fn probe_language_packs() -> CapabilityStatus {
+    if !cfg!(feature = "language-packs") {
+        return CapabilityStatus::new(
+            Capability::LanguagePacks,
+            false,
+            "built without the `language-packs` feature",
+        );
+    }
+    let names: Vec<&str> = crate::language_pack::installed().iter().map(|p| p.name()).collect();
+    let detail = if names.is_empty() {
+        "0 installed".to_string()
+    } else {
+        format!("{} installed: {}", names.len(), names.join(", "))
+    };
+    CapabilityStatus::new(Capability::LanguagePacks, true, detail)
+}
+
+fn probe_plugins() -> CapabilityStatus {
+    if !cfg!(feature = "wasm-plugins") {
+        return CapabilityStatus::new(Capability::Plugins, false, "built without the `wasm-plugins` feature");
+    }
+    let names: Vec<&str> = crate::plugin::installed().iter().map(|p| p.name()).collect();
+    let detail = if names.is_empty() {
+        "0 installed".to_string()
+    } else {
+        format!("{} installed: {}", names.len(), names.join(", "))
+    };
+    CapabilityStatus::new(Capability::Plugins, true, detail)
+}
+
+/// Whether this build can query a `[perplexity]` endpoint.  The endpoint
+/// itself is configured per project and checked on first use: a file it
+/// fails for lists [`Capability::Perplexity`] as degraded.
+fn probe_perplexity() -> CapabilityStatus {
+    if !cfg!(feature = "perplexity") {
+        return CapabilityStatus::new(Capability::Perplexity, false, "built without the `perplexity` feature");
+    }
+    CapabilityStatus::new(Capability::Perplexity, true, "HTTP client built in; enable per project in [perplexity]")
+}
+
+/// Which `[classifier]` backends this build can load.  The model itself is
+/// configured per project: a config whose backend fails to load falls back
+/// to the heuristic and lists [`Capability::Classifier`] as degraded.
+fn probe_classifier() -> CapabilityStatus {
+    if !cfg!(feature = "onnx") {
+        return CapabilityStatus::new(
+            Capability::Classifier,
+            true,
+            "heuristic, logistic; built without the `onnx` feature for embedding models",
+        );
+    }
+    CapabilityStatus::new(Capability::Classifier, true, "heuristic, logistic, onnx")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn probe_covers_every_capability() {
+        let statuses = probe();
+        for cap in [
+            Capability::CstParsing,
+            Capability::Cache,
+            Capability::LanguagePacks,
+            Capability::Plugins,
+            Capability::Perplexity,
+            Capability::Classifier,
+        ] {
+            assert!(statuses.iter().any(|s| s.capability == cap), "{cap} not probed");
+        }
+    }
+
+    #[test]
+    fn cache_never_affects_verdicts() {
+        assert!(!Capability::Cache.affects_verdicts());
+        assert!(Capability::CstParsing.affects_verdicts());
+    }
+
+    #[test]
+    fn disabled_affecting_verdicts_filters() {
+        let statuses = vec![
+            CapabilityStatus::new(Capability::Cache, false, "read-only"),
+            CapabilityStatus::new(Capability::CstParsing, false, "broken"),
+            CapabilityStatus::new(Capability::LanguagePacks, true, "0 installed"),
+        ];
+        let disabled = disabled_affecting_verdicts(&statuses);
+        assert_eq!(disabled.len(), 1);
+        assert_eq!(disabled[0].capability, Capability::CstParsing);
+    }
+
+    #[test]
+    fn capability_serializes_snake_case() {
+        assert_eq!(serde_json::to_string(&Capability::CstParsing).unwrap(), "\"cst_parsing\"");
+        assert_eq!(Capability::LanguagePacks.to_string(), "language_packs");
+    }
+}
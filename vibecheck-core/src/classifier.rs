@@ -0,0 +1,314 @@
+//! Scoring backends.
+//!
+//! Detectors produce weighted [`Signal`]s; a [`Classifier`] turns them into
+//! an [`Attribution`].  The default [`HeuristicClassifier`] is the weighted
+//! sum vibecheck has always used.  [`LogisticClassifier`] is a multinomial
+//! logistic regression trained on a labelled corpus (`vibecheck tune
+//! --classifier logistic`), and [`EmbeddingClassifier`] compares a code
+//! embedding with per-family prototypes (see [`crate::embedding`]).  The
+//! backend is chosen in `.vibecheck`:
+//!
+//! ```toml
+//! [classifier]
+//! backend = "logistic"               # heuristic | logistic | onnx
+//! model = "vibecheck-logistic.toml"  # relative to the config root; a directory for onnx
+//! ```
+//!
+//! Detectors and reporters never see the backend; only the scores differ.
+//! Not to be confused with `vibecheck_ml::Classifier`, which maps a feature
+//! vector to a distribution inside the ML ensemble.
+
+use std::collections::{BTreeMap, HashMap};
+use std::path::Path;
+
+use anyhow::bail;
+use serde::{Deserialize, Serialize};
+
+use crate::embedding::{EmbeddingClassifier, MODEL_FILE, PROTOTYPES_FILE, TOKENIZER_FILE};
+use crate::language::Language;
+use crate::report::{Attribution, ModelFamily, Signal};
+
+/// A scoring backend: weighted signals in, attribution out.
+pub trait Classifier: Send + Sync {
+    /// The backend name used in `[classifier] backend`.
+    fn name(&self) -> &str;
+
+    /// Attribute a file from its weighted `signals` and raw CST `metrics`.
+    /// `ai_probability` is left unset; the pipeline calibrates afterwards.
+    fn classify(
+        &self,
+        signals: &[Signal],
+        metrics: &HashMap<String, f64>,
+        language: Option<Language>,
+        source: &str,
+    ) -> Attribution;
+}
+
+/// Backend names accepted by `[classifier] backend`.
+pub const BACKENDS: &[&str] = &["heuristic", "logistic", "onnx"];
+
+/// The backend named `backend`, loading its model from `model` when it
+/// needs one.
+pub fn from_name(backend: &str, model: Option<&Path>) -> anyhow::Result<Box<dyn Classifier>> {
+    match backend {
+        "heuristic" => Ok(Box::new(HeuristicClassifier)),
+        "logistic" => match model {
+            Some(path) => Ok(Box::new(LogisticClassifier::from_file(path)?)),
+            None => bail!("the logistic classifier needs `[classifier] model`; train one with `vibecheck tune --classifier logistic`"),
+        },
+        "onnx" => match model {
+            Some(dir) => Ok(Box::new(EmbeddingClassifier::load(dir)?)),
+            None => bail!("the onnx classifier needs `[classifier] model`: a directory with {MODEL_FILE}, {TOKENIZER_FILE} and {PROTOTYPES_FILE}"),
+        },
+        other => bail!("unknown classifier backend: {other} (expected {})", BACKENDS.join(", ")),
+    }
+}
+
+// ---------------------------------------------------------------------------
+// Heuristic: weighted sum
+// ---------------------------------------------------------------------------
+
+/// Sums signal weights per family and normalizes the sums into a
+/// distribution.  Negative weights count against every AI family.
+pub struct HeuristicClassifier;
+
+impl HeuristicClassifier {
+    /// The weighted-sum attribution of `signals`.
+    pub fn score(signals: &[Signal]) -> Attribution {
+        let mut raw_scores: BTreeMap<ModelFamily, f64> = BTreeMap::new();
+        for family in ModelFamily::all() {
+            raw_scores.insert(*family, 0.0);
+        }
+
+        for signal in signals {
+            if signal.weight < 0.0 {
+                // Counter-evidence (the `humanity` signals): weighs against
+                // every AI family rather than against its own.
+                for family in ModelFamily::all().iter().filter(|f| **f != ModelFamily::Human) {
+                    *raw_scores.entry(*family).or_insert(0.0) += signal.weight;
+                }
+            } else {
+                *raw_scores.entry(signal.family).or_insert(0.0) += signal.weight;
+            }
+        }
+
+        // Shift all scores so the minimum is 0
+        let min_score = raw_scores.values().cloned().fold(f64::INFINITY, f64::min);
+        let mut shifted: BTreeMap<ModelFamily, f64> = raw_scores
+            .iter()
+            .map(|(&k, &v)| (k, (v - min_score).max(0.0)))
+            .collect();
+
+        // Normalize to a distribution summing to 1.0
+        let total: f64 = shifted.values().sum();
+        if total > 0.0 {
+            for v in shifted.values_mut() {
+                *v /= total;
+            }
+        } else {
+            // No signal data — leave all scores at 0.0, confidence 0.0
+            return Attribution {
+                primary: ModelFamily::Human,
+                confidence: 0.0,
+                scores: shifted,
+                era: None,
+                ai_probability: None,
+                authorship: None,
+            };
+        }
+
+        distribution(shifted)
+    }
+}
+
+impl Classifier for HeuristicClassifier {
+    fn name(&self) -> &str {
+        "heuristic"
+    }
+
+    fn classify(&self, signals: &[Signal], _: &HashMap<String, f64>, _: Option<Language>, _: &str) -> Attribution {
+        Self::score(signals)
+    }
+}
+
+/// An attribution whose primary family is the argmax of `scores`, ties going
+/// to the family whose name sorts last.
+pub(crate) fn distribution(scores: BTreeMap<ModelFamily, f64>) -> Attribution {
+    let (primary, confidence) = scores
+        .iter()
+        .max_by(|a, b| a.1.partial_cmp(b.1).unwrap().then_with(|| a.0.to_string().cmp(&b.0.to_string())))
+        .map(|(&k, &v)| (k, v))
+        .unwrap();
+    Attribution { primary, confidence, scores, era: None, ai_probability: None, authorship: None }
+}
+
+// ---------------------------------------------------------------------------
+// Logistic regression
+// ---------------------------------------------------------------------------
+
+/// Gradient-descent step and iteration count for
+/// [`LogisticClassifier::fit`], chosen on the fixture corpus.
+const LOGISTIC_LEARNING_RATE: f64 = 0.5;
+const LOGISTIC_ITERATIONS: usize = 300;
+
+/// Multinomial logistic regression over signal occurrences.
+///
+/// Features are signal IDs without their language prefix
+/// (`comments.minimal` for `rust.comments.minimal`), so evidence learned in
+/// one language carries to the others; each occurrence adds the feature's
+/// per-family coefficients to the logits.  Signal weights are ignored: the
+/// coefficients replace them.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct LogisticClassifier {
+    #[serde(default)]
+    pub intercepts: BTreeMap<ModelFamily, f64>,
+    /// Feature → family → coefficient.  Missing entries are zero.
+    #[serde(default)]
+    pub coefficients: BTreeMap<String, BTreeMap<ModelFamily, f64>>,
+}
+
+impl LogisticClassifier {
+    /// Load a model written by [`to_toml`](Self::to_toml).
+    pub fn from_file(path: &Path) -> anyhow::Result<Self> {
+        let s = std::fs::read_to_string(path)
+            .map_err(|e| anyhow::anyhow!("failed to read {}: {e}", path.display()))?;
+        toml::from_str(&s).map_err(|e| anyhow::anyhow!("failed to parse {}: {e}", path.display()))
+    }
+
+    pub fn to_toml(&self) -> anyhow::Result<String> {
+        Ok(toml::to_string(self)?)
+    }
+
+    /// Fit to `(signals, label)` samples by gradient descent on the mean
+    /// cross-entropy plus `l2` times the squared coefficients.
+    pub fn fit(samples: &[(&[Signal], ModelFamily)], l2: f64) -> Self {
+        let families = ModelFamily::all();
+        let rows: Vec<(Vec<&str>, usize)> = samples
+            .iter()
+            .map(|(signals, label)| {
+                let features = signals.iter().filter_map(|s| feature(&s.id)).collect();
+                (features, families.iter().position(|f| f == label).unwrap())
+            })
+            .collect();
+        let mut intercepts = vec![0.0; families.len()];
+        let mut coefficients: BTreeMap<&str, Vec<f64>> = rows
+            .iter()
+            .flat_map(|(features, _)| features.iter().map(|&f| (f, vec![0.0; families.len()])))
+            .collect();
+        if rows.is_empty() {
+            return Self::default();
+        }
+
+        let n = rows.len() as f64;
+        for _ in 0..LOGISTIC_ITERATIONS {
+            let mut grad_b = vec![0.0; families.len()];
+            let mut grad_w: BTreeMap<&str, Vec<f64>> =
+                coefficients.iter().map(|(&f, w)| (f, w.iter().map(|w| 2.0 * l2 * w).collect())).collect();
+            for (features, label) in &rows {
+                let mut logits = intercepts.clone();
+                for f in features {
+                    for (z, w) in logits.iter_mut().zip(&coefficients[f]) {
+                        *z += w;
+                    }
+                }
+                for (k, p) in softmax(&logits).into_iter().enumerate() {
+                    let d = (p - if k == *label { 1.0 } else { 0.0 }) / n;
+                    grad_b[k] += d;
+                    for f in features {
+                        grad_w.get_mut(f).unwrap()[k] += d;
+                    }
+                }
+            }
+            for (b, g) in intercepts.iter_mut().zip(grad_b) {
+                *b -= LOGISTIC_LEARNING_RATE * g;
+            }
+            for (f, g) in grad_w {
+                for (w, g) in coefficients.get_mut(f).unwrap().iter_mut().zip(g) {
+                    *w -= LOGISTIC_LEARNING_RATE * g;
+                }
+            }
+        }
+
+        let by_family = |values: &[f64]| families.iter().copied().zip(values.iter().copied()).collect();
+        Self {
+            intercepts: by_family(&intercepts),
+            coefficients: coefficients.iter().map(|(f, w)| (f.to_string(), by_family(w))).collect(),
+        }
+    }
+
+    /// Class probabilities for `signals`.
+    pub fn probabilities(&self, signals: &[Signal]) -> BTreeMap<ModelFamily, f64> {
+        let families = ModelFamily::all();
+        let mut logits: Vec<f64> = families.iter().map(|f| self.intercepts.get(f).copied().unwrap_or(0.0)).collect();
+        for coefficients in signals.iter().filter_map(|s| self.coefficients.get(feature(&s.id)?)) {
+            for (z, f) in logits.iter_mut().zip(families) {
+                *z += coefficients.get(f).copied().unwrap_or(0.0);
+            }
+        }
+        families.iter().copied().zip(softmax(&logits)).collect()
+    }
+}
+
+impl Classifier for LogisticClassifier {
+    fn name(&self) -> &str {
+        "logistic"
+    }
+
+    fn classify(&self, signals: &[Signal], _: &HashMap<String, f64>, _: Option<Language>, _: &str) -> Attribution {
+        distribution(self.probabilities(signals))
+    }
+}
+
+/// `comments.minimal` for `rust.comments.minimal`; `None` for signals
+/// without an ID.
+fn feature(id: &str) -> Option<&str> {
+    id.split_once('.').map(|(_, rest)| rest)
+}
+
+fn softmax(x: &[f64]) -> Vec<f64> {
+    let max = x.iter().cloned().fold(f64::NEG_INFINITY, f64::max);
+    let exp: Vec<f64> = x.iter().map(|v| (v - max).exp()).collect();
+    let total: f64 = exp.iter().sum();
+    exp.into_iter().map(|e| e / total).collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn signal(id: &str, family: ModelFamily) -> Signal {
+        Signal::new(id, "t", "", family, 1.0)
+    }
+
+    #[test]
+    fn logistic_fit_transfers_across_languages_and_round_trips() {
+        let docs = |lang: &str| signal(&format!("{lang}.comments.doc"), ModelFamily::Claude);
+        let todo = |lang: &str| signal(&format!("{lang}.ai_signals.todo"), ModelFamily::Human);
+        let claude = [docs("rust")];
+        let human = [todo("rust")];
+        let samples: Vec<(&[Signal], ModelFamily)> = vec![(&claude, ModelFamily::Claude), (&human, ModelFamily::Human)];
+        let model = LogisticClassifier::fit(&samples, crate::tuning::DEFAULT_L2);
+
+        // Learned on Rust, applied to Go.
+        let go = model.classify(&[docs("go")], &HashMap::new(), None, "");
+        assert_eq!(go.primary, ModelFamily::Claude);
+        assert!((go.scores.values().sum::<f64>() - 1.0).abs() < 1e-9);
+
+        let reloaded: LogisticClassifier = toml::from_str(&model.to_toml().unwrap()).unwrap();
+        assert_eq!(reloaded.classify(&[todo("js")], &HashMap::new(), None, "").primary, ModelFamily::Human);
+    }
+
+    #[test]
+    fn from_name_selects_backends() {
+        assert_eq!(from_name("heuristic", None).unwrap().name(), "heuristic");
+        assert!(from_name("logistic", None).is_err());
+        assert!(from_name("onnx", None).is_err());
+        assert!(from_name("svm", None).is_err());
+
+        let dir = tempfile::tempdir().unwrap();
+        let path = dir.path().join("model.toml");
+        std::fs::write(&path, "[intercepts]\nhuman = 1.0\n").unwrap();
+        let logistic = from_name("logistic", Some(&path)).unwrap();
+        assert_eq!(logistic.classify(&[], &HashMap::new(), None, "").primary, ModelFamily::Human);
+    }
+}
@@ -0,0 +1,295 @@
+//! Commit-message analysis.
+//!
+//! AI-written commit messages have tells of their own that code detectors
+//! never see: a "This commit:" preamble over a bulleted list, an emoji
+//! prefix, one bullet per touched file, a closing "Overall, these changes
+//! ..." paragraph.  Messages are scored with their own detector set — the
+//! `commit.*` rules in `heuristics.toml` — and never mixed into a file's
+//! report.
+//!
+//! Line numbers in the signals are 1-based lines of the message, the
+//! subject being line 1.
+
+use serde::Serialize;
+
+use crate::classifier::HeuristicClassifier;
+use crate::heuristics::{signal_ids, HeuristicsProvider};
+use crate::report::{Attribution, ModelFamily, Signal};
+
+const SOURCE: &str = "commit_message";
+
+/// Openers that introduce the change in the third person.
+const NARRATION_OPENERS: &[&str] = &["this commit", "this pr", "this pull request", "this change", "this patch"];
+
+/// Openers of a closing paragraph that sums the change up.
+const SUMMARY_OPENERS: &[&str] = &[
+    "overall", "in summary", "in short", "these changes", "this ensures", "together, these", "with these changes",
+];
+
+/// Verbs that open a bullet narrating one change.
+const CHANGE_VERBS: &[&str] = &[
+    "add", "added", "adds", "update", "updated", "updates", "refactor", "refactored", "refactors", "remove",
+    "removed", "removes", "improve", "improved", "improves", "introduce", "introduced", "introduces", "enhance",
+    "enhanced", "enhances", "implement", "implemented", "implements", "ensure", "ensured", "ensures",
+];
+
+/// Subject prefixes of work-in-progress commits.
+const WIP_PREFIXES: &[&str] = &["wip", "fixup!", "squash!", "amend!", "oops", "tmp", "typo"];
+
+/// Body bullets before `commit.structure.bullets` fires.
+const MIN_BULLETS: usize = 4;
+
+/// Change-verb bullets before `commit.narration.exhaustive` fires.
+const MIN_CHANGE_BULLETS: usize = 5;
+
+/// Longest subject, in words, that counts as terse.
+const TERSE_WORDS: usize = 3;
+
+/// The verdict on one commit message.
+#[derive(Debug, Clone, Serialize)]
+pub struct CommitReport {
+    pub attribution: Attribution,
+    pub signals: Vec<Signal>,
+}
+
+/// Run every commit-message detector on `message`.  Signals carry their
+/// default weights.
+pub fn detect(message: &str) -> Vec<Signal> {
+    let lines: Vec<&str> = message.lines().collect();
+    let Some(subject) = lines.first().map(|l| l.trim()) else {
+        return Vec::new();
+    };
+    // 1-based line numbers and text of the body, which starts after the
+    // first blank line.
+    let body: Vec<(usize, &str)> = match lines.iter().position(|l| l.trim().is_empty()) {
+        Some(blank) => lines.iter().enumerate().skip(blank + 1).map(|(i, l)| (i + 1, l.trim())).collect(),
+        None => Vec::new(),
+    };
+    let bullets: Vec<(usize, &str)> = body.iter().filter_map(|&(n, l)| bullet_text(l).map(|t| (n, t))).collect();
+
+    let mut signals = Vec::new();
+
+    let narrated: Vec<usize> = std::iter::once((1, subject))
+        .chain(body.iter().copied())
+        .filter(|(_, l)| starts_with_any(l, NARRATION_OPENERS))
+        .map(|(n, _)| n)
+        .collect();
+    if !narrated.is_empty() {
+        signals.push(
+            Signal::new(
+                signal_ids::COMMIT_NARRATION_THIS_COMMIT,
+                SOURCE,
+                "Introduces the change in the third person (\"This commit ...\")",
+                ModelFamily::Gpt,
+                1.5,
+            )
+            .with_lines(narrated),
+        );
+    }
+
+    if bullets.len() >= MIN_BULLETS {
+        signals.push(
+            Signal::new(
+                signal_ids::COMMIT_STRUCTURE_BULLETS,
+                SOURCE,
+                format!("Body is a list of {} bullets", bullets.len()),
+                ModelFamily::Claude,
+                1.0,
+            )
+            .with_lines(bullets.iter().map(|(n, _)| *n).collect()),
+        );
+    }
+
+    let changes: Vec<usize> = bullets
+        .iter()
+        .filter(|(_, t)| first_word(t).is_some_and(|w| CHANGE_VERBS.contains(&w.as_str())))
+        .map(|(n, _)| *n)
+        .collect();
+    if changes.len() >= MIN_CHANGE_BULLETS {
+        signals.push(
+            Signal::new(
+                signal_ids::COMMIT_NARRATION_EXHAUSTIVE,
+                SOURCE,
+                format!("{} bullets narrate one change each (\"Added ...\", \"Updated ...\")", changes.len()),
+                ModelFamily::Claude,
+                1.5,
+            )
+            .with_lines(changes),
+        );
+    }
+
+    // The first line of the last paragraph.
+    let closer = body
+        .iter()
+        .rev()
+        .skip_while(|(_, l)| l.is_empty())
+        .take_while(|(_, l)| !l.is_empty())
+        .last()
+        .filter(|(_, l)| bullet_text(l).is_none() && starts_with_any(l, SUMMARY_OPENERS));
+    if let Some(&(n, _)) = closer {
+        signals.push(
+            Signal::new(
+                signal_ids::COMMIT_NARRATION_SUMMARY_CLOSER,
+                SOURCE,
+                "Closes with a summary paragraph (\"Overall, these changes ...\")",
+                ModelFamily::Claude,
+                1.5,
+            )
+            .with_lines(vec![n]),
+        );
+    }
+
+    if subject.chars().next().is_some_and(is_emoji) || is_shortcode(subject) {
+        signals.push(
+            Signal::new(signal_ids::COMMIT_STYLE_EMOJI_PREFIX, SOURCE, "Subject starts with an emoji", ModelFamily::Gemini, 1.0)
+                .with_lines(vec![1]),
+        );
+    }
+
+    if first_word(subject).is_some_and(|w| WIP_PREFIXES.contains(&w.as_str())) {
+        signals.push(
+            Signal::new(
+                signal_ids::COMMIT_STYLE_WIP,
+                SOURCE,
+                "Work-in-progress subject (wip, fixup!, typo)",
+                ModelFamily::Human,
+                2.0,
+            )
+            .with_lines(vec![1]),
+        );
+    } else if body.iter().all(|(_, l)| l.is_empty()) && subject.split_whitespace().count() <= TERSE_WORDS {
+        signals.push(
+            Signal::new(signal_ids::COMMIT_STYLE_TERSE, SOURCE, "Terse subject and no body", ModelFamily::Human, 1.5)
+                .with_lines(vec![1]),
+        );
+    }
+
+    signals
+}
+
+/// Score `message` under `heuristics`: weights are overridden and disabled
+/// rules dropped as in the file pipeline, then the signals are summed per
+/// family.
+pub fn score(message: &str, heuristics: &dyn HeuristicsProvider) -> CommitReport {
+    let mut signals = detect(message);
+    for s in &mut signals {
+        s.weight = heuristics.weight(&s.id);
+    }
+    signals.retain(|s| heuristics.is_enabled(&s.id));
+    for s in &mut signals {
+        s.severity = heuristics.severity(s);
+    }
+    CommitReport { attribution: HeuristicClassifier::score(&signals), signals }
+}
+
+/// The text of a `-`, `*` or `•` bullet.
+fn bullet_text(line: &str) -> Option<&str> {
+    ["- ", "* ", "• "].iter().find_map(|m| line.strip_prefix(m)).map(str::trim_start)
+}
+
+/// The first word of `text`, lowercased, with trailing `:` or `,` removed.
+fn first_word(text: &str) -> Option<String> {
+    let word = text.split_whitespace().next()?;
+    Some(word.trim_end_matches([':', ',']).to_lowercase())
+}
+
+fn starts_with_any(line: &str, openers: &[&str]) -> bool {
+    let line = line.to_lowercase();
+    openers.iter().any(|o| {
+        line.strip_prefix(o).is_some_and(|rest| !rest.starts_with(|c: char| c.is_alphanumeric()))
+    })
+}
+
+/// Pictographs and dingbats; plain punctuation and letters are not emoji.
+pub(crate) fn is_emoji(c: char) -> bool {
+    matches!(c as u32, 0x1F300..=0x1FAFF | 0x2600..=0x27BF | 0x2B50 | 0x2B06 | 0x2705 | 0x274C)
+}
+
+/// A gitmoji shortcode such as `:sparkles:` opening the subject.
+fn is_shortcode(subject: &str) -> bool {
+    subject
+        .strip_prefix(':')
+        .and_then(|rest| rest.split_once(':'))
+        .is_some_and(|(name, _)| !name.is_empty() && name.chars().all(|c| c.is_ascii_lowercase() || c == '_' || c == '+'))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::heuristics::{ConfiguredHeuristics, DefaultHeuristics};
+
+    fn ids(message: &str) -> Vec<String> {
+        detect(message).into_iter().map(|s| s.id).collect()
+    }
+
+    const AI_MESSAGE: &str = "✨ Add retry support to the HTTP client\n\n\
+        This commit introduces configurable retries:\n\n\
+        - Added a RetryPolicy struct with exponential backoff\n\
+        - Updated the client builder to accept a policy\n\
+        - Refactored request dispatch into a helper\n\
+        - Added unit tests for the backoff schedule\n\
+        - Updated the README with usage examples\n\n\
+        Overall, these changes make the client more resilient to transient failures.\n";
+
+    #[test]
+    fn ai_message_trips_the_narration_detectors() {
+        let found = ids(AI_MESSAGE);
+        for id in [
+            signal_ids::COMMIT_NARRATION_THIS_COMMIT,
+            signal_ids::COMMIT_STRUCTURE_BULLETS,
+            signal_ids::COMMIT_NARRATION_EXHAUSTIVE,
+            signal_ids::COMMIT_NARRATION_SUMMARY_CLOSER,
+            signal_ids::COMMIT_STYLE_EMOJI_PREFIX,
+        ] {
+            assert!(found.iter().any(|f| f == id), "{id} did not fire: {found:?}");
+        }
+        let report = score(AI_MESSAGE, &DefaultHeuristics);
+        assert_ne!(report.attribution.primary, ModelFamily::Human);
+    }
+
+    #[test]
+    fn signals_point_at_message_lines() {
+        let signals = detect(AI_MESSAGE);
+        let lines = |id: &str| signals.iter().find(|s| s.id == id).unwrap().lines.clone();
+        assert_eq!(lines(signal_ids::COMMIT_NARRATION_THIS_COMMIT), vec![3]);
+        assert_eq!(lines(signal_ids::COMMIT_NARRATION_SUMMARY_CLOSER), vec![11]);
+        assert_eq!(lines(signal_ids::COMMIT_STYLE_EMOJI_PREFIX), vec![1]);
+    }
+
+    #[test]
+    fn human_messages_lean_human() {
+        assert_eq!(ids("fix flaky test"), vec![signal_ids::COMMIT_STYLE_TERSE]);
+        assert_eq!(ids("fixup! handle empty config\n"), vec![signal_ids::COMMIT_STYLE_WIP]);
+        let report = score("wip", &DefaultHeuristics);
+        assert_eq!(report.attribution.primary, ModelFamily::Human);
+    }
+
+    #[test]
+    fn ordinary_messages_are_quiet() {
+        let message = "Handle missing HOME when resolving the cache dir\n\n\
+            dirs::cache_dir() returns None in some containers; fall back to\n\
+            ./.cache instead of panicking.  Reported in #212.\n";
+        assert!(ids(message).is_empty(), "{:?}", ids(message));
+        assert!(!score(message, &DefaultHeuristics).attribution.has_sufficient_data());
+        assert!(ids("").is_empty());
+    }
+
+    #[test]
+    fn openers_match_whole_words() {
+        assert!(starts_with_any("This PR adds", NARRATION_OPENERS));
+        assert!(!starts_with_any("This principle is", NARRATION_OPENERS));
+        assert!(is_shortcode(":sparkles: add retries"));
+        assert!(!is_shortcode("fix: handle :memory: paths"));
+    }
+
+    #[test]
+    fn weights_come_from_heuristics() {
+        let mut overrides = std::collections::HashMap::new();
+        overrides.insert(signal_ids::COMMIT_STYLE_EMOJI_PREFIX.to_string(), 0.0);
+        overrides.insert(signal_ids::COMMIT_STRUCTURE_BULLETS.to_string(), 3.0);
+        let report = score(AI_MESSAGE, &ConfiguredHeuristics::from_config(overrides));
+        assert!(report.signals.iter().all(|s| s.id != signal_ids::COMMIT_STYLE_EMOJI_PREFIX));
+        let bullets = report.signals.iter().find(|s| s.id == signal_ids::COMMIT_STRUCTURE_BULLETS).unwrap();
+        assert_eq!(bullets.weight, 3.0);
+    }
+}
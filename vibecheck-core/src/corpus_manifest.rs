@@ -0,0 +1,381 @@
+//! The labelled-corpus manifest.
+//!
+//! A corpus directory keeps a `corpus.toml` at its root with one `[[sample]]`
+//! per file: its path relative to the root, its label, and the provenance
+//! needed to grow the corpus past a handful of hand-dropped fixtures — the
+//! model that wrote it, the task it solves, its language and its license.
+//!
+//! ```toml
+//! [[sample]]
+//! path = "lru_cache/claude.rs"
+//! label = "claude"
+//! authorship = "ai-assisted"
+//! model = "claude-3-5-sonnet"
+//! task = "lru_cache"
+//! language = "rust"
+//! license = "MIT"
+//! hash = "9f2c…"
+//! ```
+//!
+//! `authorship` is for model-written samples a person then edited; unset,
+//! a sample is AI-generated, or human-authored when labelled `human`.
+//!
+//! `hash` is the SHA-256 of the normalized content (see [`content_hash`]), so
+//! copies that differ only in line endings or trailing whitespace are found
+//! by [`Manifest::dedupe`].
+
+use std::path::{Path, PathBuf};
+
+use anyhow::{bail, Context};
+use serde::{Deserialize, Serialize};
+use sha2::{Digest, Sha256};
+
+use crate::calibration::language_key;
+use crate::report::{Authorship, ModelFamily};
+
+/// File name of the manifest at the corpus root.
+pub const MANIFEST_FILE: &str = "corpus.toml";
+
+/// Task directory for samples added without `task`.
+const DEFAULT_TASK: &str = "misc";
+
+/// One labelled file.
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct CorpusEntry {
+    /// Relative to the corpus root, `/`-separated.
+    pub path: String,
+    pub label: ModelFamily,
+    /// How much of the sample the model wrote; unset means
+    /// [`Authorship::of_label`].
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub authorship: Option<Authorship>,
+    /// The model that wrote the sample (e.g. `gpt-4o-2024-08-06`); unset for
+    /// human samples and unknown provenance.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub model: Option<String>,
+    /// What the sample implements; samples of one task are comparable.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub task: Option<String>,
+    /// Signal-ID language prefix — `rust`, `python`, `js` or `go` — or the
+    /// name of the language pack handling the file, e.g. `java`.
+    pub language: String,
+    /// SPDX identifier of the sample's license.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub license: Option<String>,
+    pub hash: String,
+}
+
+impl CorpusEntry {
+    /// The sample's authorship, defaulted from its label when unset.
+    pub fn authorship(&self) -> Authorship {
+        self.authorship.unwrap_or(Authorship::of_label(self.label))
+    }
+}
+
+/// Provenance for [`Manifest::add`].
+#[derive(Debug, Clone, Default)]
+pub struct Provenance {
+    /// Set for AI-assisted samples; see [`CorpusEntry::authorship`].
+    pub authorship: Option<Authorship>,
+    pub model: Option<String>,
+    pub task: Option<String>,
+    pub license: Option<String>,
+}
+
+/// Result of [`Manifest::dedupe`].
+#[derive(Debug, Default)]
+pub struct Dedupe {
+    /// Entries dropped because an earlier entry has the same content and label.
+    pub removed: Vec<CorpusEntry>,
+    /// Groups of same-content entries whose labels disagree.  They are kept:
+    /// one of them is mislabelled and needs a `relabel`, not a deletion.
+    pub conflicts: Vec<Vec<CorpusEntry>>,
+}
+
+#[derive(Default, Serialize, Deserialize)]
+struct ManifestFile {
+    #[serde(default, rename = "sample")]
+    samples: Vec<CorpusEntry>,
+}
+
+/// A corpus directory and its manifest.
+pub struct Manifest {
+    root: PathBuf,
+    pub samples: Vec<CorpusEntry>,
+}
+
+impl Manifest {
+    /// Load the manifest of the corpus at `root`; empty when there is none yet.
+    pub fn load(root: &Path) -> anyhow::Result<Self> {
+        let path = root.join(MANIFEST_FILE);
+        let file: ManifestFile = if path.exists() {
+            let text = std::fs::read_to_string(&path)?;
+            toml::from_str(&text).map_err(|e| anyhow::anyhow!("failed to parse {}: {e}", path.display()))?
+        } else {
+            ManifestFile::default()
+        };
+        Ok(Self { root: root.to_path_buf(), samples: file.samples })
+    }
+
+    /// Write the manifest back, samples sorted by path.
+    pub fn save(&mut self) -> anyhow::Result<()> {
+        self.samples.sort_by(|a, b| a.path.cmp(&b.path));
+        let file = ManifestFile { samples: self.samples.clone() };
+        let text = toml::to_string_pretty(&file)?;
+        let path = self.root.join(MANIFEST_FILE);
+        std::fs::write(&path, text).with_context(|| format!("failed to write {}", path.display()))
+    }
+
+    pub fn root(&self) -> &Path {
+        &self.root
+    }
+
+    /// The entry for `path`, relative to the corpus root.
+    pub fn get(&self, path: &Path) -> Option<&CorpusEntry> {
+        let key = manifest_path(path);
+        self.samples.iter().find(|s| s.path == key)
+    }
+
+    /// Register `file` under `label`.  A file already inside the corpus is
+    /// registered in place; any other is copied to `<task>/<label>.<ext>`
+    /// (`<label>_2.<ext>`, … when taken).  Fails for unsupported languages,
+    /// already-registered paths, content the corpus already has, and human
+    /// samples marked as AI-written.
+    pub fn add(&mut self, file: &Path, label: ModelFamily, provenance: Provenance) -> anyhow::Result<&CorpusEntry> {
+        if let Some(a) = provenance.authorship.filter(|&a| label == ModelFamily::Human && a != Authorship::HumanAuthored) {
+            bail!("a human sample cannot be {}; label it with the family of the model that helped", a.as_str());
+        }
+        let language = language_of(file)?;
+        let content = std::fs::read(file).with_context(|| format!("failed to read {}", file.display()))?;
+        let hash = content_hash(&content);
+        self.check_new(&hash, &file.display().to_string())?;
+
+        let inside = file
+            .canonicalize()
+            .ok()
+            .zip(self.root.canonicalize().ok())
+            .and_then(|(f, r)| f.strip_prefix(&r).ok().map(Path::to_path_buf));
+        let relative = match inside {
+            Some(rel) => {
+                if self.get(&rel).is_some() {
+                    bail!("{} is already in the corpus", rel.display());
+                }
+                rel
+            }
+            None => self.write_new(&content, file, label, provenance.task.as_deref())?,
+        };
+        Ok(self.push(relative, label, provenance, language, hash))
+    }
+
+    /// Store `source` as a new `<task>/<label>.<extension>` sample, like
+    /// [`add`](Self::add) does for files from outside the corpus.
+    pub fn add_source(
+        &mut self,
+        source: &str,
+        extension: &str,
+        label: ModelFamily,
+        provenance: Provenance,
+    ) -> anyhow::Result<&CorpusEntry> {
+        let name = PathBuf::from(format!("sample.{extension}"));
+        let language = language_of(&name)?;
+        let hash = content_hash(source.as_bytes());
+        self.check_new(&hash, "generated sample")?;
+        let relative = self.write_new(source.as_bytes(), &name, label, provenance.task.as_deref())?;
+        Ok(self.push(relative, label, provenance, language, hash))
+    }
+
+    fn check_new(&self, hash: &str, what: &str) -> anyhow::Result<()> {
+        match self.samples.iter().find(|s| s.hash == hash) {
+            Some(existing) => bail!("{what} duplicates {} (labelled {})", existing.path, existing.label),
+            None => Ok(()),
+        }
+    }
+
+    /// Write `content` to a free `<task>/<label>.<ext>` path, `ext` taken from `file`.
+    fn write_new(&self, content: &[u8], file: &Path, label: ModelFamily, task: Option<&str>) -> anyhow::Result<PathBuf> {
+        let rel = self.free_path(task.map(task_dir).as_deref().unwrap_or(DEFAULT_TASK), label, file);
+        let dest = self.root.join(&rel);
+        std::fs::create_dir_all(dest.parent().unwrap_or(&self.root))?;
+        std::fs::write(&dest, content).with_context(|| format!("failed to write {}", dest.display()))?;
+        Ok(rel)
+    }
+
+    fn push(
+        &mut self,
+        relative: PathBuf,
+        label: ModelFamily,
+        provenance: Provenance,
+        language: String,
+        hash: String,
+    ) -> &CorpusEntry {
+        self.samples.push(CorpusEntry {
+            path: manifest_path(&relative),
+            label,
+            authorship: provenance.authorship,
+            model: provenance.model,
+            task: provenance.task,
+            language,
+            license: provenance.license,
+            hash,
+        });
+        self.samples.last().unwrap()
+    }
+
+    /// First of `<task>/<label>.<ext>`, `<task>/<label>_2.<ext>`, … that is
+    /// neither on disk nor in the manifest.
+    fn free_path(&self, task: &str, label: ModelFamily, file: &Path) -> PathBuf {
+        let ext = file.extension().and_then(|e| e.to_str()).unwrap_or_default();
+        let stem = label.to_string().to_lowercase();
+        (1..)
+            .map(|n| {
+                let name = if n == 1 { format!("{stem}.{ext}") } else { format!("{stem}_{n}.{ext}") };
+                Path::new(task).join(name)
+            })
+            .find(|rel| !self.root.join(rel).exists() && self.get(rel).is_none())
+            .unwrap()
+    }
+
+    /// Change the label of the entry at `path`, returning the old one.
+    pub fn relabel(&mut self, path: &Path, label: ModelFamily) -> anyhow::Result<ModelFamily> {
+        let key = manifest_path(path);
+        let entry = self
+            .samples
+            .iter_mut()
+            .find(|s| s.path == key)
+            .with_context(|| format!("{key} is not in the corpus"))?;
+        Ok(std::mem::replace(&mut entry.label, label))
+    }
+
+    /// Drop entries whose content repeats an earlier entry's with the same
+    /// label, keeping the first by path.  Only the manifest changes; the
+    /// caller decides whether to delete the dropped files.
+    pub fn dedupe(&mut self) -> Dedupe {
+        self.samples.sort_by(|a, b| a.path.cmp(&b.path));
+        let mut result = Dedupe::default();
+        let mut kept: Vec<CorpusEntry> = Vec::with_capacity(self.samples.len());
+        for entry in std::mem::take(&mut self.samples) {
+            match kept.iter().find(|k| k.hash == entry.hash) {
+                Some(first) if first.label == entry.label => result.removed.push(entry),
+                Some(first) => {
+                    match result.conflicts.iter_mut().find(|g| g[0].hash == entry.hash) {
+                        Some(group) => group.push(entry.clone()),
+                        None => result.conflicts.push(vec![first.clone(), entry.clone()]),
+                    }
+                    kept.push(entry);
+                }
+                None => kept.push(entry),
+            }
+        }
+        self.samples = kept;
+        result
+    }
+}
+
+/// SHA-256 (hex) of `content` with line endings normalized to `\n` and
+/// trailing whitespace removed from every line and from the end of the file.
+pub fn content_hash(content: &[u8]) -> String {
+    let text = String::from_utf8_lossy(content);
+    let mut hasher = Sha256::new();
+    for line in text.trim_end().lines() {
+        hasher.update(line.trim_end().as_bytes());
+        hasher.update(b"\n");
+    }
+    hasher.finalize().iter().map(|b| format!("{b:02x}")).collect()
+}
+
+/// Directory name for a task description: `"LRU cache"` → `lru_cache`.
+pub fn task_dir(task: &str) -> String {
+    let slug: String = task
+        .trim()
+        .chars()
+        .map(|c| if c.is_alphanumeric() { c.to_ascii_lowercase() } else { '_' })
+        .collect();
+    slug.split('_').filter(|s| !s.is_empty()).collect::<Vec<_>>().join("_")
+}
+
+fn language_of(file: &Path) -> anyhow::Result<String> {
+    language_key(file).with_context(|| format!("{}: not a supported source file", file.display()))
+}
+
+fn manifest_path(path: &Path) -> String {
+    path.components()
+        .map(|c| c.as_os_str().to_string_lossy())
+        .collect::<Vec<_>>()
+        .join("/")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn write(dir: &Path, name: &str, content: &str) -> PathBuf {
+        let path = dir.join(name);
+        std::fs::write(&path, content).unwrap();
+        path
+    }
+
+    #[test]
+    fn add_copies_into_task_directory_and_round_trips() {
+        let corpus = tempfile::tempdir().unwrap();
+        let incoming = tempfile::tempdir().unwrap();
+        let provenance = Provenance { task: Some("lru_cache".into()), model: Some("gpt-4o".into()), ..Default::default() };
+
+        let mut manifest = Manifest::load(corpus.path()).unwrap();
+        let a = write(incoming.path(), "a.py", "def f():\n    return 1\n");
+        let b = write(incoming.path(), "b.py", "def g():\n    return 2\n");
+        assert_eq!(manifest.add(&a, ModelFamily::Gpt, provenance.clone()).unwrap().path, "lru_cache/gpt.py");
+        assert_eq!(manifest.add(&b, ModelFamily::Gpt, provenance).unwrap().path, "lru_cache/gpt_2.py");
+        assert!(corpus.path().join("lru_cache/gpt_2.py").exists());
+        let generated = Provenance { task: Some("LRU cache!".into()), ..Default::default() };
+        assert_eq!(manifest.add_source("x = 1\n", "py", ModelFamily::Gpt, generated).unwrap().path, "lru_cache/gpt_3.py");
+        manifest.save().unwrap();
+
+        let reloaded = Manifest::load(corpus.path()).unwrap();
+        assert_eq!(reloaded.samples, manifest.samples);
+        let entry = reloaded.get(Path::new("lru_cache/gpt.py")).unwrap();
+        assert_eq!((entry.language.as_str(), entry.model.as_deref()), ("python", Some("gpt-4o")));
+    }
+
+    #[test]
+    fn add_registers_files_in_place_and_rejects_duplicates() {
+        let corpus = tempfile::tempdir().unwrap();
+        let file = write(corpus.path(), "human.go", "package main\n");
+        let copy = write(corpus.path(), "copy.go", "package main   \r\n\r\n");
+
+        let mut manifest = Manifest::load(corpus.path()).unwrap();
+        assert_eq!(manifest.add(&file, ModelFamily::Human, Provenance::default()).unwrap().path, "human.go");
+        let err = manifest.add(&copy, ModelFamily::Human, Provenance::default()).unwrap_err();
+        assert!(err.to_string().contains("duplicates human.go"), "{err}");
+        assert!(manifest.add(&write(corpus.path(), "notes.txt", "x"), ModelFamily::Human, Provenance::default()).is_err());
+    }
+
+    #[test]
+    fn relabel_and_dedupe() {
+        let entry = |path: &str, label, hash: &str| CorpusEntry {
+            path: path.into(),
+            label,
+            authorship: None,
+            model: None,
+            task: None,
+            language: "rust".into(),
+            license: None,
+            hash: hash.into(),
+        };
+        let mut manifest = Manifest {
+            root: PathBuf::new(),
+            samples: vec![
+                entry("b.rs", ModelFamily::Claude, "1"),
+                entry("a.rs", ModelFamily::Claude, "1"),
+                entry("c.rs", ModelFamily::Gpt, "2"),
+                entry("d.rs", ModelFamily::Human, "2"),
+            ],
+        };
+        assert_eq!(manifest.relabel(Path::new("c.rs"), ModelFamily::Copilot).unwrap(), ModelFamily::Gpt);
+        assert!(manifest.relabel(Path::new("missing.rs"), ModelFamily::Gpt).is_err());
+
+        let dedupe = manifest.dedupe();
+        assert_eq!(dedupe.removed.iter().map(|e| e.path.as_str()).collect::<Vec<_>>(), ["b.rs"]);
+        assert_eq!(dedupe.conflicts.len(), 1);
+        assert_eq!(manifest.samples.len(), 3);
+    }
+}
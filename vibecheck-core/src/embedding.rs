@@ -0,0 +1,374 @@
+//! Embedding-based classification (the `onnx` classifier backend).
+//!
+//! The heuristics lean heavily on comments, and deleting the comments
+//! defeats them.  This backend embeds a file's code with its comments
+//! stripped and compares the embedding with one prototype per family: the
+//! mean embedding of that family's corpus samples.  Its distribution is
+//! blended with the weighted-sum heuristics, so it complements them rather
+//! than replacing them, and carries the verdict alone when no signal fired.
+//!
+//! A model is a directory holding a sentence-embedding model exported to
+//! ONNX ([`MODEL_FILE`]), its Hugging Face tokenizer ([`TOKENIZER_FILE`]) and
+//! the prototypes written by `vibecheck tune --classifier onnx`
+//! ([`PROTOTYPES_FILE`]).  Running the model needs the `onnx` feature;
+//! without it the backend fails to load and the config falls back to
+//! `heuristic`.
+
+use std::collections::{BTreeMap, HashMap};
+use std::path::{Path, PathBuf};
+use std::sync::{Arc, Mutex, OnceLock};
+
+use serde::{Deserialize, Serialize};
+
+use crate::classifier::{Classifier, HeuristicClassifier};
+use crate::frontend::{LanguageFrontend, BUILTIN_FRONTENDS};
+use crate::language::Language;
+use crate::report::{Attribution, ModelFamily, Signal};
+use crate::source_file::SourceFile;
+
+pub const MODEL_FILE: &str = "model.onnx";
+pub const TOKENIZER_FILE: &str = "tokenizer.json";
+pub const PROTOTYPES_FILE: &str = "prototypes.toml";
+
+/// Share of the embedding distribution in the blend unless the prototypes
+/// file sets `blend`.
+pub const DEFAULT_BLEND: f64 = 0.5;
+
+/// Softmax temperature over cosine similarities.  Embeddings of code sit
+/// close together, so differences of a few hundredths must count.
+const TEMPERATURE: f64 = 0.05;
+
+/// Maps code to a fixed-size vector.
+pub trait Embedder: Send + Sync {
+    fn embed(&self, code: &str) -> anyhow::Result<Vec<f32>>;
+}
+
+// ---------------------------------------------------------------------------
+// Prototypes
+// ---------------------------------------------------------------------------
+
+/// Per-family mean embeddings and the blend weight, as stored in
+/// [`PROTOTYPES_FILE`].
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct Prototypes {
+    /// Share of the embedding distribution in the blend (0.0–1.0).
+    #[serde(default = "default_blend")]
+    pub blend: f64,
+    /// Family → unit-length mean embedding of its samples.
+    #[serde(default)]
+    pub families: BTreeMap<ModelFamily, Vec<f32>>,
+}
+
+fn default_blend() -> f64 {
+    DEFAULT_BLEND
+}
+
+impl Prototypes {
+    /// The normalized mean embedding of each family in `samples`.
+    pub fn fit(samples: &[(&[f32], ModelFamily)], blend: f64) -> Self {
+        let mut sums: BTreeMap<ModelFamily, Vec<f32>> = BTreeMap::new();
+        for (embedding, family) in samples {
+            let unit = normalized(embedding);
+            let sum = sums.entry(*family).or_insert_with(|| vec![0.0; unit.len()]);
+            for (s, v) in sum.iter_mut().zip(unit) {
+                *s += v;
+            }
+        }
+        let families = sums.into_iter().map(|(family, sum)| (family, normalized(&sum))).collect();
+        Self { blend: blend.clamp(0.0, 1.0), families }
+    }
+
+    pub fn from_file(path: &Path) -> anyhow::Result<Self> {
+        let s = std::fs::read_to_string(path)
+            .map_err(|e| anyhow::anyhow!("failed to read {}: {e}", path.display()))?;
+        toml::from_str(&s).map_err(|e| anyhow::anyhow!("failed to parse {}: {e}", path.display()))
+    }
+
+    pub fn to_toml(&self) -> anyhow::Result<String> {
+        Ok(toml::to_string(self)?)
+    }
+
+    /// Softmax over the cosine similarity of `embedding` to each prototype.
+    /// Families without a prototype score zero.
+    pub fn distribution(&self, embedding: &[f32]) -> BTreeMap<ModelFamily, f64> {
+        let unit = normalized(embedding);
+        let logits: Vec<(ModelFamily, f64)> = self
+            .families
+            .iter()
+            .map(|(&family, prototype)| {
+                let cosine: f32 = prototype.iter().zip(&unit).map(|(a, b)| a * b).sum();
+                (family, cosine as f64 / TEMPERATURE)
+            })
+            .collect();
+        let max = logits.iter().map(|(_, z)| *z).fold(f64::NEG_INFINITY, f64::max);
+        let total: f64 = logits.iter().map(|(_, z)| (z - max).exp()).sum();
+        let mut scores: BTreeMap<ModelFamily, f64> = ModelFamily::all().iter().map(|&f| (f, 0.0)).collect();
+        for (family, z) in logits {
+            scores.insert(family, (z - max).exp() / total);
+        }
+        scores
+    }
+
+    /// The weighted-sum attribution of `signals` blended with the embedding
+    /// distribution; the embedding alone when no signal fired.
+    pub fn attribution(&self, embedding: &[f32], signals: &[Signal]) -> Attribution {
+        let heuristic = HeuristicClassifier::score(signals);
+        let embedded = self.distribution(embedding);
+        let blend = if heuristic.has_sufficient_data() { self.blend } else { 1.0 };
+        let scores = ModelFamily::all()
+            .iter()
+            .map(|f| {
+                let h = heuristic.scores.get(f).copied().unwrap_or(0.0);
+                (*f, (1.0 - blend) * h + blend * embedded[f])
+            })
+            .collect();
+        crate::classifier::distribution(scores)
+    }
+}
+
+fn normalized(v: &[f32]) -> Vec<f32> {
+    let norm = v.iter().map(|x| x * x).sum::<f32>().sqrt();
+    if norm == 0.0 {
+        return v.to_vec();
+    }
+    v.iter().map(|x| x / norm).collect()
+}
+
+// ---------------------------------------------------------------------------
+// Classifier
+// ---------------------------------------------------------------------------
+
+/// The `onnx` backend: an [`Embedder`] and the family prototypes.
+pub struct EmbeddingClassifier {
+    embedder: Arc<dyn Embedder>,
+    prototypes: Prototypes,
+}
+
+impl EmbeddingClassifier {
+    pub fn new(embedder: Arc<dyn Embedder>, prototypes: Prototypes) -> Self {
+        Self { embedder, prototypes }
+    }
+
+    /// Load the model directory `dir`.
+    pub fn load(dir: &Path) -> anyhow::Result<Self> {
+        let prototypes = Prototypes::from_file(&dir.join(PROTOTYPES_FILE))?;
+        Ok(Self::new(load_embedder(dir)?, prototypes))
+    }
+}
+
+impl Classifier for EmbeddingClassifier {
+    fn name(&self) -> &str {
+        "onnx"
+    }
+
+    fn classify(
+        &self,
+        signals: &[Signal],
+        _: &HashMap<String, f64>,
+        language: Option<Language>,
+        source: &str,
+    ) -> Attribution {
+        match self.embedder.embed(&code_only(source, language)) {
+            Ok(embedding) => self.prototypes.attribution(&embedding, signals),
+            // Inference failed for this file: fall back to the heuristics.
+            Err(_) => HeuristicClassifier::score(signals),
+        }
+    }
+}
+
+/// `source` with its comments removed, so that the embedding sees only
+/// code.  Unchanged when the language is unknown or does not parse.
+pub fn code_only(source: &str, language: Option<Language>) -> String {
+    let Some(frontend) = language.and_then(|l| BUILTIN_FRONTENDS.iter().find(|f| f.language == l)) else {
+        return source.to_string();
+    };
+    let file = SourceFile::for_language(source, frontend.language);
+    let Some(tree) = &file.tree else {
+        return source.to_string();
+    };
+
+    let mut comments = Vec::new();
+    let mut stack = vec![tree.root_node()];
+    while let Some(node) = stack.pop() {
+        if frontend.is_comment(node.kind()) {
+            comments.push(node.byte_range());
+            continue;
+        }
+        let mut cursor = node.walk();
+        stack.extend(node.children(&mut cursor));
+    }
+    comments.sort_by_key(|r| r.start);
+
+    let mut code = String::with_capacity(source.len());
+    let mut at = 0;
+    for range in comments {
+        code.push_str(&source[at..range.start]);
+        at = range.end;
+    }
+    code.push_str(&source[at..]);
+    // Drop the lines that held nothing but a comment.
+    code.lines().filter(|l| !l.trim().is_empty()).map(|l| format!("{}\n", l.trim_end())).collect()
+}
+
+// ---------------------------------------------------------------------------
+// ONNX runtime
+// ---------------------------------------------------------------------------
+
+/// The embedder for model directory `dir`, loaded once per process: every
+/// file analyzed builds a pipeline, and an ONNX session is expensive.
+pub fn load_embedder(dir: &Path) -> anyhow::Result<Arc<dyn Embedder>> {
+    static LOADED: OnceLock<Mutex<HashMap<PathBuf, Arc<dyn Embedder>>>> = OnceLock::new();
+    let mut loaded = LOADED.get_or_init(Default::default).lock().unwrap();
+    if let Some(embedder) = loaded.get(dir) {
+        return Ok(embedder.clone());
+    }
+    let embedder: Arc<dyn Embedder> = Arc::new(onnx::OnnxEmbedder::load(dir)?);
+    loaded.insert(dir.to_path_buf(), embedder.clone());
+    Ok(embedder)
+}
+
+#[cfg(feature = "onnx")]
+mod onnx {
+    use std::path::Path;
+    use std::sync::Mutex;
+
+    use anyhow::Context;
+    use ort::session::Session;
+    use ort::value::Tensor;
+
+    use super::{Embedder, MODEL_FILE, TOKENIZER_FILE};
+
+    /// Longest input in tokens; the tail of longer files is not embedded.
+    const MAX_TOKENS: usize = 512;
+
+    /// A transformer encoder whose token states are mean-pooled into one
+    /// vector.  Models that already output a pooled `[1, dim]` tensor are
+    /// used as is.
+    pub struct OnnxEmbedder {
+        session: Mutex<Session>,
+        tokenizer: tokenizers::Tokenizer,
+        token_type_ids: bool,
+    }
+
+    impl OnnxEmbedder {
+        pub fn load(dir: &Path) -> anyhow::Result<Self> {
+            let model = dir.join(MODEL_FILE);
+            let session = Session::builder()?
+                .commit_from_file(&model)
+                .with_context(|| format!("cannot load {}", model.display()))?;
+            let token_type_ids = session.inputs.iter().any(|i| i.name == "token_type_ids");
+            let tokenizer = tokenizers::Tokenizer::from_file(dir.join(TOKENIZER_FILE))
+                .map_err(|e| anyhow::anyhow!("cannot load {}: {e}", dir.join(TOKENIZER_FILE).display()))?;
+            Ok(Self { session: Mutex::new(session), tokenizer, token_type_ids })
+        }
+    }
+
+    impl Embedder for OnnxEmbedder {
+        fn embed(&self, code: &str) -> anyhow::Result<Vec<f32>> {
+            let encoding = self.tokenizer.encode(code, true).map_err(anyhow::Error::msg)?;
+            let ids: Vec<i64> = encoding.get_ids().iter().take(MAX_TOKENS).map(|&i| i as i64).collect();
+            let n = ids.len();
+            let mask = vec![1i64; n];
+
+            let mut inputs = ort::inputs![
+                "input_ids" => Tensor::from_array(([1, n], ids))?,
+                "attention_mask" => Tensor::from_array(([1, n], mask))?,
+            ];
+            if self.token_type_ids {
+                inputs.push(("token_type_ids".into(), Tensor::from_array(([1, n], vec![0i64; n]))?.into()));
+            }
+            let mut session = self.session.lock().unwrap();
+            let outputs = session.run(inputs)?;
+            let (shape, data) = outputs[0].try_extract_tensor::<f32>()?;
+            match shape.len() {
+                2 => Ok(data.to_vec()),
+                3 => {
+                    let dim = shape[2] as usize;
+                    let mut pooled = vec![0.0; dim];
+                    for token in data.chunks(dim) {
+                        for (p, v) in pooled.iter_mut().zip(token) {
+                            *p += v / n as f32;
+                        }
+                    }
+                    Ok(pooled)
+                }
+                _ => anyhow::bail!("unexpected embedding shape {shape:?}"),
+            }
+        }
+    }
+}
+
+#[cfg(not(feature = "onnx"))]
+mod onnx {
+    use std::path::Path;
+
+    use super::Embedder;
+
+    pub enum OnnxEmbedder {}
+
+    impl OnnxEmbedder {
+        pub fn load(dir: &Path) -> anyhow::Result<Self> {
+            anyhow::bail!(
+                "cannot load {}: vibecheck was built without the `onnx` feature",
+                dir.display()
+            )
+        }
+    }
+
+    impl Embedder for OnnxEmbedder {
+        fn embed(&self, _: &str) -> anyhow::Result<Vec<f32>> {
+            match *self {}
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    /// Embeds code by counting a few tokens, so that family styles separate.
+    struct CountingEmbedder;
+
+    impl Embedder for CountingEmbedder {
+        fn embed(&self, code: &str) -> anyhow::Result<Vec<f32>> {
+            Ok(["unwrap", "?", "match"].iter().map(|t| code.matches(t).count() as f32 + 0.1).collect())
+        }
+    }
+
+    #[test]
+    fn code_only_strips_comments() {
+        let source = "// Adds one.\nfn inc(x: i32) -> i32 {\n    x + 1 // obviously\n}\n";
+        assert_eq!(code_only(source, Some(Language::Rust)), "fn inc(x: i32) -> i32 {\n    x + 1\n}\n");
+        assert_eq!(code_only("# note\n", None), "# note\n");
+    }
+
+    #[test]
+    fn prototypes_classify_without_signals() {
+        let claude = CountingEmbedder.embed("a?; b?; match c {}").unwrap();
+        let human = CountingEmbedder.embed("a.unwrap(); b.unwrap();").unwrap();
+        let prototypes = Prototypes::fit(&[(&claude, ModelFamily::Claude), (&human, ModelFamily::Human)], DEFAULT_BLEND);
+        let reloaded: Prototypes = toml::from_str(&prototypes.to_toml().unwrap()).unwrap();
+
+        let classifier = EmbeddingClassifier::new(Arc::new(CountingEmbedder), reloaded);
+        let source = "// Carefully propagate every error.\nfn f() -> R { g()?; h()?; Ok(()) }\n";
+        let attribution = classifier.classify(&[], &HashMap::new(), Some(Language::Rust), source);
+        assert_eq!(attribution.primary, ModelFamily::Claude);
+        assert!((attribution.scores.values().sum::<f64>() - 1.0).abs() < 1e-9);
+    }
+
+    #[test]
+    fn blend_mixes_in_the_heuristics() {
+        let prototypes = Prototypes::fit(&[(&[1.0, 0.0], ModelFamily::Gpt), (&[0.0, 1.0], ModelFamily::Human)], 0.25);
+        let signals = [Signal::new("t.claude", "t", "", ModelFamily::Claude, 2.0)];
+        let attribution = prototypes.attribution(&[1.0, 0.0], &signals);
+        assert_eq!(attribution.primary, ModelFamily::Claude);
+        assert!(attribution.scores[&ModelFamily::Gpt] > 0.2);
+    }
+
+    #[cfg(not(feature = "onnx"))]
+    #[test]
+    fn loading_without_the_feature_fails() {
+        let err = load_embedder(Path::new("/nonexistent")).err().unwrap();
+        assert!(err.to_string().contains("`onnx` feature"), "{err}");
+    }
+}
@@ -0,0 +1,354 @@
+//! Evaluation of the detector pipeline against a labelled corpus.
+//!
+//! A corpus is a directory tree of source files.  A file's label comes from
+//! the corpus manifest ([`crate::corpus_manifest`]) when it is listed there,
+//! and otherwise from the family named by its file stem (`claude.rs`,
+//! `human.go`) or, failing that, its parent directory (`gpt/cache.py`).
+//! Model-written files under an `assisted/` directory are AI-assisted
+//! rather than AI-generated (see [`authorship_for`]).
+//! [`evaluate`] scores the pipeline's
+//! verdicts against those labels — precision, recall and F1 per family, a
+//! confusion matrix, and one for the three-way authorship verdict — and
+//! measures each detector's contribution by re-aggregating every report
+//! without that detector's signals.
+
+use std::collections::BTreeMap;
+use std::path::{Path, PathBuf};
+
+use serde::Serialize;
+
+use crate::corpus_manifest::Manifest;
+use crate::language::SUPPORTED_EXTENSIONS;
+use crate::language_pack;
+use crate::pipeline::Pipeline;
+use crate::report::{Authorship, ModelFamily, Report};
+
+/// One labelled file and the pipeline's report for it.
+pub struct Sample {
+    pub path: PathBuf,
+    pub label: ModelFamily,
+    pub authorship: Authorship,
+    pub report: Report,
+}
+
+/// Evaluation results; serializes to the `vibecheck eval --format json` output.
+#[derive(Debug, Serialize)]
+pub struct Evaluation {
+    pub files: usize,
+    pub accuracy: f64,
+    /// Unweighted mean of the per-family F1 scores over families with support.
+    pub macro_f1: f64,
+    pub families: Vec<FamilyMetrics>,
+    /// `confusion[i][j]`: files labelled `ModelFamily::all()[i]` attributed to
+    /// `ModelFamily::all()[j]`.
+    pub confusion: Vec<Vec<usize>>,
+    /// How well the three-way authorship verdict matches the labels.
+    pub authorship: AuthorshipMetrics,
+    /// Sorted by accuracy delta, most helpful first.
+    pub detectors: Vec<DetectorContribution>,
+    pub misattributed: Vec<Misattribution>,
+}
+
+#[derive(Debug, Serialize)]
+pub struct FamilyMetrics {
+    pub family: ModelFamily,
+    pub precision: f64,
+    pub recall: f64,
+    pub f1: f64,
+    /// Files labelled with this family.
+    pub support: usize,
+}
+
+#[derive(Debug, Serialize)]
+pub struct AuthorshipMetrics {
+    /// Files with a verdict; files without enough signal data have none.
+    pub files: usize,
+    pub accuracy: f64,
+    /// `confusion[i][j]`: files labelled `Authorship::all()[i]` given the
+    /// verdict `Authorship::all()[j]`.
+    pub confusion: Vec<Vec<usize>>,
+}
+
+#[derive(Debug, Serialize)]
+pub struct DetectorContribution {
+    /// The analyzer name signals report as their `source`.
+    pub detector: String,
+    /// Files the detector fired in.
+    pub files: usize,
+    pub signals: usize,
+    /// Total weight pointing at each file's true label.  Negative-weight
+    /// signals count toward Human.
+    pub weight_for: f64,
+    /// Total weight pointing away from each file's true label.
+    pub weight_against: f64,
+    /// Accuracy with the detector minus accuracy without it.
+    pub accuracy_delta: f64,
+}
+
+#[derive(Debug, Serialize)]
+pub struct Misattribution {
+    pub path: PathBuf,
+    pub label: ModelFamily,
+    pub predicted: ModelFamily,
+    pub confidence: f64,
+}
+
+/// The family `path` is labelled with: its file stem, else its parent
+/// directory's name, matched case-insensitively against the family names.
+pub fn label_for(path: &Path) -> Option<ModelFamily> {
+    let family = |name: Option<&std::ffi::OsStr>| {
+        let name = name?.to_str()?;
+        ModelFamily::all()
+            .iter()
+            .copied()
+            .find(|f| f.to_string().eq_ignore_ascii_case(name))
+    };
+    family(path.file_stem()).or_else(|| family(path.parent().and_then(Path::file_name)))
+}
+
+/// The authorship of a file at `path` labelled `family`: AI-assisted for a
+/// model family's file under an `assisted/` directory, otherwise
+/// [`Authorship::of_label`].
+pub fn authorship_for(path: &Path, family: ModelFamily) -> Authorship {
+    let assisted = path.ancestors().skip(1).any(|dir| dir.file_name().is_some_and(|n| n == "assisted"));
+    if assisted && family != ModelFamily::Human {
+        Authorship::AiAssisted
+    } else {
+        Authorship::of_label(family)
+    }
+}
+
+/// The supported source files under `dir` that carry a label — from the
+/// manifest, else from [`label_for`] — sorted, and the number of supported
+/// files skipped for lacking one.  Language-pack extensions count as
+/// supported.
+pub fn corpus_files(dir: &Path) -> anyhow::Result<(Vec<(PathBuf, ModelFamily, Authorship)>, usize)> {
+    fn walk(dir: &Path, out: &mut Vec<PathBuf>) -> std::io::Result<()> {
+        for entry in std::fs::read_dir(dir)? {
+            let path = entry?.path();
+            if path.is_dir() {
+                walk(&path, out)?;
+            } else if path
+                .extension()
+                .and_then(|e| e.to_str())
+                .is_some_and(|e| SUPPORTED_EXTENSIONS.contains(&e) || language_pack::is_pack_extension(e))
+            {
+                out.push(path);
+            }
+        }
+        Ok(())
+    }
+    let manifest = Manifest::load(dir)?;
+    let mut paths = Vec::new();
+    walk(dir, &mut paths).map_err(|e| anyhow::anyhow!("failed to read corpus {}: {e}", dir.display()))?;
+    paths.sort();
+    let total = paths.len();
+    let labelled: Vec<_> = paths
+        .into_iter()
+        .filter_map(|p| {
+            let listed = p.strip_prefix(dir).ok().and_then(|rel| manifest.get(rel)).map(|e| (e.label, e.authorship()));
+            let (label, authorship) = listed.or_else(|| label_for(&p).map(|l| (l, authorship_for(&p, l))))?;
+            Some((p, label, authorship))
+        })
+        .collect();
+    let unlabelled = total - labelled.len();
+    Ok((labelled, unlabelled))
+}
+
+/// Score `samples` against their labels.
+pub fn evaluate(samples: &[Sample]) -> Evaluation {
+    let families = ModelFamily::all();
+    let index = |f: ModelFamily| families.iter().position(|&x| x == f).unwrap();
+
+    let mut confusion = vec![vec![0; families.len()]; families.len()];
+    for s in samples {
+        confusion[index(s.label)][index(s.report.attribution.primary)] += 1;
+    }
+    let correct: usize = (0..families.len()).map(|i| confusion[i][i]).sum();
+
+    let family_metrics: Vec<FamilyMetrics> = families
+        .iter()
+        .enumerate()
+        .map(|(i, &family)| {
+            let tp = confusion[i][i] as f64;
+            let support: usize = confusion[i].iter().sum();
+            let predicted: usize = confusion.iter().map(|row| row[i]).sum();
+            let precision = ratio(tp, predicted as f64);
+            let recall = ratio(tp, support as f64);
+            let f1 = ratio(2.0 * precision * recall, precision + recall);
+            FamilyMetrics { family, precision, recall, f1, support }
+        })
+        .collect();
+    let supported: Vec<&FamilyMetrics> = family_metrics.iter().filter(|m| m.support > 0).collect();
+    let macro_f1 = ratio(supported.iter().map(|m| m.f1).sum(), supported.len() as f64);
+
+    let misattributed = samples
+        .iter()
+        .filter(|s| s.report.attribution.primary != s.label)
+        .map(|s| Misattribution {
+            path: s.path.clone(),
+            label: s.label,
+            predicted: s.report.attribution.primary,
+            confidence: s.report.attribution.confidence,
+        })
+        .collect();
+
+    let accuracy = ratio(correct as f64, samples.len() as f64);
+    Evaluation {
+        files: samples.len(),
+        accuracy,
+        macro_f1,
+        families: family_metrics,
+        confusion,
+        authorship: authorship_metrics(samples),
+        detectors: detector_contributions(samples, accuracy),
+        misattributed,
+    }
+}
+
+fn authorship_metrics(samples: &[Sample]) -> AuthorshipMetrics {
+    let index = |a: Authorship| Authorship::all().iter().position(|&x| x == a).unwrap();
+    let mut confusion = vec![vec![0; Authorship::all().len()]; Authorship::all().len()];
+    let mut files = 0;
+    for s in samples {
+        if let Some(verdict) = Authorship::of(&s.report) {
+            confusion[index(s.authorship)][index(verdict)] += 1;
+            files += 1;
+        }
+    }
+    let correct: usize = (0..confusion.len()).map(|i| confusion[i][i]).sum();
+    AuthorshipMetrics { files, accuracy: ratio(correct as f64, files as f64), confusion }
+}
+
+fn detector_contributions(samples: &[Sample], accuracy: f64) -> Vec<DetectorContribution> {
+    let mut by_detector: BTreeMap<&str, DetectorContribution> = BTreeMap::new();
+    for s in samples {
+        let mut fired_here: Vec<&str> = Vec::new();
+        for signal in &s.report.signals {
+            let c = by_detector.entry(signal.source.as_str()).or_insert_with(|| DetectorContribution {
+                detector: signal.source.clone(),
+                files: 0,
+                signals: 0,
+                weight_for: 0.0,
+                weight_against: 0.0,
+                accuracy_delta: 0.0,
+            });
+            c.signals += 1;
+            if !fired_here.contains(&signal.source.as_str()) {
+                fired_here.push(&signal.source);
+                c.files += 1;
+            }
+            let toward = if signal.weight < 0.0 { ModelFamily::Human } else { signal.family };
+            if toward == s.label {
+                c.weight_for += signal.weight.abs();
+            } else {
+                c.weight_against += signal.weight.abs();
+            }
+        }
+    }
+
+    let pipeline = Pipeline::with_defaults();
+    let mut out: Vec<DetectorContribution> = by_detector
+        .into_values()
+        .map(|mut c| {
+            let correct = samples
+                .iter()
+                .filter(|s| {
+                    let kept: Vec<_> = s.report.signals.iter().filter(|sig| sig.source != c.detector).cloned().collect();
+                    pipeline.aggregate(&kept).primary == s.label
+                })
+                .count();
+            c.accuracy_delta = accuracy - ratio(correct as f64, samples.len() as f64);
+            c
+        })
+        .collect();
+    out.sort_by(|a, b| {
+        b.accuracy_delta
+            .partial_cmp(&a.accuracy_delta)
+            .unwrap()
+            .then_with(|| (b.weight_for - b.weight_against).partial_cmp(&(a.weight_for - a.weight_against)).unwrap())
+            .then_with(|| a.detector.cmp(&b.detector))
+    });
+    out
+}
+
+/// `n / d`, or 0 when `d` is 0.
+fn ratio(n: f64, d: f64) -> f64 {
+    if d == 0.0 { 0.0 } else { n / d }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::corpus_manifest::Provenance;
+    use crate::report::Signal;
+
+    fn sample(path: &str, label: ModelFamily, signals: Vec<Signal>) -> Sample {
+        let mut report = crate::analyze("");
+        report.attribution = Pipeline::with_defaults().aggregate(&signals);
+        report.signals = signals;
+        Sample { path: PathBuf::from(path), label, authorship: Authorship::of_label(label), report }
+    }
+
+    #[test]
+    fn labels_come_from_stem_then_directory() {
+        assert_eq!(label_for(Path::new("lru_cache/claude.rs")), Some(ModelFamily::Claude));
+        assert_eq!(label_for(Path::new("corpus/GPT/cache.py")), Some(ModelFamily::Gpt));
+        assert_eq!(label_for(Path::new("corpus/misc/cache.py")), None);
+        assert_eq!(authorship_for(Path::new("lru_cache/assisted/claude.rs"), ModelFamily::Claude), Authorship::AiAssisted);
+        assert_eq!(authorship_for(Path::new("lru_cache/claude.rs"), ModelFamily::Claude), Authorship::AiGenerated);
+        assert_eq!(authorship_for(Path::new("assisted/human.rs"), ModelFamily::Human), Authorship::HumanAuthored);
+    }
+
+    #[test]
+    fn manifest_labels_take_precedence() {
+        let dir = tempfile::tempdir().unwrap();
+        for name in ["claude.rs", "cache.rs", "notes.rs"] {
+            std::fs::write(dir.path().join(name), format!("// {name}\nfn f() {{}}\n")).unwrap();
+        }
+        let mut manifest = Manifest::load(dir.path()).unwrap();
+        let assisted = Provenance { authorship: Some(Authorship::AiAssisted), ..Default::default() };
+        manifest.add(&dir.path().join("cache.rs"), ModelFamily::Gemini, assisted).unwrap();
+        manifest.add(&dir.path().join("claude.rs"), ModelFamily::Human, Default::default()).unwrap();
+        manifest.save().unwrap();
+
+        let (files, unlabelled) = corpus_files(dir.path()).unwrap();
+        let labels: Vec<_> = files.iter().map(|(p, l, a)| (p.file_name().unwrap().to_str().unwrap(), *l, *a)).collect();
+        assert_eq!(
+            labels,
+            [
+                ("cache.rs", ModelFamily::Gemini, Authorship::AiAssisted),
+                ("claude.rs", ModelFamily::Human, Authorship::HumanAuthored)
+            ]
+        );
+        assert_eq!(unlabelled, 1);
+    }
+
+    #[test]
+    fn metrics_confusion_and_ablation() {
+        let claude = |w| Signal::new("t.claude", "docs", "", ModelFamily::Claude, w);
+        let gpt = |w| Signal::new("t.gpt", "filler", "", ModelFamily::Gpt, w);
+        let samples = [
+            sample("a/claude.rs", ModelFamily::Claude, vec![claude(2.0)]),
+            sample("b/claude.rs", ModelFamily::Claude, vec![claude(1.0), gpt(2.0)]),
+            sample("c/gpt.rs", ModelFamily::Gpt, vec![gpt(2.0)]),
+        ];
+        let eval = evaluate(&samples);
+        assert_eq!(eval.files, 3);
+        assert!((eval.accuracy - 2.0 / 3.0).abs() < 1e-9);
+        let claude_row = &eval.families[0];
+        assert_eq!((claude_row.precision, claude_row.recall, claude_row.support), (1.0, 0.5, 2));
+        assert_eq!(eval.confusion[0][0..2], [1, 1]);
+        assert_eq!(eval.misattributed.len(), 1);
+        assert_eq!(eval.misattributed[0].predicted, ModelFamily::Gpt);
+        // Uncalibrated, every AI verdict is AI-generated, as are the labels.
+        assert_eq!((eval.authorship.files, eval.authorship.accuracy), (3, 1.0));
+        assert_eq!(eval.authorship.confusion[2], [0, 0, 3]);
+
+        let docs = eval.detectors.iter().find(|d| d.detector == "docs").unwrap();
+        assert_eq!((docs.files, docs.signals), (2, 2));
+        assert_eq!(docs.weight_for, 3.0);
+        // Without `docs`, a/claude.rs has no signals and falls to Human.
+        assert!((docs.accuracy_delta - 1.0 / 3.0).abs() < 1e-9);
+    }
+}
@@ -0,0 +1,181 @@
+//! Per-repository weight adjustments learned from reviewer verdicts.
+//!
+//! A file a reviewer marks as a false positive (`vibecheck review`,
+//! `vibecheck mark`) names the rules that misfire on this codebase: the
+//! AI-leaning rules that weighed most in its verdict.  [`Feedback`] turns a
+//! repository's verdicts into a weight multiplier per such rule and is
+//! stored in [`FEEDBACK_FILE`] at the project root, where
+//! [`crate::ignore_rules::IgnoreConfig`] applies it on top of the configured
+//! weights for every later scan.
+//!
+//! ```toml
+//! [rules."rust.errors.zero_unwrap"]
+//! multiplier = 0.5
+//! false_positives = 2
+//! confirmed = 0
+//! ```
+//!
+//! A rule's multiplier is the share of its verdicts that were right, with
+//! [`PRIOR`] confirmations credited up front so that a single false
+//! positive quiets a rule rather than silencing it.  Confirmed verdicts win
+//! weight back, up to the configured weight and never above it.
+
+use std::collections::{BTreeMap, HashMap};
+use std::path::{Path, PathBuf};
+
+use anyhow::Context;
+use serde::{Deserialize, Serialize};
+
+use crate::heuristics::{DefaultHeuristics, HeuristicsProvider};
+
+/// File name of the adjustments at the project root, beside `.vibecheck`.
+pub const FEEDBACK_FILE: &str = ".vibecheck-feedback";
+
+/// Confirmations every rule is credited with before its first verdict.
+pub const PRIOR: f64 = 2.0;
+
+/// Lowest multiplier.  Feedback quiets a rule but does not turn it off;
+/// that is `[heuristics] <id> = 0.0`.
+pub const MIN_MULTIPLIER: f64 = 0.25;
+
+/// One reviewer verdict on a flagged file.
+#[derive(Debug, Clone, Copy)]
+pub struct Review<'a> {
+    /// The AI-leaning rules that weighed most in the file's verdict.
+    pub rules: &'a [String],
+    /// The file was human-written after all.
+    pub false_positive: bool,
+}
+
+/// How one rule's weight is scaled, and the verdicts behind it.
+#[derive(Debug, Clone, Copy, PartialEq, Serialize, Deserialize)]
+pub struct Adjustment {
+    pub multiplier: f64,
+    pub false_positives: usize,
+    pub confirmed: usize,
+}
+
+/// Weight adjustments for one repository, by rule id.
+#[derive(Debug, Clone, Default, PartialEq, Serialize, Deserialize)]
+pub struct Feedback {
+    #[serde(default)]
+    pub rules: BTreeMap<String, Adjustment>,
+}
+
+impl Feedback {
+    /// Adjustments for every rule named in a false positive among
+    /// `reviews`.  Rules only ever confirmed keep their weight.
+    pub fn from_reviews<'a>(reviews: impl IntoIterator<Item = Review<'a>>) -> Self {
+        let mut counts: BTreeMap<&str, (usize, usize)> = BTreeMap::new();
+        for review in reviews {
+            for rule in review.rules {
+                let (false_positives, confirmed) = counts.entry(rule).or_default();
+                match review.false_positive {
+                    true => *false_positives += 1,
+                    false => *confirmed += 1,
+                }
+            }
+        }
+        let rules = counts
+            .into_iter()
+            .filter(|&(_, (false_positives, _))| false_positives > 0)
+            .map(|(rule, (false_positives, confirmed))| {
+                let right = confirmed as f64 + PRIOR;
+                let multiplier = (right / (right + false_positives as f64)).max(MIN_MULTIPLIER);
+                let multiplier = (multiplier * 100.0).round() / 100.0;
+                (rule.to_string(), Adjustment { multiplier, false_positives, confirmed })
+            })
+            .collect();
+        Self { rules }
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.rules.is_empty()
+    }
+
+    /// The adjustments saved under `root`; none when nothing was saved.
+    pub fn load(root: &Path) -> anyhow::Result<Self> {
+        let path = root.join(FEEDBACK_FILE);
+        let text = match std::fs::read_to_string(&path) {
+            Ok(text) => text,
+            Err(e) if e.kind() == std::io::ErrorKind::NotFound => return Ok(Self::default()),
+            Err(e) => return Err(e).with_context(|| format!("cannot read {}", path.display())),
+        };
+        toml::from_str(&text).with_context(|| format!("failed to parse {}", path.display()))
+    }
+
+    /// Write the adjustments under `root`, returning the file's path.
+    pub fn save(&self, root: &Path) -> anyhow::Result<PathBuf> {
+        let path = root.join(FEEDBACK_FILE);
+        let text = format!(
+            "# Weight multipliers learned from review verdicts (`vibecheck review`,\n\
+             # `vibecheck mark`).  Regenerated on every verdict; delete the file to\n\
+             # score with the configured weights alone.\n\n{}",
+            toml::to_string_pretty(self)?
+        );
+        std::fs::write(&path, text).with_context(|| format!("cannot write {}", path.display()))?;
+        Ok(path)
+    }
+
+    /// Scale each adjusted rule in `weights`, or its default weight when
+    /// `weights` has none.
+    pub fn apply(&self, weights: &mut HashMap<String, f64>) {
+        for (rule, adjustment) in &self.rules {
+            let weight = weights.get(rule).copied().unwrap_or_else(|| DefaultHeuristics.weight(rule));
+            weights.insert(rule.clone(), weight * adjustment.multiplier);
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn rules(ids: &[&str]) -> Vec<String> {
+        ids.iter().map(|s| s.to_string()).collect()
+    }
+
+    #[test]
+    fn false_positives_scale_down_the_rules_they_name() {
+        let (a, ab, b) = (rules(&["a"]), rules(&["a", "b"]), rules(&["b"]));
+        let feedback = Feedback::from_reviews([
+            Review { rules: &ab, false_positive: true },
+            Review { rules: &a, false_positive: true },
+            Review { rules: &b, false_positive: false },
+            Review { rules: &b, false_positive: false },
+        ]);
+        assert_eq!(feedback.rules["a"], Adjustment { multiplier: 0.5, false_positives: 2, confirmed: 0 });
+        assert_eq!(feedback.rules["b"], Adjustment { multiplier: 0.8, false_positives: 1, confirmed: 2 });
+
+        let only_confirmed = Feedback::from_reviews([Review { rules: &a, false_positive: false }]);
+        assert!(only_confirmed.is_empty());
+
+        let many: Vec<Review> = (0..20).map(|_| Review { rules: &a, false_positive: true }).collect();
+        assert_eq!(Feedback::from_reviews(many).rules["a"].multiplier, MIN_MULTIPLIER);
+    }
+
+    #[test]
+    fn apply_scales_configured_or_default_weights() {
+        let a = rules(&["rust.errors.zero_unwrap", "custom.rule"]);
+        let feedback = Feedback::from_reviews([Review { rules: &a, false_positive: true }]);
+        let mut weights = HashMap::from([("custom.rule".to_string(), 3.0)]);
+        feedback.apply(&mut weights);
+        assert!((weights["custom.rule"] - 2.01).abs() < 1e-9, "{weights:?}");
+        let default = DefaultHeuristics.weight("rust.errors.zero_unwrap");
+        assert!((weights["rust.errors.zero_unwrap"] - default * 0.67).abs() < 1e-9);
+    }
+
+    #[test]
+    fn saved_feedback_loads_back() {
+        let dir = tempfile::tempdir().unwrap();
+        assert!(Feedback::load(dir.path()).unwrap().is_empty());
+
+        let a = rules(&["go.naming.long_names"]);
+        let feedback = Feedback::from_reviews([Review { rules: &a, false_positive: true }]);
+        feedback.save(dir.path()).unwrap();
+        assert_eq!(Feedback::load(dir.path()).unwrap(), feedback);
+
+        std::fs::write(dir.path().join(FEEDBACK_FILE), "[rules.x]\nmultiplier = \"half\"\n").unwrap();
+        assert!(Feedback::load(dir.path()).is_err());
+    }
+}
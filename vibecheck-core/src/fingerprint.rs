@@ -0,0 +1,231 @@
+//! Repository stylometric fingerprint.
+//!
+//! The per-file detectors compare code with what models write in general.
+//! A file dropped in from an assistant is often clearest against the
+//! project's own style instead: suddenly commented in full sentences, with
+//! identifiers twice the usual length, in one long function.  This module
+//! profiles every file of a scan, takes the repository's typical profile
+//! per language as the baseline, and flags files far from it on several
+//! features at once.
+//!
+//! Like [`crate::template_reuse`], it needs the whole scan, so callers run
+//! [`outliers`] once the files are known.
+
+use std::collections::HashMap;
+use std::path::PathBuf;
+
+use crate::language::detect_language;
+use crate::segments;
+
+/// Names of the profile features, in [`profile`] order.
+pub const FEATURE_NAMES: [&str; FEATURES] = [
+    "comment density",
+    "identifier length",
+    "line length",
+    "blank lines",
+    "trailing comments",
+    "block length",
+];
+
+const FEATURES: usize = segments::FEATURES + 1;
+
+/// Files with fewer non-blank lines carry too little style to profile.
+const MIN_FILE_LINES: usize = 20;
+
+/// Profiled files of a language needed before its baseline means anything.
+const MIN_BASELINE_FILES: usize = 8;
+
+/// Robust z-score beyond which a feature counts as deviating.
+const MIN_DEVIATION: f64 = 3.0;
+
+/// Deviating features a file needs to be flagged; one odd feature is
+/// usually a legitimately odd file (a table of constants, a test fixture).
+const MIN_DEVIATING_FEATURES: usize = 2;
+
+/// Scales a median absolute deviation to a standard deviation under
+/// normality.
+const MAD_SCALE: f64 = 1.4826;
+
+/// One feature of an outlier and how far it sits from the baseline.
+#[derive(Debug, Clone)]
+pub struct Deviation {
+    /// One of [`FEATURE_NAMES`].
+    pub feature: &'static str,
+    /// Robust z-score: positive when the file is above the repository's
+    /// median.
+    pub z: f64,
+}
+
+/// A file whose style stands out from the rest of its repository.
+#[derive(Debug, Clone)]
+pub struct StyleOutlier {
+    pub path: PathBuf,
+    /// Root-mean-square robust z-score over all features.
+    pub distance: f64,
+    /// The deviating features, largest first.
+    pub deviations: Vec<Deviation>,
+}
+
+/// Files whose style differs strongly from the other files of their
+/// language in `files` (path and source pairs), most distant first.
+pub fn outliers(files: &[(PathBuf, String)]) -> Vec<StyleOutlier> {
+    let mut by_language: HashMap<String, Vec<(&PathBuf, [f64; FEATURES])>> = HashMap::new();
+    for (path, source) in files {
+        let language = match detect_language(path) {
+            Some(lang) => format!("{lang:?}"),
+            None => path.extension().map(|e| e.to_string_lossy().to_lowercase()).unwrap_or_default(),
+        };
+        if let Some(p) = profile(source) {
+            by_language.entry(language).or_default().push((path, p));
+        }
+    }
+
+    let mut found = Vec::new();
+    for profiles in by_language.values().filter(|p| p.len() >= MIN_BASELINE_FILES) {
+        let baseline: Vec<Option<(f64, f64)>> = (0..FEATURES)
+            .map(|f| center_and_spread(profiles.iter().map(|(_, p)| p[f]).collect()))
+            .collect();
+        for (path, p) in profiles {
+            let z: Vec<(usize, f64)> = baseline
+                .iter()
+                .enumerate()
+                .filter_map(|(f, b)| b.map(|(center, spread)| (f, (p[f] - center) / spread)))
+                .collect();
+            let mut deviations: Vec<Deviation> = z
+                .iter()
+                .filter(|(_, z)| z.abs() >= MIN_DEVIATION)
+                .map(|&(f, z)| Deviation { feature: FEATURE_NAMES[f], z })
+                .collect();
+            if deviations.len() < MIN_DEVIATING_FEATURES {
+                continue;
+            }
+            deviations.sort_by(|a, b| b.z.abs().total_cmp(&a.z.abs()));
+            let distance = (z.iter().map(|(_, z)| z * z).sum::<f64>() / z.len() as f64).sqrt();
+            found.push(StyleOutlier { path: (*path).clone(), distance, deviations });
+        }
+    }
+    found.sort_by(|a, b| b.distance.total_cmp(&a.distance).then_with(|| a.path.cmp(&b.path)));
+    found
+}
+
+/// Whole-file style profile: the [`segments`] unit profile over all lines,
+/// plus the log of the mean top-level block length (on a log scale, since
+/// block lengths vary by orders of magnitude between files of one repo).
+/// `None` for files under [`MIN_FILE_LINES`].
+fn profile(source: &str) -> Option<[f64; FEATURES]> {
+    let lines: Vec<&str> = source.lines().collect();
+    if segments::nonblank(&lines) < MIN_FILE_LINES {
+        return None;
+    }
+    let lengths: Vec<f64> = segments::units(&lines)
+        .into_iter()
+        .map(|u| segments::nonblank(&lines[u]) as f64)
+        .collect();
+    let mean = lengths.iter().sum::<f64>() / lengths.len() as f64;
+
+    let mut features = [0.0; FEATURES];
+    features[..segments::FEATURES].copy_from_slice(&segments::profile(&lines));
+    features[segments::FEATURES] = mean.ln();
+    Some(features)
+}
+
+/// Median and robust spread of `values`: the scaled median absolute
+/// deviation, or the scaled mean absolute deviation when more than half the
+/// files share one value.  `None` when every value is the same.
+fn center_and_spread(mut values: Vec<f64>) -> Option<(f64, f64)> {
+    let center = median(&mut values);
+    let mut deviations: Vec<f64> = values.iter().map(|v| (v - center).abs()).collect();
+    let mad = median(&mut deviations) * MAD_SCALE;
+    if mad > 1e-9 {
+        return Some((center, mad));
+    }
+    // 1.2533 = sqrt(pi / 2), the same correction for the mean deviation.
+    let mean_dev = deviations.iter().sum::<f64>() / deviations.len() as f64 * 1.2533;
+    (mean_dev > 1e-9).then_some((center, mean_dev))
+}
+
+fn median(values: &mut [f64]) -> f64 {
+    values.sort_by(f64::total_cmp);
+    let mid = values.len() / 2;
+    if values.len() % 2 == 0 {
+        (values[mid - 1] + values[mid]) / 2.0
+    } else {
+        values[mid]
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    /// Terse, sparsely commented helpers; `seed` varies them a little.
+    fn house_style(seed: usize) -> String {
+        let mut out = String::from("package util\n\n");
+        for i in 0..6 + seed % 3 {
+            if (i + seed) % 4 == 0 {
+                out.push_str("// fast path\n");
+            }
+            out.push_str(&format!(
+                "func f{i}(a, b int) int {{\n\tx := a ^ b\n\tif x > {seed} {{\n\t\treturn x\n\t}}\n\treturn a{}\n}}\n\n",
+                " + b".repeat(i % 2)
+            ));
+        }
+        out
+    }
+
+    /// Long, fully documented functions with long identifiers.
+    fn assistant_style() -> String {
+        let mut out = String::from("package util\n\n");
+        for i in 0..3 {
+            out.push_str(&format!(
+                "// ComputeNormalizedAccumulatedValue{i} calculates the normalized accumulated value\n\
+                 // for the provided configuration, ensuring that every intermediate result is\n\
+                 // validated before it is combined with the running total.\n\
+                 func ComputeNormalizedAccumulatedValue{i}(configurationOptions Options) int {{\n\
+                 \t// Initialize the running total that accumulates every validated value.\n\
+                 \taccumulatedRunningTotal := 0\n\
+                 \t// Iterate over every configured value and add it to the running total.\n\
+                 \tfor _, configuredInputValue := range configurationOptions.Values {{\n\
+                 \t\taccumulatedRunningTotal += configuredInputValue\n\
+                 \t}}\n\
+                 \t// Return the normalized accumulated value to the caller.\n\
+                 \treturn accumulatedRunningTotal / len(configurationOptions.Values)\n\
+                 }}\n\n"
+            ));
+        }
+        out
+    }
+
+    fn go_file(name: &str, source: String) -> (PathBuf, String) {
+        (PathBuf::from(format!("pkg/{name}.go")), source)
+    }
+
+    #[test]
+    fn flags_file_unlike_the_rest_of_the_repo() {
+        let mut files: Vec<_> = (0..10).map(|i| go_file(&format!("h{i}"), house_style(i))).collect();
+        files.push(go_file("dropped_in", assistant_style()));
+        let found = outliers(&files);
+        assert_eq!(found.len(), 1, "{found:?}");
+        assert_eq!(found[0].path, PathBuf::from("pkg/dropped_in.go"));
+        let z = |feature| found[0].deviations.iter().find(|d| d.feature == feature).map(|d| d.z);
+        assert!(z("comment density").is_some_and(|z| z > 0.0), "{found:?}");
+        assert!(z("identifier length").is_some_and(|z| z > 0.0), "{found:?}");
+    }
+
+    #[test]
+    fn needs_a_baseline_per_language() {
+        // Too few files of the outlier's language to judge it.
+        let mut files: Vec<_> = (0..10).map(|i| go_file(&format!("h{i}"), house_style(i))).collect();
+        files.push((PathBuf::from("pkg/dropped_in.rs"), assistant_style()));
+        assert!(outliers(&files).is_empty());
+    }
+
+    #[test]
+    fn spread_falls_back_when_most_values_tie() {
+        assert_eq!(center_and_spread(vec![1.0, 2.0, 3.0]), Some((2.0, MAD_SCALE)));
+        let (center, spread) = center_and_spread(vec![0.0, 0.0, 0.0, 1.0]).unwrap();
+        assert_eq!(center, 0.0);
+        assert!(spread > 0.0);
+        assert_eq!(center_and_spread(vec![5.0; 4]), None);
+    }
+}
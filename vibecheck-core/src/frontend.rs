@@ -0,0 +1,345 @@
+//! A uniform view of source files across tree-sitter grammars.
+//!
+//! Every language is reduced to the same three token streams — comments,
+//! identifiers and function boundaries — by classifying the grammar's node
+//! kinds.  Doc comments are picked out of the comment stream by prefix.  A [`LanguageFrontend`] only has to say which kinds are which;
+//! parsing and walking the tree is shared.  Built-in languages are rows in
+//! [`BUILTIN_FRONTENDS`] and runtime language packs implement the same trait,
+//! so supporting a new language is a matter of registering its grammar.
+//!
+//! The language-agnostic metrics computed by [`generic_metrics`] are what
+//! language packs are scored with (the `pack_cst` signals).
+
+use std::collections::HashMap;
+use std::path::Path;
+
+use tree_sitter::Tree;
+
+use crate::language::{detect_language, get_ts_language_for_path, Language};
+use crate::language_pack::LanguagePack;
+
+/// A run of source text covered by one syntax node.  Lines are 1-based and
+/// inclusive.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Span {
+    pub start_line: usize,
+    pub end_line: usize,
+    pub text: String,
+}
+
+impl Span {
+    /// Number of lines the span covers.
+    pub fn line_count(&self) -> usize {
+        self.end_line - self.start_line + 1
+    }
+}
+
+/// The token streams of one parsed file, each in source order.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct SourceTokens {
+    pub comments: Vec<Span>,
+    /// The comments that are doc comments (`///`, `/** */`, ...); each is
+    /// also in `comments`.
+    pub doc_comments: Vec<Span>,
+    pub identifiers: Vec<Span>,
+    /// Outermost function-like nodes; nested closures and inner functions
+    /// are part of their enclosing function.
+    pub functions: Vec<Span>,
+}
+
+/// A tree-sitter grammar plus the node kinds that map it onto
+/// [`SourceTokens`].
+pub trait LanguageFrontend {
+    /// Language name, e.g. `"rust"`.
+    fn name(&self) -> &str;
+    /// The tree-sitter grammar used to parse files in this language.
+    fn grammar(&self) -> tree_sitter::Language;
+    fn is_comment(&self, kind: &str) -> bool;
+    fn is_identifier(&self, kind: &str) -> bool;
+    fn is_function(&self, kind: &str) -> bool;
+
+    /// Whether the comment `text` documents the item that follows it.
+    /// Languages without doc-comment syntax keep the default.
+    fn is_doc_comment(&self, _text: &str) -> bool {
+        false
+    }
+
+    /// Parse `source` and extract its tokens.  Returns `None` if the grammar
+    /// cannot be loaded or the parse fails.
+    fn tokenize(&self, source: &str) -> Option<SourceTokens> {
+        let mut parser = tree_sitter::Parser::new();
+        parser.set_language(&self.grammar()).ok()?;
+        let tree = parser.parse(source, None)?;
+        Some(self.tokens(&tree, source))
+    }
+
+    /// Extract tokens from a tree already parsed with [`Self::grammar`].
+    fn tokens(&self, tree: &Tree, source: &str) -> SourceTokens {
+        let mut tokens = SourceTokens::default();
+        // (node, inside a function already recorded)
+        let mut stack = vec![(tree.root_node(), false)];
+        while let Some((node, in_fn)) = stack.pop() {
+            let kind = node.kind();
+            if self.is_comment(kind) {
+                let comment = span(node, source);
+                if self.is_doc_comment(&comment.text) {
+                    tokens.doc_comments.push(comment.clone());
+                }
+                tokens.comments.push(comment);
+                continue;
+            }
+            if self.is_identifier(kind) {
+                tokens.identifiers.push(span(node, source));
+                continue;
+            }
+            let is_fn = self.is_function(kind);
+            if is_fn && !in_fn {
+                tokens.functions.push(span(node, source));
+            }
+            let mut cursor = node.walk();
+            let children: Vec<_> = node.children(&mut cursor).collect();
+            // Reverse so the stack pops children in source order.
+            for child in children.into_iter().rev() {
+                stack.push((child, in_fn || is_fn));
+            }
+        }
+        tokens
+    }
+}
+
+fn span(node: tree_sitter::Node<'_>, source: &str) -> Span {
+    Span {
+        start_line: node.start_position().row + 1,
+        end_line: node.end_position().row + 1,
+        text: source[node.byte_range()].to_string(),
+    }
+}
+
+// ---------------------------------------------------------------------------
+// Built-in languages
+// ---------------------------------------------------------------------------
+
+/// A compiled-in grammar and its node-kind table.
+pub struct BuiltinFrontend {
+    pub language: Language,
+    name: &'static str,
+    grammar: fn() -> tree_sitter::Language,
+    comment_kinds: &'static [&'static str],
+    identifier_kinds: &'static [&'static str],
+    function_kinds: &'static [&'static str],
+    doc_comment_prefixes: &'static [&'static str],
+}
+
+impl LanguageFrontend for BuiltinFrontend {
+    fn name(&self) -> &str {
+        self.name
+    }
+
+    fn grammar(&self) -> tree_sitter::Language {
+        (self.grammar)()
+    }
+
+    fn is_comment(&self, kind: &str) -> bool {
+        self.comment_kinds.contains(&kind)
+    }
+
+    fn is_identifier(&self, kind: &str) -> bool {
+        self.identifier_kinds.contains(&kind)
+    }
+
+    fn is_function(&self, kind: &str) -> bool {
+        self.function_kinds.contains(&kind)
+    }
+
+    fn is_doc_comment(&self, text: &str) -> bool {
+        is_doc_comment(text, self.doc_comment_prefixes)
+    }
+}
+
+/// Whether `text` starts with one of `prefixes`.  A `/**/` or `/***` block
+/// is decoration, not a doc comment.
+pub(crate) fn is_doc_comment<S: AsRef<str>>(text: &str, prefixes: &[S]) -> bool {
+    if text.starts_with("/**/") || text.starts_with("/***") || text.starts_with("////") {
+        return false;
+    }
+    prefixes.iter().any(|p| text.starts_with(p.as_ref()))
+}
+
+/// Every compiled-in frontend.  TypeScript shares the JavaScript node kinds;
+/// only its grammar differs.  Python docstrings are string literals, not
+/// comments, and Go doc comments are ordinary `//` comments, so neither has
+/// doc-comment prefixes.
+pub static BUILTIN_FRONTENDS: &[BuiltinFrontend] = &[
+    BuiltinFrontend {
+        language: Language::Rust,
+        name: "rust",
+        grammar: || tree_sitter_rust::LANGUAGE.into(),
+        comment_kinds: &["line_comment", "block_comment"],
+        identifier_kinds: &["identifier", "field_identifier", "type_identifier"],
+        function_kinds: &["function_item", "closure_expression"],
+        doc_comment_prefixes: &["///", "//!", "/**", "/*!"],
+    },
+    BuiltinFrontend {
+        language: Language::Python,
+        name: "python",
+        grammar: || tree_sitter_python::LANGUAGE.into(),
+        comment_kinds: &["comment"],
+        identifier_kinds: &["identifier"],
+        function_kinds: &["function_definition", "lambda"],
+        doc_comment_prefixes: &[],
+    },
+    BuiltinFrontend {
+        language: Language::JavaScript,
+        name: "javascript",
+        grammar: || tree_sitter_javascript::LANGUAGE.into(),
+        comment_kinds: &["comment"],
+        identifier_kinds: &["identifier", "property_identifier", "shorthand_property_identifier"],
+        function_kinds: &["function_declaration", "function_expression", "arrow_function", "method_definition"],
+        doc_comment_prefixes: &["/**"],
+    },
+    BuiltinFrontend {
+        language: Language::JavaScript,
+        name: "typescript",
+        grammar: || tree_sitter_typescript::LANGUAGE_TYPESCRIPT.into(),
+        comment_kinds: &["comment"],
+        identifier_kinds: &["identifier", "property_identifier", "shorthand_property_identifier", "type_identifier"],
+        function_kinds: &["function_declaration", "function_expression", "arrow_function", "method_definition"],
+        doc_comment_prefixes: &["/**"],
+    },
+    BuiltinFrontend {
+        language: Language::JavaScript,
+        name: "tsx",
+        grammar: || tree_sitter_typescript::LANGUAGE_TSX.into(),
+        comment_kinds: &["comment"],
+        identifier_kinds: &["identifier", "property_identifier", "shorthand_property_identifier", "type_identifier"],
+        function_kinds: &["function_declaration", "function_expression", "arrow_function", "method_definition"],
+        doc_comment_prefixes: &["/**"],
+    },
+    BuiltinFrontend {
+        language: Language::Go,
+        name: "go",
+        grammar: || tree_sitter_go::LANGUAGE.into(),
+        comment_kinds: &["comment"],
+        identifier_kinds: &["identifier", "field_identifier", "type_identifier"],
+        function_kinds: &["function_declaration", "method_declaration", "func_literal"],
+        doc_comment_prefixes: &[],
+    },
+];
+
+/// The frontend for the file at `path`: a built-in language when one
+/// matches, otherwise the first of `packs` that handles the extension.
+pub fn frontend_for<'a>(path: &Path, packs: &'a [LanguagePack]) -> Option<&'a dyn LanguageFrontend> {
+    if let (Some(lang), Some(grammar)) = (detect_language(path), get_ts_language_for_path(path)) {
+        return BUILTIN_FRONTENDS
+            .iter()
+            .find(|f| f.language == lang && f.grammar() == grammar)
+            .map(|f| f as &dyn LanguageFrontend);
+    }
+    packs.iter().find(|p| p.matches(path)).map(|p| p as &dyn LanguageFrontend)
+}
+
+// ---------------------------------------------------------------------------
+// Language-agnostic metrics
+// ---------------------------------------------------------------------------
+
+/// Metrics computable from the token streams alone.  Metric names match the
+/// `pack_cst` rules in `heuristics.toml`.
+pub fn generic_metrics(tokens: &SourceTokens, source: &str) -> HashMap<String, f64> {
+    let mut metrics = HashMap::new();
+
+    let code_lines = source.lines().filter(|l| !l.trim().is_empty()).count();
+    let comment_lines: usize = tokens.comments.iter().map(Span::line_count).sum();
+    if code_lines > 0 {
+        metrics.insert("comment_line_ratio".into(), comment_lines as f64 / code_lines as f64);
+    }
+
+    if comment_lines > 0 {
+        let doc_lines: usize = tokens.doc_comments.iter().map(Span::line_count).sum();
+        metrics.insert("doc_comment_ratio".into(), doc_lines as f64 / comment_lines as f64);
+    }
+
+    if !tokens.identifiers.is_empty() {
+        let total: usize = tokens.identifiers.iter().map(|s| s.text.chars().count()).sum();
+        metrics.insert("avg_identifier_length".into(), total as f64 / tokens.identifiers.len() as f64);
+    }
+
+    metrics.insert("fn_count".into(), tokens.functions.len() as f64);
+    if !tokens.functions.is_empty() {
+        let total: usize = tokens.functions.iter().map(Span::line_count).sum();
+        metrics.insert("avg_fn_length".into(), total as f64 / tokens.functions.len() as f64);
+    }
+
+    metrics
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn every_builtin_extension_has_a_frontend() {
+        for ext in crate::language::SUPPORTED_EXTENSIONS {
+            let path = format!("file.{ext}");
+            assert!(frontend_for(Path::new(&path), &[]).is_some(), "no frontend for .{ext}");
+        }
+        assert!(frontend_for(Path::new("Makefile"), &[]).is_none());
+    }
+
+    #[test]
+    fn typescript_gets_its_own_frontend() {
+        assert_eq!(frontend_for(Path::new("a.ts"), &[]).unwrap().name(), "typescript");
+        assert_eq!(frontend_for(Path::new("a.tsx"), &[]).unwrap().name(), "tsx");
+        assert_eq!(frontend_for(Path::new("a.js"), &[]).unwrap().name(), "javascript");
+    }
+
+    #[test]
+    fn rust_tokens_are_uniform() {
+        let source = "// top\nfn add(a: u32) -> u32 {\n    let f = |x| x + 1; // inc\n    f(a)\n}\n";
+        let tokens = frontend_for(Path::new("a.rs"), &[]).unwrap().tokenize(source).unwrap();
+        let comments: Vec<&str> = tokens.comments.iter().map(|s| s.text.as_str()).collect();
+        assert_eq!(comments, vec!["// top", "// inc"]);
+        assert_eq!(tokens.functions.len(), 1, "the closure belongs to `add`");
+        assert_eq!((tokens.functions[0].start_line, tokens.functions[0].end_line), (2, 5));
+        assert!(tokens.identifiers.iter().any(|s| s.text == "add"));
+    }
+
+    #[test]
+    fn doc_comments_are_picked_out_by_prefix() {
+        let source = "//! crate\n/// Adds.\nfn add() {}\n// plain\n//// banner\n/**/\n";
+        let tokens = frontend_for(Path::new("a.rs"), &[]).unwrap().tokenize(source).unwrap();
+        let docs: Vec<&str> = tokens.doc_comments.iter().map(|s| s.text.trim_end()).collect();
+        assert_eq!(docs, vec!["//! crate", "/// Adds."]);
+        assert_eq!(tokens.comments.len(), 5);
+
+        let js = frontend_for(Path::new("a.js"), &[]).unwrap();
+        assert!(js.is_doc_comment("/** @param {number} x */"));
+        assert!(!js.is_doc_comment("/* note */"));
+        assert!(!frontend_for(Path::new("a.go"), &[]).unwrap().is_doc_comment("// Get returns"));
+    }
+
+    #[test]
+    fn python_tokens_are_uniform() {
+        let source = "# note\ndef greet(name):\n    return name\n";
+        let tokens = frontend_for(Path::new("a.py"), &[]).unwrap().tokenize(source).unwrap();
+        assert_eq!(tokens.comments.len(), 1);
+        assert_eq!(tokens.functions.len(), 1);
+        assert!(tokens.identifiers.iter().any(|s| s.text == "greet"));
+    }
+
+    #[test]
+    fn generic_metrics_from_tokens() {
+        let line = |n: usize| Span { start_line: n, end_line: n, text: String::new() };
+        let tokens = SourceTokens {
+            comments: vec![line(1)],
+            doc_comments: vec![],
+            identifiers: vec![Span { start_line: 2, end_line: 2, text: "a".into() }],
+            functions: vec![Span { start_line: 2, end_line: 4, text: String::new() }],
+        };
+        let metrics = generic_metrics(&tokens, "// c\nfn a() {\n}\n\n");
+        assert_eq!(metrics["fn_count"], 1.0);
+        assert_eq!(metrics["doc_comment_ratio"], 0.0);
+        assert_eq!(metrics["avg_identifier_length"], 1.0);
+        assert_eq!(metrics["avg_fn_length"], 3.0);
+        assert!((metrics["comment_line_ratio"] - 1.0 / 3.0).abs() < 1e-9);
+    }
+}
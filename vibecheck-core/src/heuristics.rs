@@ -20,7 +20,8 @@ use std::sync::OnceLock;
 use serde::Deserialize;
 
 use crate::language::Language;
-use crate::report::ModelFamily;
+use crate::policy::SeverityPolicy;
+use crate::report::{ModelFamily, Severity, Signal};
 
 // ---------------------------------------------------------------------------
 // HeuristicLanguage — type-safe language scope for heuristic specs
@@ -50,6 +51,12 @@ pub enum HeuristicLanguage {
     JsCst,
     /// Go CST analyzer signals.
     GoCst,
+    /// Language-agnostic CST signals for runtime-loaded language packs.
+    PackCst,
+    /// Commit-message signals, scored apart from code.
+    Commit,
+    /// Prose signals for Markdown / reStructuredText documents.
+    Docs,
     /// Language-agnostic signals.
     All,
 }
@@ -65,6 +72,9 @@ impl fmt::Display for HeuristicLanguage {
             HeuristicLanguage::PythonCst => "python_cst",
             HeuristicLanguage::JsCst     => "js_cst",
             HeuristicLanguage::GoCst     => "go_cst",
+            HeuristicLanguage::PackCst   => "pack_cst",
+            HeuristicLanguage::Commit    => "commit",
+            HeuristicLanguage::Docs      => "docs",
             HeuristicLanguage::All       => "all",
         })
     }
@@ -110,7 +120,8 @@ pub struct HeuristicSpec {
     pub description: &'static str,
     /// Primary attribution family this signal points toward.
     pub family: ModelFamily,
-    /// Default weight (positive = toward `family`, 0.0 = disabled).
+    /// Default weight (positive = toward `family`, 0.0 = disabled,
+    /// negative = against every AI family).
     pub default_weight: f64,
     /// Metric name that triggers this signal (CST metric-based signals only).
     pub metric: Option<&'static str>,
@@ -219,6 +230,13 @@ pub trait HeuristicsProvider: Send + Sync {
     fn is_enabled(&self, id: &str) -> bool {
         self.weight(id) != 0.0
     }
+
+    /// Grade a signal whose weight is already the effective one.
+    ///
+    /// Defaults to the thresholds of [`SeverityPolicy::default`].
+    fn severity(&self, signal: &Signal) -> Severity {
+        SeverityPolicy::default().grade(signal)
+    }
 }
 
 // ---------------------------------------------------------------------------
@@ -236,6 +254,7 @@ impl HeuristicsProvider for DefaultHeuristics {
             .iter()
             .find(|h| h.id == id)
             .map(|h| h.default_weight)
+            .or_else(|| crate::plugin::rule_weight(id))
             .unwrap_or(1.0) // unknown signals pass through at weight 1.0
     }
 }
@@ -267,13 +286,21 @@ impl HeuristicsProvider for InertHeuristics {
 /// [`DefaultHeuristics`].
 pub struct ConfiguredHeuristics {
     overrides: HashMap<String, f64>,
+    severity: SeverityPolicy,
 }
 
 impl ConfiguredHeuristics {
     /// Build from a map of signal-ID → weight overrides (e.g. parsed from
     /// the `[heuristics]` TOML section).
     pub fn from_config(overrides: HashMap<String, f64>) -> Self {
-        Self { overrides }
+        Self { overrides, severity: SeverityPolicy::default() }
+    }
+
+    /// Grade signals with `severity` (the policy's `[severity]` settings)
+    /// instead of the default thresholds.
+    pub fn with_severity(mut self, severity: SeverityPolicy) -> Self {
+        self.severity = severity;
+        self
     }
 
     /// Returns `true` if no overrides are configured (fast path: use defaults).
@@ -289,6 +316,10 @@ impl HeuristicsProvider for ConfiguredHeuristics {
             .copied()
             .unwrap_or_else(|| DefaultHeuristics.weight(id))
     }
+
+    fn severity(&self, signal: &Signal) -> Severity {
+        self.severity.grade(signal)
+    }
 }
 
 // ---------------------------------------------------------------------------
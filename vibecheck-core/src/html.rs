@@ -0,0 +1,750 @@
+//! Self-contained HTML report.
+//!
+//! One file with inline CSS and no scripts, fonts or other assets, so it
+//! can be attached to a CI run or mailed to a reviewer and opened in any
+//! browser.  It holds a summary of the scan, a per-file breakdown of the
+//! family scores and of how much each detector contributed, the signals
+//! with links to the lines behind them, and the source of each file,
+//! syntax-highlighted, with those lines marked.  Sections are `<details>`
+//! elements, collapsed until clicked.
+//!
+//! A confidence-band chart places every file's calibrated AI probability
+//! on the authorship bands and, when the calibration was fitted by `vibecheck
+//! tune`, against the probabilities of the human and AI-written files it
+//! was fitted on, so that a 0.73 can be read against the files behind it.
+
+use std::collections::{BTreeMap, HashMap};
+use std::fmt::Write;
+
+use crate::calibration::{Calibration, Distribution, DISTRIBUTION_BINS};
+use crate::language::{detect_language, Language};
+use crate::notebook;
+use crate::remediation;
+use crate::report::{ModelFamily, Report, Signal};
+
+const STYLE: &str = "
+body { background: #0d1117; color: #e6edf3; font: 14px/1.5 -apple-system, 'Segoe UI', Helvetica, Arial, sans-serif; margin: 0; padding: 24px 32px; }
+h1 { font-size: 22px; margin: 0 0 4px; }
+h2 { font-size: 16px; margin: 0; display: inline; }
+h3 { font-size: 13px; text-transform: uppercase; letter-spacing: .05em; color: #8b949e; margin: 16px 0 6px; }
+a { color: #79c0ff; text-decoration: none; }
+a:hover { text-decoration: underline; }
+.muted { color: #8b949e; }
+.card { background: #161b22; border: 1px solid #30363d; border-radius: 8px; padding: 12px 16px; margin: 16px 0; }
+details.file > summary { cursor: pointer; list-style: none; }
+details.file > summary::-webkit-details-marker { display: none; }
+details.file > summary::before { content: '▸ '; color: #8b949e; }
+details.file[open] > summary::before { content: '▾ '; }
+details.source > summary { cursor: pointer; color: #8b949e; margin-top: 12px; }
+.badge { display: inline-block; border-radius: 10px; padding: 0 8px; font-size: 12px; font-weight: 600; color: #0d1117; margin-left: 8px; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 3px 8px; border-bottom: 1px solid #21262d; vertical-align: top; }
+th { color: #8b949e; font-weight: 600; font-size: 12px; }
+td.num, th.num { text-align: right; font-variant-numeric: tabular-nums; }
+.stack { display: flex; height: 14px; border-radius: 4px; overflow: hidden; margin: 8px 0; }
+.legend span { margin-right: 14px; font-size: 12px; }
+.legend i { display: inline-block; width: 10px; height: 10px; border-radius: 2px; margin-right: 4px; }
+.bars { display: grid; grid-template-columns: 12ch 1fr 5ch; gap: 2px 8px; align-items: center; font-size: 12px; }
+.bar { height: 10px; border-radius: 2px; }
+.diverging { display: grid; grid-template-columns: 14ch 1fr 1fr 14ch; gap: 2px 0; align-items: center; font-size: 12px; }
+.diverging .human { display: flex; justify-content: flex-end; border-right: 1px solid #484f58; }
+.diverging .ai { border-left: 1px solid #484f58; }
+.diverging .num { padding: 0 8px; color: #8b949e; }
+.corpus { display: grid; grid-template-columns: repeat(20, 1fr); gap: 0 2px; }
+.corpus div { display: flex; height: 40px; }
+.corpus .ai { align-items: flex-end; }
+.corpus .human { align-items: flex-start; }
+.corpus .bar { width: 100%; border-radius: 0; }
+.bands { position: relative; height: 18px; margin: 2px 0; border-radius: 4px; overflow: hidden; }
+.bands .band { position: absolute; top: 0; bottom: 0; }
+.bands .pin { position: absolute; top: 2px; bottom: 2px; width: 3px; margin-left: -1px; background: #e6edf3; border-radius: 1px; }
+.axis { display: flex; justify-content: space-between; font-size: 11px; color: #8b949e; }
+.pos { color: #7ee787; }
+.neg { color: #f85149; }
+pre.code { background: #0d1117; border: 1px solid #30363d; border-radius: 6px; padding: 8px 0; overflow-x: auto; font: 12px/1.45 ui-monospace, SFMono-Regular, 'SF Mono', Menlo, Consolas, monospace; margin: 8px 0 0; }
+pre.code div { padding: 0 12px 0 0; white-space: pre; }
+pre.code .ln { display: inline-block; width: 5ch; text-align: right; color: #484f58; margin-right: 12px; user-select: none; }
+pre.code div.flag { background: rgba(248, 81, 73, .15); box-shadow: inset 3px 0 #f85149; }
+pre.code div:target { background: rgba(121, 192, 255, .2); }
+.k { color: #ff7b72; }
+.s { color: #a5d6ff; }
+.c { color: #8b949e; font-style: italic; }
+.n { color: #79c0ff; }
+";
+
+/// AI-written files in the confidence-band chart.
+const AI_COLOR: &str = "#f85149";
+
+/// Render `reports` as one HTML document.
+///
+/// `sources[i]` is the source of `reports[i]`, shown with its flagged lines
+/// marked; `None` leaves the source view out for that file.  With
+/// `remediation`, each file also lists the suggestions from
+/// [`remediation::for_report`].  `calibration` is the one the reports'
+/// probabilities came from (see [`crate::ignore_rules::IgnoreConfig::calibration`]),
+/// for the confidence-band chart.
+pub fn format_html(reports: &[Report], sources: &[Option<String>], remediation: bool, calibration: &Calibration) -> String {
+    let mut out = String::new();
+    out.push_str("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n");
+    out.push_str("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n");
+    out.push_str("<title>vibecheck report</title>\n");
+    let _ = writeln!(out, "<style>{STYLE}</style>\n</head>\n<body>");
+
+    summary(&mut out, reports, calibration);
+
+    for (i, report) in reports.iter().enumerate() {
+        let source = sources.get(i).and_then(|s| s.as_deref());
+        file_section(&mut out, i, report, source, remediation, calibration.distribution.as_ref());
+    }
+
+    out.push_str("</body>\n</html>\n");
+    out
+}
+
+// ---------------------------------------------------------------------------
+// Summary
+// ---------------------------------------------------------------------------
+
+fn summary(out: &mut String, reports: &[Report], calibration: &Calibration) {
+    let lines: usize = reports.iter().map(|r| r.metadata.lines_of_code).sum();
+    let flagged = reports.iter().filter(|r| is_ai(r)).count();
+
+    out.push_str("<h1>vibecheck report</h1>\n");
+    let _ = writeln!(
+        out,
+        "<div class=\"muted\">{} file{} · {lines} lines · {flagged} attributed to AI</div>",
+        reports.len(),
+        if reports.len() == 1 { "" } else { "s" },
+    );
+
+    // Share of lines by verdict.
+    let mut by_family: BTreeMap<ModelFamily, usize> = BTreeMap::new();
+    for r in reports.iter().filter(|r| r.attribution.has_sufficient_data()) {
+        *by_family.entry(r.attribution.primary).or_insert(0) += r.metadata.lines_of_code.max(1);
+    }
+    let total: usize = by_family.values().sum();
+    out.push_str("<div class=\"card\">\n<h3>Lines by verdict</h3>\n");
+    if total > 0 {
+        out.push_str("<div class=\"stack\">");
+        for (family, n) in &by_family {
+            let _ = write!(
+                out,
+                "<div style=\"width:{:.2}%;background:{}\" title=\"{family}: {n} lines\"></div>",
+                *n as f64 * 100.0 / total as f64,
+                family.svg_color(),
+            );
+        }
+        out.push_str("</div>\n<div class=\"legend\">");
+        for (family, n) in &by_family {
+            let _ = write!(
+                out,
+                "<span><i style=\"background:{}\"></i>{family} {:.0}%</span>",
+                family.svg_color(),
+                *n as f64 * 100.0 / total as f64,
+            );
+        }
+        out.push_str("</div>\n");
+    } else {
+        out.push_str("<div class=\"muted\">No file had enough signal data for a verdict.</div>\n");
+    }
+
+    out.push_str("<h3>Confidence bands</h3>\n");
+    confidence_bands(out, reports, calibration);
+
+    out.push_str("<h3>Detectors across all files</h3>\n");
+    detector_chart(out, reports.iter().flat_map(|r| &r.signals));
+
+    out.push_str("<h3>Files</h3>\n<table>\n<tr><th>File</th><th>Verdict</th><th class=\"num\">Confidence</th>");
+    out.push_str("<th class=\"num\">AI probability</th><th class=\"num\">Lines</th><th class=\"num\">Signals</th></tr>\n");
+    for (i, r) in reports.iter().enumerate() {
+        let _ = writeln!(
+            out,
+            "<tr><td><a href=\"#f{i}\">{}</a></td><td>{}</td><td class=\"num\">{}</td><td class=\"num\">{}</td>\
+             <td class=\"num\">{}</td><td class=\"num\">{}</td></tr>",
+            esc(&display_path(r)),
+            verdict_badge(r),
+            if r.attribution.has_sufficient_data() {
+                format!("{:.0}%", r.attribution.confidence * 100.0)
+            } else {
+                "—".into()
+            },
+            r.attribution.ai_probability.map_or("—".into(), |p| format!("{p:.2}")),
+            r.metadata.lines_of_code,
+            r.metadata.signal_count,
+        );
+    }
+    out.push_str("</table>\n</div>\n");
+}
+
+// ---------------------------------------------------------------------------
+// Per-file sections
+// ---------------------------------------------------------------------------
+
+fn file_section(
+    out: &mut String,
+    i: usize,
+    report: &Report,
+    source: Option<&str>,
+    remediation: bool,
+    distribution: Option<&Distribution>,
+) {
+    let path = display_path(report);
+    let _ = writeln!(out, "<details class=\"file card\" id=\"f{i}\">");
+    let _ = writeln!(out, "<summary><h2>{}</h2>{}</summary>", esc(&path), verdict_badge(report));
+
+    let mut meta = format!("{} lines · {} signals", report.metadata.lines_of_code, report.metadata.signal_count);
+    if let Some(p) = report.attribution.ai_probability {
+        let _ = write!(meta, " · AI probability {p:.2}");
+    }
+    if let Some(ref era) = report.attribution.era {
+        let _ = write!(meta, " · era {}", esc(era));
+    }
+    if !report.metadata.degraded.is_empty() {
+        let caps: Vec<String> = report.metadata.degraded.iter().map(|c| c.to_string()).collect();
+        let _ = write!(meta, " · degraded: {} unavailable", caps.join(", "));
+    }
+    let _ = writeln!(out, "<div class=\"muted\">{meta}</div>");
+    if let (Some(p), Some(distribution)) = (report.attribution.ai_probability, distribution) {
+        let (human, ai) = distribution.at_or_above(p);
+        let _ = writeln!(
+            out,
+            "<div class=\"muted\">Of the files calibrated on, {:.0}% of human-written and {:.0}% of AI-written \
+             ones scored this high.</div>",
+            human * 100.0,
+            ai * 100.0,
+        );
+    }
+
+    out.push_str("<h3>Scores</h3>\n<div class=\"bars\">\n");
+    let mut scores: Vec<_> = report.attribution.scores.iter().collect();
+    scores.sort_by(|a, b| b.1.partial_cmp(a.1).unwrap().then_with(|| a.0.cmp(b.0)));
+    for (family, score) in scores {
+        let _ = writeln!(
+            out,
+            "<span>{family}</span><div class=\"bar\" style=\"width:{:.1}%;background:{}\"></div><span>{:.1}%</span>",
+            score * 100.0,
+            family.svg_color(),
+            score * 100.0,
+        );
+    }
+    out.push_str("</div>\n");
+
+    if !report.signals.is_empty() {
+        out.push_str("<h3>Detectors</h3>\n");
+        detector_chart(out, &report.signals);
+
+        out.push_str("<h3>Signals</h3>\n<table>\n");
+        out.push_str("<tr><th class=\"num\">Weight</th><th>Family</th><th>Detector</th><th>Finding</th><th>Lines</th></tr>\n");
+        for s in &report.signals {
+            let lines: Vec<String> = s.lines.iter().map(|l| format!("<a href=\"#f{i}-L{l}\">{l}</a>")).collect();
+            let _ = writeln!(
+                out,
+                "<tr><td class=\"num {}\">{:+.1}</td><td>{}</td><td>{}</td><td>{}</td><td>{}</td></tr>",
+                if toward_ai(s) > 0.0 { "pos" } else { "neg" },
+                s.weight,
+                s.family,
+                esc(&s.source),
+                esc(&s.description),
+                lines.join(", "),
+            );
+        }
+        out.push_str("</table>\n");
+    }
+
+    if let Some(symbols) = report.symbol_reports.as_ref().filter(|s| !s.is_empty()) {
+        out.push_str("<h3>Symbols</h3>\n<table>\n");
+        out.push_str("<tr><th>Lines</th><th>Symbol</th><th>Verdict</th><th class=\"num\">Confidence</th></tr>\n");
+        for sr in symbols {
+            let _ = writeln!(
+                out,
+                "<tr><td><a href=\"#f{i}-L{start}\">{start}–{}</a></td><td>{} <span class=\"muted\">{}</span></td>\
+                 <td>{}</td><td class=\"num\">{:.0}%</td></tr>",
+                sr.metadata.end_line,
+                esc(&sr.metadata.name),
+                esc(&sr.metadata.kind),
+                sr.attribution.primary,
+                sr.attribution.confidence * 100.0,
+                start = sr.metadata.start_line,
+            );
+        }
+        out.push_str("</table>\n");
+    }
+
+    if remediation {
+        let fixes = remediation::for_report(report);
+        if !fixes.is_empty() {
+            out.push_str("<h3>Remediation</h3>\n<ul>\n");
+            for fix in fixes {
+                let _ = writeln!(out, "<li><b>{}</b> — {}</li>", esc(&fix.category), esc(fix.suggestion));
+            }
+            out.push_str("</ul>\n");
+        }
+    }
+
+    if let Some(source) = source {
+        source_view(out, i, report, source);
+    }
+    out.push_str("</details>\n");
+}
+
+/// One row per detector: the weight its signals put toward an AI family on
+/// the right, toward human authorship on the left.
+fn detector_chart<'a>(out: &mut String, signals: impl IntoIterator<Item = &'a Signal>) {
+    let mut totals: BTreeMap<&str, (f64, f64)> = BTreeMap::new();
+    for s in signals {
+        let entry = totals.entry(s.source.as_str()).or_insert((0.0, 0.0));
+        let w = toward_ai(s);
+        if w >= 0.0 {
+            entry.1 += w;
+        } else {
+            entry.0 -= w;
+        }
+    }
+    if totals.is_empty() {
+        out.push_str("<div class=\"muted\">No signals fired.</div>\n");
+        return;
+    }
+    let max = totals.values().map(|(h, a)| h.max(*a)).fold(0.0, f64::max).max(f64::EPSILON);
+    out.push_str("<div class=\"diverging\">\n");
+    for (detector, (human, ai)) in totals {
+        let _ = writeln!(
+            out,
+            "<span class=\"num\">human {human:.1}</span>\
+             <div class=\"human\"><div class=\"bar\" style=\"width:{:.1}%;background:{}\"></div></div>\
+             <div class=\"ai\"><div class=\"bar\" style=\"width:{:.1}%;background:#f85149\"></div></div>\
+             <span class=\"num\">{} · AI {ai:.1}</span>",
+            human * 100.0 / max,
+            ModelFamily::Human.svg_color(),
+            ai * 100.0 / max,
+            esc(detector),
+        );
+    }
+    out.push_str("</div>\n");
+}
+
+/// The authorship bands across the probability axis with a pin per file,
+/// beneath the calibration corpus: AI-written files' probabilities above
+/// the axis, human-written ones' below, each class scaled to its own
+/// largest bin.
+fn confidence_bands(out: &mut String, reports: &[Report], calibration: &Calibration) {
+    let corpus_row = |out: &mut String, class: &str, counts: &[u32], color: &str| {
+        let max = counts.iter().copied().max().unwrap_or(0).max(1);
+        let _ = write!(out, "<div class=\"corpus\">");
+        for bin in 0..DISTRIBUTION_BINS {
+            let n = counts.get(bin).copied().unwrap_or(0);
+            let _ = write!(
+                out,
+                "<div class=\"{class}\" title=\"{n} {class} file{} at {:.2}–{:.2}\">\
+                 <div class=\"bar\" style=\"height:{:.1}%;background:{color}\"></div></div>",
+                if n == 1 { "" } else { "s" },
+                bin as f64 / DISTRIBUTION_BINS as f64,
+                (bin + 1) as f64 / DISTRIBUTION_BINS as f64,
+                f64::from(n) * 100.0 / f64::from(max),
+            );
+        }
+        out.push_str("</div>\n");
+    };
+
+    if let Some(ref distribution) = calibration.distribution {
+        corpus_row(out, "ai", &distribution.ai, AI_COLOR);
+    }
+    let bands = calibration.bands;
+    out.push_str("<div class=\"bands\">");
+    for (from, to, color) in [
+        (0.0, bands.assisted_at, "rgba(227, 179, 65, .3)"),
+        (bands.assisted_at, bands.generated_at, "rgba(139, 148, 158, .3)"),
+        (bands.generated_at, 1.0, "rgba(248, 81, 73, .3)"),
+    ] {
+        let _ = write!(
+            out,
+            "<div class=\"band\" style=\"left:{:.1}%;width:{:.1}%;background:{color}\"></div>",
+            from * 100.0,
+            (to - from) * 100.0,
+        );
+    }
+    for (i, r) in reports.iter().enumerate() {
+        if let Some(p) = r.attribution.ai_probability {
+            let _ = write!(
+                out,
+                "<a class=\"pin\" href=\"#f{i}\" style=\"left:{:.1}%\" title=\"{}: {p:.2}\"></a>",
+                p * 100.0,
+                esc(&display_path(r)),
+            );
+        }
+    }
+    out.push_str("</div>\n");
+    if let Some(ref distribution) = calibration.distribution {
+        corpus_row(out, "human", &distribution.human, &ModelFamily::Human.svg_color());
+    }
+    out.push_str("<div class=\"axis\"><span>0</span><span>0.5</span><span>1 — AI probability</span></div>\n");
+    let _ = writeln!(
+        out,
+        "<div class=\"legend\"><span><i style=\"background:rgba(227, 179, 65, .6)\"></i>human-authored below {:.2}</span>\
+         <span><i style=\"background:rgba(139, 148, 158, .6)\"></i>AI-assisted</span>\
+         <span><i style=\"background:rgba(248, 81, 73, .6)\"></i>AI-generated from {:.2}</span></div>",
+        bands.assisted_at, bands.generated_at,
+    );
+    match calibration.distribution {
+        Some(ref distribution) => {
+            let _ = writeln!(
+                out,
+                "<div class=\"muted\">Above the bands: the {} AI-written files the calibration was fitted on; \
+                 below: the {} human-written ones.</div>",
+                distribution.ai.iter().sum::<u32>(),
+                distribution.human.iter().sum::<u32>(),
+            );
+        }
+        None => out.push_str(
+            "<div class=\"muted\">This calibration records no corpus to compare against; \
+             <code>vibecheck tune --classifier calibration</code> fits one that does.</div>\n",
+        ),
+    }
+}
+
+/// The source of one file, highlighted, with the lines that signals point
+/// at marked and titled with their findings.
+fn source_view(out: &mut String, i: usize, report: &Report, source: &str) {
+    let path = report.metadata.file_path.as_deref();
+    // Signal lines in a notebook refer to its flattened code cells.
+    let flattened;
+    let source = match path.filter(|p| notebook::is_notebook(p)) {
+        Some(_) => {
+            flattened = notebook::python_source(source).unwrap_or_default();
+            flattened.as_str()
+        }
+        None => source,
+    };
+
+    let mut findings: HashMap<usize, Vec<&str>> = HashMap::new();
+    for s in &report.signals {
+        for &l in &s.lines {
+            findings.entry(l).or_default().push(&s.description);
+        }
+    }
+
+    let _ = writeln!(
+        out,
+        "<details class=\"source\"{}><summary>Source ({} flagged line{})</summary>",
+        if findings.is_empty() { "" } else { " open" },
+        findings.len(),
+        if findings.len() == 1 { "" } else { "s" },
+    );
+    out.push_str("<pre class=\"code\">");
+    for (n, line) in highlight(source, path.and_then(detect_language)).iter().enumerate() {
+        let n = n + 1;
+        match findings.get(&n) {
+            Some(found) => {
+                let _ = write!(
+                    out,
+                    "<div id=\"f{i}-L{n}\" class=\"flag\" title=\"{}\"><span class=\"ln\">{n}</span>{line}</div>",
+                    esc(&found.join("\n")),
+                );
+            }
+            None => {
+                let _ = write!(out, "<div id=\"f{i}-L{n}\"><span class=\"ln\">{n}</span>{line}</div>");
+            }
+        }
+    }
+    out.push_str("</pre>\n</details>\n");
+}
+
+// ---------------------------------------------------------------------------
+// Syntax highlighting
+// ---------------------------------------------------------------------------
+
+/// Enough of a language's lexical grammar to colour keywords, strings,
+/// comments and numbers.  Not a parser: the point is readable evidence,
+/// and a misjudged token costs nothing but a colour.
+struct Syntax {
+    line_comment: &'static [&'static str],
+    block_comment: Option<(&'static str, &'static str)>,
+    /// Delimiters of strings that may span lines.
+    long_strings: &'static [&'static str],
+    /// Delimiters of strings that end at the line.
+    quotes: &'static [char],
+    keywords: &'static [&'static str],
+}
+
+const PLAIN: Syntax = Syntax { line_comment: &[], block_comment: None, long_strings: &[], quotes: &[], keywords: &[] };
+
+fn syntax(language: Option<Language>) -> Syntax {
+    match language {
+        Some(Language::Rust) => Syntax {
+            line_comment: &["//"],
+            block_comment: Some(("/*", "*/")),
+            long_strings: &[],
+            quotes: &['"'],
+            keywords: &[
+                "as", "async", "await", "break", "const", "continue", "crate", "dyn", "else", "enum", "extern",
+                "false", "fn", "for", "if", "impl", "in", "let", "loop", "match", "mod", "move", "mut", "pub",
+                "ref", "return", "self", "Self", "static", "struct", "super", "trait", "true", "type", "unsafe",
+                "use", "where", "while",
+            ],
+        },
+        Some(Language::Python) => Syntax {
+            line_comment: &["#"],
+            block_comment: None,
+            long_strings: &["\"\"\"", "'''"],
+            quotes: &['"', '\''],
+            keywords: &[
+                "and", "as", "assert", "async", "await", "break", "class", "continue", "def", "del", "elif",
+                "else", "except", "False", "finally", "for", "from", "global", "if", "import", "in", "is",
+                "lambda", "None", "nonlocal", "not", "or", "pass", "raise", "return", "self", "True", "try",
+                "while", "with", "yield",
+            ],
+        },
+        Some(Language::JavaScript) => Syntax {
+            line_comment: &["//"],
+            block_comment: Some(("/*", "*/")),
+            long_strings: &["`"],
+            quotes: &['"', '\''],
+            keywords: &[
+                "async", "await", "break", "case", "catch", "class", "const", "continue", "default", "delete",
+                "do", "else", "enum", "export", "extends", "false", "finally", "for", "from", "function", "if",
+                "implements", "import", "in", "instanceof", "interface", "let", "new", "null", "return",
+                "static", "super", "switch", "this", "throw", "true", "try", "type", "typeof", "undefined",
+                "var", "void", "while", "yield",
+            ],
+        },
+        Some(Language::Go) => Syntax {
+            line_comment: &["//"],
+            block_comment: Some(("/*", "*/")),
+            long_strings: &["`"],
+            quotes: &['"', '\''],
+            keywords: &[
+                "break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough",
+                "false", "for", "func", "go", "goto", "if", "import", "interface", "map", "nil", "package",
+                "range", "return", "select", "struct", "switch", "true", "type", "var",
+            ],
+        },
+        None => PLAIN,
+    }
+}
+
+/// `source` as escaped HTML, one entry per line, with tokens wrapped in
+/// `<span class="k|s|c|n">`.  Tokens that span lines are closed at the end
+/// of each line and reopened on the next, so every line stands alone.
+fn highlight(source: &str, language: Option<Language>) -> Vec<String> {
+    let syntax = syntax(language);
+    let mut lines = vec![String::new()];
+    let mut emit = |class: Option<&str>, text: &str| {
+        for (n, part) in text.split('\n').enumerate() {
+            if n > 0 {
+                lines.push(String::new());
+            }
+            let line = lines.last_mut().unwrap();
+            match class {
+                Some(class) if !part.is_empty() => {
+                    let _ = write!(line, "<span class=\"{class}\">{}</span>", esc(part.trim_end_matches('\r')));
+                }
+                _ => line.push_str(&esc(part.trim_end_matches('\r'))),
+            }
+        }
+    };
+
+    let mut i = 0;
+    while i < source.len() {
+        let rest = &source[i..];
+        let end_of_line = rest.find('\n').map_or(source.len(), |e| i + e);
+        let until = |close: &str, from: usize| rest[from..].find(close).map_or(source.len(), |e| i + from + e + close.len());
+
+        let (class, end) = if let Some((open, close)) = syntax.block_comment.filter(|(open, _)| rest.starts_with(open)) {
+            (Some("c"), until(close, open.len()))
+        } else if syntax.line_comment.iter().any(|p| rest.starts_with(p)) {
+            (Some("c"), end_of_line)
+        } else if let Some(delim) = syntax.long_strings.iter().find(|d| rest.starts_with(**d)) {
+            (Some("s"), until(*delim, delim.len()))
+        } else {
+            let c = rest.chars().next().unwrap();
+            if syntax.quotes.contains(&c) {
+                (Some("s"), string_end(source, i, c, end_of_line))
+            } else if c.is_ascii_digit() {
+                let len = rest.find(|c: char| !(c.is_ascii_alphanumeric() || c == '_' || c == '.')).unwrap_or(rest.len());
+                (Some("n"), i + len)
+            } else if c.is_alphabetic() || c == '_' {
+                let len = rest.find(|c: char| !(c.is_alphanumeric() || c == '_')).unwrap_or(rest.len());
+                let word = &rest[..len];
+                (syntax.keywords.contains(&word).then_some("k"), i + len)
+            } else {
+                (None, i + c.len_utf8())
+            }
+        };
+        emit(class, &source[i..end]);
+        i = end;
+    }
+    lines
+}
+
+/// End of the string opened by `quote` at `start`: just past the closing
+/// quote, or the end of the line when it is unterminated.
+fn string_end(source: &str, start: usize, quote: char, end_of_line: usize) -> usize {
+    let mut escaped = false;
+    for (offset, c) in source[start + 1..end_of_line].char_indices() {
+        match c {
+            _ if escaped => escaped = false,
+            '\\' => escaped = true,
+            c if c == quote => return start + 1 + offset + c.len_utf8(),
+            _ => {}
+        }
+    }
+    end_of_line
+}
+
+// ---------------------------------------------------------------------------
+// Helpers
+// ---------------------------------------------------------------------------
+
+/// Weight a signal puts toward an AI family: human-family signals count
+/// against, as in the heuristic scorer.
+fn toward_ai(signal: &Signal) -> f64 {
+    if signal.family == ModelFamily::Human {
+        -signal.weight
+    } else {
+        signal.weight
+    }
+}
+
+fn is_ai(report: &Report) -> bool {
+    report.attribution.has_sufficient_data() && report.attribution.primary != ModelFamily::Human
+}
+
+fn verdict_badge(report: &Report) -> String {
+    if report.attribution.has_sufficient_data() {
+        format!(
+            "<span class=\"badge\" style=\"background:{}\">{}</span>",
+            report.attribution.primary.svg_color(),
+            report.attribution.primary,
+        )
+    } else if let Some(ref reason) = report.metadata.skipped {
+        format!("<span class=\"badge\" style=\"background:#484f58\">Skipped: {}</span>", esc(reason))
+    } else {
+        "<span class=\"badge\" style=\"background:#484f58\">Insufficient data</span>".into()
+    }
+}
+
+fn display_path(report: &Report) -> String {
+    report
+        .metadata
+        .file_path
+        .as_ref()
+        .map(|p| p.display().to_string())
+        .unwrap_or_else(|| "<stdin>".into())
+}
+
+fn esc(s: &str) -> String {
+    s.replace('&', "&amp;").replace('<', "&lt;").replace('>', "&gt;").replace('"', "&quot;")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::report::{Attribution, ReportMetadata};
+    use std::path::PathBuf;
+
+    fn report(path: &str, signals: Vec<Signal>) -> Report {
+        let mut scores = BTreeMap::new();
+        scores.insert(ModelFamily::Claude, 0.7);
+        scores.insert(ModelFamily::Human, 0.3);
+        Report {
+            attribution: Attribution {
+                primary: ModelFamily::Claude,
+                confidence: 0.7,
+                scores,
+                era: None,
+                ai_probability: Some(0.81),
+                authorship: None,
+            },
+            metadata: ReportMetadata {
+                file_path: Some(PathBuf::from(path)),
+                lines_of_code: 3,
+                signal_count: signals.len(),
+                degraded: vec![],
+                skipped: None,
+            },
+            signals,
+            symbol_reports: None,
+            segments: None,
+            comment_free: None,
+            ensemble: None,
+        }
+    }
+
+    fn signal(source: &str, family: ModelFamily, weight: f64, lines: Vec<usize>) -> Signal {
+        let mut s = Signal::new("rust.comments.step_numbered", source, "Step-numbered comments", family, weight);
+        s.lines = lines;
+        s
+    }
+
+    #[test]
+    fn html_is_one_self_contained_document() {
+        let reports = vec![report("src/<main>.rs", vec![signal("comments", ModelFamily::Claude, 1.5, vec![2])])];
+        let html = format_html(&reports, &[Some("fn main() {\n    // Step 1: go\n}\n".into())], false, &Calibration::default());
+        assert!(html.starts_with("<!DOCTYPE html>") && html.ends_with("</html>\n"));
+        assert!(!html.contains("<script") && !html.contains("src=\"http") && !html.contains("<link"));
+        assert!(html.contains("src/&lt;main&gt;.rs"), "paths are escaped");
+        assert!(html.contains("href=\"#f0-L2\""), "signals link to their lines");
+        assert!(html.contains("id=\"f0-L2\" class=\"flag\" title=\"Step-numbered comments\""));
+        assert!(html.contains("id=\"f0-L1\"><span class=\"ln\">1</span>"), "unflagged lines are plain");
+        assert!(html.contains("AI probability 0.81"));
+    }
+
+    #[test]
+    fn source_view_is_optional_and_remediation_opt_in() {
+        let reports = vec![report("a.rs", vec![signal("comments", ModelFamily::Gpt, 1.5, vec![])])];
+        let html = format_html(&reports, &[None], false, &Calibration::default());
+        assert!(!html.contains("<pre class=\"code\">"));
+        assert!(!html.contains("Remediation"));
+        assert!(format_html(&reports, &[None], true, &Calibration::default()).contains("<h3>Remediation</h3>"));
+    }
+
+    #[test]
+    fn confidence_bands_place_files_against_the_corpus() {
+        let reports = vec![report("a.rs", vec![])];
+        let html = format_html(&reports, &[None], false, &Calibration::default());
+        assert!(html.contains("<a class=\"pin\" href=\"#f0\" style=\"left:81.0%\" title=\"a.rs: 0.81\">"), "{html}");
+        assert!(html.contains("left:70.0%;width:30.0%"), "the AI-generated band");
+        assert!(html.contains("records no corpus") && !html.contains("class=\"corpus\""));
+
+        let samples = [(None, 0.1, false), (None, 0.2, false), (None, 0.8, true), (None, 0.9, true), (None, 0.95, true)];
+        let calibration = Calibration::fit(&samples);
+        let html = format_html(&reports, &[None], false, &calibration);
+        assert_eq!(html.matches("<div class=\"corpus\">").count(), 2);
+        assert!(html.contains("the 3 AI-written files") && html.contains("the 2 human-written ones"), "{html}");
+        assert!(html.contains("% of human-written and "), "each file is read against the corpus");
+    }
+
+    #[test]
+    fn detector_chart_splits_human_and_ai_weight() {
+        let signals = [
+            signal("errors", ModelFamily::Claude, 2.0, vec![]),
+            signal("errors", ModelFamily::Human, 1.0, vec![]),
+            signal("naming", ModelFamily::Gpt, -1.0, vec![]),
+        ];
+        let mut out = String::new();
+        detector_chart(&mut out, &signals);
+        assert!(out.contains("human 1.0") && out.contains("errors · AI 2.0"), "{out}");
+        assert!(out.contains("naming · AI 0.0"), "{out}");
+        // `errors` has the largest bar, so its AI side is full width.
+        assert!(out.contains("width:100.0%;background:#f85149"), "{out}");
+    }
+
+    #[test]
+    fn highlight_marks_tokens_per_line() {
+        let lines = highlight("/* a\nb */ fn x() { \"s<\" }\nlet n = 42;", Some(Language::Rust));
+        assert_eq!(lines.len(), 3);
+        assert_eq!(lines[0], "<span class=\"c\">/* a</span>");
+        assert!(lines[1].starts_with("<span class=\"c\">b */</span> <span class=\"k\">fn</span> x()"), "{}", lines[1]);
+        assert!(lines[1].contains("<span class=\"s\">&quot;s&lt;&quot;</span>"), "{}", lines[1]);
+        assert!(lines[2].ends_with("<span class=\"n\">42</span>;"), "{}", lines[2]);
+    }
+
+    #[test]
+    fn highlight_handles_language_specific_strings() {
+        let py = highlight("x = '''doc\n# not a comment'''  # comment", Some(Language::Python));
+        assert_eq!(py[1], "<span class=\"s\"># not a comment'''</span>  <span class=\"c\"># comment</span>");
+        let plain = highlight("fn \"x\"", None);
+        assert_eq!(plain, vec!["fn &quot;x&quot;"]);
+        let unterminated = highlight("let s = \"abc\nfn", Some(Language::Rust));
+        assert_eq!(unterminated[1], "<span class=\"k\">fn</span>");
+    }
+}
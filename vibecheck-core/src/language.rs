@@ -9,17 +9,54 @@ pub enum Language {
     Go,
 }
 
+/// Every file extension with a built-in analyzer.  Runtime language packs
+/// add more; see [`crate::language_pack::is_pack_extension`].
+pub const SUPPORTED_EXTENSIONS: &[&str] = &[
+    "rs", "py", "ipynb", "js", "mjs", "cjs", "jsx", "ts", "mts", "cts", "tsx", "go",
+];
+
+/// Extensions of prose documents, scored by the prose detectors (see
+/// [`crate::prose`]) rather than a language's analyzers.
+pub const DOC_EXTENSIONS: &[&str] = &["md", "markdown", "rst"];
+
+/// Whether `path` is a prose document ([`DOC_EXTENSIONS`]).
+pub fn is_doc_file(path: &Path) -> bool {
+    path.extension()
+        .and_then(|e| e.to_str())
+        .is_some_and(|e| DOC_EXTENSIONS.iter().any(|d| e.eq_ignore_ascii_case(d)))
+}
+
 /// Detect the language of a file from its extension.
+///
+/// TypeScript shares the JavaScript analyzers and signals; only the
+/// grammar differs (see [`get_ts_language_for_path`]).
 pub fn detect_language(path: &Path) -> Option<Language> {
     match path.extension()?.to_str()? {
         "rs" => Some(Language::Rust),
-        "py" => Some(Language::Python),
-        "js" | "ts" | "jsx" | "tsx" => Some(Language::JavaScript),
+        "py" | "ipynb" => Some(Language::Python),
+        "js" | "mjs" | "cjs" | "jsx" | "ts" | "mts" | "cts" | "tsx" => Some(Language::JavaScript),
         "go" => Some(Language::Go),
         _ => None,
     }
 }
 
+/// The file extension for a language named on the command line (`go`,
+/// `python`, `typescript`, ...) or given by one of its extensions, so a
+/// snippet without a file name can be analyzed as that language.
+/// Case-insensitive.
+pub fn extension_for(name: &str) -> Option<&'static str> {
+    let name = name.to_ascii_lowercase();
+    let ext = match name.as_str() {
+        "rust" => "rs",
+        "python" => "py",
+        "javascript" => "js",
+        "typescript" => "ts",
+        "golang" => "go",
+        other => SUPPORTED_EXTENSIONS.iter().find(|e| **e == other)?,
+    };
+    Some(ext)
+}
+
 /// Get the tree-sitter grammar for a given language.
 pub fn get_ts_language(lang: Language) -> tree_sitter::Language {
     match lang {
@@ -29,3 +66,65 @@ pub fn get_ts_language(lang: Language) -> tree_sitter::Language {
         Language::Go => tree_sitter_go::LANGUAGE.into(),
     }
 }
+
+/// Get the tree-sitter grammar for the file at `path`.
+///
+/// Like [`get_ts_language`], but picks the TypeScript or TSX grammar for
+/// TypeScript files: the JavaScript grammar rejects type annotations, and
+/// the resulting error nodes would skew every CST metric.  The TypeScript
+/// grammars extend the JavaScript one, so the JavaScript CST analyzer's
+/// node kinds still apply.
+pub fn get_ts_language_for_path(path: &Path) -> Option<tree_sitter::Language> {
+    let lang = detect_language(path)?;
+    match path.extension().and_then(|e| e.to_str()) {
+        Some("ts" | "mts" | "cts") => Some(tree_sitter_typescript::LANGUAGE_TYPESCRIPT.into()),
+        Some("tsx") => Some(tree_sitter_typescript::LANGUAGE_TSX.into()),
+        _ => Some(get_ts_language(lang)),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn detect_language_covers_supported_extensions() {
+        for ext in SUPPORTED_EXTENSIONS {
+            let path = format!("file.{ext}");
+            assert!(detect_language(Path::new(&path)).is_some(), "{ext} should be detected");
+        }
+        assert_eq!(detect_language(Path::new("types.mts")), Some(Language::JavaScript));
+        assert_eq!(detect_language(Path::new("README.md")), None);
+    }
+
+    #[test]
+    fn doc_files_are_not_a_language() {
+        for name in ["README.md", "docs/design.rst", "NOTES.MD"] {
+            assert!(is_doc_file(Path::new(name)), "{name}");
+            assert_eq!(detect_language(Path::new(name)), None);
+        }
+        assert!(!is_doc_file(Path::new("main.rs")));
+    }
+
+    #[test]
+    fn extension_for_accepts_names_and_extensions() {
+        assert_eq!(extension_for("go"), Some("go"));
+        assert_eq!(extension_for("Python"), Some("py"));
+        assert_eq!(extension_for("typescript"), Some("ts"));
+        assert_eq!(extension_for("tsx"), Some("tsx"));
+        assert_eq!(extension_for("cobol"), None);
+    }
+
+    #[test]
+    fn typescript_files_use_typescript_grammar() {
+        let ts = get_ts_language_for_path(Path::new("a.ts")).unwrap();
+        let tsx = get_ts_language_for_path(Path::new("a.tsx")).unwrap();
+        let js = get_ts_language_for_path(Path::new("a.js")).unwrap();
+        let expected_ts: tree_sitter::Language = tree_sitter_typescript::LANGUAGE_TYPESCRIPT.into();
+        let expected_tsx: tree_sitter::Language = tree_sitter_typescript::LANGUAGE_TSX.into();
+        assert_eq!(ts, expected_ts);
+        assert_eq!(tsx, expected_tsx);
+        assert_ne!(js, ts);
+        assert!(get_ts_language_for_path(Path::new("notes.txt")).is_none());
+    }
+}
@@ -0,0 +1,391 @@
+//! Runtime-loadable tree-sitter grammars ("language packs").
+//!
+//! A language pack is a TOML manifest plus a compiled tree-sitter grammar
+//! (`.so` / `.dylib` / `.dll`) dropped into the packs directory.  Packs are
+//! discovered at runtime, so adding a niche language does not require a new
+//! vibecheck binary.
+//!
+//! ```toml
+//! # ~/.config/vibecheck/languages/zig.toml
+//! name           = "zig"
+//! extensions     = ["zig"]
+//! library        = "libtree-sitter-zig.so"   # relative to the manifest
+//! symbol         = "tree_sitter_zig"         # default: tree_sitter_<name>
+//! comment_kinds  = ["line_comment"]          # default: ["comment"]
+//! identifier_kinds = ["IDENTIFIER"]          # default: ["identifier"]
+//! function_kinds = ["FnProto", "function_declaration"]
+//! doc_comment_prefixes = ["///"]            # default: none
+//! ```
+//!
+//! A pack is a [`LanguageFrontend`]: the manifest's node kinds map the
+//! grammar onto the same comment, identifier and function streams as the
+//! built-in languages.  Pack languages have no language-specific analyzers;
+//! they are scored only by the language-agnostic metrics in
+//! [`LanguagePack::extract_metrics`], matched against the `pack_cst` signals
+//! in `heuristics.toml`.
+//!
+//! Java ships as a bundled pack ([`bundled`]): its grammar is compiled in,
+//! but it is scored exactly like a pack loaded from disk.
+//!
+//! Loading shared libraries requires the `language-packs` cargo feature.
+//! Without it, manifests are still discovered but every load fails with an
+//! explanatory error, and [`installed`] holds only the bundled packs.
+
+use std::collections::HashMap;
+use std::path::{Path, PathBuf};
+use std::sync::OnceLock;
+
+use anyhow::Context;
+use serde::Deserialize;
+
+use crate::frontend::{generic_metrics, is_doc_comment, LanguageFrontend};
+use crate::source_file::SourceFile;
+use crate::structure;
+
+// ---------------------------------------------------------------------------
+// Manifest
+// ---------------------------------------------------------------------------
+
+#[derive(Deserialize)]
+struct PackManifest {
+    name: String,
+    extensions: Vec<String>,
+    library: PathBuf,
+    symbol: Option<String>,
+    #[serde(default = "default_comment_kinds")]
+    comment_kinds: Vec<String>,
+    #[serde(default = "default_identifier_kinds")]
+    identifier_kinds: Vec<String>,
+    #[serde(default)]
+    function_kinds: Vec<String>,
+    #[serde(default)]
+    doc_comment_prefixes: Vec<String>,
+}
+
+fn default_comment_kinds() -> Vec<String> {
+    vec!["comment".to_string()]
+}
+
+fn default_identifier_kinds() -> Vec<String> {
+    vec!["identifier".to_string()]
+}
+
+// ---------------------------------------------------------------------------
+// LanguagePack
+// ---------------------------------------------------------------------------
+
+/// A tree-sitter grammar loaded at runtime, with the node kinds needed to
+/// compute language-agnostic metrics.
+pub struct LanguagePack {
+    name: String,
+    extensions: Vec<String>,
+    language: tree_sitter::Language,
+    comment_kinds: Vec<String>,
+    identifier_kinds: Vec<String>,
+    function_kinds: Vec<String>,
+    doc_comment_prefixes: Vec<String>,
+}
+
+impl LanguagePack {
+    /// Build a pack from an already-loaded grammar.
+    ///
+    /// Useful for embedding a grammar crate directly, and for tests.
+    pub fn new(
+        name: impl Into<String>,
+        extensions: Vec<String>,
+        language: tree_sitter::Language,
+        comment_kinds: Vec<String>,
+        identifier_kinds: Vec<String>,
+        function_kinds: Vec<String>,
+    ) -> Self {
+        Self {
+            name: name.into(),
+            extensions,
+            language,
+            comment_kinds,
+            identifier_kinds,
+            function_kinds,
+            doc_comment_prefixes: Vec::new(),
+        }
+    }
+
+    /// Treat comments starting with one of `prefixes` as doc comments.
+    pub fn with_doc_comment_prefixes(mut self, prefixes: Vec<String>) -> Self {
+        self.doc_comment_prefixes = prefixes;
+        self
+    }
+
+    /// Load the pack described by the manifest at `path`.
+    pub fn load(path: &Path) -> anyhow::Result<Self> {
+        let src = std::fs::read_to_string(path)
+            .with_context(|| format!("cannot read {}", path.display()))?;
+        let manifest: PackManifest = toml::from_str(&src)
+            .with_context(|| format!("invalid language pack manifest {}", path.display()))?;
+        let library = match path.parent() {
+            Some(dir) => dir.join(&manifest.library),
+            None => manifest.library.clone(),
+        };
+        let symbol = manifest
+            .symbol
+            .clone()
+            .unwrap_or_else(|| format!("tree_sitter_{}", manifest.name));
+        let language = load_grammar(&library, &symbol)
+            .with_context(|| format!("cannot load language pack '{}'", manifest.name))?;
+        Ok(Self::new(
+            manifest.name,
+            manifest.extensions,
+            language,
+            manifest.comment_kinds,
+            manifest.identifier_kinds,
+            manifest.function_kinds,
+        )
+        .with_doc_comment_prefixes(manifest.doc_comment_prefixes))
+    }
+
+    /// The pack's language name, e.g. `"zig"`.
+    pub fn name(&self) -> &str {
+        &self.name
+    }
+
+    /// The tree-sitter grammar.
+    pub fn ts_language(&self) -> &tree_sitter::Language {
+        &self.language
+    }
+
+    /// Return `true` if files with extension `ext` belong to this pack.
+    pub fn handles_extension(&self, ext: &str) -> bool {
+        self.extensions.iter().any(|e| e == ext)
+    }
+
+    /// Return `true` if `path` has one of this pack's extensions.
+    pub fn matches(&self, path: &Path) -> bool {
+        path.extension()
+            .and_then(|e| e.to_str())
+            .map(|e| self.handles_extension(e))
+            .unwrap_or(false)
+    }
+
+    /// Extract language-agnostic metrics from a file loaded with this pack
+    /// as its frontend.  Metric names match the `pack_cst` rules in
+    /// `heuristics.toml`.
+    pub fn extract_metrics(&self, file: &SourceFile) -> HashMap<String, f64> {
+        let mut metrics = generic_metrics(&file.tokens, &file.source);
+        if let Some(tree) = &file.tree {
+            metrics.extend(structure::metrics(tree, self));
+        }
+        metrics
+    }
+}
+
+impl LanguageFrontend for LanguagePack {
+    fn name(&self) -> &str {
+        &self.name
+    }
+
+    fn grammar(&self) -> tree_sitter::Language {
+        self.language.clone()
+    }
+
+    fn is_comment(&self, kind: &str) -> bool {
+        self.comment_kinds.iter().any(|k| k == kind)
+    }
+
+    fn is_identifier(&self, kind: &str) -> bool {
+        self.identifier_kinds.iter().any(|k| k == kind)
+    }
+
+    fn is_function(&self, kind: &str) -> bool {
+        self.function_kinds.iter().any(|k| k == kind)
+    }
+
+    fn is_doc_comment(&self, text: &str) -> bool {
+        is_doc_comment(text, &self.doc_comment_prefixes)
+    }
+}
+
+// ---------------------------------------------------------------------------
+// Bundled packs
+// ---------------------------------------------------------------------------
+
+/// The Java pack.  Methods, constructors and lambdas are function
+/// boundaries; Javadoc (`/** */`) is the doc-comment syntax.
+fn java() -> LanguagePack {
+    let kinds = |list: &[&str]| -> Vec<String> { list.iter().map(|k| k.to_string()).collect() };
+    LanguagePack::new(
+        "java",
+        kinds(&["java"]),
+        tree_sitter_java::LANGUAGE.into(),
+        kinds(&["line_comment", "block_comment"]),
+        kinds(&["identifier", "type_identifier"]),
+        kinds(&["method_declaration", "constructor_declaration", "lambda_expression"]),
+    )
+    .with_doc_comment_prefixes(kinds(&["/**"]))
+}
+
+/// Packs whose grammars are compiled in.  They need no manifest and work
+/// without the `language-packs` feature.
+pub fn bundled() -> Vec<LanguagePack> {
+    vec![java()]
+}
+
+// ---------------------------------------------------------------------------
+// Discovery
+// ---------------------------------------------------------------------------
+
+/// Resolve the language packs directory, checking (in priority order):
+/// 1. `VIBECHECK_LANGUAGE_DIR` environment variable
+/// 2. Platform default: `~/.config/vibecheck/languages/`
+pub fn packs_dir() -> PathBuf {
+    if let Ok(dir) = std::env::var("VIBECHECK_LANGUAGE_DIR") {
+        return PathBuf::from(dir);
+    }
+    dirs::config_dir()
+        .unwrap_or_else(|| PathBuf::from(".config"))
+        .join("vibecheck")
+        .join("languages")
+}
+
+/// Load every `*.toml` manifest in `dir`, in file-name order.
+///
+/// Returns one result per manifest so callers can report packs that failed
+/// to load.  A missing directory yields an empty list.
+pub fn load_dir(dir: &Path) -> Vec<anyhow::Result<LanguagePack>> {
+    let mut manifests: Vec<PathBuf> = match std::fs::read_dir(dir) {
+        Ok(entries) => entries
+            .filter_map(|e| e.ok())
+            .map(|e| e.path())
+            .filter(|p| p.extension().map(|e| e == "toml").unwrap_or(false))
+            .collect(),
+        Err(_) => return Vec::new(),
+    };
+    manifests.sort();
+    manifests.iter().map(|m| LanguagePack::load(m)).collect()
+}
+
+/// Packs successfully loaded from [`packs_dir`], then the [`bundled`] ones,
+/// discovered once per process.  Packs that fail to load are skipped; a
+/// loaded pack claiming a bundled extension takes precedence.
+pub fn installed() -> &'static [LanguagePack] {
+    static INSTALLED: OnceLock<Vec<LanguagePack>> = OnceLock::new();
+    INSTALLED.get_or_init(|| {
+        let mut packs: Vec<LanguagePack> = load_dir(&packs_dir()).into_iter().filter_map(|r| r.ok()).collect();
+        packs.extend(bundled());
+        packs
+    })
+}
+
+/// Return the installed pack handling `path`, if any.
+pub fn pack_for(path: &Path) -> Option<&'static LanguagePack> {
+    installed().iter().find(|p| p.matches(path))
+}
+
+/// Return `true` if an installed pack handles files with extension `ext`.
+pub fn is_pack_extension(ext: &str) -> bool {
+    installed().iter().any(|p| p.handles_extension(ext))
+}
+
+// ---------------------------------------------------------------------------
+// Grammar loading
+// ---------------------------------------------------------------------------
+
+#[cfg(feature = "language-packs")]
+fn load_grammar(library: &Path, symbol: &str) -> anyhow::Result<tree_sitter::Language> {
+    type GrammarFn = unsafe extern "C" fn() -> *const ();
+
+    // SAFETY: loading a grammar runs its initialisers; packs are trusted
+    // artifacts installed by the user, like any other plugin.
+    let lib = unsafe { libloading::Library::new(library) }
+        .with_context(|| format!("cannot open {}", library.display()))?;
+    // SAFETY: tree-sitter grammars export `tree_sitter_<name>` with exactly
+    // this signature.
+    let grammar: GrammarFn = unsafe {
+        *lib.get::<GrammarFn>(symbol.as_bytes())
+            .with_context(|| format!("symbol `{symbol}` not found in {}", library.display()))?
+    };
+    // The language table lives in the library's static data, so the library
+    // must stay mapped for the rest of the process.
+    std::mem::forget(lib);
+    // SAFETY: `grammar` is a tree-sitter language function (see above).
+    let language_fn = unsafe { tree_sitter::LanguageFn::from_raw(grammar) };
+    Ok(tree_sitter::Language::new(language_fn))
+}
+
+#[cfg(not(feature = "language-packs"))]
+fn load_grammar(library: &Path, _symbol: &str) -> anyhow::Result<tree_sitter::Language> {
+    anyhow::bail!(
+        "cannot load {}: vibecheck was built without the `language-packs` feature",
+        library.display()
+    )
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn rust_as_pack() -> LanguagePack {
+        LanguagePack::new(
+            "rust-pack",
+            vec!["rsx".into()],
+            tree_sitter_rust::LANGUAGE.into(),
+            vec!["line_comment".into()],
+            vec!["identifier".into()],
+            vec!["function_item".into()],
+        )
+    }
+
+    #[test]
+    fn matches_by_extension() {
+        let pack = rust_as_pack();
+        assert!(pack.matches(Path::new("src/lib.rsx")));
+        assert!(!pack.matches(Path::new("src/lib.rs")));
+        assert!(!pack.matches(Path::new("Makefile")));
+    }
+
+    #[test]
+    fn extract_metrics_counts_comments_and_functions() {
+        let pack = rust_as_pack();
+        let source = "// one\n// two\nfn a() {\n    1;\n}\nfn b() {}\n";
+        let metrics = pack.extract_metrics(&SourceFile::load(source, None, Some(&pack as &dyn LanguageFrontend)));
+        assert_eq!(metrics["fn_count"], 2.0);
+        assert_eq!(metrics["avg_fn_length"], 2.0);
+        assert!((metrics["comment_line_ratio"] - 2.0 / 6.0).abs() < 1e-9);
+    }
+
+    #[test]
+    fn java_is_bundled_with_javadoc() {
+        let packs = bundled();
+        let java = packs.iter().find(|p| p.matches(Path::new("src/Main.java"))).expect("java pack");
+        let source = "/** Adds one. */\nclass A {\n    // plain\n    int inc(int x) {\n        return x + 1;\n    }\n    A() {}\n}\n";
+        let tokens = java.tokenize(source).unwrap();
+        assert_eq!(tokens.comments.len(), 2);
+        let docs: Vec<&str> = tokens.doc_comments.iter().map(|s| s.text.as_str()).collect();
+        assert_eq!(docs, vec!["/** Adds one. */"]);
+        assert_eq!(tokens.functions.len(), 2, "method and constructor");
+        assert!(tokens.identifiers.iter().any(|s| s.text == "inc"));
+    }
+
+    #[test]
+    fn load_dir_missing_is_empty() {
+        let dir = tempfile::tempdir().unwrap();
+        assert!(load_dir(&dir.path().join("nope")).is_empty());
+    }
+
+    #[test]
+    fn load_dir_reports_bad_manifest() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join("broken.toml"), "name = ").unwrap();
+        std::fs::write(dir.path().join("readme.md"), "not a manifest").unwrap();
+        let results = load_dir(dir.path());
+        assert_eq!(results.len(), 1);
+        assert!(results[0].is_err());
+    }
+
+    #[test]
+    fn load_reports_missing_library() {
+        let dir = tempfile::tempdir().unwrap();
+        let manifest = dir.path().join("zig.toml");
+        std::fs::write(&manifest, "name = \"zig\"\nextensions = [\"zig\"]\nlibrary = \"missing.so\"\n").unwrap();
+        let err = LanguagePack::load(&manifest).err().expect("load should fail");
+        assert!(format!("{err:#}").contains("zig"));
+    }
+}
@@ -1,21 +1,62 @@
 #![deny(dead_code)]
 
 pub mod analyzers;
+mod api;
 pub mod cache;
+pub mod calibration;
+pub mod capability;
+pub mod classifier;
 pub mod colors;
+pub mod commit_message;
+pub mod corpus_manifest;
+pub mod embedding;
+pub mod eval;
+pub mod feedback;
+pub mod fingerprint;
+pub mod frontend;
+pub mod generated;
 pub mod heuristics;
+pub mod html;
 pub mod ignore_rules;
 pub mod language;
+pub mod language_pack;
+pub mod limits;
+mod lru;
 pub mod merkle;
+pub mod notebook;
 pub mod output;
+pub mod package_style;
+pub mod patch;
 pub mod pipeline;
+pub mod plugin;
+pub mod policy;
+pub mod prose;
 pub mod project_tools;
+pub mod provenance;
+pub mod ranking;
+pub mod remediation;
 pub mod report;
+pub mod report_diff;
+pub mod rollup;
+pub mod sampling;
+pub mod scrub;
+pub mod segments;
+pub mod source_file;
+pub mod source_fs;
+pub mod structure;
+pub mod template_reuse;
+pub mod test_files;
+pub mod timeout;
+pub mod tuning;
 
 #[cfg(feature = "corpus")]
 pub mod store;
 
+use std::collections::HashMap;
 use std::path::{Path, PathBuf};
+use std::sync::Arc;
+
+pub use api::{Analyzer, FileResult, ProgressFn};
 
 use cache::Cache;
 use heuristics::{ConfiguredHeuristics, HeuristicsProvider};
@@ -23,18 +64,72 @@ use ignore_rules::{IgnoreConfig, IgnoreRules};
 use merkle::walk_and_hash_with;
 use pipeline::Pipeline;
 use report::Report;
+use source_fs::OsFs;
 
-fn load_config(dir: &std::path::Path) -> IgnoreConfig {
-    IgnoreConfig::load(dir)
+fn load_config(dir: &std::path::Path, profile: Option<&str>) -> IgnoreConfig {
+    IgnoreConfig::load_with_profile(dir, profile)
 }
 
 fn heuristics_from_config(config: &IgnoreConfig) -> Box<dyn HeuristicsProvider> {
-    Box::new(ConfiguredHeuristics::from_config(config.heuristics_map()))
+    Box::new(ConfiguredHeuristics::from_config(config.heuristics_map()).with_severity(config.policy().severity.clone()))
+}
+
+/// The project's weights with the test-file overrides on top.
+fn test_heuristics_from_config(config: &IgnoreConfig) -> Box<dyn HeuristicsProvider> {
+    let mut weights = config.heuristics_map();
+    weights.extend(config.test_files().heuristics_map());
+    Box::new(ConfiguredHeuristics::from_config(weights).with_severity(config.policy().severity.clone()))
+}
+
+/// The pipeline `config` describes, with the installed language packs and
+/// plugins.  Every entry point builds its pipeline here: reports computed
+/// by one are cached under keys the others read.
+fn pipeline_from_config(config: &IgnoreConfig) -> Pipeline {
+    Pipeline::with_heuristics(
+        analyzers_from_config(config),
+        analyzers::default_cst_analyzers(),
+        heuristics_from_config(config),
+    )
+    .with_classifier(config.classifier())
+    .with_ensemble(config.ensemble())
+    .with_calibration(config.calibration())
+    .with_degraded(config.degraded())
+    .with_test_heuristics(config.test_files().clone(), test_heuristics_from_config(config))
+    .with_language_packs(language_pack::installed())
+    .with_plugins(plugin::installed())
+}
+
+fn analyzers_from_config(config: &IgnoreConfig) -> Vec<Box<dyn analyzers::Analyzer>> {
+    let mut analyzers = analyzers::analyzers_with(
+        config.filler_analyzer(),
+        config.hedging_analyzer(),
+        config.verbosity_analyzer(),
+        config.decoration_analyzer(),
+    );
+    if let Some(perplexity) = config.perplexity_analyzer() {
+        analyzers.push(Box::new(perplexity));
+    }
+    if let Some(stylometry) = config.stylometry_analyzer() {
+        analyzers.push(Box::new(stylometry));
+    }
+    analyzers
 }
 
-fn open_cache(config: &IgnoreConfig) -> Option<Cache> {
-    let path = Cache::resolve_path(config.cache_dir());
-    Cache::open(&path).ok()
+/// Open the cache at `cache_dir` if given, else at the location resolved
+/// from `config` (see [`Cache::resolve_path`]).
+fn open_cache(config: &IgnoreConfig, cache_dir: Option<&Path>) -> Option<Arc<Cache>> {
+    let path = Cache::resolve_path(cache_dir.or(config.cache_dir()));
+    Cache::shared(&path).ok()
+}
+
+/// Cache key for `bytes` of the file at `path` under the weight overrides
+/// and analyzer settings configured in `config`.
+fn content_hash(bytes: &[u8], path: &Path, config: &IgnoreConfig) -> [u8; 32] {
+    let mut settings = config.analysis_settings();
+    settings.extend(analyzers::text::go_era::cache_setting(path));
+    settings.extend(plugin::cache_settings());
+    settings.extend(config.test_files().cache_setting(path));
+    Cache::hash_content_with_settings(bytes, &config.heuristics_map(), &settings)
 }
 
 /// Analyze a source code string and return a report.
@@ -43,6 +138,31 @@ pub fn analyze(source: &str) -> Report {
     pipeline.run(source, None)
 }
 
+/// Score a commit message under the weights of the nearest `.vibecheck`
+/// above `dir` (see [`commit_message`]).
+pub fn analyze_commit_message(message: &str, dir: &Path) -> commit_message::CommitReport {
+    analyze_commit_message_with(message, dir, &FileOptions::default())
+}
+
+/// [`analyze_commit_message`] under `options.profile`.
+pub fn analyze_commit_message_with(message: &str, dir: &Path, options: &FileOptions) -> commit_message::CommitReport {
+    let config = load_config(dir, options.profile);
+    commit_message::score(message, heuristics_from_config(&config).as_ref())
+}
+
+/// How the per-file functions below load settings and use the cache,
+/// beyond their defaults.
+#[derive(Debug, Clone, Copy, Default)]
+pub struct FileOptions<'a> {
+    /// Read and write the cache here instead of the configured location.
+    pub cache_dir: Option<&'a Path>,
+    /// Neither read nor write the cache.
+    pub no_cache: bool,
+    /// Load each file's `.vibecheck` under this policy profile instead of
+    /// its `[policy] profile`.
+    pub profile: Option<&'a str>,
+}
+
 /// Analyze a file, using the content-addressed cache to skip re-analysis of unchanged files.
 ///
 /// Cache location is resolved from (in priority order):
@@ -50,11 +170,26 @@ pub fn analyze(source: &str) -> Report {
 /// 2. `VIBECHECK_CACHE_DIR` environment variable
 /// 3. Platform default (`~/.cache/vibecheck/`)
 pub fn analyze_file(path: &Path) -> std::io::Result<Report> {
+    analyze_file_with(path, &FileOptions::default())
+}
+
+/// Like [`analyze_file`], but reads and writes the cache in `cache_dir`
+/// instead of the configured location.
+///
+/// Intended for incremental CI runs: restore `cache_dir` from a previous
+/// run's artifact and only files whose content (or configured weights)
+/// changed are re-analyzed.
+pub fn analyze_file_with_cache_dir(path: &Path, cache_dir: &Path) -> std::io::Result<Report> {
+    analyze_file_with(path, &FileOptions { cache_dir: Some(cache_dir), ..FileOptions::default() })
+}
+
+/// [`analyze_file`] with `options`.
+pub fn analyze_file_with(path: &Path, options: &FileOptions) -> std::io::Result<Report> {
     let bytes = std::fs::read(path)?;
-    let hash = Cache::hash_content(&bytes);
     let dir = path.parent().unwrap_or(path);
-    let config = load_config(dir);
-    let cache = open_cache(&config);
+    let config = load_config(dir, options.profile);
+    let hash = content_hash(&bytes, path, &config);
+    let cache = if options.no_cache { None } else { open_cache(&config, options.cache_dir) };
 
     if let Some(ref c) = cache {
         if let Some(mut cached) = c.get(&hash) {
@@ -65,11 +200,7 @@ pub fn analyze_file(path: &Path) -> std::io::Result<Report> {
 
     let source = String::from_utf8(bytes)
         .map_err(|e| std::io::Error::new(std::io::ErrorKind::InvalidData, e))?;
-    let pipeline = Pipeline::with_heuristics(
-        crate::analyzers::default_analyzers(),
-        crate::analyzers::default_cst_analyzers(),
-        heuristics_from_config(&config),
-    );
+    let pipeline = pipeline_from_config(&config);
     let report = pipeline.run(&source, Some(path.to_path_buf()));
 
     if let Some(ref c) = cache {
@@ -81,15 +212,26 @@ pub fn analyze_file(path: &Path) -> std::io::Result<Report> {
 
 /// Analyze a file without consulting or updating the cache.
 pub fn analyze_file_no_cache(path: &Path) -> std::io::Result<Report> {
+    analyze_file_with(path, &FileOptions { no_cache: true, ..FileOptions::default() })
+}
+
+/// [`analyze_file_no_cache`], reporting each detector stage to `profiler`
+/// (see [`Pipeline::run_profiled`]).
+pub fn profile_file(path: &Path, profiler: &mut dyn pipeline::Profiler) -> std::io::Result<Report> {
+    profile_file_with(path, profiler, &FileOptions::default())
+}
+
+/// [`profile_file`] under `options.profile`.  Never cached.
+pub fn profile_file_with(
+    path: &Path,
+    profiler: &mut dyn pipeline::Profiler,
+    options: &FileOptions,
+) -> std::io::Result<Report> {
     let source = std::fs::read_to_string(path)?;
     let dir = path.parent().unwrap_or(path);
-    let config = load_config(dir);
-    let pipeline = Pipeline::with_heuristics(
-        crate::analyzers::default_analyzers(),
-        crate::analyzers::default_cst_analyzers(),
-        heuristics_from_config(&config),
-    );
-    Ok(pipeline.run(&source, Some(path.to_path_buf())))
+    let config = load_config(dir, options.profile);
+    let pipeline = pipeline_from_config(&config);
+    Ok(pipeline.run_profiled(&source, Some(path.to_path_buf()), profiler))
 }
 
 /// Analyze every supported source file under `dir`, using a Merkle hash tree
@@ -107,9 +249,10 @@ pub fn analyze_directory(
     dir: &Path,
     use_cache: bool,
 ) -> anyhow::Result<Vec<(PathBuf, Report)>> {
-    let config = load_config(dir);
+    let config = load_config(dir, None);
     let cache_path = Cache::resolve_path(config.cache_dir());
-    analyze_directory_inner(dir, use_cache, &config, &cache_path)
+    let options = FileOptions { no_cache: !use_cache, ..FileOptions::default() };
+    analyze_directory_inner(dir, &config, &cache_path, &options)
 }
 
 /// Like [`analyze_directory`], but accepts any [`IgnoreRules`] implementation.
@@ -127,19 +270,43 @@ pub fn analyze_directory_with(
     use_cache: bool,
     ignore: &dyn IgnoreRules,
 ) -> anyhow::Result<Vec<(PathBuf, Report)>> {
-    let cache_path = Cache::resolve_path(None);
-    analyze_directory_inner(dir, use_cache, ignore, &cache_path)
+    let options = FileOptions { no_cache: !use_cache, ..FileOptions::default() };
+    analyze_directory_with_options(dir, ignore, &options)
+}
+
+/// [`analyze_directory_with`] with `options`: the directory hashes are kept
+/// in `options.cache_dir` if set, and each file is analyzed as by
+/// [`analyze_file_with`].
+pub fn analyze_directory_with_options(
+    dir: &Path,
+    ignore: &dyn IgnoreRules,
+    options: &FileOptions,
+) -> anyhow::Result<Vec<(PathBuf, Report)>> {
+    let cache_path = Cache::resolve_path(options.cache_dir);
+    analyze_directory_inner(dir, ignore, &cache_path, options)
+}
+
+/// The `n` files and functions under `dir` most likely to be AI-generated,
+/// highest AI probability first, each with its dominant rules (see
+/// [`ranking`]).  Honours the ignore rules of the `.vibecheck` in `dir`.
+pub fn top_offenders(dir: &Path, n: usize) -> anyhow::Result<Vec<ranking::Offender>> {
+    let config = load_config(dir, None);
+    let mut reports = Vec::new();
+    for path in source_fs::source_files(&OsFs, dir, &config)? {
+        reports.push(analyze_file_symbols(&path)?);
+    }
+    Ok(ranking::top(&reports, n, true))
 }
 
 fn analyze_directory_inner(
     dir: &Path,
-    use_cache: bool,
     ignore: &dyn IgnoreRules,
     cache_path: &Path,
+    options: &FileOptions,
 ) -> anyhow::Result<Vec<(PathBuf, Report)>> {
-    let supported_exts = ["rs", "py", "js", "ts", "jsx", "tsx", "go"];
+    let use_cache = !options.no_cache;
     let cache = if use_cache {
-        Cache::open(cache_path).ok()
+        Cache::shared(cache_path).ok()
     } else {
         None
     };
@@ -160,15 +327,20 @@ fn analyze_directory_inner(
         false
     };
 
+    let files = source_fs::source_files(&OsFs, dir, ignore)?;
     let mut results = Vec::new();
 
     if unchanged {
         // Collect reports from the file cache — no pipeline work needed.
-        collect_cached_reports(dir, &supported_exts, cache.as_ref(), &mut results, ignore);
+        collect_cached_reports(&files, cache.as_deref(), options, &mut results);
     } else {
-        // Walk and analyze, relying on the per-file cache to avoid re-parsing
+        // Analyze, relying on the per-file cache to avoid re-parsing
         // individual unchanged files (analyze_file handles per-file caching).
-        walk_and_analyze(dir, &supported_exts, &mut results, ignore)?;
+        for path in files {
+            let report = analyze_file_with(&path, options)
+                .map_err(|e| anyhow::anyhow!("failed to analyze {}: {}", path.display(), e))?;
+            results.push((path, report));
+        }
 
         // Persist the updated directory node.
         if let Some(ref c) = cache {
@@ -180,85 +352,28 @@ fn analyze_directory_inner(
 }
 
 fn collect_cached_reports(
-    dir: &Path,
-    supported_exts: &[&str],
+    files: &[PathBuf],
     cache: Option<&Cache>,
+    options: &FileOptions,
     results: &mut Vec<(PathBuf, Report)>,
-    ignore: &dyn IgnoreRules,
 ) {
-    let entries = match std::fs::read_dir(dir) {
-        Ok(e) => e,
-        Err(_) => return,
-    };
-    let mut paths: Vec<_> = entries.filter_map(|e| e.ok()).map(|e| e.path()).collect();
-    paths.sort();
-
-    for path in paths {
-        if path.is_dir() {
-            if ignore.is_ignored_dir(&path) {
-                continue;
-            }
-            collect_cached_reports(&path, supported_exts, cache, results, ignore);
-        } else if path.is_file() {
-            if ignore.is_ignored(&path) {
-                continue;
-            }
-            let ext = path
-                .extension()
-                .and_then(|e| e.to_str())
-                .unwrap_or("");
-            if !supported_exts.contains(&ext) {
-                continue;
-            }
-            if let Ok(bytes) = std::fs::read(&path) {
-                let hash = Cache::hash_content(&bytes);
-                let cached = cache.and_then(|c| c.get(&hash));
-                if let Some(mut report) = cached {
-                    report.metadata.file_path = Some(path.clone());
-                    results.push((path, report));
-                } else if let Ok(report) = analyze_file(&path) {
-                    results.push((path, report));
-                }
-            }
-        }
-    }
-}
-
-fn walk_and_analyze(
-    dir: &Path,
-    supported_exts: &[&str],
-    results: &mut Vec<(PathBuf, Report)>,
-    ignore: &dyn IgnoreRules,
-) -> anyhow::Result<()> {
-    let mut entries: Vec<_> = std::fs::read_dir(dir)?
-        .filter_map(|e| e.ok())
-        .map(|e| e.path())
-        .collect();
-    entries.sort();
-
-    for path in entries {
-        if path.is_dir() {
-            if ignore.is_ignored_dir(&path) {
-                continue;
-            }
-            walk_and_analyze(&path, supported_exts, results, ignore)?;
-        } else if path.is_file() {
-            if ignore.is_ignored(&path) {
-                continue;
+    // Files of a directory share its config; load it once per directory
+    // rather than once per file.
+    let mut configs: HashMap<PathBuf, IgnoreConfig> = HashMap::new();
+    for path in files {
+        if let Ok(bytes) = std::fs::read(path) {
+            let dir = path.parent().unwrap_or(path);
+            let config = configs.entry(dir.to_path_buf()).or_insert_with(|| load_config(dir, options.profile));
+            let hash = content_hash(&bytes, path, config);
+            let cached = cache.and_then(|c| c.get(&hash));
+            if let Some(mut report) = cached {
+                report.metadata.file_path = Some(path.clone());
+                results.push((path.clone(), report));
+            } else if let Ok(report) = analyze_file_with(path, options) {
+                results.push((path.clone(), report));
             }
-            let ext = path
-                .extension()
-                .and_then(|e| e.to_str())
-                .unwrap_or("");
-            if !supported_exts.contains(&ext) {
-                continue;
-            }
-            let report = analyze_file(&path)
-                .map_err(|e| anyhow::anyhow!("failed to analyze {}: {}", path.display(), e))?;
-            results.push((path, report));
         }
     }
-    Ok(())
 }
 
 /// Analyze a source file and return a `Report` with `symbol_reports` populated.
@@ -266,12 +381,23 @@ fn walk_and_analyze(
 /// Both the base report and the symbol list are served from the
 /// content-addressed cache when available, and written back on a miss.
 pub fn analyze_file_symbols(file_path: &Path) -> anyhow::Result<Report> {
+    analyze_file_symbols_with(file_path, &FileOptions::default())
+}
+
+/// Like [`analyze_file_symbols`], but uses the cache in `cache_dir` instead
+/// of the configured location.
+pub fn analyze_file_symbols_with_cache_dir(file_path: &Path, cache_dir: &Path) -> anyhow::Result<Report> {
+    analyze_file_symbols_with(file_path, &FileOptions { cache_dir: Some(cache_dir), ..FileOptions::default() })
+}
+
+/// [`analyze_file_symbols`] with `options`.
+pub fn analyze_file_symbols_with(file_path: &Path, options: &FileOptions) -> anyhow::Result<Report> {
     let bytes = std::fs::read(file_path)
         .map_err(|e| anyhow::anyhow!("cannot read {}: {}", file_path.display(), e))?;
-    let hash = Cache::hash_content(&bytes);
     let dir = file_path.parent().unwrap_or(file_path);
-    let config = load_config(dir);
-    let cache = open_cache(&config);
+    let config = load_config(dir, options.profile);
+    let hash = content_hash(&bytes, file_path, &config);
+    let cache = if options.no_cache { None } else { open_cache(&config, options.cache_dir) };
 
     // Fast path: both layers cached.
     if let Some(ref c) = cache {
@@ -284,30 +410,56 @@ pub fn analyze_file_symbols(file_path: &Path) -> anyhow::Result<Report> {
 
     let source_str = std::str::from_utf8(&bytes)
         .map_err(|e| anyhow::anyhow!("non-UTF-8 file: {e}"))?;
-    let pipeline = Pipeline::with_defaults();
+    let pipeline = pipeline_from_config(&config);
     let mut report = pipeline.run(source_str, Some(file_path.to_path_buf()));
     let symbol_reports = pipeline.run_symbols(&bytes, file_path)?;
-    report.symbol_reports = Some(symbol_reports.clone());
 
+    // The base entry is the one plain analysis reads, so it is stored
+    // without the symbols.
     if let Some(ref c) = cache {
         let _ = c.put(&hash, &report);
         let _ = c.put_symbols(&hash, &symbol_reports);
     }
+    report.symbol_reports = Some(symbol_reports);
 
     Ok(report)
 }
 
-/// Analyze a source file at symbol level, bypassing the cache entirely.
+/// Split the file at `path` into regions of differing style and score each
+/// (see [`segments`]), using the nearest `.vibecheck` config.  Not cached.
+pub fn segment_file(path: &Path) -> anyhow::Result<Vec<report::SegmentReport>> {
+    segment_file_with(path, &FileOptions::default())
+}
+
+/// [`segment_file`] under `options.profile`.
+pub fn segment_file_with(path: &Path, options: &FileOptions) -> anyhow::Result<Vec<report::SegmentReport>> {
+    let source = std::fs::read_to_string(path)
+        .map_err(|e| anyhow::anyhow!("cannot read {}: {}", path.display(), e))?;
+    let config = load_config(path.parent().unwrap_or(path), options.profile);
+    let pipeline = pipeline_from_config(&config);
+    Ok(pipeline.run_segments(&source, Some(path)))
+}
+
+/// Score the file at `path` again with its comments stripped (see
+/// [`Pipeline::run_comment_free`]), using the nearest `.vibecheck` config.
+/// Not cached.
+pub fn score_without_comments(path: &Path) -> anyhow::Result<report::CommentFreeReport> {
+    score_without_comments_with(path, &FileOptions::default())
+}
+
+/// [`score_without_comments`] under `options.profile`.
+pub fn score_without_comments_with(path: &Path, options: &FileOptions) -> anyhow::Result<report::CommentFreeReport> {
+    let source = std::fs::read_to_string(path)
+        .map_err(|e| anyhow::anyhow!("cannot read {}: {}", path.display(), e))?;
+    let config = load_config(path.parent().unwrap_or(path), options.profile);
+    let pipeline = pipeline_from_config(&config);
+    Ok(pipeline.run_comment_free(&source, Some(path)))
+}
+
+/// Analyze a source file at symbol level under its nearest `.vibecheck`
+/// config, bypassing the cache entirely.
 pub fn analyze_file_symbols_no_cache(file_path: &Path) -> anyhow::Result<Report> {
-    let bytes = std::fs::read(file_path)
-        .map_err(|e| anyhow::anyhow!("cannot read {}: {}", file_path.display(), e))?;
-    let source_str = std::str::from_utf8(&bytes)
-        .map_err(|e| anyhow::anyhow!("non-UTF-8 file: {e}"))?;
-    let pipeline = Pipeline::with_defaults();
-    let mut report = pipeline.run(source_str, Some(file_path.to_path_buf()));
-    let symbol_reports = pipeline.run_symbols(&bytes, file_path)?;
-    report.symbol_reports = Some(symbol_reports);
-    Ok(report)
+    analyze_file_symbols_with(file_path, &FileOptions { no_cache: true, ..FileOptions::default() })
 }
 
 #[cfg(test)]
@@ -401,6 +553,22 @@ mod tests {
         assert_eq!(r2.metadata.file_path, Some(path));
     }
 
+    #[test]
+    fn analyze_file_with_cache_dir_populates_given_dir() {
+        let cache_dir = tempfile::tempdir().unwrap();
+        let mut f = tempfile::NamedTempFile::new().unwrap();
+        writeln!(f, "{}", sample_rust_source(40)).unwrap();
+        let path = f.path().to_path_buf();
+
+        let r1 = analyze_file_with_cache_dir(&path, cache_dir.path()).unwrap();
+        assert!(cache_dir.path().join("cache.redb").exists());
+
+        let cache = Cache::open(cache_dir.path()).unwrap();
+        let bytes = std::fs::read(&path).unwrap();
+        let cached = cache.get(&Cache::hash_content(&bytes)).expect("report should be cached");
+        assert_eq!(cached.metadata.lines_of_code, r1.metadata.lines_of_code);
+    }
+
     #[test]
     fn analyze_file_symbols_works() {
         let mut f = tempfile::NamedTempFile::with_suffix(".rs").unwrap();
@@ -424,6 +592,37 @@ mod tests {
         );
     }
 
+    #[test]
+    fn symbol_analysis_caches_what_plain_analysis_would_compute() {
+        let dir = tempfile::tempdir().unwrap();
+        let cache_dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join(".vibecheck"), "[heuristics]\n\"rust.errors.zero_unwrap\" = 3.0\n").unwrap();
+        let path = dir.path().join("lib.rs");
+        std::fs::write(&path, format!("fn hello() {{}}\n{}", sample_rust_source(40))).unwrap();
+
+        let symbols = analyze_file_symbols_with_cache_dir(&path, cache_dir.path()).unwrap();
+        assert!(symbols.symbol_reports.is_some());
+        // Served from the entry the symbol run wrote.
+        let cached = analyze_file_with_cache_dir(&path, cache_dir.path()).unwrap();
+        let fresh = analyze_file_no_cache(&path).unwrap();
+        assert!(cached.symbol_reports.is_none());
+        assert_eq!(serde_json::to_value(&cached).unwrap(), serde_json::to_value(&fresh).unwrap());
+    }
+
+    #[test]
+    fn uncached_symbol_analysis_uses_the_config() {
+        let dir = tempfile::tempdir().unwrap();
+        let cache_dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join(".vibecheck"), "[heuristics]\n\"rust.errors.zero_unwrap\" = 3.0\n").unwrap();
+        let path = dir.path().join("lib.rs");
+        std::fs::write(&path, format!("fn hello() {{}}\n{}", sample_rust_source(40))).unwrap();
+
+        let uncached = analyze_file_symbols_no_cache(&path).unwrap();
+        assert!(uncached.signals.iter().any(|s| s.id == "rust.errors.zero_unwrap" && s.weight == 3.0));
+        let cached = analyze_file_symbols_with_cache_dir(&path, cache_dir.path()).unwrap();
+        assert_eq!(serde_json::to_value(&uncached).unwrap(), serde_json::to_value(&cached).unwrap());
+    }
+
     #[test]
     fn analyze_directory_public_wrapper_finds_rust_file() {
         let dir = tempfile::tempdir().unwrap();
@@ -465,4 +664,17 @@ mod tests {
             analyze_directory_with(dir.path(), false, &PatternIgnore(vec!["generated".into()])).unwrap();
         assert!(results.is_empty());
     }
+
+    #[test]
+    fn analyze_directory_with_skips_generated_files_unless_included() {
+        let dir = tempfile::tempdir().unwrap();
+        let header = "// Code generated by protoc-gen-rust. DO NOT EDIT.\n";
+        std::fs::write(dir.path().join("pb.rs"), format!("{header}{}", sample_rust_source(40))).unwrap();
+        std::fs::write(dir.path().join("main.rs"), sample_rust_source(40)).unwrap();
+        let results = analyze_directory_with(dir.path(), false, &PatternIgnore(vec![])).unwrap();
+        assert_eq!(results.len(), 1);
+        assert!(results[0].0.ends_with("main.rs"));
+        let results = analyze_directory_with(dir.path(), false, &AllowAll).unwrap();
+        assert_eq!(results.len(), 2);
+    }
 }
@@ -0,0 +1,42 @@
+//! Size limits that keep enormous files out of a scan.
+//!
+//! Bundled or minified assets — a 20 MB `vendor.min.js` — say nothing about
+//! authorship, and parsing one can take more memory than the rest of the
+//! repository put together.  Files over the limit are reported with
+//! [`Report::skipped`](crate::report::Report::skipped) and
+//! [`SKIPPED_TOO_LARGE`] instead of being read.
+
+/// `ReportMetadata::skipped` reason for files over the size limit.
+pub const SKIPPED_TOO_LARGE: &str = "too_large";
+
+/// Default `--max-file-size`: far above any hand-written source file.
+pub const DEFAULT_MAX_FILE_SIZE: u64 = 1024 * 1024;
+
+/// Rough peak memory per byte of source while a file is analyzed: the
+/// source itself, the tree-sitter CST (several nodes per token) and the
+/// per-line text analyzer state.
+pub const MEMORY_PER_SOURCE_BYTE: u64 = 32;
+
+/// The per-file size limit implied by an explicit `max_file_size` and a
+/// total `memory_hint`, whichever is tighter.  `None` means no limit.
+pub fn effective_max_file_size(max_file_size: Option<u64>, memory_hint: Option<u64>) -> Option<u64> {
+    let from_hint = memory_hint.map(|bytes| bytes / MEMORY_PER_SOURCE_BYTE);
+    match (max_file_size, from_hint) {
+        (Some(a), Some(b)) => Some(a.min(b)),
+        (a, b) => a.or(b),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn tighter_limit_wins() {
+        assert_eq!(effective_max_file_size(None, None), None);
+        assert_eq!(effective_max_file_size(Some(100), None), Some(100));
+        assert_eq!(effective_max_file_size(None, Some(3200)), Some(100));
+        assert_eq!(effective_max_file_size(Some(50), Some(3200)), Some(50));
+        assert_eq!(effective_max_file_size(Some(500), Some(3200)), Some(100));
+    }
+}
@@ -0,0 +1,132 @@
+//! Bounded least-recently-used map used by the in-memory cache tier.
+//!
+//! Recency is tracked with a monotonically increasing tick per entry and a
+//! `BTreeMap` from tick to key, so lookups, inserts and evictions are all
+//! `O(log n)` rather than the `O(n)` queue scan of a naive `VecDeque` LRU.
+//!
+//! Even `get` mutates, so the map is not synchronized itself; shared
+//! users wrap it in a `Mutex` (see `cache::InMemoryBackend`).
+
+use std::collections::{BTreeMap, HashMap};
+use std::hash::Hash;
+
+pub(crate) struct LruCache<K, V> {
+    entries: HashMap<K, (V, u64)>,
+    order: BTreeMap<u64, K>,
+    tick: u64,
+    capacity: usize,
+}
+
+impl<K: Eq + Hash + Clone, V> LruCache<K, V> {
+    /// Create a cache holding at most `capacity` entries. A capacity of zero
+    /// disables storage entirely.
+    pub(crate) fn new(capacity: usize) -> Self {
+        Self {
+            entries: HashMap::new(),
+            order: BTreeMap::new(),
+            tick: 0,
+            capacity,
+        }
+    }
+
+    fn next_tick(&mut self) -> u64 {
+        self.tick += 1;
+        self.tick
+    }
+
+    /// Look up `key`, marking it as most recently used.
+    pub(crate) fn get(&mut self, key: &K) -> Option<&V> {
+        let tick = self.next_tick();
+        let (_, last) = self.entries.get_mut(key)?;
+        let old = std::mem::replace(last, tick);
+        if let Some(k) = self.order.remove(&old) {
+            self.order.insert(tick, k);
+        }
+        self.entries.get(key).map(|(v, _)| v)
+    }
+
+    /// Check for `key` without affecting recency.
+    pub(crate) fn contains(&self, key: &K) -> bool {
+        self.entries.contains_key(key)
+    }
+
+    /// Insert or replace `key`, evicting the least recently used entry when full.
+    pub(crate) fn put(&mut self, key: K, value: V) {
+        if self.capacity == 0 {
+            return;
+        }
+        let tick = self.next_tick();
+        if let Some((_, old)) = self.entries.insert(key.clone(), (value, tick)) {
+            self.order.remove(&old);
+        } else if self.entries.len() > self.capacity {
+            if let Some((_, oldest)) = self.order.pop_first() {
+                self.entries.remove(&oldest);
+            }
+        }
+        self.order.insert(tick, key);
+    }
+
+    /// Remove `key`, returning its value if present.
+    pub(crate) fn remove(&mut self, key: &K) -> Option<V> {
+        let (value, tick) = self.entries.remove(key)?;
+        self.order.remove(&tick);
+        Some(value)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn evicts_least_recently_used() {
+        let mut lru = LruCache::new(2);
+        lru.put("a", 1);
+        lru.put("b", 2);
+        lru.put("c", 3);
+
+        assert!(lru.get(&"a").is_none());
+        assert_eq!(lru.get(&"b"), Some(&2));
+        assert_eq!(lru.get(&"c"), Some(&3));
+    }
+
+    #[test]
+    fn get_promotes_entry() {
+        let mut lru = LruCache::new(2);
+        lru.put("a", 1);
+        lru.put("b", 2);
+        lru.get(&"a");
+        lru.put("c", 3);
+
+        assert!(lru.contains(&"a"));
+        assert!(!lru.contains(&"b"));
+        assert!(lru.contains(&"c"));
+    }
+
+    #[test]
+    fn update_replaces_value_without_eviction() {
+        let mut lru = LruCache::new(2);
+        lru.put("a", 1);
+        lru.put("b", 2);
+        lru.put("a", 10);
+
+        assert_eq!(lru.get(&"a"), Some(&10));
+        assert_eq!(lru.get(&"b"), Some(&2));
+    }
+
+    #[test]
+    fn remove_drops_entry() {
+        let mut lru = LruCache::new(2);
+        lru.put("a", 1);
+        assert_eq!(lru.remove(&"a"), Some(1));
+        assert!(lru.remove(&"a").is_none());
+        assert!(!lru.contains(&"a"));
+    }
+
+    #[test]
+    fn zero_capacity_stores_nothing() {
+        let mut lru = LruCache::new(0);
+        lru.put("a", 1);
+        assert!(!lru.contains(&"a"));
+    }
+}
@@ -0,0 +1,132 @@
+//! Jupyter notebook (`.ipynb`) support.
+//!
+//! A notebook is JSON, not source code, so it is flattened into a plain
+//! Python file before analysis: the code cells are concatenated in order,
+//! separated by blank lines.  Markdown cells are dropped — prose between
+//! cells is how notebooks are meant to be written, so it says nothing about
+//! who wrote the code.  IPython magics (`%timeit`, `%%bash`) and shell
+//! escapes (`!pip install …`) are not Python and are dropped too.
+//! Notebooks for other kernels (R, Julia) are reported with
+//! [`Report::skipped`](crate::report::Report::skipped) and
+//! [`SKIPPED_UNSUPPORTED_KERNEL`].
+
+use std::path::Path;
+
+/// `ReportMetadata::skipped` reason for notebooks whose kernel language is
+/// not Python.
+pub const SKIPPED_UNSUPPORTED_KERNEL: &str = "unsupported_kernel";
+
+/// Return `true` if `path` is a Jupyter notebook.
+pub fn is_notebook(path: &Path) -> bool {
+    path.extension().map(|e| e == "ipynb").unwrap_or(false)
+}
+
+/// Extract the Python source of a notebook's code cells.
+///
+/// Returns `None` if `json` is not a notebook or its kernel language is not
+/// Python (R and Julia notebooks use the same file format).
+pub fn python_source(json: &str) -> Option<String> {
+    let nb: serde_json::Value = serde_json::from_str(json).ok()?;
+    let cells = nb.get("cells")?.as_array()?;
+
+    if language(&nb).map(|l| !l.eq_ignore_ascii_case("python")).unwrap_or(false) {
+        return None;
+    }
+
+    let mut out = String::new();
+    for cell in cells {
+        if cell.get("cell_type").and_then(|t| t.as_str()) != Some("code") {
+            continue;
+        }
+        let text = match cell.get("source") {
+            Some(serde_json::Value::String(s)) => s.clone(),
+            Some(serde_json::Value::Array(lines)) => lines.iter().filter_map(|l| l.as_str()).collect(),
+            _ => continue,
+        };
+        // A `%%` cell magic makes the whole cell non-Python.
+        if text.trim_start().starts_with("%%") {
+            continue;
+        }
+        let code: Vec<&str> = text
+            .lines()
+            .filter(|l| {
+                let t = l.trim_start();
+                !t.starts_with('%') && !t.starts_with('!')
+            })
+            .collect();
+        if code.iter().all(|l| l.trim().is_empty()) {
+            continue;
+        }
+        if !out.is_empty() {
+            out.push('\n');
+        }
+        for line in code {
+            out.push_str(line);
+            out.push('\n');
+        }
+    }
+    Some(out)
+}
+
+/// The kernel language of a notebook that declares one other than Python
+/// (`"R"`, `"julia"`), or `None` for Python notebooks and anything that is
+/// not a notebook.
+pub fn unsupported_kernel(json: &str) -> Option<String> {
+    let nb: serde_json::Value = serde_json::from_str(json).ok()?;
+    nb.get("cells")?.as_array()?;
+    language(&nb).filter(|l| !l.eq_ignore_ascii_case("python")).map(String::from)
+}
+
+/// The kernel language `nb` declares, if any.
+fn language(nb: &serde_json::Value) -> Option<&str> {
+    let metadata = nb.get("metadata");
+    metadata
+        .and_then(|m| m.pointer("/language_info/name"))
+        .or_else(|| metadata.and_then(|m| m.pointer("/kernelspec/language")))
+        .and_then(|l| l.as_str())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn is_notebook_by_extension() {
+        assert!(is_notebook(Path::new("analysis.ipynb")));
+        assert!(!is_notebook(Path::new("analysis.py")));
+    }
+
+    #[test]
+    fn extracts_code_cells_in_order() {
+        let nb = r##"{
+            "metadata": {"kernelspec": {"language": "python"}},
+            "cells": [
+                {"cell_type": "markdown", "source": ["# Title\n", "Some prose"]},
+                {"cell_type": "code", "source": ["import pandas as pd\n", "df = pd.read_csv('x.csv')"]},
+                {"cell_type": "code", "source": "df.head()"}
+            ]
+        }"##;
+        let src = python_source(nb).unwrap();
+        assert_eq!(src, "import pandas as pd\ndf = pd.read_csv('x.csv')\n\ndf.head()\n");
+    }
+
+    #[test]
+    fn drops_magics_and_shell_escapes() {
+        let nb = r##"{"cells": [
+            {"cell_type": "code", "source": ["%matplotlib inline\n", "!pip install numpy\n", "x = 1"]},
+            {"cell_type": "code", "source": ["%%bash\n", "ls -la"]}
+        ]}"##;
+        assert_eq!(python_source(nb).unwrap(), "x = 1\n");
+    }
+
+    #[test]
+    fn rejects_non_python_kernels_and_invalid_json() {
+        let nb = r#"{"metadata": {"language_info": {"name": "R"}}, "cells": []}"#;
+        assert!(python_source(nb).is_none());
+        assert_eq!(unsupported_kernel(nb).as_deref(), Some("R"));
+        assert!(python_source("not json").is_none());
+        assert!(python_source("{}").is_none());
+        assert!(unsupported_kernel("not json").is_none());
+        assert!(unsupported_kernel(r#"{"metadata": {"kernelspec": {"language": "python"}}, "cells": []}"#).is_none());
+    }
+}
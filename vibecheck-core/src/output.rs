@@ -1,4 +1,17 @@
-use crate::report::Report;
+use crate::remediation;
+use crate::report::{ModelFamily, Report, Severity, Signal};
+
+/// Evidence listed per file by [`format_markdown`].
+const MARKDOWN_EVIDENCE: usize = 3;
+
+/// First line of every [`format_markdown`] document, so a bot can find and
+/// update its earlier comment instead of posting a new one.
+pub const MARKDOWN_MARKER: &str = "<!-- vibecheck-report -->";
+
+/// Drop in AI probability from stripping comments (see
+/// [`Report::comment_reliance`]) at which the verdict is reported as resting
+/// on comments.
+pub const COMMENT_RELIANT: f64 = 0.25;
 
 /// Output format for CLI.
 #[derive(Debug, Clone, Copy, PartialEq, Eq)]
@@ -6,6 +19,16 @@ pub enum OutputFormat {
     Pretty,
     Text,
     Json,
+    /// Self-contained HTML document; see [`crate::html`].
+    Html,
+    /// Summary table for a PR comment; see [`format_markdown`].
+    Markdown,
+    /// JUnit XML test report; see [`format_junit`].
+    Junit,
+    /// SPDX provenance document; see [`crate::provenance`].
+    Provenance,
+    /// `file:line:col: rule: message` lines; see [`format_annotations`].
+    Annotations,
 }
 
 /// Format a report as JSON.
@@ -13,6 +36,86 @@ pub fn format_json(report: &Report) -> String {
     serde_json::to_string_pretty(report).expect("report should be serializable")
 }
 
+/// Format a report as JSON with a top-level `remediation` array of
+/// [`remediation::Remediation`] entries alongside the usual fields.
+pub fn format_json_with_remediation(report: &Report) -> String {
+    serde_json::to_string_pretty(&json_with_remediation(report)).expect("report should be serializable")
+}
+
+/// The report as a JSON value with its remediation suggestions attached.
+///
+/// Exposed so callers serializing many reports as one array can attach
+/// suggestions per report.
+pub fn json_with_remediation(report: &Report) -> serde_json::Value {
+    let mut value = serde_json::to_value(report).expect("report should be serializable");
+    value["remediation"] =
+        serde_json::to_value(remediation::for_report(report)).expect("remediation should be serializable");
+    value
+}
+
+/// The comment-free verdict of `report`, when it has one: the verdict and
+/// AI probability with comments stripped, the change from the full
+/// probability, and a warning when the verdict rests on comments.
+pub fn comment_free_summary(report: &Report) -> Option<String> {
+    let stripped = report.comment_free.as_ref()?;
+    let mut out = if stripped.attribution.has_sufficient_data() {
+        format!("{} ({:.0}% confidence)", stripped.attribution.primary, stripped.attribution.confidence * 100.0)
+    } else {
+        "Insufficient data".to_string()
+    };
+    if let Some(p) = stripped.attribution.ai_probability {
+        out.push_str(&format!(", AI probability {p:.2}"));
+        if let Some(full) = report.attribution.ai_probability {
+            out.push_str(&format!(" ({:+.2})", p - full));
+        }
+    }
+    if report.comment_reliance().is_some_and(|r| r >= COMMENT_RELIANT) {
+        out.push_str(" — the verdict rests on comments");
+    }
+    Some(out)
+}
+
+/// The member verdicts of an ensemble-scored `report`, e.g. `logistic:
+/// Claude (71%), heuristic: Human (58%)`.
+pub fn ensemble_verdicts(report: &Report) -> Option<String> {
+    let verdicts: Vec<String> = report
+        .ensemble
+        .as_ref()?
+        .verdicts
+        .iter()
+        .map(|v| match v.confidence > 0.0 {
+            true => format!("{}: {} ({:.0}%)", v.backend, v.primary, v.confidence * 100.0),
+            false => format!("{}: insufficient data", v.backend),
+        })
+        .collect();
+    Some(verdicts.join(", "))
+}
+
+/// [`ensemble_verdicts`], ending in a call for manual review when the
+/// backends split between human and AI.
+pub fn ensemble_summary(report: &Report) -> Option<String> {
+    let mut out = ensemble_verdicts(report)?;
+    if report.needs_review() {
+        out.push_str(" — backends disagree; review manually");
+    }
+    Some(out)
+}
+
+/// Format the remediation suggestions for a report as plain text.
+///
+/// Returns an empty string when no AI-attributed finding has a suggestion.
+pub fn format_remediation_text(report: &Report) -> String {
+    let fixes = remediation::for_report(report);
+    if fixes.is_empty() {
+        return String::new();
+    }
+    let mut out = String::from("\nRemediation:\n");
+    for fix in &fixes {
+        out.push_str(&format!("  [{:<10}] {}\n", fix.category, fix.suggestion));
+    }
+    out
+}
+
 /// Format a report as plain text (no colors).
 pub fn format_text(report: &Report) -> String {
     let mut out = String::new();
@@ -26,14 +129,52 @@ pub fn format_text(report: &Report) -> String {
             report.attribution.primary,
             report.attribution.confidence * 100.0
         ));
+    } else if let Some(ref reason) = report.metadata.skipped {
+        out.push_str(&format!("Verdict: skipped: {reason}\n"));
     } else {
         out.push_str("Verdict: Insufficient data\n");
     }
+    if let Some(p) = report.attribution.ai_probability {
+        out.push_str(&format!("AI probability: {p:.2} likely AI-generated\n"));
+    }
+    if let Some(authorship) = report.attribution.authorship {
+        out.push_str(&format!("Authorship: {authorship}\n"));
+    }
+    if let Some(stripped) = comment_free_summary(report) {
+        out.push_str(&format!("Without comments: {stripped}\n"));
+    }
+    if let Some(backends) = ensemble_summary(report) {
+        out.push_str(&format!("Backends: {backends}\n"));
+    }
+    if let Some(ref era) = report.attribution.era {
+        out.push_str(&format!("Era: {era}\n"));
+    }
+    if !report.metadata.degraded.is_empty() {
+        let caps: Vec<String> = report.metadata.degraded.iter().map(|c| c.to_string()).collect();
+        out.push_str(&format!("Degraded: {} unavailable\n", caps.join(", ")));
+    }
     out.push_str(&format!(
         "Lines: {} | Signals: {}\n",
         report.metadata.lines_of_code, report.metadata.signal_count
     ));
 
+    if let Some(ref segments) = report.segments {
+        out.push_str("\nSegments:\n");
+        if segments.len() < 2 {
+            out.push_str("  uniform style\n");
+        } else {
+            for seg in segments {
+                out.push_str(&format!(
+                    "  L{}–{}  {} ({:.0}%)\n",
+                    seg.start_line,
+                    seg.end_line,
+                    seg.attribution.primary,
+                    seg.attribution.confidence * 100.0
+                ));
+            }
+        }
+    }
+
     out.push_str("\nScores:\n");
     let mut sorted_scores: Vec<_> = report.attribution.scores.iter().collect();
     sorted_scores.sort_by(|a, b| b.1.partial_cmp(a.1).unwrap().then_with(|| a.0.to_string().cmp(&b.0.to_string())));
@@ -46,24 +187,234 @@ pub fn format_text(report: &Report) -> String {
         for signal in &report.signals {
             let sign = if signal.weight >= 0.0 { "+" } else { "" };
             out.push_str(&format!(
-                "  [{:<10}] {}{:.1} {} — {}\n",
+                "  [{:<10}] {}{:.1} {} — {}",
                 signal.source, sign, signal.weight, signal.family, signal.description
             ));
+            if let Some(lines) = signal.lines_label() {
+                out.push_str(&format!(" ({lines})"));
+            }
+            if signal.severity > Severity::Info {
+                out.push_str(&format!(" [{}]", signal.severity));
+            }
+            out.push('\n');
         }
     }
 
     out
 }
 
+/// Format reports as a Markdown summary for a pull request comment: one
+/// table row per file with its AI score, verdict and the strongest AI
+/// evidence, most likely AI-generated first.
+///
+/// The score is the calibrated `ai_probability`.  Evidence is the three
+/// AI-family signals with the most weight, with the lines they point at.
+pub fn format_markdown(reports: &[Report]) -> String {
+    let flagged = reports
+        .iter()
+        .filter(|r| r.attribution.has_sufficient_data() && r.attribution.primary != ModelFamily::Human)
+        .count();
+
+    let mut out = format!("{MARKDOWN_MARKER}\n### vibecheck\n\n");
+    out.push_str(&format!(
+        "**{flagged} of {} file{}** attributed to AI.\n\n",
+        reports.len(),
+        if reports.len() == 1 { "" } else { "s" },
+    ));
+    if reports.is_empty() {
+        return out;
+    }
+
+    let mut sorted: Vec<&Report> = reports.iter().collect();
+    sorted.sort_by(|a, b| {
+        let score = |r: &Report| r.attribution.ai_probability.unwrap_or(-1.0);
+        score(b).partial_cmp(&score(a)).unwrap().then_with(|| a.metadata.file_path.cmp(&b.metadata.file_path))
+    });
+
+    out.push_str("| File | AI score | Verdict | Top evidence |\n");
+    out.push_str("|------|---------:|---------|--------------|\n");
+    for r in sorted {
+        let path = r
+            .metadata
+            .file_path
+            .as_ref()
+            .map(|p| p.display().to_string())
+            .unwrap_or_else(|| "<stdin>".into());
+        let score = r.attribution.ai_probability.map_or("—".to_string(), |p| format!("{p:.2}"));
+        let verdict = if r.attribution.has_sufficient_data() {
+            format!("{} ({:.0}%)", r.attribution.primary, r.attribution.confidence * 100.0)
+        } else if let Some(ref reason) = r.metadata.skipped {
+            format!("skipped: {reason}")
+        } else {
+            "Insufficient data".to_string()
+        };
+
+        let mut evidence: Vec<&Signal> = r
+            .signals
+            .iter()
+            .filter(|s| s.family != ModelFamily::Human && s.weight > 0.0)
+            .collect();
+        evidence.sort_by(|a, b| b.weight.partial_cmp(&a.weight).unwrap().then_with(|| a.id.cmp(&b.id)));
+        let evidence: Vec<String> = evidence
+            .iter()
+            .take(MARKDOWN_EVIDENCE)
+            .map(|s| match s.lines_label() {
+                Some(lines) => format!("{} ({lines})", markdown_cell(&s.description)),
+                None => markdown_cell(&s.description),
+            })
+            .collect();
+
+        out.push_str(&format!(
+            "| `{}` | {score} | {verdict} | {} |\n",
+            path.replace('`', "'"),
+            if evidence.is_empty() { "—".to_string() } else { evidence.join("<br>") },
+        ));
+    }
+    out
+}
+
+/// AI probability at or above which [`format_junit`] fails a file unless
+/// the caller sets another.
+pub const DEFAULT_JUNIT_THRESHOLD: f64 = 0.5;
+
+/// Format reports as a JUnit XML test report, for CI systems that only
+/// surface test results.
+///
+/// Each file is a test case, named by its file name and classed by its
+/// directory so dashboards group it with its neighbours.  A file whose
+/// calibrated `ai_probability` is at or above `threshold` fails: the
+/// failure message carries the verdict and the body lists every AI-family
+/// finding with the lines behind it.  Files without enough signal data for
+/// a verdict are skipped.
+pub fn format_junit(reports: &[Report], threshold: f64) -> String {
+    let mut cases = String::new();
+    let (mut failures, mut skipped) = (0, 0);
+    for r in reports {
+        let path = r.metadata.file_path.clone().unwrap_or_else(|| "<stdin>".into());
+        let name = path.file_name().map_or_else(|| path.display().to_string(), |n| n.to_string_lossy().into_owned());
+        let class = match path.parent().map(|p| p.display().to_string()) {
+            Some(dir) if !dir.is_empty() => format!("vibecheck.{}", dir.replace(['/', '\\'], ".")),
+            _ => "vibecheck".to_string(),
+        };
+        cases.push_str(&format!(
+            "    <testcase classname=\"{}\" name=\"{}\" file=\"{}\" time=\"0\"",
+            xml_escape(&class),
+            xml_escape(&name),
+            xml_escape(&path.display().to_string()),
+        ));
+
+        let probability = r.attribution.ai_probability.filter(|_| r.attribution.has_sufficient_data());
+        match probability {
+            None => {
+                skipped += 1;
+                let message = match r.metadata.skipped {
+                    Some(ref reason) => format!("skipped: {reason}"),
+                    None => "insufficient signal data for a verdict".to_string(),
+                };
+                cases.push_str(&format!(
+                    ">\n      <skipped message=\"{}\"/>\n    </testcase>\n",
+                    xml_escape(&message)
+                ));
+            }
+            Some(p) if p >= threshold => {
+                failures += 1;
+                let message = format!(
+                    "{} ({:.0}% confidence), AI probability {p:.2} >= {threshold:.2}",
+                    r.attribution.primary,
+                    r.attribution.confidence * 100.0,
+                );
+                let mut body = String::new();
+                for s in r.signals.iter().filter(|s| s.family != ModelFamily::Human && s.weight > 0.0) {
+                    body.push_str(&format!("[{}] {:+.1} {} {}", s.id, s.weight, s.family, s.description));
+                    if let Some(lines) = s.lines_label() {
+                        body.push_str(&format!(" ({lines})"));
+                    }
+                    body.push('\n');
+                }
+                cases.push_str(&format!(
+                    ">\n      <failure type=\"vibecheck.ai_generated\" message=\"{}\">{}</failure>\n    </testcase>\n",
+                    xml_escape(&message),
+                    xml_escape(&body),
+                ));
+            }
+            Some(_) => cases.push_str("/>\n"),
+        }
+    }
+
+    let counts = format!("tests=\"{}\" failures=\"{failures}\" errors=\"0\" skipped=\"{skipped}\"", reports.len());
+    format!(
+        "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<testsuites name=\"vibecheck\" {counts}>\n  \
+         <testsuite name=\"vibecheck\" {counts}>\n{cases}  </testsuite>\n</testsuites>\n"
+    )
+}
+
+/// Format reports as one `file:line:col: rule: message` line per AI-leaning
+/// finding, the layout Vim's quickfix list, Emacs `compilation-mode` and
+/// most editors' error parsers pick up without configuration.
+///
+/// A finding that points at several lines is listed once per line, in line
+/// order; one that points at none is anchored at the top of its file.
+/// Findings carry no column, so the column is always 1.  Human-leaning
+/// signals and skipped files produce no lines.
+pub fn format_annotations(reports: &[Report]) -> String {
+    let mut out = String::new();
+    for r in reports {
+        let path = r.metadata.file_path.as_ref().map_or_else(|| "<stdin>".to_string(), |p| p.display().to_string());
+        let mut lines: Vec<(usize, &Signal)> = r
+            .signals
+            .iter()
+            .filter(|s| s.is_ai_leaning())
+            .flat_map(|s| match s.lines.as_slice() {
+                [] => vec![(1, s)],
+                lines => lines.iter().map(|&l| (l.max(1), s)).collect(),
+            })
+            .collect();
+        lines.sort_by(|a, b| a.0.cmp(&b.0).then_with(|| a.1.id.cmp(&b.1.id)));
+        for (line, s) in lines {
+            out.push_str(&format!(
+                "{path}:{line}:1: {}: {} [{} {:+.1}]\n",
+                if s.id.is_empty() { &s.source } else { &s.id },
+                s.description.replace('\n', " "),
+                s.family,
+                s.weight,
+            ));
+        }
+    }
+    out
+}
+
+/// `text` made safe for XML text and attribute values.  Control characters
+/// XML 1.0 cannot carry are dropped.
+fn xml_escape(text: &str) -> String {
+    let mut out = String::with_capacity(text.len());
+    for c in text.chars() {
+        match c {
+            '&' => out.push_str("&amp;"),
+            '<' => out.push_str("&lt;"),
+            '>' => out.push_str("&gt;"),
+            '"' => out.push_str("&quot;"),
+            '\n' | '\t' | '\r' => out.push(c),
+            c if c.is_control() => {}
+            c => out.push(c),
+        }
+    }
+    out
+}
+
+/// `text` made safe for a Markdown table cell.
+fn markdown_cell(text: &str) -> String {
+    text.replace('|', "\\|").replace('<', "&lt;").replace('\n', " ")
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
     use crate::report::{Attribution, ModelFamily, ReportMetadata, Signal};
-    use std::collections::HashMap;
+    use std::collections::BTreeMap;
     use std::path::PathBuf;
 
     fn make_report(with_path: bool, with_signals: bool) -> Report {
-        let mut scores = HashMap::new();
+        let mut scores = BTreeMap::new();
         scores.insert(ModelFamily::Claude, 0.8);
         scores.insert(ModelFamily::Human, 0.2);
         let signals = if with_signals {
@@ -76,14 +427,22 @@ mod tests {
                 primary: ModelFamily::Claude,
                 confidence: 0.8,
                 scores,
+                era: None,
+                ai_probability: None,
+                authorship: None,
             },
             signals,
             metadata: ReportMetadata {
                 file_path: if with_path { Some(PathBuf::from("src/main.rs")) } else { None },
                 lines_of_code: 42,
                 signal_count: if with_signals { 1 } else { 0 },
+                degraded: vec![],
+                skipped: None,
             },
             symbol_reports: None,
+            segments: None,
+            comment_free: None,
+            ensemble: None,
         }
     }
 
@@ -119,28 +478,221 @@ mod tests {
         assert!(json.contains("claude"));
     }
 
+    #[test]
+    fn format_text_shows_era_when_present() {
+        let mut report = make_report(false, false);
+        assert!(!format_text(&report).contains("Era:"));
+        report.attribution.era = Some("claude-2025-terse".into());
+        assert!(format_text(&report).contains("Era: claude-2025-terse"));
+    }
+
+    #[test]
+    fn format_text_shows_ai_probability_when_calibrated() {
+        let mut report = make_report(false, false);
+        assert!(!format_text(&report).contains("AI probability:"));
+        report.attribution.ai_probability = Some(0.873);
+        assert!(format_text(&report).contains("AI probability: 0.87 likely AI-generated"));
+        assert!(!format_text(&report).contains("Authorship:"));
+        report.attribution.authorship = Some(crate::report::Authorship::AiAssisted);
+        assert!(format_text(&report).contains("Authorship: AI-assisted"));
+    }
+
+    #[test]
+    fn format_text_marks_degraded_verdicts() {
+        let mut report = make_report(false, false);
+        assert!(!format_text(&report).contains("Degraded:"));
+        report.metadata.degraded = vec![crate::capability::Capability::CstParsing];
+        assert!(format_text(&report).contains("Degraded: cst_parsing unavailable"));
+        let json: serde_json::Value = serde_json::from_str(&format_json(&report)).unwrap();
+        assert_eq!(json["metadata"]["degraded"][0], "cst_parsing");
+    }
+
     #[test]
     fn format_text_insufficient_data() {
-        let scores = HashMap::new();
+        let scores = BTreeMap::new();
         let report = Report {
             attribution: Attribution {
                 primary: ModelFamily::Human,
                 confidence: 0.0,
                 scores,
+                era: None,
+                ai_probability: None,
+                authorship: None,
             },
             signals: vec![],
             metadata: ReportMetadata {
                 file_path: Some(PathBuf::from("config.toml")),
                 lines_of_code: 10,
                 signal_count: 0,
+                degraded: vec![],
+                skipped: None,
             },
             symbol_reports: None,
+            segments: None,
+            comment_free: None,
+            ensemble: None,
         };
         let out = format_text(&report);
         assert!(out.contains("Insufficient data"), "expected 'Insufficient data' in output: {out}");
         assert!(!out.contains("confidence"));
     }
 
+    #[test]
+    fn format_remediation_text_lists_categories() {
+        let report = make_report(false, true);
+        let out = format_remediation_text(&report);
+        assert!(out.contains("Remediation:"));
+        assert!(out.contains("[errors"));
+        assert!(format_remediation_text(&make_report(false, false)).is_empty());
+    }
+
+    #[test]
+    fn format_json_with_remediation_adds_field() {
+        let report = make_report(false, true);
+        let value: serde_json::Value = serde_json::from_str(&format_json_with_remediation(&report)).unwrap();
+        assert_eq!(value["remediation"][0]["category"], "errors");
+        assert_eq!(value["remediation"][0]["signals"][0], "rust.errors.zero_unwrap");
+        assert!(value["attribution"].is_object());
+    }
+
+    #[test]
+    fn format_markdown_ranks_files_and_lists_evidence() {
+        let mut ai = make_report(true, true);
+        ai.attribution.ai_probability = Some(0.91);
+        ai.signals[0].lines = vec![3, 7];
+        ai.signals.push(Signal::new("rust.naming.x", "naming", "Names | pipes", ModelFamily::Gpt, 2.0));
+        ai.signals.push(Signal::new("rust.errors.many_unwraps", "errors", "Many unwraps", ModelFamily::Human, 3.0));
+        let mut human = make_report(true, false);
+        human.metadata.file_path = Some(PathBuf::from("a.rs"));
+        human.attribution.primary = ModelFamily::Human;
+        human.attribution.ai_probability = Some(0.12);
+
+        let md = format_markdown(&[human, ai]);
+        assert!(md.starts_with(MARKDOWN_MARKER));
+        assert!(md.contains("**1 of 2 files** attributed to AI."));
+        let rows: Vec<&str> = md.lines().filter(|l| l.starts_with("| `")).collect();
+        assert_eq!(
+            rows[0],
+            "| `src/main.rs` | 0.91 | Claude (80%) | Names \\| pipes<br>No .unwrap() calls (lines 3, 7) |"
+        );
+        assert_eq!(rows[1], "| `a.rs` | 0.12 | Human (80%) | — |");
+    }
+
+    #[test]
+    fn format_junit_fails_files_over_threshold() {
+        let mut ai = make_report(true, true);
+        ai.attribution.ai_probability = Some(0.91);
+        ai.signals[0].lines = vec![4];
+        ai.signals.push(Signal::new("rust.errors.many_unwraps", "errors", "Many <unwraps>", ModelFamily::Human, 3.0));
+        let mut under = make_report(true, false);
+        under.metadata.file_path = Some(PathBuf::from("lib.rs"));
+        under.attribution.ai_probability = Some(0.3);
+        let mut unknown = make_report(false, false);
+        unknown.attribution.confidence = 0.0;
+
+        let xml = format_junit(&[ai, under, unknown], DEFAULT_JUNIT_THRESHOLD);
+        assert!(xml.starts_with("<?xml version=\"1.0\" encoding=\"UTF-8\"?>"));
+        assert!(xml.contains("<testsuite name=\"vibecheck\" tests=\"3\" failures=\"1\" errors=\"0\" skipped=\"1\">"));
+        assert!(xml.contains(
+            "<testcase classname=\"vibecheck.src\" name=\"main.rs\" file=\"src/main.rs\" time=\"0\">\n      \
+             <failure type=\"vibecheck.ai_generated\" message=\"Claude (80% confidence), AI probability 0.91 &gt;= 0.50\">\
+             [rust.errors.zero_unwrap] +1.5 Claude No .unwrap() calls (line 4)\n</failure>"
+        ), "{xml}");
+        assert!(!xml.contains("unwraps"), "human-family signals are not findings");
+        assert!(xml.contains("<testcase classname=\"vibecheck\" name=\"lib.rs\" file=\"lib.rs\" time=\"0\"/>"));
+        assert!(xml.contains("name=\"&lt;stdin&gt;\""));
+        assert!(xml.contains("<skipped message="));
+        assert!(format_junit(&[], 0.5).contains("tests=\"0\""));
+    }
+
+    #[test]
+    fn format_annotations_lists_findings_by_line() {
+        let mut ai = make_report(true, true);
+        ai.signals[0].lines = vec![9, 4];
+        ai.signals.push(Signal::new("rust.naming.verbose", "naming", "Long\nnames", ModelFamily::Gpt, 0.5));
+        ai.signals.push(Signal::new("rust.errors.many_unwraps", "errors", "Many unwraps", ModelFamily::Human, 3.0));
+        let mut stdin = make_report(false, true);
+        stdin.signals[0].weight = -1.0;
+
+        let out = format_annotations(&[ai, stdin]);
+        assert_eq!(
+            out.lines().collect::<Vec<_>>(),
+            [
+                "src/main.rs:1:1: rust.naming.verbose: Long names [GPT +0.5]",
+                "src/main.rs:4:1: rust.errors.zero_unwrap: No .unwrap() calls [Claude +1.5]",
+                "src/main.rs:9:1: rust.errors.zero_unwrap: No .unwrap() calls [Claude +1.5]",
+            ]
+        );
+        assert!(format_annotations(&[Report::skipped(PathBuf::from("big.rs"), crate::timeout::SKIPPED_TIMEOUT)]).is_empty());
+    }
+
+    #[test]
+    fn format_text_lists_segments() {
+        let mut report = crate::analyze("fn main() {}\n");
+        assert!(!format_text(&report).contains("Segments:"));
+        let segment = |start_line, end_line| crate::report::SegmentReport {
+            start_line,
+            end_line,
+            attribution: report.attribution.clone(),
+            signals: Vec::new(),
+        };
+        report.segments = Some(vec![segment(1, 1)]);
+        assert!(format_text(&report).contains("\nSegments:\n  uniform style\n"));
+        report.segments = Some(vec![segment(1, 40), segment(41, 90)]);
+        let text = format_text(&report);
+        assert!(text.contains("\n  L1–40  "), "{text}");
+        assert!(text.contains("\n  L41–90  "), "{text}");
+    }
+
+    #[test]
+    fn format_text_compares_comment_free_verdict() {
+        let mut report = crate::analyze("fn main() {}\n");
+        assert!(!format_text(&report).contains("Without comments:"));
+        report.attribution.ai_probability = Some(0.91);
+        let mut stripped = report.attribution.clone();
+        stripped.ai_probability = Some(0.40);
+        report.comment_free = Some(crate::report::CommentFreeReport { attribution: stripped, signal_count: 1 });
+        let text = format_text(&report);
+        assert!(text.contains(", AI probability 0.40 (-0.51) — the verdict rests on comments\n"), "{text}");
+
+        report.comment_free.as_mut().unwrap().attribution.ai_probability = Some(0.85);
+        let text = format_text(&report);
+        assert!(text.contains(", AI probability 0.85 (-0.06)\n"), "{text}");
+    }
+
+    #[test]
+    fn format_text_lists_ensemble_verdicts() {
+        use crate::report::{BackendVerdict, EnsembleReport};
+        let mut report = crate::analyze("fn main() {}\n");
+        assert!(!format_text(&report).contains("Backends:"));
+        let verdict = |backend: &str, primary, confidence| BackendVerdict { backend: backend.into(), primary, confidence };
+        report.ensemble = Some(EnsembleReport {
+            verdicts: vec![verdict("logistic", ModelFamily::Claude, 0.71), verdict("heuristic", ModelFamily::Human, 0.58)],
+            disagreement: true,
+        });
+        let text = format_text(&report);
+        assert!(
+            text.contains("Backends: logistic: Claude (71%), heuristic: Human (58%) — backends disagree; review manually\n"),
+            "{text}"
+        );
+        report.ensemble = Some(EnsembleReport { verdicts: vec![verdict("heuristic", ModelFamily::Human, 0.0)], disagreement: false });
+        assert!(format_text(&report).contains("Backends: heuristic: insufficient data\n"));
+    }
+
+    #[test]
+    fn skipped_reports_show_their_reason() {
+        let report = Report::skipped(PathBuf::from("big.rs"), crate::timeout::SKIPPED_TIMEOUT);
+        assert!(format_text(&report).contains("Verdict: skipped: timeout\n"));
+        assert!(format_markdown(std::slice::from_ref(&report)).contains("| skipped: timeout |"));
+        let xml = format_junit(&[report], DEFAULT_JUNIT_THRESHOLD);
+        assert!(xml.contains("<skipped message=\"skipped: timeout\"/>"), "{xml}");
+    }
+
+    #[test]
+    fn xml_escape_drops_invalid_control_characters() {
+        assert_eq!(xml_escape("a\u{1b}[0m<b>\n"), "a[0m&lt;b&gt;\n");
+    }
+
     #[test]
     fn output_format_eq() {
         assert_eq!(OutputFormat::Pretty, OutputFormat::Pretty);
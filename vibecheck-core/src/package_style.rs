@@ -0,0 +1,359 @@
+//! Package-level consistency of Go code.
+//!
+//! A Go package is written as a whole: its files share receiver names, wrap
+//! errors one way and group their imports one way.  An assistant writing a
+//! file sees none of its siblings, so the file it adds tends to follow its
+//! own habits instead, even when it looks unremarkable on its own.  This
+//! module reads each package's conventions off its files and lists the
+//! files that break them.
+//!
+//! Like [`crate::fingerprint`], it needs the whole scan, so callers run
+//! [`divergent_files`] once the files are known.
+
+use std::collections::{BTreeMap, HashMap};
+use std::fmt;
+use std::path::{Path, PathBuf};
+
+use tree_sitter::Node;
+
+use crate::language::{get_ts_language, Language};
+use crate::test_files::is_test_path;
+
+/// Other files of a package that must agree on a convention before a file
+/// is held to it.
+const MIN_SIBLINGS: usize = 2;
+
+/// Share of the siblings that must agree for their choice to count as the
+/// package's convention.
+const MIN_AGREEMENT: f64 = 2.0 / 3.0;
+
+/// Classified error returns a file needs before it has a style.
+const MIN_ERROR_RETURNS: usize = 2;
+
+/// Share of a file's error returns its most common style needs for the file
+/// to count as following it; below that the file mixes styles.
+const MIN_ERROR_STYLE_SHARE: f64 = 2.0 / 3.0;
+
+/// A convention a file can break.
+#[derive(Debug, Clone, PartialEq, Eq, PartialOrd, Ord)]
+pub enum Aspect {
+    /// The receiver name of the type's methods.
+    Receiver(String),
+    /// How errors from callees are returned.
+    ErrorHandling,
+    /// Whether standard-library imports are kept in their own group.
+    ImportGrouping,
+}
+
+impl fmt::Display for Aspect {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Aspect::Receiver(ty) => write!(f, "receiver name of {ty}"),
+            Aspect::ErrorHandling => f.write_str("error handling"),
+            Aspect::ImportGrouping => f.write_str("import grouping"),
+        }
+    }
+}
+
+/// One convention a file breaks.
+#[derive(Debug, Clone)]
+pub struct Divergence {
+    pub aspect: Aspect,
+    /// What the file does.
+    pub file: String,
+    /// What the rest of the package does.
+    pub package: String,
+}
+
+/// A Go file that breaks conventions the rest of its package keeps.
+#[derive(Debug, Clone)]
+pub struct DivergentFile {
+    pub path: PathBuf,
+    /// The name in the file's `package` clause.
+    pub package: String,
+    pub divergences: Vec<Divergence>,
+}
+
+/// The conventions one file follows.
+#[derive(Debug, Default)]
+struct Conventions {
+    values: BTreeMap<Aspect, String>,
+}
+
+/// Go files in `files` (path and source pairs) that break their package's
+/// conventions, those breaking the most first.  A package is the Go files
+/// of one directory with the same `package` clause; test files follow
+/// conventions of their own and are left out.
+pub fn divergent_files(files: &[(PathBuf, String)]) -> Vec<DivergentFile> {
+    let mut parser = tree_sitter::Parser::new();
+    parser.set_language(&get_ts_language(Language::Go)).expect("bundled grammar");
+
+    let mut packages: HashMap<(&Path, String), Vec<(&PathBuf, Conventions)>> = HashMap::new();
+    for (path, source) in files {
+        if path.extension().is_none_or(|e| e != "go") || is_test_path(path) {
+            continue;
+        }
+        let Some(tree) = parser.parse(source, None) else {
+            continue;
+        };
+        let root = tree.root_node();
+        let Some(name) = package_name(root, source) else {
+            continue;
+        };
+        let dir = path.parent().unwrap_or(Path::new(""));
+        packages.entry((dir, name)).or_default().push((path, conventions(root, source)));
+    }
+
+    let mut found = Vec::new();
+    for ((_, package), members) in &packages {
+        for (i, (path, own)) in members.iter().enumerate() {
+            let divergences: Vec<Divergence> = own
+                .values
+                .iter()
+                .filter_map(|(aspect, value)| {
+                    let siblings = members
+                        .iter()
+                        .enumerate()
+                        .filter(|&(j, _)| j != i)
+                        .filter_map(|(_, (_, c))| c.values.get(aspect));
+                    let convention = convention(siblings)?;
+                    (convention != value)
+                        .then(|| Divergence { aspect: aspect.clone(), file: value.clone(), package: convention.clone() })
+                })
+                .collect();
+            if !divergences.is_empty() {
+                found.push(DivergentFile { path: (*path).clone(), package: package.clone(), divergences });
+            }
+        }
+    }
+    found.sort_by(|a, b| b.divergences.len().cmp(&a.divergences.len()).then_with(|| a.path.cmp(&b.path)));
+    found
+}
+
+/// The value most of `siblings` agree on, if enough of them do.
+fn convention<'a>(siblings: impl Iterator<Item = &'a String>) -> Option<&'a String> {
+    let mut counts: BTreeMap<&String, usize> = BTreeMap::new();
+    let mut total = 0;
+    for value in siblings {
+        *counts.entry(value).or_default() += 1;
+        total += 1;
+    }
+    let (value, n) = counts.into_iter().max_by_key(|&(_, n)| n)?;
+    (n >= MIN_SIBLINGS && n as f64 >= total as f64 * MIN_AGREEMENT).then_some(value)
+}
+
+fn package_name(root: Node, source: &str) -> Option<String> {
+    let mut cursor = root.walk();
+    let clause = root.children(&mut cursor).find(|n| n.kind() == "package_clause")?;
+    let mut cursor = clause.walk();
+    let name = clause.named_children(&mut cursor).find(|n| n.kind() == "package_identifier")?;
+    Some(text(name, source).to_string())
+}
+
+fn conventions(root: Node, source: &str) -> Conventions {
+    // Type → receiver name → methods using it.
+    let mut receivers: BTreeMap<String, BTreeMap<String, usize>> = BTreeMap::new();
+    let mut error_returns: BTreeMap<&'static str, usize> = BTreeMap::new();
+    let mut imports: Vec<Vec<bool>> = Vec::new();
+
+    let mut stack = vec![root];
+    while let Some(node) = stack.pop() {
+        match node.kind() {
+            "method_declaration" => {
+                if let Some((ty, name)) = node.child_by_field_name("receiver").and_then(|r| receiver(r, source)) {
+                    *receivers.entry(ty).or_default().entry(name).or_default() += 1;
+                }
+            }
+            "return_statement" => {
+                if let Some(style) = error_return_style(node, source) {
+                    *error_returns.entry(style).or_default() += 1;
+                }
+            }
+            "import_declaration" => imports.extend(import_groups(node, source)),
+            _ => {}
+        }
+        let mut cursor = node.walk();
+        for child in node.children(&mut cursor) {
+            stack.push(child);
+        }
+    }
+
+    let mut values = BTreeMap::new();
+    for (ty, names) in receivers {
+        // Ties go to the alphabetically first name.
+        let name = names.iter().max_by(|a, b| a.1.cmp(b.1).then_with(|| b.0.cmp(a.0))).map(|(name, _)| name);
+        if let Some(name) = name {
+            values.insert(Aspect::Receiver(ty), format!("`{name}`"));
+        }
+    }
+    let classified: usize = error_returns.values().sum();
+    if classified >= MIN_ERROR_RETURNS {
+        if let Some((style, n)) = error_returns.into_iter().max_by_key(|&(_, n)| n) {
+            let style = if n as f64 >= classified as f64 * MIN_ERROR_STYLE_SHARE { style } else { "mixes styles" };
+            values.insert(Aspect::ErrorHandling, style.to_string());
+        }
+    }
+    let has = |std: bool| imports.iter().flatten().any(|&s| s == std);
+    if has(true) && has(false) {
+        let apart = imports.iter().all(|group| group.iter().all(|&s| s == group[0]));
+        let layout = if apart { "standard library in its own group" } else { "standard library mixed with other imports" };
+        values.insert(Aspect::ImportGrouping, layout.to_string());
+    }
+    Conventions { values }
+}
+
+/// The receiver's type, without pointer and type parameters, and its name.
+fn receiver(list: Node, source: &str) -> Option<(String, String)> {
+    let mut cursor = list.walk();
+    let param = list.named_children(&mut cursor).find(|n| n.kind() == "parameter_declaration")?;
+    let name = text(param.child_by_field_name("name")?, source).to_string();
+    if name == "_" {
+        return None;
+    }
+    let ty = text(param.child_by_field_name("type")?, source);
+    let ty = ty.trim_start_matches('*');
+    let ty = ty.split('[').next().unwrap_or(ty).trim();
+    Some((ty.to_string(), name))
+}
+
+/// How a `return` statement returns an error: passed up as is (`return
+/// err`), wrapped with `fmt.Errorf("...: %w", err)`, or wrapped with
+/// `errors.Wrap`.  `None` when its last value is none of these.
+fn error_return_style(node: Node, source: &str) -> Option<&'static str> {
+    let values = node.named_child(0)?;
+    let last = values.named_child(values.named_child_count().checked_sub(1)?)?;
+    match last.kind() {
+        "identifier" if text(last, source) == "err" => Some("returns errors unwrapped"),
+        "call_expression" => {
+            let callee = text(last.child_by_field_name("function")?, source);
+            let args = text(last.child_by_field_name("arguments")?, source);
+            match callee {
+                "fmt.Errorf" if args.contains("%w") => Some("wraps errors with fmt.Errorf and %w"),
+                "errors.Wrap" | "errors.Wrapf" | "errors.WithMessage" | "errors.WithMessagef" => {
+                    Some("wraps errors with errors.Wrap")
+                }
+                _ => None,
+            }
+        }
+        _ => None,
+    }
+}
+
+/// The import groups of one declaration — runs of specs not separated by a
+/// blank line — each as whether its specs import the standard library.
+fn import_groups(node: Node, source: &str) -> Vec<Vec<bool>> {
+    let mut specs = Vec::new();
+    let mut stack = vec![node];
+    while let Some(n) = stack.pop() {
+        if n.kind() == "import_spec" {
+            if let Some(path) = n.child_by_field_name("path") {
+                let path = text(path, source).trim_matches(['"', '`']);
+                // Standard-library paths have no domain in their first element.
+                let std = !path.split('/').next().unwrap_or("").contains('.');
+                specs.push((n.start_position().row, n.end_position().row, std));
+            }
+            continue;
+        }
+        let mut cursor = n.walk();
+        for child in n.children(&mut cursor) {
+            stack.push(child);
+        }
+    }
+    specs.sort_unstable();
+
+    let mut groups: Vec<Vec<bool>> = Vec::new();
+    let mut last_row = None;
+    for (start, end, std) in specs {
+        match groups.last_mut() {
+            Some(group) if last_row.is_some_and(|r: usize| start <= r + 1) => group.push(std),
+            _ => groups.push(vec![std]),
+        }
+        last_row = Some(end);
+    }
+    groups
+}
+
+fn text<'s>(node: Node, source: &'s str) -> &'s str {
+    node.utf8_text(source.as_bytes()).unwrap_or("")
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    /// A file of the `cache` package in its house style: receiver `c`,
+    /// errors passed up unwrapped, standard library imported apart.
+    fn house_file(n: usize) -> String {
+        format!(
+            "package cache\n\n\
+             import (\n\t\"fmt\"\n\t\"os\"\n\n\t\"github.com/acme/store\"\n)\n\n\
+             func (c *Cache) Load{n}(p string) error {{\n\
+             \tf, err := os.Open(p)\n\tif err != nil {{\n\t\treturn err\n\t}}\n\
+             \tif err := store.Read(f); err != nil {{\n\t\treturn err\n\t}}\n\
+             \tfmt.Println(c)\n\treturn nil\n}}\n"
+        )
+    }
+
+    fn file(name: &str, source: String) -> (PathBuf, String) {
+        (PathBuf::from(format!("internal/cache/{name}.go")), source)
+    }
+
+    #[test]
+    fn flags_the_file_that_breaks_package_conventions() {
+        let mut files: Vec<_> = (0..3).map(|i| file(&format!("f{i}"), house_file(i))).collect();
+        files.push(file(
+            "evict",
+            "package cache\n\n\
+             import (\n\t\"fmt\"\n\t\"github.com/acme/store\"\n\t\"os\"\n)\n\n\
+             func (cache *Cache) Evict(p string) error {\n\
+             \tf, err := os.Open(p)\n\tif err != nil {\n\t\treturn fmt.Errorf(\"failed to open %s: %w\", p, err)\n\t}\n\
+             \tif err := store.Drop(f); err != nil {\n\t\treturn fmt.Errorf(\"failed to drop: %w\", err)\n\t}\n\
+             \treturn nil\n}\n"
+                .to_string(),
+        ));
+        let found = divergent_files(&files);
+        assert_eq!(found.len(), 1, "{found:?}");
+        assert_eq!(found[0].path, PathBuf::from("internal/cache/evict.go"));
+        assert_eq!(found[0].package, "cache");
+        let by_aspect: BTreeMap<String, (&str, &str)> = found[0]
+            .divergences
+            .iter()
+            .map(|d| (d.aspect.to_string(), (d.file.as_str(), d.package.as_str())))
+            .collect();
+        assert_eq!(by_aspect["receiver name of Cache"], ("`cache`", "`c`"));
+        assert_eq!(by_aspect["error handling"], ("wraps errors with fmt.Errorf and %w", "returns errors unwrapped"));
+        assert_eq!(
+            by_aspect["import grouping"],
+            ("standard library mixed with other imports", "standard library in its own group")
+        );
+    }
+
+    #[test]
+    fn needs_agreeing_siblings_in_the_same_package() {
+        let odd = "package cache\n\nfunc (cache *Cache) Len() int { return 0 }\n".to_string();
+        // One sibling is not a convention.
+        assert!(divergent_files(&[file("a", house_file(0)), file("b", odd.clone())]).is_empty());
+        // Siblings in another directory, or in a test file, are another package.
+        let files = vec![
+            (PathBuf::from("other/a.go"), house_file(0)),
+            (PathBuf::from("other/b.go"), house_file(1)),
+            file("a_test", house_file(2)),
+            file("b", odd),
+        ];
+        assert!(divergent_files(&files).is_empty());
+    }
+
+    #[test]
+    fn import_groups_split_on_blank_lines() {
+        let mut parser = tree_sitter::Parser::new();
+        parser.set_language(&get_ts_language(Language::Go)).unwrap();
+        let source = "package p\n\nimport (\n\t\"fmt\"\n\t\"net/http\"\n\n\t\"golang.org/x/sync/errgroup\"\n)\n\nimport \"os\"\n";
+        let tree = parser.parse(source, None).unwrap();
+        let mut groups = Vec::new();
+        let mut cursor = tree.root_node().walk();
+        for decl in tree.root_node().children(&mut cursor).filter(|n| n.kind() == "import_declaration") {
+            groups.extend(import_groups(decl, source));
+        }
+        assert_eq!(groups, vec![vec![true, true], vec![false], vec![true]]);
+    }
+}
@@ -0,0 +1,176 @@
+//! Unified diff input.
+//!
+//! Review tooling usually has the change as a diff rather than a checkout.
+//! [`parse`] extracts the lines each file gained, with their line numbers in
+//! the new file, so only added code is scored (see
+//! [`Analyzer::analyze_patch`](crate::Analyzer::analyze_patch)) and findings
+//! point at lines the reviewer can see.
+
+use std::path::PathBuf;
+
+use anyhow::Context;
+
+/// A line added by the patch.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct AddedLine {
+    /// 1-based line number in the new file.
+    pub line: usize,
+    pub text: String,
+}
+
+/// The lines a patch adds to one file.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct FilePatch {
+    /// Path of the new file, without the `b/` prefix.
+    pub path: PathBuf,
+    pub added: Vec<AddedLine>,
+}
+
+impl FilePatch {
+    /// The added lines joined into one source text; line `n` of it is
+    /// `added[n - 1]`.
+    pub fn source(&self) -> String {
+        let mut source = String::new();
+        for added in &self.added {
+            source.push_str(&added.text);
+            source.push('\n');
+        }
+        source
+    }
+
+    /// Map a 1-based line of [`source`](Self::source) to its new-file line.
+    pub fn new_line(&self, source_line: usize) -> usize {
+        source_line
+            .checked_sub(1)
+            .and_then(|i| self.added.get(i))
+            .map_or(source_line, |a| a.line)
+    }
+}
+
+/// Parse a unified diff (`git diff`, `diff -u`) into the added lines of each
+/// file, in patch order.  Deleted files and files without added lines
+/// (pure deletions, renames, binary changes) are left out.
+pub fn parse(diff: &str) -> anyhow::Result<Vec<FilePatch>> {
+    let mut files: Vec<FilePatch> = Vec::new();
+    let mut current: Option<FilePatch> = None;
+    // Lines left in the current hunk on the old and new side.
+    let (mut old_left, mut new_left, mut next_line) = (0usize, 0usize, 0usize);
+    let mut seen_header = false;
+
+    for (number, line) in diff.lines().enumerate() {
+        if old_left > 0 || new_left > 0 {
+            match line.as_bytes().first() {
+                Some(b'+') => {
+                    if let Some(ref mut file) = current {
+                        file.added.push(AddedLine { line: next_line, text: line[1..].to_string() });
+                    }
+                    new_left = new_left.saturating_sub(1);
+                    next_line += 1;
+                }
+                Some(b'-') => old_left = old_left.saturating_sub(1),
+                Some(b'\\') => {}
+                // Some tools strip the space from empty context lines.
+                Some(b' ') | None => {
+                    old_left = old_left.saturating_sub(1);
+                    new_left = new_left.saturating_sub(1);
+                    next_line += 1;
+                }
+                Some(_) => anyhow::bail!("line {}: unexpected line inside a hunk", number + 1),
+            }
+            continue;
+        }
+        if let Some(rest) = line.strip_prefix("+++ ") {
+            seen_header = true;
+            files.extend(current.take().filter(|f| !f.added.is_empty()));
+            current = new_file_path(rest).map(|path| FilePatch { path, added: Vec::new() });
+        } else if line.starts_with("@@") {
+            let (old, new, start) =
+                hunk_header(line).with_context(|| format!("line {}: malformed hunk header", number + 1))?;
+            (old_left, new_left, next_line) = (old, new, start);
+        }
+    }
+    files.extend(current.filter(|f| !f.added.is_empty()));
+    anyhow::ensure!(seen_header || diff.trim().is_empty(), "not a unified diff: no `+++` file header");
+    Ok(files)
+}
+
+/// The path on a `+++` line; `None` for `/dev/null` (a deleted file).
+fn new_file_path(rest: &str) -> Option<PathBuf> {
+    // `diff -u` appends a tab and the modification time.
+    let path = rest.split('\t').next().unwrap_or(rest).trim_end();
+    let path = path.trim_matches('"');
+    if path == "/dev/null" {
+        return None;
+    }
+    Some(PathBuf::from(path.strip_prefix("b/").unwrap_or(path)))
+}
+
+/// `(old count, new count, new start)` from `@@ -a,b +c,d @@ context`.
+/// An omitted count is 1.
+fn hunk_header(line: &str) -> Option<(usize, usize, usize)> {
+    let mut ranges = line.strip_prefix("@@ ")?.split(' ');
+    let range = |r: &str| -> Option<(usize, usize)> {
+        match r.split_once(',') {
+            Some((start, count)) => Some((start.parse().ok()?, count.parse().ok()?)),
+            None => Some((r.parse().ok()?, 1)),
+        }
+    };
+    let (_, old) = range(ranges.next()?.strip_prefix('-')?)?;
+    let (start, new) = range(ranges.next()?.strip_prefix('+')?)?;
+    Some((old, new, start))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const DIFF: &str = "\
+diff --git a/src/lib.rs b/src/lib.rs
+index 1111111..2222222 100644
+--- a/src/lib.rs
++++ b/src/lib.rs
+@@ -1,4 +1,5 @@
+ fn a() {}
+-fn b() {}
++fn b() -> u8 { 1 }
++--not a header
+
+ fn c() {}
+@@ -10,2 +11,3 @@ fn d() {
+     let x = 1;
++    let y = 2;
+ }
+diff --git a/old.py b/old.py
+deleted file mode 100644
+--- a/old.py
++++ /dev/null
+@@ -1,1 +0,0 @@
+-print(1)
+";
+
+    #[test]
+    fn parses_added_lines_with_new_file_numbers() {
+        let files = parse(DIFF).unwrap();
+        assert_eq!(files.len(), 1);
+        assert_eq!(files[0].path, PathBuf::from("src/lib.rs"));
+        let lines: Vec<(usize, &str)> = files[0].added.iter().map(|a| (a.line, a.text.as_str())).collect();
+        assert_eq!(lines, vec![(2, "fn b() -> u8 { 1 }"), (3, "--not a header"), (12, "    let y = 2;")]);
+        assert_eq!(files[0].source(), "fn b() -> u8 { 1 }\n--not a header\n    let y = 2;\n");
+        assert_eq!(files[0].new_line(3), 12);
+    }
+
+    #[test]
+    fn plain_diff_u_output_and_new_files() {
+        let diff = "--- /dev/null\t2024-01-01\n+++ new.go\t2024-01-02\n@@ -0,0 +1 @@\n+package main\n";
+        let files = parse(diff).unwrap();
+        assert_eq!(files[0].path, PathBuf::from("new.go"));
+        assert_eq!(files[0].added, vec![AddedLine { line: 1, text: "package main".into() }]);
+    }
+
+    #[test]
+    fn rejects_input_that_is_not_a_diff() {
+        assert!(parse("fn main() {}\n").is_err());
+        assert!(parse("+++ b/a.rs\n@@ -x +1 @@\n").is_err());
+        assert!(parse("").unwrap().is_empty());
+    }
+}
@@ -1,10 +1,28 @@
-use std::collections::HashMap;
+use std::collections::{BTreeMap, HashMap};
 use std::path::{Path, PathBuf};
 
+use crate::analyzers::text::go_era;
 use crate::analyzers::{default_analyzers, default_cst_analyzers, Analyzer, CstAnalyzer};
+use crate::calibration::Calibration;
+use crate::capability::Capability;
+use crate::classifier::{Classifier, HeuristicClassifier};
+use crate::embedding;
+use crate::frontend::frontend_for;
 use crate::heuristics::{all_heuristics, DefaultHeuristics, HeuristicLanguage, HeuristicsProvider};
-use crate::language::{detect_language, get_ts_language, Language};
-use crate::report::{Attribution, ModelFamily, Report, ReportMetadata, Signal, SymbolReport};
+use crate::language::{detect_language, get_ts_language_for_path, is_doc_file, Language};
+use crate::language_pack::LanguagePack;
+use crate::plugin::Plugin;
+use crate::prose;
+use crate::notebook;
+use crate::report::{
+    Attribution, BackendVerdict, CommentFreeReport, EnsembleReport, ModelFamily, Report, ReportMetadata, SegmentReport,
+    Signal, SymbolReport,
+};
+use crate::segments;
+use crate::source_file::SourceFile;
+use crate::structure;
+use crate::test_files::TestFiles;
+use crate::timeout;
 
 /// Match extracted CST metrics against TOML-defined threshold rules to produce signals.
 pub(crate) fn match_metric_signals(
@@ -93,7 +111,7 @@ pub trait PostScorer: Send + Sync {
 ///
 /// `blend = 0.0` → pure heuristic, `blend = 1.0` → pure ML.
 fn blend_attributions(heuristic: &Attribution, ml: &Attribution, blend: f64) -> Attribution {
-    let mut scores = HashMap::new();
+    let mut scores = BTreeMap::new();
     for family in ModelFamily::all() {
         let h = heuristic.scores.get(family).copied().unwrap_or(0.0);
         let m = ml.scores.get(family).copied().unwrap_or(0.0);
@@ -114,16 +132,94 @@ fn blend_attributions(heuristic: &Attribution, ml: &Attribution, blend: f64) ->
         primary,
         confidence,
         scores,
+        // An era only makes sense alongside the family the ML scorer chose.
+        era: ml.era.clone().filter(|_| ml.primary == primary),
+        // Calibrated afterwards, from the blended scores.
+        ai_probability: None,
+        authorship: None,
     }
 }
 
+/// The mean of the `members`' score distributions, over the members that
+/// had data to score.  Unlike [`blend_attributions`], every member counts
+/// the same.
+fn average_attributions(members: &[(String, Attribution)]) -> Attribution {
+    let scored: Vec<&Attribution> = members.iter().map(|(_, a)| a).filter(|a| a.has_sufficient_data()).collect();
+    if scored.is_empty() {
+        return members[0].1.clone();
+    }
+    let mut scores = BTreeMap::new();
+    for family in ModelFamily::all() {
+        let sum: f64 = scored.iter().map(|a| a.scores.get(family).copied().unwrap_or(0.0)).sum();
+        scores.insert(*family, sum / scored.len() as f64);
+    }
+    let mut attribution = crate::classifier::distribution(scores);
+    attribution.era = scored.iter().find(|a| a.primary == attribution.primary).and_then(|a| a.era.clone());
+    attribution
+}
+
+/// The verdict of each ensemble member, and whether those with data split
+/// between human and AI.
+fn ensemble_report(members: &[(String, Attribution)]) -> EnsembleReport {
+    let scored = || members.iter().map(|(_, a)| a).filter(|a| a.has_sufficient_data());
+    let human = scored().any(|a| a.primary == ModelFamily::Human);
+    let ai = scored().any(|a| a.primary != ModelFamily::Human);
+    EnsembleReport {
+        verdicts: members
+            .iter()
+            .map(|(backend, a)| BackendVerdict { backend: backend.clone(), primary: a.primary, confidence: a.confidence })
+            .collect(),
+        disagreement: human && ai,
+    }
+}
+
+/// Observes the detector stages of [`Pipeline::run_profiled`].  Stages do
+/// not nest: each `begin` is followed by its `end` before the next begins.
+pub trait Profiler {
+    fn begin(&mut self, stage: &str);
+    fn end(&mut self, stage: &str);
+}
+
+/// The profiler behind [`Pipeline::run`].
+struct NoProfiler;
+
+impl Profiler for NoProfiler {
+    fn begin(&mut self, _stage: &str) {}
+    fn end(&mut self, _stage: &str) {}
+}
+
+/// Run `f` as the stage `name` of `profiler`.
+fn stage<T>(profiler: &mut dyn Profiler, name: &str, f: impl FnOnce() -> T) -> T {
+    profiler.begin(name);
+    let out = f();
+    profiler.end(name);
+    out
+}
+
+/// What a pipeline run returns once [`timeout::run`] has given up on it.
+/// The caller has stopped waiting, so only its shape matters.
+fn timed_out(file_path: &Option<PathBuf>) -> Report {
+    Report::skipped(file_path.clone().unwrap_or_default(), timeout::SKIPPED_TIMEOUT)
+}
+
 /// Orchestrates analyzers and aggregates their signals into a report.
 pub struct Pipeline {
     analyzers: Vec<Box<dyn Analyzer>>,
     cst_analyzers: Vec<Box<dyn CstAnalyzer>>,
     heuristics: Box<dyn HeuristicsProvider>,
+    classifier: Box<dyn Classifier>,
+    /// Backends scored alongside `classifier`; see [`Pipeline::with_ensemble`].
+    ensemble: Vec<Box<dyn Classifier>>,
+    /// Test files and their weights; see [`Pipeline::with_test_heuristics`].
+    tests: Option<(TestFiles, Box<dyn HeuristicsProvider>)>,
     scorer: Option<Box<dyn PostScorer>>,
     ml_blend: f64,
+    language_packs: &'static [LanguagePack],
+    plugins: &'static [Plugin],
+    /// Maps AI shares to probabilities; see [`Pipeline::with_calibration`].
+    calibration: Calibration,
+    /// Listed as degraded on every report; see [`Pipeline::with_degraded`].
+    degraded: Vec<Capability>,
 }
 
 impl Pipeline {
@@ -141,8 +237,15 @@ impl Pipeline {
             analyzers,
             cst_analyzers,
             heuristics,
+            classifier: Box::new(HeuristicClassifier),
+            ensemble: Vec::new(),
+            tests: None,
             scorer: None,
             ml_blend: 0.0,
+            language_packs: &[],
+            plugins: &[],
+            calibration: Calibration::default(),
+            degraded: Vec::new(),
         }
     }
 
@@ -171,62 +274,277 @@ impl Pipeline {
             analyzers,
             cst_analyzers,
             heuristics,
+            classifier: Box::new(HeuristicClassifier),
+            ensemble: Vec::new(),
+            tests: None,
             scorer: Some(scorer),
             ml_blend: blend.clamp(0.0, 1.0),
+            language_packs: &[],
+            plugins: &[],
+            calibration: Calibration::default(),
+            degraded: Vec::new(),
         }
     }
 
+    /// Score with `classifier` instead of the default weighted sum
+    /// (typically [`crate::ignore_rules::IgnoreConfig::classifier`]).
+    pub fn with_classifier(mut self, classifier: Box<dyn Classifier>) -> Self {
+        self.classifier = classifier;
+        self
+    }
+
+    /// Also score with `members` (typically
+    /// [`crate::ignore_rules::IgnoreConfig::ensemble`]) and average them with
+    /// the classifier, each counting the same.  Every report then lists the
+    /// member verdicts in [`Report::ensemble`], including the
+    /// [`PostScorer`]'s as `ml`, and flags files on which they split between
+    /// human and AI rather than letting the average hide it.  No members
+    /// leaves the classifier to score alone.
+    pub fn with_ensemble(mut self, members: Vec<Box<dyn Classifier>>) -> Self {
+        self.ensemble = members;
+        self
+    }
+
+    /// Score the files `tests` recognizes with `heuristics` instead
+    /// (typically the project's weights under
+    /// [`TestFiles::heuristics_map`]), so that table-driven boilerplate in
+    /// test code is not taken for templated generation.
+    pub fn with_test_heuristics(mut self, tests: TestFiles, heuristics: Box<dyn HeuristicsProvider>) -> Self {
+        self.tests = Some((tests, heuristics));
+        self
+    }
+
+    /// The weights to score the file at `path` with.
+    fn heuristics_for(&self, path: Option<&Path>) -> &dyn HeuristicsProvider {
+        match (&self.tests, path) {
+            (Some((tests, heuristics)), Some(path)) if tests.is_test(path) => &**heuristics,
+            _ => &*self.heuristics,
+        }
+    }
+
+    /// Also analyze files handled by runtime-loaded language packs
+    /// (typically [`crate::language_pack::installed`]).
+    ///
+    /// Files in a built-in language never consult the packs.
+    pub fn with_language_packs(mut self, packs: &'static [LanguagePack]) -> Self {
+        self.language_packs = packs;
+        self
+    }
+
+    /// Also run WebAssembly detector plugins (typically
+    /// [`crate::plugin::installed`]) on the languages they declare.
+    pub fn with_plugins(mut self, plugins: &'static [Plugin]) -> Self {
+        self.plugins = plugins;
+        self
+    }
+
+    /// Calibrate with `calibration` (typically
+    /// [`crate::ignore_rules::IgnoreConfig::calibration`]), which may map
+    /// each language's scores differently, instead of the fixture
+    /// calibration.
+    pub fn with_calibration(mut self, calibration: Calibration) -> Self {
+        self.calibration = calibration;
+        self
+    }
+
+    /// List `capabilities` as degraded on every report: backends the config
+    /// asked for that could not be loaded (typically
+    /// [`crate::ignore_rules::IgnoreConfig::degraded`]), so that verdicts
+    /// scored without them say so.
+    pub fn with_degraded(mut self, capabilities: Vec<Capability>) -> Self {
+        self.degraded = capabilities;
+        self
+    }
+
     pub fn run(&self, source: &str, file_path: Option<PathBuf>) -> Report {
+        self.run_profiled(source, file_path, &mut NoProfiler)
+    }
+
+    /// [`Pipeline::run`], reporting each detector stage to `profiler` as it
+    /// runs.  Stages are named after the text analyzer, CST analyzer,
+    /// language pack or plugin that runs in them, plus `parse` (tree-sitter),
+    /// `shape` (structural metrics), `go_era` and `classify` (weighting,
+    /// scoring and calibration).
+    pub fn run_profiled(&self, source: &str, file_path: Option<PathBuf>, profiler: &mut dyn Profiler) -> Report {
+        let Some(path) = file_path.as_deref().filter(|p| notebook::is_notebook(p)) else {
+            return self.profile_source(source, file_path, profiler);
+        };
+        match notebook::python_source(source) {
+            Some(python) => self.profile_source(&python, file_path, profiler),
+            // An R or Julia notebook has no Python to score; an empty report
+            // would read as a confident human verdict.
+            None if notebook::unsupported_kernel(source).is_some() => {
+                Report::skipped(path.to_path_buf(), notebook::SKIPPED_UNSUPPORTED_KERNEL)
+            }
+            None => self.profile_source("", file_path, profiler),
+        }
+    }
+
+    /// [`Pipeline::run`] on source that is already code (notebooks
+    /// flattened).
+    fn run_source(&self, source: &str, file_path: Option<PathBuf>) -> Report {
+        self.profile_source(source, file_path, &mut NoProfiler)
+    }
+
+    fn profile_source(&self, source: &str, file_path: Option<PathBuf>, profiler: &mut dyn Profiler) -> Report {
         let lang = file_path.as_ref().and_then(|p| detect_language(p));
+        let heuristics = self.heuristics_for(file_path.as_deref());
+        let pack = match (lang, &file_path) {
+            (None, Some(path)) => self.language_packs.iter().find(|p| p.matches(path)),
+            _ => None,
+        };
 
-        let mut signals: Vec<Signal> = self
-            .analyzers
-            .iter()
-            .flat_map(|a| a.analyze_with_language(source, lang))
-            .collect();
+        // Parse and tokenize once; every detector below reads the same
+        // artifact, which is reused outright when these bytes were seen
+        // before (see `source_file`).
+        let frontend = file_path.as_deref().and_then(|p| frontend_for(p, self.language_packs));
+        let file = match frontend {
+            Some(_) => stage(profiler, "parse", || SourceFile::load(source, lang, frontend)),
+            None => SourceFile::load(source, lang, None),
+        };
+
+        // Capabilities that should have contributed but did not.  Attribution
+        // is still normalized over the signals that remain.
+        let mut degraded = self.degraded.clone();
+
+        // Text analyzers are language-specific; pack languages skip them and
+        // are scored from language-agnostic CST metrics only.  Documents get
+        // the prose detectors instead.
+        let mut signals: Vec<Signal> = if file_path.as_deref().is_some_and(is_doc_file) {
+            stage(profiler, "prose", || prose::detect(source))
+        } else if pack.is_some() {
+            Vec::new()
+        } else {
+            let mut signals = Vec::new();
+            for analyzer in &self.analyzers {
+                if timeout::cancelled() {
+                    return timed_out(&file_path);
+                }
+                match stage(profiler, analyzer.name(), || analyzer.try_analyze_file(&file)) {
+                    Ok(found) => signals.extend(found),
+                    Err(capability) if !degraded.contains(&capability) => degraded.push(capability),
+                    Err(_) => {}
+                }
+            }
+            signals
+        };
+
+        // Go idioms are judged against the module's declared Go version,
+        // which only the file's location can tell.
+        if lang == Some(Language::Go) {
+            if let Some(version) = file_path.as_deref().and_then(go_era::module_go_version) {
+                signals.extend(stage(profiler, "go_era", || go_era::anachronisms(source, version)));
+            }
+        }
 
         // CST analysis — extract metrics, match against TOML rules, and
         // accumulate raw metrics for the PostScorer (if configured).
         let mut collected_metrics = HashMap::new();
 
-        if let Some(ref path) = file_path {
-            if let Some(cst_lang) = detect_language(path) {
-                let ts_lang = crate::language::get_ts_language(cst_lang);
-                let mut parser = tree_sitter::Parser::new();
-                if parser.set_language(&ts_lang).is_ok() {
-                    if let Some(tree) = parser.parse(source.as_bytes(), None) {
-                        let cst_heur_lang = HeuristicLanguage::cst_from(cst_lang);
-                        for cst_analyzer in &self.cst_analyzers {
-                            if cst_analyzer.target_language() == cst_lang {
-                                let metrics = cst_analyzer.extract_metrics(&tree, source);
-                                if metrics.is_empty() {
-                                    signals.extend(cst_analyzer.analyze_tree(&tree, source));
-                                } else {
-                                    collected_metrics.extend(
-                                        metrics.iter().map(|(k, &v)| (k.clone(), v)),
-                                    );
-                                    signals.extend(match_metric_signals(
-                                        &metrics,
-                                        cst_heur_lang,
-                                        &*self.heuristics,
-                                    ));
-                                }
+        if let (Some(frontend), Some(cst_lang)) = (frontend, lang) {
+            match &file.tree {
+                Some(tree) => {
+                    let cst_heur_lang = HeuristicLanguage::cst_from(cst_lang);
+                    for cst_analyzer in &self.cst_analyzers {
+                        if timeout::cancelled() {
+                            return timed_out(&file_path);
+                        }
+                        if cst_analyzer.target_language() == cst_lang {
+                            profiler.begin(cst_analyzer.name());
+                            let metrics = cst_analyzer.extract_metrics(tree, source);
+                            if metrics.is_empty() {
+                                signals.extend(cst_analyzer.analyze_tree(tree, source));
+                            } else {
+                                collected_metrics.extend(
+                                    metrics.iter().map(|(k, &v)| (k.clone(), v)),
+                                );
+                                signals.extend(match_metric_signals(
+                                    &metrics,
+                                    cst_heur_lang,
+                                    heuristics,
+                                ));
+                            }
+                            profiler.end(cst_analyzer.name());
+                        }
+                    }
+                    // Shape metrics ignore names and layout, so they hold
+                    // when the rest are laundered by renaming.
+                    let metrics = stage(profiler, "shape", || structure::metrics(tree, frontend));
+                    signals.extend(match_metric_signals(&metrics, cst_heur_lang, heuristics));
+                    collected_metrics.extend(metrics);
+                    // A file of nothing but doc comments (`doc.go`, a
+                    // `//!`-only `lib.rs`) is documentation too.
+                    if let Some(text) = prose::doc_comment_text(&file.tokens, source) {
+                        signals.extend(stage(profiler, "prose", || prose::detect(&text)));
+                    }
+                }
+                None => degraded.push(Capability::CstParsing),
+            }
+        } else if let Some(pack) = pack {
+            match file.tree {
+                Some(_) => {
+                    let metrics = stage(profiler, pack.name(), || pack.extract_metrics(&file));
+                    signals.extend(match_metric_signals(
+                        &metrics,
+                        HeuristicLanguage::PackCst,
+                        heuristics,
+                    ));
+                    collected_metrics.extend(metrics);
+                }
+                None => degraded.push(Capability::LanguagePacks),
+            }
+        }
+
+        // Plugins see the same comment and identifier streams as language
+        // packs.  Their rules are weighted like built-in signals below.
+        if let Some(frontend) = frontend {
+            let plugins: Vec<&Plugin> = self.plugins.iter().filter(|p| p.applies_to(frontend.name())).collect();
+            if !plugins.is_empty() {
+                let mut failed = false;
+                match file.tree {
+                    Some(_) => {
+                        for plugin in plugins {
+                            match stage(profiler, plugin.name(), || plugin.detect(&file.tokens)) {
+                                Ok(found) => signals.extend(found),
+                                Err(_) => failed = true,
                             }
                         }
                     }
+                    None => failed = true,
+                }
+                if failed {
+                    degraded.push(Capability::Plugins);
                 }
             }
         }
 
+        if timeout::cancelled() {
+            return timed_out(&file_path);
+        }
+        profiler.begin("classify");
         for s in &mut signals {
             if !s.id.is_empty() {
-                s.weight = self.heuristics.weight(&s.id);
+                s.weight = heuristics.weight(&s.id);
             }
         }
-        signals.retain(|s| s.id.is_empty() || self.heuristics.is_enabled(&s.id));
+        signals.retain(|s| s.id.is_empty() || heuristics.is_enabled(&s.id));
+        for s in &mut signals {
+            s.severity = heuristics.severity(s);
+        }
 
-        let attribution = if let Some(ref scorer) = self.scorer {
-            let heuristic_attr = self.aggregate(&signals);
+        let base_attr = self.classifier.classify(&signals, &collected_metrics, lang, source);
+        let mut members = None;
+        let base_attr = if self.ensemble.is_empty() {
+            base_attr
+        } else {
+            let mut scored = vec![(self.classifier.name().to_string(), base_attr)];
+            for member in &self.ensemble {
+                scored.push((member.name().to_string(), member.classify(&signals, &collected_metrics, lang, source)));
+            }
+            average_attributions(members.insert(scored))
+        };
+        let mut attribution = if let Some(ref scorer) = self.scorer {
+            let heuristic_attr = base_attr;
             let ml_attr = scorer.rescore(
                 &signals,
                 &collected_metrics,
@@ -234,12 +552,19 @@ impl Pipeline {
                 lang,
                 source,
             );
+            if let Some(ref mut scored) = members {
+                scored.push(("ml".to_string(), ml_attr.clone()));
+            }
             blend_attributions(&heuristic_attr, &ml_attr, self.ml_blend)
         } else {
-            self.aggregate(&signals)
+            base_attr
         };
+        let language = lang.map(|l| HeuristicLanguage::from(l).to_string()).or_else(|| pack.map(|p| p.name().to_string()));
+        self.calibration.calibrate(&mut attribution, language.as_deref());
+        let ensemble = members.as_deref().map(ensemble_report);
+        profiler.end("classify");
 
-        let lines_of_code = source.lines().count();
+        let lines_of_code = file.stats.lines;
         let signal_count = signals.len();
 
         Report {
@@ -249,8 +574,13 @@ impl Pipeline {
                 file_path,
                 lines_of_code,
                 signal_count,
+                degraded,
+                skipped: None,
             },
             symbol_reports: None,
+            segments: None,
+            comment_free: None,
+            ensemble,
         }
     }
 
@@ -264,10 +594,22 @@ impl Pipeline {
             Some(l) => l,
             None => return Ok(vec![]),
         };
+        let notebook_source;
+        let source = if notebook::is_notebook(file_path) {
+            let json = std::str::from_utf8(source)
+                .map_err(|e| anyhow::anyhow!("non-UTF-8 notebook: {e}"))?;
+            notebook_source = notebook::python_source(json).unwrap_or_default();
+            notebook_source.as_bytes()
+        } else {
+            source
+        };
 
         // Parse once and share the tree with both symbol extraction and
         // per-symbol signal collection.
-        let ts_lang = get_ts_language(lang);
+        let ts_lang = match get_ts_language_for_path(file_path) {
+            Some(l) => l,
+            None => return Ok(vec![]),
+        };
         let mut parser = tree_sitter::Parser::new();
         parser
             .set_language(&ts_lang)
@@ -291,7 +633,7 @@ impl Pipeline {
             let range = node.byte_range();
             let symbol_bytes = source.get(range).unwrap_or(b"");
             let symbol_str = std::str::from_utf8(symbol_bytes).unwrap_or("");
-            let sub_report = self.run(symbol_str, Some(file_path.to_path_buf()));
+            let sub_report = self.run_source(symbol_str, Some(file_path.to_path_buf()));
             reports.push(SymbolReport {
                 metadata,
                 attribution: sub_report.attribution,
@@ -302,49 +644,68 @@ impl Pipeline {
         Ok(reports)
     }
 
-    fn aggregate(&self, signals: &[Signal]) -> Attribution {
-        let mut raw_scores: HashMap<ModelFamily, f64> = HashMap::new();
-        for family in ModelFamily::all() {
-            raw_scores.insert(*family, 0.0);
-        }
-
-        for signal in signals {
-            *raw_scores.entry(signal.family).or_insert(0.0) += signal.weight;
-        }
-
-        // Shift all scores so the minimum is 0
-        let min_score = raw_scores.values().cloned().fold(f64::INFINITY, f64::min);
-        let mut shifted: HashMap<ModelFamily, f64> = raw_scores
-            .iter()
-            .map(|(&k, &v)| (k, (v - min_score).max(0.0)))
-            .collect();
+    /// Split `source` into regions of differing style (see
+    /// [`crate::segments`]) and score each on its own, so a human skeleton
+    /// with AI-filled functions gets one verdict per region rather than a
+    /// single blended score.  Notebooks are segmented on their flattened
+    /// Python source.
+    pub fn run_segments(&self, source: &str, file_path: Option<&Path>) -> Vec<SegmentReport> {
+        let flattened;
+        let source = match file_path.filter(|p| notebook::is_notebook(p)) {
+            Some(_) => {
+                flattened = notebook::python_source(source).unwrap_or_default();
+                flattened.as_str()
+            }
+            None => source,
+        };
+        let lines: Vec<&str> = source.lines().collect();
+        segments::boundaries(source)
+            .into_iter()
+            .map(|range| {
+                let text = lines[range.clone()].join("\n");
+                let report = self.run_source(&text, file_path.map(Path::to_path_buf));
+                let signals = report
+                    .signals
+                    .into_iter()
+                    .map(|mut s| {
+                        s.lines.iter_mut().for_each(|l| *l += range.start);
+                        s
+                    })
+                    .collect();
+                SegmentReport {
+                    start_line: range.start + 1,
+                    end_line: range.end,
+                    attribution: report.attribution,
+                    signals,
+                }
+            })
+            .collect()
+    }
 
-        // Normalize to a distribution summing to 1.0
-        let total: f64 = shifted.values().sum();
-        if total > 0.0 {
-            for v in shifted.values_mut() {
-                *v /= total;
+    /// Score `source` again with every comment removed (see
+    /// [`embedding::code_only`]), so the verdict can be compared with one
+    /// that anyone could get by deleting the comments.  Notebooks are
+    /// stripped on their flattened Python source.
+    pub fn run_comment_free(&self, source: &str, file_path: Option<&Path>) -> CommentFreeReport {
+        let flattened;
+        let source = match file_path.filter(|p| notebook::is_notebook(p)) {
+            Some(_) => {
+                flattened = notebook::python_source(source).unwrap_or_default();
+                flattened.as_str()
             }
-        } else {
-            // No signal data — leave all scores at 0.0, confidence 0.0
-            return Attribution {
-                primary: ModelFamily::Human,
-                confidence: 0.0,
-                scores: shifted,
-            };
-        }
+            None => source,
+        };
+        let code = embedding::code_only(source, file_path.and_then(detect_language));
+        let report = self.run_source(&code, file_path.map(Path::to_path_buf));
+        CommentFreeReport { attribution: report.attribution, signal_count: report.metadata.signal_count }
+    }
 
-        let (primary, confidence) = shifted
-            .iter()
-            .max_by(|a, b| a.1.partial_cmp(b.1).unwrap().then_with(|| a.0.to_string().cmp(&b.0.to_string())))
-            .map(|(&k, &v)| (k, v))
-            .unwrap();
-
-        Attribution {
-            primary,
-            confidence,
-            scores: shifted,
-        }
+    /// Combine already-weighted `signals` into a family score distribution
+    /// with the weighted-sum [`HeuristicClassifier`], whatever classifier the
+    /// pipeline is configured with.
+    /// The result is uncalibrated: `ai_probability` is left unset.
+    pub fn aggregate(&self, signals: &[Signal]) -> Attribution {
+        HeuristicClassifier::score(signals)
     }
 }
 
@@ -352,6 +713,55 @@ impl Pipeline {
 mod tests {
     use super::*;
 
+    #[derive(Default)]
+    struct Recorder {
+        open: Option<String>,
+        stages: Vec<String>,
+    }
+
+    impl Profiler for Recorder {
+        fn begin(&mut self, stage: &str) {
+            assert!(self.open.is_none(), "{stage} began inside {:?}", self.open);
+            self.open = Some(stage.to_string());
+        }
+        fn end(&mut self, stage: &str) {
+            assert_eq!(self.open.take().as_deref(), Some(stage));
+            self.stages.push(stage.to_string());
+        }
+    }
+
+    #[test]
+    fn reports_serialize_identically_across_runs() {
+        let source = "// Helper function to add two numbers together.\nfn add(a: i32, b: i32) -> i32 {\n    a + b\n}\n";
+        let runs: Vec<String> = (0..8)
+            .map(|_| serde_json::to_string(&Pipeline::with_defaults().run(source, Some(PathBuf::from("add.rs")))).unwrap())
+            .collect();
+        assert!(runs.iter().all(|r| *r == runs[0]));
+        let positions: Vec<usize> = ModelFamily::all()
+            .iter()
+            .map(|f| runs[0].find(&format!("\"{}\":", f.to_string().to_lowercase())).unwrap())
+            .collect();
+        assert!(positions.windows(2).all(|w| w[0] < w[1]), "scores not in family order: {}", runs[0]);
+    }
+
+    #[test]
+    fn run_profiled_reports_every_stage_and_the_same_report() {
+        let source = "fn add(a: i32, b: i32) -> i32 {\n    a + b\n}\n";
+        let path = Some(PathBuf::from("add.rs"));
+        let pipeline = Pipeline::with_defaults();
+        let mut recorder = Recorder::default();
+        let report = pipeline.run_profiled(source, path.clone(), &mut recorder);
+
+        let ids = |r: &Report| r.signals.iter().map(|s| s.id.clone()).collect::<Vec<_>>();
+        assert_eq!(ids(&report), ids(&pipeline.run(source, path)));
+        let expected: Vec<String> = std::iter::once("parse")
+            .chain(default_analyzers().iter().map(|a| a.name()))
+            .chain(["rust_cst", "shape", "classify"])
+            .map(String::from)
+            .collect();
+        assert_eq!(recorder.stages, expected);
+    }
+
     #[test]
     fn run_symbols_returns_one_report_per_function() {
         let source = b"fn add(a: i32, b: i32) -> i32 { a + b }\nfn sub(a: i32, b: i32) -> i32 { a - b }\n";
@@ -414,6 +824,130 @@ mod tests {
         assert!(names.contains(&"baz"), "expected 'baz' function; got: {:?}", names);
     }
 
+    #[test]
+    fn run_symbols_typescript_parses_type_annotations() {
+        let source = b"function add(a: number, b: number): number {\n  return a + b;\n}\n\ninterface Point { x: number }\n";
+        let dir = tempfile::tempdir().unwrap();
+        let path = dir.path().join("math.ts");
+        std::fs::write(&path, source).unwrap();
+
+        let pipeline = Pipeline::with_defaults();
+        let reports = pipeline.run_symbols(source, &path).unwrap();
+        assert!(reports.iter().any(|r| r.metadata.name == "add"),
+            "expected 'add'; got: {:?}", reports.iter().map(|r| &r.metadata.name).collect::<Vec<_>>());
+    }
+
+    #[test]
+    fn run_analyzes_notebook_code_cells_as_python() {
+        let nb = r##"{"cells": [
+            {"cell_type": "markdown", "source": "# Notes"},
+            {"cell_type": "code", "source": ["def total(xs):\n", "    return sum(xs)\n"]}
+        ]}"##;
+        let pipeline = Pipeline::with_defaults();
+        let report = pipeline.run(nb, Some(PathBuf::from("analysis.ipynb")));
+        assert_eq!(report.metadata.lines_of_code, 2);
+
+        let reports = pipeline.run_symbols(nb.as_bytes(), Path::new("analysis.ipynb")).unwrap();
+        assert!(reports.iter().any(|r| r.metadata.name == "total"));
+    }
+
+    #[test]
+    fn run_skips_notebooks_of_other_kernels() {
+        let nb = r##"{"metadata": {"kernelspec": {"language": "R"}}, "cells": [
+            {"cell_type": "code", "source": ["total <- function(xs) sum(xs)\n"]}
+        ]}"##;
+        let report = Pipeline::with_defaults().run(nb, Some(PathBuf::from("analysis.ipynb")));
+        assert_eq!(report.metadata.skipped.as_deref(), Some(notebook::SKIPPED_UNSUPPORTED_KERNEL));
+        assert!(report.attribution.scores.is_empty());
+    }
+
+    #[test]
+    fn run_stops_between_analyzers_once_timed_out() {
+        use std::sync::mpsc::{self, Receiver, Sender};
+        use std::sync::{Arc, Mutex};
+        use std::time::Duration;
+
+        // Each call reports itself, then blocks until the test releases it.
+        struct Gated {
+            called: Sender<()>,
+            release: Arc<Mutex<Receiver<()>>>,
+        }
+        impl Analyzer for Gated {
+            fn name(&self) -> &str {
+                "gated"
+            }
+            fn analyze(&self, _source: &str) -> Vec<Signal> {
+                self.called.send(()).unwrap();
+                let _ = self.release.lock().unwrap().recv();
+                Vec::new()
+            }
+        }
+
+        let (called, calls) = mpsc::channel();
+        let (release, gate) = mpsc::channel::<()>();
+        let gate = Arc::new(Mutex::new(gate));
+        let analyzers: Vec<Box<dyn Analyzer>> = (0..20)
+            .map(|_| Box::new(Gated { called: called.clone(), release: Arc::clone(&gate) }) as _)
+            .collect();
+        drop(called);
+        let pipeline = Pipeline::with_heuristics(analyzers, Vec::new(), Box::new(DefaultHeuristics));
+        // The first analyzer holds the worker until released, so the limit
+        // always expires first.
+        let run = timeout::run(Duration::from_millis(20), move || pipeline.run("x = 1\n", Some(PathBuf::from("x.py"))));
+        assert!(run.is_none());
+        drop(release);
+        // The senders go away with the pipeline, once the worker returns.
+        assert_eq!(calls.iter().count(), 1, "the worker went on to later analyzers");
+    }
+
+    #[test]
+    fn run_lists_config_capabilities_as_degraded() {
+        let source = "def total(xs):\n    return sum(xs)\n";
+        let pipeline = Pipeline::with_defaults().with_degraded(vec![Capability::Classifier]);
+        let report = pipeline.run(source, Some(PathBuf::from("total.py")));
+        assert!(report.metadata.degraded.contains(&Capability::Classifier));
+        assert!(Pipeline::with_defaults().run(source, None).metadata.degraded.is_empty());
+    }
+
+    #[test]
+    fn run_judges_go_idioms_against_module_version() {
+        let source = "package main\n\nfunc Keys(m map[string]interface{}) {}\n";
+        let dir = tempfile::tempdir().unwrap();
+        let path = dir.path().join("keys.go");
+        let pipeline = Pipeline::with_defaults();
+        let era_signals = |report: Report| report.signals.iter().filter(|s| s.source == "era").count();
+
+        assert_eq!(era_signals(pipeline.run(source, Some(path.clone()))), 0, "no go.mod, no version");
+        std::fs::write(dir.path().join("go.mod"), "module m\n\ngo 1.17\n").unwrap();
+        assert_eq!(era_signals(pipeline.run(source, Some(path.clone()))), 0);
+        std::fs::write(dir.path().join("go.mod"), "module m\n\ngo 1.22\n").unwrap();
+        assert_eq!(era_signals(pipeline.run(source, Some(path))), 1);
+    }
+
+    #[test]
+    fn run_comment_free_scores_code_without_comments() {
+        let source = "\
+# Calculate the total of the provided values, ensuring that every value is
+# validated before it is added to the running total.
+def total(values):
+    # Initialize the running total that accumulates every value.
+    result = 0
+    for value in values:  # Iterate over each value in the collection.
+        result += value
+    # Return the computed total to the caller.
+    return result
+";
+        let code = "def total(values):\n    result = 0\n    for value in values:\n        result += value\n    return result\n";
+        let path = Path::new("total.py");
+        let pipeline = Pipeline::with_defaults();
+
+        let stripped = pipeline.run_comment_free(source, Some(path));
+        let by_hand = pipeline.run(code, Some(path.to_path_buf()));
+        assert_eq!(stripped.signal_count, by_hand.metadata.signal_count);
+        assert_eq!(stripped.attribution.primary, by_hand.attribution.primary);
+        assert!(stripped.signal_count < pipeline.run(source, Some(path.to_path_buf())).metadata.signal_count);
+    }
+
     #[test]
     fn aggregate_empty_signals_returns_zero_confidence() {
         let pipeline = Pipeline::with_defaults();
@@ -424,6 +958,19 @@ mod tests {
         assert_eq!(total, 0.0, "scores should all be 0.0 when no signals");
     }
 
+    #[test]
+    fn aggregate_negative_weight_counts_against_ai_families() {
+        let pipeline = Pipeline::with_defaults();
+        let claude = Signal::new("t.claude", "t", "claude", ModelFamily::Claude, 3.0);
+        let gpt = Signal::new("t.gpt", "t", "gpt", ModelFamily::Gpt, 1.0);
+        let before = pipeline.aggregate(&[claude.clone(), gpt.clone()]);
+        let ticket = Signal::new("t.ticket", "humanity", "ticket", ModelFamily::Human, -2.0);
+        let after = pipeline.aggregate(&[claude, gpt, ticket]);
+        assert_eq!(after.primary, ModelFamily::Claude);
+        assert!(after.scores[&ModelFamily::Human] > before.scores[&ModelFamily::Human]);
+        assert!(after.scores[&ModelFamily::Claude] < before.scores[&ModelFamily::Claude]);
+    }
+
     // -- PostScorer / blend tests ------------------------------------------
 
     struct FixedScorer(Attribution);
@@ -441,12 +988,34 @@ mod tests {
         }
     }
 
+    struct FixedClassifier(&'static str, Attribution);
+
+    impl Classifier for FixedClassifier {
+        fn name(&self) -> &str {
+            self.0
+        }
+
+        fn classify(&self, _: &[Signal], _: &HashMap<String, f64>, _: Option<Language>, _: &str) -> Attribution {
+            self.1.clone()
+        }
+    }
+
     fn make_attribution(primary: ModelFamily, confidence: f64) -> Attribution {
-        let mut scores = HashMap::new();
+        let mut scores = BTreeMap::new();
         for f in ModelFamily::all() {
             scores.insert(*f, if *f == primary { confidence } else { (1.0 - confidence) / 4.0 });
         }
-        Attribution { primary, confidence, scores }
+        Attribution { primary, confidence, scores, era: None, ai_probability: None, authorship: None }
+    }
+
+    #[test]
+    fn blend_keeps_ml_era_only_when_families_agree() {
+        let h = make_attribution(ModelFamily::Claude, 0.8);
+        let mut ml = make_attribution(ModelFamily::Gpt, 0.9);
+        ml.era = Some("gpt-2025-terse".into());
+
+        assert_eq!(blend_attributions(&h, &ml, 1.0).era.as_deref(), Some("gpt-2025-terse"));
+        assert_eq!(blend_attributions(&h, &ml, 0.0).era, None);
     }
 
     #[test]
@@ -486,6 +1055,69 @@ mod tests {
         assert!((total - 1.0).abs() < 1e-9, "blended scores should sum to ~1.0; got {total}");
     }
 
+    #[test]
+    fn average_counts_members_equally_and_skips_empty_ones() {
+        let mut empty = make_attribution(ModelFamily::Human, 0.0);
+        empty.scores.values_mut().for_each(|v| *v = 0.0);
+        let mut gpt = make_attribution(ModelFamily::Gpt, 0.6);
+        gpt.era = Some("gpt-2025-terse".into());
+        let members = vec![
+            ("a".to_string(), make_attribution(ModelFamily::Human, 0.6)),
+            ("b".to_string(), empty),
+            ("c".to_string(), gpt),
+        ];
+        let averaged = average_attributions(&members);
+        assert!((averaged.scores[&ModelFamily::Human] - 0.35).abs() < 1e-9);
+        assert!((averaged.scores[&ModelFamily::Gpt] - 0.35).abs() < 1e-9);
+        assert_eq!(averaged.era.is_some(), averaged.primary == ModelFamily::Gpt);
+        let total: f64 = averaged.scores.values().sum();
+        assert!((total - 1.0).abs() < 1e-9, "{total}");
+    }
+
+    #[test]
+    fn ensemble_reports_backends_that_split_on_human_vs_ai() {
+        let pipeline = |members: Vec<Box<dyn Classifier>>| {
+            Pipeline::with_model(
+                default_analyzers(),
+                default_cst_analyzers(),
+                Box::new(DefaultHeuristics),
+                Box::new(FixedScorer(make_attribution(ModelFamily::Claude, 0.7))),
+                0.5,
+            )
+            .with_classifier(Box::new(FixedClassifier("logistic", make_attribution(ModelFamily::Claude, 0.8))))
+            .with_ensemble(members)
+        };
+
+        let report = pipeline(Vec::new()).run("fn main() {}\n", None);
+        assert!(report.ensemble.is_none() && !report.needs_review());
+
+        let human: Box<dyn Classifier> = Box::new(FixedClassifier("heuristic", make_attribution(ModelFamily::Human, 0.9)));
+        let report = pipeline(vec![human]).run("fn main() {}\n", None);
+        let ensemble = report.ensemble.as_ref().unwrap();
+        let backends: Vec<&str> = ensemble.verdicts.iter().map(|v| v.backend.as_str()).collect();
+        assert_eq!(backends, ["logistic", "heuristic", "ml"]);
+        assert_eq!(ensemble.verdicts[1].primary, ModelFamily::Human);
+        assert!(ensemble.disagreement && report.needs_review());
+
+        let gpt: Box<dyn Classifier> = Box::new(FixedClassifier("heuristic", make_attribution(ModelFamily::Gpt, 0.9)));
+        assert!(!pipeline(vec![gpt]).run("fn main() {}\n", None).needs_review(), "AI families differing is no split");
+    }
+
+    #[test]
+    fn test_files_are_scored_with_the_test_weights() {
+        let source = "package cache\n\nimport \"testing\"\n\nfunc TestGet(t *testing.T) {\n\ttests := []struct {\n\t\tname string\n\t\twant int\n\t}{\n\t\t{\"empty\", 0},\n\t\t{\"one\", 1},\n\t}\n\tfor _, tt := range tests {\n\t\t_ = tt\n\t}\n}\n";
+        let weights = crate::test_files::default_weights().clone();
+        let pipeline = Pipeline::with_defaults().with_test_heuristics(
+            TestFiles::new(Path::new("/repo"), &[], HashMap::new()),
+            Box::new(crate::heuristics::ConfiguredHeuristics::from_config(weights)),
+        );
+        let ids = |path: &str| -> Vec<String> {
+            pipeline.run(source, Some(PathBuf::from(path))).signals.into_iter().map(|s| s.id).collect()
+        };
+        assert!(ids("/repo/cache/lru.go").iter().any(|id| id == "go.idioms.table_driven_tests"));
+        assert!(!ids("/repo/cache/lru_test.go").iter().any(|id| id == "go.idioms.table_driven_tests"));
+    }
+
     #[test]
     fn with_model_scorer_is_called() {
         let ml_attr = make_attribution(ModelFamily::Gemini, 0.95);
@@ -0,0 +1,439 @@
+//! Detector plugins compiled to WebAssembly.
+//!
+//! A plugin is a TOML manifest plus a `.wasm` module dropped into the
+//! plugins directory.  It lets a team ship organization-specific rules
+//! without forking or rebuilding vibecheck: the module sees each file's
+//! comments and identifiers and emits findings against the rules its
+//! manifest declares.
+//!
+//! ```toml
+//! # ~/.config/vibecheck/plugins/acme.toml
+//! name      = "acme"
+//! module    = "acme_rules.wasm"      # relative to the manifest
+//! languages = ["go", "python"]       # default: every language
+//! fuel      = 10_000_000             # instruction budget per file
+//!
+//! [[rule]]
+//! id          = "acme.comments.ticketless_todo"
+//! description = "TODO without a ticket reference"
+//! family      = "human"
+//! weight      = 0.8
+//! ```
+//!
+//! # Host API
+//!
+//! The module exports its `memory` and a `detect()` function, called once
+//! per file on a fresh instance.  It imports three functions from the
+//! `vibecheck` namespace:
+//!
+//! - `comments(ptr: i32, cap: i32) -> i32` and
+//!   `identifiers(ptr: i32, cap: i32) -> i32` write the file's comments
+//!   (`[{"line": 3, "end_line": 4, "text": "// ..."}]`) or identifiers
+//!   (`[{"line": 7, "name": "cfg"}]`) as JSON to `ptr` when they fit in
+//!   `cap` bytes, and return the length either way, so a call with `cap`
+//!   0 sizes the buffer.
+//! - `emit(ptr: i32, len: i32)` reports a finding, the JSON object
+//!   `{"id": "acme.comments.ticketless_todo", "lines": [12]}`.  `lines` is
+//!   optional.  Each rule becomes one signal per file, however often it is
+//!   emitted.
+//!
+//! Rule weights are defaults like those in `heuristics.toml`: `[heuristics]`
+//! in `.vibecheck` overrides them, and 0 disables a rule.  A plugin that
+//! traps, runs out of fuel or emits an undeclared rule contributes nothing
+//! to that file, which is reported as degraded.
+//!
+//! Running modules requires the `wasm-plugins` cargo feature.  Without it,
+//! manifests are still discovered but every load fails with an explanatory
+//! error, and [`installed`] is empty.
+
+use std::collections::BTreeMap;
+use std::path::{Path, PathBuf};
+use std::sync::OnceLock;
+
+use anyhow::Context;
+use serde::Deserialize;
+use serde_json::{json, Value};
+use sha2::{Digest, Sha256};
+
+use crate::frontend::SourceTokens;
+use crate::report::{ModelFamily, Signal};
+
+/// Instructions a plugin may execute per file unless its manifest says
+/// otherwise.
+pub const DEFAULT_FUEL: u64 = 10_000_000;
+
+// ---------------------------------------------------------------------------
+// Manifest
+// ---------------------------------------------------------------------------
+
+#[derive(Deserialize)]
+struct PluginManifest {
+    name: String,
+    module: PathBuf,
+    #[serde(default)]
+    languages: Vec<String>,
+    fuel: Option<u64>,
+    #[serde(default, rename = "rule")]
+    rules: Vec<PluginRule>,
+}
+
+/// One rule a plugin may emit.
+#[derive(Debug, Clone, Deserialize)]
+pub struct PluginRule {
+    pub id: String,
+    pub description: String,
+    pub family: ModelFamily,
+    pub weight: f64,
+}
+
+// ---------------------------------------------------------------------------
+// Plugin
+// ---------------------------------------------------------------------------
+
+/// A WebAssembly detector loaded at runtime.
+pub struct Plugin {
+    name: String,
+    languages: Vec<String>,
+    rules: Vec<PluginRule>,
+    /// SHA-256 of the manifest and module, for cache keys.
+    digest: String,
+    runtime: runtime::Runtime,
+}
+
+impl Plugin {
+    /// Load the plugin described by the manifest at `path`.
+    pub fn load(path: &Path) -> anyhow::Result<Self> {
+        let text = std::fs::read_to_string(path).with_context(|| format!("cannot read {}", path.display()))?;
+        let manifest: PluginManifest =
+            toml::from_str(&text).with_context(|| format!("invalid plugin manifest {}", path.display()))?;
+        for rule in &manifest.rules {
+            anyhow::ensure!(
+                crate::heuristics::all_heuristics().iter().all(|h| h.id != rule.id),
+                "plugin {}: rule {} is a built-in signal",
+                manifest.name,
+                rule.id
+            );
+        }
+        let module_path = path.parent().unwrap_or(Path::new(".")).join(&manifest.module);
+        let wasm = std::fs::read(&module_path)
+            .with_context(|| format!("plugin {}: cannot read {}", manifest.name, module_path.display()))?;
+        let runtime = runtime::Runtime::new(&wasm, manifest.fuel.unwrap_or(DEFAULT_FUEL))
+            .with_context(|| format!("plugin {}", manifest.name))?;
+
+        let mut hasher = Sha256::new();
+        hasher.update(text.as_bytes());
+        hasher.update(&wasm);
+        let digest = hasher.finalize().iter().map(|b| format!("{b:02x}")).collect();
+        Ok(Self { name: manifest.name, languages: manifest.languages, rules: manifest.rules, digest, runtime })
+    }
+
+    pub fn name(&self) -> &str {
+        &self.name
+    }
+
+    pub fn rules(&self) -> &[PluginRule] {
+        &self.rules
+    }
+
+    /// Whether the plugin runs on files of `language`, a frontend name
+    /// such as `"go"` or a language pack's name.
+    pub fn applies_to(&self, language: &str) -> bool {
+        self.languages.is_empty() || self.languages.iter().any(|l| l == language)
+    }
+
+    /// Run the plugin over one file's tokens.
+    pub fn detect(&self, tokens: &SourceTokens) -> anyhow::Result<Vec<Signal>> {
+        let input = HostInput { comments: comments_json(tokens), identifiers: identifiers_json(tokens) };
+        let emitted = self.runtime.run(input).with_context(|| format!("plugin {}", self.name))?;
+        signals(&self.name, &self.rules, &emitted).with_context(|| format!("plugin {}", self.name))
+    }
+}
+
+/// One signal per emitted rule of `plugin`, with the lines of every
+/// emission.
+fn signals(plugin: &str, rules: &[PluginRule], emitted: &[Vec<u8>]) -> anyhow::Result<Vec<Signal>> {
+    let mut found: BTreeMap<&str, (&PluginRule, Vec<usize>)> = BTreeMap::new();
+    for finding in emitted {
+        let finding: Value = serde_json::from_slice(finding).context("emit: invalid JSON")?;
+        let id = finding["id"].as_str().context("emit: no id")?;
+        let rule = rules.iter().find(|r| r.id == id).with_context(|| format!("emit: undeclared rule {id}"))?;
+        let (_, lines) = found.entry(&rule.id).or_insert((rule, Vec::new()));
+        if let Some(at) = finding["lines"].as_array() {
+            lines.extend(at.iter().filter_map(Value::as_u64).map(|l| l as usize));
+        }
+    }
+    Ok(found
+        .into_values()
+        .map(|(rule, mut lines)| {
+            lines.sort_unstable();
+            lines.dedup();
+            let mut signal = Signal::new(&rule.id, &format!("plugin:{plugin}"), &rule.description, rule.family, rule.weight);
+            signal.lines = lines;
+            signal
+        })
+        .collect())
+}
+
+/// What the host functions hand the guest for one file.
+#[cfg_attr(not(feature = "wasm-plugins"), allow(dead_code))]
+struct HostInput {
+    comments: Vec<u8>,
+    identifiers: Vec<u8>,
+}
+
+fn comments_json(tokens: &SourceTokens) -> Vec<u8> {
+    let comments: Vec<Value> = tokens
+        .comments
+        .iter()
+        .map(|c| json!({ "line": c.start_line, "end_line": c.end_line, "text": c.text }))
+        .collect();
+    serde_json::to_vec(&comments).unwrap_or_default()
+}
+
+fn identifiers_json(tokens: &SourceTokens) -> Vec<u8> {
+    let identifiers: Vec<Value> =
+        tokens.identifiers.iter().map(|i| json!({ "line": i.start_line, "name": i.text })).collect();
+    serde_json::to_vec(&identifiers).unwrap_or_default()
+}
+
+// ---------------------------------------------------------------------------
+// Discovery
+// ---------------------------------------------------------------------------
+
+/// Resolve the plugins directory, checking (in priority order):
+/// 1. `VIBECHECK_PLUGIN_DIR` environment variable
+/// 2. Platform default: `~/.config/vibecheck/plugins/`
+pub fn plugins_dir() -> PathBuf {
+    if let Ok(dir) = std::env::var("VIBECHECK_PLUGIN_DIR") {
+        return PathBuf::from(dir);
+    }
+    dirs::config_dir()
+        .unwrap_or_else(|| PathBuf::from(".config"))
+        .join("vibecheck")
+        .join("plugins")
+}
+
+/// Load every `*.toml` manifest in `dir`, in file-name order.
+///
+/// Returns one result per manifest so callers can report plugins that
+/// failed to load.  A missing directory yields an empty list.
+pub fn load_dir(dir: &Path) -> Vec<anyhow::Result<Plugin>> {
+    let mut manifests: Vec<PathBuf> = match std::fs::read_dir(dir) {
+        Ok(entries) => entries
+            .filter_map(|e| e.ok())
+            .map(|e| e.path())
+            .filter(|p| p.extension().map(|e| e == "toml").unwrap_or(false))
+            .collect(),
+        Err(_) => return Vec::new(),
+    };
+    manifests.sort();
+    manifests.iter().map(|m| Plugin::load(m)).collect()
+}
+
+/// Plugins successfully loaded from [`plugins_dir`], discovered once per
+/// process.  Plugins that fail to load are skipped.
+pub fn installed() -> &'static [Plugin] {
+    static INSTALLED: OnceLock<Vec<Plugin>> = OnceLock::new();
+    INSTALLED.get_or_init(|| load_dir(&plugins_dir()).into_iter().filter_map(|r| r.ok()).collect())
+}
+
+/// Default weight of an installed plugin's rule.
+pub fn rule_weight(id: &str) -> Option<f64> {
+    installed().iter().flat_map(|p| &p.rules).find(|r| r.id == id).map(|r| r.weight)
+}
+
+/// Cache-key settings identifying the installed plugins, so reports are
+/// recomputed when one is added, removed or changed.
+pub fn cache_settings() -> Vec<String> {
+    installed().iter().map(|p| format!("plugin.{}={}", p.name, p.digest)).collect()
+}
+
+// ---------------------------------------------------------------------------
+// Runtime
+// ---------------------------------------------------------------------------
+
+#[cfg(feature = "wasm-plugins")]
+mod runtime {
+    use anyhow::Context;
+    use wasmi::{Caller, Config, Engine, Extern, Linker, Module, Store};
+
+    use super::HostInput;
+
+    /// A compiled module, instantiated afresh for every file.
+    pub struct Runtime {
+        engine: Engine,
+        module: Module,
+        fuel: u64,
+    }
+
+    struct State {
+        input: HostInput,
+        emitted: Vec<Vec<u8>>,
+        /// The first bad host call; the run fails with it.
+        error: Option<String>,
+    }
+
+    impl Runtime {
+        pub fn new(wasm: &[u8], fuel: u64) -> anyhow::Result<Self> {
+            let mut config = Config::default();
+            config.consume_fuel(true);
+            let engine = Engine::new(&config);
+            let module = Module::new(&engine, wasm).context("invalid WebAssembly module")?;
+            Ok(Self { engine, module, fuel })
+        }
+
+        /// Instantiate the module, call `detect()` and return the raw
+        /// findings it emitted.
+        pub fn run(&self, input: HostInput) -> anyhow::Result<Vec<Vec<u8>>> {
+            let mut store = Store::new(&self.engine, State { input, emitted: Vec::new(), error: None });
+            store.set_fuel(self.fuel)?;
+            let mut linker = <Linker<State>>::new(&self.engine);
+            linker.func_wrap("vibecheck", "comments", |caller: Caller<'_, State>, ptr: i32, cap: i32| {
+                copy_out(caller, ptr, cap, |input| &input.comments)
+            })?;
+            linker.func_wrap("vibecheck", "identifiers", |caller: Caller<'_, State>, ptr: i32, cap: i32| {
+                copy_out(caller, ptr, cap, |input| &input.identifiers)
+            })?;
+            linker.func_wrap("vibecheck", "emit", |mut caller: Caller<'_, State>, ptr: i32, len: i32| {
+                let mut finding = vec![0; len.max(0) as usize];
+                let read = memory(&caller).map(|m| m.read(&caller, ptr as usize, &mut finding));
+                match read {
+                    Some(Ok(())) => caller.data_mut().emitted.push(finding),
+                    _ => fail(&mut caller, "emit: out-of-bounds finding"),
+                }
+            })?;
+
+            let instance = linker.instantiate(&mut store, &self.module)?.start(&mut store)?;
+            let detect = instance
+                .get_typed_func::<(), ()>(&store, "detect")
+                .context("module does not export detect()")?;
+            detect.call(&mut store, ())?;
+            let state = store.into_data();
+            match state.error {
+                Some(error) => anyhow::bail!("{error}"),
+                None => Ok(state.emitted),
+            }
+        }
+    }
+
+    fn memory(caller: &Caller<'_, State>) -> Option<wasmi::Memory> {
+        caller.get_export("memory").and_then(Extern::into_memory)
+    }
+
+    fn fail(caller: &mut Caller<'_, State>, error: &str) {
+        caller.data_mut().error.get_or_insert_with(|| error.to_string());
+    }
+
+    /// Write the buffer `pick` selects to guest memory at `ptr` if it fits
+    /// in `cap` bytes, returning its length either way.
+    fn copy_out(mut caller: Caller<'_, State>, ptr: i32, cap: i32, pick: fn(&HostInput) -> &Vec<u8>) -> i32 {
+        let data = pick(&caller.data().input).clone();
+        if data.len() <= cap.max(0) as usize {
+            let written = memory(&caller).map(|m| m.write(&mut caller, ptr as usize, &data));
+            if !matches!(written, Some(Ok(()))) {
+                fail(&mut caller, "out-of-bounds buffer");
+            }
+        }
+        data.len() as i32
+    }
+}
+
+#[cfg(not(feature = "wasm-plugins"))]
+mod runtime {
+    use super::HostInput;
+
+    /// Uninhabited: no plugin loads without the feature.
+    pub enum Runtime {}
+
+    impl Runtime {
+        pub fn new(_wasm: &[u8], _fuel: u64) -> anyhow::Result<Self> {
+            anyhow::bail!("vibecheck was built without the `wasm-plugins` feature")
+        }
+
+        pub fn run(&self, _input: HostInput) -> anyhow::Result<Vec<Vec<u8>>> {
+            match *self {}
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::frontend::Span;
+
+    fn manifest(dir: &Path, extra: &str) -> PathBuf {
+        let path = dir.join("acme.toml");
+        std::fs::write(
+            &path,
+            format!(
+                "name = \"acme\"\nmodule = \"acme.wasm\"\nlanguages = [\"go\"]\n{extra}\n\
+                 [[rule]]\nid = \"acme.todo\"\ndescription = \"TODO without a ticket\"\nfamily = \"human\"\nweight = 0.8\n"
+            ),
+        )
+        .unwrap();
+        path
+    }
+
+    #[test]
+    fn load_dir_missing_is_empty() {
+        let dir = tempfile::tempdir().unwrap();
+        assert!(load_dir(&dir.path().join("nope")).is_empty());
+    }
+
+    #[test]
+    fn load_reports_missing_module_and_builtin_ids() {
+        let dir = tempfile::tempdir().unwrap();
+        let err = Plugin::load(&manifest(dir.path(), "")).err().expect("load should fail");
+        assert!(format!("{err:#}").contains("acme.wasm"), "{err:#}");
+
+        let builtin = crate::heuristics::all_heuristics()[0].id;
+        std::fs::write(
+            dir.path().join("acme.toml"),
+            format!("name = \"acme\"\nmodule = \"acme.wasm\"\n[[rule]]\nid = \"{builtin}\"\ndescription = \"x\"\nfamily = \"human\"\nweight = 1.0\n"),
+        )
+        .unwrap();
+        let err = Plugin::load(&dir.path().join("acme.toml")).err().expect("load should fail");
+        assert!(format!("{err:#}").contains("is a built-in signal"), "{err:#}");
+    }
+
+    #[cfg(not(feature = "wasm-plugins"))]
+    #[test]
+    fn load_explains_missing_feature() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join("acme.wasm"), b"\0asm\x01\0\0\0").unwrap();
+        let err = Plugin::load(&manifest(dir.path(), "fuel = 1000")).err().expect("load should fail");
+        assert!(format!("{err:#}").contains("`wasm-plugins` feature"), "{err:#}");
+    }
+
+    #[test]
+    fn emitted_findings_become_one_signal_per_rule() {
+        let rules = vec![PluginRule {
+            id: "acme.todo".into(),
+            description: "TODO without a ticket".into(),
+            family: ModelFamily::Human,
+            weight: 0.8,
+        }];
+        let emitted = vec![br#"{"id": "acme.todo", "lines": [9, 3]}"#.to_vec(), br#"{"id": "acme.todo", "lines": [3]}"#.to_vec()];
+        let found = signals("acme", &rules, &emitted).unwrap();
+        assert_eq!(found.len(), 1);
+        assert_eq!((found[0].id.as_str(), found[0].source.as_str()), ("acme.todo", "plugin:acme"));
+        assert_eq!((found[0].weight, found[0].lines.clone()), (0.8, vec![3, 9]));
+
+        let err = signals("acme", &rules, &[br#"{"id": "acme.other"}"#.to_vec()]).unwrap_err();
+        assert_eq!(err.to_string(), "emit: undeclared rule acme.other");
+        assert!(signals("acme", &rules, &[b"not json".to_vec()]).is_err());
+    }
+
+    #[test]
+    fn host_input_is_json_with_lines() {
+        let span = |line: usize, text: &str| Span { start_line: line, end_line: line, text: text.to_string() };
+        let tokens = SourceTokens {
+            comments: vec![span(1, "// TODO: fix")],
+            doc_comments: Vec::new(),
+            identifiers: vec![span(2, "cfg")],
+            functions: Vec::new(),
+        };
+        assert_eq!(String::from_utf8(comments_json(&tokens)).unwrap(), r#"[{"end_line":1,"line":1,"text":"// TODO: fix"}]"#);
+        assert_eq!(String::from_utf8(identifiers_json(&tokens)).unwrap(), r#"[{"line":2,"name":"cfg"}]"#);
+    }
+}
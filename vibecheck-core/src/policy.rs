@@ -0,0 +1,248 @@
+//! Finding severities and the policy profiles that bundle them.
+//!
+//! Every signal is graded `info`, `warn` or `error` from its effective
+//! weight: evidence for AI authorship at or over `warn_at` is a warning, at
+//! or over `error_at` an error, and anything weaker (or pointing at a human)
+//! is informational.  `[severity]` in `.vibecheck` pins single rules to a
+//! level regardless of weight.
+//!
+//! A profile is a partial `.vibecheck` shipped with vibecheck — `[policy]`
+//! thresholds and gate defaults, `[severity]` pins and `[heuristics]` weight
+//! overrides — so that a classroom, a hiring pipeline and an open-source
+//! triage bot can each get a sensible strictness with one line:
+//!
+//! ```toml
+//! [policy]
+//! profile = "lenient"
+//! ```
+//!
+//! or `--profile lenient` on the command line.  Settings in `.vibecheck`
+//! itself take precedence over the profile's.
+
+use std::collections::HashMap;
+
+use anyhow::{bail, Context};
+use serde::Deserialize;
+
+use crate::heuristics::all_heuristics;
+use crate::report::{Severity, Signal};
+
+/// The shipped profiles, strictest first.
+pub const PROFILES: &[&str] = &["strict", "balanced", "lenient"];
+
+/// Severity thresholds when neither the profile nor `.vibecheck` sets them.
+pub const DEFAULT_WARN_AT: f64 = 1.0;
+pub const DEFAULT_ERROR_AT: f64 = 2.0;
+
+fn profile_source(name: &str) -> Option<&'static str> {
+    match name {
+        "strict" => Some(include_str!("../profiles/strict.toml")),
+        "balanced" => Some(include_str!("../profiles/balanced.toml")),
+        "lenient" => Some(include_str!("../profiles/lenient.toml")),
+        _ => None,
+    }
+}
+
+/// The `[policy]` table of `.vibecheck` or of a profile.
+#[derive(Deserialize, Default)]
+pub(crate) struct PolicySection {
+    /// One of [`PROFILES`]; only read from `.vibecheck`.
+    profile: Option<String>,
+    /// One-line summary; only read from profiles.
+    description: Option<String>,
+    warn_at: Option<f64>,
+    error_at: Option<f64>,
+    /// Rules whose default weight is under this are disabled.
+    disable_below: Option<f64>,
+    /// Default for `--fail-over`.
+    fail_over: Option<f64>,
+    /// Default for `--fail-on`: rule IDs, prefixes or severities.
+    fail_on: Option<Vec<String>>,
+}
+
+/// A profile file: the tables of `.vibecheck` a profile may set.
+#[derive(Deserialize)]
+struct ProfileFile {
+    #[serde(default)]
+    policy: PolicySection,
+    #[serde(default)]
+    severity: HashMap<String, Severity>,
+    #[serde(default)]
+    heuristics: HashMap<String, f64>,
+}
+
+fn load_profile(name: &str) -> anyhow::Result<ProfileFile> {
+    let Some(source) = profile_source(name) else {
+        bail!("unknown profile: {name} (expected one of: {})", PROFILES.join(", "));
+    };
+    toml::from_str(source).with_context(|| format!("embedded profile {name} is invalid"))
+}
+
+/// The one-line summary of a shipped profile, for `--help` and listings.
+pub fn describe(name: &str) -> Option<String> {
+    load_profile(name).ok()?.policy.description
+}
+
+/// How signals are graded: weight thresholds plus per-rule pins.
+#[derive(Debug, Clone, PartialEq)]
+pub struct SeverityPolicy {
+    pub warn_at: f64,
+    pub error_at: f64,
+    /// Signal ID → severity, regardless of weight.
+    pub rules: HashMap<String, Severity>,
+}
+
+impl Default for SeverityPolicy {
+    fn default() -> Self {
+        Self { warn_at: DEFAULT_WARN_AT, error_at: DEFAULT_ERROR_AT, rules: HashMap::new() }
+    }
+}
+
+impl SeverityPolicy {
+    /// The severity of `signal`, whose weight is already the effective one.
+    pub fn grade(&self, signal: &Signal) -> Severity {
+        if let Some(&pinned) = self.rules.get(&signal.id) {
+            return pinned;
+        }
+        if !signal.is_ai_leaning() {
+            Severity::Info
+        } else if signal.weight >= self.error_at {
+            Severity::Error
+        } else if signal.weight >= self.warn_at {
+            Severity::Warn
+        } else {
+            Severity::Info
+        }
+    }
+
+    /// Stable string for cache keys; `None` for the defaults.
+    pub fn cache_setting(&self) -> Option<String> {
+        if *self == Self::default() {
+            return None;
+        }
+        let mut rules: Vec<String> = self.rules.iter().map(|(id, s)| format!("{id}={s}")).collect();
+        rules.sort();
+        Some(format!("severity={},{};{}", self.warn_at, self.error_at, rules.join(",")))
+    }
+}
+
+/// The policy in effect for a project: the chosen profile merged under the
+/// `.vibecheck` settings.
+#[derive(Debug, Clone, Default)]
+pub struct Policy {
+    /// The profile the policy started from, if any.
+    pub profile: Option<String>,
+    pub severity: SeverityPolicy,
+    /// Default for `--fail-over` when the command line has none.
+    pub fail_over: Option<f64>,
+    /// Default for `--fail-on` when the command line has none.
+    pub fail_on: Vec<String>,
+}
+
+/// Merge the profile named by `profile` (or by `[policy] profile`) under
+/// the `.vibecheck` tables, adding the profile's weight overrides and
+/// disabled rules to `heuristics` where it has none of its own.
+pub(crate) fn resolve(
+    profile: Option<&str>,
+    section: PolicySection,
+    severity: HashMap<String, Severity>,
+    heuristics: &mut HashMap<String, f64>,
+) -> anyhow::Result<Policy> {
+    let name = profile.map(str::to_string).or(section.profile.clone());
+    let base = match &name {
+        Some(name) => load_profile(name)?,
+        None => ProfileFile { policy: PolicySection::default(), severity: HashMap::new(), heuristics: HashMap::new() },
+    };
+
+    let mut rules = base.severity;
+    rules.extend(severity);
+    let policy = Policy {
+        profile: name,
+        severity: SeverityPolicy {
+            warn_at: section.warn_at.or(base.policy.warn_at).unwrap_or(DEFAULT_WARN_AT),
+            error_at: section.error_at.or(base.policy.error_at).unwrap_or(DEFAULT_ERROR_AT),
+            rules,
+        },
+        fail_over: section.fail_over.or(base.policy.fail_over),
+        fail_on: section.fail_on.or(base.policy.fail_on).unwrap_or_default(),
+    };
+    if policy.severity.warn_at > policy.severity.error_at {
+        bail!("warn_at ({}) must not be over error_at ({})", policy.severity.warn_at, policy.severity.error_at);
+    }
+    if let Some(limit) = policy.fail_over {
+        if !(0.0..=1.0).contains(&limit) {
+            bail!("fail_over must be between 0 and 1, got {limit}");
+        }
+    }
+
+    for (id, weight) in base.heuristics {
+        heuristics.entry(id).or_insert(weight);
+    }
+    if let Some(floor) = section.disable_below.or(base.policy.disable_below) {
+        for spec in all_heuristics() {
+            if spec.default_weight > 0.0 && spec.default_weight < floor {
+                heuristics.entry(spec.id.to_string()).or_insert(0.0);
+            }
+        }
+    }
+    Ok(policy)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::report::ModelFamily;
+
+    #[test]
+    fn shipped_profiles_parse_and_name_real_rules() {
+        let ids: Vec<&str> = all_heuristics().iter().map(|h| h.id).collect();
+        for name in PROFILES {
+            let profile = load_profile(name).unwrap();
+            assert!(describe(name).is_some(), "{name} has no description");
+            for id in profile.heuristics.keys().chain(profile.severity.keys()) {
+                assert!(ids.contains(&id.as_str()), "{name} names unknown rule {id}");
+            }
+            resolve(Some(name), PolicySection::default(), HashMap::new(), &mut HashMap::new()).unwrap();
+        }
+        assert!(load_profile("paranoid").is_err());
+    }
+
+    #[test]
+    fn signals_are_graded_by_weight_and_pins() {
+        let policy = SeverityPolicy::default();
+        let at = |weight, family| policy.grade(&Signal::new("x", "x", "x", family, weight));
+        assert_eq!(at(0.5, ModelFamily::Claude), Severity::Info);
+        assert_eq!(at(1.0, ModelFamily::Gpt), Severity::Warn);
+        assert_eq!(at(2.0, ModelFamily::Gpt), Severity::Error);
+        assert_eq!(at(2.0, ModelFamily::Human), Severity::Info);
+        assert_eq!(at(-1.5, ModelFamily::Claude), Severity::Info);
+
+        let pinned = SeverityPolicy { rules: HashMap::from([("x".to_string(), Severity::Error)]), ..policy.clone() };
+        assert_eq!(pinned.grade(&Signal::new("x", "x", "x", ModelFamily::Human, 0.1)), Severity::Error);
+        assert!(policy.cache_setting().is_none());
+        assert_eq!(pinned.cache_setting().as_deref(), Some("severity=1,2;x=error"));
+    }
+
+    #[test]
+    fn config_settings_take_precedence_over_the_profile() {
+        let section: PolicySection = toml::from_str("profile = \"lenient\"\nfail_over = 0.7").unwrap();
+        let mut heuristics = HashMap::from([("rust.ai_signals.all_fns_documented".to_string(), 1.8)]);
+        let policy = resolve(None, section, HashMap::new(), &mut heuristics).unwrap();
+        assert_eq!(policy.profile.as_deref(), Some("lenient"));
+        assert_eq!(policy.fail_over, Some(0.7));
+        assert_eq!((policy.severity.warn_at, policy.severity.error_at), (1.5, 2.5));
+        assert_eq!(heuristics["rust.ai_signals.all_fns_documented"], 1.8);
+        assert_eq!(heuristics["go.ai_signals.all_exported_documented"], 1.0);
+        // disable_below turns off the tidiness rules.
+        assert_eq!(heuristics["rust.structure.sorted_imports"], 0.0);
+        assert!(!heuristics.contains_key("rust.comments.step_numbered"));
+
+        // An explicit profile (--profile) wins over [policy] profile.
+        let section: PolicySection = toml::from_str("profile = \"lenient\"").unwrap();
+        let policy = resolve(Some("strict"), section, HashMap::new(), &mut HashMap::new()).unwrap();
+        assert_eq!(policy.fail_on, vec!["error".to_string()]);
+
+        let bad: PolicySection = toml::from_str("warn_at = 3.0").unwrap();
+        assert!(resolve(None, bad, HashMap::new(), &mut HashMap::new()).is_err());
+    }
+}
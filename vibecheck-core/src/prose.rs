@@ -0,0 +1,390 @@
+//! Prose analysis for documentation.
+//!
+//! READMEs and design docs are where assistant output lands most often, and
+//! none of the code analyzers apply to them.  Markdown and reStructuredText
+//! files ([`crate::language::DOC_EXTENSIONS`]) are scored with these
+//! detectors instead — the `docs.*` rules in `heuristics.toml` — and so is
+//! the text of source files that are nothing but doc comments, such as a
+//! Go `doc.go` or a `//!`-only `lib.rs` (see [`doc_comment_text`]).
+//!
+//! Fenced code blocks are skipped; every signal's lines are 1-based lines
+//! of the document.
+
+use std::collections::HashSet;
+
+use crate::commit_message::is_emoji;
+use crate::frontend::SourceTokens;
+use crate::heuristics::signal_ids;
+use crate::report::{ModelFamily, Signal};
+
+const SOURCE: &str = "prose";
+
+/// Section headings assistants reach for whatever the document is about.
+const BOILERPLATE_HEADINGS: &[&str] = &[
+    "overview", "introduction", "key features", "conclusion", "summary", "prerequisites", "table of contents",
+    "best practices", "key concepts", "next steps",
+];
+
+/// Boilerplate headings before `docs.structure.boilerplate_headings` fires.
+const MIN_BOILERPLATE_HEADINGS: usize = 3;
+
+/// Openers that announce what the document is about to do.
+const ANNOUNCEMENTS: &[&str] = &[
+    "in this document", "in this guide", "in this readme", "in this section, we", "this document describes",
+    "this document provides", "this document outlines", "this guide will", "this guide walks", "we will explore",
+    "let's dive", "let's explore",
+];
+
+/// Openers of a closing paragraph that wraps the document up.
+const CLOSERS: &[&str] = &["in conclusion", "in summary", "by following these", "with these steps", "happy coding"];
+
+/// Markers of unfinished human notes.
+const OPEN_QUESTIONS: &[&str] = &["TODO", "FIXME", "TBD", "XXX", "???"];
+
+/// Bold-lead-in bullets before `docs.structure.uniform_bullets` fires, and
+/// the share of all bullets they must make up.
+const MIN_UNIFORM_BULLETS: usize = 4;
+const UNIFORM_BULLET_SHARE: f64 = 0.7;
+
+/// Emoji-led headings before `docs.style.emoji_headings` fires.
+const MIN_EMOJI_HEADINGS: usize = 2;
+
+/// Comment lines a doc-comment-only file needs, and the non-comment lines
+/// (a `package` clause, a `mod` declaration) it may still have.
+const MIN_DOC_LINES: usize = 5;
+const MAX_CODE_LINES: usize = 2;
+
+/// Run every prose detector on the document `text`.  Signals carry their
+/// default weights.
+pub fn detect(text: &str) -> Vec<Signal> {
+    let lines = prose_lines(text);
+    let headings = headings(&lines);
+    let bullets: Vec<(usize, &str)> = lines.iter().filter_map(|&(n, l)| bullet_text(l).map(|b| (n, b))).collect();
+    let mut signals = Vec::new();
+
+    let boilerplate: Vec<usize> = headings
+        .iter()
+        .filter(|(_, h)| BOILERPLATE_HEADINGS.contains(&normalize_heading(h).as_str()))
+        .map(|(n, _)| *n)
+        .collect();
+    if boilerplate.len() >= MIN_BOILERPLATE_HEADINGS {
+        signals.push(
+            Signal::new(
+                signal_ids::DOCS_STRUCTURE_BOILERPLATE_HEADINGS,
+                SOURCE,
+                format!("{} stock section headings (Overview, Key Features, Conclusion)", boilerplate.len()),
+                ModelFamily::Claude,
+                1.5,
+            )
+            .with_lines(boilerplate),
+        );
+    }
+
+    let announced: Vec<usize> = lines
+        .iter()
+        .filter(|(_, l)| {
+            let l = l.to_lowercase().replace('\u{2019}', "'");
+            ANNOUNCEMENTS.iter().any(|a| l.contains(a))
+        })
+        .map(|(n, _)| *n)
+        .collect();
+    if !announced.is_empty() {
+        signals.push(
+            Signal::new(
+                signal_ids::DOCS_NARRATION_IN_THIS_DOCUMENT,
+                SOURCE,
+                "Announces what the document will do (\"In this document we will ...\")",
+                ModelFamily::Gpt,
+                1.5,
+            )
+            .with_lines(announced),
+        );
+    }
+
+    let bold: Vec<usize> = bullets.iter().filter(|(_, b)| has_bold_lead_in(b)).map(|(n, _)| *n).collect();
+    if bold.len() >= MIN_UNIFORM_BULLETS && bold.len() as f64 >= bullets.len() as f64 * UNIFORM_BULLET_SHARE {
+        signals.push(
+            Signal::new(
+                signal_ids::DOCS_STRUCTURE_UNIFORM_BULLETS,
+                SOURCE,
+                format!("{} of {} bullets open with a bold label (\"**Fast**: ...\")", bold.len(), bullets.len()),
+                ModelFamily::Claude,
+                1.5,
+            )
+            .with_lines(bold),
+        );
+    }
+
+    let emoji: Vec<usize> = headings
+        .iter()
+        .filter(|(_, h)| h.chars().next().is_some_and(is_emoji))
+        .map(|(n, _)| *n)
+        .collect();
+    if emoji.len() >= MIN_EMOJI_HEADINGS {
+        signals.push(
+            Signal::new(
+                signal_ids::DOCS_STYLE_EMOJI_HEADINGS,
+                SOURCE,
+                format!("{} headings start with an emoji", emoji.len()),
+                ModelFamily::Gemini,
+                1.0,
+            )
+            .with_lines(emoji),
+        );
+    }
+
+    // The first line of the last paragraph that is not a heading or a list.
+    let closer = lines
+        .iter()
+        .rev()
+        .filter(|(n, l)| !headings.iter().any(|(h, _)| h == n) && bullet_text(l).is_none())
+        .find(|(_, l)| !l.trim().is_empty())
+        .filter(|(_, l)| {
+            let l = l.trim().to_lowercase();
+            CLOSERS.iter().any(|c| l.starts_with(c))
+        });
+    if let Some(&(n, _)) = closer {
+        signals.push(
+            Signal::new(
+                signal_ids::DOCS_NARRATION_CLOSING_SUMMARY,
+                SOURCE,
+                "Ends on a wrap-up (\"In conclusion\", \"Happy coding!\")",
+                ModelFamily::Gpt,
+                1.0,
+            )
+            .with_lines(vec![n]),
+        );
+    }
+
+    let open: Vec<usize> = lines
+        .iter()
+        .filter(|(_, l)| OPEN_QUESTIONS.iter().any(|m| l.contains(m)))
+        .map(|(n, _)| *n)
+        .collect();
+    if !open.is_empty() {
+        signals.push(
+            Signal::new(
+                signal_ids::DOCS_STYLE_OPEN_QUESTIONS,
+                SOURCE,
+                format!("{} unfinished notes (TODO, TBD, ???)", open.len()),
+                ModelFamily::Human,
+                1.5,
+            )
+            .with_lines(open),
+        );
+    }
+
+    signals
+}
+
+/// The prose of a source file that is nothing but doc comments: at least
+/// [`MIN_DOC_LINES`] comment lines and at most [`MAX_CODE_LINES`] other
+/// non-blank ones.  Comment markers are stripped and every other line
+/// blanked, so line numbers still match the file.  `None` for ordinary
+/// source files.
+pub fn doc_comment_text(tokens: &SourceTokens, source: &str) -> Option<String> {
+    let covered: HashSet<usize> = tokens.comments.iter().flat_map(|s| s.start_line..=s.end_line).collect();
+    let lines: Vec<&str> = source.lines().collect();
+    let code = lines
+        .iter()
+        .enumerate()
+        .filter(|(i, l)| !l.trim().is_empty() && !covered.contains(&(i + 1)))
+        .count();
+    if covered.len() < MIN_DOC_LINES || code > MAX_CODE_LINES {
+        return None;
+    }
+    let text: Vec<&str> = lines
+        .iter()
+        .enumerate()
+        .map(|(i, l)| if covered.contains(&(i + 1)) { strip_comment_marker(l) } else { "" })
+        .collect();
+    Some(text.join("\n"))
+}
+
+/// 1-based numbers and text of the lines outside fenced code blocks.
+fn prose_lines(text: &str) -> Vec<(usize, &str)> {
+    let mut fenced = false;
+    let mut out = Vec::new();
+    for (i, line) in text.lines().enumerate() {
+        let trimmed = line.trim_start();
+        if trimmed.starts_with("```") || trimmed.starts_with("~~~") {
+            fenced = !fenced;
+            continue;
+        }
+        if !fenced {
+            out.push((i + 1, line));
+        }
+    }
+    out
+}
+
+/// Markdown `#` headings and underlined (setext / reStructuredText)
+/// headings, with their line numbers.
+fn headings(lines: &[(usize, &str)]) -> Vec<(usize, String)> {
+    let mut out = Vec::new();
+    for (i, &(n, line)) in lines.iter().enumerate() {
+        let trimmed = line.trim();
+        if let Some(rest) = trimmed.strip_prefix('#') {
+            let rest = rest.trim_start_matches('#');
+            if rest.starts_with(' ') {
+                out.push((n, rest.trim().to_string()));
+            }
+            continue;
+        }
+        let underlined = lines.get(i + 1).is_some_and(|&(next_n, next)| next_n == n + 1 && is_underline(next, trimmed));
+        if underlined && !trimmed.is_empty() && bullet_text(trimmed).is_none() {
+            out.push((n, trimmed.to_string()));
+        }
+    }
+    out
+}
+
+/// Whether `line` underlines `title`: one repeated punctuation character at
+/// least as long as the title.
+fn is_underline(line: &str, title: &str) -> bool {
+    let line = line.trim_end();
+    let mut chars = line.chars();
+    let Some(first) = chars.next() else {
+        return false;
+    };
+    "=-~^\"'`#*+".contains(first) && chars.all(|c| c == first) && line.chars().count() >= title.chars().count().max(3)
+}
+
+/// The text of a `-`, `*` or `+` bullet, or of a numbered item.
+fn bullet_text(line: &str) -> Option<&str> {
+    let trimmed = line.trim_start();
+    if let Some(rest) = ["- ", "* ", "+ "].iter().find_map(|m| trimmed.strip_prefix(m)) {
+        return Some(rest.trim_start());
+    }
+    let digits = trimmed.chars().take_while(char::is_ascii_digit).count();
+    (digits > 0).then(|| trimmed[digits..].strip_prefix(". ")).flatten().map(str::trim_start)
+}
+
+/// `**Label**: ...`, `**Label** - ...` or `**Label:** ...`.
+fn has_bold_lead_in(bullet: &str) -> bool {
+    let Some(rest) = bullet.strip_prefix("**") else {
+        return false;
+    };
+    let Some((label, after)) = rest.split_once("**") else {
+        return false;
+    };
+    let after = after.trim_start();
+    !label.trim().is_empty()
+        && (label.ends_with(':') || after.starts_with(':') || after.starts_with('-') || after.starts_with('\u{2014}'))
+}
+
+/// Lowercase a heading and drop its emoji, numbering and trailing colon:
+/// `"🚀 1. Key Features:"` → `"key features"`.
+fn normalize_heading(heading: &str) -> String {
+    let text: String = heading.chars().filter(|c| !is_emoji(*c) && *c != '\u{fe0f}').collect();
+    let text = text.trim().trim_start_matches(|c: char| c.is_ascii_digit() || c == '.').trim();
+    text.trim_end_matches(':').trim().to_lowercase()
+}
+
+/// A comment line without its marker: `/// text`, `// text`, ` * text`,
+/// `# text` → `text`.
+fn strip_comment_marker(line: &str) -> &str {
+    let t = line.trim();
+    let t = t.strip_suffix("*/").unwrap_or(t).trim_end();
+    for marker in ["///", "//!", "//", "/**", "/*!", "/*", "*", "#"] {
+        if let Some(rest) = t.strip_prefix(marker) {
+            return rest.strip_prefix(' ').unwrap_or(rest);
+        }
+    }
+    t
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::frontend::frontend_for;
+    use std::path::Path;
+
+    fn ids(text: &str) -> Vec<String> {
+        detect(text).into_iter().map(|s| s.id).collect()
+    }
+
+    const AI_README: &str = "# 🚀 Project Nimbus\n\
+        \n\
+        In this document, we will explore how Nimbus keeps your caches warm.\n\
+        \n\
+        ## 📖 Overview\n\
+        \n\
+        Nimbus is a cache warmer.\n\
+        \n\
+        ## ✨ Key Features\n\
+        \n\
+        - **Fast**: warms thousands of keys per second\n\
+        - **Safe**: never evicts hot entries\n\
+        - **Simple**: one binary, no daemons\n\
+        - **Observable**: exports Prometheus metrics\n\
+        \n\
+        ```bash\n\
+        # Conclusion\n\
+        nimbus --warm\n\
+        ```\n\
+        \n\
+        ## Conclusion\n\
+        \n\
+        In conclusion, Nimbus makes cache warming effortless. Happy coding!\n";
+
+    #[test]
+    fn assistant_readme_trips_the_prose_detectors() {
+        let found = ids(AI_README);
+        for id in [
+            signal_ids::DOCS_STRUCTURE_BOILERPLATE_HEADINGS,
+            signal_ids::DOCS_NARRATION_IN_THIS_DOCUMENT,
+            signal_ids::DOCS_STRUCTURE_UNIFORM_BULLETS,
+            signal_ids::DOCS_STYLE_EMOJI_HEADINGS,
+            signal_ids::DOCS_NARRATION_CLOSING_SUMMARY,
+        ] {
+            assert!(found.iter().any(|f| f == id), "{id} did not fire: {found:?}");
+        }
+        assert!(!found.iter().any(|f| f == signal_ids::DOCS_STYLE_OPEN_QUESTIONS));
+    }
+
+    #[test]
+    fn fenced_code_is_not_prose() {
+        let signals = detect(AI_README);
+        let headings = signals.iter().find(|s| s.id == signal_ids::DOCS_STRUCTURE_BOILERPLATE_HEADINGS).unwrap();
+        assert_eq!(headings.lines, vec![5, 9, 21], "the `# Conclusion` in the bash block is not a heading");
+    }
+
+    #[test]
+    fn human_notes_lean_human() {
+        let notes = "Cache warmer\n============\n\nRun `nimbus --warm` from cron.\n\nTODO: document the retry knobs\n\n- flags are in main.go\n- ask ops about TTLs ???\n";
+        assert_eq!(ids(notes), vec![signal_ids::DOCS_STYLE_OPEN_QUESTIONS]);
+    }
+
+    #[test]
+    fn rst_headings_are_underlined() {
+        let lines = prose_lines("Overview\n========\n\ntext\n\nSummary\n-------\n\n- item\n---\n");
+        let found: Vec<String> = headings(&lines).into_iter().map(|(_, h)| h).collect();
+        assert_eq!(found, vec!["Overview", "Summary"]);
+    }
+
+    #[test]
+    fn bullets_and_lead_ins() {
+        assert_eq!(bullet_text("  - item"), Some("item"));
+        assert_eq!(bullet_text("12. item"), Some("item"));
+        assert_eq!(bullet_text("2024 was a year"), None);
+        assert!(has_bold_lead_in("**Fast**: warms keys"));
+        assert!(has_bold_lead_in("**Fast:** warms keys"));
+        assert!(!has_bold_lead_in("**Note** that keys expire"));
+        assert_eq!(normalize_heading("🚀 1. Key Features:"), "key features");
+    }
+
+    #[test]
+    fn doc_comment_only_files_yield_their_prose() {
+        let doc_go = "// Package nimbus warms caches.\n//\n// # Overview\n//\n// In this document we will explore it.\npackage nimbus\n";
+        let tokens = frontend_for(Path::new("doc.go"), &[]).unwrap().tokenize(doc_go).unwrap();
+        let text = doc_comment_text(&tokens, doc_go).expect("doc.go is documentation");
+        assert_eq!(text.lines().nth(2), Some("# Overview"));
+        assert_eq!(text.lines().nth(5), Some(""), "the package clause is blanked");
+        assert_eq!(detect(&text)[0].lines, vec![5]);
+
+        let code = "// Add adds.\nfunc Add(a, b int) int {\n\treturn a + b\n}\n";
+        let tokens = frontend_for(Path::new("add.go"), &[]).unwrap().tokenize(code).unwrap();
+        assert!(doc_comment_text(&tokens, code).is_none());
+    }
+}
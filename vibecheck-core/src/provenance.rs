@@ -0,0 +1,259 @@
+//! Provenance statements as an SPDX 2.3 JSON document.
+//!
+//! Each analyzed file becomes an SPDX file element annotated with its
+//! provenance — human-authored, AI-assisted or AI-generated — and the
+//! confidence behind it.  The model families vibecheck attributed files to
+//! are package elements, and relationships tie the two together:
+//!
+//! * `SPDXRef-Model-<family> GENERATES <file>` for AI-generated files,
+//! * `<file> OTHER SPDXRef-Model-<family>` with the comment `ai-assisted`
+//!   for AI-assisted ones.
+//!
+//! Human-authored files and files without enough signal data only have the
+//! document's `DESCRIBES` relationship.  Every annotation comment is a
+//! `key: value; ...` list so it can be read back without parsing prose.
+
+use std::collections::BTreeSet;
+
+use serde_json::{json, Value};
+use sha2::{Digest, Sha256};
+
+use crate::report::{ModelFamily, Report};
+
+pub use crate::calibration::{AI_ASSISTED_AT, AI_GENERATED_AT};
+
+/// How a file came to be, as stated in the provenance document: the
+/// report's [`Authorship`](crate::report::Authorship) verdict.
+pub use crate::report::Authorship as Provenance;
+
+/// Render `reports` as an SPDX 2.3 JSON document created at `created`
+/// (Unix seconds).  `sources` holds each report's source, in the same
+/// order, for the file checksums; files whose source is `None` get no
+/// checksum.
+pub fn format_spdx(reports: &[Report], sources: &[Option<String>], created: i64) -> String {
+    serde_json::to_string_pretty(&spdx_document(reports, sources, created)).expect("document should be serializable")
+}
+
+/// The document [`format_spdx`] renders.
+pub fn spdx_document(reports: &[Report], sources: &[Option<String>], created: i64) -> Value {
+    let mut namespace = Sha256::new();
+    namespace.update(created.to_le_bytes());
+    let created = timestamp(created);
+    let tool = format!("Tool: vibecheck-{}", env!("CARGO_PKG_VERSION"));
+    let mut files = Vec::new();
+    let mut relationships = Vec::new();
+    let mut families = BTreeSet::new();
+
+    for (i, report) in reports.iter().enumerate() {
+        let id = format!("SPDXRef-File-{}", i + 1);
+        let path = report.metadata.file_path.as_ref().map_or_else(|| "<stdin>".to_string(), |p| p.display().to_string());
+        let name = if path.starts_with("./") || path.starts_with('/') || path.starts_with('<') {
+            path
+        } else {
+            format!("./{path}")
+        };
+        namespace.update(name.as_bytes());
+
+        let provenance = Provenance::of(report);
+        let attribution = &report.attribution;
+        let statement = match provenance {
+            Some(p) => {
+                let mut s = format!(
+                    "provenance: {}; family: {}; confidence: {:.2}",
+                    p.as_str(),
+                    attribution.primary.to_string().to_lowercase(),
+                    attribution.confidence
+                );
+                if let Some(probability) = attribution.ai_probability {
+                    s.push_str(&format!("; ai_probability: {probability:.2}"));
+                }
+                s
+            }
+            None => format!(
+                "provenance: noassertion; reason: {}",
+                report.metadata.skipped.as_deref().unwrap_or("insufficient signal data")
+            ),
+        };
+
+        let mut file = json!({
+            "SPDXID": id,
+            "fileName": name,
+            "licenseConcluded": "NOASSERTION",
+            "copyrightText": "NOASSERTION",
+            "comment": statement,
+            "annotations": [{
+                "annotationType": "REVIEW",
+                "annotator": tool,
+                "annotationDate": created,
+                "comment": statement,
+            }],
+        });
+        if let Some(source) = sources.get(i).and_then(Option::as_ref) {
+            let digest: String = Sha256::digest(source.as_bytes()).iter().map(|b| format!("{b:02x}")).collect();
+            namespace.update(digest.as_bytes());
+            file["checksums"] = json!([{ "algorithm": "SHA256", "checksumValue": digest }]);
+        }
+        files.push(file);
+
+        relationships.push(json!({
+            "spdxElementId": "SPDXRef-DOCUMENT",
+            "relationshipType": "DESCRIBES",
+            "relatedSpdxElement": id,
+        }));
+        let model = model_id(attribution.primary);
+        match provenance {
+            Some(Provenance::AiGenerated) => {
+                families.insert(attribution.primary);
+                relationships.push(json!({
+                    "spdxElementId": model,
+                    "relationshipType": "GENERATES",
+                    "relatedSpdxElement": id,
+                }));
+            }
+            Some(Provenance::AiAssisted) if attribution.primary != ModelFamily::Human => {
+                families.insert(attribution.primary);
+                relationships.push(json!({
+                    "spdxElementId": id,
+                    "relationshipType": "OTHER",
+                    "relatedSpdxElement": model,
+                    "comment": "ai-assisted",
+                }));
+            }
+            _ => {}
+        }
+    }
+
+    let packages: Vec<Value> = families
+        .iter()
+        .map(|&family| {
+            json!({
+                "SPDXID": model_id(family),
+                "name": family.to_string(),
+                "downloadLocation": "NOASSERTION",
+                "filesAnalyzed": false,
+                "licenseConcluded": "NOASSERTION",
+                "copyrightText": "NOASSERTION",
+                "comment": "AI model family files were attributed to by vibecheck",
+            })
+        })
+        .collect();
+
+    let digest: String = namespace.finalize().iter().take(8).map(|b| format!("{b:02x}")).collect();
+    json!({
+        "spdxVersion": "SPDX-2.3",
+        "dataLicense": "CC0-1.0",
+        "SPDXID": "SPDXRef-DOCUMENT",
+        "name": "vibecheck-provenance",
+        "documentNamespace": format!("https://spdx.org/spdxdocs/vibecheck-provenance-{digest}"),
+        "creationInfo": {
+            "created": created,
+            "creators": [tool],
+            "comment": format!(
+                "Provenance from vibecheck attribution: ai-generated at AI probability >= {AI_GENERATED_AT}, \
+                 ai-assisted at >= {AI_ASSISTED_AT}, human-authored below."
+            ),
+        },
+        "packages": packages,
+        "files": files,
+        "relationships": relationships,
+    })
+}
+
+fn model_id(family: ModelFamily) -> String {
+    format!("SPDXRef-Model-{}", family.to_string().to_lowercase())
+}
+
+/// Format Unix seconds as an SPDX timestamp, `YYYY-MM-DDThh:mm:ssZ`.
+fn timestamp(unix_secs: i64) -> String {
+    let secs = unix_secs.max(0) as u64;
+    let (days, rem) = (secs / 86400, secs % 86400);
+
+    // Gregorian calendar from days since the epoch.
+    let z = days + 719468;
+    let era = z / 146097;
+    let doe = z - era * 146097;
+    let yoe = (doe - doe / 1460 + doe / 36524 - doe / 146096) / 365;
+    let doy = doe - (365 * yoe + yoe / 4 - yoe / 100);
+    let mp = (5 * doy + 2) / 153;
+    let d = doy - (153 * mp + 2) / 5 + 1;
+    let m = if mp < 10 { mp + 3 } else { mp - 9 };
+    let y = yoe + era * 400 + u64::from(m <= 2);
+
+    format!("{y:04}-{m:02}-{d:02}T{:02}:{:02}:{:02}Z", rem / 3600, rem % 3600 / 60, rem % 60)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::report::{Attribution, ReportMetadata};
+    use std::collections::BTreeMap;
+    use std::path::PathBuf;
+
+    fn report(path: &str, primary: ModelFamily, confidence: f64, ai_probability: Option<f64>) -> Report {
+        Report {
+            attribution: Attribution { primary, confidence, scores: BTreeMap::new(), era: None, ai_probability, authorship: None },
+            signals: vec![],
+            metadata: ReportMetadata {
+                file_path: Some(PathBuf::from(path)),
+                lines_of_code: 10,
+                signal_count: 0,
+                degraded: vec![],
+                skipped: None,
+            },
+            symbol_reports: None,
+            segments: None,
+            comment_free: None,
+            ensemble: None,
+        }
+    }
+
+    #[test]
+    fn provenance_follows_the_calibrated_probability() {
+        assert_eq!(Provenance::of(&report("a.rs", ModelFamily::Claude, 0.8, Some(0.9))), Some(Provenance::AiGenerated));
+        assert_eq!(Provenance::of(&report("a.rs", ModelFamily::Claude, 0.5, Some(0.5))), Some(Provenance::AiAssisted));
+        assert_eq!(Provenance::of(&report("a.rs", ModelFamily::Human, 0.7, Some(0.1))), Some(Provenance::HumanAuthored));
+        assert_eq!(Provenance::of(&report("a.rs", ModelFamily::Gpt, 0.6, None)), Some(Provenance::AiGenerated));
+        assert_eq!(Provenance::of(&report("a.rs", ModelFamily::Human, 0.0, None)), None);
+    }
+
+    #[test]
+    fn document_relates_files_to_model_families() {
+        let reports = [
+            report("src/gen.rs", ModelFamily::Claude, 0.8, Some(0.9)),
+            report("src/mixed.rs", ModelFamily::Gpt, 0.5, Some(0.5)),
+            report("src/main.rs", ModelFamily::Human, 0.7, Some(0.1)),
+            report("src/empty.rs", ModelFamily::Human, 0.0, None),
+        ];
+        let doc = spdx_document(&reports, &[Some("fn main() {}\n".into()), None, None, None], 1_700_000_000);
+        assert_eq!(doc["spdxVersion"], "SPDX-2.3");
+        assert_eq!(doc["creationInfo"]["created"], "2023-11-14T22:13:20Z");
+
+        let files = doc["files"].as_array().unwrap();
+        assert_eq!(files[0]["fileName"], "./src/gen.rs");
+        assert_eq!(files[0]["checksums"][0]["algorithm"], "SHA256");
+        assert!(files[1].get("checksums").is_none());
+        assert_eq!(files[0]["annotations"][0]["comment"], "provenance: ai-generated; family: claude; confidence: 0.80; ai_probability: 0.90");
+        assert!(files[3]["comment"].as_str().unwrap().starts_with("provenance: noassertion"));
+
+        let packages: Vec<&str> = doc["packages"].as_array().unwrap().iter().map(|p| p["SPDXID"].as_str().unwrap()).collect();
+        assert_eq!(packages, vec!["SPDXRef-Model-claude", "SPDXRef-Model-gpt"]);
+
+        let relationships = doc["relationships"].as_array().unwrap();
+        let typed = |t: &str| relationships.iter().filter(|r| r["relationshipType"] == t).count();
+        assert_eq!(typed("DESCRIBES"), 4);
+        assert_eq!(typed("GENERATES"), 1);
+        assert_eq!(typed("OTHER"), 1);
+        assert!(relationships.iter().any(|r| r["spdxElementId"] == "SPDXRef-Model-claude" && r["relatedSpdxElement"] == "SPDXRef-File-1"));
+    }
+
+    #[test]
+    fn each_document_gets_its_own_namespace() {
+        let reports = [report("a.rs", ModelFamily::Claude, 0.8, Some(0.9))];
+        let a = spdx_document(&reports, &[None], 0);
+        assert_eq!(a["documentNamespace"], spdx_document(&reports, &[None], 0)["documentNamespace"]);
+        assert_ne!(a["documentNamespace"], spdx_document(&reports, &[None], 60)["documentNamespace"]);
+        assert_ne!(a["documentNamespace"], spdx_document(&reports, &[Some("x".into())], 0)["documentNamespace"]);
+        assert_eq!(timestamp(0), "1970-01-01T00:00:00Z");
+        assert_eq!(timestamp(951_782_400), "2000-02-29T00:00:00Z");
+    }
+}
@@ -0,0 +1,186 @@
+//! Ranked "top offenders" of a scan.
+//!
+//! Reviewers facing thousands of reports want a worklist, not an
+//! alphabetical dump: this module ranks files, and the functions inside
+//! them when the reports carry symbol reports, by calibrated AI
+//! probability, each with the rules that contributed most to its verdict.
+
+use std::path::PathBuf;
+
+use serde::{Deserialize, Serialize};
+
+use crate::calibration;
+use crate::report::{Attribution, ModelFamily, Report, Signal};
+
+/// Rules listed per offender.
+pub const DOMINANT_RULES: usize = 3;
+
+/// One ranked file or function.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct Offender {
+    pub path: PathBuf,
+    /// The function or method, for symbol-level entries.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub symbol: Option<String>,
+    /// 1-based, inclusive line range of the symbol.
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub lines: Option<(usize, usize)>,
+    pub family: ModelFamily,
+    pub ai_probability: f64,
+    /// Ids of the AI-leaning rules with the largest weights, largest first.
+    pub rules: Vec<String>,
+}
+
+impl Offender {
+    /// `path`, or `path:start-end name()` for a symbol.
+    pub fn location(&self) -> String {
+        let path = self.path.display();
+        match (&self.symbol, self.lines) {
+            (Some(name), Some((start, end))) => format!("{path}:{start}-{end} {name}()"),
+            (Some(name), None) => format!("{path} {name}()"),
+            _ => path.to_string(),
+        }
+    }
+}
+
+/// Every file in `reports`, and with `functions` every symbol in their
+/// symbol reports, that has a verdict, highest AI probability first.  Ties
+/// go to the entry with more AI-leaning weight, then to path order.
+/// Skipped files and entries without enough signal data are left out.
+pub fn rank(reports: &[Report], functions: bool) -> Vec<Offender> {
+    let mut ranked: Vec<(Offender, f64)> = Vec::new();
+    for report in reports.iter().filter(|r| r.metadata.skipped.is_none()) {
+        let path = report.metadata.file_path.clone().unwrap_or_else(|| "<stdin>".into());
+        if let Some(entry) = entry(path.clone(), None, None, &report.attribution, &report.signals) {
+            ranked.push(entry);
+        }
+        if !functions {
+            continue;
+        }
+        for symbol in report.symbol_reports.iter().flatten() {
+            let lines = Some((symbol.metadata.start_line, symbol.metadata.end_line));
+            let name = Some(symbol.metadata.name.clone());
+            if let Some(entry) = entry(path.clone(), name, lines, &symbol.attribution, &symbol.signals) {
+                ranked.push(entry);
+            }
+        }
+    }
+    ranked.sort_by(|(a, wa), (b, wb)| {
+        b.ai_probability
+            .total_cmp(&a.ai_probability)
+            .then_with(|| wb.total_cmp(wa))
+            .then_with(|| a.path.cmp(&b.path))
+            .then_with(|| a.lines.cmp(&b.lines))
+    });
+    ranked.into_iter().map(|(o, _)| o).collect()
+}
+
+/// The first `n` entries of [`rank`].
+pub fn top(reports: &[Report], n: usize, functions: bool) -> Vec<Offender> {
+    let mut ranked = rank(reports, functions);
+    ranked.truncate(n);
+    ranked
+}
+
+/// The offender for one attribution, with its total AI-leaning weight for
+/// tie-breaking; `None` without a verdict.
+fn entry(
+    path: PathBuf,
+    symbol: Option<String>,
+    lines: Option<(usize, usize)>,
+    attribution: &Attribution,
+    signals: &[Signal],
+) -> Option<(Offender, f64)> {
+    // Symbol attributions are not calibrated by the pipeline.
+    let ai_probability = attribution.ai_probability.or_else(|| {
+        let mut calibrated = attribution.clone();
+        calibration::calibrate(&mut calibrated);
+        calibrated.ai_probability
+    })?;
+    let mut leaning: Vec<&Signal> = signals.iter().filter(|s| s.is_ai_leaning()).collect();
+    leaning.sort_by(|a, b| b.weight.total_cmp(&a.weight).then_with(|| a.id.cmp(&b.id)));
+    let weight = leaning.iter().map(|s| s.weight).sum();
+    let mut rules: Vec<String> = Vec::new();
+    for s in leaning {
+        if rules.len() == DOMINANT_RULES {
+            break;
+        }
+        if !s.id.is_empty() && !rules.contains(&s.id) {
+            rules.push(s.id.clone());
+        }
+    }
+    Some((Offender { path, symbol, lines, family: attribution.primary, ai_probability, rules }, weight))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::report::{ReportMetadata, SymbolMetadata, SymbolReport};
+    use std::collections::BTreeMap;
+
+    fn attribution(primary: ModelFamily, ai_probability: Option<f64>) -> Attribution {
+        let scores = BTreeMap::from([(primary, 1.0)]);
+        Attribution { primary, confidence: 0.8, scores, era: None, ai_probability, authorship: None }
+    }
+
+    fn report(path: &str, ai_probability: f64, signals: Vec<Signal>) -> Report {
+        Report {
+            attribution: attribution(ModelFamily::Claude, Some(ai_probability)),
+            signals,
+            metadata: ReportMetadata {
+                file_path: Some(PathBuf::from(path)),
+                lines_of_code: 10,
+                signal_count: 0,
+                degraded: vec![],
+                skipped: None,
+            },
+            symbol_reports: None,
+            segments: None,
+            comment_free: None,
+            ensemble: None,
+        }
+    }
+
+    fn signal(id: &str, family: ModelFamily, weight: f64) -> Signal {
+        Signal::new(id, "test", id, family, weight)
+    }
+
+    #[test]
+    fn files_rank_by_probability_with_their_heaviest_rules() {
+        let signals = vec![
+            signal("a.light", ModelFamily::Claude, 0.5),
+            signal("a.heavy", ModelFamily::Gpt, 2.0),
+            signal("a.human", ModelFamily::Human, 3.0),
+            signal("a.mid", ModelFamily::Claude, 1.0),
+            signal("a.least", ModelFamily::Claude, 0.1),
+        ];
+        let reports = vec![report("low.rs", 0.2, vec![]), report("high.rs", 0.9, signals), report("mid.rs", 0.5, vec![])];
+        let ranked = top(&reports, 2, false);
+        let paths: Vec<String> = ranked.iter().map(Offender::location).collect();
+        assert_eq!(paths, vec!["high.rs", "mid.rs"]);
+        assert_eq!(ranked[0].rules, vec!["a.heavy", "a.mid", "a.light"]);
+    }
+
+    #[test]
+    fn functions_are_ranked_alongside_files() {
+        let mut file = report("lib.rs", 0.4, vec![]);
+        file.symbol_reports = Some(vec![SymbolReport {
+            metadata: SymbolMetadata { name: "parse".into(), kind: "function".into(), start_line: 3, end_line: 9 },
+            attribution: attribution(ModelFamily::Gpt, None),
+            signals: vec![],
+        }]);
+        let ranked = rank(std::slice::from_ref(&file), true);
+        assert_eq!(ranked[0].location(), "lib.rs:3-9 parse()");
+        assert_eq!(ranked[0].family, ModelFamily::Gpt);
+        assert!(ranked[0].ai_probability > 0.4, "the symbol is calibrated: {}", ranked[0].ai_probability);
+        assert_eq!(rank(&[file], false).len(), 1);
+    }
+
+    #[test]
+    fn entries_without_a_verdict_are_left_out() {
+        let mut empty = report("empty.rs", 0.0, vec![]);
+        empty.attribution = Attribution { confidence: 0.0, ai_probability: None, ..empty.attribution };
+        let skipped = Report::skipped(PathBuf::from("big.rs"), "too_large");
+        assert!(rank(&[empty, skipped], true).is_empty());
+    }
+}
@@ -0,0 +1,157 @@
+//! Cleanup suggestions for AI-attributed findings.
+//!
+//! Every signal ID has the shape `<language>.<category>.<name>`.  Signals are
+//! grouped by category and each category maps to one short, actionable
+//! suggestion, so a report can say what to change rather than only what was
+//! detected.  Human-family and negative-weight signals never get a
+//! suggestion — they point *away* from AI authorship.
+
+use serde::Serialize;
+
+use crate::report::{ModelFamily, Report};
+
+/// A cleanup suggestion for one finding category in a report.
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct Remediation {
+    /// Finding category, e.g. `"comments"` or `"naming"`.
+    pub category: String,
+    /// What to change to address the category.
+    pub suggestion: &'static str,
+    /// IDs of the signals in this category that fired, in report order.
+    pub signals: Vec<String>,
+}
+
+const SUGGESTIONS: &[(&str, &str)] = &[
+    ("ai_signals", "Drop doc comments on private or self-explanatory items and strip markdown from code comments."),
+    ("comments", "Condense narration comments: delete ones that restate the code, step numbers and tutorial phrasing; keep the why."),
+    ("doc_coverage", "Document the public surface only; remove doc comments whose text repeats the item's name."),
+    ("errors", "Follow the project's error-handling conventions instead of wrapping every call; drop handlers for errors that cannot occur."),
+    ("fn_length", "Fold tiny single-use helpers back into their callers where the split adds no clarity."),
+    ("idioms", "Replace showcase patterns (builders, long iterator chains, extra trait impls) with the plainer form the codebase already uses."),
+    ("inline_comments", "Remove inline comments that narrate each statement; keep only non-obvious rationale."),
+    ("naming", "Shorten over-descriptive identifiers to the length and convention the surrounding code uses."),
+    ("structure", "Remove unused exported functions, types and derives; inline one-line wrappers with a single caller."),
+    ("type_annotations", "Drop annotations the project does not otherwise require."),
+];
+
+/// The category of a signal ID — its second dotted segment.
+///
+/// Returns `None` for IDs that do not follow the `<language>.<category>.…`
+/// shape (including the empty ID of signals created without one).
+pub fn category(signal_id: &str) -> Option<&str> {
+    signal_id.split('.').nth(1).filter(|c| !c.is_empty())
+}
+
+/// The suggestion for a finding category, if one is defined.
+pub fn suggestion(category: &str) -> Option<&'static str> {
+    SUGGESTIONS
+        .iter()
+        .find(|(c, _)| *c == category)
+        .map(|(_, s)| *s)
+}
+
+/// Suggestions for the AI-attributed findings in `report`.
+///
+/// One entry per category with a suggestion, ordered by the total weight the
+/// category contributed (largest first) so the most impactful cleanup leads.
+pub fn for_report(report: &Report) -> Vec<Remediation> {
+    let mut grouped: Vec<(Remediation, f64)> = Vec::new();
+    for signal in &report.signals {
+        if signal.family == ModelFamily::Human || signal.weight <= 0.0 {
+            continue;
+        }
+        let Some(cat) = category(&signal.id) else { continue };
+        let Some(text) = suggestion(cat) else { continue };
+        match grouped.iter_mut().find(|(r, _)| r.category == cat) {
+            Some((r, total)) => {
+                r.signals.push(signal.id.clone());
+                *total += signal.weight;
+            }
+            None => grouped.push((
+                Remediation {
+                    category: cat.to_string(),
+                    suggestion: text,
+                    signals: vec![signal.id.clone()],
+                },
+                signal.weight,
+            )),
+        }
+    }
+    grouped.sort_by(|a, b| b.1.partial_cmp(&a.1).unwrap().then_with(|| a.0.category.cmp(&b.0.category)));
+    grouped.into_iter().map(|(r, _)| r).collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::report::{Attribution, ReportMetadata, Signal};
+    use std::collections::BTreeMap;
+
+    fn report(signals: Vec<Signal>) -> Report {
+        Report {
+            attribution: Attribution {
+                primary: ModelFamily::Claude,
+                confidence: 0.8,
+                scores: BTreeMap::new(),
+                era: None,
+                ai_probability: None,
+                authorship: None,
+            },
+            metadata: ReportMetadata {
+                file_path: None,
+                lines_of_code: 10,
+                signal_count: signals.len(),
+                degraded: vec![],
+                skipped: None,
+            },
+            signals,
+            symbol_reports: None,
+            segments: None,
+            comment_free: None,
+            ensemble: None,
+        }
+    }
+
+    #[test]
+    fn category_is_second_segment() {
+        assert_eq!(category("rust.comments.step_numbered"), Some("comments"));
+        assert_eq!(category("go_cst.named_returns"), Some("named_returns"));
+        assert_eq!(category("bare"), None);
+        assert_eq!(category(""), None);
+    }
+
+    #[test]
+    fn every_suggestion_category_is_used_by_a_signal() {
+        for (cat, _) in SUGGESTIONS {
+            assert!(
+                crate::heuristics::all_heuristics().iter().any(|h| category(h.id) == Some(*cat)),
+                "suggestion for unknown category `{cat}`"
+            );
+        }
+    }
+
+    #[test]
+    fn groups_by_category_ordered_by_weight() {
+        let r = report(vec![
+            Signal::new("rust.naming.very_descriptive", "naming", "x", ModelFamily::Claude, 1.0),
+            Signal::new("rust.comments.step_numbered", "comments", "x", ModelFamily::Gpt, 1.5),
+            Signal::new("rust.comments.teaching_voice", "comments", "x", ModelFamily::Claude, 1.0),
+        ]);
+        let fixes = for_report(&r);
+        assert_eq!(fixes.len(), 2);
+        assert_eq!(fixes[0].category, "comments");
+        assert_eq!(fixes[0].signals, vec!["rust.comments.step_numbered", "rust.comments.teaching_voice"]);
+        assert_eq!(fixes[1].category, "naming");
+    }
+
+    #[test]
+    fn skips_human_and_unknown_signals() {
+        let r = report(vec![
+            Signal::new("rust.comments.minimal", "comments", "x", ModelFamily::Human, 1.0),
+            Signal::new("rust.comments.dense", "comments", "x", ModelFamily::Claude, -0.5),
+            Signal::new("rust.imports.sorted", "imports", "x", ModelFamily::Claude, 1.0),
+            Signal::new("", "naming", "x", ModelFamily::Claude, 1.0),
+        ]);
+        assert!(for_report(&r).is_empty());
+    }
+}
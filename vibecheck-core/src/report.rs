@@ -1,9 +1,11 @@
 use serde::{Deserialize, Serialize};
-use std::collections::HashMap;
+use std::collections::BTreeMap;
 use std::path::PathBuf;
 
+use crate::capability::Capability;
+
 /// The model families we can attribute code to.
-#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Serialize, Deserialize)]
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Hash, Serialize, Deserialize)]
 #[serde(rename_all = "lowercase")]
 pub enum ModelFamily {
     Claude,
@@ -53,12 +55,29 @@ impl std::fmt::Display for ModelFamily {
 /// Maps to [`ModelFamily`] for known families, keeps the raw string for new
 /// ones (e.g. "deepseek", "qwen").  The heuristic path uses `ModelFamily`
 /// directly; the ML layer uses `FamilyId` and converts at boundaries.
+///
+/// A label may also name a model *era* — `<family>-<year>-<style>`, e.g.
+/// `"gpt-2023-chat"` or `"gpt-2025-terse"`.  Style fingerprints drift as
+/// models are retrained, so the corpus is labelled by era and classifiers
+/// can be evaluated per era; the family is always the part before the
+/// first `-`.
 #[derive(Debug, Clone, PartialEq, Eq, Hash, Serialize, Deserialize)]
 pub struct FamilyId(pub String);
 
 impl FamilyId {
+    /// The family part of the label: `"gpt"` for both `"gpt"` and
+    /// `"gpt-2023-chat"`.
+    pub fn family(&self) -> &str {
+        self.0.split('-').next().unwrap_or(&self.0)
+    }
+
+    /// The full label when it names an era, `None` for a bare family.
+    pub fn era(&self) -> Option<&str> {
+        self.0.contains('-').then_some(self.0.as_str())
+    }
+
     pub fn to_model_family(&self) -> Option<ModelFamily> {
-        match self.0.to_lowercase().as_str() {
+        match self.family().to_lowercase().as_str() {
             "claude"  => Some(ModelFamily::Claude),
             "gpt"     => Some(ModelFamily::Gpt),
             "gemini"  => Some(ModelFamily::Gemini),
@@ -79,6 +98,41 @@ impl std::fmt::Display for FamilyId {
     }
 }
 
+/// How much a finding matters to whoever acts on it, set from the
+/// signal's weight by the active policy (see [`crate::policy`]).
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, PartialOrd, Ord, Hash, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum Severity {
+    /// Context: weak evidence, or evidence for human authorship.
+    #[default]
+    Info,
+    Warn,
+    Error,
+}
+
+impl std::fmt::Display for Severity {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        match self {
+            Severity::Info => write!(f, "info"),
+            Severity::Warn => write!(f, "warn"),
+            Severity::Error => write!(f, "error"),
+        }
+    }
+}
+
+impl std::str::FromStr for Severity {
+    type Err = anyhow::Error;
+
+    fn from_str(s: &str) -> anyhow::Result<Self> {
+        match s {
+            "info" => Ok(Severity::Info),
+            "warn" => Ok(Severity::Warn),
+            "error" => Ok(Severity::Error),
+            _ => anyhow::bail!("unknown severity: {s} (expected info, warn or error)"),
+        }
+    }
+}
+
 /// A single signal emitted by an analyzer.
 #[non_exhaustive]
 #[derive(Debug, Clone, Serialize, Deserialize)]
@@ -95,6 +149,14 @@ pub struct Signal {
     pub family: ModelFamily,
     /// Weight of this signal (negative = evidence against).
     pub weight: f64,
+    /// 1-based source lines of the findings behind this signal, when the
+    /// analyzer can point at them.
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub lines: Vec<usize>,
+    /// Severity under the active policy; `info` for signals loaded from old
+    /// cache entries.
+    #[serde(default)]
+    pub severity: Severity,
 }
 
 impl Signal {
@@ -115,6 +177,30 @@ impl Signal {
             description: desc.into(),
             family,
             weight,
+            lines: Vec::new(),
+            severity: Severity::Info,
+        }
+    }
+
+    /// Attach the source lines of the findings behind this signal.
+    pub fn with_lines(mut self, lines: Vec<usize>) -> Self {
+        self.lines = lines;
+        self
+    }
+
+    /// Whether this signal is evidence of AI authorship, as opposed to
+    /// evidence against it or for human authorship.
+    pub fn is_ai_leaning(&self) -> bool {
+        self.weight > 0.0 && self.family != ModelFamily::Human
+    }
+
+    /// `"line 4"` or `"lines 4, 9, 12"`; `None` when no lines are attached.
+    pub fn lines_label(&self) -> Option<String> {
+        let list: Vec<String> = self.lines.iter().map(|l| l.to_string()).collect();
+        match list.len() {
+            0 => None,
+            1 => Some(format!("line {}", list[0])),
+            _ => Some(format!("lines {}", list.join(", "))),
         }
     }
 }
@@ -126,8 +212,24 @@ pub struct Attribution {
     pub primary: ModelFamily,
     /// Confidence in the primary attribution (0.0–1.0).
     pub confidence: f64,
-    /// Score distribution across all families (sums to ~1.0).
-    pub scores: HashMap<ModelFamily, f64>,
+    /// Score distribution across all families (sums to ~1.0), in family
+    /// order so serialized reports are stable.
+    pub scores: BTreeMap<ModelFamily, f64>,
+    /// Most likely model era within `primary`, e.g. `"gpt-2025-terse"`.
+    /// Only set when an ML scorer trained on era-labelled data is attached;
+    /// heuristic attribution has no notion of era.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub era: Option<String>,
+    /// Calibrated probability that the code is AI-generated (0.0–1.0),
+    /// unlike `confidence` comparable across files and repos.  `None` when
+    /// there was no signal data (see [`crate::calibration`]).
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub ai_probability: Option<f64>,
+    /// Three-way verdict read off `ai_probability` by the configured bands
+    /// (see [`crate::calibration::Bands`]).  `None` when `ai_probability`
+    /// is.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub authorship: Option<Authorship>,
 }
 
 impl Attribution {
@@ -139,12 +241,98 @@ impl Attribution {
     }
 }
 
+/// How much of a file an AI wrote: not at all, in part (suggestions a
+/// person accepted and edited), or all of it.  Ordered from least to most
+/// AI involvement.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Hash, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum Authorship {
+    HumanAuthored,
+    AiAssisted,
+    AiGenerated,
+}
+
+impl Authorship {
+    pub fn all() -> &'static [Authorship] {
+        &[Authorship::HumanAuthored, Authorship::AiAssisted, Authorship::AiGenerated]
+    }
+
+    /// The verdict for `report`: its `authorship`, or for reports without
+    /// one (calibrated elsewhere, or cached before verdicts existed) one
+    /// read off `ai_probability` by the default bands, or off the primary
+    /// family when there is no probability either.  `None` when the report
+    /// has too little signal data.
+    pub fn of(report: &Report) -> Option<Self> {
+        let attribution = &report.attribution;
+        if !attribution.has_sufficient_data() {
+            return None;
+        }
+        if let Some(authorship) = attribution.authorship {
+            return Some(authorship);
+        }
+        let probability = attribution.ai_probability.unwrap_or(match attribution.primary {
+            ModelFamily::Human => 0.0,
+            _ => 1.0,
+        });
+        Some(crate::calibration::Bands::default().authorship(probability))
+    }
+
+    /// What an unmarked corpus sample of `family` is: human-authored for
+    /// human samples, AI-generated for the rest.
+    pub fn of_label(family: ModelFamily) -> Self {
+        match family {
+            ModelFamily::Human => Authorship::HumanAuthored,
+            _ => Authorship::AiGenerated,
+        }
+    }
+
+    /// `human-authored`, `ai-assisted` or `ai-generated`, as serialized.
+    pub fn as_str(self) -> &'static str {
+        match self {
+            Authorship::HumanAuthored => "human-authored",
+            Authorship::AiAssisted => "ai-assisted",
+            Authorship::AiGenerated => "ai-generated",
+        }
+    }
+}
+
+impl std::fmt::Display for Authorship {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        f.write_str(match self {
+            Authorship::HumanAuthored => "Human-authored",
+            Authorship::AiAssisted => "AI-assisted",
+            Authorship::AiGenerated => "AI-generated",
+        })
+    }
+}
+
+impl std::str::FromStr for Authorship {
+    type Err = anyhow::Error;
+
+    fn from_str(s: &str) -> anyhow::Result<Self> {
+        match s {
+            "human-authored" => Ok(Authorship::HumanAuthored),
+            "ai-assisted" => Ok(Authorship::AiAssisted),
+            "ai-generated" => Ok(Authorship::AiGenerated),
+            _ => anyhow::bail!("unknown authorship: {s} (expected human-authored, ai-assisted or ai-generated)"),
+        }
+    }
+}
+
 /// Metadata about the analysis.
 #[derive(Debug, Clone, Serialize, Deserialize)]
 pub struct ReportMetadata {
     pub file_path: Option<PathBuf>,
     pub lines_of_code: usize,
     pub signal_count: usize,
+    /// Capabilities that were unavailable for this file; the verdict was
+    /// computed from the remaining signals.
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub degraded: Vec<Capability>,
+    /// Why the file was not analyzed (e.g. `"timeout"`).  A skipped report
+    /// has no signals and insufficient data for a verdict.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub skipped: Option<String>,
 }
 
 /// Metadata about a named symbol (function, method, class, etc.) within a file.
@@ -195,6 +383,45 @@ pub struct SymbolReport {
     pub signals: Vec<Signal>,
 }
 
+/// Analysis report for one contiguous region of a file whose style differs
+/// from its neighbours (see [`crate::segments`]).
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct SegmentReport {
+    /// 1-based, inclusive line range of the region.
+    pub start_line: usize,
+    pub end_line: usize,
+    pub attribution: Attribution,
+    /// Signals from the region alone; their `lines` are file line numbers.
+    pub signals: Vec<Signal>,
+}
+
+/// Verdict on a file's code with every comment removed, to show how much of
+/// the full verdict rests on comments — which anyone can delete.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct CommentFreeReport {
+    pub attribution: Attribution,
+    pub signal_count: usize,
+}
+
+/// One backend's verdict on a file scored by an ensemble of classifiers.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct BackendVerdict {
+    /// The backend name, as in `[classifier] backend`; `ml` for a
+    /// [`crate::pipeline::PostScorer`].
+    pub backend: String,
+    pub primary: ModelFamily,
+    pub confidence: f64,
+}
+
+/// The member verdicts behind an ensemble's averaged attribution.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct EnsembleReport {
+    pub verdicts: Vec<BackendVerdict>,
+    /// Some backends call the file human and others AI.  The averaged
+    /// verdict then hides a split decision, and the file wants a human look.
+    pub disagreement: bool,
+}
+
 /// The full analysis report for a single source input.
 #[derive(Debug, Clone, Serialize, Deserialize)]
 pub struct Report {
@@ -202,6 +429,60 @@ pub struct Report {
     pub signals: Vec<Signal>,
     pub metadata: ReportMetadata,
     pub symbol_reports: Option<Vec<SymbolReport>>,
+    /// Per-region verdicts for files of mixed authorship, when requested.
+    /// A file with a uniform style has a single segment.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub segments: Option<Vec<SegmentReport>>,
+    /// The verdict with comments stripped, when requested.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub comment_free: Option<CommentFreeReport>,
+    /// Member verdicts, when an ensemble of backends scored the file.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub ensemble: Option<EnsembleReport>,
+}
+
+impl Report {
+    /// A report for a file that was not analyzed, recording `reason` so the
+    /// file is listed as skipped rather than silently dropped.
+    pub fn skipped(file_path: PathBuf, reason: &str) -> Self {
+        Self {
+            attribution: Attribution {
+                primary: ModelFamily::Human,
+                confidence: 0.0,
+                scores: BTreeMap::new(),
+                era: None,
+                ai_probability: None,
+                authorship: None,
+            },
+            signals: Vec::new(),
+            metadata: ReportMetadata {
+                file_path: Some(file_path),
+                lines_of_code: 0,
+                signal_count: 0,
+                degraded: vec![],
+                skipped: Some(reason.to_string()),
+            },
+            symbol_reports: None,
+            segments: None,
+            comment_free: None,
+            ensemble: None,
+        }
+    }
+
+    /// How much of the AI probability rests on comments: the full
+    /// probability minus the comment-free one.  Positive when stripping
+    /// comments makes the file look more human.  `None` unless both are
+    /// known.
+    pub fn comment_reliance(&self) -> Option<f64> {
+        let stripped = self.comment_free.as_ref()?.attribution.ai_probability?;
+        Some(self.attribution.ai_probability? - stripped)
+    }
+
+    /// Whether the ensemble's backends split between human and AI, so the
+    /// file should be reviewed by hand.
+    pub fn needs_review(&self) -> bool {
+        self.ensemble.as_ref().is_some_and(|e| e.disagreement)
+    }
 }
 
 #[cfg(test)]
@@ -261,6 +542,16 @@ mod tests {
         assert_eq!(s.description, "desc");
         assert_eq!(s.family, ModelFamily::Claude);
         assert_eq!(s.weight, 1.5);
+        assert!(s.lines.is_empty());
+    }
+
+    #[test]
+    fn signal_lines_label() {
+        let s = Signal::new("x", "x", "desc", ModelFamily::Gpt, 1.0);
+        assert_eq!(s.lines_label(), None);
+        assert!(!serde_json::to_string(&s).unwrap().contains("lines"));
+        assert_eq!(s.clone().with_lines(vec![4]).lines_label().as_deref(), Some("line 4"));
+        assert_eq!(s.with_lines(vec![4, 9]).lines_label().as_deref(), Some("lines 4, 9"));
     }
 
     #[test]
@@ -287,6 +578,34 @@ mod tests {
         assert_eq!(FamilyId("GPT".into()).to_model_family(), Some(ModelFamily::Gpt));
     }
 
+    #[test]
+    fn family_id_era_labels_map_to_family() {
+        let id = FamilyId("gpt-2023-chat".into());
+        assert_eq!(id.family(), "gpt");
+        assert_eq!(id.era(), Some("gpt-2023-chat"));
+        assert_eq!(id.to_model_family(), Some(ModelFamily::Gpt));
+
+        let bare = FamilyId("claude".into());
+        assert_eq!(bare.family(), "claude");
+        assert_eq!(bare.era(), None);
+    }
+
+    #[test]
+    fn attribution_without_era_omits_field() {
+        let attr = Attribution {
+            primary: ModelFamily::Human,
+            confidence: 0.5,
+            scores: BTreeMap::new(),
+            era: None,
+            ai_probability: None,
+            authorship: None,
+        };
+        let json = serde_json::to_string(&attr).unwrap();
+        assert!(!json.contains("era"));
+        let back: Attribution = serde_json::from_str(&json).unwrap();
+        assert_eq!(back.era, None);
+    }
+
     #[test]
     fn family_id_to_model_family_unknown() {
         assert_eq!(FamilyId("deepseek".into()).to_model_family(), None);
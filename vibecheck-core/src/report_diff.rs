@@ -0,0 +1,236 @@
+//! Differences between two scans of the same tree.
+//!
+//! Nightly scans of a large repository produce walls of near-identical
+//! results; what a reviewer wants is what changed since the last one.
+//! Reports are matched by file path, and findings — AI-leaning signals —
+//! by file path and rule id, so lines moving around does not make a
+//! finding look new.
+
+use std::collections::{BTreeMap, BTreeSet};
+use std::path::{Path, PathBuf};
+
+use serde::Serialize;
+
+use crate::report::{ModelFamily, Report};
+
+/// AI probability changes smaller than this are not reported for a file
+/// whose findings did not change either.
+pub const MIN_DELTA: f64 = 0.005;
+
+/// How a file differs between the two scans.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "snake_case")]
+pub enum FileStatus {
+    /// Only in the new scan.
+    Added,
+    /// Only in the old scan.
+    Removed,
+    /// In both, with a different score or different findings.
+    Changed,
+}
+
+/// One finding, as of the scan it is reported from.
+#[derive(Debug, Clone, Serialize)]
+pub struct Finding {
+    pub path: PathBuf,
+    pub rule: String,
+    pub family: ModelFamily,
+    pub weight: f64,
+    pub description: String,
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub lines: Vec<usize>,
+}
+
+/// A file whose score or findings changed.
+#[derive(Debug, Clone, Serialize)]
+pub struct FileDelta {
+    pub path: PathBuf,
+    pub status: FileStatus,
+    /// AI probability in each scan; `None` when the file is missing from
+    /// it or had no verdict.
+    pub old: Option<f64>,
+    pub new: Option<f64>,
+}
+
+impl FileDelta {
+    /// `new - old`, when the file has a score in both scans.
+    pub fn delta(&self) -> Option<f64> {
+        Some(self.new? - self.old?)
+    }
+}
+
+/// What changed from one scan to the next.
+#[derive(Debug, Clone, Default, Serialize)]
+pub struct ReportDiff {
+    /// Findings in the new scan that the old one did not have.
+    pub new_findings: Vec<Finding>,
+    /// Findings in the old scan that are gone from the new one.
+    pub resolved_findings: Vec<Finding>,
+    /// Changed files, largest score change first; added and removed files
+    /// after them.
+    pub files: Vec<FileDelta>,
+    /// Files in both scans with the same findings and score.
+    pub unchanged: usize,
+}
+
+/// Compare the `old` scan with the `new` one.  Reports without a path are
+/// matched as `<stdin>`.
+pub fn diff(old: &[Report], new: &[Report]) -> ReportDiff {
+    let old = by_path(old);
+    let new = by_path(new);
+    let paths: BTreeSet<&PathBuf> = old.keys().chain(new.keys()).collect();
+
+    let mut out = ReportDiff::default();
+    for path in paths {
+        let (before, after) = (old.get(path), new.get(path));
+        let before_findings = before.map(|r| findings(path, r)).unwrap_or_default();
+        let after_findings = after.map(|r| findings(path, r)).unwrap_or_default();
+        let mut findings_changed = false;
+        for (rule, finding) in &after_findings {
+            if !before_findings.contains_key(rule) {
+                out.new_findings.push(finding.clone());
+                findings_changed = true;
+            }
+        }
+        for (rule, finding) in &before_findings {
+            if !after_findings.contains_key(rule) {
+                out.resolved_findings.push(finding.clone());
+                findings_changed = true;
+            }
+        }
+
+        let status = match (before, after) {
+            (None, _) => FileStatus::Added,
+            (_, None) => FileStatus::Removed,
+            _ => FileStatus::Changed,
+        };
+        let delta = FileDelta {
+            path: path.clone(),
+            status,
+            old: before.and_then(|r| r.attribution.ai_probability),
+            new: after.and_then(|r| r.attribution.ai_probability),
+        };
+        let score_changed = match (delta.old, delta.new) {
+            (Some(a), Some(b)) => (b - a).abs() >= MIN_DELTA,
+            (a, b) => a.is_some() != b.is_some(),
+        };
+        if status == FileStatus::Changed && !score_changed && !findings_changed {
+            out.unchanged += 1;
+        } else {
+            out.files.push(delta);
+        }
+    }
+
+    out.files.sort_by(|a, b| {
+        let rank = |d: &FileDelta| match d.status {
+            FileStatus::Changed => 0,
+            FileStatus::Added => 1,
+            FileStatus::Removed => 2,
+        };
+        let magnitude = |d: &FileDelta| d.delta().map_or(0.0, f64::abs);
+        rank(a)
+            .cmp(&rank(b))
+            .then_with(|| magnitude(b).total_cmp(&magnitude(a)))
+            .then_with(|| a.path.cmp(&b.path))
+    });
+    out
+}
+
+fn by_path(reports: &[Report]) -> BTreeMap<PathBuf, &Report> {
+    reports
+        .iter()
+        .map(|r| (r.metadata.file_path.clone().unwrap_or_else(|| "<stdin>".into()), r))
+        .collect()
+}
+
+/// The AI-leaning findings of `report`, by rule id.
+fn findings(path: &Path, report: &Report) -> BTreeMap<String, Finding> {
+    report
+        .signals
+        .iter()
+        .filter(|s| !s.id.is_empty() && s.is_ai_leaning())
+        .map(|s| {
+            let finding = Finding {
+                path: path.to_path_buf(),
+                rule: s.id.clone(),
+                family: s.family,
+                weight: s.weight,
+                description: s.description.clone(),
+                lines: s.lines.clone(),
+            };
+            (s.id.clone(), finding)
+        })
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::report::{Attribution, ReportMetadata, Signal};
+
+    fn report(path: &str, ai_probability: Option<f64>, rules: &[&str]) -> Report {
+        Report {
+            attribution: Attribution {
+                primary: ModelFamily::Claude,
+                confidence: 0.8,
+                scores: BTreeMap::new(),
+                era: None,
+                ai_probability,
+                authorship: None,
+            },
+            signals: rules.iter().map(|id| Signal::new(id, "test", *id, ModelFamily::Claude, 1.0)).collect(),
+            metadata: ReportMetadata {
+                file_path: Some(PathBuf::from(path)),
+                lines_of_code: 10,
+                signal_count: rules.len(),
+                degraded: vec![],
+                skipped: None,
+            },
+            symbol_reports: None,
+            segments: None,
+            comment_free: None,
+            ensemble: None,
+        }
+    }
+
+    #[test]
+    fn findings_are_matched_by_file_and_rule() {
+        let old = [report("a.rs", Some(0.5), &["r.one", "r.two"]), report("b.rs", Some(0.2), &["r.one"])];
+        let new = [report("a.rs", Some(0.5), &["r.two", "r.three"]), report("b.rs", Some(0.2), &["r.one"])];
+        let diff = diff(&old, &new);
+        let rules = |f: &[Finding]| f.iter().map(|f| format!("{}:{}", f.path.display(), f.rule)).collect::<Vec<_>>();
+        assert_eq!(rules(&diff.new_findings), vec!["a.rs:r.three"]);
+        assert_eq!(rules(&diff.resolved_findings), vec!["a.rs:r.one"]);
+        assert_eq!(diff.files.len(), 1, "a.rs changed findings at the same score");
+        assert_eq!(diff.unchanged, 1);
+    }
+
+    #[test]
+    fn files_are_ordered_by_score_change() {
+        let old = [report("small.rs", Some(0.5), &[]), report("big.rs", Some(0.9), &[]), report("gone.rs", Some(0.4), &["r.x"])];
+        let new = [report("small.rs", Some(0.55), &[]), report("big.rs", Some(0.3), &[]), report("fresh.rs", Some(0.7), &[])];
+        let diff = diff(&old, &new);
+        let order: Vec<(String, FileStatus)> =
+            diff.files.iter().map(|f| (f.path.display().to_string(), f.status)).collect();
+        assert_eq!(
+            order,
+            vec![
+                ("big.rs".into(), FileStatus::Changed),
+                ("small.rs".into(), FileStatus::Changed),
+                ("fresh.rs".into(), FileStatus::Added),
+                ("gone.rs".into(), FileStatus::Removed),
+            ]
+        );
+        assert!((diff.files[0].delta().unwrap() + 0.6).abs() < 1e-9);
+        assert_eq!(diff.files[2].delta(), None);
+        assert_eq!(diff.resolved_findings.len(), 1, "a removed file's findings are resolved");
+    }
+
+    #[test]
+    fn identical_scans_have_no_changes() {
+        let scan = [report("a.rs", Some(0.5), &["r.one"]), report("b.rs", None, &[])];
+        let diff = diff(&scan, &scan);
+        assert!(diff.files.is_empty() && diff.new_findings.is_empty() && diff.resolved_findings.is_empty());
+        assert_eq!(diff.unchanged, 2);
+    }
+}
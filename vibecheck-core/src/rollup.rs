@@ -0,0 +1,248 @@
+//! Per-directory and per-package rollups of a scan.
+//!
+//! A scan of a large repository is a long flat list of files.  Owners want
+//! to know which subsystems are AI-heavy, so this module groups the
+//! reports by directory, or Go files by package, and summarizes each
+//! group's AI probabilities and verdicts.
+
+use std::collections::BTreeMap;
+use std::path::{Path, PathBuf};
+
+use serde::{Deserialize, Serialize};
+
+use crate::language::{detect_language, Language};
+use crate::report::{ModelFamily, Report};
+
+/// What a rollup groups files by.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum Grouping {
+    /// The directory a file is in.
+    Directory,
+    /// The Go package a file declares: its directory plus the name in its
+    /// `package` clause, so an external `foo_test` package is its own
+    /// group.  Files of other languages are left out.
+    Package,
+}
+
+/// Summary of one group of files.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct Rollup {
+    /// The directory, relative to wherever the scanned paths are.
+    pub directory: PathBuf,
+    /// The Go package name, for [`Grouping::Package`].
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub package: Option<String>,
+    /// Analyzed files in the group; skipped files are not counted.
+    pub files: usize,
+    /// Files with a calibrated AI probability, which the statistics below
+    /// are computed over.
+    pub scored: usize,
+    pub mean: f64,
+    pub median: f64,
+    pub max: f64,
+    /// Files per attributed family.
+    pub verdicts: BTreeMap<ModelFamily, usize>,
+}
+
+impl Rollup {
+    /// The group's label: the directory, followed by the package name in
+    /// parentheses when grouping by package.
+    pub fn label(&self) -> String {
+        let dir = if self.directory.as_os_str().is_empty() {
+            ".".to_string()
+        } else {
+            self.directory.display().to_string()
+        };
+        match &self.package {
+            Some(name) => format!("{dir} ({name})"),
+            None => dir,
+        }
+    }
+
+    /// The group's most frequent verdict; ties go to the first family in
+    /// [`ModelFamily::all`] order.
+    pub fn dominant(&self) -> Option<ModelFamily> {
+        ModelFamily::all()
+            .iter()
+            .copied()
+            .filter(|f| self.verdicts.contains_key(f))
+            .max_by(|a, b| self.verdicts[a].cmp(&self.verdicts[b]).then_with(|| b.cmp(a)))
+    }
+}
+
+/// Roll `reports` up by `grouping`, most AI-heavy group (highest mean AI
+/// probability) first.  `sources[i]` is the source of `reports[i]`; it is
+/// only read for [`Grouping::Package`], and a Go file without one (or
+/// without a `package` clause) is left out.
+pub fn rollup(reports: &[Report], sources: &[Option<String>], grouping: Grouping) -> Vec<Rollup> {
+    let mut groups: BTreeMap<(PathBuf, Option<String>), Vec<&Report>> = BTreeMap::new();
+    for (i, report) in reports.iter().enumerate() {
+        if report.metadata.skipped.is_some() {
+            continue;
+        }
+        let Some(path) = report.metadata.file_path.as_deref() else {
+            continue;
+        };
+        let directory = path.parent().unwrap_or(Path::new("")).to_path_buf();
+        let package = match grouping {
+            Grouping::Directory => None,
+            Grouping::Package => {
+                if detect_language(path) != Some(Language::Go) {
+                    continue;
+                }
+                let source = sources.get(i).and_then(|s| s.as_deref());
+                match source.and_then(go_package_name) {
+                    Some(name) => Some(name.to_string()),
+                    None => continue,
+                }
+            }
+        };
+        groups.entry((directory, package)).or_default().push(report);
+    }
+
+    let mut rollups: Vec<Rollup> = groups
+        .into_iter()
+        .map(|((directory, package), members)| summarize(directory, package, &members))
+        .collect();
+    rollups.sort_by(|a, b| b.mean.total_cmp(&a.mean).then_with(|| a.label().cmp(&b.label())));
+    rollups
+}
+
+/// Every analyzed file of `reports` as one group labelled `directory`, for
+/// summarizing a whole checkout.
+pub fn total(reports: &[Report], directory: impl Into<PathBuf>) -> Rollup {
+    let members: Vec<&Report> = reports.iter().filter(|r| r.metadata.skipped.is_none()).collect();
+    summarize(directory.into(), None, &members)
+}
+
+fn summarize(directory: PathBuf, package: Option<String>, members: &[&Report]) -> Rollup {
+    let mut scores: Vec<f64> = members.iter().filter_map(|r| r.attribution.ai_probability).collect();
+    scores.sort_by(f64::total_cmp);
+    let mut verdicts = BTreeMap::new();
+    for r in members {
+        *verdicts.entry(r.attribution.primary).or_insert(0) += 1;
+    }
+    let n = scores.len();
+    let median = match n {
+        0 => 0.0,
+        _ if n % 2 == 1 => scores[n / 2],
+        _ => (scores[n / 2 - 1] + scores[n / 2]) / 2.0,
+    };
+    Rollup {
+        directory,
+        package,
+        files: members.len(),
+        scored: n,
+        mean: if n == 0 { 0.0 } else { scores.iter().sum::<f64>() / n as f64 },
+        median,
+        max: scores.last().copied().unwrap_or(0.0),
+        verdicts,
+    }
+}
+
+/// The name in a Go file's `package` clause, skipping the comments (and
+/// build constraints) that may come before it.
+pub fn go_package_name(source: &str) -> Option<&str> {
+    let mut in_block = false;
+    for line in source.lines() {
+        let mut line = line.trim();
+        if in_block {
+            match line.find("*/") {
+                Some(end) => {
+                    in_block = false;
+                    line = line[end + 2..].trim();
+                }
+                None => continue,
+            }
+        }
+        if let Some(rest) = line.strip_prefix("/*") {
+            match rest.find("*/") {
+                Some(end) => line = rest[end + 2..].trim(),
+                None => {
+                    in_block = true;
+                    continue;
+                }
+            }
+        }
+        if line.is_empty() || line.starts_with("//") {
+            continue;
+        }
+        let name = line.strip_prefix("package")?;
+        if !name.starts_with(char::is_whitespace) {
+            return None;
+        }
+        return name.split(|c: char| c.is_whitespace() || c == ';' || c == '/').find(|s| !s.is_empty());
+    }
+    None
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn report(path: &str, family: ModelFamily, probability: f64) -> Report {
+        let mut report = crate::analyze("x = 1\n");
+        report.metadata.file_path = Some(PathBuf::from(path));
+        report.attribution.primary = family;
+        report.attribution.ai_probability = Some(probability);
+        report
+    }
+
+    #[test]
+    fn groups_by_directory_most_ai_heavy_first() {
+        let reports = vec![
+            report("cmd/main.go", ModelFamily::Human, 0.1),
+            report("internal/cache/lru.go", ModelFamily::Claude, 0.9),
+            report("internal/cache/shard.go", ModelFamily::Gpt, 0.6),
+            report("internal/cache/util.go", ModelFamily::Claude, 0.7),
+            Report::skipped(PathBuf::from("internal/cache/big.go"), "too_large"),
+        ];
+        let rollups = rollup(&reports, &[], Grouping::Directory);
+        assert_eq!(rollups.len(), 2);
+        let cache = &rollups[0];
+        assert_eq!(cache.label(), "internal/cache");
+        assert_eq!((cache.files, cache.scored), (3, 3));
+        assert!((cache.mean - 0.733).abs() < 0.001);
+        assert_eq!((cache.median, cache.max), (0.7, 0.9));
+        assert_eq!(cache.verdicts[&ModelFamily::Claude], 2);
+        assert_eq!(cache.dominant(), Some(ModelFamily::Claude));
+        assert_eq!(rollups[1].label(), "cmd");
+    }
+
+    #[test]
+    fn total_summarizes_every_analyzed_file() {
+        let reports = vec![
+            report("cmd/main.go", ModelFamily::Human, 0.1),
+            report("internal/cache/lru.go", ModelFamily::Claude, 0.9),
+            Report::skipped(PathBuf::from("internal/cache/big.go"), "too_large"),
+        ];
+        let all = total(&reports, "api");
+        assert_eq!(all.label(), "api");
+        assert_eq!((all.files, all.scored, all.max), (2, 2, 0.9));
+        assert_eq!(all.verdicts.len(), 2);
+    }
+
+    #[test]
+    fn groups_go_files_by_package_clause() {
+        let reports = vec![
+            report("cache/lru.go", ModelFamily::Claude, 0.8),
+            report("cache/lru_test.go", ModelFamily::Human, 0.2),
+            report("cache/notes.py", ModelFamily::Human, 0.1),
+        ];
+        let sources = vec![
+            Some("// Package cache is an LRU.\npackage cache\n".to_string()),
+            Some("//go:build linux\n\n/* external\n   tests */\npackage cache_test // black-box\n".to_string()),
+            Some("x = 1\n".to_string()),
+        ];
+        let labels: Vec<String> = rollup(&reports, &sources, Grouping::Package).iter().map(Rollup::label).collect();
+        assert_eq!(labels, ["cache (cache)", "cache (cache_test)"]);
+    }
+
+    #[test]
+    fn go_package_name_needs_a_clause() {
+        assert_eq!(go_package_name("package main\n"), Some("main"));
+        assert_eq!(go_package_name("/* a */ package a; import \"fmt\"\n"), Some("a"));
+        assert_eq!(go_package_name("packagex\n"), None);
+        assert_eq!(go_package_name("// only a comment\n"), None);
+    }
+}
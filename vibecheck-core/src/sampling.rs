@@ -0,0 +1,177 @@
+//! Reproducible file sampling for scans of very large repositories.
+//!
+//! Analyzing every file of a million-file monorepo takes too long for a
+//! weekly health check, and the repository-level AI share does not need
+//! it: a few thousand files estimate it to within a point or two.  Files
+//! are chosen by hashing their path with a seed, so the same seed picks
+//! the same files on every machine, and a file added to the repository
+//! does not reshuffle the rest of the sample.
+
+use std::path::{Path, PathBuf};
+use std::str::FromStr;
+
+use anyhow::{bail, Context};
+use serde::Serialize;
+use sha2::{Digest, Sha256};
+
+/// z-score of the two-sided 95% confidence interval.
+const Z_95: f64 = 1.96;
+
+/// How many files to sample: a count, or a percentage of the files found.
+#[derive(Debug, Clone, Copy, PartialEq)]
+pub enum SampleSize {
+    Files(usize),
+    Percent(f64),
+}
+
+impl SampleSize {
+    /// The number of files to sample from `population`; at least one and
+    /// at most all of them.
+    pub fn of(self, population: usize) -> usize {
+        let n = match self {
+            SampleSize::Files(n) => n,
+            SampleSize::Percent(p) => (population as f64 * p / 100.0).ceil() as usize,
+        };
+        n.clamp(1, population.max(1))
+    }
+}
+
+impl FromStr for SampleSize {
+    type Err = anyhow::Error;
+
+    /// `1000` or `5%`.
+    fn from_str(s: &str) -> anyhow::Result<Self> {
+        let s = s.trim();
+        if let Some(p) = s.strip_suffix('%') {
+            let p = p.trim().parse::<f64>().with_context(|| format!("invalid sample percentage: {s}"))?;
+            if !(p > 0.0 && p <= 100.0) {
+                bail!("sample percentage must be in (0, 100], got {s}");
+            }
+            return Ok(SampleSize::Percent(p));
+        }
+        match s.parse::<usize>() {
+            Ok(0) => bail!("sample size must be at least 1"),
+            Ok(n) => Ok(SampleSize::Files(n)),
+            Err(_) => bail!("invalid sample size: {s} (expected a file count like 1000 or a percentage like 5%)"),
+        }
+    }
+}
+
+/// The files of `size` drawn from `files` with `seed`, in their original
+/// order.  A file's draw depends only on the seed and its path relative to
+/// `root`, so the sample is the same wherever the scan runs from.
+pub fn select(files: &[PathBuf], root: &Path, size: SampleSize, seed: u64) -> Vec<PathBuf> {
+    let n = size.of(files.len());
+    let mut keyed: Vec<([u8; 32], usize)> = files.iter().enumerate().map(|(i, f)| (key(f, root, seed), i)).collect();
+    keyed.sort_unstable();
+    let mut chosen: Vec<usize> = keyed.into_iter().take(n).map(|(_, i)| i).collect();
+    chosen.sort_unstable();
+    chosen.into_iter().map(|i| files[i].clone()).collect()
+}
+
+fn key(file: &Path, root: &Path, seed: u64) -> [u8; 32] {
+    let relative = file.strip_prefix(root).unwrap_or(file);
+    let mut hasher = Sha256::new();
+    hasher.update(seed.to_le_bytes());
+    hasher.update(relative.to_string_lossy().replace('\\', "/").as_bytes());
+    hasher.finalize().into()
+}
+
+/// A share of lines estimated from a sample, with its 95% confidence
+/// interval.
+#[derive(Debug, Clone, Copy, PartialEq, Serialize)]
+pub struct Estimate {
+    pub fraction: f64,
+    pub low: f64,
+    pub high: f64,
+}
+
+/// Estimate the share of AI-attributed lines in a population of
+/// `population` files from a sample of `(lines, ai_lines)` per file.
+///
+/// Files vary in size, so this is the ratio estimator (AI lines over lines
+/// in the sample) with its linearized standard error and the finite
+/// population correction: sampling every file gives a zero-width interval.
+/// `None` when the sample has no lines.
+pub fn estimate_fraction(sample: &[(usize, usize)], population: usize) -> Option<Estimate> {
+    let n = sample.len();
+    let lines: usize = sample.iter().map(|&(x, _)| x).sum();
+    if lines == 0 {
+        return None;
+    }
+    let fraction = sample.iter().map(|&(_, y)| y).sum::<usize>() as f64 / lines as f64;
+    if n < 2 {
+        let certain = n >= population;
+        return Some(Estimate {
+            fraction,
+            low: if certain { fraction } else { 0.0 },
+            high: if certain { fraction } else { 1.0 },
+        });
+    }
+    let residuals: f64 = sample.iter().map(|&(x, y)| (y as f64 - fraction * x as f64).powi(2)).sum::<f64>() / (n - 1) as f64;
+    let mean_lines = lines as f64 / n as f64;
+    let correction = (1.0 - n as f64 / population.max(n) as f64).max(0.0);
+    let se = (correction * residuals / n as f64).sqrt() / mean_lines;
+    Some(Estimate {
+        fraction,
+        low: (fraction - Z_95 * se).max(0.0),
+        high: (fraction + Z_95 * se).min(1.0),
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn files(n: usize) -> Vec<PathBuf> {
+        (0..n).map(|i| PathBuf::from(format!("repo/src/f{i:04}.rs"))).collect()
+    }
+
+    #[test]
+    fn sample_sizes_parse_counts_and_percentages() {
+        assert_eq!("1000".parse::<SampleSize>().unwrap(), SampleSize::Files(1000));
+        assert_eq!(" 2.5% ".parse::<SampleSize>().unwrap(), SampleSize::Percent(2.5));
+        for bad in ["0", "0%", "101%", "ten", "-3"] {
+            assert!(bad.parse::<SampleSize>().is_err(), "{bad}");
+        }
+        assert_eq!(SampleSize::Percent(1.0).of(250), 3);
+        assert_eq!(SampleSize::Files(50).of(10), 10);
+    }
+
+    #[test]
+    fn selection_is_reproducible_and_seeded() {
+        let all = files(500);
+        let a = select(&all, Path::new("repo"), SampleSize::Files(40), 7);
+        assert_eq!(a.len(), 40);
+        assert!(a.windows(2).all(|w| w[0] < w[1]), "kept in input order");
+        assert_eq!(a, select(&all, Path::new("repo"), SampleSize::Files(40), 7));
+
+        // The same files from another working directory, not under another seed.
+        let moved: Vec<PathBuf> = all.iter().map(|f| Path::new("/abs").join(f)).collect();
+        let b = select(&moved, Path::new("/abs/repo"), SampleSize::Files(40), 7);
+        assert!(b.iter().zip(&a).all(|(b, a)| b.ends_with(a)));
+        assert_ne!(a, select(&all, Path::new("repo"), SampleSize::Files(40), 8));
+
+        // Adding files only displaces sampled files whose draw is beaten.
+        let grown = files(600);
+        let c = select(&grown, Path::new("repo"), SampleSize::Files(40), 7);
+        assert!(a.iter().filter(|f| c.contains(f)).count() >= 30);
+    }
+
+    #[test]
+    fn estimate_covers_the_population_share() {
+        // 1000 files of 10–59 lines; every third is AI.
+        let population: Vec<(usize, usize)> =
+            (0..1000).map(|i| (10 + i % 50, if i % 3 == 0 { 10 + i % 50 } else { 0 })).collect();
+        let truth = population.iter().map(|p| p.1).sum::<usize>() as f64 / population.iter().map(|p| p.0).sum::<usize>() as f64;
+        let sample: Vec<(usize, usize)> = population.iter().copied().step_by(7).collect();
+        let e = estimate_fraction(&sample, population.len()).unwrap();
+        assert!(e.low < truth && truth < e.high, "{truth} not in {e:?}");
+        assert!(e.high - e.low < 0.25, "{e:?}");
+
+        let full = estimate_fraction(&population, population.len()).unwrap();
+        assert_eq!((full.low, full.high), (full.fraction, full.fraction));
+        assert_eq!(estimate_fraction(&[(10, 10)], 50).map(|e| (e.low, e.high)), Some((0.0, 1.0)));
+        assert!(estimate_fraction(&[(0, 0)], 5).is_none());
+    }
+}
@@ -0,0 +1,428 @@
+//! Scrubbing of personal and internal details from corpus samples, so an
+//! internal corpus can be shared.
+//!
+//! Each detail is replaced with a placeholder of the same shape, so the
+//! humanity detectors that look for ticket references and `@mentions` fire
+//! on the scrubbed sample as they did on the original:
+//!
+//! | Found                                         | Replaced with                              |
+//! |-----------------------------------------------|--------------------------------------------|
+//! | `jane.doe@acme.io`                            | `dev1@example.com`                         |
+//! | `@jdoe`, `TODO(jdoe)` in a comment            | `@dev2`, `TODO(dev2)`                      |
+//! | `/home/jdoe/`, `/Users/jdoe/`                 | `/home/dev2/`, `/Users/dev2/`              |
+//! | `ACME-342` in a comment                       | `PROJ-1`                                   |
+//! | `db1.prod.corp`, or a host under `--domain`   | `host1.example.com`                        |
+//!
+//! A [`Scrubber`] numbers placeholders across every file it sees, so one
+//! person or ticket gets one placeholder throughout a corpus, and leaves
+//! placeholders alone, so scrubbing twice changes nothing.  Mentions,
+//! ticket IDs and `TODO(name)` are only looked for in comments (Python
+//! docstrings included), where decorators and scoped package names cannot
+//! be mistaken for them.
+
+use std::collections::BTreeMap;
+use std::ops::Range;
+
+use crate::analyzers::text::humanity::{DOC_TAGS, NOT_TICKET_KEYS};
+use crate::language::Language;
+
+/// Top-level domains that only resolve inside an organization.  `.local` is
+/// left out: `self.local` is more often a field than an mDNS host; pass it
+/// as a domain to scrub it.
+const INTERNAL_SUFFIXES: &[&str] = &[".internal", ".corp", ".lan", ".intranet", ".localdomain", ".home.arpa"];
+
+/// Domains reserved for documentation (RFC 2606), left as they are.
+const EXAMPLE_DOMAINS: &[&str] = &["example.com", "example.org", "example.net"];
+
+/// Directory prefixes followed by a username.
+const HOME_DIRS: &[&str] = &["/home/", "/Users/", "\\Users\\", "\\\\Users\\\\"];
+
+/// Comment markers that attribute work to a person: `TODO(jdoe)`.
+const OWNER_MARKERS: &[&str] = &["TODO(", "FIXME(", "XXX(", "HACK(", "NOTE("];
+
+/// Project key of ticket placeholders.
+const TICKET_KEY: &str = "PROJ";
+
+/// What a replacement stood for.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord)]
+pub enum Kind {
+    Email,
+    Username,
+    Ticket,
+    Hostname,
+}
+
+impl Kind {
+    /// `n` of this kind, e.g. `2 emails`.
+    pub fn count(self, n: usize) -> String {
+        let noun = match self {
+            Kind::Email => "email",
+            Kind::Username => "username",
+            Kind::Ticket => "ticket ID",
+            Kind::Hostname => "hostname",
+        };
+        format!("{n} {noun}{}", if n == 1 { "" } else { "s" })
+    }
+}
+
+/// One detail replaced in a file.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Replacement {
+    pub kind: Kind,
+    pub original: String,
+    pub placeholder: String,
+}
+
+/// Rewrites sources with placeholders, numbering them consistently across
+/// every file it is given.
+#[derive(Debug, Default)]
+pub struct Scrubber {
+    /// Extra internal domains, lower-case.
+    domains: Vec<String>,
+    users: BTreeMap<String, String>,
+    hosts: BTreeMap<String, String>,
+    tickets: BTreeMap<String, String>,
+}
+
+impl Scrubber {
+    /// A scrubber that also treats hosts under `domains` (`acme.net`) as
+    /// internal.
+    pub fn new(domains: &[String]) -> Self {
+        let domains = domains.iter().map(|d| d.trim_start_matches('.').to_ascii_lowercase()).filter(|d| !d.is_empty());
+        Self { domains: domains.collect(), ..Self::default() }
+    }
+
+    /// `source` with its details replaced, and the replacements in order.
+    /// `language` decides the comment syntax; `None` reads `//` and `/* */`.
+    pub fn scrub(&mut self, source: &str, language: Option<Language>) -> (String, Vec<Replacement>) {
+        let mut found = Vec::new();
+        let text = rewrite(source, |b, i| {
+            let (end, kind, placeholder) = self.email_at(b, i).or_else(|| self.host_at(b, i)).or_else(|| self.home_at(b, i))?;
+            found.push(Replacement { kind, original: source_slice(b, i, end), placeholder: placeholder.clone() });
+            Some((end, placeholder))
+        });
+
+        let comments = comment_spans(&text, language);
+        let in_comment = |i: usize| comments.iter().any(|r| r.contains(&i));
+        let text = rewrite(&text, |b, i| {
+            if !in_comment(i) {
+                return None;
+            }
+            let (end, kind, placeholder) =
+                self.mention_at(b, i).or_else(|| self.owner_at(b, i)).or_else(|| self.ticket_at(b, i))?;
+            found.push(Replacement { kind, original: source_slice(b, i, end), placeholder: placeholder.clone() });
+            Some((end, placeholder))
+        });
+        (text, found)
+    }
+
+    fn user(&mut self, name: &str) -> String {
+        let n = self.users.len() + 1;
+        self.users.entry(name.to_string()).or_insert_with(|| format!("dev{n}")).clone()
+    }
+
+    /// `jane.doe@acme.io` → `dev1@example.com`.
+    fn email_at(&mut self, b: &[u8], i: usize) -> Option<(usize, Kind, String)> {
+        if !is_local(b[i]) || b[i] == b'.' || (i > 0 && is_local(b[i - 1])) {
+            return None;
+        }
+        let at = i + b[i..].iter().take_while(|&&c| is_local(c)).count();
+        if b.get(at) != Some(&b'@') {
+            return None;
+        }
+        let end = hostname_end(b, at + 1)?;
+        let domain = source_slice(b, at + 1, end).to_ascii_lowercase();
+        let local = source_slice(b, i, at).to_ascii_lowercase();
+        if EXAMPLE_DOMAINS.contains(&domain.as_str()) {
+            return None;
+        }
+        Some((end, Kind::Email, format!("{}@example.com", self.user(&local))))
+    }
+
+    /// An internal hostname → `host1.example.com`.
+    fn host_at(&mut self, b: &[u8], i: usize) -> Option<(usize, Kind, String)> {
+        if !b[i].is_ascii_alphanumeric() || (i > 0 && (is_host(b[i - 1]) || b[i - 1] == b'@')) {
+            return None;
+        }
+        let end = hostname_end(b, i)?;
+        // `config.internal()` is a method call, not a host.
+        if b.get(end) == Some(&b'(') {
+            return None;
+        }
+        let host = source_slice(b, i, end).to_ascii_lowercase();
+        let dotted = format!(".{host}");
+        let internal = INTERNAL_SUFFIXES.iter().any(|s| dotted.ends_with(s))
+            || self.domains.iter().any(|d| dotted.ends_with(&format!(".{d}")));
+        if !internal {
+            return None;
+        }
+        let n = self.hosts.len() + 1;
+        let placeholder = self.hosts.entry(host).or_insert_with(|| format!("host{n}.example.com")).clone();
+        Some((end, Kind::Hostname, placeholder))
+    }
+
+    /// `/home/jdoe/` → `/home/dev1/`.
+    fn home_at(&mut self, b: &[u8], i: usize) -> Option<(usize, Kind, String)> {
+        if i > 0 && b[i - 1].is_ascii_alphanumeric() {
+            return None;
+        }
+        let prefix = HOME_DIRS.iter().find(|p| b[i..].starts_with(p.as_bytes()))?;
+        let start = i + prefix.len();
+        let end = start + b[start..].iter().take_while(|&&c| is_name(c)).count();
+        let name = source_slice(b, start, end).to_ascii_lowercase();
+        if name.is_empty() || is_placeholder_user(&name) {
+            return None;
+        }
+        Some((end, Kind::Username, format!("{prefix}{}", self.user(&name))))
+    }
+
+    /// `@jdoe` → `@dev1`; a capitalized `@Jdoe` becomes `@Dev1`, so the
+    /// mention detector, which only counts lower-case names, sees what it
+    /// saw before.
+    fn mention_at(&mut self, b: &[u8], i: usize) -> Option<(usize, Kind, String)> {
+        if b[i] != b'@' || (i > 0 && b[i - 1].is_ascii_alphanumeric()) {
+            return None;
+        }
+        let end = i + 1 + b[i + 1..].iter().take_while(|&&c| is_name(c) && c != b'.').count();
+        let name = source_slice(b, i + 1, end);
+        let first = name.chars().next().filter(char::is_ascii_alphabetic)?;
+        let lower = name.to_ascii_lowercase();
+        if DOC_TAGS.contains(&lower.as_str()) || is_placeholder_user(&lower) {
+            return None;
+        }
+        let mut placeholder = self.user(&lower);
+        if first.is_ascii_uppercase() {
+            placeholder[..1].make_ascii_uppercase();
+        }
+        Some((end, Kind::Username, format!("@{placeholder}")))
+    }
+
+    /// `TODO(jdoe)` → `TODO(dev1)`.
+    fn owner_at(&mut self, b: &[u8], i: usize) -> Option<(usize, Kind, String)> {
+        let marker = OWNER_MARKERS.iter().find(|m| b[i..].starts_with(m.as_bytes()))?;
+        let start = i + marker.len();
+        let end = start + b[start..].iter().take_while(|&&c| is_name(c)).count();
+        let name = source_slice(b, start, end).to_ascii_lowercase();
+        if b.get(end) != Some(&b')') || name.is_empty() || is_placeholder_user(&name) {
+            return None;
+        }
+        Some((end, Kind::Username, format!("{marker}{}", self.user(&name))))
+    }
+
+    /// `ACME-342` → `PROJ-1`, matched like the humanity detector does.
+    fn ticket_at(&mut self, b: &[u8], i: usize) -> Option<(usize, Kind, String)> {
+        if i > 0 && b[i - 1].is_ascii_alphanumeric() {
+            return None;
+        }
+        let key = b[i..].iter().take_while(|c| c.is_ascii_uppercase()).count();
+        let digits = b.get(i + key + 1..).map_or(0, |rest| rest.iter().take_while(|c| c.is_ascii_digit()).count());
+        let end = i + key + 1 + digits;
+        let key_name = source_slice(b, i, i + key);
+        if key < 2
+            || b.get(i + key) != Some(&b'-')
+            || digits == 0
+            || b.get(end).is_some_and(|c| c.is_ascii_alphanumeric())
+            || NOT_TICKET_KEYS.contains(&key_name.as_str())
+            || key_name == TICKET_KEY
+        {
+            return None;
+        }
+        let n = self.tickets.len() + 1;
+        let placeholder =
+            self.tickets.entry(source_slice(b, i, end)).or_insert_with(|| format!("{TICKET_KEY}-{n}")).clone();
+        Some((end, Kind::Ticket, placeholder))
+    }
+}
+
+/// `text` with each match of `at_match` replaced.  `at_match` is offered
+/// every byte offset and returns the end of a match starting there and its
+/// replacement; matches begin on ASCII bytes, so offsets are char
+/// boundaries.
+fn rewrite(text: &str, mut at_match: impl FnMut(&[u8], usize) -> Option<(usize, String)>) -> String {
+    let b = text.as_bytes();
+    let mut out = String::with_capacity(text.len());
+    let (mut i, mut copied) = (0, 0);
+    while i < b.len() {
+        match at_match(b, i) {
+            Some((end, replacement)) => {
+                out.push_str(&text[copied..i]);
+                out.push_str(&replacement);
+                (i, copied) = (end, end);
+            }
+            None => i += 1,
+        }
+    }
+    out.push_str(&text[copied..]);
+    out
+}
+
+fn source_slice(b: &[u8], start: usize, end: usize) -> String {
+    String::from_utf8_lossy(&b[start..end]).into_owned()
+}
+
+fn is_local(c: u8) -> bool {
+    c.is_ascii_alphanumeric() || matches!(c, b'.' | b'_' | b'%' | b'+' | b'-')
+}
+
+fn is_host(c: u8) -> bool {
+    c.is_ascii_alphanumeric() || matches!(c, b'.' | b'-')
+}
+
+fn is_name(c: u8) -> bool {
+    c.is_ascii_alphanumeric() || matches!(c, b'.' | b'_' | b'-')
+}
+
+/// `dev1`, `dev2`, … — names this module wrote.
+fn is_placeholder_user(name: &str) -> bool {
+    name.strip_prefix("dev").is_some_and(|n| !n.is_empty() && n.bytes().all(|c| c.is_ascii_digit()))
+}
+
+/// End of the hostname starting at `i`: two or more dot-separated labels,
+/// the last alphabetic.  A trailing `.` ends a sentence, not the host.
+fn hostname_end(b: &[u8], i: usize) -> Option<usize> {
+    let mut end = i + b.get(i..)?.iter().take_while(|&&c| is_host(c)).count();
+    while end > i && matches!(b[end - 1], b'.' | b'-') {
+        end -= 1;
+    }
+    let host = std::str::from_utf8(&b[i..end]).ok()?;
+    let labels: Vec<&str> = host.split('.').collect();
+    let tld = labels.last()?;
+    (labels.len() >= 2 && labels.iter().all(|l| !l.is_empty()) && tld.len() >= 2 && tld.bytes().all(|c| c.is_ascii_alphabetic()))
+        .then_some(end)
+}
+
+/// Byte ranges of the comments in `source`: line and block comments, and
+/// Python's triple-quoted strings, where docstrings name people too.
+/// Single quotes open strings except in Rust, where they open lifetimes.
+fn comment_spans(source: &str, language: Option<Language>) -> Vec<Range<usize>> {
+    let python = language == Some(Language::Python);
+    let b = source.as_bytes();
+    let find = |from: usize, pat: &[u8]| b.get(from..)?.windows(pat.len()).position(|w| w == pat).map(|p| p + from);
+    let line_end = |from: usize| find(from, b"\n").unwrap_or(b.len());
+
+    let mut spans = Vec::new();
+    let mut i = 0;
+    while i < b.len() {
+        let rest = &b[i..];
+        let end = if python && (rest.starts_with(b"\"\"\"") || rest.starts_with(b"'''")) {
+            find(i + 3, &rest[..3]).map_or(b.len(), |e| e + 3)
+        } else if (python && b[i] == b'#') || (!python && rest.starts_with(b"//")) {
+            line_end(i)
+        } else if !python && rest.starts_with(b"/*") {
+            find(i + 2, b"*/").map_or(b.len(), |e| e + 2)
+        } else if matches!(b[i], b'"' | b'`') || (b[i] == b'\'' && language != Some(Language::Rust)) {
+            i = string_end(b, i);
+            continue;
+        } else {
+            i += 1;
+            continue;
+        };
+        spans.push(i..end);
+        i = end;
+    }
+    spans
+}
+
+/// The offset past the string literal opening at `start`.  Only backtick
+/// strings span lines.
+fn string_end(b: &[u8], start: usize) -> usize {
+    let quote = b[start];
+    let mut i = start + 1;
+    while i < b.len() {
+        match b[i] {
+            b'\\' => i += 2,
+            b'\n' if quote != b'`' => return i,
+            c if c == quote => return i + 1,
+            _ => i += 1,
+        }
+    }
+    b.len()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::analyzers::text::humanity::HumanityAnalyzer;
+    use crate::analyzers::Analyzer;
+    use crate::heuristics::signal_ids;
+
+    const GO: &str = "\
+// LRU cache for the cfg service - see ACME-342, ask @agarwal.
+// Maintainer: Jane Doe <jane.doe@acme.io>
+package cache
+
+const upstream = \"https://cfg.prod.corp/v1\" // TODO(jdoe): move to ACME-351
+const keys = \"/home/jdoe/.ssh\"
+";
+
+    #[test]
+    fn replaces_details_with_same_shaped_placeholders() {
+        let (text, found) = Scrubber::new(&[]).scrub(GO, Some(Language::Go));
+        assert_eq!(
+            text,
+            "\
+// LRU cache for the cfg service - see PROJ-1, ask @dev3.
+// Maintainer: Jane Doe <dev1@example.com>
+package cache
+
+const upstream = \"https://host1.example.com/v1\" // TODO(dev2): move to PROJ-2
+const keys = \"/home/dev2/.ssh\"
+"
+        );
+        let kinds: Vec<Kind> = found.iter().map(|r| r.kind).collect();
+        assert_eq!(kinds.iter().filter(|&&k| k == Kind::Username).count(), 3);
+        assert_eq!(kinds.iter().filter(|&&k| k == Kind::Ticket).count(), 2);
+        assert!(found.contains(&Replacement {
+            kind: Kind::Hostname,
+            original: "cfg.prod.corp".into(),
+            placeholder: "host1.example.com".into(),
+        }));
+    }
+
+    #[test]
+    fn humanity_markers_still_fire() {
+        let (text, _) = Scrubber::new(&[]).scrub(GO, Some(Language::Go));
+        let ids = |source: &str| {
+            let mut ids: Vec<String> = HumanityAnalyzer.analyze_go(source).into_iter().map(|s| s.id).collect();
+            ids.sort();
+            ids
+        };
+        assert_eq!(ids(&text), ids(GO));
+        assert!(ids(&text).contains(&signal_ids::GO_HUMANITY_TICKET_REFS.to_string()));
+        assert!(ids(&text).contains(&signal_ids::GO_HUMANITY_MENTIONS.to_string()));
+    }
+
+    #[test]
+    fn placeholders_are_shared_across_files_and_stable() {
+        let mut scrubber = Scrubber::new(&["acme.net".to_string()]);
+        let (a, _) = scrubber.scrub("# ping @jdoe about db.acme.net\n", Some(Language::Python));
+        let (b, _) = scrubber.scrub("# @jdoe owns api.acme.net and db.acme.net\n", Some(Language::Python));
+        assert_eq!(a, "# ping @dev1 about host1.example.com\n");
+        assert_eq!(b, "# @dev1 owns host2.example.com and host1.example.com\n");
+        let (again, found) = scrubber.scrub(&b, Some(Language::Python));
+        assert_eq!(again, b);
+        assert!(found.is_empty(), "{found:?}");
+    }
+
+    #[test]
+    fn code_is_left_alone() {
+        let python = "@property\ndef local(self):\n    import x  # UTF-8 only, see RFC-3629\n    return self.internal(\"@babel/core\")\n";
+        let (text, found) = Scrubber::new(&[]).scrub(python, Some(Language::Python));
+        assert_eq!(text, python);
+        assert!(found.is_empty(), "{found:?}");
+
+        let rust = "#[derive(Debug)]\nstruct A<'a> { s: &'a str } // see @param docs, ops@example.com\n";
+        assert_eq!(Scrubber::new(&[]).scrub(rust, Some(Language::Rust)).0, rust);
+    }
+
+    #[test]
+    fn docstrings_and_block_comments_are_comments() {
+        let python = "def f():\n    \"\"\"Owned by @jdoe (ACME-7).\"\"\"\n    return \"@jdoe\"\n";
+        let (text, _) = Scrubber::new(&[]).scrub(python, Some(Language::Python));
+        assert_eq!(text, "def f():\n    \"\"\"Owned by @dev1 (PROJ-1).\"\"\"\n    return \"@jdoe\"\n");
+
+        let js = "/*\n * @param key the key\n * Reviewed by @kim\n */\nconst s = '// @kim';\n";
+        let (text, _) = Scrubber::new(&[]).scrub(js, Some(Language::JavaScript));
+        assert_eq!(text, "/*\n * @param key the key\n * Reviewed by @dev1\n */\nconst s = '// @kim';\n");
+    }
+}
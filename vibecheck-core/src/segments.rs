@@ -0,0 +1,282 @@
+//! Mixed-authorship segmentation.
+//!
+//! A file is often a human skeleton with AI-filled functions, or the other
+//! way round, and one file-level score averages the two into mush.  This
+//! module finds the change points: it splits the source into top-level
+//! units (blank-line separated blocks starting at column 0), profiles each
+//! unit's style, and recursively splits the unit sequence where the profile
+//! shifts the most — keeping a split only when it explains clearly more
+//! than it costs (a BIC-style penalty).  The pipeline then scores each
+//! resulting region on its own (see
+//! [`Pipeline::run_segments`](crate::pipeline::Pipeline::run_segments)).
+
+use std::ops::Range;
+
+/// Fewest non-blank lines a segment may have; shorter regions carry too
+/// little style to profile or score.
+pub const MIN_SEGMENT_LINES: usize = 8;
+
+/// Style features per unit; see [`profile`].
+pub(crate) const FEATURES: usize = 5;
+
+/// Multiplier on the per-split penalty `FEATURES * ln(units)`.  Higher
+/// values demand a sharper style shift before a file is split.
+const SPLIT_PENALTY: f64 = 1.5;
+
+/// Contiguous line ranges (0-based, end-exclusive) of `source` whose
+/// styles differ materially, in order and covering every line.  A file with
+/// a uniform style is a single range; an empty file has none.
+pub fn boundaries(source: &str) -> Vec<Range<usize>> {
+    let lines: Vec<&str> = source.lines().collect();
+    if lines.is_empty() {
+        return Vec::new();
+    }
+    let units = units(&lines);
+    // Non-blank lines before each unit, for the minimum segment length.
+    let mut lines_before = vec![0];
+    for u in &units {
+        lines_before.push(lines_before.last().unwrap() + nonblank(&lines[u.clone()]));
+    }
+    let profiles = normalized(units.iter().map(|u| profile(&lines[u.clone()])).collect());
+    let penalty = SPLIT_PENALTY * FEATURES as f64 * (units.len() as f64).ln().max(1.0);
+
+    let mut cuts = Vec::new();
+    split(&Costs::new(&profiles), &lines_before, 0..units.len(), penalty, &mut cuts);
+    cuts.sort_unstable();
+
+    let mut ranges = Vec::with_capacity(cuts.len() + 1);
+    let mut start = 0;
+    for cut in cuts {
+        ranges.push(start..units[cut].start);
+        start = units[cut].start;
+    }
+    ranges.push(start..lines.len());
+    ranges
+}
+
+/// Top-level blocks: a new unit starts at a non-blank, unindented line that
+/// follows a blank line (closing brackets excepted).
+pub(crate) fn units(lines: &[&str]) -> Vec<Range<usize>> {
+    let mut starts = vec![0];
+    for i in 1..lines.len() {
+        let line = lines[i];
+        let top_level = !line.trim().is_empty()
+            && !line.starts_with(char::is_whitespace)
+            && !line.starts_with(['}', ')', ']']);
+        if top_level && lines[i - 1].trim().is_empty() {
+            starts.push(i);
+        }
+    }
+    let mut units: Vec<Range<usize>> = starts.windows(2).map(|w| w[0]..w[1]).collect();
+    units.push(*starts.last().unwrap_or(&0)..lines.len());
+    units
+}
+
+pub(crate) fn nonblank(lines: &[&str]) -> usize {
+    lines.iter().filter(|l| !l.trim().is_empty()).count()
+}
+
+fn is_comment(trimmed: &str) -> bool {
+    trimmed.starts_with("//")
+        || trimmed.starts_with("/*")
+        || trimmed.starts_with('*')
+        || trimmed.starts_with("\"\"\"")
+        || (trimmed.starts_with('#') && !trimmed.starts_with("#[") && !trimmed.starts_with("#!"))
+}
+
+/// Style profile of one unit: comment share, mean identifier length, mean
+/// code line length, blank-line share, and share of code lines carrying a
+/// trailing comment.
+pub(crate) fn profile(lines: &[&str]) -> [f64; FEATURES] {
+    let (mut blank, mut comments, mut code, mut trailing) = (0usize, 0usize, 0usize, 0usize);
+    let (mut code_chars, mut ident_chars, mut idents) = (0usize, 0usize, 0usize);
+    for line in lines {
+        let trimmed = line.trim();
+        if trimmed.is_empty() {
+            blank += 1;
+        } else if is_comment(trimmed) {
+            comments += 1;
+        } else {
+            code += 1;
+            code_chars += line.trim_end().chars().count();
+            if trimmed.contains(" //") || trimmed.contains(" # ") {
+                trailing += 1;
+            }
+            for word in trimmed.split(|c: char| !c.is_alphanumeric() && c != '_') {
+                if word.len() > 1 && word.starts_with(|c: char| c.is_alphabetic() || c == '_') {
+                    ident_chars += word.len();
+                    idents += 1;
+                }
+            }
+        }
+    }
+    let share = |n: usize, of: usize| if of == 0 { 0.0 } else { n as f64 / of as f64 };
+    [
+        share(comments, comments + code),
+        share(ident_chars, idents),
+        share(code_chars, code),
+        share(blank, lines.len()),
+        share(trailing, code),
+    ]
+}
+
+/// Z-score each feature across units so no feature dominates by scale.
+/// Features that never vary are zeroed.
+fn normalized(mut profiles: Vec<[f64; FEATURES]>) -> Vec<[f64; FEATURES]> {
+    let n = profiles.len() as f64;
+    for f in 0..FEATURES {
+        let mean = profiles.iter().map(|p| p[f]).sum::<f64>() / n;
+        let sd = (profiles.iter().map(|p| (p[f] - mean).powi(2)).sum::<f64>() / n).sqrt();
+        for p in &mut profiles {
+            p[f] = if sd > 1e-9 { (p[f] - mean) / sd } else { 0.0 };
+        }
+    }
+    profiles
+}
+
+/// Prefix sums of the profiles and their squares, so the cost of any run
+/// of units is O(features).
+struct Costs {
+    sum: Vec<[f64; FEATURES]>,
+    sq: Vec<[f64; FEATURES]>,
+}
+
+impl Costs {
+    fn new(profiles: &[[f64; FEATURES]]) -> Self {
+        let mut sum = vec![[0.0; FEATURES]];
+        let mut sq = vec![[0.0; FEATURES]];
+        for p in profiles {
+            let (mut s, mut q) = (*sum.last().unwrap(), *sq.last().unwrap());
+            for f in 0..FEATURES {
+                s[f] += p[f];
+                q[f] += p[f] * p[f];
+            }
+            sum.push(s);
+            sq.push(q);
+        }
+        Self { sum, sq }
+    }
+
+    /// Sum of squared deviations from the mean profile of units `a..b`.
+    fn cost(&self, a: usize, b: usize) -> f64 {
+        let n = (b - a) as f64;
+        (0..FEATURES)
+            .map(|f| {
+                let s = self.sum[b][f] - self.sum[a][f];
+                self.sq[b][f] - self.sq[a][f] - s * s / n
+            })
+            .sum()
+    }
+}
+
+/// Recursive segmentation of units `range`: try every single cut and every
+/// pair of cuts (which isolates a middle run, e.g. AI functions inside
+/// human code, that no single cut separates cleanly), take the one whose
+/// cost reduction beats `penalty` per cut by the most, with every piece at
+/// least [`MIN_SEGMENT_LINES`], and recurse into the pieces.
+fn split(costs: &Costs, lines_before: &[usize], range: Range<usize>, penalty: f64, cuts: &mut Vec<usize>) {
+    let Range { start, end } = range;
+    let long_enough = |a: usize, b: usize| lines_before[b] - lines_before[a] >= MIN_SEGMENT_LINES;
+    let whole = costs.cost(start, end);
+    let mut best: Option<(Vec<usize>, f64)> = None;
+    let mut consider = |pieces: Vec<usize>, gain: f64| {
+        let score = gain - penalty * (pieces.len() - 1) as f64;
+        if score > 0.0 && best.as_ref().is_none_or(|(_, s)| score > *s) {
+            best = Some((pieces, score));
+        }
+    };
+    for a in start + 1..end {
+        if !long_enough(start, a) {
+            continue;
+        }
+        if long_enough(a, end) {
+            consider(vec![start, a], whole - costs.cost(start, a) - costs.cost(a, end));
+        }
+        for b in a + 1..end {
+            if long_enough(a, b) && long_enough(b, end) {
+                let gain = whole - costs.cost(start, a) - costs.cost(a, b) - costs.cost(b, end);
+                consider(vec![start, a, b], gain);
+            }
+        }
+    }
+    if let Some((pieces, _)) = best {
+        let mut bounds = pieces.clone();
+        bounds.push(end);
+        cuts.extend(&pieces[1..]);
+        for w in bounds.windows(2) {
+            split(costs, lines_before, w[0]..w[1], penalty, cuts);
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    /// Terse, uncommented helpers with short names.
+    fn human(n: usize) -> String {
+        (0..n)
+            .map(|i| format!("fn f{i}(a: u8, b: u8) -> u8 {{\n    let x = a ^ b;\n    x.rotate_left({i})\n}}\n"))
+            .collect::<Vec<_>>()
+            .join("\n")
+    }
+
+    /// Documented, narrated functions with long descriptive names.
+    fn ai(n: usize) -> String {
+        (0..n)
+            .map(|i| {
+                format!(
+                    "/// Computes the normalized accumulated value for the provided input.\n\
+                     ///\n\
+                     /// This ensures that the result is always within the expected bounds.\n\
+                     fn compute_normalized_accumulated_value_{i}(input_value: u64) -> u64 {{\n    \
+                     // Step 1: Initialize the accumulator with the input value\n    \
+                     let accumulated_value = input_value.saturating_mul(2); // Double the input\n    \
+                     // Step 2: Return the normalized result to the caller\n    \
+                     accumulated_value / 3\n\
+                     }}\n"
+                )
+            })
+            .collect::<Vec<_>>()
+            .join("\n")
+    }
+
+    #[test]
+    fn uniform_file_is_one_segment() {
+        let src = human(8);
+        assert_eq!(boundaries(&src), vec![0..src.lines().count()]);
+        assert!(boundaries("").is_empty());
+    }
+
+    #[test]
+    fn splits_human_skeleton_from_ai_functions() {
+        let head = human(6);
+        let src = format!("{head}\n{}", ai(4));
+        let ranges = boundaries(&src);
+        assert_eq!(ranges.len(), 2, "{ranges:?}");
+        let cut = head.lines().count() + 1;
+        assert_eq!(ranges[0], 0..cut);
+        assert_eq!(ranges[1], cut..src.lines().count());
+    }
+
+    #[test]
+    fn finds_an_ai_island_in_human_code() {
+        let src = format!("{}\n{}\n{}", human(5), ai(3), human(5));
+        let ranges = boundaries(&src);
+        assert_eq!(ranges.len(), 3, "{ranges:?}");
+        assert_eq!(ranges.last().unwrap().end, src.lines().count());
+    }
+
+    #[test]
+    fn short_regions_are_not_split_off() {
+        let narrated = "// Step 1: Return the normalized accumulated value\nfn normalized_accumulated_value() -> u64 { 42 }\n";
+        let src = format!("{}\n{narrated}", human(8));
+        assert_eq!(boundaries(&src).len(), 1);
+    }
+
+    #[test]
+    fn units_start_at_top_level_lines_after_blanks() {
+        let lines: Vec<&str> = "a\nb\n\n    c\n\nd\n}\n\n}\ne".lines().collect();
+        assert_eq!(units(&lines), vec![0..5, 5..10]);
+    }
+}
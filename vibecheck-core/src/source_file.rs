@@ -0,0 +1,161 @@
+//! One file read, parsed and tokenized once for every detector.
+//!
+//! A [`SourceFile`] bundles the source text with its tree-sitter tree, its
+//! comment map (the [`SourceTokens`] streams) and line and token counts.
+//! The pipeline builds one per file and hands it to the text analyzers, CST
+//! analyzers, language packs and plugins alike, so none of them parses the
+//! file again.
+//!
+//! Artifacts are cached process-wide by a hash of their content, language
+//! and frontend ([`SourceFile::load`]).  Text detectors that only receive
+//! the source string ([`SourceFile::for_language`]) and the embedding
+//! backend's comment stripping get the pipeline's artifact back, and so do
+//! repeated runs over the same bytes: symbol and segment reports, the
+//! comment-free rerun, ensemble members and `eval`/`tune` folds.
+
+use std::collections::HashSet;
+use std::sync::{Arc, Mutex, OnceLock};
+
+use sha2::{Digest, Sha256};
+use tree_sitter::Tree;
+
+use crate::frontend::{LanguageFrontend, SourceTokens, BUILTIN_FRONTENDS};
+use crate::language::Language;
+use crate::lru::LruCache;
+
+/// Parsed files kept in memory.  Trees cost a few times their source, so
+/// this bounds the cache to a few megabytes on typical files while still
+/// covering a file's symbols and segments being rescored.
+pub const ARTIFACT_CAPACITY: usize = 64;
+
+/// Line and token counts of a [`SourceFile`].
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub struct TokenStats {
+    pub lines: usize,
+    pub blank_lines: usize,
+    /// Lines covered by at least one comment.
+    pub comment_lines: usize,
+    pub identifiers: usize,
+    /// Outermost functions (see [`SourceTokens::functions`]).
+    pub functions: usize,
+}
+
+/// A file's source with everything detectors derive from its syntax.
+pub struct SourceFile {
+    pub source: String,
+    /// The built-in language, or `None` for language packs and unknown
+    /// files.
+    pub language: Option<Language>,
+    /// `None` when the file has no grammar or did not parse.
+    pub tree: Option<Tree>,
+    /// The comment, identifier and function streams; empty without a tree.
+    pub tokens: SourceTokens,
+    pub stats: TokenStats,
+}
+
+impl SourceFile {
+    /// `source` parsed and tokenized with `frontend`, or the artifact built
+    /// the last time these bytes were.  Without a frontend the file is
+    /// source and line counts only.
+    pub fn load(source: &str, language: Option<Language>, frontend: Option<&dyn LanguageFrontend>) -> Arc<Self> {
+        let key = artifact_key(source, language, frontend.map(|f| f.name()));
+        if let Some(file) = artifacts().lock().unwrap().get(&key) {
+            return Arc::clone(file);
+        }
+        // Parse outside the lock; two threads racing on one file both parse
+        // it and the second insert wins.
+        let file = Arc::new(Self::parse(source, language, frontend));
+        artifacts().lock().unwrap().put(key, Arc::clone(&file));
+        file
+    }
+
+    /// [`Self::load`] with the first built-in frontend for `language`, for
+    /// detectors that are handed the source without its path.
+    pub fn for_language(source: &str, language: Language) -> Arc<Self> {
+        let frontend = BUILTIN_FRONTENDS.iter().find(|f| f.language == language);
+        Self::load(source, Some(language), frontend.map(|f| f as &dyn LanguageFrontend))
+    }
+
+    fn parse(source: &str, language: Option<Language>, frontend: Option<&dyn LanguageFrontend>) -> Self {
+        let tree = frontend.and_then(|f| {
+            let mut parser = tree_sitter::Parser::new();
+            parser.set_language(&f.grammar()).ok()?;
+            parser.parse(source, None)
+        });
+        let tokens = match (frontend, &tree) {
+            (Some(f), Some(tree)) => f.tokens(tree, source),
+            _ => SourceTokens::default(),
+        };
+        let stats = stats(source, &tokens);
+        Self { source: source.to_string(), language, tree, tokens, stats }
+    }
+}
+
+fn stats(source: &str, tokens: &SourceTokens) -> TokenStats {
+    let commented: HashSet<usize> = tokens.comments.iter().flat_map(|s| s.start_line..=s.end_line).collect();
+    TokenStats {
+        lines: source.lines().count(),
+        blank_lines: source.lines().filter(|l| l.trim().is_empty()).count(),
+        comment_lines: commented.len(),
+        identifiers: tokens.identifiers.len(),
+        functions: tokens.functions.len(),
+    }
+}
+
+type ArtifactKey = [u8; 32];
+
+fn artifact_key(source: &str, language: Option<Language>, frontend: Option<&str>) -> ArtifactKey {
+    let mut hasher = Sha256::new();
+    hasher.update(format!("{language:?}").as_bytes());
+    hasher.update([0]);
+    hasher.update(frontend.unwrap_or("").as_bytes());
+    hasher.update([0]);
+    hasher.update(source.as_bytes());
+    hasher.finalize().into()
+}
+
+fn artifacts() -> &'static Mutex<LruCache<ArtifactKey, Arc<SourceFile>>> {
+    static ARTIFACTS: OnceLock<Mutex<LruCache<ArtifactKey, Arc<SourceFile>>>> = OnceLock::new();
+    ARTIFACTS.get_or_init(|| Mutex::new(LruCache::new(ARTIFACT_CAPACITY)))
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::Path;
+
+    use super::*;
+    use crate::frontend::frontend_for;
+
+    #[test]
+    fn same_bytes_share_one_artifact() {
+        let source = "// one\nfn same_bytes_share_one_artifact() {}\n";
+        let a = SourceFile::for_language(source, Language::Rust);
+        let b = SourceFile::load(source, Some(Language::Rust), frontend_for(Path::new("a.rs"), &[]));
+        assert!(Arc::ptr_eq(&a, &b));
+
+        // Another grammar is another artifact.
+        let c = SourceFile::load(source, Some(Language::JavaScript), frontend_for(Path::new("a.js"), &[]));
+        assert!(!Arc::ptr_eq(&a, &c));
+    }
+
+    #[test]
+    fn artifact_matches_a_fresh_parse() {
+        let source = "# note\n\ndef greet(name):\n    # say it\n    return name\n";
+        let frontend = frontend_for(Path::new("a.py"), &[]).unwrap();
+        let file = SourceFile::load(source, Some(Language::Python), Some(frontend));
+        assert!(file.tree.is_some());
+        assert_eq!(file.tokens, frontend.tokenize(source).unwrap());
+        assert_eq!(
+            file.stats,
+            TokenStats { lines: 5, blank_lines: 1, comment_lines: 2, identifiers: file.tokens.identifiers.len(), functions: 1 }
+        );
+    }
+
+    #[test]
+    fn no_frontend_is_source_only() {
+        let file = SourceFile::load("# Title\n\nSome prose.\n", None, None);
+        assert!(file.tree.is_none());
+        assert_eq!(file.tokens, SourceTokens::default());
+        assert_eq!(file.stats.lines, 3);
+    }
+}
@@ -0,0 +1,352 @@
+//! Read-only file sources for directory scans.
+//!
+//! [`SourceFs`] abstracts the two operations a scan needs — list a directory
+//! and read a file — so the same walk can run over the real filesystem
+//! ([`OsFs`]), an in-memory file set from a code-review service ([`MemFs`]),
+//! or any other store (an extracted archive, files embedded with
+//! `include_bytes!`).
+
+use std::collections::BTreeMap;
+use std::io;
+use std::path::{Component, Path, PathBuf};
+
+use crate::ignore_rules::IgnoreRules;
+use crate::{generated, language, language_pack};
+
+/// One child of a directory listed by [`SourceFs::read_dir`].
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct DirEntry {
+    /// Full path of the entry (the listed directory joined with its name).
+    pub path: PathBuf,
+    pub is_dir: bool,
+}
+
+/// A read-only tree of files.
+pub trait SourceFs: Send + Sync {
+    /// Read the whole file at `path`.
+    fn read(&self, path: &Path) -> io::Result<Vec<u8>>;
+
+    /// List the files and directories directly inside `dir`, sorted by path.
+    fn read_dir(&self, dir: &Path) -> io::Result<Vec<DirEntry>>;
+
+    /// Size in bytes of the file at `path`, used to skip oversized files
+    /// without reading them.  Defaults to reading the file; override when
+    /// the store can answer more cheaply.
+    fn size(&self, path: &Path) -> io::Result<u64> {
+        self.read(path).map(|bytes| bytes.len() as u64)
+    }
+}
+
+// ---------------------------------------------------------------------------
+// OsFs — the real filesystem
+// ---------------------------------------------------------------------------
+
+/// The operating system's filesystem.  Paths are used as given.
+pub struct OsFs;
+
+impl SourceFs for OsFs {
+    fn read(&self, path: &Path) -> io::Result<Vec<u8>> {
+        std::fs::read(path)
+    }
+
+    fn read_dir(&self, dir: &Path) -> io::Result<Vec<DirEntry>> {
+        let mut entries: Vec<DirEntry> = std::fs::read_dir(dir)?
+            .filter_map(|e| e.ok())
+            .map(|e| e.path())
+            // Sockets, FIFOs and dangling symlinks are neither; skip them.
+            .filter(|p| p.is_dir() || p.is_file())
+            .map(|path| DirEntry { is_dir: path.is_dir(), path })
+            .collect();
+        entries.sort_by(|a, b| a.path.cmp(&b.path));
+        Ok(entries)
+    }
+
+    fn size(&self, path: &Path) -> io::Result<u64> {
+        std::fs::metadata(path).map(|m| m.len())
+    }
+}
+
+// ---------------------------------------------------------------------------
+// MemFs — an in-memory file set
+// ---------------------------------------------------------------------------
+
+/// Files held in memory, keyed by relative path.  Directories are implied by
+/// the file paths; the root is `""` (or `"."`).
+///
+/// ```
+/// use vibecheck_core::source_fs::{MemFs, SourceFs};
+/// use std::path::Path;
+///
+/// let fs = MemFs::new().with_file("src/main.rs", "fn main() {}");
+/// assert_eq!(fs.read(Path::new("src/main.rs")).unwrap(), b"fn main() {}");
+/// assert!(fs.read_dir(Path::new("")).unwrap()[0].is_dir);
+/// ```
+#[derive(Debug, Clone, Default)]
+pub struct MemFs {
+    files: BTreeMap<PathBuf, Vec<u8>>,
+}
+
+impl MemFs {
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    /// Add (or replace) the file at `path`.
+    pub fn insert(&mut self, path: impl AsRef<Path>, content: impl Into<Vec<u8>>) {
+        self.files.insert(normalize(path.as_ref()), content.into());
+    }
+
+    /// Builder form of [`insert`](Self::insert).
+    pub fn with_file(mut self, path: impl AsRef<Path>, content: impl Into<Vec<u8>>) -> Self {
+        self.insert(path, content);
+        self
+    }
+}
+
+impl<P: AsRef<Path>, C: Into<Vec<u8>>> FromIterator<(P, C)> for MemFs {
+    fn from_iter<I: IntoIterator<Item = (P, C)>>(iter: I) -> Self {
+        let mut fs = Self::new();
+        for (path, content) in iter {
+            fs.insert(path, content);
+        }
+        fs
+    }
+}
+
+impl SourceFs for MemFs {
+    fn read(&self, path: &Path) -> io::Result<Vec<u8>> {
+        self.files
+            .get(&normalize(path))
+            .cloned()
+            .ok_or_else(|| io::Error::new(io::ErrorKind::NotFound, format!("{}: no such file", path.display())))
+    }
+
+    fn read_dir(&self, dir: &Path) -> io::Result<Vec<DirEntry>> {
+        let dir = normalize(dir);
+        let mut children: BTreeMap<PathBuf, bool> = BTreeMap::new();
+        for file in self.files.keys() {
+            let Ok(rest) = file.strip_prefix(&dir) else { continue };
+            let mut components = rest.components();
+            let Some(first) = components.next() else { continue };
+            let is_dir = components.next().is_some();
+            *children.entry(dir.join(first)).or_default() |= is_dir;
+        }
+        if children.is_empty() && !dir.as_os_str().is_empty() {
+            return Err(io::Error::new(io::ErrorKind::NotFound, format!("{}: no such directory", dir.display())));
+        }
+        Ok(children.into_iter().map(|(path, is_dir)| DirEntry { path, is_dir }).collect())
+    }
+
+    fn size(&self, path: &Path) -> io::Result<u64> {
+        self.files
+            .get(&normalize(path))
+            .map(|bytes| bytes.len() as u64)
+            .ok_or_else(|| io::Error::new(io::ErrorKind::NotFound, format!("{}: no such file", path.display())))
+    }
+}
+
+/// Drop `.` components so `./src/a.rs`, `src/a.rs` and `src/./a.rs` name the
+/// same file.
+fn normalize(path: &Path) -> PathBuf {
+    path.components().filter(|c| !matches!(c, Component::CurDir)).collect()
+}
+
+// ---------------------------------------------------------------------------
+// Walking
+// ---------------------------------------------------------------------------
+
+/// Whether `path` has the extension of a built-in language, an installed
+/// language pack, or a prose document.
+pub fn is_supported(path: &Path) -> bool {
+    let ext = path.extension().and_then(|e| e.to_str()).unwrap_or("");
+    language::SUPPORTED_EXTENSIONS.contains(&ext) || language_pack::is_pack_extension(ext) || language::is_doc_file(path)
+}
+
+/// Every supported source file under `root` in `fs`, in sorted order.
+///
+/// Paths and directories rejected by `ignore` are skipped, as are files with
+/// the generated-code header unless [`IgnoreRules::include_generated`] says
+/// otherwise.
+pub fn source_files(fs: &dyn SourceFs, root: &Path, ignore: &dyn IgnoreRules) -> io::Result<Vec<PathBuf>> {
+    Walk::new(fs, root, ignore)?
+        .map(|item| item.map_err(|(dir, e)| io::Error::new(e.kind(), format!("cannot list {}: {e}", dir.display()))))
+        .collect()
+}
+
+/// Lazy form of [`source_files`]: directories are listed only as the walk
+/// reaches them, so the first file is available before a large tree has
+/// been traversed.  A directory that cannot be listed yields its path and
+/// error, and the walk carries on with its siblings.
+pub(crate) struct Walk<'a> {
+    fs: &'a dyn SourceFs,
+    ignore: &'a dyn IgnoreRules,
+    /// Entries still to visit, in reverse order so `pop` yields the next.
+    pending: Vec<DirEntry>,
+}
+
+impl<'a> Walk<'a> {
+    /// Start a walk at `root`, which is listed eagerly so a missing root is
+    /// reported up front.
+    pub(crate) fn new(fs: &'a dyn SourceFs, root: &Path, ignore: &'a dyn IgnoreRules) -> io::Result<Self> {
+        let mut pending = fs.read_dir(root)?;
+        pending.reverse();
+        Ok(Self { fs, ignore, pending })
+    }
+
+    fn is_source_file(&self, path: &Path) -> bool {
+        if self.ignore.is_ignored(path) {
+            return false;
+        }
+        if !is_supported(path) {
+            return false;
+        }
+        if self.ignore.include_generated() {
+            return true;
+        }
+        let head = self.fs.read(path).unwrap_or_default();
+        !generated::is_generated(&String::from_utf8_lossy(&head))
+    }
+}
+
+impl Iterator for Walk<'_> {
+    type Item = Result<PathBuf, (PathBuf, io::Error)>;
+
+    fn next(&mut self) -> Option<Self::Item> {
+        while let Some(entry) = self.pending.pop() {
+            if !entry.is_dir {
+                if self.is_source_file(&entry.path) {
+                    return Some(Ok(entry.path));
+                }
+                continue;
+            }
+            if self.ignore.is_ignored_dir(&entry.path) {
+                continue;
+            }
+            match self.fs.read_dir(&entry.path) {
+                Ok(children) => self.pending.extend(children.into_iter().rev()),
+                Err(e) => return Some(Err((entry.path, e))),
+            }
+        }
+        None
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::ignore_rules::{AllowAll, PatternIgnore};
+
+    /// Defaults apart from ignore patterns: generated files are skipped.
+    struct Patterns(PatternIgnore);
+
+    impl IgnoreRules for Patterns {
+        fn is_ignored(&self, path: &Path) -> bool {
+            self.0.is_ignored(path)
+        }
+    }
+
+    fn sample() -> MemFs {
+        MemFs::new()
+            .with_file("src/main.rs", "fn main() {}")
+            .with_file("src/util/mod.py", "def f():\n    pass\n")
+            .with_file("./README.md", "# hello")
+            .with_file("gen/api.go", "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage api\n")
+            .with_file("vendor/lib.js", "export const x = 1;")
+    }
+
+    #[test]
+    fn mem_read_dir_lists_children_once() {
+        let entries = sample().read_dir(Path::new(".")).unwrap();
+        let listed: Vec<(&str, bool)> = entries.iter().map(|e| (e.path.to_str().unwrap(), e.is_dir)).collect();
+        assert_eq!(
+            listed,
+            vec![("README.md", false), ("gen", true), ("src", true), ("vendor", true)]
+        );
+        let src = sample().read_dir(Path::new("src")).unwrap();
+        assert_eq!(src[0].path, PathBuf::from("src/main.rs"));
+        assert!(src[1].is_dir);
+    }
+
+    #[test]
+    fn mem_missing_paths_are_not_found() {
+        let fs = sample();
+        assert_eq!(fs.read(Path::new("src/nope.rs")).unwrap_err().kind(), io::ErrorKind::NotFound);
+        assert_eq!(fs.read_dir(Path::new("nope")).unwrap_err().kind(), io::ErrorKind::NotFound);
+        assert!(MemFs::new().read_dir(Path::new("")).unwrap().is_empty());
+    }
+
+    #[test]
+    fn mem_normalizes_current_dir_components() {
+        let fs = sample();
+        assert_eq!(fs.read(Path::new("./src/main.rs")).unwrap(), b"fn main() {}");
+        assert_eq!(fs.read(Path::new("README.md")).unwrap(), b"# hello");
+    }
+
+    #[test]
+    fn size_matches_content_length() {
+        let fs = sample();
+        assert_eq!(fs.size(Path::new("src/main.rs")).unwrap(), 12);
+        assert!(fs.size(Path::new("nope.rs")).is_err());
+
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::write(dir.path().join("a.rs"), "fn main() {}").unwrap();
+        assert_eq!(OsFs.size(&dir.path().join("a.rs")).unwrap(), 12);
+    }
+
+    #[test]
+    fn source_files_skips_unsupported_ignored_and_generated() {
+        let ignore = Patterns(PatternIgnore(vec!["vendor".into()]));
+        let files = source_files(&sample(), Path::new(""), &ignore).unwrap();
+        assert_eq!(files, vec![PathBuf::from("src/main.rs"), PathBuf::from("src/util/mod.py")]);
+    }
+
+    #[test]
+    fn source_files_keeps_generated_when_included() {
+        let files = source_files(&sample(), Path::new(""), &AllowAll).unwrap();
+        assert!(files.contains(&PathBuf::from("gen/api.go")));
+        assert!(files.contains(&PathBuf::from("vendor/lib.js")));
+    }
+
+    /// Fails to list one directory.
+    struct Unlistable(MemFs, &'static str);
+
+    impl SourceFs for Unlistable {
+        fn read(&self, path: &Path) -> io::Result<Vec<u8>> {
+            self.0.read(path)
+        }
+
+        fn read_dir(&self, dir: &Path) -> io::Result<Vec<DirEntry>> {
+            if dir == Path::new(self.1) {
+                return Err(io::Error::new(io::ErrorKind::PermissionDenied, "denied"));
+            }
+            self.0.read_dir(dir)
+        }
+    }
+
+    #[test]
+    fn walk_reports_unlistable_directory_and_continues() {
+        let fs = Unlistable(sample(), "src/util");
+        let items: Vec<_> = Walk::new(&fs, Path::new(""), &AllowAll).unwrap().collect();
+        assert_eq!(items.len(), 4);
+        assert!(matches!(&items[2], Err((dir, _)) if dir == Path::new("src/util")));
+        assert!(matches!(&items[3], Ok(p) if p == Path::new("vendor/lib.js")));
+
+        let err = source_files(&fs, Path::new(""), &AllowAll).unwrap_err();
+        assert_eq!(err.kind(), io::ErrorKind::PermissionDenied);
+        assert!(err.to_string().contains("src/util"));
+    }
+
+    #[test]
+    fn os_and_mem_agree() {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::create_dir_all(dir.path().join("src/util")).unwrap();
+        std::fs::write(dir.path().join("src/main.rs"), "fn main() {}").unwrap();
+        std::fs::write(dir.path().join("src/util/mod.py"), "def f():\n    pass\n").unwrap();
+        std::fs::write(dir.path().join("README.md"), "# hello").unwrap();
+
+        let on_disk = source_files(&OsFs, dir.path(), &AllowAll).unwrap();
+        let relative: Vec<_> = on_disk.iter().map(|p| p.strip_prefix(dir.path()).unwrap().to_path_buf()).collect();
+        let in_memory = source_files(&sample(), Path::new(""), &Patterns(PatternIgnore(vec!["vendor".into()]))).unwrap();
+        assert_eq!(relative, in_memory);
+    }
+}
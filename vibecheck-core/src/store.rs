@@ -2,9 +2,15 @@
 
 use std::path::Path;
 
-use rusqlite::{Connection, Result, params};
+use rusqlite::{Connection, OptionalExtension, Result, params};
 
-/// A persistent corpus and trend store backed by SQLite.
+use crate::report::Report;
+
+/// A persistent corpus, trend and scan-results store backed by SQLite.
+///
+/// Scan results let an org-wide deployment answer questions about past
+/// scans ("every file over 0.9 first seen this month") with a query
+/// instead of a re-scan; see [`Store::record_scan`].
 pub struct Store {
     conn: Connection,
 }
@@ -17,7 +23,7 @@ impl Store {
             let _ = std::fs::create_dir_all(parent);
         }
         let conn = Connection::open(path)?;
-        conn.execute_batch("PRAGMA journal_mode=WAL;")?;
+        conn.execute_batch("PRAGMA journal_mode=WAL; PRAGMA foreign_keys=ON;")?;
         conn.execute_batch(
             "CREATE TABLE IF NOT EXISTS corpus_entries (
                 id           INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -25,6 +31,7 @@ impl Store {
                 path         TEXT,
                 attribution  TEXT    NOT NULL,
                 confidence   REAL    NOT NULL,
+                era          TEXT,
                 created_at   TEXT    NOT NULL DEFAULT (datetime('now'))
             );
             CREATE UNIQUE INDEX IF NOT EXISTS corpus_entries_hash
@@ -36,23 +43,70 @@ impl Store {
                 attribution  TEXT    NOT NULL,
                 confidence   REAL    NOT NULL,
                 recorded_at  TEXT    NOT NULL DEFAULT (datetime('now'))
-            );",
+            );
+
+            CREATE TABLE IF NOT EXISTS scans (
+                id           INTEGER PRIMARY KEY AUTOINCREMENT,
+                root         TEXT,
+                git_commit   TEXT,
+                scanned_at   TEXT    NOT NULL DEFAULT (datetime('now'))
+            );
+
+            CREATE TABLE IF NOT EXISTS scan_files (
+                id             INTEGER PRIMARY KEY AUTOINCREMENT,
+                scan_id        INTEGER NOT NULL REFERENCES scans(id) ON DELETE CASCADE,
+                path           TEXT    NOT NULL,
+                attribution    TEXT    NOT NULL,
+                confidence     REAL    NOT NULL,
+                ai_probability REAL,
+                lines_of_code  INTEGER NOT NULL
+            );
+            CREATE INDEX IF NOT EXISTS scan_files_path ON scan_files(path, scan_id);
+
+            CREATE TABLE IF NOT EXISTS findings (
+                id           INTEGER PRIMARY KEY AUTOINCREMENT,
+                file_id      INTEGER NOT NULL REFERENCES scan_files(id) ON DELETE CASCADE,
+                signal_id    TEXT    NOT NULL,
+                family       TEXT    NOT NULL,
+                weight       REAL    NOT NULL,
+                description  TEXT    NOT NULL,
+                lines        TEXT    NOT NULL
+            );
+            CREATE INDEX IF NOT EXISTS findings_file ON findings(file_id);
+
+            CREATE TABLE IF NOT EXISTS suppressions (
+                id           INTEGER PRIMARY KEY AUTOINCREMENT,
+                path         TEXT    NOT NULL,
+                signal_id    TEXT,
+                reason       TEXT    NOT NULL,
+                created_at   TEXT    NOT NULL DEFAULT (datetime('now'))
+            );
+            CREATE UNIQUE INDEX IF NOT EXISTS suppressions_target
+                ON suppressions(path, IFNULL(signal_id, ''));",
         )?;
+        // Stores created before eras were tracked lack the column.
+        if !has_column(&conn, "corpus_entries", "era")? {
+            conn.execute("ALTER TABLE corpus_entries ADD COLUMN era TEXT", [])?;
+        }
         Ok(Self { conn })
     }
 
     /// Insert a corpus entry. Silently ignores duplicates (same file_hash).
+    ///
+    /// `era` is the model-era label (e.g. `"gpt-2025-terse"`) when known, so
+    /// the corpus can be sliced by era for per-era evaluation.
     pub fn insert_corpus(
         &self,
         file_hash: &str,
         path: Option<&str>,
         attribution: &str,
         confidence: f64,
+        era: Option<&str>,
     ) -> Result<()> {
         self.conn.execute(
-            "INSERT OR IGNORE INTO corpus_entries (file_hash, path, attribution, confidence)
-             VALUES (?1, ?2, ?3, ?4)",
-            params![file_hash, path, attribution, confidence],
+            "INSERT OR IGNORE INTO corpus_entries (file_hash, path, attribution, confidence, era)
+             VALUES (?1, ?2, ?3, ?4, ?5)",
+            params![file_hash, path, attribution, confidence, era],
         )?;
         Ok(())
     }
@@ -71,4 +125,189 @@ impl Store {
         )?;
         Ok(())
     }
+
+    /// Record one scan: a row per analyzed file (skipped files are left
+    /// out) and a row per finding, in one transaction.  `root` and
+    /// `git_commit` say what was scanned; paths are stored as the reports
+    /// give them, so scan from the same place each time for them to line
+    /// up.  Returns the scan's id.
+    pub fn record_scan(&self, root: Option<&str>, git_commit: Option<&str>, reports: &[Report]) -> Result<i64> {
+        let tx = self.conn.unchecked_transaction()?;
+        tx.execute("INSERT INTO scans (root, git_commit) VALUES (?1, ?2)", params![root, git_commit])?;
+        let scan_id = tx.last_insert_rowid();
+        {
+            let mut insert_file = tx.prepare(
+                "INSERT INTO scan_files (scan_id, path, attribution, confidence, ai_probability, lines_of_code)
+                 VALUES (?1, ?2, ?3, ?4, ?5, ?6)",
+            )?;
+            let mut insert_finding = tx.prepare(
+                "INSERT INTO findings (file_id, signal_id, family, weight, description, lines)
+                 VALUES (?1, ?2, ?3, ?4, ?5, ?6)",
+            )?;
+            for report in reports.iter().filter(|r| r.metadata.skipped.is_none()) {
+                let path = report
+                    .metadata
+                    .file_path
+                    .as_ref()
+                    .map(|p| p.display().to_string())
+                    .unwrap_or_else(|| "<stdin>".into());
+                insert_file.execute(params![
+                    scan_id,
+                    path,
+                    report.attribution.primary.to_string().to_lowercase(),
+                    report.attribution.confidence,
+                    report.attribution.ai_probability,
+                    report.metadata.lines_of_code as i64,
+                ])?;
+                let file_id = tx.last_insert_rowid();
+                for signal in &report.signals {
+                    let lines: Vec<String> = signal.lines.iter().map(|l| l.to_string()).collect();
+                    insert_finding.execute(params![
+                        file_id,
+                        signal.id,
+                        signal.family.to_string().to_lowercase(),
+                        signal.weight,
+                        signal.description,
+                        lines.join(","),
+                    ])?;
+                }
+            }
+        }
+        tx.commit()?;
+        Ok(scan_id)
+    }
+
+    /// Suppress a reviewed file (`signal_id` of `None`) or one signal in
+    /// it, so [`Store::files_over`] and [`Store::findings`] leave it out.
+    /// Suppressing the same target again replaces the reason.
+    pub fn suppress(&self, path: &str, signal_id: Option<&str>, reason: &str) -> Result<()> {
+        self.conn.execute(
+            "DELETE FROM suppressions WHERE path = ?1 AND IFNULL(signal_id, '') = IFNULL(?2, '')",
+            params![path, signal_id],
+        )?;
+        self.conn.execute(
+            "INSERT INTO suppressions (path, signal_id, reason) VALUES (?1, ?2, ?3)",
+            params![path, signal_id, reason],
+        )?;
+        Ok(())
+    }
+
+    /// Lift a suppression added with [`Store::suppress`].  Returns whether
+    /// there was one.
+    pub fn unsuppress(&self, path: &str, signal_id: Option<&str>) -> Result<bool> {
+        let removed = self.conn.execute(
+            "DELETE FROM suppressions WHERE path = ?1 AND IFNULL(signal_id, '') = IFNULL(?2, '')",
+            params![path, signal_id],
+        )?;
+        Ok(removed > 0)
+    }
+
+    /// Files whose AI probability in the latest scan that saw them is over
+    /// `min_probability`, highest first, leaving out suppressed files.
+    /// With `first_seen_within_days`, only files first recorded that
+    /// recently — "added in the last month" is `Some(30)`.
+    pub fn files_over(&self, min_probability: f64, first_seen_within_days: Option<u32>) -> Result<Vec<StoredFile>> {
+        let mut stmt = self.conn.prepare(
+            "WITH latest AS (SELECT path, MAX(scan_id) AS scan_id FROM scan_files GROUP BY path),
+                  first AS (SELECT f.path, MIN(s.scanned_at) AS first_seen
+                              FROM scan_files f JOIN scans s ON s.id = f.scan_id GROUP BY f.path)
+             SELECT f.path, f.attribution, f.ai_probability, f.lines_of_code,
+                    s.id, s.scanned_at, s.git_commit, first.first_seen
+             FROM scan_files f
+             JOIN latest ON latest.path = f.path AND latest.scan_id = f.scan_id
+             JOIN first ON first.path = f.path
+             JOIN scans s ON s.id = f.scan_id
+             WHERE f.ai_probability > ?1
+               AND NOT EXISTS (SELECT 1 FROM suppressions x WHERE x.path = f.path AND x.signal_id IS NULL)
+               AND (?2 IS NULL OR first.first_seen >= datetime('now', '-' || ?2 || ' days'))
+             ORDER BY f.ai_probability DESC, f.path",
+        )?;
+        let rows = stmt.query_map(params![min_probability, first_seen_within_days], |row| {
+            Ok(StoredFile {
+                path: row.get(0)?,
+                attribution: row.get(1)?,
+                ai_probability: row.get(2)?,
+                lines_of_code: row.get::<_, i64>(3)? as usize,
+                scan_id: row.get(4)?,
+                scanned_at: row.get(5)?,
+                git_commit: row.get(6)?,
+                first_seen: row.get(7)?,
+            })
+        })?;
+        rows.collect()
+    }
+
+    /// The findings for `path` in the latest scan that saw it, minus
+    /// suppressed signals.  Empty if the file was never recorded or is
+    /// suppressed as a whole.
+    pub fn findings(&self, path: &str) -> Result<Vec<StoredFinding>> {
+        let file_id: Option<i64> = self
+            .conn
+            .query_row(
+                "SELECT id FROM scan_files WHERE path = ?1 ORDER BY scan_id DESC LIMIT 1",
+                params![path],
+                |row| row.get(0),
+            )
+            .optional()?;
+        let Some(file_id) = file_id else {
+            return Ok(Vec::new());
+        };
+        let mut stmt = self.conn.prepare(
+            "SELECT signal_id, family, weight, description, lines FROM findings
+             WHERE file_id = ?1
+               AND NOT EXISTS (SELECT 1 FROM suppressions x
+                                WHERE x.path = ?2 AND (x.signal_id IS NULL OR x.signal_id = findings.signal_id))
+             ORDER BY id",
+        )?;
+        let rows = stmt.query_map(params![file_id, path], |row| {
+            let lines: String = row.get(4)?;
+            Ok(StoredFinding {
+                signal_id: row.get(0)?,
+                family: row.get(1)?,
+                weight: row.get(2)?,
+                description: row.get(3)?,
+                lines: lines.split(',').filter_map(|l| l.parse().ok()).collect(),
+            })
+        })?;
+        rows.collect()
+    }
+}
+
+/// Whether `table` has a column named `column`.
+fn has_column(conn: &Connection, table: &str, column: &str) -> Result<bool> {
+    let mut stmt = conn.prepare(&format!("PRAGMA table_info({table})"))?;
+    let names = stmt.query_map([], |row| row.get::<_, String>(1))?;
+    for name in names {
+        if name? == column {
+            return Ok(true);
+        }
+    }
+    Ok(false)
+}
+
+/// One file of a recorded scan, as returned by [`Store::files_over`].
+#[derive(Debug, Clone, PartialEq)]
+pub struct StoredFile {
+    pub path: String,
+    /// Lowercase family name, e.g. `"claude"`.
+    pub attribution: String,
+    pub ai_probability: Option<f64>,
+    pub lines_of_code: usize,
+    /// The scan the row comes from, and when it ran (SQLite `datetime`, UTC).
+    pub scan_id: i64,
+    pub scanned_at: String,
+    pub git_commit: Option<String>,
+    /// When any scan first recorded the path.
+    pub first_seen: String,
+}
+
+/// One signal that fired in a recorded file.
+#[derive(Debug, Clone, PartialEq)]
+pub struct StoredFinding {
+    pub signal_id: String,
+    /// Lowercase family name the signal points at.
+    pub family: String,
+    pub weight: f64,
+    pub description: String,
+    pub lines: Vec<usize>,
 }
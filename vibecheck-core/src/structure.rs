@@ -0,0 +1,431 @@
+//! Structural fingerprint: detectors that survive renaming and reformatting.
+//!
+//! Most detectors read names, comments or layout — identifier length and
+//! entropy, comment ratios, line counts — so renaming every identifier and
+//! running a formatter resets them.  This module reduces each function to a
+//! normalized tree: node kinds only, every identifier replaced by one
+//! placeholder, comments and punctuation dropped.  What is left is the
+//! code's shape, and the metrics here read nothing else:
+//!
+//! - `shape_guard_first_ratio` — share of functions that open with an
+//!   early-exit guard before doing any work (validate, then compute);
+//! - `shape_template_ratio` — share of functions whose statement sequence
+//!   nearly matches another function's: one template, filled in repeatedly;
+//! - `shape_max_nesting` — mean deepest control-flow nesting per function.
+//!   Models flatten control flow; people nest;
+//! - `shape_length_cv` — coefficient of variation of the functions'
+//!   statement counts.  Generated files are made of functions of eerily
+//!   similar length;
+//! - `shape_ordering_entropy` — normalized Shannon entropy of the
+//!   functions' statement orderings, each statement reduced to its role
+//!   (guard, binding, action, branch, loop, exit).  Zero when every function
+//!   follows one ordering — guard, lookup, mutate, return — and one when no
+//!   two share one.
+//!
+//! Node kinds are classified by tree-sitter's naming conventions
+//! (`if_statement`, `return_expression`, `for_in_statement`, …), so the
+//! metrics apply to every built-in language and to language packs alike.
+
+use std::collections::HashMap;
+
+use tree_sitter::{Node, Tree};
+
+use crate::frontend::LanguageFrontend;
+
+/// The kind every identifier is normalized to.
+pub const PLACEHOLDER: &str = "_";
+
+/// Deeper subtrees are cut off, bounding recursion on pathological input
+/// (a thousand-term string concatenation nests a thousand levels deep).
+const MAX_DEPTH: usize = 128;
+
+/// Body statements a function needs for its opening to count as ordering.
+const MIN_STATEMENTS: usize = 3;
+
+/// Body statements a function needs to be compared as a template; shorter
+/// sequences match by chance.
+const MIN_TEMPLATE_STATEMENTS: usize = 5;
+
+/// Qualifying functions a file needs before a metric is reported.
+const MIN_FUNCTIONS: usize = 3;
+
+/// Most edits between two statement sequences, as a share of the longer,
+/// for them to count as one template.
+const MAX_TEMPLATE_EDITS: f64 = 0.25;
+
+/// Qualifying functions a file needs before the uniformity metrics are
+/// reported: three functions agree by chance too often.
+const MIN_UNIFORM_FUNCTIONS: usize = 4;
+
+/// A syntax node reduced to its kind and its named, non-comment children.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct Shape {
+    /// The grammar's node kind, or [`PLACEHOLDER`] for identifiers.
+    pub kind: String,
+    pub children: Vec<Shape>,
+}
+
+impl Shape {
+    pub fn new(kind: &str, children: Vec<Shape>) -> Self {
+        Self { kind: kind.to_string(), children }
+    }
+}
+
+/// Rename- and layout-independent metrics for `tree`, parsed with
+/// `frontend`'s grammar.  Metrics without enough functions to judge are
+/// left out.
+pub fn metrics(tree: &Tree, frontend: &dyn LanguageFrontend) -> HashMap<String, f64> {
+    shape_metrics(&functions(tree, frontend))
+}
+
+/// The normalized body statements of every outermost function in `tree`;
+/// nested closures and inner functions are part of their enclosing one.
+pub fn functions(tree: &Tree, frontend: &dyn LanguageFrontend) -> Vec<Vec<Shape>> {
+    let mut found = Vec::new();
+    let mut stack = vec![tree.root_node()];
+    while let Some(node) = stack.pop() {
+        if frontend.is_function(node.kind()) {
+            if let Some(body) = node.child_by_field_name("body") {
+                found.push(statements(&normalize(body, frontend, 0)));
+            }
+            continue;
+        }
+        let mut cursor = node.walk();
+        stack.extend(node.named_children(&mut cursor));
+    }
+    found.reverse();
+    found
+}
+
+fn normalize(node: Node<'_>, frontend: &dyn LanguageFrontend, depth: usize) -> Shape {
+    let kind = if frontend.is_identifier(node.kind()) { PLACEHOLDER } else { node.kind() };
+    if depth >= MAX_DEPTH {
+        return Shape::new(kind, Vec::new());
+    }
+    let mut cursor = node.walk();
+    let children = node
+        .named_children(&mut cursor)
+        .filter(|c| !frontend.is_comment(c.kind()))
+        .map(|c| normalize(c, frontend, depth + 1))
+        .collect();
+    Shape::new(kind, children)
+}
+
+/// The statements of a function body, unwrapped from their expression
+/// statements.  Empty for expression bodies (`x => x + 1`, `lambda`).
+/// Go's `statement_list` is flattened and a leading docstring skipped.
+fn statements(body: &Shape) -> Vec<Shape> {
+    if !(body.kind.ends_with("block") || body.kind == "compound_statement") {
+        return Vec::new();
+    }
+    let mut out: Vec<Shape> = body
+        .children
+        .iter()
+        .flat_map(|c| if c.kind == "statement_list" { c.children.clone() } else { vec![c.clone()] })
+        .map(|s| match s.children.as_slice() {
+            [only] if s.kind == "expression_statement" => only.clone(),
+            _ => s,
+        })
+        .collect();
+    if out.first().is_some_and(|s| s.kind == "string") {
+        out.remove(0);
+    }
+    out
+}
+
+/// Statements and expressions, as opposed to clauses, arms and blocks.
+fn is_construct(kind: &str) -> bool {
+    kind.ends_with("_statement") || kind.ends_with("_expression")
+}
+
+fn is_branch(kind: &str) -> bool {
+    is_construct(kind) && (kind.starts_with("if") || kind.starts_with("match") || kind.contains("switch"))
+}
+
+fn is_loop(kind: &str) -> bool {
+    is_construct(kind) && ["for", "while", "loop", "do_"].iter().any(|p| kind.starts_with(p))
+}
+
+fn is_exit(kind: &str) -> bool {
+    is_construct(kind) && ["return", "raise", "throw"].iter().any(|p| kind.starts_with(p))
+}
+
+fn is_control_flow(kind: &str) -> bool {
+    is_branch(kind) || is_loop(kind) || kind == "try_statement"
+}
+
+fn contains_exit(shape: &Shape) -> bool {
+    is_exit(&shape.kind) || shape.children.iter().any(contains_exit)
+}
+
+/// An `if` whose body leaves the function, as the first of several
+/// statements: the function validates before it does anything.
+fn opens_with_guard(body: &[Shape]) -> bool {
+    body.len() >= MIN_STATEMENTS && body[0].kind.starts_with("if") && is_branch(&body[0].kind) && contains_exit(&body[0])
+}
+
+/// Deepest control-flow nesting in `shape`.  An `else if` continues its
+/// chain rather than nesting inside it.
+fn nesting(shape: &Shape, parent: &str) -> usize {
+    let chained = shape.kind.starts_with("if") && (parent == "else_clause" || parent.starts_with("if"));
+    let own = usize::from(is_control_flow(&shape.kind) && !chained);
+    own + shape.children.iter().map(|c| nesting(c, &shape.kind)).max().unwrap_or(0)
+}
+
+/// What a body statement does, coarsely enough that two functions doing
+/// different things in the same order get the same ordering.
+fn role(statement: &Shape) -> &'static str {
+    let kind = statement.kind.as_str();
+    if is_branch(kind) && kind.starts_with("if") && contains_exit(statement) {
+        "guard"
+    } else if is_branch(kind) {
+        "branch"
+    } else if is_loop(kind) {
+        "loop"
+    } else if is_exit(kind) {
+        "exit"
+    } else if kind.contains("declaration") || kind.contains("assignment") || kind.starts_with("let") {
+        "binding"
+    } else {
+        "action"
+    }
+}
+
+/// The roles of a body's statements with runs collapsed: a function that
+/// binds three values before acting is ordered like one that binds one.
+fn ordering(body: &[Shape]) -> Vec<&'static str> {
+    let mut roles: Vec<&'static str> = body.iter().map(role).collect();
+    roles.dedup();
+    roles
+}
+
+/// Levenshtein distance between two statement-kind sequences.
+fn edit_distance(a: &[&str], b: &[&str]) -> usize {
+    let mut previous: Vec<usize> = (0..=b.len()).collect();
+    for (i, x) in a.iter().enumerate() {
+        let mut current = vec![i + 1];
+        for (j, y) in b.iter().enumerate() {
+            let substitute = previous[j] + usize::from(x != y);
+            current.push(substitute.min(previous[j + 1] + 1).min(current[j] + 1));
+        }
+        previous = current;
+    }
+    previous[b.len()]
+}
+
+/// The metrics over already-normalized function bodies.
+fn shape_metrics(functions: &[Vec<Shape>]) -> HashMap<String, f64> {
+    let mut metrics = HashMap::new();
+
+    let ordered: Vec<&Vec<Shape>> = functions.iter().filter(|f| f.len() >= MIN_STATEMENTS).collect();
+    if ordered.len() >= MIN_FUNCTIONS {
+        let guarded = ordered.iter().filter(|f| opens_with_guard(f)).count();
+        metrics.insert("shape_guard_first_ratio".into(), guarded as f64 / ordered.len() as f64);
+    }
+
+    let skeletons: Vec<Vec<&str>> = functions
+        .iter()
+        .filter(|f| f.len() >= MIN_TEMPLATE_STATEMENTS)
+        .map(|f| f.iter().map(|s| s.kind.as_str()).collect())
+        .collect();
+    if skeletons.len() >= MIN_FUNCTIONS {
+        let templated = skeletons
+            .iter()
+            .enumerate()
+            .filter(|(i, a)| {
+                skeletons.iter().enumerate().any(|(j, b)| {
+                    *i != j && edit_distance(a, b) as f64 <= MAX_TEMPLATE_EDITS * a.len().max(b.len()) as f64
+                })
+            })
+            .count();
+        metrics.insert("shape_template_ratio".into(), templated as f64 / skeletons.len() as f64);
+    }
+
+    if ordered.len() >= MIN_UNIFORM_FUNCTIONS {
+        let n = ordered.len() as f64;
+        let mean = ordered.iter().map(|f| f.len() as f64).sum::<f64>() / n;
+        let variance = ordered.iter().map(|f| (f.len() as f64 - mean).powi(2)).sum::<f64>() / n;
+        metrics.insert("shape_length_cv".into(), variance.sqrt() / mean);
+
+        let mut counts: HashMap<Vec<&str>, usize> = HashMap::new();
+        for f in &ordered {
+            *counts.entry(ordering(f)).or_default() += 1;
+        }
+        let entropy: f64 = counts.values().map(|&c| c as f64 / n * (n / c as f64).log2()).sum();
+        metrics.insert("shape_ordering_entropy".into(), entropy / n.log2());
+    }
+
+    let bodies: Vec<&Vec<Shape>> = functions.iter().filter(|f| !f.is_empty()).collect();
+    if bodies.len() >= MIN_FUNCTIONS {
+        let total: usize = bodies.iter().map(|f| f.iter().map(|s| nesting(s, "")).max().unwrap_or(0)).sum();
+        metrics.insert("shape_max_nesting".into(), total as f64 / bodies.len() as f64);
+    }
+
+    metrics
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::path::Path;
+
+    use crate::frontend::frontend_for;
+
+    fn leaf(kind: &str) -> Shape {
+        Shape::new(kind, Vec::new())
+    }
+
+    /// `if <cond> { return }` — a guard.
+    fn guard() -> Shape {
+        Shape::new("if_statement", vec![leaf("binary_expression"), Shape::new("block", vec![leaf("return_statement")])])
+    }
+
+    /// `for … { if … { for … {} } }`, nesting three deep.
+    fn nested() -> Shape {
+        let inner = Shape::new("for_statement", vec![leaf("block")]);
+        let branch = Shape::new("if_statement", vec![leaf("_"), Shape::new("block", vec![inner])]);
+        Shape::new("for_statement", vec![leaf("_"), Shape::new("block", vec![branch])])
+    }
+
+    #[test]
+    fn guard_first_ratio_counts_functions_that_validate_first() {
+        let work = || leaf("call_expression");
+        let functions = vec![
+            vec![guard(), work(), leaf("return_statement")],
+            vec![guard(), work(), work()],
+            vec![work(), guard(), work()],
+            vec![guard()], // too short to have an order
+        ];
+        let metrics = shape_metrics(&functions);
+        assert!((metrics["shape_guard_first_ratio"] - 2.0 / 3.0).abs() < 1e-9);
+    }
+
+    #[test]
+    fn template_ratio_matches_near_identical_statement_sequences() {
+        let body = |kinds: &[&str]| kinds.iter().map(|k| leaf(k)).collect::<Vec<_>>();
+        let template = ["if_statement", "assignment", "try_statement", "call", "call", "return_statement"];
+        let mut variant = template;
+        variant[3] = "assignment";
+        let functions = vec![
+            body(&template),
+            body(&variant),
+            body(&["for_statement", "call", "if_statement", "assignment", "while_statement"]),
+        ];
+        assert!((shape_metrics(&functions)["shape_template_ratio"] - 2.0 / 3.0).abs() < 1e-9);
+    }
+
+    #[test]
+    fn max_nesting_ignores_else_if_chains() {
+        let chain = Shape::new(
+            "if_statement",
+            vec![leaf("block"), Shape::new("else_clause", vec![Shape::new("if_statement", vec![leaf("block")])])],
+        );
+        assert_eq!(nesting(&chain, ""), 1);
+        assert_eq!(nesting(&nested(), ""), 3);
+
+        let functions = vec![vec![nested()], vec![chain], vec![leaf("call_expression")]];
+        assert!((shape_metrics(&functions)["shape_max_nesting"] - 4.0 / 3.0).abs() < 1e-9);
+    }
+
+    #[test]
+    fn uniform_functions_have_low_length_variation_and_ordering_entropy() {
+        let bind = || leaf("let_declaration");
+        let act = || leaf("call_expression");
+        let exit = || leaf("return_expression");
+        // guard, lookup, mutate, return — four times over.
+        let uniform = vec![
+            vec![guard(), bind(), act(), exit()],
+            vec![guard(), bind(), bind(), act(), exit()],
+            vec![guard(), bind(), act(), exit()],
+            vec![guard(), bind(), act(), act(), exit()],
+        ];
+        let metrics = shape_metrics(&uniform);
+        assert_eq!(metrics["shape_ordering_entropy"], 0.0);
+        assert!(metrics["shape_length_cv"] < 0.15, "{metrics:?}");
+
+        let varied = vec![
+            vec![guard(), bind(), act(), exit()],
+            vec![bind(), nested(), act(), act(), act(), act(), act(), act(), act(), exit()],
+            vec![act(), act(), act()],
+            vec![bind(), leaf("if_expression"), bind(), bind(), bind(), bind(), bind(), bind(), bind(), bind(), bind(), bind(), act()],
+        ];
+        let metrics = shape_metrics(&varied);
+        assert_eq!(metrics["shape_ordering_entropy"], 1.0);
+        assert!(metrics["shape_length_cv"] > 0.4, "{metrics:?}");
+        assert!(!shape_metrics(&uniform[..3]).contains_key("shape_length_cv"));
+    }
+
+    #[test]
+    fn too_few_functions_report_nothing() {
+        assert!(shape_metrics(&[vec![guard(), leaf("call"), leaf("call")]]).is_empty());
+    }
+
+    #[test]
+    fn statements_unwrap_expressions_and_skip_docstrings() {
+        let body = Shape::new(
+            "block",
+            vec![
+                Shape::new("expression_statement", vec![leaf("string")]),
+                Shape::new("expression_statement", vec![leaf("call")]),
+                Shape::new("statement_list", vec![leaf("return_statement")]),
+            ],
+        );
+        let kinds: Vec<String> = statements(&body).into_iter().map(|s| s.kind).collect();
+        assert_eq!(kinds, ["call", "return_statement"]);
+        assert!(statements(&leaf("binary_expression")).is_empty());
+    }
+
+    #[test]
+    fn renaming_and_reformatting_leave_metrics_unchanged() {
+        let original = "\
+def load(path):
+    if not path:
+        raise ValueError('path required')
+    data = read(path)
+    return parse(data)
+
+def save(path, value):
+    if value is None:
+        return None
+    text = dump(value)
+    write(path, text)
+
+def merge(left, right):
+    seen = set()
+    for key in right:
+        if key in left:
+            for item in right[key]:
+                left[key].append(item)
+    return left
+";
+        let laundered = "\
+def fetch_resource(resource_location):
+    # Make sure a location was provided.
+    if not resource_location: raise ValueError('a location is required')
+    raw_payload = read(resource_location)
+    return parse(raw_payload)
+def persist(destination, payload):
+    if payload is None:
+        return None
+    serialized = dump(payload)
+    write(destination, serialized)
+def combine(base_mapping, overrides):
+    visited = set()
+    for k in overrides:
+        if k in base_mapping:
+            for entry in overrides[k]: base_mapping[k].append(entry)
+    return base_mapping
+";
+        let frontend = frontend_for(Path::new("a.py"), &[]).unwrap();
+        let parse = |source: &str| {
+            let mut parser = tree_sitter::Parser::new();
+            parser.set_language(&frontend.grammar()).unwrap();
+            parser.parse(source, None).unwrap()
+        };
+        let (a, b) = (parse(original), parse(laundered));
+        assert_eq!(functions(&a, frontend), functions(&b, frontend));
+        let metrics = metrics(&a, frontend);
+        assert!((metrics["shape_guard_first_ratio"] - 2.0 / 3.0).abs() < 1e-9, "{metrics:?}");
+        assert!((metrics["shape_max_nesting"] - 5.0 / 3.0).abs() < 1e-9, "{metrics:?}");
+    }
+}
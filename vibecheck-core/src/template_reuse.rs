@@ -0,0 +1,263 @@
+//! Repository-level detection of templated comments.
+//!
+//! When an assistant writes several files of a repository, it reuses its
+//! own narrative comments almost verbatim ("Validate the request before
+//! processing to ensure ...").  A human copy-pastes too, but mostly within a
+//! package.  This module shingles every comment block of a scan and clusters
+//! near-duplicates that appear in unrelated packages, i.e. files in
+//! different directories.
+//!
+//! The finding needs every file at once, so it is not a per-file signal;
+//! callers run [`detect`] over a finished scan.
+
+use std::collections::hash_map::DefaultHasher;
+use std::collections::{HashMap, HashSet};
+use std::hash::{Hash, Hasher};
+use std::path::{Path, PathBuf};
+
+/// Comment blocks shorter than this are labels, not narrative.
+const MIN_WORDS: usize = 8;
+
+/// Words per shingle.
+const SHINGLE_WORDS: usize = 3;
+
+/// Jaccard similarity of two blocks' shingle sets for them to count as the
+/// same comment.
+const MIN_SIMILARITY: f64 = 0.6;
+
+/// Shingles shared by more blocks than this are stock phrases and ignored
+/// when pairing blocks, which also bounds the work on large repositories.
+const MAX_SHINGLE_BLOCKS: usize = 64;
+
+/// Where one copy of a reused comment starts.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct CommentLocation {
+    pub path: PathBuf,
+    /// 1-based line of the first comment line.
+    pub line: usize,
+}
+
+/// A narrative comment repeated near-verbatim across packages.
+#[derive(Debug, Clone)]
+pub struct ReusedComment {
+    /// The comment text of the first copy, markers stripped.
+    pub text: String,
+    /// Every copy, sorted by path and line.
+    pub locations: Vec<CommentLocation>,
+}
+
+impl ReusedComment {
+    /// Number of distinct directories the comment appears in.
+    pub fn package_count(&self) -> usize {
+        self.locations.iter().map(|l| package(&l.path)).collect::<HashSet<_>>().len()
+    }
+}
+
+/// One comment block of one file.
+struct Block {
+    file: usize,
+    line: usize,
+    text: String,
+    shingles: HashSet<u64>,
+}
+
+/// Near-duplicate narrative comments shared by files of different
+/// packages, most widespread first.  `files` pairs each path with its
+/// source.
+pub fn detect(files: &[(PathBuf, String)]) -> Vec<ReusedComment> {
+    let blocks: Vec<Block> = files
+        .iter()
+        .enumerate()
+        .flat_map(|(file, (_, source))| {
+            comment_blocks(source).into_iter().filter_map(move |(line, text)| {
+                let shingles = shingles(&text)?;
+                Some(Block { file, line, text, shingles })
+            })
+        })
+        .collect();
+
+    let mut postings: HashMap<u64, Vec<usize>> = HashMap::new();
+    for (i, block) in blocks.iter().enumerate() {
+        for &s in &block.shingles {
+            postings.entry(s).or_default().push(i);
+        }
+    }
+    let mut shared: HashMap<(usize, usize), usize> = HashMap::new();
+    for ids in postings.values().filter(|ids| ids.len() <= MAX_SHINGLE_BLOCKS) {
+        for (n, &a) in ids.iter().enumerate() {
+            for &b in &ids[n + 1..] {
+                if package(&files[blocks[a].file].0) != package(&files[blocks[b].file].0) {
+                    *shared.entry((a, b)).or_default() += 1;
+                }
+            }
+        }
+    }
+
+    let mut parent: Vec<usize> = (0..blocks.len()).collect();
+    for (&(a, b), &common) in &shared {
+        let union = blocks[a].shingles.len() + blocks[b].shingles.len() - common;
+        if common as f64 / union as f64 >= MIN_SIMILARITY {
+            let (ra, rb) = (root(&mut parent, a), root(&mut parent, b));
+            parent[ra.max(rb)] = ra.min(rb);
+        }
+    }
+
+    // Roots are the lowest index of their cluster, so each cluster's first
+    // member is its earliest block.
+    let mut clusters: HashMap<usize, Vec<usize>> = HashMap::new();
+    for i in 0..blocks.len() {
+        clusters.entry(root(&mut parent, i)).or_default().push(i);
+    }
+    let mut reused: Vec<ReusedComment> = clusters
+        .into_values()
+        .filter(|members| members.len() > 1)
+        .map(|members| {
+            let mut locations: Vec<CommentLocation> = members
+                .iter()
+                .map(|&i| CommentLocation { path: files[blocks[i].file].0.clone(), line: blocks[i].line })
+                .collect();
+            locations.sort_by(|a, b| (&a.path, a.line).cmp(&(&b.path, b.line)));
+            ReusedComment { text: blocks[members[0]].text.clone(), locations }
+        })
+        .collect();
+    reused.sort_by(|a, b| {
+        b.package_count()
+            .cmp(&a.package_count())
+            .then_with(|| (&a.locations[0].path, a.locations[0].line).cmp(&(&b.locations[0].path, b.locations[0].line)))
+    });
+    reused
+}
+
+fn root(parent: &mut [usize], mut i: usize) -> usize {
+    while parent[i] != i {
+        parent[i] = parent[parent[i]];
+        i = parent[i];
+    }
+    i
+}
+
+/// The package a file belongs to: its directory.
+fn package(path: &Path) -> &Path {
+    path.parent().unwrap_or(Path::new(""))
+}
+
+/// Runs of consecutive whole-line comments as `(first line, text)`, with
+/// comment markers stripped and lines joined by spaces.
+fn comment_blocks(source: &str) -> Vec<(usize, String)> {
+    let mut blocks = Vec::new();
+    let mut current: Option<(usize, String)> = None;
+    for (i, line) in source.lines().enumerate() {
+        match comment_text(line.trim()) {
+            Some(text) => {
+                let (_, block) = current.get_or_insert_with(|| (i + 1, String::new()));
+                if !block.is_empty() && !text.is_empty() {
+                    block.push(' ');
+                }
+                block.push_str(text);
+            }
+            None => blocks.extend(current.take()),
+        }
+    }
+    blocks.extend(current);
+    blocks
+}
+
+/// The text of a whole-line comment, or `None` for any other line.
+fn comment_text(trimmed: &str) -> Option<&str> {
+    const MARKERS: &[&str] = &["///", "//!", "//", "/**", "/*", "*/", "*", "#"];
+    if trimmed.starts_with("#[") || trimmed.starts_with("#!") || trimmed.starts_with("#include") {
+        return None;
+    }
+    let marker = MARKERS.iter().find(|m| trimmed.starts_with(**m))?;
+    Some(trimmed[marker.len()..].trim_end_matches("*/").trim())
+}
+
+/// Hashed word shingles of a narrative comment; `None` for comments too
+/// short to be narrative, commented-out code and license headers.
+fn shingles(text: &str) -> Option<HashSet<u64>> {
+    let lower = text.to_lowercase();
+    if ["copyright", "license", "spdx-"].iter().any(|w| lower.contains(w)) {
+        return None;
+    }
+    if text.contains(['{', '}', ';', '=']) {
+        return None;
+    }
+    let words: Vec<&str> = lower
+        .split(|c: char| !c.is_alphanumeric())
+        .filter(|w| !w.is_empty())
+        .collect();
+    if words.len() < MIN_WORDS {
+        return None;
+    }
+    Some(
+        words
+            .windows(SHINGLE_WORDS)
+            .map(|w| {
+                let mut h = DefaultHasher::new();
+                w.hash(&mut h);
+                h.finish()
+            })
+            .collect(),
+    )
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn file(path: &str, source: &str) -> (PathBuf, String) {
+        (PathBuf::from(path), source.to_string())
+    }
+
+    const HANDLER: &str = "\
+package users
+
+// Validate the incoming request payload before processing it to ensure
+// that all required fields are present and well formed.
+func Create(r Request) error {
+    return nil
+}
+";
+
+    #[test]
+    fn flags_near_duplicates_across_packages() {
+        let orders = HANDLER
+            .replace("package users", "package orders")
+            .replace("well formed", "properly formed");
+        let files = [file("users/create.go", HANDLER), file("orders/create.go", &orders)];
+        let reused = detect(&files);
+        assert_eq!(reused.len(), 1);
+        assert!(reused[0].text.starts_with("Validate the incoming request payload"));
+        let at: Vec<(String, usize)> =
+            reused[0].locations.iter().map(|l| (l.path.display().to_string(), l.line)).collect();
+        assert_eq!(at, [("orders/create.go".to_string(), 3), ("users/create.go".to_string(), 3)]);
+        assert_eq!(reused[0].package_count(), 2);
+    }
+
+    #[test]
+    fn same_package_and_short_comments_are_not_flagged() {
+        let files = [file("users/create.go", HANDLER), file("users/update.go", HANDLER)];
+        assert!(detect(&files).is_empty());
+
+        let short = "// Create a user.\nfunc Create() {}\n";
+        let files = [file("users/a.go", short), file("orders/a.go", short)];
+        assert!(detect(&files).is_empty());
+    }
+
+    #[test]
+    fn license_headers_and_commented_code_are_not_flagged() {
+        let header = "// Copyright 2024 The Authors. Licensed under the Apache License, Version 2.0.\npackage x\n";
+        let code = "// for _, item := range items { total = total + item.Price * item.Quantity }\n";
+        let files = [file("a/x.go", header), file("b/x.go", header), file("a/y.go", code), file("b/y.go", code)];
+        assert!(detect(&files).is_empty());
+    }
+
+    #[test]
+    fn comment_blocks_join_consecutive_lines() {
+        let source = "# First line\n# second line.\nx = 1\n    /* Block\n     * comment */\n";
+        assert_eq!(
+            comment_blocks(source),
+            [(1, "First line second line.".to_string()), (4, "Block comment".to_string())]
+        );
+    }
+}
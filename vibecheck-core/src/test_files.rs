@@ -0,0 +1,177 @@
+//! Scoring test files with their own weights.
+//!
+//! Test code is repetitive on purpose: a table-driven Go test repeats one
+//! statement sequence per case, test names spell out scenarios and nobody
+//! documents a test for its callers.  Scored like library code, a
+//! well-written human test suite reads as templated and over-descriptive.
+//! Test files are therefore scored with the embedded `test_heuristics.toml`
+//! on top of the project's weights, plus any `[tests.heuristics]` from
+//! `.vibecheck`.
+
+use std::collections::HashMap;
+use std::path::{Component, Path, PathBuf};
+use std::sync::OnceLock;
+
+use ignore::gitignore::{Gitignore, GitignoreBuilder};
+
+/// Directories whose files are tests by convention (outside Go, where only
+/// `_test.go` files are).
+const TEST_DIRS: &[&str] = &["tests", "test", "__tests__", "spec"];
+
+/// Directories of sample inputs, which are not test code even inside a
+/// test directory.
+const SAMPLE_DIRS: &[&str] = &["fixtures", "testdata"];
+
+/// Whether `path` names a test file by its language's convention:
+/// `_test.go`; `test_*.py`, `*_test.py` and `conftest.py`; `*.test.*` and
+/// `*.spec.*` for JavaScript and TypeScript; `*_test.rs`; or any file of
+/// those languages under a `tests/`, `test/`, `__tests__/` or `spec/`
+/// directory but not under `fixtures/` or `testdata/`.
+pub fn is_test_path(path: &Path) -> bool {
+    let Some(name) = path.file_name().and_then(|n| n.to_str()) else {
+        return false;
+    };
+    let Some((stem, ext)) = name.rsplit_once('.') else {
+        return false;
+    };
+    if ext == "go" {
+        return stem.ends_with("_test");
+    }
+    let by_name = match ext {
+        "py" => stem.starts_with("test_") || stem.ends_with("_test") || stem == "conftest",
+        "rs" => stem.ends_with("_test"),
+        "js" | "jsx" | "mjs" | "cjs" | "ts" | "tsx" | "mts" | "cts" => {
+            stem.ends_with(".test") || stem.ends_with(".spec")
+        }
+        _ => return false,
+    };
+    let in_dir = |dirs: &[&str]| {
+        path.parent()
+            .into_iter()
+            .flat_map(Path::components)
+            .any(|c| matches!(c, Component::Normal(dir) if dir.to_str().is_some_and(|d| dirs.contains(&d))))
+    };
+    by_name || (in_dir(TEST_DIRS) && !in_dir(SAMPLE_DIRS))
+}
+
+/// The embedded weight overrides for test files.
+pub fn default_weights() -> &'static HashMap<String, f64> {
+    static WEIGHTS: OnceLock<HashMap<String, f64>> = OnceLock::new();
+    WEIGHTS.get_or_init(|| {
+        #[derive(serde::Deserialize)]
+        struct Manifest {
+            heuristics: HashMap<String, f64>,
+        }
+        toml::from_str::<Manifest>(include_str!("../test_heuristics.toml"))
+            .expect("embedded test_heuristics.toml is invalid")
+            .heuristics
+    })
+}
+
+/// Which files of a project are tests, and the weights they are scored
+/// with on top of the project's own.
+#[derive(Clone)]
+pub struct TestFiles {
+    /// `false` when test files are scored like any other.
+    enabled: bool,
+    root: PathBuf,
+    /// Extra gitignore-style globs for test files, relative to `root`.
+    patterns: Gitignore,
+    /// `[tests.heuristics]`, over [`default_weights`].
+    overrides: HashMap<String, f64>,
+}
+
+impl TestFiles {
+    /// Test files under `root` by convention and by `patterns`, scored with
+    /// `overrides` over the default test weights.
+    pub fn new(root: &Path, patterns: &[String], overrides: HashMap<String, f64>) -> Self {
+        let mut b = GitignoreBuilder::new(root);
+        for p in patterns {
+            let _ = b.add_line(None, p);
+        }
+        Self { enabled: true, root: root.to_path_buf(), patterns: b.build().unwrap_or(Gitignore::empty()), overrides }
+    }
+
+    /// No test files: everything is scored with the project's weights.
+    pub fn none() -> Self {
+        Self { enabled: false, root: PathBuf::new(), patterns: Gitignore::empty(), overrides: HashMap::new() }
+    }
+
+    /// Whether `path` is a test file.
+    pub fn is_test(&self, path: &Path) -> bool {
+        if !self.enabled {
+            return false;
+        }
+        let rel = path.strip_prefix(&self.root).unwrap_or(path);
+        is_test_path(rel) || (rel.is_relative() && self.patterns.matched_path_or_any_parents(rel, false).is_ignore())
+    }
+
+    /// Weight overrides for test files, to apply over the project's
+    /// `[heuristics]`.
+    pub fn heuristics_map(&self) -> HashMap<String, f64> {
+        let mut weights = default_weights().clone();
+        weights.extend(self.overrides.iter().map(|(id, w)| (id.clone(), *w)));
+        weights
+    }
+
+    /// Stable string for the cache key of `path`: test files are keyed by
+    /// their overrides, other files not at all.
+    pub fn cache_setting(&self, path: &Path) -> Option<String> {
+        if !self.is_test(path) {
+            return None;
+        }
+        let mut overrides: Vec<String> = self.overrides.iter().map(|(id, w)| format!("{id}={w}")).collect();
+        overrides.sort();
+        Some(format!("tests={}", overrides.join(",")))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::heuristics::all_heuristics;
+
+    #[test]
+    fn test_paths_follow_each_languages_convention() {
+        for path in [
+            "pkg/cache/lru_test.go",
+            "tests/test_api.py",
+            "app/conftest.py",
+            "src/util_test.py",
+            "web/src/Button.test.tsx",
+            "web/src/api.spec.js",
+            "web/__tests__/api.js",
+            "crate/tests/integration.rs",
+        ] {
+            assert!(is_test_path(Path::new(path)), "{path}");
+        }
+        for path in ["pkg/tests/helpers.go", "src/testing.py", "src/contest.py", "src/test.rs.bak", "README.md", "tests/data.json", "tests/fixtures/claude/app.py"] {
+            assert!(!is_test_path(Path::new(path)), "{path}");
+        }
+    }
+
+    #[test]
+    fn patterns_and_overrides_extend_the_defaults() {
+        let root = Path::new("/repo");
+        let tests = TestFiles::new(root, &["internal/testutil/".into()], HashMap::from([("go_cst.shape.templated".into(), 0.5)]));
+        assert!(tests.is_test(Path::new("/repo/internal/testutil/fake.go")));
+        assert!(tests.is_test(Path::new("/repo/cmd/main_test.go")));
+        // Conventions apply below the root, not to where the root lives.
+        assert!(!TestFiles::new(Path::new("/home/ci/tests/repo"), &[], HashMap::new()).is_test(Path::new("/home/ci/tests/repo/src/lib.py")));
+
+        let weights = tests.heuristics_map();
+        assert_eq!(weights["go_cst.shape.templated"], 0.5);
+        assert_eq!(weights["go.idioms.table_driven_tests"], 0.0);
+        assert_eq!(tests.cache_setting(Path::new("/repo/cmd/main.go")), None);
+        assert_eq!(tests.cache_setting(Path::new("/repo/cmd/main_test.go")).as_deref(), Some("tests=go_cst.shape.templated=0.5"));
+        assert!(!TestFiles::none().is_test(Path::new("/repo/cmd/main_test.go")));
+    }
+
+    #[test]
+    fn default_weights_name_real_rules() {
+        let ids: Vec<&str> = all_heuristics().iter().map(|h| h.id).collect();
+        for id in default_weights().keys() {
+            assert!(ids.contains(&id.as_str()), "unknown rule {id}");
+        }
+    }
+}
@@ -0,0 +1,110 @@
+//! Per-file time limits.
+//!
+//! A pathological file — a megabyte-long generated line, a deeply nested
+//! expression — can keep the analyzers busy for minutes.  [`run`] bounds the
+//! wait so one such file cannot stall a whole scan; the file is then
+//! reported with [`Report::skipped`](crate::report::Report::skipped) and
+//! [`SKIPPED_TIMEOUT`] instead of being dropped.  The abandoned worker sees
+//! [`cancelled`] and stops at the pipeline's next stage.
+
+#[cfg(not(target_arch = "wasm32"))]
+use std::cell::RefCell;
+#[cfg(not(target_arch = "wasm32"))]
+use std::sync::atomic::{AtomicBool, Ordering};
+#[cfg(not(target_arch = "wasm32"))]
+use std::sync::{mpsc, Arc};
+use std::time::Duration;
+
+/// `ReportMetadata::skipped` reason for files that hit the time limit.
+pub const SKIPPED_TIMEOUT: &str = "timeout";
+
+#[cfg(not(target_arch = "wasm32"))]
+thread_local! {
+    /// Set on a [`run`] worker; raised when the caller stops waiting.
+    static CANCEL: RefCell<Option<Arc<AtomicBool>>> = const { RefCell::new(None) };
+}
+
+/// Run `work` on a worker thread and wait at most `limit` for its result.
+///
+/// Returns `None` on timeout, and raises [`cancelled`] on the worker:
+/// [`Pipeline::run`](crate::pipeline::Pipeline::run) checks it between
+/// stages and returns early, and the cache refuses to store what the
+/// worker produced.  A stage already running finishes first.  A panic in
+/// `work` is re-raised on the calling thread.
+#[cfg(not(target_arch = "wasm32"))]
+pub fn run<T: Send + 'static>(limit: Duration, work: impl FnOnce() -> T + Send + 'static) -> Option<T> {
+    let (tx, rx) = mpsc::channel();
+    let cancel = Arc::new(AtomicBool::new(false));
+    let flag = Arc::clone(&cancel);
+    let worker = std::thread::spawn(move || {
+        CANCEL.with(|c| *c.borrow_mut() = Some(flag));
+        let _ = tx.send(work());
+    });
+    match rx.recv_timeout(limit) {
+        Ok(value) => Some(value),
+        Err(mpsc::RecvTimeoutError::Timeout) => {
+            cancel.store(true, Ordering::Relaxed);
+            None
+        }
+        Err(mpsc::RecvTimeoutError::Disconnected) => match worker.join() {
+            Err(panic) => std::panic::resume_unwind(panic),
+            Ok(()) => None,
+        },
+    }
+}
+
+/// Whether the [`run`] this thread is working for has timed out.  Always
+/// `false` outside a worker.
+#[cfg(not(target_arch = "wasm32"))]
+pub fn cancelled() -> bool {
+    CANCEL.with(|c| c.borrow().as_ref().is_some_and(|flag| flag.load(Ordering::Relaxed)))
+}
+
+/// `wasm32` has no threads to abandon a worker on, so `work` runs to
+/// completion on the calling thread and `limit` is not enforced.
+#[cfg(target_arch = "wasm32")]
+pub fn run<T: Send + 'static>(_limit: Duration, work: impl FnOnce() -> T + Send + 'static) -> Option<T> {
+    Some(work())
+}
+
+/// Nothing is ever abandoned on `wasm32`.
+#[cfg(target_arch = "wasm32")]
+pub fn cancelled() -> bool {
+    false
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn returns_result_within_limit() {
+        assert_eq!(run(Duration::from_secs(5), || 42), Some(42));
+    }
+
+    #[test]
+    fn gives_up_after_limit() {
+        let slow = || std::thread::sleep(Duration::from_secs(2));
+        assert_eq!(run(Duration::from_millis(20), slow), None);
+    }
+
+    #[test]
+    fn cancels_the_abandoned_worker() {
+        let (tx, rx) = mpsc::channel();
+        let polling = move || {
+            while !cancelled() {
+                std::thread::sleep(Duration::from_millis(1));
+            }
+            tx.send(()).unwrap();
+        };
+        assert_eq!(run(Duration::from_millis(20), polling), None);
+        rx.recv_timeout(Duration::from_secs(5)).expect("worker still running after the timeout");
+        assert!(!cancelled(), "only the worker is cancelled");
+    }
+
+    #[test]
+    #[should_panic(expected = "boom")]
+    fn propagates_worker_panics() {
+        run(Duration::from_secs(5), || panic!("boom"));
+    }
+}
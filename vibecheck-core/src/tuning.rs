@@ -0,0 +1,594 @@
+//! Signal-weight tuning against a labelled corpus.
+//!
+//! Attribution is an argmax over per-family sums of signal weights (see
+//! [`Pipeline::aggregate`]), so the weights are the parameters of a linear
+//! classifier.  [`tune`] fits them as a softmax regression over those sums:
+//! each signal keeps the family it points at — negative-weight signals point
+//! at Human — and only its magnitude is learned, with an L2 penalty pulling
+//! it back toward the current weight so that a small corpus nudges the
+//! hand-tuned values instead of replacing them.  k-fold cross-validation
+//! reports whether the fitted weights generalize before they are used.
+//!
+//! [`train_logistic`] instead fits a [`LogisticClassifier`] to replace the
+//! weighted sum altogether, [`train_prototypes`] fits the family
+//! prototypes of an embedding model, and [`train_stylometry`] counts the
+//! comment n-grams of each family; all are cross-validated the same way.
+//! [`train_calibration`] leaves attribution alone and fits the per-language
+//! [`Calibration`] that maps AI shares to probabilities.
+
+use std::collections::{BTreeMap, HashMap};
+use std::sync::Arc;
+
+use crate::analyzers::text::stylometry::{comment_prose, NgramModel, StylometryAnalyzer, DEFAULT_MARGIN};
+use crate::analyzers::Analyzer;
+use crate::calibration::{ai_share, language_key, Calibration};
+use crate::classifier::{Classifier, LogisticClassifier};
+use crate::embedding::Prototypes;
+use crate::eval::Sample;
+use crate::language::detect_language;
+use crate::pipeline::Pipeline;
+use crate::report::{ModelFamily, Signal};
+
+/// Folds used by `vibecheck tune` unless `--folds` is given.
+pub const DEFAULT_FOLDS: usize = 5;
+
+/// L2 strength unless `--l2` is given: the pull toward the current weights
+/// when tuning them, toward zero when training a logistic model.
+pub const DEFAULT_L2: f64 = 0.01;
+
+/// Gradient-descent step and iteration count; tuned on the fixture corpus,
+/// where the loss flattens out well before the last iteration.
+const LEARNING_RATE: f64 = 0.05;
+const ITERATIONS: usize = 400;
+
+/// Result of [`tune`].
+pub struct Tuning {
+    pub files: usize,
+    pub folds: usize,
+    /// Cross-validated accuracy of the current weights.
+    pub baseline_accuracy: f64,
+    /// Cross-validated accuracy of weights fitted on the other folds.
+    pub tuned_accuracy: f64,
+    /// Signal ID → weight fitted on every sample, for each signal that fired
+    /// in the corpus.  Signs are preserved.
+    pub weights: BTreeMap<String, f64>,
+    /// Signal ID → the weight each signal had in the corpus reports.
+    pub previous: BTreeMap<String, f64>,
+}
+
+impl Tuning {
+    /// `(id, previous, tuned)` for every tuned signal, largest change first.
+    pub fn changes(&self) -> Vec<(&str, f64, f64)> {
+        let mut changes: Vec<_> = self
+            .weights
+            .iter()
+            .map(|(id, &w)| (id.as_str(), self.previous[id], w))
+            .collect();
+        changes.sort_by(|a, b| (b.2 - b.1).abs().partial_cmp(&(a.2 - a.1).abs()).unwrap().then(a.0.cmp(b.0)));
+        changes
+    }
+
+    /// The tuned weights as a `[heuristics]` table, loadable through
+    /// `[weights] file` in `.vibecheck`.
+    pub fn to_toml(&self) -> String {
+        let mut out = format!(
+            "# Weights fitted by `vibecheck tune` on {} files.\n\
+             # {}-fold cross-validated accuracy: {:.1}% tuned, {:.1}% before tuning.\n\
+             # Load with `[weights] file = \"<this file>\"` in .vibecheck.\n\
+             [heuristics]\n",
+            self.files,
+            self.folds,
+            self.tuned_accuracy * 100.0,
+            self.baseline_accuracy * 100.0,
+        );
+        for (id, w) in &self.weights {
+            out.push_str(&format!("\"{id}\" = {w:.2}\n"));
+        }
+        out
+    }
+}
+
+/// A tunable signal: the family its weight adds to, and its current magnitude.
+struct Param {
+    target: ModelFamily,
+    prior: f64,
+    negative: bool,
+}
+
+/// Fit signal weights to `samples` with `folds`-fold cross-validation and an
+/// L2 penalty of `l2` toward the current weights.  `folds` is clamped to
+/// `2..=samples.len()`.
+pub fn tune(samples: &[Sample], folds: usize, l2: f64) -> Tuning {
+    let mut params: BTreeMap<String, Param> = BTreeMap::new();
+    for signal in samples.iter().flat_map(|s| &s.report.signals) {
+        if signal.id.is_empty() {
+            continue;
+        }
+        params.entry(signal.id.clone()).or_insert(Param {
+            target: if signal.weight < 0.0 { ModelFamily::Human } else { signal.family },
+            prior: signal.weight.abs(),
+            negative: signal.weight < 0.0,
+        });
+    }
+    let prior: HashMap<&str, f64> = params.iter().map(|(id, p)| (id.as_str(), p.prior)).collect();
+
+    let folds = folds.clamp(2, samples.len().max(2));
+    let (mut baseline_correct, mut tuned_correct) = (0, 0);
+    for (train, test) in split(samples, folds) {
+        let train: Vec<&Sample> = train.into_iter().map(|i| &samples[i]).collect();
+        let test: Vec<&Sample> = test.into_iter().map(|i| &samples[i]).collect();
+        let fitted = fit(&train, &params, l2);
+        baseline_correct += correct(&test, &params, &prior);
+        tuned_correct += correct(&test, &params, &fitted);
+    }
+
+    let all: Vec<&Sample> = samples.iter().collect();
+    let fitted = fit(&all, &params, l2);
+    let signed = |id: &str, m: f64| if params[id].negative { -m } else { m };
+    let total = samples.len().max(1) as f64;
+    Tuning {
+        files: samples.len(),
+        folds,
+        baseline_accuracy: baseline_correct as f64 / total,
+        tuned_accuracy: tuned_correct as f64 / total,
+        weights: params.keys().map(|id| (id.clone(), signed(id, fitted[id.as_str()]))).collect(),
+        previous: params.iter().map(|(id, p)| (id.clone(), signed(id, p.prior))).collect(),
+    }
+}
+
+/// Result of [`train_logistic`].
+pub struct LogisticTraining {
+    pub files: usize,
+    pub folds: usize,
+    /// Cross-validated accuracy of the weighted-sum classifier.
+    pub baseline_accuracy: f64,
+    /// Cross-validated accuracy of models fitted on the other folds.
+    pub accuracy: f64,
+    /// The model fitted on every sample.
+    pub model: LogisticClassifier,
+}
+
+impl LogisticTraining {
+    /// The model file, loadable through `[classifier] model` in `.vibecheck`.
+    pub fn to_toml(&self) -> anyhow::Result<String> {
+        Ok(format!(
+            "# Logistic model fitted by `vibecheck tune --classifier logistic` on {} files.\n\
+             # {}-fold cross-validated accuracy: {:.1}%, {:.1}% for the weighted sum.\n\
+             # Load with `[classifier] backend = \"logistic\"` and `model = \"<this file>\"` in .vibecheck.\n{}",
+            self.files,
+            self.folds,
+            self.accuracy * 100.0,
+            self.baseline_accuracy * 100.0,
+            self.model.to_toml()?,
+        ))
+    }
+}
+
+/// Fit a [`LogisticClassifier`] to `samples` with `folds`-fold
+/// cross-validation and an L2 penalty of `l2`.  `folds` is clamped to
+/// `2..=samples.len()`.
+pub fn train_logistic(samples: &[Sample], folds: usize, l2: f64) -> LogisticTraining {
+    let fit = |train: &[usize]| {
+        let rows: Vec<(&[Signal], ModelFamily)> =
+            train.iter().map(|&i| (&samples[i].report.signals[..], samples[i].label)).collect();
+        LogisticClassifier::fit(&rows, l2)
+    };
+    let folds = folds.clamp(2, samples.len().max(2));
+    let pipeline = Pipeline::with_defaults();
+    let (mut baseline_correct, mut correct) = (0, 0);
+    for (train, test) in split(samples, folds) {
+        let model = fit(&train);
+        for s in test.into_iter().map(|i| &samples[i]) {
+            baseline_correct += usize::from(pipeline.aggregate(&s.report.signals).primary == s.label);
+            let attribution = model.classify(&s.report.signals, &HashMap::new(), None, "");
+            correct += usize::from(attribution.primary == s.label);
+        }
+    }
+
+    let all: Vec<usize> = (0..samples.len()).collect();
+    let total = samples.len().max(1) as f64;
+    LogisticTraining {
+        files: samples.len(),
+        folds,
+        baseline_accuracy: baseline_correct as f64 / total,
+        accuracy: correct as f64 / total,
+        model: fit(&all),
+    }
+}
+
+/// Result of [`train_prototypes`].
+pub struct PrototypeTraining {
+    pub files: usize,
+    pub folds: usize,
+    /// Cross-validated accuracy of the weighted-sum classifier.
+    pub baseline_accuracy: f64,
+    /// Cross-validated accuracy of prototypes fitted on the other folds,
+    /// blended with the weighted sum.
+    pub accuracy: f64,
+    /// The prototypes fitted on every sample.
+    pub prototypes: Prototypes,
+}
+
+/// Fit per-family prototypes to `samples`, whose code embeddings are
+/// `embeddings` (in the same order), with `folds`-fold cross-validation.
+/// `blend` is the embedding's share of the blended scores.
+pub fn train_prototypes(samples: &[Sample], embeddings: &[Vec<f32>], folds: usize, blend: f64) -> PrototypeTraining {
+    let fit = |train: &[usize]| {
+        let rows: Vec<(&[f32], ModelFamily)> = train.iter().map(|&i| (&embeddings[i][..], samples[i].label)).collect();
+        Prototypes::fit(&rows, blend)
+    };
+    let folds = folds.clamp(2, samples.len().max(2));
+    let pipeline = Pipeline::with_defaults();
+    let (mut baseline_correct, mut correct) = (0, 0);
+    for (train, test) in split(samples, folds) {
+        let prototypes = fit(&train);
+        for i in test {
+            let s = &samples[i];
+            baseline_correct += usize::from(pipeline.aggregate(&s.report.signals).primary == s.label);
+            correct += usize::from(prototypes.attribution(&embeddings[i], &s.report.signals).primary == s.label);
+        }
+    }
+
+    let all: Vec<usize> = (0..samples.len()).collect();
+    let total = samples.len().max(1) as f64;
+    PrototypeTraining {
+        files: samples.len(),
+        folds,
+        baseline_accuracy: baseline_correct as f64 / total,
+        accuracy: correct as f64 / total,
+        prototypes: fit(&all),
+    }
+}
+
+/// Result of [`train_stylometry`].
+pub struct StylometryTraining {
+    pub files: usize,
+    pub folds: usize,
+    /// Cross-validated accuracy of the weighted-sum classifier.
+    pub baseline_accuracy: f64,
+    /// Cross-validated accuracy with the signal of an n-gram model trained
+    /// on the other folds added.
+    pub accuracy: f64,
+    /// Held-out files the model fired on.
+    pub fired: usize,
+    /// The model trained on every sample.
+    pub model: NgramModel,
+}
+
+/// Train a comment n-gram model on `samples`, whose source texts are
+/// `sources` (in the same order), with `folds`-fold cross-validation.
+pub fn train_stylometry(samples: &[Sample], sources: &[String], folds: usize) -> StylometryTraining {
+    let prose: Vec<String> = samples
+        .iter()
+        .zip(sources)
+        .map(|(s, source)| comment_prose(source, detect_language(&s.path)))
+        .collect();
+    let fit = |train: &[usize]| NgramModel::train(train.iter().map(|&i| (samples[i].label, prose[i].as_str())));
+    let folds = folds.clamp(2, samples.len().max(2));
+    let pipeline = Pipeline::with_defaults();
+    let (mut baseline_correct, mut correct, mut fired) = (0, 0, 0);
+    for (train, test) in split(samples, folds) {
+        let analyzer = StylometryAnalyzer::new(Arc::new(fit(&train)), DEFAULT_MARGIN);
+        for i in test {
+            let s = &samples[i];
+            let extra = analyzer.analyze_with_language(&sources[i], detect_language(&s.path));
+            fired += usize::from(!extra.is_empty());
+            let signals: Vec<Signal> = s.report.signals.iter().cloned().chain(extra).collect();
+            baseline_correct += usize::from(pipeline.aggregate(&s.report.signals).primary == s.label);
+            correct += usize::from(pipeline.aggregate(&signals).primary == s.label);
+        }
+    }
+
+    let all: Vec<usize> = (0..samples.len()).collect();
+    let total = samples.len().max(1) as f64;
+    StylometryTraining {
+        files: samples.len(),
+        folds,
+        baseline_accuracy: baseline_correct as f64 / total,
+        accuracy: correct as f64 / total,
+        fired,
+        model: fit(&all),
+    }
+}
+
+/// Result of [`train_calibration`].
+pub struct CalibrationTraining {
+    /// Files with signal data to calibrate.
+    pub files: usize,
+    pub folds: usize,
+    /// Cross-validated Brier score of one calibration for every language.
+    pub baseline_brier: f64,
+    /// Cross-validated Brier score of per-language calibrations.
+    pub brier: f64,
+    /// Language (`other` for files without one) → the same, over its files.
+    pub languages: BTreeMap<String, LanguageBrier>,
+    /// The calibration fitted on every file.
+    pub calibration: Calibration,
+}
+
+/// Cross-validated Brier scores over one language's files.
+pub struct LanguageBrier {
+    pub files: usize,
+    pub baseline: f64,
+    pub calibrated: f64,
+}
+
+impl CalibrationTraining {
+    /// The calibration file, loadable through `[calibration] file` in
+    /// `.vibecheck`.
+    pub fn to_toml(&self) -> anyhow::Result<String> {
+        Ok(format!(
+            "# Calibration fitted by `vibecheck tune --classifier calibration` on {} files.\n\
+             # {}-fold cross-validated Brier score: {:.4}, {:.4} with one calibration for every language.\n\
+             # Load with `[calibration] file = \"<this file>\"` in .vibecheck.\n{}",
+            self.files,
+            self.folds,
+            self.brier,
+            self.baseline_brier,
+            self.calibration.to_toml()?,
+        ))
+    }
+}
+
+/// Fit a per-language [`Calibration`] to `samples` with `folds`-fold
+/// cross-validation, comparing it with a single calibration fitted on the
+/// same folds.  Files without signal data are left out, as the pipeline
+/// leaves them uncalibrated.
+pub fn train_calibration(samples: &[Sample], folds: usize) -> CalibrationTraining {
+    let rows: Vec<(Option<String>, f64, bool)> = samples
+        .iter()
+        .map(|s| (language_key(&s.path), ai_share(&s.report.attribution), s.label != ModelFamily::Human))
+        .collect();
+    let usable = |i: &usize| samples[*i].report.attribution.has_sufficient_data();
+    let fit = |train: &[usize]| {
+        let rows: Vec<(Option<&str>, f64, bool)> =
+            train.iter().filter(|i| usable(i)).map(|&i| (rows[i].0.as_deref(), rows[i].1, rows[i].2)).collect();
+        Calibration::fit(&rows)
+    };
+    let folds = folds.clamp(2, samples.len().max(2));
+    let mut languages: BTreeMap<String, LanguageBrier> = BTreeMap::new();
+    for (train, test) in split(samples, folds) {
+        let calibration = fit(&train);
+        let single = Calibration { languages: BTreeMap::new(), ..calibration.clone() };
+        for i in test.into_iter().filter(usable) {
+            let (language, x, ai) = &rows[i];
+            let error = |c: &Calibration| (c.model(language.as_deref()).probability(*x) - f64::from(u8::from(*ai))).powi(2);
+            let entry = languages.entry(language.clone().unwrap_or_else(|| "other".into())).or_insert(LanguageBrier {
+                files: 0,
+                baseline: 0.0,
+                calibrated: 0.0,
+            });
+            entry.files += 1;
+            entry.baseline += error(&single);
+            entry.calibrated += error(&calibration);
+        }
+    }
+
+    let files: usize = languages.values().map(|l| l.files).sum();
+    let total = files.max(1) as f64;
+    let baseline_brier = languages.values().map(|l| l.baseline).sum::<f64>() / total;
+    let brier = languages.values().map(|l| l.calibrated).sum::<f64>() / total;
+    for l in languages.values_mut() {
+        l.baseline /= l.files as f64;
+        l.calibrated /= l.files as f64;
+    }
+    let all: Vec<usize> = (0..samples.len()).collect();
+    CalibrationTraining { files, folds, baseline_brier, brier, languages, calibration: fit(&all) }
+}
+
+/// `(train, test)` indices for each of `folds` stratified folds:
+/// round-robin over samples sorted by label.
+fn split(samples: &[Sample], folds: usize) -> Vec<(Vec<usize>, Vec<usize>)> {
+    let mut order: Vec<usize> = (0..samples.len()).collect();
+    order.sort_by_key(|&i| (ModelFamily::all().iter().position(|&f| f == samples[i].label), samples[i].path.clone()));
+    let fold_of: HashMap<usize, usize> = order.iter().enumerate().map(|(n, &i)| (i, n % folds)).collect();
+    (0..folds)
+        .map(|fold| {
+            let (test, train): (Vec<usize>, Vec<usize>) = (0..samples.len()).partition(|i| fold_of[i] == fold);
+            (train, test)
+        })
+        .collect()
+}
+
+/// Proximal gradient descent on the mean softmax cross-entropy of the
+/// per-family weight sums, starting from and regularized toward the priors.
+/// The L2 term is applied in closed form, which keeps the step stable however
+/// strong the penalty; magnitudes are then clamped at zero.
+fn fit<'a>(samples: &[&Sample], params: &'a BTreeMap<String, Param>, l2: f64) -> HashMap<&'a str, f64> {
+    let mut m: HashMap<&str, f64> = params.iter().map(|(id, p)| (id.as_str(), p.prior)).collect();
+    if samples.is_empty() {
+        return m;
+    }
+    let n = samples.len() as f64;
+    for _ in 0..ITERATIONS {
+        let mut grad: HashMap<&str, f64> = params.keys().map(|id| (id.as_str(), 0.0)).collect();
+        for sample in samples {
+            let p = softmax(&raw_scores(&sample.report.signals, params, &m));
+            for signal in sample.report.signals.iter().filter(|s| !s.id.is_empty()) {
+                let (id, param) = params.get_key_value(&signal.id).unwrap();
+                let target = ModelFamily::all().iter().position(|&f| f == param.target).unwrap();
+                let observed = if sample.label == param.target { 1.0 } else { 0.0 };
+                *grad.get_mut(id.as_str()).unwrap() += (p[target] - observed) / n;
+            }
+        }
+        let shrink = 2.0 * LEARNING_RATE * l2;
+        for (id, g) in grad {
+            let w = m.get_mut(id).unwrap();
+            *w = ((*w - LEARNING_RATE * g + shrink * params[id].prior) / (1.0 + shrink)).max(0.0);
+        }
+    }
+    m
+}
+
+/// Per-family sums in [`ModelFamily::all`] order, with tunable signals
+/// weighted by `m`.  Counter-evidence adds to Human, which ranks families
+/// exactly as subtracting from every AI family does.
+fn raw_scores(signals: &[Signal], params: &BTreeMap<String, Param>, m: &HashMap<&str, f64>) -> Vec<f64> {
+    let families = ModelFamily::all();
+    let mut raw = vec![0.0; families.len()];
+    for signal in signals {
+        let (family, w) = match params.get(&signal.id) {
+            Some(p) => (p.target, m[signal.id.as_str()]),
+            None if signal.weight < 0.0 => (ModelFamily::Human, -signal.weight),
+            None => (signal.family, signal.weight),
+        };
+        raw[families.iter().position(|&f| f == family).unwrap()] += w;
+    }
+    raw
+}
+
+fn softmax(x: &[f64]) -> Vec<f64> {
+    let max = x.iter().cloned().fold(f64::NEG_INFINITY, f64::max);
+    let exp: Vec<f64> = x.iter().map(|v| (v - max).exp()).collect();
+    let total: f64 = exp.iter().sum();
+    exp.into_iter().map(|e| e / total).collect()
+}
+
+/// Samples in `test` the pipeline attributes correctly under weights `m`.
+fn correct(test: &[&Sample], params: &BTreeMap<String, Param>, m: &HashMap<&str, f64>) -> usize {
+    let pipeline = Pipeline::with_defaults();
+    test.iter()
+        .filter(|s| {
+            let reweighted: Vec<Signal> = s
+                .report
+                .signals
+                .iter()
+                .map(|sig| {
+                    let mut sig = sig.clone();
+                    if let Some(p) = params.get(&sig.id) {
+                        let w = m[sig.id.as_str()];
+                        sig.weight = if p.negative { -w } else { w };
+                    }
+                    sig
+                })
+                .collect();
+            pipeline.aggregate(&reweighted).primary == s.label
+        })
+        .count()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::report::Authorship;
+    use std::path::PathBuf;
+
+    fn sample(label: ModelFamily, signals: Vec<Signal>) -> Sample {
+        let mut report = crate::analyze("");
+        report.attribution = Pipeline::with_defaults().aggregate(&signals);
+        report.signals = signals;
+        Sample { path: PathBuf::from(format!("{label}.rs")), label, authorship: Authorship::of_label(label), report }
+    }
+
+    /// `a` is evidence for Claude but also fires in GPT files, as does the
+    /// Claude signal `c`; with equal weights those GPT files go to Claude.
+    fn corpus() -> Vec<Sample> {
+        let a = || Signal::new("t.a", "t", "", ModelFamily::Claude, 1.0);
+        let b = || Signal::new("t.b", "t", "", ModelFamily::Gpt, 1.0);
+        let c = || Signal::new("t.c", "t", "", ModelFamily::Claude, 1.0);
+        let h = || Signal::new("t.h", "t", "", ModelFamily::Claude, -1.0);
+        let mut samples = Vec::new();
+        for _ in 0..4 {
+            samples.push(sample(ModelFamily::Claude, vec![a()]));
+            samples.push(sample(ModelFamily::Gpt, vec![a(), b(), c()]));
+            samples.push(sample(ModelFamily::Human, vec![h()]));
+        }
+        samples
+    }
+
+    #[test]
+    fn tuning_fixes_confusable_signals_and_keeps_signs() {
+        let tuning = tune(&corpus(), 4, DEFAULT_L2);
+        assert_eq!((tuning.files, tuning.folds), (12, 4));
+        assert!(tuning.tuned_accuracy > tuning.baseline_accuracy, "{} <= {}", tuning.tuned_accuracy, tuning.baseline_accuracy);
+        assert!(tuning.weights["t.b"] > tuning.weights["t.a"] + tuning.weights["t.c"]);
+        assert!(tuning.weights["t.h"] < 0.0);
+        assert_eq!(tuning.previous["t.h"], -1.0);
+        assert_eq!(tuning.changes().len(), 4);
+    }
+
+    #[test]
+    fn strong_regularization_keeps_current_weights() {
+        let tuning = tune(&corpus(), DEFAULT_FOLDS, 1e6);
+        for (id, previous, tuned) in tuning.changes() {
+            assert!((previous - tuned).abs() < 1e-3, "{id}: {previous} -> {tuned}");
+        }
+    }
+
+    #[test]
+    fn logistic_training_learns_confusable_signals() {
+        let training = train_logistic(&corpus(), 4, DEFAULT_L2);
+        assert_eq!((training.files, training.folds), (12, 4));
+        assert!(training.accuracy > training.baseline_accuracy, "{} <= {}", training.accuracy, training.baseline_accuracy);
+        let model: LogisticClassifier = toml::from_str(&training.to_toml().unwrap()).unwrap();
+        assert!(model.coefficients["b"][&ModelFamily::Gpt] > model.coefficients["b"][&ModelFamily::Claude]);
+    }
+
+    #[test]
+    fn prototype_training_separates_embeddings() {
+        let samples = corpus();
+        // The embedding tells Claude and GPT apart where the signals cannot.
+        let embeddings: Vec<Vec<f32>> = samples
+            .iter()
+            .map(|s| match s.label {
+                ModelFamily::Claude => vec![1.0, 0.1, 0.0],
+                ModelFamily::Gpt => vec![0.1, 1.0, 0.0],
+                _ => vec![0.0, 0.1, 1.0],
+            })
+            .collect();
+        let training = train_prototypes(&samples, &embeddings, 4, 1.0);
+        assert_eq!(training.accuracy, 1.0);
+        assert!(training.baseline_accuracy < 1.0);
+        assert_eq!(training.prototypes.families.len(), 3);
+    }
+
+    #[test]
+    fn calibration_training_fits_languages_apart() {
+        // The same AI share reads as AI-written in Python but human in Go.
+        let mut samples = Vec::new();
+        for i in 0..12 {
+            let jitter = i as f64 * 0.01;
+            for (ext, ai_share, label) in
+                [("go", 0.8, ModelFamily::Claude), ("go", 0.5, ModelFamily::Human), ("py", 0.5, ModelFamily::Gpt), ("py", 0.2, ModelFamily::Human)]
+            {
+                let mut s = sample(label, vec![]);
+                s.path = PathBuf::from(format!("{i}/{label}.{ext}"));
+                s.report.attribution.confidence = 0.5;
+                s.report.attribution.scores =
+                    BTreeMap::from([(ModelFamily::Human, 1.0 - ai_share - jitter), (ModelFamily::Claude, ai_share + jitter)]);
+                samples.push(s);
+            }
+        }
+        let training = train_calibration(&samples, 3);
+        assert_eq!((training.files, training.folds), (48, 3));
+        assert!(training.brier < training.baseline_brier, "{} >= {}", training.brier, training.baseline_brier);
+        assert_eq!(training.languages.keys().collect::<Vec<_>>(), vec!["go", "python"]);
+        assert_eq!(training.calibration.languages.len(), 2);
+        let loaded: Calibration = toml::from_str(&training.to_toml().unwrap()).unwrap();
+        assert_eq!(loaded, training.calibration);
+    }
+
+    #[test]
+    fn stylometry_is_trained_on_comment_prose() {
+        let narration = "// Here we iterate over each item in the list to ensure it is valid.\n".repeat(6);
+        let terse = "// hack: skip dupes, upstream sends them twice\n".repeat(6);
+        let samples: Vec<Sample> = (0..4)
+            .flat_map(|_| [sample(ModelFamily::Claude, vec![]), sample(ModelFamily::Human, vec![])])
+            .collect();
+        let sources: Vec<String> = samples
+            .iter()
+            .map(|s| if s.label == ModelFamily::Claude { narration.clone() } else { terse.clone() })
+            .collect();
+        let training = train_stylometry(&samples, &sources, 2);
+        assert_eq!(training.fired, samples.len());
+        assert_eq!(training.accuracy, 1.0);
+        assert_eq!(training.model.families.len(), 2);
+    }
+
+    #[test]
+    fn toml_output_is_a_heuristics_table() {
+        let toml = tune(&corpus(), 2, DEFAULT_L2).to_toml();
+        let parsed: toml::Value = toml::from_str(&toml).unwrap();
+        assert!(parsed["heuristics"]["t.h"].as_float().unwrap() < 0.0);
+    }
+}
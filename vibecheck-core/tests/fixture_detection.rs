@@ -127,3 +127,32 @@ fn copilot_javascript() {
 fn copilot_go() {
     assert_fixture("lru_cache/copilot.go", ModelFamily::Copilot);
 }
+
+// ── Probability calibration ───────────────────────────────────────────
+
+#[test]
+fn calibration_separates_fixture_corpus() {
+    use vibecheck_core::calibration::{ai_share, Platt, FIXTURE_CALIBRATION};
+
+    let dir = Path::new(env!("CARGO_MANIFEST_DIR")).join("tests/fixtures/lru_cache");
+    let mut samples = Vec::new();
+    for entry in std::fs::read_dir(&dir).unwrap().flatten() {
+        let path = entry.path();
+        let report = vibecheck_core::analyze_file_no_cache(&path).unwrap();
+        let is_ai = !path.file_stem().is_some_and(|s| s == "human");
+        let p = report.attribution.ai_probability.expect("fixtures have signal data");
+        assert_eq!(p > 0.5, is_ai, "{}: AI probability {p:.2}", path.display());
+        samples.push((ai_share(&report.attribution), is_ai));
+    }
+
+    // The shipped constants should fit nearly as well as a fresh fit.
+    let brier = |m: &Platt| {
+        samples.iter().map(|&(x, ai)| (m.probability(x) - if ai { 1.0 } else { 0.0 }).powi(2)).sum::<f64>()
+            / samples.len() as f64
+    };
+    let refit = Platt::fit(&samples);
+    assert!(
+        brier(&FIXTURE_CALIBRATION) - brier(&refit) < 0.01,
+        "FIXTURE_CALIBRATION is stale; refit gives {refit:?}"
+    );
+}
@@ -0,0 +1,156 @@
+package lrucache;
+
+import java.util.HashMap;
+import java.util.Map;
+import java.util.Optional;
+
+/**
+ * A fixed-capacity key-value store that automatically evicts the least
+ * recently accessed entry when the capacity is exceeded.
+ *
+ * <p>It combines a doubly-linked list for recency tracking with a hash map
+ * for constant-time key lookups, so every operation runs in O(1) time.
+ *
+ * @param <K> the type of keys maintained by this cache
+ * @param <V> the type of cached values
+ */
+public final class LeastRecentlyUsedCache<K, V> {
+
+    /**
+     * A single node within the doubly-linked recency list. Each node keeps
+     * its key so that we can remove the map entry during eviction without
+     * a reverse lookup.
+     */
+    private static final class CacheNode<K, V> {
+        private final K entryKey;
+        private V entryValue;
+        private CacheNode<K, V> previousNode;
+        private CacheNode<K, V> nextNode;
+
+        CacheNode(K entryKey, V entryValue) {
+            this.entryKey = entryKey;
+            this.entryValue = entryValue;
+        }
+    }
+
+    private final int maximumCapacity;
+    private final Map<K, CacheNode<K, V>> entryLookupTable;
+    private final CacheNode<K, V> sentinelHead;
+    private final CacheNode<K, V> sentinelTail;
+
+    /**
+     * Creates a cache with the given maximum capacity.
+     *
+     * @param maximumCapacity the number of entries the cache may hold; must be
+     *                        at least one
+     * @throws IllegalArgumentException if {@code maximumCapacity} is not positive
+     */
+    public LeastRecentlyUsedCache(int maximumCapacity) {
+        if (maximumCapacity < 1) {
+            throw new IllegalArgumentException("Capacity must be at least one, got " + maximumCapacity);
+        }
+        this.maximumCapacity = maximumCapacity;
+        this.entryLookupTable = new HashMap<>(maximumCapacity);
+        this.sentinelHead = new CacheNode<>(null, null);
+        this.sentinelTail = new CacheNode<>(null, null);
+        this.sentinelHead.nextNode = this.sentinelTail;
+        this.sentinelTail.previousNode = this.sentinelHead;
+    }
+
+    /**
+     * Looks up the value for the given key. Accessing an entry promotes it to
+     * the most-recently-used position, which protects it from near-term
+     * eviction.
+     *
+     * @param cacheKey the key whose associated value is to be returned
+     * @return the cached value, or an empty {@link Optional} if the key is absent
+     */
+    public Optional<V> retrieveEntry(K cacheKey) {
+        CacheNode<K, V> foundNode = entryLookupTable.get(cacheKey);
+        if (foundNode == null) {
+            return Optional.empty();
+        }
+        promoteToFront(foundNode);
+        return Optional.ofNullable(foundNode.entryValue);
+    }
+
+    /**
+     * Adds or updates a key-value pair in the cache. If the key already
+     * exists, its value is replaced and the entry is promoted. If the cache is
+     * at capacity, the least recently used entry is evicted first, which
+     * ensures the cache never exceeds its configured bound.
+     *
+     * @param cacheKey   the key with which the value is to be associated
+     * @param cacheValue the value to be cached
+     */
+    public void insertEntry(K cacheKey, V cacheValue) {
+        CacheNode<K, V> existingNode = entryLookupTable.get(cacheKey);
+        if (existingNode != null) {
+            existingNode.entryValue = cacheValue;
+            promoteToFront(existingNode);
+            return;
+        }
+
+        if (entryLookupTable.size() >= maximumCapacity) {
+            evictLeastRecentlyUsedEntry();
+        }
+
+        CacheNode<K, V> newNode = new CacheNode<>(cacheKey, cacheValue);
+        attachAfterHead(newNode);
+        entryLookupTable.put(cacheKey, newNode);
+    }
+
+    /**
+     * Returns the number of entries currently stored in the cache.
+     *
+     * @return the current entry count
+     */
+    public int currentSize() {
+        return entryLookupTable.size();
+    }
+
+    /**
+     * Moves an existing node to the most-recently-used position.
+     *
+     * @param targetNode the node to promote
+     */
+    private void promoteToFront(CacheNode<K, V> targetNode) {
+        detachNode(targetNode);
+        attachAfterHead(targetNode);
+    }
+
+    /**
+     * Removes the least recently used entry, which always sits just before
+     * the tail sentinel.
+     */
+    private void evictLeastRecentlyUsedEntry() {
+        CacheNode<K, V> leastRecentNode = sentinelTail.previousNode;
+        if (leastRecentNode == sentinelHead) {
+            return;
+        }
+        detachNode(leastRecentNode);
+        entryLookupTable.remove(leastRecentNode.entryKey);
+    }
+
+    /**
+     * Unlinks a node from its neighbours in the recency list.
+     *
+     * @param targetNode the node to unlink
+     */
+    private void detachNode(CacheNode<K, V> targetNode) {
+        targetNode.previousNode.nextNode = targetNode.nextNode;
+        targetNode.nextNode.previousNode = targetNode.previousNode;
+    }
+
+    /**
+     * Links a node directly after the head sentinel.
+     *
+     * @param targetNode the node to link
+     */
+    private void attachAfterHead(CacheNode<K, V> targetNode) {
+        targetNode.previousNode = sentinelHead;
+        targetNode.nextNode = sentinelHead.nextNode;
+        sentinelHead.nextNode.previousNode = targetNode;
+        sentinelHead.nextNode = targetNode;
+    }
+}
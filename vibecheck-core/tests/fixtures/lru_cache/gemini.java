@@ -0,0 +1,84 @@
+package cache;
+
+import java.util.HashMap;
+import java.util.Map;
+
+/**
+ * A simple LRU cache.
+ */
+public class LruCache<K, V> {
+
+    private static class Entry<K, V> {
+        final K key;
+        V value;
+        Entry<K, V> prev;
+        Entry<K, V> next;
+
+        Entry(K key, V value) {
+            this.key = key;
+            this.value = value;
+        }
+    }
+
+    private final int capacity;
+    private final Map<K, Entry<K, V>> entries = new HashMap<>();
+    private final Entry<K, V> head = new Entry<>(null, null);
+    private final Entry<K, V> tail = new Entry<>(null, null);
+
+    public LruCache(int capacity) {
+        this.capacity = capacity;
+        head.next = tail;
+        tail.prev = head;
+    }
+
+    public V get(K key) {
+        Entry<K, V> entry = entries.get(key);
+        if (entry == null) {
+            return null;
+        }
+        moveToFront(entry);
+        return entry.value;
+    }
+
+    public void put(K key, V value) {
+        Entry<K, V> entry = entries.get(key);
+        if (entry != null) {
+            entry.value = value;
+            moveToFront(entry);
+            return;
+        }
+        if (entries.size() == capacity) {
+            evict();
+        }
+        entry = new Entry<>(key, value);
+        entries.put(key, entry);
+        addFirst(entry);
+    }
+
+    public int size() {
+        return entries.size();
+    }
+
+    private void moveToFront(Entry<K, V> entry) {
+        unlink(entry);
+        addFirst(entry);
+    }
+
+    private void evict() {
+        Entry<K, V> last = tail.prev;
+        unlink(last);
+        entries.remove(last.key);
+    }
+
+    private void unlink(Entry<K, V> entry) {
+        entry.prev.next = entry.next;
+        entry.next.prev = entry.prev;
+    }
+
+    private void addFirst(Entry<K, V> entry) {
+        entry.next = head.next;
+        entry.prev = head;
+        head.next.prev = entry;
+        head.next = entry;
+    }
+}
@@ -0,0 +1,135 @@
+package com.example.cache;
+
+import java.util.HashMap;
+import java.util.Map;
+
+/**
+ * LRUCache implements a Least Recently Used (LRU) cache.
+ *
+ * <p>Key features:
+ * <ul>
+ *   <li>O(1) get and put operations</li>
+ *   <li>Automatic eviction of the least recently used entry</li>
+ *   <li>Configurable capacity</li>
+ * </ul>
+ */
+public class LRUCache<K, V> {
+
+    /**
+     * Node represents an entry in the doubly linked list.
+     */
+    private class Node {
+        K key;
+        V value;
+        Node prev;
+        Node next;
+
+        Node(K key, V value) {
+            this.key = key;
+            this.value = value;
+        }
+    }
+
+    private final int capacity;
+    private final Map<K, Node> map;
+    private final Node head;
+    private final Node tail;
+
+    /**
+     * Constructs a new LRUCache with the specified capacity.
+     *
+     * @param capacity the maximum number of entries
+     */
+    public LRUCache(int capacity) {
+        if (capacity <= 0) {
+            throw new IllegalArgumentException("Capacity must be greater than zero");
+        }
+        this.capacity = capacity;
+        this.map = new HashMap<>();
+        this.head = new Node(null, null);
+        this.tail = new Node(null, null);
+        head.next = tail;
+        tail.prev = head;
+    }
+
+    /**
+     * Retrieves the value associated with the given key.
+     *
+     * @param key the key to look up
+     * @return the value, or null if the key does not exist
+     */
+    public V get(K key) {
+        // Step 1: Check if the key exists in the map
+        Node node = map.get(key);
+        if (node == null) {
+            return null;
+        }
+
+        // Step 2: Move the accessed node to the front
+        removeNode(node);
+        addToFront(node);
+
+        // Step 3: Return the value
+        return node.value;
+    }
+
+    /**
+     * Inserts or updates a key-value pair in the cache.
+     *
+     * @param key   the key
+     * @param value the value
+     */
+    public void put(K key, V value) {
+        // Step 1: Update the value if the key already exists
+        Node node = map.get(key);
+        if (node != null) {
+            node.value = value;
+            removeNode(node);
+            addToFront(node);
+            return;
+        }
+
+        // Step 2: Evict the least recently used entry if needed
+        if (map.size() >= capacity) {
+            Node lru = tail.prev;
+            removeNode(lru);
+            map.remove(lru.key);
+        }
+
+        // Step 3: Insert the new node
+        Node newNode = new Node(key, value);
+        addToFront(newNode);
+        map.put(key, newNode);
+    }
+
+    /**
+     * Returns the current number of entries in the cache.
+     *
+     * @return the size of the cache
+     */
+    public int size() {
+        return map.size();
+    }
+
+    /**
+     * Removes a node from the linked list.
+     *
+     * @param node the node to remove
+     */
+    private void removeNode(Node node) {
+        node.prev.next = node.next;
+        node.next.prev = node.prev;
+    }
+
+    /**
+     * Adds a node right after the head.
+     *
+     * @param node the node to add
+     */
+    private void addToFront(Node node) {
+        node.next = head.next;
+        node.prev = head;
+        head.next.prev = node;
+        head.next = node;
+    }
+}
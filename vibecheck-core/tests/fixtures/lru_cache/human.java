@@ -0,0 +1,37 @@
+package cfg.cache;
+
+import java.util.LinkedHashMap;
+import java.util.Map;
+
+// LRU for the cfg service lookups - see CFG-1187
+// TODO: swap for caffeine once we drop the java 8 build
+
+/** Basic LRU. Not thread-safe, wrap it if you share it. */
+public class Lru<K, V> extends LinkedHashMap<K, V> {
+    private static final long serialVersionUID = 1L;
+
+    private final int cap;
+
+    public Lru(int cap) {
+        super(16, 0.75f, true); // true = access order, that's the whole trick
+        this.cap = cap > 0 ? cap : 1;
+    }
+
+    @Override
+    protected boolean removeEldestEntry(Map.Entry<K, V> e) {
+        return size() > cap;
+    }
+
+    // hot path, callers expect null on miss (legacy api)
+    public V peek(K k) {
+        V v = get(k);
+        return v;
+    }
+
+    public int cap() { return cap; }
+
+    //public void resize(int n) {
+    //    cap = n;
+    //    while (size() > cap) remove(keySet().iterator().next());
+    //}
+}
@@ -0,0 +1,242 @@
+//! The rule × language matrix.
+//!
+//! Every rule in `heuristics.toml` claims a language.  These tests run every
+//! text rule against every input in that language — the fixtures under
+//! `tests/fixtures/` plus the snippets in `tests/rule_matrix.toml` — and fail
+//! when a rule never fires, so a detector that silently no-ops on a language
+//! it claims to support is caught here rather than by degraded attribution.
+//! CST rules are checked one level down: the language's extractor must
+//! produce the metric the rule matches on.
+
+use std::collections::{BTreeSet, HashMap, HashSet};
+use std::path::{Path, PathBuf};
+
+use vibecheck_core::analyzers::default_cst_analyzers;
+use vibecheck_core::frontend::{generic_metrics, LanguageFrontend, BUILTIN_FRONTENDS};
+use vibecheck_core::heuristics::{all_heuristics, HeuristicLanguage};
+use vibecheck_core::language::{detect_language, Language};
+use vibecheck_core::pipeline::Pipeline;
+use vibecheck_core::report::Report;
+
+/// A snippet from `rule_matrix.toml` and the signals it must produce.
+struct Case {
+    name: String,
+    language: HeuristicLanguage,
+    source: String,
+    fires: Vec<String>,
+}
+
+impl Case {
+    /// A file name the pipeline dispatches to the case's language.
+    fn path(&self) -> PathBuf {
+        let ext = match self.language {
+            HeuristicLanguage::Rust => "rs",
+            HeuristicLanguage::Python => "py",
+            HeuristicLanguage::Js => "js",
+            HeuristicLanguage::Go => "go",
+            other => panic!("case `{}`: no text analyzers for `{other}`", self.name),
+        };
+        PathBuf::from(format!("{}.{ext}", self.name))
+    }
+}
+
+fn cases() -> Vec<Case> {
+    let path = Path::new(env!("CARGO_MANIFEST_DIR")).join("tests/rule_matrix.toml");
+    let text = std::fs::read_to_string(&path).expect("read rule_matrix.toml");
+    let doc: toml::Table = text.parse().expect("parse rule_matrix.toml");
+    let str_field = |case: &toml::Value, key: &str| {
+        case.get(key)
+            .and_then(|v| v.as_str())
+            .unwrap_or_else(|| panic!("case missing `{key}`: {case:?}"))
+            .to_string()
+    };
+    doc["case"]
+        .as_array()
+        .expect("[[case]] entries")
+        .iter()
+        .map(|case| {
+            let name = str_field(case, "name");
+            let language = serde_json::from_value(serde_json::Value::String(str_field(case, "language")))
+                .unwrap_or_else(|e| panic!("case `{name}`: {e}"));
+            let fires = case
+                .get("fires")
+                .and_then(|v| v.as_array())
+                .unwrap_or_else(|| panic!("case `{name}` missing `fires`"))
+                .iter()
+                .map(|v| v.as_str().expect("signal id").to_string())
+                .collect();
+            Case { name, language, source: str_field(case, "source"), fires }
+        })
+        .collect()
+}
+
+/// Every fixture file in a built-in language, with its source.
+fn fixtures() -> Vec<(PathBuf, Language, String)> {
+    fn walk(dir: &Path, out: &mut Vec<(PathBuf, Language, String)>) {
+        let mut entries: Vec<_> = std::fs::read_dir(dir).unwrap().flatten().map(|e| e.path()).collect();
+        entries.sort();
+        for path in entries {
+            if path.is_dir() {
+                walk(&path, out);
+            } else if let Some(lang) = detect_language(&path) {
+                let source = std::fs::read_to_string(&path).unwrap();
+                out.push((path, lang, source));
+            }
+        }
+    }
+    let mut out = Vec::new();
+    walk(&Path::new(env!("CARGO_MANIFEST_DIR")).join("tests/fixtures"), &mut out);
+    out
+}
+
+/// Reports for every fixture and every case, paired with the language the
+/// input was analyzed as.
+fn all_reports() -> Vec<(String, HeuristicLanguage, Report)> {
+    let pipeline = Pipeline::with_defaults();
+    let mut reports: Vec<_> = fixtures()
+        .into_iter()
+        .map(|(path, lang, source)| {
+            let report = pipeline.run(&source, Some(path.clone()));
+            (path.display().to_string(), HeuristicLanguage::from(lang), report)
+        })
+        .collect();
+    for case in cases() {
+        let report = pipeline.run(&case.source, Some(case.path()));
+        reports.push((format!("case `{}`", case.name), case.language, report));
+    }
+    reports
+}
+
+/// Detectors that only run when `.vibecheck` opts in to a network service
+/// or a trained model, and so never fire here; their unit tests stand in for the matrix.
+const OPT_IN_ANALYZERS: &[&str] = &["perplexity", "stylometry"];
+
+fn is_text_language(lang: HeuristicLanguage) -> bool {
+    matches!(
+        lang,
+        HeuristicLanguage::Rust | HeuristicLanguage::Python | HeuristicLanguage::Js | HeuristicLanguage::Go
+    )
+}
+
+// ── Text rules ─────────────────────────────────────────────────────────
+
+#[test]
+fn cases_fire_their_signals() {
+    let pipeline = Pipeline::with_defaults();
+    let mut failures = Vec::new();
+    for case in cases() {
+        let report = pipeline.run(&case.source, Some(case.path()));
+        let fired: HashSet<&str> = report.signals.iter().map(|s| s.id.as_str()).collect();
+        for id in &case.fires {
+            if !fired.contains(id.as_str()) {
+                failures.push(format!("case `{}`: {id} did not fire", case.name));
+            }
+        }
+    }
+    assert!(failures.is_empty(), "{}", failures.join("\n"));
+}
+
+#[test]
+fn cases_name_known_signals_in_their_language() {
+    let known: HashMap<&str, HeuristicLanguage> = all_heuristics().iter().map(|h| (h.id, h.language)).collect();
+    for case in cases() {
+        assert!(!case.fires.is_empty(), "case `{}` asserts nothing", case.name);
+        for id in &case.fires {
+            match known.get(id.as_str()) {
+                Some(lang) => assert_eq!(*lang, case.language, "case `{}`: {id} is a {lang} signal", case.name),
+                None => panic!("case `{}`: unknown signal {id}", case.name),
+            }
+        }
+    }
+}
+
+#[test]
+fn every_text_rule_fires_in_its_language() {
+    let mut fired: HashSet<String> = HashSet::new();
+    for (_, _, report) in all_reports() {
+        fired.extend(report.signals.into_iter().map(|s| s.id));
+    }
+    let silent: BTreeSet<String> = all_heuristics()
+        .iter()
+        .filter(|h| is_text_language(h.language) && !OPT_IN_ANALYZERS.contains(&h.analyzer))
+        .filter(|h| !fired.contains(h.id))
+        .map(|h| format!("{} ({} analyzer)", h.id, h.analyzer))
+        .collect();
+    assert!(
+        silent.is_empty(),
+        "rules that never fire on any input in their language — add a [[case]] to \
+         tests/rule_matrix.toml or fix the detector:\n{}",
+        silent.into_iter().collect::<Vec<_>>().join("\n")
+    );
+}
+
+#[test]
+fn signals_stay_in_their_language() {
+    for (input, lang, report) in all_reports() {
+        let text = format!("{lang}.");
+        let cst = format!("{}.", HeuristicLanguage::cst_from(language_of(lang)));
+        for signal in &report.signals {
+            assert!(
+                signal.id.starts_with(&text) || signal.id.starts_with(&cst),
+                "{input}: {lang} input produced {}",
+                signal.id
+            );
+        }
+    }
+}
+
+fn language_of(lang: HeuristicLanguage) -> Language {
+    match lang {
+        HeuristicLanguage::Rust => Language::Rust,
+        HeuristicLanguage::Python => Language::Python,
+        HeuristicLanguage::Js => Language::JavaScript,
+        HeuristicLanguage::Go => Language::Go,
+        other => panic!("not a file language: {other}"),
+    }
+}
+
+// ── CST rules ──────────────────────────────────────────────────────────
+
+/// Metric names each CST language (by its `heuristics.toml` name) produces on
+/// at least one fixture.
+fn extracted_metrics() -> HashMap<String, HashSet<String>> {
+    let analyzers = default_cst_analyzers();
+    let mut out: HashMap<String, HashSet<String>> = HashMap::new();
+    for (path, lang, source) in fixtures() {
+        let analyzer = analyzers
+            .iter()
+            .find(|a| a.target_language() == lang)
+            .unwrap_or_else(|| panic!("no CST analyzer for {lang:?}"));
+        let mut parser = tree_sitter::Parser::new();
+        parser.set_language(&analyzer.ts_language()).expect("load grammar");
+        let tree = parser
+            .parse(&source, None)
+            .unwrap_or_else(|| panic!("{}: parse failed", path.display()));
+        out.entry(HeuristicLanguage::cst_from(lang).to_string())
+            .or_default()
+            .extend(analyzer.extract_metrics(&tree, &source).into_keys());
+
+        // Language packs are scored with the frontend-generic metrics, so
+        // every built-in frontend stands in for a pack.
+        let frontend = BUILTIN_FRONTENDS.iter().find(|f| f.language == lang).unwrap();
+        let tokens = frontend
+            .tokenize(&source)
+            .unwrap_or_else(|| panic!("{}: {} frontend failed", path.display(), frontend.name()));
+        out.entry(HeuristicLanguage::PackCst.to_string())
+            .or_default()
+            .extend(generic_metrics(&tokens, &source).into_keys());
+    }
+    out
+}
+
+#[test]
+fn every_cst_metric_is_extracted() {
+    let extracted = extracted_metrics();
+    let missing: BTreeSet<String> = all_heuristics()
+        .iter()
+        .filter_map(|h| h.metric.map(|m| (h, m)))
+        .filter(|(h, m)| !extracted.get(&h.language.to_string()).is_some_and(|set| set.contains(*m)))
+        .map(|(h, m)| format!("{}: metric `{m}` never extracted by {}", h.id, h.language))
+        .collect();
+    assert!(missing.is_empty(), "{}", missing.into_iter().collect::<Vec<_>>().join("\n"));
+}
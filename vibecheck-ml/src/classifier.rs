@@ -23,6 +23,9 @@ pub struct ModelMetadata {
     pub feature_dimensions: usize,
     pub coverage: HashMap<String, HashMap<String, usize>>,
     pub accuracy: Option<f64>,
+    /// Held-out accuracy per model era (see `evaluate_accuracy_by_era`).
+    #[serde(default)]
+    pub accuracy_by_era: HashMap<String, f64>,
 }
 
 #[cfg(test)]
@@ -74,6 +77,7 @@ mod tests {
             feature_dimensions: 0,
             coverage: HashMap::new(),
             accuracy: None,
+            accuracy_by_era: HashMap::new(),
         };
         let json = serde_json::to_string(&meta).unwrap();
         let back: ModelMetadata = serde_json::from_str(&json).unwrap();
@@ -91,11 +95,21 @@ mod tests {
             feature_dimensions: 278,
             coverage: HashMap::new(),
             accuracy: Some(0.75),
+            accuracy_by_era: HashMap::from([("gpt-2023-chat".to_string(), 0.5)]),
         };
         let json = serde_json::to_string(&meta).unwrap();
         let back: ModelMetadata = serde_json::from_str(&json).unwrap();
         assert_eq!(back.version, "0.1.0");
         assert_eq!(back.feature_dimensions, 278);
         assert_eq!(back.accuracy, Some(0.75));
+        assert_eq!(back.accuracy_by_era["gpt-2023-chat"], 0.5);
+    }
+
+    #[test]
+    fn model_metadata_without_era_accuracy_deserializes() {
+        let json = r#"{"version":"0.1.0","trained_at":"","algorithms":[],"training_samples":0,
+            "feature_dimensions":0,"coverage":{},"accuracy":null}"#;
+        let meta: ModelMetadata = serde_json::from_str(json).unwrap();
+        assert!(meta.accuracy_by_era.is_empty());
     }
 }
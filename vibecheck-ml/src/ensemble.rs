@@ -1,4 +1,4 @@
-use std::collections::HashMap;
+use std::collections::{BTreeMap, HashMap};
 
 use linfa::prelude::*;
 use ndarray::{Array1, Array2};
@@ -243,11 +243,15 @@ impl PostScorer for EnsembleModel {
 
         let scores_fid = self.predict(&fv);
 
-        let mut scores: HashMap<ModelFamily, f64> = HashMap::new();
+        // Era labels ("gpt-2023-chat") fold into their family's score.
+        let mut scores: BTreeMap<ModelFamily, f64> = BTreeMap::new();
         for family in ModelFamily::all() {
-            let fid = FamilyId::from_model_family(*family);
-            let score = scores_fid.get(&fid).copied().unwrap_or(0.0);
-            scores.insert(*family, score);
+            scores.insert(*family, 0.0);
+        }
+        for (fid, score) in &scores_fid {
+            if let Some(family) = fid.to_model_family() {
+                *scores.entry(family).or_insert(0.0) += score;
+            }
         }
 
         // Handle unknown families: add their mass to the highest-scoring known family
@@ -278,10 +282,19 @@ impl PostScorer for EnsembleModel {
             .map(|(&k, &v)| (k, v))
             .unwrap_or((ModelFamily::Human, 0.0));
 
+        let era = scores_fid
+            .iter()
+            .filter(|(fid, _)| fid.era().is_some() && fid.to_model_family() == Some(primary))
+            .max_by(|a, b| a.1.partial_cmp(b.1).unwrap().then_with(|| b.0 .0.cmp(&a.0 .0)))
+            .map(|(fid, _)| fid.0.clone());
+
         Attribution {
             primary,
             confidence,
             scores,
+            era,
+            ai_probability: None,
+            authorship: None,
         }
     }
 }
@@ -359,6 +372,53 @@ pub fn evaluate_accuracy(
     correct as f64 / test_vectors.len() as f64
 }
 
+/// Held-out accuracy for one model era.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub struct EraAccuracy {
+    pub correct: usize,
+    pub total: usize,
+}
+
+impl EraAccuracy {
+    pub fn accuracy(&self) -> f64 {
+        if self.total == 0 {
+            0.0
+        } else {
+            self.correct as f64 / self.total as f64
+        }
+    }
+}
+
+/// Evaluate accuracy of a classifier on held-out data, broken down by the
+/// era of each label (e.g. `gpt-2023-chat`, `gpt-2025-terse`).
+///
+/// A prediction counts as correct when it names the right *family*, so a
+/// classifier trained on one era is still credited for recognising another
+/// era of the same family.  An era whose accuracy trails the rest is a
+/// sign its fingerprints have gone stale.  Labels without an era are
+/// reported under the bare family name.
+pub fn evaluate_accuracy_by_era(
+    classifier: &dyn Classifier,
+    test_vectors: &[FeatureVector],
+    test_labels: &[FamilyId],
+) -> BTreeMap<String, EraAccuracy> {
+    let mut by_era: BTreeMap<String, EraAccuracy> = BTreeMap::new();
+    for (fv, expected) in test_vectors.iter().zip(test_labels.iter()) {
+        let pred = classifier.predict(fv);
+        let hit = pred
+            .iter()
+            .max_by(|a, b| a.1.partial_cmp(b.1).unwrap())
+            .map(|(fid, _)| fid.family().eq_ignore_ascii_case(expected.family()))
+            .unwrap_or(false);
+        let entry = by_era.entry(expected.0.clone()).or_default();
+        entry.total += 1;
+        if hit {
+            entry.correct += 1;
+        }
+    }
+    by_era
+}
+
 /// Train a full ensemble from feature vectors and labels.
 ///
 /// Trains logistic regression, naive bayes, and decision tree classifiers,
@@ -476,6 +536,9 @@ mod tests {
                 .iter()
                 .map(|&f| (f, 0.2))
                 .collect(),
+            era: None,
+            ai_probability: None,
+            authorship: None,
         };
 
         let result = ensemble.rescore(&[], &HashMap::new(), &heuristic, None, "fn main() {}");
@@ -554,6 +617,9 @@ mod tests {
             primary: ModelFamily::Human,
             confidence: 0.5,
             scores: ModelFamily::all().iter().map(|&f| (f, 0.2)).collect(),
+            era: None,
+            ai_probability: None,
+            authorship: None,
         };
 
         for lang in [Language::Rust, Language::Python, Language::JavaScript, Language::Go] {
@@ -589,4 +655,51 @@ mod tests {
         let acc = evaluate_accuracy(&ensemble, &[], &[]);
         assert_eq!(acc, 0.0);
     }
+
+    struct FixedClassifier(FamilyId);
+
+    impl Classifier for FixedClassifier {
+        fn predict(&self, _features: &FeatureVector) -> HashMap<FamilyId, f64> {
+            HashMap::from([(self.0.clone(), 1.0)])
+        }
+
+        fn name(&self) -> &str {
+            "fixed"
+        }
+    }
+
+    #[test]
+    fn evaluate_accuracy_by_era_scores_family_matches_per_era() {
+        let clf = FixedClassifier(fid("gpt-2023-chat"));
+        let vectors = vec![make_fv(0.5, 1.0); 4];
+        let labels = vec![
+            fid("gpt-2023-chat"),
+            fid("gpt-2025-terse"),
+            fid("claude-2024-verbose"),
+            fid("human"),
+        ];
+        let by_era = evaluate_accuracy_by_era(&clf, &vectors, &labels);
+        assert_eq!(by_era.len(), 4);
+        assert_eq!(by_era["gpt-2023-chat"], EraAccuracy { correct: 1, total: 1 });
+        assert_eq!(by_era["gpt-2025-terse"].accuracy(), 1.0);
+        assert_eq!(by_era["claude-2024-verbose"].accuracy(), 0.0);
+        assert_eq!(by_era["human"].correct, 0);
+    }
+
+    #[test]
+    fn rescore_folds_era_labels_into_family_and_reports_era() {
+        let mut ensemble = EnsembleModel::new();
+        ensemble.add(1.0, Box::new(FixedClassifier(fid("gpt-2025-terse"))));
+        let heuristic = Attribution {
+            primary: ModelFamily::Human,
+            confidence: 0.5,
+            scores: BTreeMap::new(),
+            era: None,
+            ai_probability: None,
+            authorship: None,
+        };
+        let result = ensemble.rescore(&[], &HashMap::new(), &heuristic, None, "fn main() {}");
+        assert_eq!(result.primary, ModelFamily::Gpt);
+        assert_eq!(result.era.as_deref(), Some("gpt-2025-terse"));
+    }
 }
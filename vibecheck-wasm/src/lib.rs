@@ -0,0 +1,89 @@
+//! Browser bindings for vibecheck-core.
+//!
+//! `wasm-pack build vibecheck-wasm --target web` compiles the full pipeline
+//! (text analyzers, tree-sitter CST analyzers, calibration) to WebAssembly
+//! and exposes [`check`] to JavaScript, so a playground can score pasted
+//! code without sending it anywhere.  Nothing here touches a filesystem:
+//! there is no `.vibecheck` config, report cache, language pack or plugin,
+//! so reports match `vibecheck check - --lang <lang>` run with no config.
+//!
+//! ```js
+//! import init, { check } from "./pkg/vibecheck_wasm.js";
+//! await init();
+//! const report = check(source, "python");
+//! console.log(report.attribution.primary, report.attribution.ai_probability);
+//! ```
+
+use anyhow::Context;
+use serde::Serialize;
+use wasm_bindgen::prelude::*;
+
+use vibecheck_core::language::{self, SUPPORTED_EXTENSIONS};
+use vibecheck_core::report::Report;
+use vibecheck_core::Analyzer;
+
+/// Analyze `source` as `lang` and return the report, shaped like the CLI's
+/// `--format json` output.
+///
+/// `lang` is a language name (`rust`, `python`, `javascript`, `typescript`,
+/// `go`) or one of their extensions such as `tsx`.  An empty `lang` runs
+/// only the language-independent analyzers.  Throws on an unknown language.
+#[wasm_bindgen]
+pub fn check(source: &str, lang: &str) -> Result<JsValue, JsError> {
+    let report = analyze(source, lang).map_err(|e| JsError::new(&format!("{e:#}")))?;
+    Ok(report.serialize(&serde_wasm_bindgen::Serializer::json_compatible())?)
+}
+
+/// The language names and extensions [`check`] accepts.
+#[wasm_bindgen]
+pub fn languages() -> Vec<String> {
+    ["rust", "python", "javascript", "typescript", "go"]
+        .into_iter()
+        .chain(SUPPORTED_EXTENSIONS.iter().copied())
+        .map(String::from)
+        .collect()
+}
+
+fn analyze(source: &str, lang: &str) -> anyhow::Result<Report> {
+    let name = match lang.trim() {
+        "" => "snippet".to_string(),
+        lang => {
+            let ext = language::extension_for(lang).with_context(|| {
+                format!(
+                    "unknown language: {lang} (expected rust, python, javascript, typescript, go, or one of: {})",
+                    SUPPORTED_EXTENSIONS.join(", ")
+                )
+            })?;
+            format!("snippet.{ext}")
+        }
+    };
+    let mut report = Analyzer::new().analyze_source(&name, source.as_bytes())?;
+    report.metadata.file_path = None;
+    Ok(report)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use vibecheck_core::report::ModelFamily;
+
+    #[test]
+    fn analyze_runs_the_named_language() {
+        let source = "def add(a, b):\n    return a + b\n";
+        let report = analyze(source, "Python").unwrap();
+        assert!(report.metadata.file_path.is_none());
+        assert!(report.signals.iter().all(|s| !s.id.starts_with("rust.")));
+        assert_eq!(analyze("", "").unwrap().attribution.primary, ModelFamily::Human);
+    }
+
+    #[test]
+    fn analyze_rejects_unknown_languages() {
+        let err = analyze("x", "cobol").unwrap_err();
+        assert!(err.to_string().starts_with("unknown language: cobol"), "{err}");
+    }
+
+    #[test]
+    fn languages_round_trip_through_extension_for() {
+        assert!(languages().iter().all(|l| language::extension_for(l).is_some()));
+    }
+}